@@ -9,6 +9,8 @@ require (
 
 require github.com/swaggo/swag v1.16.3
 
+require github.com/lib/pq v1.10.9
+
 require (
 	github.com/bytedance/sonic v1.11.6 // indirect
 	github.com/bytedance/sonic/loader v0.1.1 // indirect
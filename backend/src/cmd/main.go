@@ -4,14 +4,16 @@ import (
 	// "fmt"
 	"fmt"
 	_ "literary-lions/backend/docs"
+	"literary-lions/backend/src/config"
 	"literary-lions/backend/src/internal/db"
 	"literary-lions/backend/src/internal/handlers"
 	"literary-lions/backend/src/internal/middleware"
 	"literary-lions/backend/src/internal/models"
+	"literary-lions/backend/src/internal/storage"
 	"log"
+	"time"
 
 	"github.com/gin-gonic/gin"
-	_ "github.com/mattn/go-sqlite3"
 	swaggerFiles "github.com/swaggo/files"
 	ginSwagger "github.com/swaggo/gin-swagger"
 )
@@ -25,19 +27,68 @@ import (
 // @in header
 // @name Authorization
 
+// trashPurgeInterval is how often deleted posts and comments past their retention are purged.
+const trashPurgeInterval = time.Hour
+
+// draftPublishInterval is how often scheduled posts are checked for being due.
+const draftPublishInterval = time.Minute
+
+// attachmentCleanupInterval is how often uploaded images no post carries are removed.
+const attachmentCleanupInterval = time.Hour
+
 func main() {
-	// Initialize the database
-	database, err := db.InitDB()
+	// Load configuration from the environment (and .env if present)
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		log.Fatalf("Configuration loading failed: %v\n", err)
+	}
+
+	// Initialize the database selected by DATABASE_DSN (SQLite by default)
+	database, err := db.InitDB(cfg.DatabaseDSN)
 	if err != nil {
 		log.Fatalf("Database initialization failed: %v\n", err)
 	}
 	defer database.Close()
+	log.Printf("Using %s database\n", database.Dialect.Name())
 
-	// Set the database for the models package
-	models.SetDatabase(database)
+	// Validate the reactions offered besides likes and dislikes
+	reactions, err := models.NewReactionSet(cfg.Reactions)
+	if err != nil {
+		log.Fatalf("Invalid REACTIONS: %v\n", err)
+	}
+
+	// Store uploaded images on the local disk
+	uploads, err := storage.NewLocal(cfg.UploadDir)
+	if err != nil {
+		log.Fatalf("Upload directory initialization failed: %v\n", err)
+	}
 
-	// Initialize handlers with the database connection
-	handlers.InitHandlers(database)
+	// Initialize handlers with the repositories backed by the database
+	handlers.InitHandlers(models.NewStore(database), reactions, cfg.CommentMaxDepth, cfg.TrashRetentionDays, uploads, cfg.UploadMaxMB)
+
+	// Purge what stayed in the trash past its retention, at startup and then periodically
+	go func() {
+		for {
+			handlers.PurgeTrash()
+			time.Sleep(trashPurgeInterval)
+		}
+	}()
+
+	// Put scheduled posts live once their time has come
+	go func() {
+		for {
+			handlers.PublishScheduledPosts()
+			time.Sleep(draftPublishInterval)
+		}
+	}()
+
+	// Remove the uploaded images no post carries, and their files
+	go func() {
+		for {
+			handlers.CleanupAttachments()
+			time.Sleep(attachmentCleanupInterval)
+		}
+	}()
 
 	// Set up Gin router
 	r := gin.Default()
@@ -73,6 +124,25 @@ func main() {
 	addRoute("POST", "/logout", handlers.Logout)
 	addRoute("POST", "/register", handlers.Register)
 	addRoute("GET", "/posts", handlers.GetAllPosts)
+	addRoute("GET", "/search", handlers.Search)
+	addRoute("GET", "/reactions", handlers.GetReactions)
+	addRoute("GET", "/categories", handlers.GetCategories)
+	addRoute("GET", "/categories/:slug", handlers.GetCategory)
+	addRoute("GET", "/tags", handlers.GetTags)
+	addRoute("GET", "/tags/:slug", handlers.GetTag)
+	addRoute("GET", "/uploads/:key", handlers.GetUpload)
+	addRoute("GET", "/books", handlers.GetBooks)
+	addRoute("GET", "/books/:id", handlers.GetBook)
+	addRoute("GET", "/books/:id/reviews", handlers.GetBookReviews)
+	addRoute("GET", "/users/:username", handlers.GetUserProfile)
+	addRoute("GET", "/users/:username/posts", handlers.GetUserPosts)
+	addRoute("GET", "/users/:username/comments", handlers.GetUserComments)
+	addRoute("GET", "/users/:username/shelves", handlers.GetUserShelves)
+	addRoute("GET", "/users/:username/liked-posts", handlers.GetUserLikedPosts)
+	addRoute("GET", "/clubs", handlers.GetClubs)
+	addRoute("GET", "/clubs/:slug", handlers.GetClub)
+	addRoute("GET", "/clubs/:slug/schedule", handlers.GetClubSchedule)
+	addRoute("GET", "/post/:id/poll", handlers.GetPostPoll)
 
 	api := r.Group("/api/v1.0")
 
@@ -81,8 +151,29 @@ func main() {
 	api.POST("/login", handlers.Login)
 	api.POST("/logout", handlers.Logout)
 	api.GET("/posts", handlers.GetAllPosts)
+	api.GET("/search", handlers.Search)                                 // Full-text search over posts and comments
+	api.GET("/reactions", handlers.GetReactions)                        // The reactions offered besides likes and dislikes
+	api.GET("/categories", handlers.GetCategories)                      // The categories posts are filed under
+	api.GET("/categories/:slug", handlers.GetCategory)                  // A category and a page of its posts
+	api.GET("/tags", handlers.GetTags)                                  // Tag autocomplete
+	api.GET("/tags/:slug", handlers.GetTag)                             // A tag and a page of its posts
+	api.GET("/uploads/:key", handlers.GetUpload)                        // An uploaded image or thumbnail
+	api.GET("/books", handlers.GetBooks)                                // The catalog of books
+	api.GET("/books/:id", handlers.GetBook)                             // A book and a page of the posts discussing it
+	api.GET("/books/:id/reviews", handlers.GetBookReviews)              // A page of the reviews of a book
+	api.GET("/users/:username", handlers.GetUserProfile)                // The public profile of a member
+	api.GET("/users/:username/posts", handlers.GetUserPosts)            // A page of the posts of a member
+	api.GET("/users/:username/comments", handlers.GetUserComments)      // A page of the comments of a member
+	api.GET("/users/:username/shelves", handlers.GetUserShelves)        // The reading shelves and yearly stats of a member
+	api.GET("/users/:username/liked-posts", handlers.GetUserLikedPosts) // A page of the posts a member likes, unless kept private
+	api.GET("/clubs", handlers.GetClubs)                                // The book clubs
+	api.GET("/clubs/:slug", handlers.GetClub)                           // A club with its members and a page of its posts
+	api.GET("/clubs/:slug/schedule", handlers.GetClubSchedule)          // The reading schedule of a club
 
-	api.GET("/post/:id", handlers.GetPostByID) // Get a specific post by ID
+	api.GET("/post/:id", handlers.GetPostByID)                                  // Get a specific post by ID
+	api.GET("/post/:id/revisions", handlers.GetPostRevisions)                   // The edit history of a post
+	api.GET("/post/:id/revisions/:revision/diff", handlers.GetPostRevisionDiff) // What a revision of a post changed
+	api.GET("/post/:id/poll", handlers.GetPostPoll)                             // The poll of a post and its results
 
 	// Authorization middleware setup
 	api.Use(handlers.AuthMiddleware("user")) // Apply middleware to the group
@@ -102,22 +193,125 @@ func main() {
 	}
 
 	{
-		api.GET("/filtered-posts", handlers.GetAllPosts)           // This is the endpoint to be called when filter query is set
-		api.GET("/users", handlers.GetAllUsers)                    // Apply middleware based on role in the function
-		api.POST("/post", handlers.CreatePost)                     // Create a new post
-		api.PUT("/post/:id", handlers.UpdatePost)                  // Update a specific post by ID
-		api.DELETE("/post/:id", handlers.DeletePost)               // Delete a specific post by ID
-		api.POST("/post/:id/comment", handlers.AddComment)         // Add a comment to a specific post by ID
-		api.PUT("/userprofile-update", handlers.UpdateUserProfile) // Update user profile
+		api.GET("/filtered-posts", handlers.GetAllPosts)                                                                         // This is the endpoint to be called when filter query is set
+		api.GET("/users", handlers.GetAllUsers)                                                                                  // Apply middleware based on role in the function
+		api.POST("/post", handlers.CreatePost)                                                                                   // Create a new post
+		api.PUT("/post/:id", handlers.UpdatePost)                                                                                // Update a specific post by ID
+		api.DELETE("/post/:id", handlers.DeletePost)                                                                             // Delete a specific post by ID
+		api.POST("/post/:id/comment", handlers.ClubDiscussionMiddleware(models.ReactionTargetPost), handlers.AddComment)         // Add a comment to a specific post by ID
+		api.POST("/comment/:id/reply", handlers.ClubDiscussionMiddleware(models.ReactionTargetComment), handlers.ReplyToComment) // Reply to a specific comment by ID
+		api.PUT("/comment/:id", handlers.UpdateComment)                                                                          // Edit a specific comment by ID, for its author or admins
+		api.DELETE("/comment/:id", handlers.DeleteComment)                                                                       // Delete a specific comment by ID, for its author or admins
+		api.PUT("/userprofile-update", handlers.UpdateUserProfile)                                                               // Update user profile
+		api.PUT("/userprofile-avatar", handlers.UploadAvatar)                                                                    // Set the avatar of the user from an uploaded image
+		api.DELETE("/userprofile-avatar", handlers.DeleteAvatar)                                                                 // Go back to the default avatar
+		api.POST("/markdown/preview", handlers.PreviewMarkdown)                                                                  // Render Markdown as a post or comment shows it
+
+		// Drafts and scheduled posts, seen by their authors alone
+		api.POST("/drafts", handlers.SaveDraft)                // Save a post without publishing it, or schedule it
+		api.GET("/drafts", handlers.GetDrafts)                 // The drafts and scheduled posts of the user
+		api.GET("/drafts/:id", handlers.GetDraft)              // Get a specific draft by ID
+		api.PUT("/drafts/:id", handlers.UpdateDraft)           // Update or reschedule a specific draft by ID
+		api.DELETE("/drafts/:id", handlers.DeleteDraft)        // Move a specific draft to the trash
+		api.POST("/drafts/:id/publish", handlers.PublishDraft) // Publish a specific draft right away
+
+		// Images attached to posts and drafts
+		api.POST("/uploads", handlers.UploadImage)                       // Upload an image and make its thumbnail
+		api.PUT("/post/:id/attachments", handlers.SetPostAttachments)    // Set the images of a post, for its author or admins
+		api.PUT("/drafts/:id/attachments", handlers.SetDraftAttachments) // Set the images of a draft
+
+		// Books of the catalog discussed in posts
+		api.POST("/books", handlers.CreateBook)              // Add a book, approved right away for admins and curators
+		api.PUT("/post/:id/books", handlers.SetPostBooks)    // Set the books of a post, for its author or admins
+		api.PUT("/drafts/:id/books", handlers.SetDraftBooks) // Set the books of a draft
+
+		// Reviews of the books of the catalog
+		api.POST("/books/:id/reviews", handlers.CreateReview) // Rate and review a book, once per member
+		api.PUT("/reviews/:id", handlers.UpdateReview)        // Edit a review, for its author or admins
+		api.DELETE("/reviews/:id", handlers.DeleteReview)     // Delete a review, for its author or admins
+
+		// Reading shelves of the logged-in member
+		api.GET("/shelves", handlers.GetShelves)                  // The books on the shelves, with the yearly stats
+		api.GET("/shelves/stats", handlers.GetReadingStats)       // The number of books finished each year
+		api.PUT("/shelves/:book_id", handlers.SetShelf)           // Put a book on a shelf, with the reading progress
+		api.DELETE("/shelves/:book_id", handlers.RemoveFromShelf) // Take a book off the shelves
+
+		// The trash of deleted posts and comments
+		api.GET("/trash", handlers.GetTrash)                      // The posts and comments the user can restore
+		api.POST("/post/:id/restore", handlers.RestorePost)       // Restore a deleted post, for its author or admins
+		api.POST("/comment/:id/restore", handlers.RestoreComment) // Restore a deleted comment, for its author or admins
 
 		// Likes and dislikes for posts
-		api.POST("/post/:id/like", handlers.LikePost)       // Like a specific post by ID
-		api.POST("/post/:id/dislike", handlers.DislikePost) // Dislike a specific post by ID
+		api.POST("/post/:id/like", handlers.ClubDiscussionMiddleware(models.ReactionTargetPost), handlers.LikePost)       // Like a specific post by ID
+		api.POST("/post/:id/dislike", handlers.ClubDiscussionMiddleware(models.ReactionTargetPost), handlers.DislikePost) // Dislike a specific post by ID
 
 		// // Likes and dislikes for comments
-		api.POST("/comment/:id/like", handlers.LikeComment)       // Like a specific comment by ID
-		api.POST("/comment/:id/dislike", handlers.DislikeComment) // Dislike a specific comment by ID
+		api.POST("/comment/:id/like", handlers.ClubDiscussionMiddleware(models.ReactionTargetComment), handlers.LikeComment)       // Like a specific comment by ID
+		api.POST("/comment/:id/dislike", handlers.ClubDiscussionMiddleware(models.ReactionTargetComment), handlers.DislikeComment) // Dislike a specific comment by ID
+
+		// Reactions from the configured set for posts and comments
+		api.POST("/post/:id/reactions/:reaction", handlers.ClubDiscussionMiddleware(models.ReactionTargetPost), handlers.AddPostReaction)               // Add a reaction to a post
+		api.DELETE("/post/:id/reactions/:reaction", handlers.ClubDiscussionMiddleware(models.ReactionTargetPost), handlers.RemovePostReaction)          // Remove a reaction from a post
+		api.POST("/comment/:id/reactions/:reaction", handlers.ClubDiscussionMiddleware(models.ReactionTargetComment), handlers.AddCommentReaction)      // Add a reaction to a comment
+		api.DELETE("/comment/:id/reactions/:reaction", handlers.ClubDiscussionMiddleware(models.ReactionTargetComment), handlers.RemoveCommentReaction) // Remove a reaction from a comment
+
+		// Polls carried by posts, voted in once by each member
+		api.POST("/post/:id/poll/vote", handlers.ClubDiscussionMiddleware(models.ReactionTargetPost), handlers.VotePoll) // Vote in the poll of a post
+
+		// Book clubs. Commenting, reacting and voting in a club, above, is kept to its members
+		api.POST("/clubs", handlers.CreateClub)                   // Start a club, owned by the user
+		api.POST("/clubs/:slug/join", handlers.JoinClub)          // Join a public club, or ask to join a private one
+		api.DELETE("/clubs/:slug/membership", handlers.LeaveClub) // Leave a club, or withdraw a request to join it
+
+	}
+
+	// Club routes, layered on the authentication above, for the approved members of
+	// the club, its moderators and its owner. Admins pass the role checks of every
+	// club. They are not listed on the root page either.
+	member := api.Group("/clubs/:slug", handlers.ClubMiddleware())
+	{
+		member.POST("/posts", handlers.CreateClubPost)                          // Write a post in the club
+		member.PUT("/schedule/:section_id/read", handlers.MarkSectionRead)      // Mark a section of the schedule read
+		member.DELETE("/schedule/:section_id/read", handlers.MarkSectionUnread) // Mark a section of the schedule unread
+	}
+	moderator := api.Group("/clubs/:slug", handlers.ClubMiddleware(models.ClubOwnerRole, models.ClubModeratorRole))
+	{
+		moderator.POST("/members/:username/approve", handlers.ApproveClubMember) // Let a member into a private club
+		moderator.DELETE("/members/:username", handlers.RemoveClubMember)        // Remove a member, or turn down a request to join
+		moderator.DELETE("/posts/:post_id", handlers.DeleteClubPost)             // Move a post of the club to the trash
+		moderator.DELETE("/comments/:comment_id", handlers.DeleteClubComment)    // Move a comment in the club to the trash
+		moderator.POST("/schedule", handlers.CreateClubSection)                  // Add a section to the reading schedule
+		moderator.PUT("/schedule/:section_id", handlers.UpdateClubSection)       // Edit a section of the reading schedule
+		moderator.DELETE("/schedule/:section_id", handlers.DeleteClubSection)    // Take a section off the schedule, unless posts discuss it
+	}
+	owner := api.Group("/clubs/:slug", handlers.ClubMiddleware(models.ClubOwnerRole))
+	{
+		owner.PUT("", handlers.UpdateClub)                               // Edit the club
+		owner.DELETE("", handlers.DeleteClub)                            // Delete the club and trash its posts
+		owner.PUT("/members/:username/role", handlers.SetClubMemberRole) // Make a member a moderator, or a member again
+	}
+
+	// Admin routes, only for users with the admin role. They are not listed on the
+	// root page, which registers its routes without authentication.
+	admin := api.Group("/admin", handlers.AuthMiddleware("admin"))
+	{
+		admin.POST("/categories", handlers.CreateCategory)       // Create a category
+		admin.PUT("/categories/:id", handlers.UpdateCategory)    // Update a category
+		admin.DELETE("/categories/:id", handlers.DeleteCategory) // Delete a category without posts
+
+		admin.POST("/posts/:id/revisions/:revision/restore", handlers.RestorePostRevision) // Restore an older revision of a post
 
+		admin.PUT("/users/:id/role", handlers.UpdateUserRole) // Make a user a curator or an admin, or a plain user again
+	}
+
+	// Curation routes, for admins and curators, who look after the book catalog.
+	// They are not listed on the root page either.
+	curation := api.Group("/curation", handlers.AuthMiddleware("admin", "curator"))
+	{
+		curation.GET("/books", handlers.GetPendingBooks)          // The books waiting for approval
+		curation.PUT("/books/:id", handlers.UpdateBook)           // Update a book
+		curation.POST("/books/:id/approve", handlers.ApproveBook) // List a book in the catalog
+		curation.DELETE("/books/:id", handlers.DeleteBook)        // Delete a book, or turn it down
 	}
 
 	// Start server on port 8080
@@ -0,0 +1,77 @@
+// Command migrate applies, reverts and reports the database schema migrations.
+//
+// Usage (from backend/src):
+//
+//	go run ./cmd/migrate [-dsn dsn] up
+//	go run ./cmd/migrate [-dsn dsn] [-steps n] down
+//	go run ./cmd/migrate [-dsn dsn] status
+//
+// The DSN defaults to the DATABASE_DSN environment variable; see db.Open for the accepted forms.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"literary-lions/backend/src/config"
+	"literary-lions/backend/src/internal/db"
+	"log"
+	"os"
+)
+
+func main() {
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		log.Fatalf("Configuration loading failed: %v\n", err)
+	}
+
+	dsn := flag.String("dsn", cfg.DatabaseDSN, "database DSN: a SQLite file path or a postgres:// URL (defaults to "+db.DefaultPath+")")
+	steps := flag.Int("steps", 1, "number of migrations to revert with the down command")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: migrate [flags] up|down|status\n\nFlags:\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	// Open the database without touching the schema
+	database, err := db.Open(*dsn)
+	if err != nil {
+		log.Fatalf("Could not open database: %v\n", err)
+	}
+	defer database.Close()
+
+	switch command := flag.Arg(0); command {
+	case "up":
+		if err := db.Migrate(database); err != nil {
+			log.Fatalf("Migration failed: %v\n", err)
+		}
+		log.Println("Database is up to date")
+	case "down":
+		if *steps < 1 {
+			log.Fatalf("-steps must be at least 1")
+		}
+		if err := db.Rollback(database, *steps); err != nil {
+			log.Fatalf("Rollback failed: %v\n", err)
+		}
+	case "status":
+		statuses, err := db.MigrationStatuses(database)
+		if err != nil {
+			log.Fatalf("Could not read migration status: %v\n", err)
+		}
+		for _, status := range statuses {
+			state := "pending"
+			if status.Applied {
+				state = "applied " + status.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			fmt.Printf("%04d_%-30s %s\n", status.Version, status.Name, state)
+		}
+	default:
+		fmt.Fprintf(os.Stderr, "unknown command %q\n", command)
+		flag.Usage()
+		os.Exit(2)
+	}
+}
@@ -1,17 +1,30 @@
 package config
 
 import (
+	"fmt"
 	"log"
 	"os"
+	"strconv"
+	"strings"
 	"github.com/joho/godotenv"
 )
 
 // Config holds application configuration values.
 // JWTSecret: Secret key used for signing JWT tokens.
 // DatabaseDSN: Data Source Name for connecting to the database.
+// Reactions: Names of the reactions offered on posts and comments besides likes and dislikes.
+// CommentMaxDepth: Deepest level replies to comments are nested at.
+// TrashRetentionDays: Number of days deleted posts and comments can be restored before they are purged.
+// UploadDir: Directory the uploaded images are stored in.
+// UploadMaxMB: Size limit of an uploaded image, in megabytes.
 type Config struct {
-	JWTSecret   string // Secret key for JWT authentication
-	DatabaseDSN string // Data Source Name for database connection
+	JWTSecret          string   // Secret key for JWT authentication
+	DatabaseDSN        string   // Data Source Name for database connection
+	Reactions          []string // Reaction set, the default set if empty
+	CommentMaxDepth    int      // Reply nesting limit, the default limit if zero
+	TrashRetentionDays int      // Trash retention, the default retention if zero
+	UploadDir          string   // Image directory, "uploads" if not set
+	UploadMaxMB        int      // Upload size limit, the default limit if zero
 }
 
 // LoadConfig loads configuration values from environment variables and returns a Config struct.
@@ -19,7 +32,7 @@ type Config struct {
 //
 // Returns:
 //   - *Config: A pointer to a Config struct containing the loaded configuration values.
-//   - error: An error if there was an issue loading the .env file.
+//   - error: An error if a value is malformed, e.g. a COMMENT_MAX_DEPTH, TRASH_RETENTION_DAYS or UPLOAD_MAX_MB that is not a positive number.
 func LoadConfig() (*Config, error) {
 	// Load environment variables from a .env file if it exists
 	err := godotenv.Load()
@@ -31,8 +44,53 @@ func LoadConfig() (*Config, error) {
 
 	// Create and return a Config struct populated with values from environment variables
 	// os.Getenv retrieves the value of the environment variable specified
+	commentMaxDepth, err := parsePositive(os.Getenv("COMMENT_MAX_DEPTH"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid COMMENT_MAX_DEPTH: %v", err)
+	}
+	trashRetentionDays, err := parsePositive(os.Getenv("TRASH_RETENTION_DAYS"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid TRASH_RETENTION_DAYS: %v", err)
+	}
+	uploadMaxMB, err := parsePositive(os.Getenv("UPLOAD_MAX_MB"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid UPLOAD_MAX_MB: %v", err)
+	}
+	uploadDir := os.Getenv("UPLOAD_DIR")
+	if uploadDir == "" {
+		uploadDir = "uploads"
+	}
+
 	return &Config{
-		JWTSecret:   os.Getenv("JWT_SECRET"),   // JWT secret key for token generation and verification
-		DatabaseDSN: os.Getenv("DATABASE_DSN"), // Data Source Name for database connection
+		JWTSecret:          os.Getenv("JWT_SECRET"),           // JWT secret key for token generation and verification
+		DatabaseDSN:        os.Getenv("DATABASE_DSN"),         // Data Source Name for database connection
+		Reactions:          splitList(os.Getenv("REACTIONS")), // Comma-separated reaction names, e.g. "insightful,funny"
+		CommentMaxDepth:    commentMaxDepth,                   // Number of reply levels, e.g. 3
+		TrashRetentionDays: trashRetentionDays,                // Days in the trash, e.g. 7
+		UploadDir:          uploadDir,                         // Path of the image directory, e.g. /var/lib/lions/uploads
+		UploadMaxMB:        uploadMaxMB,                       // Megabytes per image, e.g. 10
 	}, nil
 }
+
+// parsePositive parses a positive number, returning 0 for an empty value.
+func parsePositive(value string) (int, error) {
+	if value == "" {
+		return 0, nil
+	}
+	number, err := strconv.Atoi(strings.TrimSpace(value))
+	if err != nil || number < 1 {
+		return 0, fmt.Errorf("%q is not a positive number", value)
+	}
+	return number, nil
+}
+
+// splitList splits a comma-separated value into its trimmed, non-empty items.
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
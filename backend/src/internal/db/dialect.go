@@ -0,0 +1,132 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/lib/pq"
+	"github.com/mattn/go-sqlite3"
+)
+
+// Dialect captures the differences between the SQL databases the application can run on.
+// Queries are written once with "?" placeholders and rewritten by the dialect when needed.
+type Dialect interface {
+	// Name is the short name of the dialect; it is also the migrations sub-directory.
+	Name() string
+	// Driver is the database/sql driver name used to open connections.
+	Driver() string
+	// Rebind rewrites "?" placeholders into the form the database expects.
+	Rebind(query string) string
+	// IsUniqueViolation reports whether err is a unique constraint failure on the given column.
+	IsUniqueViolation(err error, column string) bool
+}
+
+// SQLite is the dialect for the embedded SQLite database.
+var SQLite Dialect = sqliteDialect{}
+
+// Postgres is the dialect for PostgreSQL servers.
+var Postgres Dialect = postgresDialect{}
+
+type sqliteDialect struct{}
+
+func (sqliteDialect) Name() string   { return "sqlite" }
+func (sqliteDialect) Driver() string { return "sqlite3" }
+
+// Rebind returns the query unchanged because SQLite understands "?" placeholders.
+func (sqliteDialect) Rebind(query string) string { return query }
+
+// IsUniqueViolation matches errors such as "UNIQUE constraint failed: users.username".
+func (sqliteDialect) IsUniqueViolation(err error, column string) bool {
+	var sqliteErr sqlite3.Error
+	if !errors.As(err, &sqliteErr) || sqliteErr.ExtendedCode != sqlite3.ErrConstraintUnique {
+		return false
+	}
+	return strings.Contains(sqliteErr.Error(), "."+column)
+}
+
+type postgresDialect struct{}
+
+func (postgresDialect) Name() string   { return "postgres" }
+func (postgresDialect) Driver() string { return "postgres" }
+
+// Rebind turns each "?" placeholder into the numbered "$n" form used by PostgreSQL.
+func (postgresDialect) Rebind(query string) string {
+	var builder strings.Builder
+	builder.Grow(len(query) + 10)
+
+	n := 0
+	for _, r := range query {
+		if r == '?' {
+			n++
+			builder.WriteByte('$')
+			builder.WriteString(strconv.Itoa(n))
+			continue
+		}
+		builder.WriteRune(r)
+	}
+	return builder.String()
+}
+
+// IsUniqueViolation matches unique_violation (23505) errors whose constraint mentions the column,
+// e.g. the default "users_username_key" constraint name.
+func (postgresDialect) IsUniqueViolation(err error, column string) bool {
+	var pqErr *pq.Error
+	if !errors.As(err, &pqErr) || pqErr.Code != "23505" {
+		return false
+	}
+	return strings.Contains(pqErr.Constraint, column)
+}
+
+// DB wraps a database connection together with its dialect so that callers can
+// write "?" placeholders regardless of the underlying database.
+type DB struct {
+	*sql.DB
+	Dialect Dialect
+}
+
+// Tx is a transaction started from a DB; it rewrites placeholders like DB does.
+type Tx struct {
+	*sql.Tx
+	dialect Dialect
+}
+
+// Exec executes a statement after rewriting its placeholders for the dialect.
+func (db *DB) Exec(query string, args ...interface{}) (sql.Result, error) {
+	return db.DB.Exec(db.Dialect.Rebind(query), args...)
+}
+
+// Query runs a query after rewriting its placeholders for the dialect.
+func (db *DB) Query(query string, args ...interface{}) (*sql.Rows, error) {
+	return db.DB.Query(db.Dialect.Rebind(query), args...)
+}
+
+// QueryRow runs a single-row query after rewriting its placeholders for the dialect.
+func (db *DB) QueryRow(query string, args ...interface{}) *sql.Row {
+	return db.DB.QueryRow(db.Dialect.Rebind(query), args...)
+}
+
+// Begin starts a transaction that rewrites placeholders for the dialect.
+func (db *DB) Begin() (*Tx, error) {
+	tx, err := db.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	return &Tx{Tx: tx, dialect: db.Dialect}, nil
+}
+
+// Exec executes a statement within the transaction after rewriting its placeholders.
+func (tx *Tx) Exec(query string, args ...interface{}) (sql.Result, error) {
+	return tx.Tx.Exec(tx.dialect.Rebind(query), args...)
+}
+
+// Query runs a query within the transaction after rewriting its placeholders.
+func (tx *Tx) Query(query string, args ...interface{}) (*sql.Rows, error) {
+	return tx.Tx.Query(tx.dialect.Rebind(query), args...)
+}
+
+// QueryRow runs a single-row query within the transaction after rewriting its placeholders.
+func (tx *Tx) QueryRow(query string, args ...interface{}) *sql.Row {
+	return tx.Tx.QueryRow(tx.dialect.Rebind(query), args...)
+}
@@ -0,0 +1,266 @@
+package db
+
+import (
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// migrationFiles holds the numbered SQL migrations shipped with the binary, one
+// sub-directory per dialect. Every migration is a pair of files named
+// NNNN_name.up.sql and NNNN_name.down.sql, and each dialect must provide the same versions.
+//
+//go:embed migrations
+var migrationFiles embed.FS
+
+// ErrNoFTS5 is returned when a SQLite migration needs the FTS5 extension but the
+// sqlite3 driver was compiled without it. Build with -tags sqlite_fts5 to enable it.
+var ErrNoFTS5 = errors.New("sqlite3 driver built without FTS5, rebuild with -tags sqlite_fts5")
+
+// Migration is a single versioned schema change with its up and down SQL.
+type Migration struct {
+	Version int    // Sequential version number taken from the file name prefix
+	Name    string // Human readable name taken from the file name
+	Up      string // SQL applied when migrating forward
+	Down    string // SQL applied when rolling the migration back
+}
+
+// MigrationStatus reports whether a known migration has been applied to a database.
+type MigrationStatus struct {
+	Migration
+	Applied   bool      // True if the migration is recorded in schema_migrations
+	AppliedAt time.Time // When the migration was applied, zero if pending
+}
+
+// LoadMigrations reads the embedded migration files for a dialect and returns them sorted by version.
+//
+// Parameters:
+//   - dialect: The dialect whose migrations sub-directory is read.
+//
+// Returns:
+//   - []Migration: All known migrations in ascending version order.
+//   - error: An error if a file name is malformed or an up/down pair is incomplete.
+func LoadMigrations(dialect Dialect) ([]Migration, error) {
+	dir := path.Join("migrations", dialect.Name())
+	entries, err := fs.ReadDir(migrationFiles, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := make(map[int]*Migration)
+	for _, entry := range entries {
+		fileName := entry.Name()
+
+		// Split "0001_initial_schema.up.sql" into its version, name and direction
+		base := strings.TrimSuffix(fileName, ".sql")
+		direction := path.Ext(base)
+		base = strings.TrimSuffix(base, direction)
+		versionStr, name, found := strings.Cut(base, "_")
+		version, err := strconv.Atoi(versionStr)
+		if !found || err != nil || (direction != ".up" && direction != ".down") {
+			return nil, fmt.Errorf("invalid migration file name %q", fileName)
+		}
+
+		content, err := migrationFiles.ReadFile(path.Join(dir, fileName))
+		if err != nil {
+			return nil, err
+		}
+
+		migration, exists := byVersion[version]
+		if !exists {
+			migration = &Migration{Version: version, Name: name}
+			byVersion[version] = migration
+		} else if migration.Name != name {
+			return nil, fmt.Errorf("migration %04d has conflicting names %q and %q", version, migration.Name, name)
+		}
+
+		if direction == ".up" {
+			migration.Up = string(content)
+		} else {
+			migration.Down = string(content)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, migration := range byVersion {
+		if migration.Up == "" || migration.Down == "" {
+			return nil, fmt.Errorf("migration %04d_%s must have both up and down files", migration.Version, migration.Name)
+		}
+		migrations = append(migrations, *migration)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+
+	return migrations, nil
+}
+
+// Migrate applies every migration that has not yet been recorded in schema_migrations.
+// Each migration runs in its own transaction together with its bookkeeping row.
+//
+// Parameters:
+//   - db: The database connection to migrate.
+//
+// Returns:
+//   - error: An error if any migration fails; migrations applied before the failure are kept.
+func Migrate(db *DB) error {
+	migrations, err := LoadMigrations(db.Dialect)
+	if err != nil {
+		return err
+	}
+
+	applied, err := appliedMigrations(db)
+	if err != nil {
+		return err
+	}
+
+	for _, migration := range migrations {
+		if _, done := applied[migration.Version]; done {
+			continue
+		}
+		if err := runMigration(db, migration.Version, migration.Name, migration.Up, true); err != nil {
+			return err
+		}
+		log.Printf("Applied migration %04d_%s", migration.Version, migration.Name)
+	}
+
+	return nil
+}
+
+// Rollback reverts the most recently applied migrations.
+//
+// Parameters:
+//   - db: The database connection to roll back.
+//   - steps: The number of applied migrations to revert, newest first.
+//
+// Returns:
+//   - error: An error if a down migration fails or an applied migration is unknown to this binary.
+func Rollback(db *DB, steps int) error {
+	migrations, err := LoadMigrations(db.Dialect)
+	if err != nil {
+		return err
+	}
+
+	applied, err := appliedMigrations(db)
+	if err != nil {
+		return err
+	}
+
+	// Collect applied versions newest first
+	versions := make([]int, 0, len(applied))
+	for version := range applied {
+		versions = append(versions, version)
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(versions)))
+
+	known := make(map[int]Migration, len(migrations))
+	for _, migration := range migrations {
+		known[migration.Version] = migration
+	}
+
+	for i := 0; i < steps && i < len(versions); i++ {
+		migration, ok := known[versions[i]]
+		if !ok {
+			return fmt.Errorf("cannot roll back migration %04d: no down migration available", versions[i])
+		}
+		if err := runMigration(db, migration.Version, migration.Name, migration.Down, false); err != nil {
+			return err
+		}
+		log.Printf("Rolled back migration %04d_%s", migration.Version, migration.Name)
+	}
+
+	return nil
+}
+
+// MigrationStatuses lists every known migration along with whether it has been applied.
+//
+// Parameters:
+//   - db: The database connection to inspect.
+//
+// Returns:
+//   - []MigrationStatus: The status of each migration in ascending version order.
+//   - error: An error if the migrations or the bookkeeping table cannot be read.
+func MigrationStatuses(db *DB) ([]MigrationStatus, error) {
+	migrations, err := LoadMigrations(db.Dialect)
+	if err != nil {
+		return nil, err
+	}
+
+	applied, err := appliedMigrations(db)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]MigrationStatus, 0, len(migrations))
+	for _, migration := range migrations {
+		appliedAt, done := applied[migration.Version]
+		statuses = append(statuses, MigrationStatus{Migration: migration, Applied: done, AppliedAt: appliedAt})
+	}
+
+	return statuses, nil
+}
+
+// appliedMigrations makes sure the schema_migrations table exists and returns
+// the applied versions mapped to the time they were applied.
+func appliedMigrations(db *DB) (map[int]time.Time, error) {
+	_, err := db.Exec(`CREATE TABLE IF NOT EXISTS schema_migrations (
+		version INTEGER PRIMARY KEY,
+		name TEXT NOT NULL,
+		applied_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+	)`)
+	if err != nil {
+		return nil, fmt.Errorf("could not create schema_migrations table: %w", err)
+	}
+
+	rows, err := db.Query("SELECT version, applied_at FROM schema_migrations")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	applied := make(map[int]time.Time)
+	for rows.Next() {
+		var version int
+		var appliedAt time.Time
+		if err := rows.Scan(&version, &appliedAt); err != nil {
+			return nil, err
+		}
+		applied[version] = appliedAt
+	}
+
+	return applied, rows.Err()
+}
+
+// runMigration executes one migration script and records (or removes) its
+// schema_migrations row inside a single transaction.
+func runMigration(db *DB, version int, name, script string, up bool) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// Scripts are dialect specific already, so they bypass placeholder rewriting
+	if _, err := tx.Tx.Exec(script); err != nil {
+		if strings.Contains(err.Error(), "no such module: fts5") {
+			err = ErrNoFTS5
+		}
+		return fmt.Errorf("migration %04d_%s failed: %w", version, name, err)
+	}
+
+	if up {
+		_, err = tx.Exec("INSERT INTO schema_migrations (version, name) VALUES (?, ?)", version, name)
+	} else {
+		_, err = tx.Exec("DELETE FROM schema_migrations WHERE version = ?", version)
+	}
+	if err != nil {
+		return fmt.Errorf("could not record migration %04d_%s: %w", version, name, err)
+	}
+
+	return tx.Commit()
+}
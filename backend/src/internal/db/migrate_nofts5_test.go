@@ -0,0 +1,30 @@
+//go:build !sqlite_fts5
+
+package db
+
+import (
+	"errors"
+	"testing"
+)
+
+// TestMigrateWithoutFTS5 checks that a driver built without FTS5 stops at the
+// search index with ErrNoFTS5, keeping the migrations applied before it.
+func TestMigrateWithoutFTS5(t *testing.T) {
+	database := openTestDB(t)
+
+	if err := Migrate(database); !errors.Is(err, ErrNoFTS5) {
+		t.Fatalf("migrate = %v, want ErrNoFTS5", err)
+	}
+	statuses, err := MigrationStatuses(database)
+	if err != nil {
+		t.Fatalf("migration statuses: %v", err)
+	}
+	for _, status := range statuses {
+		if want := status.Version == 1; status.Applied != want {
+			t.Errorf("migration %04d_%s applied = %t, want %t", status.Version, status.Name, status.Applied, want)
+		}
+	}
+	if tableExists(t, database, "posts_fts") {
+		t.Error("search index table exists after the failed migration")
+	}
+}
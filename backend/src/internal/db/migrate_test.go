@@ -0,0 +1,109 @@
+package db
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+// openTestDB opens an empty SQLite file, closed when the test ends.
+func openTestDB(t *testing.T) *DB {
+	t.Helper()
+
+	database, err := Open(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("open sqlite: %v", err)
+	}
+	t.Cleanup(func() { database.Close() })
+	return database
+}
+
+// tableExists reports whether the SQLite database has a table with the given name.
+func tableExists(t *testing.T, database *DB, name string) bool {
+	t.Helper()
+
+	var exists bool
+	if err := database.QueryRow("SELECT EXISTS (SELECT 1 FROM sqlite_master WHERE type = 'table' AND name = ?)", name).Scan(&exists); err != nil {
+		t.Fatalf("look up table %s: %v", name, err)
+	}
+	return exists
+}
+
+func TestLoadMigrations(t *testing.T) {
+	sqlite, err := LoadMigrations(SQLite)
+	if err != nil {
+		t.Fatalf("load sqlite migrations: %v", err)
+	}
+	postgres, err := LoadMigrations(Postgres)
+	if err != nil {
+		t.Fatalf("load postgres migrations: %v", err)
+	}
+
+	// Both dialects number the same migrations from 1 without gaps
+	if len(sqlite) == 0 || len(sqlite) != len(postgres) {
+		t.Fatalf("%d sqlite and %d postgres migrations, want the same number", len(sqlite), len(postgres))
+	}
+	for i := range sqlite {
+		if sqlite[i].Version != i+1 || postgres[i].Version != i+1 || sqlite[i].Name != postgres[i].Name {
+			t.Errorf("migration %d is %04d_%s in sqlite and %04d_%s in postgres", i+1, sqlite[i].Version, sqlite[i].Name, postgres[i].Version, postgres[i].Name)
+		}
+	}
+}
+
+func TestMigrate(t *testing.T) {
+	migrations, err := LoadMigrations(SQLite)
+	if err != nil {
+		t.Fatalf("load migrations: %v", err)
+	}
+	latest := len(migrations)
+
+	migrate := func(database *DB) error { return Migrate(database) }
+	rollback := func(steps int) func(*DB) error {
+		return func(database *DB) error { return Rollback(database, steps) }
+	}
+	tests := []struct {
+		name    string
+		steps   []func(*DB) error
+		version int // The last migration applied once the steps are done
+	}{
+		{"apply", []func(*DB) error{migrate}, latest},
+		{"apply again", []func(*DB) error{migrate, migrate}, latest},
+		{"roll back one", []func(*DB) error{migrate, rollback(1)}, latest - 1},
+		{"roll back several", []func(*DB) error{migrate, rollback(3)}, latest - 3},
+		{"roll back all", []func(*DB) error{migrate, rollback(latest)}, 0},
+		{"roll back more than applied", []func(*DB) error{migrate, rollback(latest + 5)}, 0},
+		{"roll back nothing applied", []func(*DB) error{rollback(1)}, 0},
+		{"apply after rolling back", []func(*DB) error{migrate, rollback(latest), migrate}, latest},
+		{"apply after rolling back some", []func(*DB) error{migrate, rollback(3), migrate, migrate}, latest},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			database := openTestDB(t)
+			for i, step := range test.steps {
+				if err := step(database); err != nil {
+					t.Fatalf("step %d: %v", i+1, err)
+				}
+			}
+
+			statuses, err := MigrationStatuses(database)
+			if err != nil {
+				t.Fatalf("migration statuses: %v", err)
+			}
+			if len(statuses) != latest {
+				t.Fatalf("%d statuses, want %d", len(statuses), latest)
+			}
+			for _, status := range statuses {
+				if want := status.Version <= test.version; status.Applied != want || status.AppliedAt.IsZero() == want {
+					t.Errorf("migration %04d_%s applied = %t at %v, want %t", status.Version, status.Name, status.Applied, status.AppliedAt, want)
+				}
+			}
+
+			// The first migration creates the users, the latest the polls
+			if got, want := tableExists(t, database, "users"), test.version >= 1; got != want {
+				t.Errorf("users table exists = %t, want %t", got, want)
+			}
+			if got, want := tableExists(t, database, "polls"), test.version == latest; got != want {
+				t.Errorf("polls table exists = %t, want %t", got, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,9 @@
+-- Drop the tables created by 0001_initial_schema, children before parents.
+DROP TABLE IF EXISTS comment_dislikes;
+DROP TABLE IF EXISTS comment_likes;
+DROP TABLE IF EXISTS post_dislikes;
+DROP TABLE IF EXISTS post_likes;
+DROP TABLE IF EXISTS comments;
+DROP TABLE IF EXISTS posts;
+DROP TABLE IF EXISTS sessions;
+DROP TABLE IF EXISTS users;
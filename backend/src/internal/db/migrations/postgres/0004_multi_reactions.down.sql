@@ -0,0 +1,7 @@
+-- Go back to a single reaction per user and target, keeping only likes and dislikes.
+DELETE FROM reactions WHERE reaction NOT IN ('like', 'dislike');
+
+DROP INDEX IF EXISTS reactions_vote_idx;
+ALTER TABLE reactions DROP CONSTRAINT reactions_user_id_target_type_target_id_reaction_key;
+ALTER TABLE reactions ADD CONSTRAINT reactions_user_id_target_type_target_id_key
+    UNIQUE (user_id, target_type, target_id); -- One reaction per user and target.
@@ -0,0 +1,10 @@
+-- Let users give several reactions to the same post or comment.
+-- Likes and dislikes stay exclusive: a partial unique index allows one of the two
+-- per user and target, while other reactions are only unique per reaction.
+ALTER TABLE reactions DROP CONSTRAINT reactions_user_id_target_type_target_id_key;
+ALTER TABLE reactions ADD CONSTRAINT reactions_user_id_target_type_target_id_reaction_key
+    UNIQUE (user_id, target_type, target_id, reaction); -- Each reaction at most once per user and target.
+
+-- A user either likes or dislikes a target, never both.
+CREATE UNIQUE INDEX IF NOT EXISTS reactions_vote_idx ON reactions (user_id, target_type, target_id)
+WHERE reaction IN ('like', 'dislike');
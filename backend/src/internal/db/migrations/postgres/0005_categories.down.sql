@@ -0,0 +1,9 @@
+-- Bring back the free-text post category and drop the 'categories' table.
+ALTER TABLE posts ADD COLUMN category TEXT;              -- Category of the post, can be null.
+
+UPDATE posts SET category = (SELECT name FROM categories WHERE id = posts.category_id);
+
+DROP INDEX IF EXISTS posts_category_id_idx;
+ALTER TABLE posts DROP COLUMN category_id;
+
+DROP TABLE IF EXISTS categories;
@@ -0,0 +1,41 @@
+-- Turn the free-text post category into a managed 'categories' table.
+
+-- Create the 'categories' table to store the categories posts are filed under.
+CREATE TABLE IF NOT EXISTS categories (
+    id SERIAL PRIMARY KEY,                      -- Unique identifier for each category, auto-incremented.
+    slug TEXT NOT NULL UNIQUE,                  -- URL-friendly identifier of the category, unique.
+    name TEXT NOT NULL,                         -- Display name of the category, unique ignoring case.
+    description TEXT NOT NULL DEFAULT '',       -- Short description shown on the category page.
+    position INTEGER NOT NULL DEFAULT 0,        -- Position of the category in listings, lowest first.
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP  -- Timestamp of category creation, defaults to current time.
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS categories_name_idx ON categories (LOWER(name));
+
+-- The categories the forum offered so far.
+INSERT INTO categories (slug, name, description, position) VALUES
+    ('random', 'Random', 'Anything that does not fit elsewhere.', 1),
+    ('news', 'News', 'Publishing news, prizes and events.', 2),
+    ('sport', 'Sport', 'Sports writing and sporting lives.', 3),
+    ('technology', 'Technology', 'Technology in and around books.', 4),
+    ('science', 'Science', 'Popular science and science fiction.', 5),
+    ('health', 'Health', 'Health, wellbeing and the reading life.', 6);
+
+-- Keep any other category already used by a post, after the ones above.
+INSERT INTO categories (slug, name, position)
+SELECT LOWER(REPLACE(TRIM(category), ' ', '-')), MIN(TRIM(category)), 100
+FROM posts
+WHERE TRIM(COALESCE(category, '')) <> ''
+  AND LOWER(REPLACE(TRIM(category), ' ', '-')) NOT IN (SELECT slug FROM categories)
+GROUP BY LOWER(REPLACE(TRIM(category), ' ', '-'));
+
+-- Point every post at its category and drop the free-text column.
+ALTER TABLE posts ADD COLUMN category_id INTEGER REFERENCES categories(id);
+
+UPDATE posts SET category_id = (
+    SELECT id FROM categories WHERE slug = LOWER(REPLACE(TRIM(posts.category), ' ', '-'))
+);
+
+ALTER TABLE posts DROP COLUMN category;
+
+CREATE INDEX IF NOT EXISTS posts_category_id_idx ON posts (category_id);
@@ -0,0 +1,4 @@
+-- Flatten the comment threads.
+DROP INDEX IF EXISTS comments_parent_id_idx;
+ALTER TABLE comments DROP COLUMN IF EXISTS depth;
+ALTER TABLE comments DROP COLUMN IF EXISTS parent_id;
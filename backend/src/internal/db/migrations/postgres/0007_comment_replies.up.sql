@@ -0,0 +1,6 @@
+-- Let comments reply to other comments, forming threads under a post.
+ALTER TABLE comments ADD COLUMN IF NOT EXISTS parent_id INTEGER REFERENCES comments(id); -- The comment replied to, null for a comment on the post itself.
+ALTER TABLE comments ADD COLUMN IF NOT EXISTS depth INTEGER NOT NULL DEFAULT 0;          -- Number of comments above in the thread, 0 for a comment on the post.
+
+-- Loading the replies of a comment is the common read.
+CREATE INDEX IF NOT EXISTS comments_parent_id_idx ON comments (parent_id);
@@ -0,0 +1,3 @@
+-- Forget comment edits and removals. Removed comments stay, with their text gone.
+ALTER TABLE comments DROP COLUMN IF EXISTS deleted_at;
+ALTER TABLE comments DROP COLUMN IF EXISTS edited_at;
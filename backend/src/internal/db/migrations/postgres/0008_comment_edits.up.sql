@@ -0,0 +1,3 @@
+-- Let authors edit and remove their comments.
+ALTER TABLE comments ADD COLUMN IF NOT EXISTS edited_at TIMESTAMP;   -- Timestamp of the last edit, null if never edited.
+ALTER TABLE comments ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP;  -- Timestamp of the removal of a comment kept for its replies, null otherwise.
@@ -0,0 +1,4 @@
+-- Drop the image attachments. The stored files stay on disk.
+DROP INDEX IF EXISTS post_attachments_attachment_id_idx;
+DROP TABLE IF EXISTS post_attachments;
+DROP TABLE IF EXISTS attachments;
@@ -0,0 +1,29 @@
+-- Let members attach images, such as book covers and photos, to their posts.
+
+-- Create the 'attachments' table to store the uploaded images.
+CREATE TABLE IF NOT EXISTS attachments (
+    id SERIAL PRIMARY KEY,                      -- Unique identifier for each upload, auto-incremented.
+    user_id INTEGER NOT NULL,                   -- Foreign key referencing the 'users' table, the uploader.
+    file_key TEXT NOT NULL,                     -- Storage key of the image, derived from its content.
+    thumbnail_key TEXT NOT NULL,                -- Storage key of the thumbnail, derived from its content.
+    content_type TEXT NOT NULL,                 -- MIME type of the image.
+    size INTEGER NOT NULL,                      -- Size of the image in bytes.
+    width INTEGER NOT NULL,                     -- Width of the image in pixels.
+    height INTEGER NOT NULL,                    -- Height of the image in pixels.
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP, -- Timestamp of the upload, defaults to current time.
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE -- Removed along with the uploader.
+);
+
+-- Create the 'post_attachments' table linking posts to their images.
+CREATE TABLE IF NOT EXISTS post_attachments (
+    post_id INTEGER NOT NULL,                   -- Foreign key referencing the 'posts' table.
+    attachment_id INTEGER NOT NULL,             -- Foreign key referencing the 'attachments' table.
+    position INTEGER NOT NULL,                  -- Order of the image in the post, from 0.
+    cover BOOLEAN NOT NULL DEFAULT FALSE,       -- Whether the image is the cover of the post, shown in the listings.
+    PRIMARY KEY (post_id, attachment_id),       -- A post carries an image at most once.
+    FOREIGN KEY (post_id) REFERENCES posts(id) ON DELETE CASCADE,           -- Removed along with the post.
+    FOREIGN KEY (attachment_id) REFERENCES attachments(id) ON DELETE CASCADE -- Removed along with the image.
+);
+
+-- The cleanup looks up the images no post uses.
+CREATE INDEX IF NOT EXISTS post_attachments_attachment_id_idx ON post_attachments (attachment_id);
@@ -0,0 +1,8 @@
+-- Drop the profile fields. The avatar files are removed by the next cleanup of
+-- unused uploads.
+ALTER TABLE users DROP COLUMN IF EXISTS created_at;
+ALTER TABLE users DROP COLUMN IF EXISTS avatar_key;
+ALTER TABLE users DROP COLUMN IF EXISTS favorite_genres;
+ALTER TABLE users DROP COLUMN IF EXISTS favorite_books;
+ALTER TABLE users DROP COLUMN IF EXISTS location;
+ALTER TABLE users DROP COLUMN IF EXISTS bio;
@@ -0,0 +1,15 @@
+-- Let members tell others about themselves on their profile.
+ALTER TABLE users ADD COLUMN IF NOT EXISTS bio TEXT NOT NULL DEFAULT '';             -- A few words about the member.
+ALTER TABLE users ADD COLUMN IF NOT EXISTS location TEXT NOT NULL DEFAULT '';        -- Where the member reads from.
+ALTER TABLE users ADD COLUMN IF NOT EXISTS favorite_books TEXT NOT NULL DEFAULT '';  -- Favorite books, one per line.
+ALTER TABLE users ADD COLUMN IF NOT EXISTS favorite_genres TEXT NOT NULL DEFAULT ''; -- Favorite genres, one per line.
+ALTER TABLE users ADD COLUMN IF NOT EXISTS avatar_key TEXT NOT NULL DEFAULT '';      -- Storage key of the profile picture, empty for none.
+ALTER TABLE users ADD COLUMN IF NOT EXISTS created_at TIMESTAMP;                     -- When the member joined.
+
+-- Members who joined earlier are taken to have joined with their first post
+-- or comment, or now if they have written nothing yet.
+UPDATE users SET created_at = COALESCE(
+    (SELECT MIN(activity.created_at) FROM (
+        SELECT created_at FROM posts WHERE user_id = users.id
+        UNION ALL SELECT created_at FROM comments WHERE user_id = users.id) AS activity),
+    CURRENT_TIMESTAMP);
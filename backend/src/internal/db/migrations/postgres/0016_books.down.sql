@@ -0,0 +1,10 @@
+-- Drop the book catalog and the links from posts to books.
+DROP INDEX IF EXISTS post_books_book_id_idx;
+DROP TABLE IF EXISTS post_books;
+DROP INDEX IF EXISTS books_isbn_key;
+DROP TABLE IF EXISTS books;
+
+-- Curators go back to being plain users, and the role constraint to what it was.
+UPDATE users SET role = 'user' WHERE role = 'curator';
+ALTER TABLE users DROP CONSTRAINT IF EXISTS users_role_check;
+ALTER TABLE users ADD CONSTRAINT users_role_check CHECK (role IN ('user', 'admin'));
@@ -0,0 +1,3 @@
+-- Drop the reading shelves of members.
+DROP INDEX IF EXISTS reading_shelves_finished_idx;
+DROP TABLE IF EXISTS reading_shelves;
@@ -0,0 +1,19 @@
+-- Let members keep the books they want to read, are reading and have finished on shelves.
+
+-- Create the 'reading_shelves' table to store the shelf of each book a member keeps, with their progress.
+CREATE TABLE IF NOT EXISTS reading_shelves (
+    id SERIAL PRIMARY KEY,                      -- Unique identifier for each entry, auto-incremented.
+    user_id INTEGER NOT NULL,                   -- Foreign key referencing the 'users' table, the reader.
+    book_id INTEGER NOT NULL,                   -- Foreign key referencing the 'books' table, the book shelved.
+    shelf TEXT NOT NULL CHECK (shelf IN ('want_to_read', 'reading', 'finished')), -- Shelf the book is on.
+    progress INTEGER NOT NULL DEFAULT 0 CHECK (progress BETWEEN 0 AND 100), -- Percentage of the book read.
+    started_at TIMESTAMP,                       -- When the member started reading, NULL if not yet.
+    finished_at TIMESTAMP,                      -- When the member finished the book, NULL unless finished.
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP, -- Time of the last change, defaults to current time.
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE, -- Removed along with the reader.
+    FOREIGN KEY (book_id) REFERENCES books(id) ON DELETE CASCADE, -- Removed along with the book.
+    UNIQUE (user_id, book_id)                   -- A book is on one shelf of a member at a time.
+);
+
+-- Index the finished books of each member, counted by the yearly reading stats.
+CREATE INDEX IF NOT EXISTS reading_shelves_finished_idx ON reading_shelves (user_id, finished_at);
@@ -0,0 +1,17 @@
+-- Without clubs, their posts would show in the forum, so they go away along with
+-- their comments, the reactions to both, their tags, revisions, images and books.
+DELETE FROM reactions WHERE target_type = 'comment' AND target_id IN (SELECT id FROM comments WHERE post_id IN (SELECT id FROM posts WHERE club_id IS NOT NULL));
+DELETE FROM reactions WHERE target_type = 'post' AND target_id IN (SELECT id FROM posts WHERE club_id IS NOT NULL);
+DELETE FROM comments WHERE post_id IN (SELECT id FROM posts WHERE club_id IS NOT NULL);
+DELETE FROM post_tags WHERE post_id IN (SELECT id FROM posts WHERE club_id IS NOT NULL);
+DELETE FROM post_revisions WHERE post_id IN (SELECT id FROM posts WHERE club_id IS NOT NULL);
+DELETE FROM post_attachments WHERE post_id IN (SELECT id FROM posts WHERE club_id IS NOT NULL);
+DELETE FROM post_books WHERE post_id IN (SELECT id FROM posts WHERE club_id IS NOT NULL);
+DELETE FROM posts WHERE club_id IS NOT NULL;
+
+DROP INDEX IF EXISTS posts_club_id_idx;
+ALTER TABLE posts DROP COLUMN IF EXISTS club_id;
+
+DROP INDEX IF EXISTS club_members_user_id_idx;
+DROP TABLE IF EXISTS club_members;
+DROP TABLE IF EXISTS clubs;
@@ -0,0 +1,36 @@
+-- Let clubs read books together on a schedule, with discussions gated by section.
+
+-- Create the 'club_sections' table to store the reading schedules of clubs. Each
+-- section, such as a few chapters of the book being read, opens for discussion on
+-- its unlock date.
+CREATE TABLE IF NOT EXISTS club_sections (
+    id SERIAL PRIMARY KEY,                      -- Unique identifier for each section, auto-incremented.
+    club_id INTEGER NOT NULL,                   -- Foreign key referencing the 'clubs' table.
+    title TEXT NOT NULL,                        -- What the section covers, such as "Chapters 1-5".
+    book_id INTEGER,                            -- Foreign key referencing the 'books' table, the book read, if any.
+    unlocks_at TIMESTAMP NOT NULL,              -- When the section opens for discussion.
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP, -- Timestamp of the creation, defaults to current time.
+    FOREIGN KEY (club_id) REFERENCES clubs(id) ON DELETE CASCADE, -- Removed along with the club.
+    FOREIGN KEY (book_id) REFERENCES books(id) ON DELETE SET NULL -- Kept when the book leaves the catalog.
+);
+
+-- Schedules are listed per club, in the order the sections unlock.
+CREATE INDEX IF NOT EXISTS club_sections_club_id_idx ON club_sections (club_id, unlocks_at);
+
+-- Create the 'club_section_reads' table to store the sections each member has
+-- read, whose posts are shown to them.
+CREATE TABLE IF NOT EXISTS club_section_reads (
+    section_id INTEGER NOT NULL,                -- Foreign key referencing the 'club_sections' table.
+    user_id INTEGER NOT NULL,                   -- Foreign key referencing the 'users' table, the reader.
+    read_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP, -- When the member marked the section read.
+    PRIMARY KEY (section_id, user_id),          -- A member reads a section once.
+    FOREIGN KEY (section_id) REFERENCES club_sections(id) ON DELETE CASCADE, -- Removed along with the section.
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE           -- Removed along with the member.
+);
+
+-- Posts of a club may discuss a section of its schedule, and are hidden from the
+-- members who have not read it. As with clubs, the section is not declared as a
+-- foreign key, as on SQLite, which cannot drop such a column.
+ALTER TABLE posts ADD COLUMN section_id INTEGER; -- The section the post discusses, null for none.
+
+CREATE INDEX IF NOT EXISTS posts_section_id_idx ON posts (section_id);
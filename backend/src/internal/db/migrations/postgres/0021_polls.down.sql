@@ -0,0 +1,6 @@
+-- The posts stay, without their polls.
+DROP TABLE IF EXISTS poll_votes;
+DROP TABLE IF EXISTS poll_voters;
+DROP INDEX IF EXISTS poll_options_poll_id_idx;
+DROP TABLE IF EXISTS poll_options;
+DROP TABLE IF EXISTS polls;
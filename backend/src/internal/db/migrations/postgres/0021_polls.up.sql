@@ -0,0 +1,44 @@
+-- Let posts carry a poll, such as a vote on the next book to read.
+
+-- Create the 'polls' table to store the poll of a post, at most one per post.
+CREATE TABLE IF NOT EXISTS polls (
+    id SERIAL PRIMARY KEY,                      -- Unique identifier for each poll, auto-incremented.
+    post_id INTEGER NOT NULL UNIQUE,            -- Foreign key referencing the 'posts' table, the post carrying the poll.
+    question TEXT NOT NULL,                     -- The question asked.
+    multiple BOOLEAN NOT NULL DEFAULT FALSE,    -- Whether voters may pick several options.
+    anonymous BOOLEAN NOT NULL DEFAULT FALSE,   -- Whether the voters of each option are kept hidden.
+    closes_at TIMESTAMP,                        -- When voting ends, null to keep the poll open.
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP, -- Timestamp of the creation, defaults to current time.
+    FOREIGN KEY (post_id) REFERENCES posts(id) ON DELETE CASCADE -- Removed along with the post.
+);
+
+-- Create the 'poll_options' table to store the options of the polls, in order.
+CREATE TABLE IF NOT EXISTS poll_options (
+    id SERIAL PRIMARY KEY,                      -- Unique identifier for each option, auto-incremented.
+    poll_id INTEGER NOT NULL,                   -- Foreign key referencing the 'polls' table.
+    label TEXT NOT NULL,                        -- The answer offered, such as a book title.
+    position INTEGER NOT NULL,                  -- Order of the option in the poll, from 0.
+    FOREIGN KEY (poll_id) REFERENCES polls(id) ON DELETE CASCADE -- Removed along with the poll.
+);
+
+CREATE INDEX IF NOT EXISTS poll_options_poll_id_idx ON poll_options (poll_id, position);
+
+-- Create the 'poll_voters' table to store who voted in each poll. Its unique key
+-- lets each member vote once per poll.
+CREATE TABLE IF NOT EXISTS poll_voters (
+    poll_id INTEGER NOT NULL,                   -- Foreign key referencing the 'polls' table.
+    user_id INTEGER NOT NULL,                   -- Foreign key referencing the 'users' table, the voter.
+    voted_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP, -- When the member voted.
+    UNIQUE (poll_id, user_id),                  -- A member votes once per poll.
+    FOREIGN KEY (poll_id) REFERENCES polls(id) ON DELETE CASCADE, -- Removed along with the poll.
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE  -- Removed along with the member.
+);
+
+-- Create the 'poll_votes' table to store the options each voter picked.
+CREATE TABLE IF NOT EXISTS poll_votes (
+    option_id INTEGER NOT NULL,                 -- Foreign key referencing the 'poll_options' table.
+    user_id INTEGER NOT NULL,                   -- Foreign key referencing the 'users' table, the voter.
+    PRIMARY KEY (option_id, user_id),           -- An option is picked once per voter.
+    FOREIGN KEY (option_id) REFERENCES poll_options(id) ON DELETE CASCADE, -- Removed along with the option.
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE          -- Removed along with the member.
+);
@@ -0,0 +1,60 @@
+-- Restore the four like/dislike tables from the 'reactions' table.
+
+-- Create the 'post_likes' table to track likes on posts by users.
+CREATE TABLE IF NOT EXISTS post_likes (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,       -- Unique identifier for each post like, auto-incremented.
+    user_id INTEGER NOT NULL,                   -- Foreign key referencing the 'users' table, links like to a user.
+    post_id INTEGER,                            -- Foreign key referencing the 'posts' table, links like to a post.
+    is_like BOOLEAN NOT NULL,                   -- Boolean indicating whether it is a like (true) or not (false).
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,  -- Timestamp of like creation, defaults to current time.
+    FOREIGN KEY (user_id) REFERENCES users(id), -- Ensure user_id corresponds to a valid user in the 'users' table.
+    FOREIGN KEY (post_id) REFERENCES posts(id)  -- Ensure post_id corresponds to a valid post in the 'posts' table.
+);
+
+-- Create the 'post_dislikes' table to track dislikes on posts by users.
+CREATE TABLE IF NOT EXISTS post_dislikes (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,       -- Unique identifier for each post dislike, auto-incremented.
+    user_id INTEGER NOT NULL,                   -- Foreign key referencing the 'users' table, links dislike to a user.
+    post_id INTEGER,                            -- Foreign key referencing the 'posts' table, links dislike to a post.
+    is_dislike BOOLEAN NOT NULL,                -- Boolean indicating whether it is a dislike (true) or not (false).
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,  -- Timestamp of dislike creation, defaults to current time.
+    FOREIGN KEY (user_id) REFERENCES users(id), -- Ensure user_id corresponds to a valid user in the 'users' table.
+    FOREIGN KEY (post_id) REFERENCES posts(id)  -- Ensure post_id corresponds to a valid post in the 'posts' table.
+);
+
+-- Create the 'comment_likes' table to track likes on comments by users.
+CREATE TABLE IF NOT EXISTS comment_likes (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,       -- Unique identifier for each comment like, auto-incremented.
+    user_id INTEGER NOT NULL,                   -- Foreign key referencing the 'users' table, links like to a user.
+    comment_id INTEGER,                         -- Foreign key referencing the 'comments' table, links like to a comment.
+    is_like BOOLEAN NOT NULL,                   -- Boolean indicating whether it is a like (true) or not (false).
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,  -- Timestamp of like creation, defaults to current time.
+    FOREIGN KEY (user_id) REFERENCES users(id), -- Ensure user_id corresponds to a valid user in the 'users' table.
+    FOREIGN KEY (comment_id) REFERENCES comments(id) -- Ensure comment_id corresponds to a valid comment in the 'comments' table.
+);
+
+-- Create the 'comment_dislikes' table to track dislikes on comments by users.
+CREATE TABLE IF NOT EXISTS comment_dislikes (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,       -- Unique identifier for each comment dislike, auto-incremented.
+    user_id INTEGER NOT NULL,                   -- Foreign key referencing the 'users' table, links dislike to a user.
+    comment_id INTEGER,                         -- Foreign key referencing the 'comments' table, links dislike to a comment.
+    is_dislike BOOLEAN NOT NULL,                -- Boolean indicating whether it is a dislike (true) or not (false).
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,  -- Timestamp of dislike creation, defaults to current time.
+    FOREIGN KEY (user_id) REFERENCES users(id), -- Ensure user_id corresponds to a valid user in the 'users' table.
+    FOREIGN KEY (comment_id) REFERENCES comments(id) -- Ensure comment_id corresponds to a valid comment in the 'comments' table.
+);
+
+INSERT INTO post_likes (user_id, post_id, is_like, created_at)
+SELECT user_id, target_id, TRUE, created_at FROM reactions WHERE target_type = 'post' AND reaction = 'like';
+
+INSERT INTO post_dislikes (user_id, post_id, is_dislike, created_at)
+SELECT user_id, target_id, TRUE, created_at FROM reactions WHERE target_type = 'post' AND reaction = 'dislike';
+
+INSERT INTO comment_likes (user_id, comment_id, is_like, created_at)
+SELECT user_id, target_id, TRUE, created_at FROM reactions WHERE target_type = 'comment' AND reaction = 'like';
+
+INSERT INTO comment_dislikes (user_id, comment_id, is_dislike, created_at)
+SELECT user_id, target_id, TRUE, created_at FROM reactions WHERE target_type = 'comment' AND reaction = 'dislike';
+
+DROP INDEX IF EXISTS reactions_target_idx;
+DROP TABLE IF EXISTS reactions;
@@ -0,0 +1,53 @@
+-- Replace the four like/dislike tables with a single 'reactions' table.
+-- A user holds at most one reaction per post or comment, so liking and disliking
+-- the same target at once is ruled out by the unique constraint.
+CREATE TABLE IF NOT EXISTS reactions (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,       -- Unique identifier for each reaction, auto-incremented.
+    user_id INTEGER NOT NULL,                   -- Foreign key referencing the 'users' table, links reaction to a user.
+    target_type TEXT NOT NULL CHECK (target_type IN ('post', 'comment')), -- Kind of record reacted to.
+    target_id INTEGER NOT NULL,                 -- ID of the post or comment reacted to.
+    reaction TEXT NOT NULL,                     -- The reaction given, 'like' or 'dislike'.
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,  -- Timestamp of the reaction, defaults to current time.
+    FOREIGN KEY (user_id) REFERENCES users(id), -- Ensure user_id corresponds to a valid user in the 'users' table.
+    UNIQUE (user_id, target_type, target_id)    -- One reaction per user and target.
+);
+
+-- Counting the reactions on a target is the common read.
+CREATE INDEX IF NOT EXISTS reactions_target_idx ON reactions (target_type, target_id, reaction);
+
+-- Carry over the active likes and dislikes. The old tables could hold both a like
+-- and a dislike from the same user; the most recent of the two wins.
+INSERT INTO reactions (user_id, target_type, target_id, reaction, created_at)
+SELECT user_id, 'post', post_id, 'like', MAX(created_at)
+FROM post_likes
+WHERE is_like = TRUE AND post_id IS NOT NULL
+GROUP BY user_id, post_id;
+
+INSERT INTO reactions (user_id, target_type, target_id, reaction, created_at)
+SELECT user_id, 'post', post_id, 'dislike', MAX(created_at)
+FROM post_dislikes
+WHERE is_dislike = TRUE AND post_id IS NOT NULL
+GROUP BY user_id, post_id
+ON CONFLICT (user_id, target_type, target_id) DO UPDATE
+SET reaction = excluded.reaction, created_at = excluded.created_at
+WHERE excluded.created_at > reactions.created_at;
+
+INSERT INTO reactions (user_id, target_type, target_id, reaction, created_at)
+SELECT user_id, 'comment', comment_id, 'like', MAX(created_at)
+FROM comment_likes
+WHERE is_like = TRUE AND comment_id IS NOT NULL
+GROUP BY user_id, comment_id;
+
+INSERT INTO reactions (user_id, target_type, target_id, reaction, created_at)
+SELECT user_id, 'comment', comment_id, 'dislike', MAX(created_at)
+FROM comment_dislikes
+WHERE is_dislike = TRUE AND comment_id IS NOT NULL
+GROUP BY user_id, comment_id
+ON CONFLICT (user_id, target_type, target_id) DO UPDATE
+SET reaction = excluded.reaction, created_at = excluded.created_at
+WHERE excluded.created_at > reactions.created_at;
+
+DROP TABLE IF EXISTS comment_dislikes;
+DROP TABLE IF EXISTS comment_likes;
+DROP TABLE IF EXISTS post_dislikes;
+DROP TABLE IF EXISTS post_likes;
@@ -0,0 +1,20 @@
+-- Go back to a single reaction per user and target, keeping only likes and dislikes.
+CREATE TABLE reactions_old (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,       -- Unique identifier for each reaction, auto-incremented.
+    user_id INTEGER NOT NULL,                   -- Foreign key referencing the 'users' table, links reaction to a user.
+    target_type TEXT NOT NULL CHECK (target_type IN ('post', 'comment')), -- Kind of record reacted to.
+    target_id INTEGER NOT NULL,                 -- ID of the post or comment reacted to.
+    reaction TEXT NOT NULL,                     -- The reaction given, 'like' or 'dislike'.
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,  -- Timestamp of the reaction, defaults to current time.
+    FOREIGN KEY (user_id) REFERENCES users(id), -- Ensure user_id corresponds to a valid user in the 'users' table.
+    UNIQUE (user_id, target_type, target_id)    -- One reaction per user and target.
+);
+
+INSERT INTO reactions_old (id, user_id, target_type, target_id, reaction, created_at)
+SELECT id, user_id, target_type, target_id, reaction, created_at FROM reactions
+WHERE reaction IN ('like', 'dislike');
+
+DROP TABLE reactions;
+ALTER TABLE reactions_old RENAME TO reactions;
+
+CREATE INDEX IF NOT EXISTS reactions_target_idx ON reactions (target_type, target_id, reaction);
@@ -0,0 +1,27 @@
+-- Let users give several reactions to the same post or comment.
+-- Likes and dislikes stay exclusive: a partial unique index allows one of the two
+-- per user and target, while other reactions are only unique per reaction.
+-- SQLite cannot drop a table constraint, so the table is rebuilt.
+CREATE TABLE reactions_new (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,       -- Unique identifier for each reaction, auto-incremented.
+    user_id INTEGER NOT NULL,                   -- Foreign key referencing the 'users' table, links reaction to a user.
+    target_type TEXT NOT NULL CHECK (target_type IN ('post', 'comment')), -- Kind of record reacted to.
+    target_id INTEGER NOT NULL,                 -- ID of the post or comment reacted to.
+    reaction TEXT NOT NULL,                     -- The reaction given, e.g. 'like', 'dislike' or 'insightful'.
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,  -- Timestamp of the reaction, defaults to current time.
+    FOREIGN KEY (user_id) REFERENCES users(id), -- Ensure user_id corresponds to a valid user in the 'users' table.
+    UNIQUE (user_id, target_type, target_id, reaction) -- Each reaction at most once per user and target.
+);
+
+INSERT INTO reactions_new (id, user_id, target_type, target_id, reaction, created_at)
+SELECT id, user_id, target_type, target_id, reaction, created_at FROM reactions;
+
+DROP TABLE reactions;
+ALTER TABLE reactions_new RENAME TO reactions;
+
+-- Counting the reactions on a target is the common read.
+CREATE INDEX IF NOT EXISTS reactions_target_idx ON reactions (target_type, target_id, reaction);
+
+-- A user either likes or dislikes a target, never both.
+CREATE UNIQUE INDEX IF NOT EXISTS reactions_vote_idx ON reactions (user_id, target_type, target_id)
+WHERE reaction IN ('like', 'dislike');
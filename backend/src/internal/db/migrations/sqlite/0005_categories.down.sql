@@ -0,0 +1,35 @@
+-- Bring back the free-text post category and drop the 'categories' table.
+-- SQLite cannot drop a column with a foreign key, so the posts table is rebuilt
+-- and the triggers keeping the search index in sync are created again.
+CREATE TABLE posts_old (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,       -- Unique identifier for each post, auto-incremented.
+    user_id INTEGER NOT NULL,                   -- Foreign key referencing the 'users' table, links post to a user.
+    category TEXT,                              -- Category of the post, can be null.
+    title TEXT NOT NULL,                        -- Title of the post, not null.
+    content TEXT NOT NULL,                      -- Content of the post, not null.
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,  -- Timestamp of post creation, defaults to current time.
+    FOREIGN KEY (user_id) REFERENCES users(id)  -- Ensure user_id corresponds to a valid user in the 'users' table.
+);
+
+INSERT INTO posts_old (id, user_id, category, title, content, created_at)
+SELECT p.id, p.user_id, c.name, p.title, p.content, p.created_at
+FROM posts p
+LEFT JOIN categories c ON c.id = p.category_id;
+
+DROP TABLE posts;
+ALTER TABLE posts_old RENAME TO posts;
+
+CREATE TRIGGER IF NOT EXISTS posts_fts_after_insert AFTER INSERT ON posts BEGIN
+    INSERT INTO posts_fts (rowid, title, content) VALUES (new.id, new.title, new.content);
+END;
+
+CREATE TRIGGER IF NOT EXISTS posts_fts_after_delete AFTER DELETE ON posts BEGIN
+    INSERT INTO posts_fts (posts_fts, rowid, title, content) VALUES ('delete', old.id, old.title, old.content);
+END;
+
+CREATE TRIGGER IF NOT EXISTS posts_fts_after_update AFTER UPDATE OF title, content ON posts BEGIN
+    INSERT INTO posts_fts (posts_fts, rowid, title, content) VALUES ('delete', old.id, old.title, old.content);
+    INSERT INTO posts_fts (rowid, title, content) VALUES (new.id, new.title, new.content);
+END;
+
+DROP TABLE IF EXISTS categories;
@@ -0,0 +1,4 @@
+-- Drop the post tags.
+DROP INDEX IF EXISTS post_tags_tag_id_idx;
+DROP TABLE IF EXISTS post_tags;
+DROP TABLE IF EXISTS tags;
@@ -0,0 +1,20 @@
+-- Add free-form tags on posts, such as a genre, an author, an era or a book title.
+
+-- Create the 'tags' table to store every tag used on a post.
+CREATE TABLE IF NOT EXISTS tags (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,       -- Unique identifier for each tag, auto-incremented.
+    slug TEXT NOT NULL UNIQUE,                  -- The tag itself, lowercase words joined by dashes, unique.
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP   -- Timestamp of the first use of the tag, defaults to current time.
+);
+
+-- Create the 'post_tags' table linking posts to their tags.
+CREATE TABLE IF NOT EXISTS post_tags (
+    post_id INTEGER NOT NULL,                   -- Foreign key referencing the 'posts' table.
+    tag_id INTEGER NOT NULL,                    -- Foreign key referencing the 'tags' table.
+    PRIMARY KEY (post_id, tag_id),              -- A post carries a tag at most once.
+    FOREIGN KEY (post_id) REFERENCES posts(id) ON DELETE CASCADE, -- Removed along with the post.
+    FOREIGN KEY (tag_id) REFERENCES tags(id) ON DELETE CASCADE    -- Removed along with the tag.
+);
+
+-- Listing the posts of a tag is the common read.
+CREATE INDEX IF NOT EXISTS post_tags_tag_id_idx ON post_tags (tag_id);
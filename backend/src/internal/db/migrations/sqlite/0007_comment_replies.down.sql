@@ -0,0 +1,32 @@
+-- Flatten the comment threads.
+-- SQLite cannot drop a column with a foreign key, so the comments table is rebuilt
+-- and the triggers keeping the search index in sync are created again.
+CREATE TABLE comments_old (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,       -- Unique identifier for each comment, auto-incremented.
+    post_id INTEGER NOT NULL,                   -- Foreign key referencing the 'posts' table, links comment to a post.
+    user_id INTEGER NOT NULL,                   -- Foreign key referencing the 'users' table, links comment to a user.
+    content TEXT NOT NULL,                      -- Content of the comment, not null.
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,  -- Timestamp of comment creation, defaults to current time.
+    FOREIGN KEY (post_id) REFERENCES posts(id), -- Ensure post_id corresponds to a valid post in the 'posts' table.
+    FOREIGN KEY (user_id) REFERENCES users(id)  -- Ensure user_id corresponds to a valid user in the 'users' table.
+);
+
+INSERT INTO comments_old (id, post_id, user_id, content, created_at)
+SELECT id, post_id, user_id, content, created_at FROM comments;
+
+DROP INDEX IF EXISTS comments_parent_id_idx;
+DROP TABLE comments;
+ALTER TABLE comments_old RENAME TO comments;
+
+CREATE TRIGGER IF NOT EXISTS comments_fts_after_insert AFTER INSERT ON comments BEGIN
+    INSERT INTO comments_fts (rowid, content) VALUES (new.id, new.content);
+END;
+
+CREATE TRIGGER IF NOT EXISTS comments_fts_after_delete AFTER DELETE ON comments BEGIN
+    INSERT INTO comments_fts (comments_fts, rowid, content) VALUES ('delete', old.id, old.content);
+END;
+
+CREATE TRIGGER IF NOT EXISTS comments_fts_after_update AFTER UPDATE OF content ON comments BEGIN
+    INSERT INTO comments_fts (comments_fts, rowid, content) VALUES ('delete', old.id, old.content);
+    INSERT INTO comments_fts (rowid, content) VALUES (new.id, new.content);
+END;
@@ -0,0 +1,3 @@
+-- Let authors edit and remove their comments.
+ALTER TABLE comments ADD COLUMN edited_at DATETIME;   -- Timestamp of the last edit, null if never edited.
+ALTER TABLE comments ADD COLUMN deleted_at DATETIME;  -- Timestamp of the removal of a comment kept for its replies, null otherwise.
@@ -0,0 +1,2 @@
+-- The removed rows cannot be brought back; there is no schema change to undo.
+SELECT 1;
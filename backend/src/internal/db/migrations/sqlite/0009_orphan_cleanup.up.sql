@@ -0,0 +1,6 @@
+-- Remove what deleted posts left behind: their comments, and reactions to posts
+-- and comments that no longer exist. Deleting a post now removes them too.
+DELETE FROM comments WHERE post_id NOT IN (SELECT id FROM posts);
+DELETE FROM post_tags WHERE post_id NOT IN (SELECT id FROM posts);
+DELETE FROM reactions WHERE target_type = 'post' AND target_id NOT IN (SELECT id FROM posts);
+DELETE FROM reactions WHERE target_type = 'comment' AND target_id NOT IN (SELECT id FROM comments);
@@ -0,0 +1,3 @@
+-- Drop the post history.
+ALTER TABLE posts DROP COLUMN edited_at;
+DROP TABLE IF EXISTS post_revisions;
@@ -0,0 +1,24 @@
+-- Keep every version of the posts, so that readers can see what an edit changed.
+
+-- Create the 'post_revisions' table to store the successive versions of each post.
+CREATE TABLE IF NOT EXISTS post_revisions (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,       -- Unique identifier for each revision, auto-incremented.
+    post_id INTEGER NOT NULL,                   -- Foreign key referencing the 'posts' table.
+    revision INTEGER NOT NULL,                  -- 1 for the post as first written, counting up with each edit.
+    title TEXT NOT NULL,                        -- Title of the post in this version.
+    content TEXT NOT NULL,                      -- Content of the post in this version.
+    category_id INTEGER,                        -- Category of the post in this version, null if it has since been deleted.
+    editor_id INTEGER NOT NULL,                 -- Foreign key referencing the 'users' table, the user who wrote this version.
+    restored_from INTEGER,                      -- The revision this version brought back, null for an ordinary edit.
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,  -- Timestamp of the version, defaults to current time.
+    UNIQUE (post_id, revision),                 -- Revisions are numbered per post.
+    FOREIGN KEY (post_id) REFERENCES posts(id) ON DELETE CASCADE, -- Removed along with the post.
+    FOREIGN KEY (editor_id) REFERENCES users(id)
+);
+
+-- Record when a post was last edited.
+ALTER TABLE posts ADD COLUMN edited_at DATETIME;    -- Timestamp of the last edit, null if never edited.
+
+-- The existing posts start their history with their current version.
+INSERT INTO post_revisions (post_id, revision, title, content, category_id, editor_id, created_at)
+SELECT id, 1, title, content, category_id, user_id, created_at FROM posts;
@@ -0,0 +1,24 @@
+-- Without a trash, deleted posts go away along with everything attached to them.
+DELETE FROM reactions WHERE target_type = 'comment' AND target_id IN (SELECT c.id FROM comments c INNER JOIN posts p ON p.id = c.post_id WHERE p.deleted_at IS NOT NULL);
+DELETE FROM reactions WHERE target_type = 'post' AND target_id IN (SELECT id FROM posts WHERE deleted_at IS NOT NULL);
+DELETE FROM comments WHERE post_id IN (SELECT id FROM posts WHERE deleted_at IS NOT NULL);
+DELETE FROM post_tags WHERE post_id IN (SELECT id FROM posts WHERE deleted_at IS NOT NULL);
+DELETE FROM post_revisions WHERE post_id IN (SELECT id FROM posts WHERE deleted_at IS NOT NULL);
+DELETE FROM posts WHERE deleted_at IS NOT NULL;
+
+-- Deleted comments lose their reactions and content, and only those holding the
+-- place of replies are kept.
+DELETE FROM reactions WHERE target_type = 'comment' AND target_id IN (SELECT id FROM comments WHERE deleted_at IS NOT NULL);
+UPDATE comments SET content = '' WHERE deleted_at IS NOT NULL;
+DELETE FROM comments WHERE deleted_at IS NOT NULL AND id NOT IN (
+    WITH RECURSIVE kept (id, parent_id) AS (
+        SELECT id, parent_id FROM comments WHERE deleted_at IS NULL
+        UNION
+        SELECT c.id, c.parent_id FROM comments c INNER JOIN kept ON c.id = kept.parent_id
+    )
+    SELECT id FROM kept
+);
+
+DROP INDEX IF EXISTS comments_deleted_at_idx;
+DROP INDEX IF EXISTS posts_deleted_at_idx;
+ALTER TABLE posts DROP COLUMN deleted_at;
@@ -0,0 +1,9 @@
+-- Keep deleted posts and comments in a trash their authors can restore them from,
+-- until they are purged once the retention window is over.
+
+-- Record when a post was deleted.
+ALTER TABLE posts ADD COLUMN deleted_at DATETIME;    -- Timestamp of the deletion, null while the post is live.
+
+-- Comments already record when they were deleted; the purge looks both up by it.
+CREATE INDEX IF NOT EXISTS posts_deleted_at_idx ON posts (deleted_at);
+CREATE INDEX IF NOT EXISTS comments_deleted_at_idx ON comments (deleted_at);
@@ -0,0 +1,9 @@
+-- Without drafts, unpublished posts go away along with their tags. They have no
+-- comments, reactions or revisions yet.
+DELETE FROM post_tags WHERE post_id IN (SELECT id FROM posts WHERE status <> 'published');
+DELETE FROM post_revisions WHERE post_id IN (SELECT id FROM posts WHERE status <> 'published');
+DELETE FROM posts WHERE status <> 'published';
+
+DROP INDEX IF EXISTS posts_status_publish_at_idx;
+ALTER TABLE posts DROP COLUMN publish_at;
+ALTER TABLE posts DROP COLUMN status;
@@ -0,0 +1,6 @@
+-- Let authors keep posts as drafts, or schedule them, before they go live.
+ALTER TABLE posts ADD COLUMN status TEXT NOT NULL DEFAULT 'published' CHECK (status IN ('draft', 'scheduled', 'published')); -- Whether the post is live.
+ALTER TABLE posts ADD COLUMN publish_at DATETIME;   -- When a scheduled post goes live, null for the others.
+
+-- The scheduler looks up the scheduled posts that are due.
+CREATE INDEX IF NOT EXISTS posts_status_publish_at_idx ON posts (status, publish_at);
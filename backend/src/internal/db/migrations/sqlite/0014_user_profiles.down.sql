@@ -0,0 +1,8 @@
+-- Drop the profile fields. The avatar files are removed by the next cleanup of
+-- unused uploads.
+ALTER TABLE users DROP COLUMN created_at;
+ALTER TABLE users DROP COLUMN avatar_key;
+ALTER TABLE users DROP COLUMN favorite_genres;
+ALTER TABLE users DROP COLUMN favorite_books;
+ALTER TABLE users DROP COLUMN location;
+ALTER TABLE users DROP COLUMN bio;
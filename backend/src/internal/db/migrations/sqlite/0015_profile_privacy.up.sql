@@ -0,0 +1,2 @@
+-- Let members keep the posts they like to themselves on their public profile.
+ALTER TABLE users ADD COLUMN hide_liked_posts BOOLEAN NOT NULL DEFAULT FALSE; -- Whether others are kept from listing the posts the member likes.
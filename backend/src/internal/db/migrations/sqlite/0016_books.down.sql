@@ -0,0 +1,32 @@
+-- Drop the book catalog and the links from posts to books.
+DROP INDEX IF EXISTS post_books_book_id_idx;
+DROP TABLE IF EXISTS post_books;
+DROP INDEX IF EXISTS books_isbn_key;
+DROP TABLE IF EXISTS books;
+
+-- Curators go back to being plain users, and the role constraint to what it was.
+UPDATE users SET role = 'user' WHERE role = 'curator';
+
+-- The columns from the role on are left without comments: dropping a column
+-- rewrites the CREATE TABLE statement, and a trailing comment could swallow its
+-- closing parenthesis.
+CREATE TABLE users_new (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,       -- Unique identifier for each user, auto-incremented.
+    email TEXT NOT NULL UNIQUE,                 -- User's email, must be unique and not null.
+    username TEXT NOT NULL UNIQUE,              -- User's username, must be unique and not null.
+    password TEXT NOT NULL,                     -- Hashed password for user authentication, not null.
+    role TEXT NOT NULL CHECK (role IN ('user', 'admin')),
+    bio TEXT NOT NULL DEFAULT '',
+    location TEXT NOT NULL DEFAULT '',
+    favorite_books TEXT NOT NULL DEFAULT '',
+    favorite_genres TEXT NOT NULL DEFAULT '',
+    avatar_key TEXT NOT NULL DEFAULT '',
+    created_at DATETIME,
+    hide_liked_posts BOOLEAN NOT NULL DEFAULT FALSE
+);
+
+INSERT INTO users_new (id, email, username, password, role, bio, location, favorite_books, favorite_genres, avatar_key, created_at, hide_liked_posts)
+SELECT id, email, username, password, role, bio, location, favorite_books, favorite_genres, avatar_key, created_at, hide_liked_posts FROM users;
+
+DROP TABLE users;
+ALTER TABLE users_new RENAME TO users;
@@ -0,0 +1,59 @@
+-- Add a catalog of books that posts can discuss, looked after by curators.
+
+-- Curators approve the books members add. SQLite cannot change a CHECK
+-- constraint, so the 'users' table is rebuilt to allow the new role.
+-- The columns from the role on are left without comments: dropping a column
+-- rewrites the CREATE TABLE statement, and a trailing comment could swallow its
+-- closing parenthesis.
+CREATE TABLE users_new (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,       -- Unique identifier for each user, auto-incremented.
+    email TEXT NOT NULL UNIQUE,                 -- User's email, must be unique and not null.
+    username TEXT NOT NULL UNIQUE,              -- User's username, must be unique and not null.
+    password TEXT NOT NULL,                     -- Hashed password for user authentication, not null.
+    role TEXT NOT NULL CHECK (role IN ('user', 'curator', 'admin')),
+    bio TEXT NOT NULL DEFAULT '',
+    location TEXT NOT NULL DEFAULT '',
+    favorite_books TEXT NOT NULL DEFAULT '',
+    favorite_genres TEXT NOT NULL DEFAULT '',
+    avatar_key TEXT NOT NULL DEFAULT '',
+    created_at DATETIME,
+    hide_liked_posts BOOLEAN NOT NULL DEFAULT FALSE
+);
+
+INSERT INTO users_new (id, email, username, password, role, bio, location, favorite_books, favorite_genres, avatar_key, created_at, hide_liked_posts)
+SELECT id, email, username, password, role, bio, location, favorite_books, favorite_genres, avatar_key, created_at, hide_liked_posts FROM users;
+
+DROP TABLE users;
+ALTER TABLE users_new RENAME TO users;
+
+-- Create the 'books' table to store the catalog. Books added by members wait for
+-- an admin or curator to approve them before they are listed.
+CREATE TABLE IF NOT EXISTS books (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,       -- Unique identifier for each book, auto-incremented.
+    title TEXT NOT NULL,                        -- Title of the book.
+    authors TEXT NOT NULL DEFAULT '',           -- Authors of the book, one per line.
+    isbn TEXT NOT NULL DEFAULT '',              -- ISBN-10 or ISBN-13, digits only, empty if unknown.
+    year INTEGER NOT NULL DEFAULT 0,            -- Year of first publication, 0 if unknown.
+    cover_id INTEGER,                           -- Foreign key referencing the 'attachments' table, the cover image.
+    approved BOOLEAN NOT NULL DEFAULT FALSE,    -- Whether an admin or curator accepted the book into the catalog.
+    submitted_by INTEGER,                       -- Foreign key referencing the 'users' table, who added the book.
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,  -- Timestamp of the addition, defaults to current time.
+    FOREIGN KEY (cover_id) REFERENCES attachments(id) ON DELETE SET NULL,
+    FOREIGN KEY (submitted_by) REFERENCES users(id) ON DELETE SET NULL
+);
+
+-- A book is in the catalog once per ISBN.
+CREATE UNIQUE INDEX IF NOT EXISTS books_isbn_key ON books (isbn) WHERE isbn <> '';
+
+-- Create the 'post_books' table linking posts to the books they discuss.
+CREATE TABLE IF NOT EXISTS post_books (
+    post_id INTEGER NOT NULL,                   -- Foreign key referencing the 'posts' table.
+    book_id INTEGER NOT NULL,                   -- Foreign key referencing the 'books' table.
+    position INTEGER NOT NULL,                  -- Order of the book in the post, from 0.
+    PRIMARY KEY (post_id, book_id),             -- A post links a book at most once.
+    FOREIGN KEY (post_id) REFERENCES posts(id) ON DELETE CASCADE, -- Removed along with the post.
+    FOREIGN KEY (book_id) REFERENCES books(id) ON DELETE CASCADE  -- Removed along with the book.
+);
+
+-- Listing the discussions of a book is the common read.
+CREATE INDEX IF NOT EXISTS post_books_book_id_idx ON post_books (book_id);
@@ -0,0 +1,2 @@
+-- Drop the reviews of books.
+DROP TABLE IF EXISTS book_reviews;
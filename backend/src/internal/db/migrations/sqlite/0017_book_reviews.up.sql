@@ -0,0 +1,16 @@
+-- Let members review the books of the catalog with a rating from 1 to 5 stars.
+
+-- Create the 'book_reviews' table to store reviews, one per member and book.
+CREATE TABLE IF NOT EXISTS book_reviews (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,       -- Unique identifier for each review, auto-incremented.
+    book_id INTEGER NOT NULL,                   -- Foreign key referencing the 'books' table, the book reviewed.
+    user_id INTEGER NOT NULL,                   -- Foreign key referencing the 'users' table, the reviewer.
+    rating INTEGER NOT NULL CHECK (rating BETWEEN 1 AND 5), -- Number of stars given to the book.
+    spoiler BOOLEAN NOT NULL DEFAULT FALSE,     -- Whether the text reveals the plot, hidden until asked for.
+    content TEXT NOT NULL DEFAULT '',           -- Text of the review in Markdown, empty for a rating alone.
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,  -- Timestamp of the review, defaults to current time.
+    edited_at DATETIME,                         -- Time of the last edit, NULL if never edited.
+    FOREIGN KEY (book_id) REFERENCES books(id) ON DELETE CASCADE, -- Removed along with the book.
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE, -- Removed along with the reviewer.
+    UNIQUE (book_id, user_id)                   -- A member reviews a book at most once.
+);
@@ -0,0 +1,36 @@
+-- Let members gather in book clubs with discussions of their own.
+
+-- Create the 'clubs' table to store the book clubs. Private clubs show their
+-- posts and members to their members alone.
+CREATE TABLE IF NOT EXISTS clubs (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,       -- Unique identifier for each club, auto-incremented.
+    slug TEXT NOT NULL UNIQUE,                  -- URL-friendly identifier, derived from the name.
+    name TEXT NOT NULL UNIQUE,                  -- Name of the club, must be unique.
+    description TEXT NOT NULL DEFAULT '',       -- What the club reads and talks about.
+    visibility TEXT NOT NULL DEFAULT 'public' CHECK (visibility IN ('public', 'private')), -- Who sees the club.
+    owner_id INTEGER NOT NULL,                  -- Foreign key referencing the 'users' table, who started the club.
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,  -- Timestamp of the creation, defaults to current time.
+    FOREIGN KEY (owner_id) REFERENCES users(id) ON DELETE CASCADE -- Removed along with its owner.
+);
+
+-- Create the 'club_members' table to store who belongs to each club, and the
+-- requests to join private clubs waiting for a moderator.
+CREATE TABLE IF NOT EXISTS club_members (
+    club_id INTEGER NOT NULL,                   -- Foreign key referencing the 'clubs' table.
+    user_id INTEGER NOT NULL,                   -- Foreign key referencing the 'users' table, the member.
+    role TEXT NOT NULL DEFAULT 'member' CHECK (role IN ('member', 'moderator', 'owner')), -- What the member may do.
+    approved BOOLEAN NOT NULL DEFAULT TRUE,     -- False while a request to join a private club waits.
+    joined_at DATETIME DEFAULT CURRENT_TIMESTAMP,   -- When the member joined or asked to, defaults to current time.
+    PRIMARY KEY (club_id, user_id),             -- A member belongs to a club once.
+    FOREIGN KEY (club_id) REFERENCES clubs(id) ON DELETE CASCADE, -- Removed along with the club.
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE  -- Removed along with the member.
+);
+
+-- Listing the clubs of a member, and checking their membership, are the common reads.
+CREATE INDEX IF NOT EXISTS club_members_user_id_idx ON club_members (user_id);
+
+-- Posts in a club are discussed there rather than in the forum. SQLite cannot
+-- drop a column used by a foreign key, so the club is not declared as one.
+ALTER TABLE posts ADD COLUMN club_id INTEGER;   -- The club the post is in, null for the forum.
+
+CREATE INDEX IF NOT EXISTS posts_club_id_idx ON posts (club_id);
@@ -0,0 +1,7 @@
+-- The posts of the sections stay in their clubs, shown to every member.
+DROP INDEX IF EXISTS posts_section_id_idx;
+ALTER TABLE posts DROP COLUMN section_id;
+
+DROP TABLE IF EXISTS club_section_reads;
+DROP INDEX IF EXISTS club_sections_club_id_idx;
+DROP TABLE IF EXISTS club_sections;
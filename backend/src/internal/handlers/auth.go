@@ -1,19 +1,18 @@
 package handlers
 
 import (
-	"database/sql"
-	"literary-lions/backend/src/internal/models"
 	"literary-lions/backend/src/internal/utils"
 	"net/http"
 	"regexp"
+	"slices"
 
 	"github.com/gin-gonic/gin"
 )
 
 // AuthMiddleware is a middleware function that checks if the user is authenticated
-// and optionally checks if the user has the required role.
-// If the user is not authenticated or doesn't have the required role, the request is aborted.
-func AuthMiddleware(requiredRole string) gin.HandlerFunc {
+// and optionally checks if the user has one of the required roles.
+// If the user is not authenticated or doesn't have a required role, the request is aborted.
+func AuthMiddleware(requiredRoles ...string) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// Retrieve the session token from the cookie
 		cookie, err := c.Cookie("session_token")
@@ -26,7 +25,7 @@ func AuthMiddleware(requiredRole string) gin.HandlerFunc {
 
 		// Validate the session token and retrieve the associated user ID
 		token := cookie
-		userID, err := models.ValidateSession(token)
+		userID, err := store.Sessions.Validate(token)
 		if err != nil || userID == 0 {
 			// If the session is invalid or the user ID is 0, return an unauthorized error
 			c.JSON(http.StatusUnauthorized, gin.H{"error": `It seems the session has expired, try <a href="/login">login</a> again`})
@@ -34,12 +33,88 @@ func AuthMiddleware(requiredRole string) gin.HandlerFunc {
 			return
 		}
 
+		// Any logged-in user has the "user" role; other roles are checked against the account
+		if len(requiredRoles) > 0 && !slices.Contains(requiredRoles, "user") {
+			user, err := store.Users.GetByID(userID)
+			if err != nil || user == nil || !slices.Contains(requiredRoles, user.Role) {
+				c.JSON(http.StatusForbidden, gin.H{"error": "You do not have permission to do this"})
+				c.Abort() // Abort the request, no further handlers will be called
+				return
+			}
+		}
+
 		// Store the user ID in the context for further use in the request lifecycle
 		c.Set("userID", userID)
 		c.Next() // Continue to the next handler in the chain
 	}
 }
 
+// sessionUserID returns the ID of the user whose session cookie comes with the
+// request, or 0 for guests and expired sessions. Public routes use it to
+// personalise their response without requiring a login.
+func sessionUserID(c *gin.Context) int {
+	token, err := c.Cookie("session_token")
+	if err != nil {
+		return 0
+	}
+	userID, err := store.Sessions.Validate(token)
+	if err != nil {
+		return 0
+	}
+	return userID
+}
+
+// currentUserID returns the ID of the user AuthMiddleware let through. A handler
+// reached without the middleware has none: it responds with 401 Unauthorized
+// and returns false, rather than acting as user 0.
+func currentUserID(c *gin.Context) (int, bool) {
+	value, exists := c.Get("userID")
+	userID, _ := value.(int)
+	if !exists || userID == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return 0, false
+	}
+	return userID, true
+}
+
+// isAdmin reports whether the user has the admin role.
+func isAdmin(userID int) (bool, error) {
+	user, err := store.Users.GetByID(userID)
+	if err != nil || user == nil {
+		return false, err
+	}
+	return user.Role == "admin", nil
+}
+
+// isCurator reports whether the user looks after the book catalog, as curators
+// and admins do.
+func isCurator(userID int) (bool, error) {
+	user, err := store.Users.GetByID(userID)
+	if err != nil || user == nil {
+		return false, err
+	}
+	return user.Role == "admin" || user.Role == "curator", nil
+}
+
+// authorizeAuthor checks that the user may change something written by authorID:
+// its author and admins may. It responds with 403 Forbidden, using message, or with
+// 500 Internal Server Error and returns false if the user may not.
+func authorizeAuthor(c *gin.Context, userID, authorID int, message string) bool {
+	if userID == authorID {
+		return true
+	}
+	admin, err := isAdmin(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return false
+	}
+	if !admin {
+		c.JSON(http.StatusForbidden, gin.H{"error": message})
+		return false
+	}
+	return true
+}
+
 // Login godoc
 // @Summary Login a user
 // @Description Login a user
@@ -68,24 +143,8 @@ func Login(c *gin.Context) {
 		return
 	}
 
-	// Open a database connection
-	db, err := sql.Open("sqlite3", "literary_lions.db")
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to connect to the database"})
-		return
-	}
-	defer db.Close()
-
-	// Begin a transaction
-	tx, err := db.Begin()
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to begin database transaction"})
-		return
-	}
-	defer tx.Rollback()
-
 	// Check if the user exists
-	user, err := models.FindUserByEmail(tx, creds.Email)
+	user, err := store.Users.FindByEmail(creds.Email)
 	if err != nil {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "User does not exist"})
 		return
@@ -97,14 +156,8 @@ func Login(c *gin.Context) {
 		return
 	}
 
-	// Commit the transaction
-	if err := tx.Commit(); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to commit transaction"})
-		return
-	}
-
 	// Create a session token for the authenticated user
-	token, err := models.CreateSession(user.ID)
+	token, err := store.Sessions.Create(user.ID)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not create session"})
 		return
@@ -145,7 +198,7 @@ func Logout(c *gin.Context) {
 	}
 
 	// Invalidate the session in the database
-	if err := models.InvalidateSession(token); err != nil {
+	if err := store.Sessions.Invalidate(token); err != nil {
 		// If session invalidation fails, return an internal server error
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to log out"})
 		return
@@ -0,0 +1,421 @@
+package handlers
+
+import (
+	"errors"
+	"literary-lions/backend/src/internal/models"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// defaultBookListing is the number of books listed when the request sets no limit.
+	defaultBookListing = 50
+	// maxBookListing is the largest number of books listed at once.
+	maxBookListing = 100
+)
+
+// BookRequest is the payload of the endpoints that add and change books.
+type BookRequest struct {
+	Title   string   `json:"title" binding:"required"`
+	Authors []string `json:"authors"`
+	ISBN    string   `json:"isbn"`     // ISBN-10 or ISBN-13, dashes and spaces are dropped
+	Year    int      `json:"year"`     // Year of first publication, 0 if unknown
+	CoverID int      `json:"cover_id"` // An uploaded image, 0 for no cover
+}
+
+// booksInput is the body of a request setting the books a post or draft links.
+type booksInput struct {
+	BookIDs []int `json:"book_ids"` // The books, in the order they are shown
+}
+
+// GetBooks godoc
+// @Summary List the books of the catalog
+// @Description List the approved books of the catalog by title, or by average rating with sort=rating, with the number of posts discussing each and their rating. With q, only the books whose title or an author contains it, ignoring case, are listed.
+// @Tags books
+// @Produce json
+// @Param q query string false "Part of the title or of an author"
+// @Param sort query string false "title (default) or rating"
+// @Param limit query int false "Number of books (default 50, max 100)"
+// @Success 200 {array} models.Book
+// @Failure 400 {object} gin.H
+// @Router /api/v1.0/books [get]
+func GetBooks(c *gin.Context) {
+	listBooks(c, false)
+}
+
+// GetPendingBooks godoc
+// @Summary List the books waiting for approval
+// @Description List the books members added that are not in the catalog yet, by title. Only admins and curators may list them.
+// @Tags books
+// @Produce json
+// @Param q query string false "Part of the title or of an author"
+// @Param sort query string false "title (default) or rating"
+// @Param limit query int false "Number of books (default 50, max 100)"
+// @Success 200 {array} models.Book
+// @Failure 400 {object} gin.H
+// @Failure 401 {object} gin.H
+// @Failure 403 {object} gin.H
+// @Router /api/v1.0/curation/books [get]
+// @Security ApiKeyAuth
+func GetPendingBooks(c *gin.Context) {
+	listBooks(c, true)
+}
+
+// listBooks responds with the books of the catalog, or those waiting for approval,
+// narrowed by the q and limit query parameters and ordered by the sort one.
+func listBooks(c *gin.Context, pending bool) {
+	limit := defaultBookListing
+	if value := c.Query("limit"); value != "" {
+		var err error
+		limit, err = strconv.Atoi(value)
+		if err != nil || limit < 1 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid limit"})
+			return
+		}
+		if limit > maxBookListing {
+			limit = maxBookListing
+		}
+	}
+
+	books, err := store.Books.List(models.BookFilter{Query: c.Query("q"), Pending: pending, Sort: c.Query("sort"), Limit: limit})
+	if errors.Is(err, models.ErrInvalidSort) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	} else if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, books)
+}
+
+// GetBook godoc
+// @Summary Browse a book
+// @Description Retrieve a book by ID and its rating together with one page of the posts discussing it, and the review of the logged-in member and the shelf they keep it on, if any. Books waiting for approval are found too, since posts may already link them.
+// @Tags books
+// @Produce json
+// @Param id path int true "Book ID"
+// @Param sort query string false "newest (default), oldest, most_liked or most_commented"
+// @Param cursor query string false "The next cursor of the previous page"
+// @Param limit query int false "Number of posts per page (default 20, max 100)"
+// @Success 200 {object} gin.H
+// @Failure 400 {object} gin.H
+// @Failure 404 {object} gin.H
+// @Router /api/v1.0/books/{id} [get]
+func GetBook(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid book ID"})
+		return
+	}
+	book, err := store.Books.GetByID(id)
+	if errors.Is(err, models.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Book not found"})
+		return
+	} else if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Retrieve the sort order, cursor and page size
+	page, ok := parsePageRequest(c)
+	if !ok {
+		return
+	}
+
+	posts, err := store.Posts.GetFiltered(models.PostFilter{Book: book.ID}, page)
+	if isPageError(err) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	} else if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	// The review of the requesting user, to edit it rather than write another, and
+	// the shelf they keep the book on
+	var myReview *models.Review
+	var myShelf *models.ShelfEntry
+	if userID := sessionUserID(c); userID != 0 {
+		review, err := store.Reviews.FindByAuthor(book.ID, userID)
+		if err == nil {
+			review.Editable = true
+			myReview = &review
+		} else if !errors.Is(err, models.ErrNotFound) {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		entry, err := store.Shelves.Get(userID, book.ID)
+		if err == nil {
+			myShelf = &entry
+		} else if !errors.Is(err, models.ErrNotFound) {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	renderPosts(posts.Posts)
+	c.JSON(http.StatusOK, gin.H{"book": book, "posts": posts, "my_review": myReview, "my_shelf": myShelf})
+}
+
+// CreateBook godoc
+// @Summary Add a book
+// @Description Add a book to the catalog, with its title, authors, ISBN, year of first publication and an uploaded image as its cover. Books added by admins and curators are approved right away; those added by other members wait for approval, and only they can link them to their posts until then.
+// @Tags books
+// @Accept json
+// @Produce json
+// @Param book body BookRequest true "Book object"
+// @Success 201 {object} models.Book
+// @Failure 400 {object} gin.H
+// @Failure 401 {object} gin.H
+// @Failure 409 {object} gin.H
+// @Router /api/v1.0/books [post]
+// @Security ApiKeyAuth
+func CreateBook(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	var request BookRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request payload"})
+		return
+	}
+
+	curator, err := isCurator(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	book, err := store.Books.Create(models.Book{
+		Title:       request.Title,
+		Authors:     request.Authors,
+		ISBN:        request.ISBN,
+		Year:        request.Year,
+		CoverID:     request.CoverID,
+		Approved:    curator,
+		SubmittedBy: userID,
+	})
+	if err != nil {
+		bookError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, book)
+}
+
+// UpdateBook godoc
+// @Summary Update a book
+// @Description Change the title, authors, ISBN, year or cover of a book. Only admins and curators may update books.
+// @Tags books
+// @Accept json
+// @Produce json
+// @Param id path int true "Book ID"
+// @Param book body BookRequest true "Updated book object"
+// @Success 200 {object} models.Book
+// @Failure 400 {object} gin.H
+// @Failure 401 {object} gin.H
+// @Failure 403 {object} gin.H
+// @Failure 404 {object} gin.H
+// @Failure 409 {object} gin.H
+// @Router /api/v1.0/curation/books/{id} [put]
+// @Security ApiKeyAuth
+func UpdateBook(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid book ID"})
+		return
+	}
+
+	var request BookRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request payload"})
+		return
+	}
+
+	book, err := store.Books.Update(models.Book{
+		ID:      id,
+		Title:   request.Title,
+		Authors: request.Authors,
+		ISBN:    request.ISBN,
+		Year:    request.Year,
+		CoverID: request.CoverID,
+	})
+	if err != nil {
+		bookError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, book)
+}
+
+// ApproveBook godoc
+// @Summary Approve a book
+// @Description List a book a member added in the catalog, so that every member can link it to their posts. Only admins and curators may approve books.
+// @Tags books
+// @Produce json
+// @Param id path int true "Book ID"
+// @Success 200 {object} gin.H
+// @Failure 400 {object} gin.H
+// @Failure 401 {object} gin.H
+// @Failure 403 {object} gin.H
+// @Failure 404 {object} gin.H
+// @Router /api/v1.0/curation/books/{id}/approve [post]
+// @Security ApiKeyAuth
+func ApproveBook(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid book ID"})
+		return
+	}
+
+	if err := store.Books.Approve(id); err != nil {
+		bookError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Book approved successfully"})
+}
+
+// DeleteBook godoc
+// @Summary Delete a book
+// @Description Remove a book from the catalog, or turn down one waiting for approval. The posts linking it stay, without the link. Only admins and curators may delete books.
+// @Tags books
+// @Produce json
+// @Param id path int true "Book ID"
+// @Success 200 {object} gin.H
+// @Failure 400 {object} gin.H
+// @Failure 401 {object} gin.H
+// @Failure 403 {object} gin.H
+// @Failure 404 {object} gin.H
+// @Router /api/v1.0/curation/books/{id} [delete]
+// @Security ApiKeyAuth
+func DeleteBook(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid book ID"})
+		return
+	}
+
+	if err := store.Books.Delete(id); err != nil {
+		bookError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Book deleted successfully"})
+}
+
+// SetPostBooks godoc
+// @Summary Set the books of a post
+// @Description Replace the books a post discusses, in the order given. Members can link the books of the catalog, those they added that wait for approval, and those already on the post. Only its author and admins may change a post.
+// @Tags books
+// @Accept json
+// @Produce json
+// @Param id path int true "Post ID"
+// @Param books body object true "book_ids"
+// @Success 200 {object} gin.H
+// @Failure 400 {object} gin.H
+// @Failure 401 {object} gin.H
+// @Failure 403 {object} gin.H
+// @Failure 404 {object} gin.H
+// @Router /api/v1.0/post/{id}/books [put]
+// @Security ApiKeyAuth
+func SetPostBooks(c *gin.Context) {
+	id, ok := findOwnPost(c, "You can only change the books of your own posts")
+	if !ok {
+		return
+	}
+	setBooks(c, id)
+}
+
+// SetDraftBooks godoc
+// @Summary Set the books of a draft
+// @Description Replace the books a draft or scheduled post of the logged-in user discusses, as for a post. They stay with it once it is published.
+// @Tags drafts
+// @Accept json
+// @Produce json
+// @Param id path int true "Draft ID"
+// @Param books body object true "book_ids"
+// @Success 200 {object} gin.H
+// @Failure 400 {object} gin.H
+// @Failure 401 {object} gin.H
+// @Failure 404 {object} gin.H
+// @Router /api/v1.0/drafts/{id}/books [put]
+// @Security ApiKeyAuth
+func SetDraftBooks(c *gin.Context) {
+	draft, ok := findOwnDraft(c)
+	if !ok {
+		return
+	}
+	setBooks(c, draft.ID)
+}
+
+// setBooks replaces the books of the post with the given ID with those in the
+// request body.
+func setBooks(c *gin.Context, postID int) {
+	var input booksInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request payload"})
+		return
+	}
+
+	err := store.Books.SetForPost(postID, c.GetInt("userID"), input.BookIDs)
+	if isBookLinkError(err) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	} else if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not update the books"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Books updated successfully"})
+}
+
+// checkNewPostBooks checks that a post about to be created may link the books:
+// at most models.MaxPostBooks, each in the catalog or added by the user. It
+// responds with 400 Bad Request or 500 Internal Server Error and returns false
+// if it may not, so that no post is created without its books.
+func checkNewPostBooks(c *gin.Context, userID int, bookIDs []int) bool {
+	seen := map[int]bool{}
+	for _, id := range bookIDs {
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+		if len(seen) > models.MaxPostBooks {
+			c.JSON(http.StatusBadRequest, gin.H{"error": models.ErrTooManyBooks.Error()})
+			return false
+		}
+
+		book, err := store.Books.GetByID(id)
+		if errors.Is(err, models.ErrNotFound) || (err == nil && !book.Approved && book.SubmittedBy != userID) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": models.ErrUnknownBook.Error()})
+			return false
+		} else if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return false
+		}
+	}
+	return true
+}
+
+// isBookLinkError reports whether a post was refused the books it links.
+func isBookLinkError(err error) bool {
+	return errors.Is(err, models.ErrUnknownBook) || errors.Is(err, models.ErrTooManyBooks)
+}
+
+// bookError responds to a failed book change with the matching status code.
+func bookError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, models.ErrInvalidBook), errors.Is(err, models.ErrInvalidISBN), errors.Is(err, models.ErrInvalidAttachment):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, models.ErrNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Book not found"})
+	case errors.Is(err, models.ErrBookExists):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
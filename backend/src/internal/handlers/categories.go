@@ -0,0 +1,227 @@
+package handlers
+
+import (
+	"errors"
+	"literary-lions/backend/src/internal/models"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CategoryRequest is the payload of the admin endpoints that create and update categories.
+type CategoryRequest struct {
+	Slug        string `json:"slug"` // Derived from the name if left empty
+	Name        string `json:"name" binding:"required"`
+	Description string `json:"description"`
+	Position    int    `json:"position"`
+}
+
+// GetCategories godoc
+// @Summary List the categories
+// @Description List every category posts can be filed under, in display order, with the number of posts in each
+// @Tags categories
+// @Produce json
+// @Success 200 {array} models.Category
+// @Router /api/v1.0/categories [get]
+// GetCategories returns all categories using Gin
+func GetCategories(c *gin.Context) {
+	categories, err := store.Categories.List()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, categories)
+}
+
+// GetCategory godoc
+// @Summary Browse a category
+// @Description Retrieve a category by its slug together with one page of its posts
+// @Tags categories
+// @Produce json
+// @Param slug path string true "Category slug"
+// @Param sort query string false "newest (default), oldest, most_liked or most_commented"
+// @Param cursor query string false "The next cursor of the previous page"
+// @Param limit query int false "Number of posts per page (default 20, max 100)"
+// @Success 200 {object} gin.H
+// @Failure 400 {object} gin.H
+// @Failure 404 {object} gin.H
+// @Router /api/v1.0/categories/{slug} [get]
+// GetCategory returns a category and a page of its posts using Gin
+func GetCategory(c *gin.Context) {
+	category, err := store.Categories.Find(c.Param("slug"))
+	if errors.Is(err, models.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Category not found"})
+		return
+	} else if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Retrieve the sort order, cursor and page size
+	page, ok := parsePageRequest(c)
+	if !ok {
+		return
+	}
+
+	posts, err := store.Posts.GetFiltered(models.PostFilter{Category: category.Slug}, page)
+	if isPageError(err) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	} else if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	renderPosts(posts.Posts)
+	c.JSON(http.StatusOK, gin.H{"category": category, "posts": posts})
+}
+
+// CreateCategory godoc
+// @Summary Create a category
+// @Description Add a category posts can be filed under. Only admins may create categories.
+// @Tags categories
+// @Accept json
+// @Produce json
+// @Param category body CategoryRequest true "Category object"
+// @Success 201 {object} models.Category
+// @Failure 400 {object} gin.H
+// @Failure 401 {object} gin.H
+// @Failure 403 {object} gin.H
+// @Failure 409 {object} gin.H
+// @Router /api/v1.0/admin/categories [post]
+// @Security ApiKeyAuth
+func CreateCategory(c *gin.Context) {
+	var request CategoryRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request payload"})
+		return
+	}
+
+	category, err := store.Categories.Create(models.Category{
+		Slug:        request.Slug,
+		Name:        request.Name,
+		Description: request.Description,
+		Position:    request.Position,
+	})
+	if err != nil {
+		categoryError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, category)
+}
+
+// UpdateCategory godoc
+// @Summary Update a category
+// @Description Change the slug, name, description or position of a category. Only admins may update categories.
+// @Tags categories
+// @Accept json
+// @Produce json
+// @Param id path int true "Category ID"
+// @Param category body CategoryRequest true "Updated category object"
+// @Success 200 {object} models.Category
+// @Failure 400 {object} gin.H
+// @Failure 401 {object} gin.H
+// @Failure 403 {object} gin.H
+// @Failure 404 {object} gin.H
+// @Failure 409 {object} gin.H
+// @Router /api/v1.0/admin/categories/{id} [put]
+// @Security ApiKeyAuth
+func UpdateCategory(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid category ID"})
+		return
+	}
+
+	var request CategoryRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request payload"})
+		return
+	}
+
+	category, err := store.Categories.Update(models.Category{
+		ID:          id,
+		Slug:        request.Slug,
+		Name:        request.Name,
+		Description: request.Description,
+		Position:    request.Position,
+	})
+	if err != nil {
+		categoryError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, category)
+}
+
+// DeleteCategory godoc
+// @Summary Delete a category
+// @Description Delete a category that has no posts. Only admins may delete categories.
+// @Tags categories
+// @Produce json
+// @Param id path int true "Category ID"
+// @Success 200 {object} gin.H
+// @Failure 400 {object} gin.H
+// @Failure 401 {object} gin.H
+// @Failure 403 {object} gin.H
+// @Failure 404 {object} gin.H
+// @Failure 409 {object} gin.H
+// @Router /api/v1.0/admin/categories/{id} [delete]
+// @Security ApiKeyAuth
+func DeleteCategory(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid category ID"})
+		return
+	}
+
+	if err := store.Categories.Delete(id); err != nil {
+		categoryError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Category deleted successfully"})
+}
+
+// categoryError responds to a failed category change with the matching status code.
+func categoryError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, models.ErrInvalidCategory):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, models.ErrNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Category not found"})
+	case errors.Is(err, models.ErrCategoryExists), errors.Is(err, models.ErrCategoryInUse):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
+
+// findPostCategory looks up the category a post is filed under, given by ID or by
+// slug or name. It responds with 400 Bad Request and returns false if none is given
+// or the category does not exist.
+func findPostCategory(c *gin.Context, categoryID int, ref string) (models.Category, bool) {
+	var category models.Category
+	var err error
+	switch {
+	case categoryID != 0:
+		category, err = store.Categories.GetByID(categoryID)
+	case strings.TrimSpace(ref) != "":
+		category, err = store.Categories.Find(ref)
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Category is required"})
+		return models.Category{}, false
+	}
+
+	if errors.Is(err, models.ErrNotFound) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Category does not exist"})
+		return models.Category{}, false
+	} else if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return models.Category{}, false
+	}
+	return category, true
+}
@@ -0,0 +1,798 @@
+package handlers
+
+import (
+	"errors"
+	"literary-lions/backend/src/internal/models"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ClubRequest is the payload of the endpoints that start and edit clubs.
+type ClubRequest struct {
+	Name        string `json:"name" binding:"required"` // At most 100 characters
+	Description string `json:"description"`             // At most 1000 characters
+	Visibility  string `json:"visibility"`              // public (default) or private
+}
+
+// ClubPostRequest is the payload of the endpoint that writes posts in clubs. It
+// takes the same fields as a forum post.
+type ClubPostRequest struct {
+	Title      string   `json:"title" binding:"required"`
+	Content    string   `json:"content" binding:"required"`
+	Category   string   `json:"category"`    // Slug or name of the category
+	CategoryID int      `json:"category_id"` // Or its ID
+	Tags       []string `json:"tags"`        // Optional tags, as typed
+	BookIDs    []int    `json:"book_ids"`    // Optional books the post discusses
+	SectionID  int      `json:"section_id"`  // Optional section of the reading schedule it discusses
+}
+
+// ClubRoleRequest is the payload of the endpoint that names the moderators of a club.
+type ClubRoleRequest struct {
+	Role string `json:"role" binding:"required"` // member or moderator
+}
+
+// ClubMiddleware is a middleware function, layered on AuthMiddleware, that checks
+// that the logged-in user belongs to the club named by the slug path parameter and
+// optionally holds one of the required roles in it. Admins pass the role checks of
+// every club. The club is stored in the context for the handlers that follow.
+func ClubMiddleware(requiredRoles ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, ok := currentUserID(c)
+		if !ok {
+			c.Abort()
+			return
+		}
+		club, err := store.Clubs.GetBySlug(c.Param("slug"), userID)
+		if errors.Is(err, models.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Club not found"})
+			c.Abort()
+			return
+		} else if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			c.Abort()
+			return
+		}
+
+		member := club.Membership != nil && club.Membership.Approved
+		if len(requiredRoles) == 0 && !member {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Only the members of the club can do this"})
+			c.Abort()
+			return
+		}
+		if len(requiredRoles) > 0 && !(member && slices.Contains(requiredRoles, club.Membership.Role)) {
+			admin, err := isAdmin(userID)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+				c.Abort()
+				return
+			}
+			if !admin {
+				c.JSON(http.StatusForbidden, gin.H{"error": "You do not have permission to do this"})
+				c.Abort()
+				return
+			}
+		}
+
+		c.Set("club", club)
+		c.Next()
+	}
+}
+
+// ClubDiscussionMiddleware is a middleware function, layered on AuthMiddleware,
+// that keeps the discussions of clubs to their members. It checks that the
+// logged-in user belongs to the club of the post, or of the post of the comment,
+// named by the id path parameter, as targetType tells, and that the post is not
+// locked for them by a section of the reading schedule they have not marked read.
+// Posts and comments in the forum, and IDs that do not name one, are left to the handler.
+func ClubDiscussionMiddleware(targetType string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		targetID, err := strconv.Atoi(c.Param("id"))
+		if err != nil {
+			c.Next()
+			return
+		}
+		clubID, err := store.Clubs.ClubOf(targetType, targetID)
+		if errors.Is(err, models.ErrNotFound) || (err == nil && clubID == 0) {
+			c.Next()
+			return
+		} else if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			c.Abort()
+			return
+		}
+
+		userID, ok := currentUserID(c)
+		if !ok {
+			c.Abort()
+			return
+		}
+		member, err := store.Clubs.Membership(clubID, userID)
+		if (err == nil && !member.Approved) || errors.Is(err, models.ErrNotFound) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Only the members of the club take part in its discussions"})
+			c.Abort()
+			return
+		} else if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			c.Abort()
+			return
+		}
+
+		// Members only discuss the sections of the schedule they have read
+		locked, err := discussionLocked(targetType, targetID, userID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			c.Abort()
+			return
+		} else if locked {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Mark the section read to take part in this discussion"})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// discussionLocked reports whether the post, or the post of the comment, with the
+// given ID is locked for the user by a section they have not marked read. Posts and
+// comments that do not exist are not locked, and left to the handler.
+func discussionLocked(targetType string, targetID, userID int) (bool, error) {
+	postID := targetID
+	if targetType == models.ReactionTargetComment {
+		comment, err := store.Comments.GetByID(targetID)
+		if errors.Is(err, models.ErrNotFound) {
+			return false, nil
+		} else if err != nil {
+			return false, err
+		}
+		postID = comment.PostID
+	}
+
+	post, err := store.Posts.GetByID(postID)
+	if errors.Is(err, models.ErrNotFound) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+	return postLocked(post, userID)
+}
+
+// currentClub returns the club stored in the context by ClubMiddleware.
+func currentClub(c *gin.Context) models.Club {
+	return c.MustGet("club").(models.Club)
+}
+
+// mayReadClub reports whether the user may read the posts of a club: everyone may
+// read public clubs, and members and admins private ones.
+func mayReadClub(club models.Club, userID int) (bool, error) {
+	if club.Visibility == models.ClubPublic || (club.Membership != nil && club.Membership.Approved) {
+		return true, nil
+	}
+	if userID == 0 {
+		return false, nil
+	}
+	return isAdmin(userID)
+}
+
+// mayModerateClub reports whether the user may moderate a club: its owner and
+// moderators may, and admins.
+func mayModerateClub(club models.Club, userID int) (bool, error) {
+	if club.Membership != nil && club.Membership.Moderates() {
+		return true, nil
+	}
+	if userID == 0 {
+		return false, nil
+	}
+	return isAdmin(userID)
+}
+
+// checkClubReader checks that the requesting user, if any, may read the posts of
+// the club with the given ID, 0 standing for the forum that everyone reads.
+// Otherwise it responds with 404 Not Found, using notFound, so that the posts of
+// private clubs do not show they exist, or 500 Internal Server Error, and returns false.
+func checkClubReader(c *gin.Context, clubID int, notFound string) bool {
+	if clubID == 0 {
+		return true
+	}
+	userID := sessionUserID(c)
+	club, err := store.Clubs.GetByID(clubID, userID)
+	if errors.Is(err, models.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": notFound})
+		return false
+	} else if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return false
+	}
+
+	readable, err := mayReadClub(club, userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return false
+	}
+	if !readable {
+		c.JSON(http.StatusNotFound, gin.H{"error": notFound})
+		return false
+	}
+	return true
+}
+
+// checkPostReader is checkClubReader for the club of the post with the given ID.
+// Posts of clubs discussing a section the user has not marked read get 403
+// Forbidden. Posts that do not exist are left to the handler.
+func checkPostReader(c *gin.Context, postID int) bool {
+	clubID, err := store.Clubs.ClubOf(models.ReactionTargetPost, postID)
+	if errors.Is(err, models.ErrNotFound) {
+		return true
+	} else if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return false
+	}
+	if clubID == 0 || !checkClubReader(c, clubID, "Post not found") {
+		return clubID == 0
+	}
+
+	post, err := store.Posts.GetByID(postID)
+	if errors.Is(err, models.ErrNotFound) {
+		return true
+	} else if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return false
+	}
+	locked, err := postLocked(post, sessionUserID(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return false
+	} else if locked {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Mark the section read to see this post"})
+		return false
+	}
+	return true
+}
+
+// GetClubs godoc
+// @Summary List the book clubs
+// @Description List every book club by name, with its owner and numbers of members and posts. Private clubs are listed too, so that members can ask to join them. For logged-in members, each club carries their membership, if any.
+// @Tags clubs
+// @Produce json
+// @Success 200 {array} models.Club
+// @Router /api/v1.0/clubs [get]
+func GetClubs(c *gin.Context) {
+	clubs, err := store.Clubs.List(sessionUserID(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, clubs)
+}
+
+// GetClub godoc
+// @Summary Get a book club
+// @Description Retrieve a book club with the membership of the logged-in member, its members, its reading schedule and one page of its posts, optionally those discussing one section of the schedule. The posts discussing a section the member has not marked read are locked: their content is held back. The members, schedule and posts of private clubs are only listed to their members and to admins; can_read tells whether they are. The requests to join waiting for approval are listed to the moderators of the club, flagged by can_moderate.
+// @Tags clubs
+// @Produce json
+// @Param slug path string true "Club slug"
+// @Param section query int false "ID of a section of the reading schedule to list the posts of"
+// @Param sort query string false "newest (default), oldest, most_liked or most_commented"
+// @Param cursor query string false "The next cursor of the previous page"
+// @Param limit query int false "Number of posts per page (default 20, max 100)"
+// @Success 200 {object} gin.H
+// @Failure 400 {object} gin.H
+// @Failure 404 {object} gin.H
+// @Router /api/v1.0/clubs/{slug} [get]
+func GetClub(c *gin.Context) {
+	userID := sessionUserID(c)
+	club, err := store.Clubs.GetBySlug(c.Param("slug"), userID)
+	if errors.Is(err, models.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Club not found"})
+		return
+	} else if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Retrieve the sort order, cursor and page size
+	page, ok := parsePageRequest(c)
+	if !ok {
+		return
+	}
+
+	sectionID := 0
+	if value := c.Query("section"); value != "" {
+		if sectionID, err = strconv.Atoi(value); err != nil || sectionID < 1 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid section ID"})
+			return
+		}
+	}
+
+	canRead, err := mayReadClub(club, userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	canModerate, err := mayModerateClub(club, userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Outsiders of a private club only see what it is about
+	members, requests := []models.ClubMember{}, []models.ClubMember{}
+	sections := []models.ClubSection{}
+	posts := models.PostPage{Posts: []models.Post{}}
+	if canRead {
+		if members, err = store.Clubs.Members(club.ID, true); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if sections, err = store.Schedules.List(club.ID, userID); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		posts, err = store.Posts.GetByClub(club.ID, sectionID, page)
+		if isPageError(err) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		} else if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	if canModerate {
+		if requests, err = store.Clubs.Members(club.ID, false); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	lockUnreadPosts(posts.Posts, sections, userID)
+	renderPosts(posts.Posts)
+	c.JSON(http.StatusOK, gin.H{
+		"club":         club,
+		"members":      members,
+		"requests":     requests,
+		"sections":     sections,
+		"posts":        posts,
+		"can_read":     canRead,
+		"can_moderate": canModerate,
+	})
+}
+
+// CreateClub godoc
+// @Summary Start a book club
+// @Description Start a book club, public or private, with the logged-in member as its owner and first member. The slug of the club is derived from its name.
+// @Tags clubs
+// @Accept json
+// @Produce json
+// @Param club body ClubRequest true "Club object"
+// @Success 201 {object} models.Club
+// @Failure 400 {object} gin.H
+// @Failure 401 {object} gin.H
+// @Failure 409 {object} gin.H
+// @Router /api/v1.0/clubs [post]
+// @Security ApiKeyAuth
+func CreateClub(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	var request ClubRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request payload"})
+		return
+	}
+
+	club, err := store.Clubs.Create(models.Club{
+		Name:        request.Name,
+		Description: request.Description,
+		Visibility:  request.Visibility,
+		OwnerID:     userID,
+	})
+	if err != nil {
+		clubError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, club)
+}
+
+// UpdateClub godoc
+// @Summary Edit a book club
+// @Description Replace the name, description and visibility of a club; its slug stays the same. Making a private club public lets in the members waiting to join it. Only the owner of the club and admins may edit it.
+// @Tags clubs
+// @Accept json
+// @Produce json
+// @Param slug path string true "Club slug"
+// @Param club body ClubRequest true "Updated club object"
+// @Success 200 {object} gin.H
+// @Failure 400 {object} gin.H
+// @Failure 401 {object} gin.H
+// @Failure 403 {object} gin.H
+// @Failure 404 {object} gin.H
+// @Failure 409 {object} gin.H
+// @Router /api/v1.0/clubs/{slug} [put]
+// @Security ApiKeyAuth
+func UpdateClub(c *gin.Context) {
+	club := currentClub(c)
+
+	var request ClubRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request payload"})
+		return
+	}
+
+	club.Name, club.Description, club.Visibility = request.Name, request.Description, request.Visibility
+	if err := store.Clubs.Update(club); err != nil {
+		clubError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Club updated successfully", "slug": club.Slug})
+}
+
+// DeleteClub godoc
+// @Summary Delete a book club
+// @Description Delete a club and its memberships. Its posts go to the trash, from which they can no longer be restored, and are purged with the rest. Only the owner of the club and admins may delete it.
+// @Tags clubs
+// @Produce json
+// @Param slug path string true "Club slug"
+// @Success 200 {object} gin.H
+// @Failure 401 {object} gin.H
+// @Failure 403 {object} gin.H
+// @Failure 404 {object} gin.H
+// @Router /api/v1.0/clubs/{slug} [delete]
+// @Security ApiKeyAuth
+func DeleteClub(c *gin.Context) {
+	if err := store.Clubs.Delete(currentClub(c).ID); err != nil {
+		clubError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Club deleted successfully"})
+}
+
+// JoinClub godoc
+// @Summary Join a book club
+// @Description Join a public club right away, or ask to join a private one, which its moderators approve. Joining a club again keeps the membership as it is.
+// @Tags clubs
+// @Produce json
+// @Param slug path string true "Club slug"
+// @Success 200 {object} models.ClubMember
+// @Failure 401 {object} gin.H
+// @Failure 404 {object} gin.H
+// @Router /api/v1.0/clubs/{slug}/join [post]
+// @Security ApiKeyAuth
+func JoinClub(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	club, ok := findClub(c, userID)
+	if !ok {
+		return
+	}
+
+	member, err := store.Clubs.Join(club.ID, userID)
+	if err != nil {
+		clubError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, member)
+}
+
+// LeaveClub godoc
+// @Summary Leave a book club
+// @Description Leave a club, or withdraw a request to join it. The posts of the member stay in the club. The owner stays until the club is deleted.
+// @Tags clubs
+// @Produce json
+// @Param slug path string true "Club slug"
+// @Success 200 {object} gin.H
+// @Failure 401 {object} gin.H
+// @Failure 404 {object} gin.H
+// @Failure 409 {object} gin.H
+// @Router /api/v1.0/clubs/{slug}/membership [delete]
+// @Security ApiKeyAuth
+func LeaveClub(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	club, ok := findClub(c, userID)
+	if !ok {
+		return
+	}
+
+	if err := store.Clubs.RemoveMember(club.ID, userID); err != nil {
+		clubError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "You left the club"})
+}
+
+// CreateClubPost godoc
+// @Summary Write a post in a book club
+// @Description Create a post in a club, discussed there rather than in the forum, with the same fields as a forum post. A post may discuss a section of the reading schedule of the club once it unlocks; it is then held back from the members who have not marked the section read. Only the members of the club may write in it; the posts of private clubs are read by their members alone.
+// @Tags clubs
+// @Accept json
+// @Produce json
+// @Param slug path string true "Club slug"
+// @Param post body ClubPostRequest true "Post object"
+// @Success 201 {object} gin.H
+// @Failure 400 {object} gin.H
+// @Failure 401 {object} gin.H
+// @Failure 403 {object} gin.H
+// @Failure 404 {object} gin.H
+// @Router /api/v1.0/clubs/{slug}/posts [post]
+// @Security ApiKeyAuth
+func CreateClubPost(c *gin.Context) {
+	club := currentClub(c)
+	userID := c.GetInt("userID")
+
+	var post ClubPostRequest
+	if err := c.ShouldBindJSON(&post); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request payload"})
+		return
+	}
+
+	// Check if the content is empty or contains only whitespace
+	if len(strings.TrimSpace(post.Content)) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Content cannot be empty or only whitespace"})
+		return
+	}
+
+	// Posts can only be filed under an existing category
+	category, ok := findPostCategory(c, post.CategoryID, post.Category)
+	if !ok {
+		return
+	}
+	if !checkNewPostBooks(c, userID, post.BookIDs) {
+		return
+	}
+	if post.SectionID != 0 && !checkOpenSection(c, club.ID, post.SectionID) {
+		return
+	}
+
+	postID, err := store.Posts.CreateInClub(club.ID, post.SectionID, userID, post.Title, post.Content, category.ID, post.Tags)
+	if isTagError(err) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	} else if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if len(post.BookIDs) > 0 {
+		if err := store.Books.SetForPost(postID, userID, post.BookIDs); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"message": "Post created successfully", "id": postID})
+}
+
+// DeleteClubPost godoc
+// @Summary Delete a post of a book club
+// @Description Move a post of a club to the trash, from which its author can restore it until it is purged. Only the moderators of the club and admins may delete the posts of others this way.
+// @Tags clubs
+// @Produce json
+// @Param slug path string true "Club slug"
+// @Param post_id path int true "Post ID"
+// @Success 200 {object} gin.H
+// @Failure 400 {object} gin.H
+// @Failure 401 {object} gin.H
+// @Failure 403 {object} gin.H
+// @Failure 404 {object} gin.H
+// @Router /api/v1.0/clubs/{slug}/posts/{post_id} [delete]
+// @Security ApiKeyAuth
+func DeleteClubPost(c *gin.Context) {
+	postID, err := strconv.Atoi(c.Param("post_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid post ID"})
+		return
+	}
+	if !checkInClub(c, models.ReactionTargetPost, postID) {
+		return
+	}
+
+	err = store.Posts.Delete(postID)
+	if errors.Is(err, models.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Post not found"})
+		return
+	} else if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not delete post"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Post deleted successfully"})
+}
+
+// DeleteClubComment godoc
+// @Summary Delete a comment in a book club
+// @Description Move a comment on a post of a club to the trash, from which its author can restore it until it is purged. Only the moderators of the club and admins may delete the comments of others this way.
+// @Tags clubs
+// @Produce json
+// @Param slug path string true "Club slug"
+// @Param comment_id path int true "Comment ID"
+// @Success 200 {object} gin.H
+// @Failure 400 {object} gin.H
+// @Failure 401 {object} gin.H
+// @Failure 403 {object} gin.H
+// @Failure 404 {object} gin.H
+// @Router /api/v1.0/clubs/{slug}/comments/{comment_id} [delete]
+// @Security ApiKeyAuth
+func DeleteClubComment(c *gin.Context) {
+	commentID, err := strconv.Atoi(c.Param("comment_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid comment ID"})
+		return
+	}
+	if !checkInClub(c, models.ReactionTargetComment, commentID) {
+		return
+	}
+
+	err = store.Comments.Delete(commentID)
+	if errors.Is(err, models.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Comment not found"})
+		return
+	} else if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not delete comment"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Comment deleted successfully"})
+}
+
+// ApproveClubMember godoc
+// @Summary Let a member into a private club
+// @Description Approve the request of a member to join a private club. Only the moderators of the club and admins may approve requests.
+// @Tags clubs
+// @Produce json
+// @Param slug path string true "Club slug"
+// @Param username path string true "Username"
+// @Success 200 {object} gin.H
+// @Failure 401 {object} gin.H
+// @Failure 403 {object} gin.H
+// @Failure 404 {object} gin.H
+// @Router /api/v1.0/clubs/{slug}/members/{username}/approve [post]
+// @Security ApiKeyAuth
+func ApproveClubMember(c *gin.Context) {
+	user, ok := findProfile(c)
+	if !ok {
+		return
+	}
+
+	err := store.Clubs.Approve(currentClub(c).ID, user.ID)
+	if errors.Is(err, models.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "This member has not asked to join the club"})
+		return
+	} else if err != nil {
+		clubError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Member approved successfully"})
+}
+
+// RemoveClubMember godoc
+// @Summary Remove a member from a book club
+// @Description Remove a member from a club, or turn down their request to join it. Their posts stay in the club. Moderators may remove members; only the owner of the club and admins may remove moderators. The owner cannot be removed.
+// @Tags clubs
+// @Produce json
+// @Param slug path string true "Club slug"
+// @Param username path string true "Username"
+// @Success 200 {object} gin.H
+// @Failure 401 {object} gin.H
+// @Failure 403 {object} gin.H
+// @Failure 404 {object} gin.H
+// @Failure 409 {object} gin.H
+// @Router /api/v1.0/clubs/{slug}/members/{username} [delete]
+// @Security ApiKeyAuth
+func RemoveClubMember(c *gin.Context) {
+	club := currentClub(c)
+	user, ok := findProfile(c)
+	if !ok {
+		return
+	}
+
+	// Moderators look after the members, the owner after the moderators
+	member, err := store.Clubs.Membership(club.ID, user.ID)
+	if err != nil {
+		clubError(c, err)
+		return
+	}
+	if member.Role == models.ClubModeratorRole && (club.Membership == nil || club.Membership.Role != models.ClubOwnerRole) {
+		admin, err := isAdmin(c.GetInt("userID"))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if !admin {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Only the owner of the club can remove its moderators"})
+			return
+		}
+	}
+
+	if err := store.Clubs.RemoveMember(club.ID, user.ID); err != nil {
+		clubError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Member removed successfully"})
+}
+
+// SetClubMemberRole godoc
+// @Summary Name the moderators of a book club
+// @Description Make a member of a club a moderator, or a plain member again. Only the owner of the club and admins may change roles, and the owner keeps theirs.
+// @Tags clubs
+// @Accept json
+// @Produce json
+// @Param slug path string true "Club slug"
+// @Param username path string true "Username"
+// @Param role body ClubRoleRequest true "Role object"
+// @Success 200 {object} gin.H
+// @Failure 400 {object} gin.H
+// @Failure 401 {object} gin.H
+// @Failure 403 {object} gin.H
+// @Failure 404 {object} gin.H
+// @Failure 409 {object} gin.H
+// @Router /api/v1.0/clubs/{slug}/members/{username}/role [put]
+// @Security ApiKeyAuth
+func SetClubMemberRole(c *gin.Context) {
+	user, ok := findProfile(c)
+	if !ok {
+		return
+	}
+
+	var request ClubRoleRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request payload"})
+		return
+	}
+
+	if err := store.Clubs.SetRole(currentClub(c).ID, user.ID, request.Role); err != nil {
+		clubError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Role updated successfully"})
+}
+
+// findClub looks up the club named by the slug path parameter, with the membership
+// of the user with the given ID. It responds with 404 Not Found or 500 Internal
+// Server Error and returns false if the club cannot be found.
+func findClub(c *gin.Context, userID int) (models.Club, bool) {
+	club, err := store.Clubs.GetBySlug(c.Param("slug"), userID)
+	if errors.Is(err, models.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Club not found"})
+		return models.Club{}, false
+	} else if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return models.Club{}, false
+	}
+	return club, true
+}
+
+// checkInClub checks that the post or comment, as targetType tells, with the given
+// ID belongs to the club stored in the context. It responds with 404 Not Found or
+// 500 Internal Server Error and returns false if it does not.
+func checkInClub(c *gin.Context, targetType string, targetID int) bool {
+	clubID, err := store.Clubs.ClubOf(targetType, targetID)
+	if errors.Is(err, models.ErrNotFound) || (err == nil && clubID != currentClub(c).ID) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "The " + targetType + " is not in this club"})
+		return false
+	} else if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return false
+	}
+	return true
+}
+
+// clubError responds with the status matching an error from the club repository.
+func clubError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, models.ErrInvalidClub), errors.Is(err, models.ErrInvalidClubRole):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, models.ErrNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Club or member not found"})
+	case errors.Is(err, models.ErrClubExists), errors.Is(err, models.ErrClubOwner):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
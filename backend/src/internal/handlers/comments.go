@@ -0,0 +1,120 @@
+package handlers
+
+import (
+	"errors"
+	"literary-lions/backend/src/internal/models"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// UpdateComment godoc
+// @Summary Edit a comment
+// @Description Replace the content of a comment and record when it was edited. Only its author and admins may edit a comment.
+// @Tags comments
+// @Accept json
+// @Produce json
+// @Param id path int true "Comment ID"
+// @Param comment body object true "New comment content"
+// @Success 200 {object} gin.H
+// @Failure 400 {object} gin.H
+// @Failure 401 {object} gin.H
+// @Failure 403 {object} gin.H
+// @Failure 404 {object} gin.H
+// @Router /api/comment/{id} [put]
+// @Security ApiKeyAuth
+func UpdateComment(c *gin.Context) {
+	comment, ok := findOwnComment(c, "You can only edit your own comments")
+	if !ok {
+		return
+	}
+
+	var edit struct {
+		Content string `json:"content"`
+	}
+	if err := c.ShouldBindJSON(&edit); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request payload"})
+		return
+	}
+
+	// Check if the content is empty or contains only whitespace
+	if len(strings.TrimSpace(edit.Content)) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Content cannot be empty or only whitespace"})
+		return
+	}
+
+	err := store.Comments.Update(comment.ID, edit.Content)
+	if errors.Is(err, models.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Comment not found"})
+		return
+	} else if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not update comment"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Comment updated successfully", "post_id": comment.PostID})
+}
+
+// DeleteComment godoc
+// @Summary Delete a comment
+// @Description Move a comment to the trash, from which its author can restore it until it is purged. A comment with replies is shown as a "comment removed" placeholder so that the thread stays in place. Only its author and admins may delete a comment.
+// @Tags comments
+// @Produce json
+// @Param id path int true "Comment ID"
+// @Success 200 {object} gin.H
+// @Failure 400 {object} gin.H
+// @Failure 401 {object} gin.H
+// @Failure 403 {object} gin.H
+// @Failure 404 {object} gin.H
+// @Router /api/comment/{id} [delete]
+// @Security ApiKeyAuth
+func DeleteComment(c *gin.Context) {
+	comment, ok := findOwnComment(c, "You can only delete your own comments")
+	if !ok {
+		return
+	}
+
+	err := store.Comments.Delete(comment.ID)
+	if errors.Is(err, models.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Comment not found"})
+		return
+	} else if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not delete comment"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Comment deleted successfully", "post_id": comment.PostID})
+}
+
+// findOwnComment looks up the comment named by the id path parameter and checks
+// that the logged-in user wrote it or is an admin. Otherwise it responds with the
+// matching error, using forbidden for 403 Forbidden, and returns false.
+func findOwnComment(c *gin.Context, forbidden string) (models.Comment, bool) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return models.Comment{}, false
+	}
+
+	commentID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid comment ID"})
+		return models.Comment{}, false
+	}
+
+	comment, err := store.Comments.GetByID(commentID)
+	if errors.Is(err, models.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Comment not found"})
+		return models.Comment{}, false
+	} else if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return models.Comment{}, false
+	}
+
+	if !authorizeAuthor(c, userID.(int), comment.UserID, forbidden) {
+		return models.Comment{}, false
+	}
+	return comment, true
+}
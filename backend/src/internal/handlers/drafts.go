@@ -0,0 +1,265 @@
+package handlers
+
+import (
+	"errors"
+	"literary-lions/backend/src/internal/markdown"
+	"literary-lions/backend/src/internal/models"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// draftInput is the body of a request saving a draft.
+type draftInput struct {
+	Title      string     `json:"title" binding:"required"`
+	Content    string     `json:"content" binding:"required"`
+	Category   string     `json:"category"`    // Slug or name of the category
+	CategoryID int        `json:"category_id"` // Or its ID
+	Tags       []string   `json:"tags"`        // Optional tags, as typed
+	PublishAt  *time.Time `json:"publish_at"`  // Optional time to publish the post at, in RFC 3339
+}
+
+// bindDraft reads and checks the draft in the request body, and resolves its
+// category. It writes the error response and returns false if the draft is invalid.
+func bindDraft(c *gin.Context) (draftInput, models.Category, bool) {
+	var draft draftInput
+	if err := c.ShouldBindJSON(&draft); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request payload"})
+		return draftInput{}, models.Category{}, false
+	}
+	if len(strings.TrimSpace(draft.Content)) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Content cannot be empty or only whitespace"})
+		return draftInput{}, models.Category{}, false
+	}
+	if draft.PublishAt != nil && !draft.PublishAt.After(time.Now()) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "The publishing time must be in the future"})
+		return draftInput{}, models.Category{}, false
+	}
+
+	// Drafts can only be filed under an existing category
+	category, ok := findPostCategory(c, draft.CategoryID, draft.Category)
+	if !ok {
+		return draftInput{}, models.Category{}, false
+	}
+	return draft, category, true
+}
+
+// findOwnDraft retrieves the draft identified by the id path parameter. Drafts
+// are seen by their authors alone, so the drafts of others are reported as not
+// found. It writes the error response and returns false if there is no such draft,
+// or no logged-in user.
+func findOwnDraft(c *gin.Context) (models.Post, bool) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return models.Post{}, false
+	}
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid draft ID"})
+		return models.Post{}, false
+	}
+
+	draft, err := store.Posts.GetDraft(id)
+	if errors.Is(err, models.ErrNotFound) || (err == nil && draft.UserID != userID) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Draft not found"})
+		return models.Post{}, false
+	} else if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return models.Post{}, false
+	}
+	return draft, true
+}
+
+// SaveDraft godoc
+// @Summary Save a draft
+// @Description Save a post without publishing it, with the same fields as a new post. With publish_at, an RFC 3339 time in the future, the post is scheduled and goes live at that time; without it, it stays a draft until its author publishes it. Drafts are seen by their authors alone.
+// @Tags drafts
+// @Accept json
+// @Produce json
+// @Param draft body object true "Title, content, category or category_id, tags and optional publish_at"
+// @Success 201 {object} gin.H
+// @Failure 400 {object} gin.H
+// @Failure 401 {object} gin.H
+// @Router /api/v1.0/drafts [post]
+// @Security ApiKeyAuth
+func SaveDraft(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	draft, category, ok := bindDraft(c)
+	if !ok {
+		return
+	}
+
+	id, err := store.Posts.SaveDraft(userID, draft.Title, draft.Content, category.ID, draft.Tags, draft.PublishAt)
+	if isTagError(err) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	} else if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"message": "Draft saved successfully", "id": id})
+}
+
+// GetDrafts godoc
+// @Summary List drafts
+// @Description List the drafts and scheduled posts of the logged-in user, the most recently started first. Scheduled posts carry the time they go live in publish_at.
+// @Tags drafts
+// @Produce json
+// @Success 200 {array} models.Post
+// @Failure 401 {object} gin.H
+// @Router /api/v1.0/drafts [get]
+// @Security ApiKeyAuth
+func GetDrafts(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	drafts, err := store.Posts.Drafts(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, drafts)
+}
+
+// GetDraft godoc
+// @Summary Get a draft
+// @Description Retrieve a draft or scheduled post of the logged-in user, its content also rendered to sanitized HTML in content_html.
+// @Tags drafts
+// @Produce json
+// @Param id path int true "Draft ID"
+// @Success 200 {object} models.Post
+// @Failure 400 {object} gin.H
+// @Failure 401 {object} gin.H
+// @Failure 404 {object} gin.H
+// @Router /api/v1.0/drafts/{id} [get]
+// @Security ApiKeyAuth
+func GetDraft(c *gin.Context) {
+	draft, ok := findOwnDraft(c)
+	if !ok {
+		return
+	}
+	draft.ContentHTML = markdown.Render(draft.Content)
+	c.JSON(http.StatusOK, draft)
+}
+
+// UpdateDraft godoc
+// @Summary Update a draft
+// @Description Overwrite a draft or scheduled post of the logged-in user. Setting publish_at schedules it, and leaving it out takes it back to a draft.
+// @Tags drafts
+// @Accept json
+// @Produce json
+// @Param id path int true "Draft ID"
+// @Param draft body object true "Title, content, category or category_id, tags and optional publish_at"
+// @Success 200 {object} gin.H
+// @Failure 400 {object} gin.H
+// @Failure 401 {object} gin.H
+// @Failure 404 {object} gin.H
+// @Router /api/v1.0/drafts/{id} [put]
+// @Security ApiKeyAuth
+func UpdateDraft(c *gin.Context) {
+	current, ok := findOwnDraft(c)
+	if !ok {
+		return
+	}
+	draft, category, ok := bindDraft(c)
+	if !ok {
+		return
+	}
+
+	err := store.Posts.UpdateDraft(current.ID, draft.Title, draft.Content, category.ID, draft.Tags, draft.PublishAt)
+	if isTagError(err) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	} else if errors.Is(err, models.ErrNotFound) {
+		// Published or deleted in the meantime
+		c.JSON(http.StatusNotFound, gin.H{"error": "Draft not found"})
+		return
+	} else if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not update draft"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Draft updated successfully"})
+}
+
+// PublishDraft godoc
+// @Summary Publish a draft
+// @Description Put a draft or scheduled post of the logged-in user live right away. The published post keeps its ID, returned in post_id, and is dated from the time it went live.
+// @Tags drafts
+// @Produce json
+// @Param id path int true "Draft ID"
+// @Success 200 {object} gin.H
+// @Failure 400 {object} gin.H
+// @Failure 401 {object} gin.H
+// @Failure 404 {object} gin.H
+// @Router /api/v1.0/drafts/{id}/publish [post]
+// @Security ApiKeyAuth
+func PublishDraft(c *gin.Context) {
+	draft, ok := findOwnDraft(c)
+	if !ok {
+		return
+	}
+
+	postID, err := store.Posts.Publish(draft.ID)
+	if errors.Is(err, models.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Draft not found"})
+		return
+	} else if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not publish draft"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Post published successfully", "post_id": postID})
+}
+
+// DeleteDraft godoc
+// @Summary Delete a draft
+// @Description Move a draft or scheduled post of the logged-in user to the trash, where it can be restored until it is purged. A scheduled post whose time passed in the meantime goes live once restored.
+// @Tags drafts
+// @Produce json
+// @Param id path int true "Draft ID"
+// @Success 200 {object} gin.H
+// @Failure 400 {object} gin.H
+// @Failure 401 {object} gin.H
+// @Failure 404 {object} gin.H
+// @Router /api/v1.0/drafts/{id} [delete]
+// @Security ApiKeyAuth
+func DeleteDraft(c *gin.Context) {
+	draft, ok := findOwnDraft(c)
+	if !ok {
+		return
+	}
+
+	err := store.Posts.Delete(draft.ID)
+	if errors.Is(err, models.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Draft not found"})
+		return
+	} else if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not delete draft"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Draft moved to the trash"})
+}
+
+// PublishScheduledPosts puts live the scheduled posts whose time has come,
+// logging how many were published. It is run in the background.
+func PublishScheduledPosts() {
+	published, err := store.Posts.PublishDue(time.Now())
+	if err != nil {
+		log.Printf("Publishing scheduled posts failed: %v", err)
+		return
+	}
+	if published > 0 {
+		log.Printf("Published %d scheduled posts", published)
+	}
+}
@@ -1,15 +1,19 @@
 package handlers
 
 import (
-	"database/sql"
+	"errors"
 	"literary-lions/backend/src/internal/models"
+	"literary-lions/backend/src/internal/storage"
 	"log"
 	"net/http"
+	"strconv"
+	"time"
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt"
 )
 
-var db *sql.DB
+// store holds the repositories used by every handler.
+var store *models.Store
 
 type Credentials struct {
 	Email    string `json:"email" binding:"required"`
@@ -28,16 +32,37 @@ type Claims struct {
 	jwt.StandardClaims
 }
 
-// InitHandlers initializes the handlers by setting up the database connection.
-// It sets the global database variable and configures the models package to use this database.
-func InitHandlers(database *sql.DB) {
-	db = database               // Set the global db variable to the provided database connection
-	models.SetDatabase(db)       // Configure the models package to use this database connection
+// reactions holds the reactions offered on posts and comments besides likes and dislikes.
+var reactions models.ReactionSet
+
+// commentMaxDepth is the deepest level replies to comments are nested at.
+var commentMaxDepth = models.DefaultCommentMaxDepth
+
+// trashRetention is how long deleted posts and comments can be restored before they are purged.
+var trashRetention = models.DefaultTrashRetention
+
+// InitHandlers initializes the handlers with the repositories they read from and write to,
+// the configured reaction set, reply nesting limit and trash retention, and the storage of
+// uploaded images with their size limit. The store decides which database backend is used.
+// A maxDepth, retentionDays or uploadMaxMB of 0 keeps the default.
+func InitHandlers(s *models.Store, reactionSet models.ReactionSet, maxDepth, retentionDays int, uploads storage.Storage, uploadMaxMB int) {
+	store = s                // Set the global store used by all handlers
+	reactions = reactionSet // Set the reactions users can add to posts and comments
+	if maxDepth > 0 {
+		commentMaxDepth = maxDepth // Set how deep replies are nested
+	}
+	if retentionDays > 0 {
+		trashRetention = time.Duration(retentionDays) * 24 * time.Hour // Set how long the trash is kept
+	}
+	files = uploads // Set where uploaded images are stored
+	if uploadMaxMB > 0 {
+		uploadMaxBytes = int64(uploadMaxMB) << 20 // Set how large uploaded images can be
+	}
 }
 
 // UpdatePost godoc
 // @Summary Update a post
-// @Description Update an existing post by ID
+// @Description Update an existing post by ID. The category is given by slug or name in category, or by ID in category_id, and must exist. The tags replace the current ones. Only its author and admins may update a post.
 // @Tags post
 // @Accept json
 // @Produce json
@@ -46,11 +71,16 @@ func InitHandlers(database *sql.DB) {
 // @Success 200 {object} gin.H
 // @Failure 400 {object} gin.H
 // @Failure 401 {object} gin.H
+// @Failure 403 {object} gin.H
 // @Failure 404 {object} gin.H
 // @Router /api/post/{id} [put]
 // @Security ApiKeyAuth
 func UpdatePost(c *gin.Context) {
-	id := c.Param("id")  // Retrieve the post ID from the URL path
+	// Retrieve the post and check that the user may change it
+	id, ok := findOwnPost(c, "You can only edit your own posts")
+	if !ok {
+		return
+	}
 
 	var post models.Post
 	// Bind the incoming JSON payload to the post variable
@@ -60,21 +90,25 @@ func UpdatePost(c *gin.Context) {
 		return
 	}
 
-	// Update the post in the database with the new data
-	query := "UPDATE posts SET category_id = $1, title = $2, content = $3 WHERE id = $4"
-	result, err := db.Exec(query, post.Category, post.Title, post.Content, id)
-	if err != nil {
-		// If the update fails, return an internal server error
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not update post"})
+	// Posts can only be filed under an existing category
+	category, ok := findPostCategory(c, post.CategoryID, post.Category)
+	if !ok {
 		return
 	}
 
-	// Check how many rows were affected by the update
-	rowsAffected, _ := result.RowsAffected()
-	if rowsAffected == 0 {
+	// Update the post in the database with the new data
+	err := store.Posts.Update(id, c.GetInt("userID"), post.Title, post.Content, category.ID, post.Tags)
+	if isTagError(err) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	} else if errors.Is(err, models.ErrNotFound) {
 		// If no rows were affected, the post was not found, return a 404 error
 		c.JSON(http.StatusNotFound, gin.H{"error": "Post not found"})
 		return
+	} else if err != nil {
+		// If the update fails, return an internal server error
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not update post"})
+		return
 	}
 
 	// Return a success message if the post was updated successfully
@@ -83,7 +117,7 @@ func UpdatePost(c *gin.Context) {
 
 // DeletePost godoc
 // @Summary Delete a post
-// @Description Delete a post by ID
+// @Description Move a post to the trash, hiding it along with its comments. Its author can restore it until it is purged with its comments and the reactions to them. Only its author and admins may delete a post.
 // @Tags post
 // @Accept json
 // @Produce json
@@ -91,33 +125,64 @@ func UpdatePost(c *gin.Context) {
 // @Success 200 {object} gin.H
 // @Failure 400 {object} gin.H
 // @Failure 401 {object} gin.H
+// @Failure 403 {object} gin.H
 // @Failure 404 {object} gin.H
 // @Router /api/post/{id} [delete]
 // @Security ApiKeyAuth
 func DeletePost(c *gin.Context) {
-	id := c.Param("id")  // Retrieve the post ID from the URL path
-
-	// Delete the post from the database
-	query := "DELETE FROM posts WHERE id = $1"
-	result, err := db.Exec(query, id)
-	if err != nil {
-		// If the deletion fails, return an internal server error
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not delete post"})
+	// Retrieve the post and check that the user may delete it
+	id, ok := findOwnPost(c, "You can only delete your own posts")
+	if !ok {
 		return
 	}
 
-	// Check how many rows were affected by the deletion
-	rowsAffected, _ := result.RowsAffected()
-	if rowsAffected == 0 {
+	// Delete the post from the database
+	err := store.Posts.Delete(id)
+	if errors.Is(err, models.ErrNotFound) {
 		// If no rows were affected, the post was not found, return a 404 error
 		c.JSON(http.StatusNotFound, gin.H{"error": "Post not found"})
 		return
+	} else if err != nil {
+		// If the deletion fails, return an internal server error
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not delete post"})
+		return
 	}
 
 	// Return a success message if the post was deleted successfully
 	c.JSON(http.StatusOK, gin.H{"message": "Post deleted successfully"})
 }
 
+// findOwnPost looks up the post named by the id path parameter and checks that
+// the logged-in user wrote it or is an admin. Otherwise it responds with the
+// matching error, using forbidden for 403 Forbidden, and returns false.
+func findOwnPost(c *gin.Context, forbidden string) (int, bool) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return 0, false
+	}
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid post ID"})
+		return 0, false
+	}
+
+	post, err := store.Posts.GetByID(id)
+	if errors.Is(err, models.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Post not found"})
+		return 0, false
+	} else if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return 0, false
+	}
+
+	if !authorizeAuthor(c, userID.(int), post.UserID, forbidden) {
+		return 0, false
+	}
+	return id, true
+}
+
 // GetAllUsers godoc
 // @Summary Get all users
 // @Description Retrieve all users from the database
@@ -130,26 +195,12 @@ func DeletePost(c *gin.Context) {
 // @Security ApiKeyAuth
 func GetAllUsers(c *gin.Context) {
 	// Query the database to retrieve all users
-	rows, err := db.Query("SELECT id, username, email, role FROM users")
+	users, err := store.Users.List()
 	if err != nil {
 		// If the query fails, return an internal server error
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not retrieve users"})
 		return
 	}
-	defer rows.Close()  // Ensure the rows are closed after use
-
-	var users []models.User
-	// Iterate over the rows and scan the user data into the user struct
-	for rows.Next() {
-		var user models.User
-		if err := rows.Scan(&user.ID, &user.Username, &user.Email, &user.Role); err != nil {
-			// If scanning fails, return an internal server error
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not scan user data"})
-			return
-		}
-		// Append the user to the users slice
-		users = append(users, user)
-	}
 
 	// Return the list of users in the response
 	c.JSON(http.StatusOK, users)
@@ -168,22 +219,21 @@ func GetAllUsers(c *gin.Context) {
 // @Router /api/users/{id} [get]
 // @Security ApiKeyAuth
 func GetUser(c *gin.Context) {
-	id := c.Param("id")  // Retrieve the user ID from the URL path
-	var user models.User
+	// Retrieve the user ID from the URL path
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
 
 	// Query the database to retrieve the user by ID
-	query := "SELECT id, username, email, role FROM users WHERE id = $1"
-	err := db.QueryRow(query, id).Scan(&user.ID, &user.Username, &user.Email, &user.Role)
+	user, err := store.Users.GetByID(id)
 	if err != nil {
-		if err == sql.ErrNoRows {
-			// If no rows were found, return a 404 error
-			c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
-		} else {
-			// If the query fails, return an internal server error
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not retrieve user"})
-		}
+		// If no user was found, return a 404 error
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
 		return
 	}
+	user.Password = "" // Never expose the password hash
 
 	// Return the user data in the response
 	c.JSON(http.StatusOK, user)
@@ -204,7 +254,12 @@ func GetUser(c *gin.Context) {
 // @Router /api/users/{id} [put]
 // @Security ApiKeyAuth
 func UpdateUser(c *gin.Context) {
-	id := c.Param("id")  // Retrieve the user ID from the URL path
+	// Retrieve the user ID from the URL path
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
 	var user models.User
 
 	// Bind the incoming JSON payload to the user variable
@@ -215,20 +270,16 @@ func UpdateUser(c *gin.Context) {
 	}
 
 	// Update the user in the database with the new data
-	query := "UPDATE users SET username = $1, email = $2, role = $3 WHERE id = $4"
-	result, err := db.Exec(query, user.Username, user.Email, user.Role, id)
-	if err != nil {
-		// If the update fails, return an internal server error
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not update user"})
-		return
-	}
-
-	// Check how many rows were affected by the update
-	rowsAffected, _ := result.RowsAffected()
-	if rowsAffected == 0 {
+	user.ID = id
+	err = store.Users.Update(user)
+	if errors.Is(err, models.ErrNotFound) {
 		// If no rows were affected, the user was not found, return a 404 error
 		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
 		return
+	} else if err != nil {
+		// If the update fails, return an internal server error
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not update user"})
+		return
 	}
 
 	// Return a success message if the user was updated successfully
@@ -249,23 +300,23 @@ func UpdateUser(c *gin.Context) {
 // @Router /api/users/{id} [delete]
 // @Security ApiKeyAuth
 func DeleteUser(c *gin.Context) {
-	id := c.Param("id")  // Retrieve the user ID from the URL path
-
-	// Delete the user from the database
-	query := "DELETE FROM users WHERE id = $1"
-	result, err := db.Exec(query, id)
+	// Retrieve the user ID from the URL path
+	id, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
-		// If the deletion fails, return an internal server error
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not delete user"})
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
 		return
 	}
 
-	// Check how many rows were affected by the deletion
-	rowsAffected, _ := result.RowsAffected()
-	if rowsAffected == 0 {
+	// Delete the user from the database
+	err = store.Users.Delete(id)
+	if errors.Is(err, models.ErrNotFound) {
 		// If no rows were affected, the user was not found, return a 404 error
 		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
 		return
+	} else if err != nil {
+		// If the deletion fails, return an internal server error
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not delete user"})
+		return
 	}
 
 	// Return a success message if the user was deleted successfully
@@ -279,16 +330,21 @@ func DeleteUser(c *gin.Context) {
 // @Accept json
 // @Produce json
 // @Param id path int true "User ID"
-// @Param role body string true "New role"
+// @Param role body string true "New role: user, curator or admin"
 // @Success 200 {object} gin.H
 // @Failure 400 {object} gin.H
 // @Failure 401 {object} gin.H
 // @Failure 403 {object} gin.H
 // @Failure 404 {object} gin.H
-// @Router /api/users/{id}/role [put]
+// @Router /api/v1.0/admin/users/{id}/role [put]
 // @Security ApiKeyAuth
 func UpdateUserRole(c *gin.Context) {
-	id := c.Param("id")  // Retrieve the user ID from the URL path
+	// Retrieve the user ID from the URL path
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
 	var requestBody struct {
 		Role string `json:"role"`
 	}
@@ -301,38 +357,39 @@ func UpdateUserRole(c *gin.Context) {
 	}
 
 	// Check if the provided role is valid
-	if requestBody.Role != "admin" && requestBody.Role != "user" {
+	if requestBody.Role != "admin" && requestBody.Role != "curator" && requestBody.Role != "user" {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid role"})
 		return
 	}
 
 	// Update the user's role in the database
-	res, err := db.Exec("UPDATE users SET role = ? WHERE id = ?", requestBody.Role, id)
-	if err != nil {
+	err = store.Users.UpdateRole(id, requestBody.Role)
+	if errors.Is(err, models.ErrNotFound) {
+		// If no rows were affected, the user was not found, return a 404 error
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	} else if err != nil {
 		// If the update fails, return an internal server error
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not update user role"})
 		return
 	}
 
-	// Check how many rows were affected by the update
-	rowsAffected, err := res.RowsAffected()
-	if err != nil {
-		// If there's an error retrieving affected rows, return an internal server error
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not retrieve affected rows"})
-		return
-	}
-
-	// If no rows were affected, the user was not found, return a 404 error
-	if rowsAffected == 0 {
-		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
-		return
-	}
-
 	// Return a success message if the user's role was updated successfully
 	c.JSON(http.StatusOK, gin.H{"message": "User role updated successfully"})
 }
 
-// UpdateUserProfile handles the user profile update request
+// UpdateUserProfile godoc
+// @Summary Update the profile of the logged-in user
+// @Description Update the email, username, bio, location and favorite books and genres of the logged-in user, and whether others may list the posts they like. Fields left out of the request are kept. Favorites are lists of at most 10 entries; repeated and empty ones are dropped.
+// @Tags users
+// @Accept json
+// @Produce json
+// @Param profile body object true "email, username, and optional bio, location, favorite_books, favorite_genres and hide_liked_posts"
+// @Success 200 {object} models.User
+// @Failure 400 {object} gin.H
+// @Failure 401 {object} gin.H
+// @Router /api/v1.0/userprofile-update [put]
+// @Security ApiKeyAuth
 func UpdateUserProfile(c *gin.Context) {
 	// Retrieve the user ID from the context (assuming it's set by the middleware)
 	userID, exists := c.Get("userID")
@@ -343,10 +400,16 @@ func UpdateUserProfile(c *gin.Context) {
 		return
 	}
 
-	// Define the data structure for the expected input
+	// Define the data structure for the expected input; profile fields that are
+	// left out stay as they are
 	var data struct {
-		Email     string `json:"email" binding:"required"`
-		Username  string `json:"username" binding:"required"`
+		Email          string    `json:"email" binding:"required"`
+		Username       string    `json:"username" binding:"required"`
+		Bio            *string   `json:"bio"`
+		Location       *string   `json:"location"`
+		FavoriteBooks  *[]string `json:"favorite_books"`
+		FavoriteGenres *[]string `json:"favorite_genres"`
+		HideLikedPosts *bool     `json:"hide_liked_posts"`
 	}
 
 	// Bind the incoming JSON data to the data struct
@@ -356,22 +419,49 @@ func UpdateUserProfile(c *gin.Context) {
 		return
 	}
 
-	// Call the ProfileUpdate function to update the user's profile in the database
-	err := models.ProfileUpdate(userID.(int), data.Email, data.Username)
+	// Start from the current profile
+	user, err := store.Users.GetByID(userID.(int))
 	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	user.Email, user.Username = data.Email, data.Username
+	if data.Bio != nil {
+		user.Bio = *data.Bio
+	}
+	if data.Location != nil {
+		user.Location = *data.Location
+	}
+	if data.FavoriteBooks != nil {
+		user.FavoriteBooks = *data.FavoriteBooks
+	}
+	if data.FavoriteGenres != nil {
+		user.FavoriteGenres = *data.FavoriteGenres
+	}
+	if data.HideLikedPosts != nil {
+		user.HideLikedPosts = *data.HideLikedPosts
+	}
+
+	// Call the ProfileUpdate function to update the user's profile in the database
+	err = store.Users.UpdateProfile(*user)
+	if errors.Is(err, models.ErrInvalidProfile) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	} else if err != nil {
 		// If the update fails, return an internal server error
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
 	// Retrieve the updated user data from the database
-	user, err := models.GetUser(userID.(int))
+	user, err = store.Users.GetByID(userID.(int))
 	if err != nil {
 		// If there's an error retrieving the user, return an internal server error
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
-	
-	// Return a success message with the updated user data
-	c.JSON(http.StatusOK, gin.H{"username": user.Username, "email": user.Email})
+	user.Password = "" // Never expose the password hash
+
+	// Return the updated user data
+	c.JSON(http.StatusOK, user)
 }
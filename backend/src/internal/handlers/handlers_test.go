@@ -0,0 +1,382 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"literary-lions/backend/src/internal/db"
+	"literary-lions/backend/src/internal/models"
+	"literary-lions/backend/src/internal/storage"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// newTestRouter initializes the handlers with a freshly migrated SQLite store and
+// returns a router serving the routes under test behind the middleware main puts
+// them behind.
+func newTestRouter(t *testing.T) *gin.Engine {
+	t.Helper()
+
+	database, err := db.InitDB(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("init database: %v", err)
+	}
+	t.Cleanup(func() { database.Close() })
+
+	reactionSet, err := models.NewReactionSet(nil)
+	if err != nil {
+		t.Fatalf("reaction set: %v", err)
+	}
+	uploads, err := storage.NewLocal(filepath.Join(t.TempDir(), "uploads"))
+	if err != nil {
+		t.Fatalf("upload storage: %v", err)
+	}
+	InitHandlers(models.NewStore(database), reactionSet, 0, 0, uploads, 0)
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	api := r.Group("/api/v1.0")
+	api.GET("/clubs/:slug", GetClub)
+	api.GET("/post/:id", GetPostByID)
+	api.GET("/users/:username/comments", GetUserComments)
+
+	api.Use(AuthMiddleware("user"))
+	api.PUT("/post/:id", UpdatePost)
+	api.DELETE("/post/:id", DeletePost)
+	api.POST("/post/:id/comment", ClubDiscussionMiddleware(models.ReactionTargetPost), AddComment)
+	api.POST("/post/:id/like", ClubDiscussionMiddleware(models.ReactionTargetPost), LikePost)
+	api.POST("/post/:id/poll/vote", ClubDiscussionMiddleware(models.ReactionTargetPost), VotePoll)
+	api.POST("/comment/:id/reply", ClubDiscussionMiddleware(models.ReactionTargetComment), ReplyToComment)
+	api.PUT("/comment/:id", UpdateComment)
+	api.DELETE("/comment/:id", DeleteComment)
+	api.GET("/drafts/:id", GetDraft)
+	api.PUT("/drafts/:id", UpdateDraft)
+	api.POST("/drafts/:id/publish", PublishDraft)
+	api.POST("/clubs/:slug/join", JoinClub)
+
+	member := api.Group("/clubs/:slug", ClubMiddleware())
+	member.POST("/posts", CreateClubPost)
+	member.PUT("/schedule/:section_id/read", MarkSectionRead)
+	return r
+}
+
+// testUser is a registered member with an open session.
+type testUser struct {
+	ID    int
+	Token string
+}
+
+// mustLogin registers a member with the given username and opens a session for them.
+func mustLogin(t *testing.T, username string) testUser {
+	t.Helper()
+
+	if err := store.Users.Register(username+"@mail.com", username, "password123"); err != nil {
+		t.Fatalf("register %s: %v", username, err)
+	}
+	user, err := store.Users.GetByUsername(username)
+	if err != nil {
+		t.Fatalf("get %s: %v", username, err)
+	}
+	token, err := store.Sessions.Create(user.ID)
+	if err != nil {
+		t.Fatalf("log %s in: %v", username, err)
+	}
+	return testUser{ID: user.ID, Token: token}
+}
+
+// mustCategory returns the ID of the category with the given slug or name.
+func mustCategory(t *testing.T, ref string) int {
+	t.Helper()
+
+	category, err := store.Categories.Find(ref)
+	if err != nil {
+		t.Fatalf("find category %q: %v", ref, err)
+	}
+	return category.ID
+}
+
+// serve sends a request to the router as the given member, or as a guest if
+// user is nil, with body encoded as JSON unless nil, and returns the response.
+func serve(r *gin.Engine, user *testUser, method, path string, body interface{}) *httptest.ResponseRecorder {
+	var payload bytes.Buffer
+	if body != nil {
+		json.NewEncoder(&payload).Encode(body)
+	}
+	req := httptest.NewRequest(method, path, &payload)
+	req.Header.Set("Content-Type", "application/json")
+	if user != nil {
+		req.AddCookie(&http.Cookie{Name: "session_token", Value: user.Token})
+	}
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+// expectStatus fails the test unless the response has the wanted status.
+func expectStatus(t *testing.T, name string, w *httptest.ResponseRecorder, want int) {
+	t.Helper()
+
+	if w.Code != want {
+		t.Errorf("%s: status = %d, want %d; body %s", name, w.Code, want, w.Body)
+	}
+}
+
+func TestPrivateClubAccess(t *testing.T) {
+	r := newTestRouter(t)
+	owner := mustLogin(t, "owner")
+	pending := mustLogin(t, "pending")
+	outsider := mustLogin(t, "outsider")
+
+	club, err := store.Clubs.Create(models.Club{Name: "Night Readers", Visibility: models.ClubPrivate, OwnerID: owner.ID})
+	if err != nil {
+		t.Fatalf("create club: %v", err)
+	}
+	postID, err := store.Posts.CreateInClub(club.ID, 0, owner.ID, "Members only", "Not for everyone", mustCategory(t, "Random"), nil)
+	if err != nil {
+		t.Fatalf("create club post: %v", err)
+	}
+	poll := models.Poll{PostID: postID, Question: "Which book next?", Options: []models.PollOption{{Label: "Emma"}, {Label: "Persuasion"}}}
+	if _, err := store.Polls.Create(poll); err != nil {
+		t.Fatalf("create poll: %v", err)
+	}
+	expectStatus(t, "join", serve(r, &pending, http.MethodPost, "/api/v1.0/clubs/"+club.Slug+"/join", nil), http.StatusOK)
+
+	post := "/api/v1.0/post/" + strconv.Itoa(postID)
+	newPost := gin.H{"title": "Let me in", "content": "Hello", "category": "Random"}
+	comment := gin.H{"content": "Hello"}
+	vote := gin.H{"option_ids": []int{1}}
+	for name, user := range map[string]*testUser{"guest": nil, "pending": &pending, "outsider": &outsider} {
+		expectStatus(t, name+" reading the post", serve(r, user, http.MethodGet, post, nil), http.StatusNotFound)
+		if user == nil {
+			continue
+		}
+		expectStatus(t, name+" posting", serve(r, user, http.MethodPost, "/api/v1.0/clubs/"+club.Slug+"/posts", newPost), http.StatusForbidden)
+		expectStatus(t, name+" commenting", serve(r, user, http.MethodPost, post+"/comment", comment), http.StatusForbidden)
+		expectStatus(t, name+" liking", serve(r, user, http.MethodPost, post+"/like", nil), http.StatusForbidden)
+		expectStatus(t, name+" voting", serve(r, user, http.MethodPost, post+"/poll/vote", vote), http.StatusForbidden)
+	}
+
+	// Once approved, the member takes part
+	if err := store.Clubs.Approve(club.ID, pending.ID); err != nil {
+		t.Fatalf("approve: %v", err)
+	}
+	expectStatus(t, "member reading the post", serve(r, &pending, http.MethodGet, post, nil), http.StatusOK)
+	expectStatus(t, "member posting", serve(r, &pending, http.MethodPost, "/api/v1.0/clubs/"+club.Slug+"/posts", newPost), http.StatusCreated)
+	expectStatus(t, "member commenting", serve(r, &pending, http.MethodPost, post+"/comment", comment), http.StatusCreated)
+	expectStatus(t, "member voting", serve(r, &pending, http.MethodPost, post+"/poll/vote", vote), http.StatusOK)
+}
+
+func TestAuthorOnlyChanges(t *testing.T) {
+	r := newTestRouter(t)
+	author := mustLogin(t, "author")
+	other := mustLogin(t, "other")
+
+	postID, err := store.Posts.Create(author.ID, "Mine", "My own words", mustCategory(t, "Random"), nil)
+	if err != nil {
+		t.Fatalf("create post: %v", err)
+	}
+	if err := store.Comments.Create(postID, author.ID, "My own comment"); err != nil {
+		t.Fatalf("create comment: %v", err)
+	}
+	comments, err := store.Comments.GetByPostID(postID, models.PageRequest{}, 0)
+	if err != nil || len(comments.Comments) != 1 {
+		t.Fatalf("comments = %+v, %v", comments, err)
+	}
+
+	post := "/api/v1.0/post/" + strconv.Itoa(postID)
+	comment := "/api/v1.0/comment/" + strconv.Itoa(comments.Comments[0].ID)
+	edit := gin.H{"title": "Theirs", "content": "Other words", "category": "Random"}
+	expectStatus(t, "editing another's post", serve(r, &other, http.MethodPut, post, edit), http.StatusForbidden)
+	expectStatus(t, "deleting another's post", serve(r, &other, http.MethodDelete, post, nil), http.StatusForbidden)
+	expectStatus(t, "editing another's comment", serve(r, &other, http.MethodPut, comment, gin.H{"content": "Other words"}), http.StatusForbidden)
+	expectStatus(t, "deleting another's comment", serve(r, &other, http.MethodDelete, comment, nil), http.StatusForbidden)
+	if got, err := store.Posts.GetByID(postID); err != nil || got.Title != "Mine" {
+		t.Errorf("post after refused changes = %+v, %v", got, err)
+	}
+
+	// The author changes them
+	expectStatus(t, "editing own comment", serve(r, &author, http.MethodPut, comment, gin.H{"content": "Better words"}), http.StatusOK)
+	expectStatus(t, "deleting own comment", serve(r, &author, http.MethodDelete, comment, nil), http.StatusOK)
+	expectStatus(t, "editing own post", serve(r, &author, http.MethodPut, post, edit), http.StatusOK)
+	expectStatus(t, "deleting own post", serve(r, &author, http.MethodDelete, post, nil), http.StatusOK)
+}
+
+func TestDraftsHiddenFromOthers(t *testing.T) {
+	r := newTestRouter(t)
+	author := mustLogin(t, "author")
+	other := mustLogin(t, "other")
+
+	draftID, err := store.Posts.SaveDraft(author.ID, "Not yet", "Still thinking", mustCategory(t, "Random"), nil, nil)
+	if err != nil {
+		t.Fatalf("save draft: %v", err)
+	}
+
+	draft := "/api/v1.0/drafts/" + strconv.Itoa(draftID)
+	post := "/api/v1.0/post/" + strconv.Itoa(draftID)
+	edit := gin.H{"title": "Taken over", "content": "Other words", "category": "Random"}
+	for name, user := range map[string]*testUser{"guest": nil, "other": &other} {
+		expectStatus(t, name+" reading the post", serve(r, user, http.MethodGet, post, nil), http.StatusNotFound)
+		if user == nil {
+			continue
+		}
+		expectStatus(t, name+" reading the draft", serve(r, user, http.MethodGet, draft, nil), http.StatusNotFound)
+		expectStatus(t, name+" editing the draft", serve(r, user, http.MethodPut, draft, edit), http.StatusNotFound)
+		expectStatus(t, name+" publishing the draft", serve(r, user, http.MethodPost, draft+"/publish", nil), http.StatusNotFound)
+		expectStatus(t, name+" commenting", serve(r, user, http.MethodPost, post+"/comment", gin.H{"content": "Hello"}), http.StatusNotFound)
+		expectStatus(t, name+" liking", serve(r, user, http.MethodPost, post+"/like", nil), http.StatusNotFound)
+	}
+
+	expectStatus(t, "author reading the draft", serve(r, &author, http.MethodGet, draft, nil), http.StatusOK)
+	expectStatus(t, "author publishing the draft", serve(r, &author, http.MethodPost, draft+"/publish", nil), http.StatusOK)
+	expectStatus(t, "other reading the published post", serve(r, &other, http.MethodGet, post, nil), http.StatusOK)
+}
+
+func TestLockedPostDiscussion(t *testing.T) {
+	r := newTestRouter(t)
+	owner := mustLogin(t, "owner")
+	reader := mustLogin(t, "reader")
+
+	club, err := store.Clubs.Create(models.Club{Name: "Slow Readers", OwnerID: owner.ID})
+	if err != nil {
+		t.Fatalf("create club: %v", err)
+	}
+	if _, err := store.Clubs.Join(club.ID, reader.ID); err != nil {
+		t.Fatalf("join: %v", err)
+	}
+	section, err := store.Schedules.Create(models.ClubSection{ClubID: club.ID, Title: "Chapters 1-5", UnlocksAt: time.Now().Add(-time.Hour)})
+	if err != nil {
+		t.Fatalf("create section: %v", err)
+	}
+	postID, err := store.Posts.CreateInClub(club.ID, section.ID, owner.ID, "The ending", "Spoilers ahead", mustCategory(t, "Random"), nil)
+	if err != nil {
+		t.Fatalf("create club post: %v", err)
+	}
+	if err := store.Comments.Create(postID, owner.ID, "More spoilers"); err != nil {
+		t.Fatalf("create comment: %v", err)
+	}
+	comments, err := store.Comments.GetByPostID(postID, models.PageRequest{}, 0)
+	if err != nil || len(comments.Comments) != 1 {
+		t.Fatalf("comments = %+v, %v", comments, err)
+	}
+
+	// The member has not marked the section read yet
+	post := "/api/v1.0/post/" + strconv.Itoa(postID)
+	reply := "/api/v1.0/comment/" + strconv.Itoa(comments.Comments[0].ID) + "/reply"
+	w := serve(r, &reader, http.MethodGet, post, nil)
+	expectStatus(t, "reading the locked post", w, http.StatusOK)
+	var detail struct {
+		Post     models.Post      `json:"post"`
+		Comments []models.Comment `json:"comments"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &detail); err != nil || !detail.Post.Locked || detail.Post.Content != "" || len(detail.Comments) != 0 {
+		t.Errorf("locked post = %+v, %v; want its content and comments held back", detail, err)
+	}
+	expectStatus(t, "commenting on the locked post", serve(r, &reader, http.MethodPost, post+"/comment", gin.H{"content": "What?"}), http.StatusForbidden)
+	expectStatus(t, "liking the locked post", serve(r, &reader, http.MethodPost, post+"/like", nil), http.StatusForbidden)
+	expectStatus(t, "replying in the locked post", serve(r, &reader, http.MethodPost, reply, gin.H{"content": "What?"}), http.StatusForbidden)
+
+	read := "/api/v1.0/clubs/" + club.Slug + "/schedule/" + strconv.Itoa(section.ID) + "/read"
+	expectStatus(t, "marking the section read", serve(r, &reader, http.MethodPut, read, nil), http.StatusOK)
+	expectStatus(t, "commenting on the unlocked post", serve(r, &reader, http.MethodPost, post+"/comment", gin.H{"content": "Wow"}), http.StatusCreated)
+	expectStatus(t, "replying in the unlocked post", serve(r, &reader, http.MethodPost, reply, gin.H{"content": "Wow"}), http.StatusCreated)
+}
+
+func TestCommentJSON(t *testing.T) {
+	r := newTestRouter(t)
+	author := mustLogin(t, "author")
+
+	postID, err := store.Posts.Create(author.ID, "Emma", "Matchmaking", mustCategory(t, "Random"), nil)
+	if err != nil {
+		t.Fatalf("create post: %v", err)
+	}
+	if err := store.Comments.Create(postID, author.ID, "Well said"); err != nil {
+		t.Fatalf("create comment: %v", err)
+	}
+
+	// Every field of a comment is sent under a snake_case key
+	pages := map[string]string{
+		"post page":        "/api/v1.0/post/" + strconv.Itoa(postID),
+		"comments of user": "/api/v1.0/users/author/comments",
+	}
+	keys := []string{"id", "post_id", "parent_id", "depth", "user_id", "username", "likes", "dislikes", "content", "content_html", "created_at", "deleted", "editable"}
+	for name, path := range pages {
+		w := serve(r, &author, http.MethodGet, path, nil)
+		expectStatus(t, name, w, http.StatusOK)
+		var body struct {
+			Comments []map[string]interface{} `json:"comments"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil || len(body.Comments) != 1 {
+			t.Fatalf("%s: comments = %s, %v", name, w.Body, err)
+		}
+		comment := body.Comments[0]
+		for _, key := range keys {
+			if _, ok := comment[key]; !ok {
+				t.Errorf("%s: comment has no %q key: %v", name, key, comment)
+			}
+		}
+		for key := range comment {
+			if key != strings.ToLower(key) {
+				t.Errorf("%s: comment has the key %q, want snake_case", name, key)
+			}
+		}
+		if comment["user_id"] != float64(author.ID) || comment["username"] != "author" || comment["content"] != "Well said" {
+			t.Errorf("%s: comment = %v", name, comment)
+		}
+	}
+}
+
+func TestHandlersRequireLogin(t *testing.T) {
+	newTestRouter(t)
+
+	// Handlers registered without AuthMiddleware, as on the root router, must not
+	// act as user 0
+	tests := []struct {
+		method, route, path string
+		handler             gin.HandlerFunc
+	}{
+		{http.MethodPost, "/post/:id/reactions/:reaction", "/post/1/reactions/funny", AddPostReaction},
+		{http.MethodDelete, "/comment/:id/reactions/:reaction", "/comment/1/reactions/funny", RemoveCommentReaction},
+		{http.MethodPost, "/comment/:id/reply", "/comment/1/reply", ReplyToComment},
+		{http.MethodPut, "/comment/:id", "/comment/1", UpdateComment},
+		{http.MethodDelete, "/comment/:id", "/comment/1", DeleteComment},
+		{http.MethodPost, "/uploads", "/uploads", UploadImage},
+		{http.MethodPost, "/drafts", "/drafts", SaveDraft},
+		{http.MethodGet, "/drafts", "/drafts", GetDrafts},
+		{http.MethodGet, "/drafts/:id", "/drafts/1", GetDraft},
+		{http.MethodPut, "/drafts/:id", "/drafts/1", UpdateDraft},
+		{http.MethodDelete, "/drafts/:id", "/drafts/1", DeleteDraft},
+		{http.MethodPost, "/drafts/:id/publish", "/drafts/1/publish", PublishDraft},
+		{http.MethodPost, "/post/:id/poll/vote", "/post/1/poll/vote", VotePoll},
+		{http.MethodPut, "/userprofile-avatar", "/userprofile-avatar", UploadAvatar},
+		{http.MethodDelete, "/userprofile-avatar", "/userprofile-avatar", DeleteAvatar},
+		{http.MethodGet, "/trash", "/trash", GetTrash},
+		{http.MethodPost, "/books", "/books", CreateBook},
+		{http.MethodPut, "/post/:id/books", "/post/1/books", SetPostBooks},
+		{http.MethodPut, "/drafts/:id/books", "/drafts/1/books", SetDraftBooks},
+		{http.MethodPost, "/books/:id/reviews", "/books/1/reviews", CreateReview},
+		{http.MethodPut, "/reviews/:id", "/reviews/1", UpdateReview},
+		{http.MethodDelete, "/reviews/:id", "/reviews/1", DeleteReview},
+		{http.MethodGet, "/shelves", "/shelves", GetShelves},
+		{http.MethodGet, "/shelves/stats", "/shelves/stats", GetReadingStats},
+		{http.MethodPut, "/shelves/:book_id", "/shelves/1", SetShelf},
+		{http.MethodDelete, "/shelves/:book_id", "/shelves/1", RemoveFromShelf},
+		{http.MethodPost, "/clubs", "/clubs", CreateClub},
+		{http.MethodPost, "/clubs/:slug/join", "/clubs/readers/join", JoinClub},
+		{http.MethodDelete, "/clubs/:slug/membership", "/clubs/readers/membership", LeaveClub},
+		{http.MethodPost, "/clubs/:slug/posts", "/clubs/readers/posts", ClubMiddleware()},
+		{http.MethodPost, "/post/:id/restore", "/post/1/restore", RestorePost},
+		{http.MethodPost, "/comment/:id/restore", "/comment/1/restore", RestoreComment},
+	}
+	for _, tt := range tests {
+		r := gin.New()
+		r.Handle(tt.method, tt.route, tt.handler)
+		expectStatus(t, tt.method+" "+tt.path, serve(r, nil, tt.method, tt.path, nil), http.StatusUnauthorized)
+	}
+}
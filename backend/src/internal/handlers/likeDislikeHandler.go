@@ -1,10 +1,11 @@
 package handlers
 
 import (
+	"errors"
+	"literary-lions/backend/src/internal/models"
 	"log"
 	"net/http"
 	"strconv"
-	"literary-lions/backend/src/internal/models"
 	"github.com/gin-gonic/gin"
 )
 
@@ -18,6 +19,7 @@ import (
 // @Success 200 {object} gin.H
 // @Failure 400 {object} gin.H
 // @Failure 401 {object} gin.H
+// @Failure 404 {object} gin.H
 // @Router /api/post/{id}/like [post]
 // @Security ApiKeyAuth
 func LikePost(c *gin.Context) {
@@ -39,8 +41,12 @@ func LikePost(c *gin.Context) {
     }
 
     // Call the function to like or unlike the post
-    err = models.PostLikeAndUnlike(userID.(int), postID)
-    if err != nil {
+    err = store.Reactions.TogglePostLike(userID.(int), postID)
+    if errors.Is(err, models.ErrNotFound) {
+        // Drafts and posts or comments in the trash cannot be liked or disliked
+        c.JSON(http.StatusNotFound, gin.H{"error": "Post not found"})
+        return
+    } else if err != nil {
         // If the operation fails, return an internal server error
         c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
         return
@@ -60,6 +66,7 @@ func LikePost(c *gin.Context) {
 // @Success 200 {object} gin.H
 // @Failure 400 {object} gin.H
 // @Failure 401 {object} gin.H
+// @Failure 404 {object} gin.H
 // @Router /api/post/{id}/dislike [post]
 // @Security ApiKeyAuth
 func DislikePost(c *gin.Context) {
@@ -81,8 +88,12 @@ func DislikePost(c *gin.Context) {
     }
 
     // Call the function to dislike or undislike the post
-    err = models.PostDisLikeAndUndislike(userID.(int), postID)
-    if err != nil {
+    err = store.Reactions.TogglePostDislike(userID.(int), postID)
+    if errors.Is(err, models.ErrNotFound) {
+        // Drafts and posts or comments in the trash cannot be liked or disliked
+        c.JSON(http.StatusNotFound, gin.H{"error": "Post not found"})
+        return
+    } else if err != nil {
         // If the operation fails, return an internal server error
         c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
         return
@@ -102,6 +113,7 @@ func DislikePost(c *gin.Context) {
 // @Success 200 {object} gin.H
 // @Failure 400 {object} gin.H
 // @Failure 401 {object} gin.H
+// @Failure 404 {object} gin.H
 // @Router /api/comment/{id}/like [post]
 // @Security ApiKeyAuth
 func LikeComment(c *gin.Context) {
@@ -123,8 +135,12 @@ func LikeComment(c *gin.Context) {
     }
 
     // Call the function to like or unlike the comment
-    err = models.CommentLikeAndUnlike(userID.(int), commentID)
-    if err != nil {
+    err = store.Reactions.ToggleCommentLike(userID.(int), commentID)
+    if errors.Is(err, models.ErrNotFound) {
+        // Drafts and posts or comments in the trash cannot be liked or disliked
+        c.JSON(http.StatusNotFound, gin.H{"error": "Comment not found"})
+        return
+    } else if err != nil {
         // If the operation fails, return an internal server error
         c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
         return
@@ -144,6 +160,7 @@ func LikeComment(c *gin.Context) {
 // @Success 200 {object} gin.H
 // @Failure 400 {object} gin.H
 // @Failure 401 {object} gin.H
+// @Failure 404 {object} gin.H
 // @Router /api/comment/{id}/dislike [post]
 // @Security ApiKeyAuth
 func DislikeComment(c *gin.Context) {
@@ -165,8 +182,12 @@ func DislikeComment(c *gin.Context) {
     }
 
     // Call the function to dislike or undislike the comment
-    err = models.CommentDisLikeAndUndislike(userID.(int), commentID)
-    if err != nil {
+    err = store.Reactions.ToggleCommentDislike(userID.(int), commentID)
+    if errors.Is(err, models.ErrNotFound) {
+        // Drafts and posts or comments in the trash cannot be liked or disliked
+        c.JSON(http.StatusNotFound, gin.H{"error": "Comment not found"})
+        return
+    } else if err != nil {
         // If the operation fails, log the error and return an internal server error
         log.Print("error: ", err.Error())
         c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
@@ -0,0 +1,47 @@
+package handlers
+
+import (
+	"literary-lions/backend/src/internal/markdown"
+	"literary-lions/backend/src/internal/models"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxPreviewLength is the longest content, in bytes, the preview renders.
+const maxPreviewLength = 64 << 10
+
+// PreviewMarkdown godoc
+// @Summary Preview Markdown
+// @Description Render the Markdown of a post or comment being written to the sanitized HTML it will be shown as: paragraphs, quotes, lists, spoiler blocks, emphasis, inline code and links. Raw HTML is shown as text.
+// @Tags posts
+// @Accept json
+// @Produce json
+// @Param content body object true "Markdown to render, as {\"content\": \"...\"}"
+// @Success 200 {object} gin.H
+// @Failure 400 {object} gin.H
+// @Failure 401 {object} gin.H
+// @Router /api/v1.0/markdown/preview [post]
+// @Security ApiKeyAuth
+func PreviewMarkdown(c *gin.Context) {
+	var preview struct {
+		Content string `json:"content"`
+	}
+	if err := c.ShouldBindJSON(&preview); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request payload"})
+		return
+	}
+	if len(preview.Content) > maxPreviewLength {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Content is too long to preview"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"html": markdown.Render(preview.Content)})
+}
+
+// renderPosts renders the Markdown content of a listing of posts to HTML.
+func renderPosts(posts []models.Post) {
+	for i := range posts {
+		posts[i].ContentHTML = markdown.Render(posts[i].Content)
+	}
+}
@@ -0,0 +1,36 @@
+package handlers
+
+import (
+	"errors"
+	"literary-lions/backend/src/internal/models"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// parsePageRequest reads the sort, cursor and limit query parameters of a paginated listing.
+// It responds with 400 Bad Request and returns false if the limit is not a positive number.
+func parsePageRequest(c *gin.Context) (models.PageRequest, bool) {
+	page := models.PageRequest{
+		Sort:   c.Query("sort"),
+		Cursor: c.Query("cursor"),
+	}
+
+	if limit := c.Query("limit"); limit != "" {
+		var err error
+		page.Limit, err = strconv.Atoi(limit)
+		if err != nil || page.Limit < 1 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid limit"})
+			return models.PageRequest{}, false
+		}
+	}
+
+	return page, true
+}
+
+// isPageError reports whether a listing failed because of a bad sort order or cursor,
+// which is the client's fault rather than the server's.
+func isPageError(err error) bool {
+	return errors.Is(err, models.ErrInvalidSort) || errors.Is(err, models.ErrInvalidCursor)
+}
@@ -0,0 +1,137 @@
+package handlers
+
+import (
+	"errors"
+	"literary-lions/backend/src/internal/models"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// PollRequest is the poll a post carries when it is created.
+type PollRequest struct {
+	Question  string     `json:"question"`
+	Options   []string   `json:"options"`   // 2 to 10 distinct labels, in display order
+	Multiple  bool       `json:"multiple"`  // Whether voters may pick several options
+	Anonymous bool       `json:"anonymous"` // Whether to keep the voters of each option hidden
+	ClosesAt  *time.Time `json:"closes_at"` // Optional close date, RFC 3339, in the future
+}
+
+// VoteRequest is the body of a vote in a poll.
+type VoteRequest struct {
+	OptionIDs []int `json:"option_ids" binding:"required"` // One option, or several if the poll allows it
+}
+
+// toPoll turns the request into a poll, not yet attached to a post.
+func (p PollRequest) toPoll() models.Poll {
+	poll := models.Poll{
+		Question:  p.Question,
+		Multiple:  p.Multiple,
+		Anonymous: p.Anonymous,
+		ClosesAt:  p.ClosesAt,
+	}
+	for _, label := range p.Options {
+		poll.Options = append(poll.Options, models.PollOption{Label: label})
+	}
+	return poll
+}
+
+// GetPostPoll godoc
+// @Summary Get the poll of a post
+// @Description Get the poll a post carries with its results: the number of voters, the votes of each option and, unless the poll is anonymous, the usernames of who picked it. For logged-in members, the poll tells whether they voted and the options they chose. The polls of posts in private clubs, or in sections of a reading schedule the user has not marked read, are hidden like the posts.
+// @Tags polls
+// @Produce json
+// @Param id path int true "Post ID"
+// @Success 200 {object} models.Poll
+// @Failure 400 {object} gin.H
+// @Failure 403 {object} gin.H
+// @Failure 404 {object} gin.H
+// @Router /api/v1.0/post/{id}/poll [get]
+func GetPostPoll(c *gin.Context) {
+	postID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid post ID"})
+		return
+	}
+	if !checkPostReader(c, postID) {
+		return
+	}
+
+	poll, err := store.Polls.GetForPost(postID, sessionUserID(c))
+	if err != nil {
+		pollError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, poll)
+}
+
+// VotePoll godoc
+// @Summary Vote in the poll of a post
+// @Description Vote in the poll a post carries by picking one of its options, or several if the poll allows it. Each member votes once and cannot change their vote, and polls take no vote after their close date. Only the members of a club vote in the polls of its posts.
+// @Tags polls
+// @Accept json
+// @Produce json
+// @Param id path int true "Post ID"
+// @Param vote body VoteRequest true "The options picked"
+// @Success 200 {object} models.Poll
+// @Failure 400 {object} gin.H
+// @Failure 401 {object} gin.H
+// @Failure 403 {object} gin.H
+// @Failure 404 {object} gin.H
+// @Failure 409 {object} gin.H
+// @Router /api/v1.0/post/{id}/poll/vote [post]
+// @Security ApiKeyAuth
+func VotePoll(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	postID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid post ID"})
+		return
+	}
+
+	var vote VoteRequest
+	if err := c.ShouldBindJSON(&vote); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request payload"})
+		return
+	}
+
+	// Members vote only in the polls they can read
+	if !checkPostReader(c, postID) {
+		return
+	}
+	poll, err := store.Polls.GetForPost(postID, userID)
+	if err != nil {
+		pollError(c, err)
+		return
+	}
+	if err := store.Polls.Vote(poll.ID, userID, vote.OptionIDs); err != nil {
+		pollError(c, err)
+		return
+	}
+
+	// Answer with the results the vote unveils
+	if poll, err = store.Polls.GetForPost(postID, userID); err != nil {
+		pollError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, poll)
+}
+
+// pollError responds with the status matching an error from the poll repository.
+func pollError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, models.ErrInvalidPoll), errors.Is(err, models.ErrInvalidVote):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, models.ErrNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "This post has no poll"})
+	case errors.Is(err, models.ErrAlreadyVoted), errors.Is(err, models.ErrPollClosed):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
@@ -1,6 +1,8 @@
 package handlers
 
 import (
+	"errors"
+	"literary-lions/backend/src/internal/markdown"
 	"literary-lions/backend/src/internal/models"
 	"net/http"
 	"strconv"
@@ -55,7 +57,7 @@ import (
 // 	}
 
 // 	// Call the function to create the comment in the database
-// 	if err := models.CreateComment(postID, userID.(int), comment.Content); err != nil {
+// 	if err := store.Comments.Create(postID, userID.(int), comment.Content); err != nil {
 // 		// If the operation fails, return an internal server error
 // 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 // 		return
@@ -103,7 +105,11 @@ func AddComment(c *gin.Context) {
 	}
 
 	// Call the function to create the comment in the database
-	if err := models.CreateComment(postID, userID.(int), comment.Content); err != nil {
+	err = store.Comments.Create(postID, userID.(int), comment.Content)
+	if errors.Is(err, models.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Post not found"})
+		return
+	} else if err != nil {
 		// If the operation fails, return an internal server error
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -113,9 +119,65 @@ func AddComment(c *gin.Context) {
 	c.JSON(http.StatusCreated, gin.H{"message": "Comment added successfully"})
 }
 
+// ReplyToComment godoc
+// @Summary Reply to a comment
+// @Description Add a reply to a comment, on the same post. Replies are nested up to COMMENT_MAX_DEPTH levels (5 by default); a reply to a comment at the deepest level is added next to it.
+// @Tags comments
+// @Accept json
+// @Produce json
+// @Param id path int true "Comment ID"
+// @Param comment body object true "Reply content"
+// @Success 201 {object} gin.H
+// @Failure 400 {object} gin.H
+// @Failure 401 {object} gin.H
+// @Failure 404 {object} gin.H
+// @Router /api/comment/{id}/reply [post]
+// @Security ApiKeyAuth
+// ReplyToComment handles adding a reply to a comment using Gin
+func ReplyToComment(c *gin.Context) {
+	// Retrieve the user ID from the context (set by middleware)
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	// Get the ID of the comment replied to from the URL parameter
+	commentID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid comment ID"})
+		return
+	}
+
+	var reply struct {
+		Content string `json:"content"`
+	}
+	if err := c.ShouldBindJSON(&reply); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Check if the content is empty or contains only whitespace
+	if len(strings.TrimSpace(reply.Content)) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Content cannot be empty or only whitespace"})
+		return
+	}
+
+	postID, err := store.Comments.Reply(commentID, userID.(int), reply.Content, commentMaxDepth)
+	if errors.Is(err, models.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Comment not found"})
+		return
+	} else if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"message": "Reply added successfully", "post_id": postID})
+}
+
 // CreatePost godoc
 // @Summary Create a new post
-// @Description Create a new post with title, content, and category
+// @Description Create a new post with title, content, category and optional tags and books. The category is given by slug or name in category, or by ID in category_id, and must exist. Tags are turned into slugs, e.g. "Jane Austen" into jane-austen, and a post carries at most 10. book_ids links up to 5 books of the catalog, or books waiting for approval that the user added. poll optionally attaches a poll: a question, 2 to 10 distinct options, whether voters may pick several, whether their names stay hidden, and an optional close date in the future.
 // @Tags posts
 // @Accept json
 // @Produce json
@@ -149,7 +211,7 @@ func AddComment(c *gin.Context) {
 //     }
 
 //     // Call the function to create the post in the database
-//     err := models.CreatePost(userID.(int), post.Title, post.Content, post.Category)
+//     err := store.Posts.Create(userID.(int), post.Title, post.Content, post.Category)
 //     if err != nil {
 //         // If the operation fails, return an internal server error
 //         c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
@@ -171,9 +233,13 @@ func CreatePost(c *gin.Context) {
 
 	// Define a structure to bind the incoming JSON request
 	var post struct {
-		Title    string `json:"title" binding:"required"`
-		Content  string `json:"content" binding:"required"`
-		Category string `json:"category" binding:"required"`
+		Title      string       `json:"title" binding:"required"`
+		Content    string       `json:"content" binding:"required"`
+		Category   string       `json:"category"`    // Slug or name of the category
+		CategoryID int          `json:"category_id"` // Or its ID
+		Tags       []string     `json:"tags"`        // Optional tags, as typed
+		BookIDs    []int        `json:"book_ids"`    // Optional books the post discusses
+		Poll       *PollRequest `json:"poll"`        // Optional poll, such as on the next book to read
 	}
 
 	// Bind the JSON request body to the post struct
@@ -189,16 +255,51 @@ func CreatePost(c *gin.Context) {
 		return
 	}
 
+	// Posts can only be filed under an existing category
+	category, ok := findPostCategory(c, post.CategoryID, post.Category)
+	if !ok {
+		return
+	}
+	if !checkNewPostBooks(c, userID.(int), post.BookIDs) {
+		return
+	}
+
+	// Check the poll before the post is created, so that a bad one creates neither
+	var poll models.Poll
+	if post.Poll != nil {
+		poll = post.Poll.toPoll()
+		if err := poll.Normalize(); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
 	// Call the function to create the post in the database
-	err := models.CreatePost(userID.(int), post.Title, post.Content, post.Category)
-	if err != nil {
+	postID, err := store.Posts.Create(userID.(int), post.Title, post.Content, category.ID, post.Tags)
+	if isTagError(err) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	} else if err != nil {
 		// If the operation fails, return an internal server error
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
+	if len(post.BookIDs) > 0 {
+		if err := store.Books.SetForPost(postID, userID.(int), post.BookIDs); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	if post.Poll != nil {
+		poll.PostID = postID
+		if _, err := store.Polls.Create(poll); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+	}
 
 	// Return a success message if the post was created successfully
-	c.JSON(http.StatusCreated, gin.H{"message": "Post created successfully"})
+	c.JSON(http.StatusCreated, gin.H{"message": "Post created successfully", "id": postID})
 }
 
 // GetAllPosts godoc
@@ -207,14 +308,24 @@ func CreatePost(c *gin.Context) {
 // @Tags posts
 // @Accept json
 // @Produce json
-// @Success 200 {array} models.Post
+// @Param keyword query string false "Part of the post title"
+// @Param category query string false "Slug or name of the post category"
+// @Param tag query string false "Comma-separated tags; only posts carrying all of them"
+// @Param start_date query string false "Only posts created on or after this date (YYYY-MM-DD)"
+// @Param end_date query string false "Only posts created on or before this date (YYYY-MM-DD)"
+// @Param filter query string false "my-posts or liked-posts, requires authentication"
+// @Param sort query string false "newest (default), oldest, most_liked or most_commented"
+// @Param cursor query string false "The next cursor of the previous page"
+// @Param limit query int false "Number of posts per page (default 20, max 100)"
+// @Success 200 {object} models.PostPage
+// @Failure 400 {object} gin.H
 // @Failure 401 {object} gin.H
 // @Router /api/posts [get]
 // @Security ApiKeyAuth
 // GetAllPosts handles the retrieval of all posts using Gin
 // GetAllPosts handles the retrieval of all posts with optional advanced search filters.
 func GetAllPosts(c *gin.Context) {
-	var posts []models.Post
+	var posts models.PostPage
 	// Retrieve query parameters for filtering
 	title := c.Query("keyword")
 	startDate := c.Query("start_date")
@@ -240,6 +351,12 @@ func GetAllPosts(c *gin.Context) {
 		}
 	}
 
+	// Retrieve the sort order, cursor and page size
+	page, ok := parsePageRequest(c)
+	if !ok {
+		return
+	}
+
 	var userID int
 
 	// Handle different filters like my-posts and liked-posts
@@ -253,7 +370,7 @@ func GetAllPosts(c *gin.Context) {
 			return
 		}
 		userID = userIDValue.(int)
-		posts, err = models.GetUserPosts(userID)
+		posts, err = store.Posts.GetByUser(userID, page)
 	case "liked-posts":
 		// Retrieve the user ID from the context (assuming it's set by the middleware)
 		userIDValue, exists := c.Get("userID")
@@ -263,31 +380,44 @@ func GetAllPosts(c *gin.Context) {
 			return
 		}
 		userID = userIDValue.(int)
-		posts, err = models.GetLikedPostsByUserID(userID)
+		posts, err = store.Posts.GetLikedByUser(userID, page)
 	default:
 		// Implement function to handle combined search/filter logic
-		posts, err = models.GetFilteredPosts(category, title, parsedStartDate, parsedEndDate)
+		posts, err = store.Posts.GetFiltered(models.PostFilter{
+			Category:  category,
+			Tags:      parseTags(c),
+			Title:     title,
+			StartDate: parsedStartDate,
+			EndDate:   parsedEndDate,
+		}, page)
 	}
 
 	// // Call the function to get all posts from the database with the provided filters
-	// posts, err := models.GetFilteredPosts(category, title, parsedStartDate, parsedEndDate)
-	if err != nil {
+	// posts, err := store.Posts.GetFiltered(category, title, parsedStartDate, parsedEndDate)
+	if isPageError(err) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	} else if err != nil {
 		// If the operation fails, return an internal server error
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
-	// Return the list of posts as a JSON response
+	// Return the page of posts, their content rendered, and the cursor of the next one as a JSON response
+	renderPosts(posts.Posts)
 	c.JSON(http.StatusOK, posts)
 }
 
 // GetPost godoc
 // @Summary Get a post by ID
-// @Description Retrieve a single post by its ID along with comments, likes, and dislikes
+// @Description Retrieve a single post by its ID along with one page of its comment threads, each comment followed by its replies depth first, likes, dislikes and the counts of the configured reactions. Reactions given by the logged-in user, if any, are flagged as reacted, and the post and comments they may edit or delete as editable. The Markdown content of the post and comments is also rendered to sanitized HTML in content_html. A post of a club discussing a section of its reading schedule that the user has not marked read comes back locked, without its content or comments.
 // @Tags posts
 // @Accept json
 // @Produce json
 // @Param id path int true "Post ID"
+// @Param sort query string false "Comment order: oldest (default), newest or most_liked"
+// @Param cursor query string false "The next cursor of the previous page of comments"
+// @Param limit query int false "Number of comments per page (default 20, max 100)"
 // @Success 200 {object} models.Post
 // @Failure 400 {object} gin.H
 // @Failure 404 {object} gin.H
@@ -303,48 +433,109 @@ func GetPostByID(c *gin.Context) {
 		return
 	}
 
-	// Call the function to get the post by ID from the database
-	post, err := models.GetPostByID(postID)
-	if err != nil {
+	// Call the function to get the post by ID, with its author and reaction counts, from the database
+	post, err := store.Posts.GetByID(postID)
+	if errors.Is(err, models.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Post not found"})
+		return
+	} else if err != nil {
 		// If the operation fails, return an internal server error
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
-	// Call the function to get comments associated with the post
-	comments, err := models.GetCommentsByPostID(postID)
+	// The posts of private clubs are read by their members alone
+	if !checkClubReader(c, post.ClubID, "Post not found") {
+		return
+	}
+
+	// Retrieve the sort order, cursor and page size of the comments
+	page, ok := parsePageRequest(c)
+	if !ok {
+		return
+	}
+
+	// Posts discussing a section of a club's reading schedule are held back, with
+	// their comments, until the requesting user marks the section read
+	userID := sessionUserID(c)
+	locked, err := postLocked(post, userID)
 	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Call the function to get the page of comment threads associated with the post
+	comments := models.CommentPage{Comments: []models.Comment{}}
+	if locked {
+		post.Lock()
+	} else if comments, err = store.Comments.GetByPostID(postID, page, commentMaxDepth); isPageError(err) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	} else if err != nil {
 		// If the operation fails, return an internal server error
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
-	// Call the functions to count likes and dislikes for the post
-	likes, err := models.CountPostLikes(postID)
+	// Count the configured reactions on the post and on the page of comments,
+	// flagging those given by the requesting user if they are logged in
+	postReactions, err := store.Reactions.CountReactions(userID, models.ReactionTargetPost, []int{postID}, reactions)
 	if err != nil {
-		// If the operation fails, return an internal server error
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
+	post.Reactions = postReactions[postID]
 
-	dislikes, err := models.CountPostDislikes(postID)
+	// Reactions to removed comments are kept in case they are restored, but not shown
+	var commentIDs []int
+	for _, comment := range comments.Comments {
+		if !comment.Deleted {
+			commentIDs = append(commentIDs, comment.ID)
+		}
+	}
+	commentReactions, err := store.Reactions.CountReactions(userID, models.ReactionTargetComment, commentIDs, reactions)
 	if err != nil {
-		// If the operation fails, return an internal server error
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
+	for i := range comments.Comments {
+		comments.Comments[i].Reactions = commentReactions[comments.Comments[i].ID]
+	}
+
+	// Flag the post and comments the requesting user may edit and delete: their own, or all of them for admins
+	admin := false
+	if userID != 0 {
+		if admin, err = isAdmin(userID); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	post.Editable = userID != 0 && (admin || post.UserID == userID)
+	for i, comment := range comments.Comments {
+		comments.Comments[i].Editable = !comment.Deleted && (admin || comment.UserID == userID)
+	}
+
+	// Render the Markdown the post and comments are written in
+	post.ContentHTML = markdown.Render(post.Content)
+	for i, comment := range comments.Comments {
+		if !comment.Deleted {
+			comments.Comments[i].ContentHTML = markdown.Render(comment.Content)
+		}
+	}
 
 	// Prepare the response with the post, comments, likes, and dislikes
 	response := struct {
 		Post     models.Post      `json:"post"`
 		Comments []models.Comment `json:"comments"`
+		Next     string           `json:"next,omitempty"` // Cursor of the next page of comments
 		Likes    int              `json:"likes"`
 		Dislikes int              `json:"dislikes"`
 	}{
 		Post:     post,
-		Comments: comments,
-		Likes:    likes,
-		Dislikes: dislikes,
+		Comments: comments.Comments,
+		Next:     comments.Next,
+		Likes:    post.Likes,
+		Dislikes: post.Dislikes,
 	}
 
 	// Return the response as a JSON object
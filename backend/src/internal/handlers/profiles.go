@@ -0,0 +1,227 @@
+package handlers
+
+import (
+	"errors"
+	"literary-lions/backend/src/internal/images"
+	"literary-lions/backend/src/internal/markdown"
+	"literary-lions/backend/src/internal/models"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetUserProfile godoc
+// @Summary Get the public profile of a member
+// @Description Retrieve the avatar, bio, location, favorite books and genres, role and join date of a member by username, with the number of posts and comments they wrote and the likes, dislikes and reactions others gave to them. Email addresses are left out. The avatar is served at /api/v1.0/uploads/{profile_pic}.
+// @Tags users
+// @Produce json
+// @Param username path string true "Username"
+// @Success 200 {object} models.User
+// @Failure 404 {object} gin.H
+// @Router /api/v1.0/users/{username} [get]
+func GetUserProfile(c *gin.Context) {
+	user, ok := findProfile(c)
+	if !ok {
+		return
+	}
+
+	activity, err := store.Users.Activity(user.ID, reactions)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not retrieve the profile"})
+		return
+	}
+	user.Activity = &activity
+
+	// Only the member sees their own email address, on their profile page
+	user.Email, user.Password = "", ""
+	c.JSON(http.StatusOK, user)
+}
+
+// GetUserPosts godoc
+// @Summary Browse the posts of a member
+// @Description Retrieve one page of the published posts of a member, by username
+// @Tags users
+// @Produce json
+// @Param username path string true "Username"
+// @Param sort query string false "newest (default), oldest, most_liked or most_commented"
+// @Param cursor query string false "The next cursor of the previous page"
+// @Param limit query int false "Number of posts per page (default 20, max 100)"
+// @Success 200 {object} models.PostPage
+// @Failure 400 {object} gin.H
+// @Failure 404 {object} gin.H
+// @Router /api/v1.0/users/{username}/posts [get]
+func GetUserPosts(c *gin.Context) {
+	user, ok := findProfile(c)
+	if !ok {
+		return
+	}
+	page, ok := parsePageRequest(c)
+	if !ok {
+		return
+	}
+
+	posts, err := store.Posts.GetByUser(user.ID, page)
+	respondWithPosts(c, posts, err)
+}
+
+// GetUserLikedPosts godoc
+// @Summary Browse the posts a member likes
+// @Description Retrieve one page of the posts a member likes, by username. Members who keep their liked posts private get 403 Forbidden for everyone but themselves.
+// @Tags users
+// @Produce json
+// @Param username path string true "Username"
+// @Param sort query string false "newest (default), oldest, most_liked or most_commented"
+// @Param cursor query string false "The next cursor of the previous page"
+// @Param limit query int false "Number of posts per page (default 20, max 100)"
+// @Success 200 {object} models.PostPage
+// @Failure 400 {object} gin.H
+// @Failure 403 {object} gin.H
+// @Failure 404 {object} gin.H
+// @Router /api/v1.0/users/{username}/liked-posts [get]
+func GetUserLikedPosts(c *gin.Context) {
+	user, ok := findProfile(c)
+	if !ok {
+		return
+	}
+	if user.HideLikedPosts && sessionUserID(c) != user.ID {
+		c.JSON(http.StatusForbidden, gin.H{"error": user.Username + " keeps their liked posts private"})
+		return
+	}
+	page, ok := parsePageRequest(c)
+	if !ok {
+		return
+	}
+
+	posts, err := store.Posts.GetLikedByUser(user.ID, page)
+	respondWithPosts(c, posts, err)
+}
+
+// GetUserComments godoc
+// @Summary Browse the comments of a member
+// @Description Retrieve one page of the comments of a member on published posts, by username, each with the title of its post
+// @Tags users
+// @Produce json
+// @Param username path string true "Username"
+// @Param sort query string false "newest (default), oldest or most_liked"
+// @Param cursor query string false "The next cursor of the previous page"
+// @Param limit query int false "Number of comments per page (default 20, max 100)"
+// @Success 200 {object} models.CommentPage
+// @Failure 400 {object} gin.H
+// @Failure 404 {object} gin.H
+// @Router /api/v1.0/users/{username}/comments [get]
+func GetUserComments(c *gin.Context) {
+	user, ok := findProfile(c)
+	if !ok {
+		return
+	}
+	page, ok := parsePageRequest(c)
+	if !ok {
+		return
+	}
+
+	comments, err := store.Comments.GetByUser(user.ID, page)
+	if isPageError(err) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	} else if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	for i := range comments.Comments {
+		comments.Comments[i].ContentHTML = markdown.Render(comments.Comments[i].Content)
+	}
+	c.JSON(http.StatusOK, comments)
+}
+
+// findProfile looks up the member named by the username path parameter. It
+// responds with 404 Not Found or 500 Internal Server Error and returns false if
+// the member cannot be found.
+func findProfile(c *gin.Context) (*models.User, bool) {
+	user, err := store.Users.GetByUsername(c.Param("username"))
+	if errors.Is(err, models.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return nil, false
+	} else if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not retrieve the profile"})
+		return nil, false
+	}
+	return user, true
+}
+
+// respondWithPosts sends a page of posts, their content rendered, or the error
+// that kept it from being listed.
+func respondWithPosts(c *gin.Context, posts models.PostPage, err error) {
+	if isPageError(err) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	} else if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	renderPosts(posts.Posts)
+	c.JSON(http.StatusOK, posts)
+}
+
+// UploadAvatar godoc
+// @Summary Upload the avatar of the logged-in user
+// @Description Set the profile picture of the logged-in user from a JPEG, PNG or GIF image in the avatar field of a multipart form. The middle of the image is cropped to a square of at most 256 pixels a side.
+// @Tags users
+// @Accept multipart/form-data
+// @Produce json
+// @Param avatar formData file true "The image"
+// @Success 200 {object} gin.H
+// @Failure 400 {object} gin.H
+// @Failure 401 {object} gin.H
+// @Failure 413 {object} gin.H
+// @Failure 415 {object} gin.H
+// @Router /api/v1.0/userprofile-avatar [put]
+// @Security ApiKeyAuth
+func UploadAvatar(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	img, ok := readImage(c, "avatar", images.Avatar)
+	if !ok {
+		return
+	}
+
+	key, err := files.Put(img.Data, img.Ext)
+	if err != nil {
+		log.Printf("Storing an avatar failed: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not store the image"})
+		return
+	}
+	setAvatar(c, userID, key)
+}
+
+// DeleteAvatar godoc
+// @Summary Remove the avatar of the logged-in user
+// @Description Remove the profile picture of the logged-in user, who is shown with the default one again.
+// @Tags users
+// @Produce json
+// @Success 200 {object} gin.H
+// @Failure 401 {object} gin.H
+// @Router /api/v1.0/userprofile-avatar [delete]
+// @Security ApiKeyAuth
+func DeleteAvatar(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	setAvatar(c, userID, "")
+}
+
+// setAvatar sets the avatar of the user with the given ID to the stored file with
+// the given key, or removes it for an empty key. The previous file goes with the
+// next cleanup of unused uploads.
+func setAvatar(c *gin.Context, userID int, key string) {
+	if err := store.Users.SetAvatar(userID, key); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not update the avatar"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Avatar updated successfully", "profile_pic": key})
+}
@@ -0,0 +1,128 @@
+package handlers
+
+import (
+	"errors"
+	"literary-lions/backend/src/internal/models"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetReactions godoc
+// @Summary List the reactions
+// @Description List the reactions users can add to posts and comments besides likes and dislikes, in display order
+// @Tags reactions
+// @Produce json
+// @Success 200 {array} string
+// @Router /api/v1.0/reactions [get]
+// GetReactions returns the configured reaction set using Gin
+func GetReactions(c *gin.Context) {
+	c.JSON(http.StatusOK, reactions)
+}
+
+// AddPostReaction godoc
+// @Summary Add a reaction to a post
+// @Description Add one of the configured reactions to a post. Adding a reaction twice has no effect.
+// @Tags reactions
+// @Produce json
+// @Param id path int true "Post ID"
+// @Param reaction path string true "Reaction name, see /reactions"
+// @Success 200 {object} gin.H
+// @Failure 400 {object} gin.H
+// @Failure 401 {object} gin.H
+// @Failure 404 {object} gin.H
+// @Router /api/post/{id}/reactions/{reaction} [post]
+// @Security ApiKeyAuth
+func AddPostReaction(c *gin.Context) {
+	changeReaction(c, models.ReactionTargetPost, true)
+}
+
+// RemovePostReaction godoc
+// @Summary Remove a reaction from a post
+// @Description Take back a reaction the user added to a post. Removing a reaction that was not added has no effect.
+// @Tags reactions
+// @Produce json
+// @Param id path int true "Post ID"
+// @Param reaction path string true "Reaction name, see /reactions"
+// @Success 200 {object} gin.H
+// @Failure 400 {object} gin.H
+// @Failure 401 {object} gin.H
+// @Router /api/post/{id}/reactions/{reaction} [delete]
+// @Security ApiKeyAuth
+func RemovePostReaction(c *gin.Context) {
+	changeReaction(c, models.ReactionTargetPost, false)
+}
+
+// AddCommentReaction godoc
+// @Summary Add a reaction to a comment
+// @Description Add one of the configured reactions to a comment. Adding a reaction twice has no effect.
+// @Tags reactions
+// @Produce json
+// @Param id path int true "Comment ID"
+// @Param reaction path string true "Reaction name, see /reactions"
+// @Success 200 {object} gin.H
+// @Failure 400 {object} gin.H
+// @Failure 401 {object} gin.H
+// @Failure 404 {object} gin.H
+// @Router /api/comment/{id}/reactions/{reaction} [post]
+// @Security ApiKeyAuth
+func AddCommentReaction(c *gin.Context) {
+	changeReaction(c, models.ReactionTargetComment, true)
+}
+
+// RemoveCommentReaction godoc
+// @Summary Remove a reaction from a comment
+// @Description Take back a reaction the user added to a comment. Removing a reaction that was not added has no effect.
+// @Tags reactions
+// @Produce json
+// @Param id path int true "Comment ID"
+// @Param reaction path string true "Reaction name, see /reactions"
+// @Success 200 {object} gin.H
+// @Failure 400 {object} gin.H
+// @Failure 401 {object} gin.H
+// @Router /api/comment/{id}/reactions/{reaction} [delete]
+// @Security ApiKeyAuth
+func RemoveCommentReaction(c *gin.Context) {
+	changeReaction(c, models.ReactionTargetComment, false)
+}
+
+// changeReaction adds or removes the reaction named in the URL on the post or
+// comment whose ID is in the URL, on behalf of the logged-in user.
+func changeReaction(c *gin.Context, targetType string, add bool) {
+	// Retrieve the user ID from the context (set by the middleware)
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	// Get the post or comment ID from the URL parameter
+	targetID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid " + targetType + " ID"})
+		return
+	}
+
+	// Only the configured reactions can be added or removed
+	reaction := c.Param("reaction")
+	if !reactions.Contains(reaction) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown reaction " + strconv.Quote(reaction)})
+		return
+	}
+
+	if add {
+		err = store.Reactions.AddReaction(userID.(int), targetType, targetID, reaction)
+	} else {
+		err = store.Reactions.RemoveReaction(userID.(int), targetType, targetID, reaction)
+	}
+	if errors.Is(err, models.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "The " + targetType + " does not exist"})
+		return
+	} else if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Request successfully processed"})
+}
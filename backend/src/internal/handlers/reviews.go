@@ -0,0 +1,230 @@
+package handlers
+
+import (
+	"errors"
+	"literary-lions/backend/src/internal/markdown"
+	"literary-lions/backend/src/internal/models"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ReviewRequest is the payload of the endpoints that write and edit reviews.
+type ReviewRequest struct {
+	Rating  int    `json:"rating" binding:"required"` // Number of stars, from 1 to 5
+	Spoiler bool   `json:"spoiler"`                   // Whether the text reveals the plot
+	Content string `json:"content"`                   // Markdown text, may be empty
+}
+
+// GetBookReviews godoc
+// @Summary List the reviews of a book
+// @Description Retrieve one page of the reviews of a book, rendered from Markdown. Reviews flagged as spoilers are listed too; clients hide their text until asked for.
+// @Tags reviews
+// @Produce json
+// @Param id path int true "Book ID"
+// @Param sort query string false "newest (default), oldest, highest_rated or lowest_rated"
+// @Param cursor query string false "The next cursor of the previous page"
+// @Param limit query int false "Number of reviews per page (default 20, max 100)"
+// @Success 200 {object} models.ReviewPage
+// @Failure 400 {object} gin.H
+// @Failure 404 {object} gin.H
+// @Router /api/v1.0/books/{id}/reviews [get]
+func GetBookReviews(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid book ID"})
+		return
+	}
+	if _, err := store.Books.GetByID(id); errors.Is(err, models.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Book not found"})
+		return
+	} else if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Retrieve the sort order, cursor and page size
+	page, ok := parsePageRequest(c)
+	if !ok {
+		return
+	}
+
+	reviews, err := store.Reviews.ListForBook(id, page)
+	if isPageError(err) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	} else if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Flag the reviews the requesting user may edit and delete: their own, or all of them for admins
+	userID := sessionUserID(c)
+	admin := false
+	if userID != 0 {
+		if admin, err = isAdmin(userID); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	for i, review := range reviews.Reviews {
+		reviews.Reviews[i].Editable = userID != 0 && (admin || review.UserID == userID)
+		reviews.Reviews[i].ContentHTML = markdown.Render(review.Content)
+	}
+
+	c.JSON(http.StatusOK, reviews)
+}
+
+// CreateReview godoc
+// @Summary Review a book
+// @Description Rate an approved book from 1 to 5 stars, with an optional text in Markdown that may be flagged as a spoiler. Each member reviews a book at most once and edits their review afterwards.
+// @Tags reviews
+// @Accept json
+// @Produce json
+// @Param id path int true "Book ID"
+// @Param review body ReviewRequest true "Review object"
+// @Success 201 {object} gin.H
+// @Failure 400 {object} gin.H
+// @Failure 401 {object} gin.H
+// @Failure 404 {object} gin.H
+// @Failure 409 {object} gin.H
+// @Router /api/v1.0/books/{id}/reviews [post]
+// @Security ApiKeyAuth
+func CreateReview(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	bookID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid book ID"})
+		return
+	}
+
+	var request ReviewRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request payload"})
+		return
+	}
+
+	id, err := store.Reviews.Create(models.Review{
+		BookID:  bookID,
+		UserID:  userID,
+		Rating:  request.Rating,
+		Spoiler: request.Spoiler,
+		Content: request.Content,
+	})
+	if err != nil {
+		reviewError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"message": "Review created successfully", "id": id})
+}
+
+// UpdateReview godoc
+// @Summary Edit a review
+// @Description Replace the rating, spoiler flag and text of a review and record when it was edited. Only its author and admins may edit a review.
+// @Tags reviews
+// @Accept json
+// @Produce json
+// @Param id path int true "Review ID"
+// @Param review body ReviewRequest true "Updated review object"
+// @Success 200 {object} gin.H
+// @Failure 400 {object} gin.H
+// @Failure 401 {object} gin.H
+// @Failure 403 {object} gin.H
+// @Failure 404 {object} gin.H
+// @Router /api/v1.0/reviews/{id} [put]
+// @Security ApiKeyAuth
+func UpdateReview(c *gin.Context) {
+	review, ok := findOwnReview(c, "You can only edit your own reviews")
+	if !ok {
+		return
+	}
+
+	var request ReviewRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request payload"})
+		return
+	}
+
+	review.Rating, review.Spoiler, review.Content = request.Rating, request.Spoiler, request.Content
+	if err := store.Reviews.Update(review); err != nil {
+		reviewError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Review updated successfully", "book_id": review.BookID})
+}
+
+// DeleteReview godoc
+// @Summary Delete a review
+// @Description Delete a review, which no longer counts towards the rating of the book. Only its author and admins may delete a review.
+// @Tags reviews
+// @Produce json
+// @Param id path int true "Review ID"
+// @Success 200 {object} gin.H
+// @Failure 400 {object} gin.H
+// @Failure 401 {object} gin.H
+// @Failure 403 {object} gin.H
+// @Failure 404 {object} gin.H
+// @Router /api/v1.0/reviews/{id} [delete]
+// @Security ApiKeyAuth
+func DeleteReview(c *gin.Context) {
+	review, ok := findOwnReview(c, "You can only delete your own reviews")
+	if !ok {
+		return
+	}
+
+	if err := store.Reviews.Delete(review.ID); err != nil {
+		reviewError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Review deleted successfully", "book_id": review.BookID})
+}
+
+// findOwnReview looks up the review named by the id path parameter and checks
+// that the logged-in user wrote it or is an admin. Otherwise it responds with the
+// matching error, using forbidden for 403 Forbidden, and returns false.
+func findOwnReview(c *gin.Context, forbidden string) (models.Review, bool) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return models.Review{}, false
+	}
+	reviewID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid review ID"})
+		return models.Review{}, false
+	}
+
+	review, err := store.Reviews.GetByID(reviewID)
+	if errors.Is(err, models.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Review not found"})
+		return models.Review{}, false
+	} else if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return models.Review{}, false
+	}
+
+	if !authorizeAuthor(c, userID, review.UserID, forbidden) {
+		return models.Review{}, false
+	}
+	return review, true
+}
+
+// reviewError responds with the status matching an error from the review repository.
+func reviewError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, models.ErrInvalidReview):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, models.ErrNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Book or review not found"})
+	case errors.Is(err, models.ErrReviewExists):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
@@ -0,0 +1,154 @@
+package handlers
+
+import (
+	"errors"
+	"literary-lions/backend/src/internal/models"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetPostRevisions godoc
+// @Summary List the revisions of a post
+// @Description List every version of the title, content and category of a post, the latest first. can_restore tells whether the logged-in user, if any, is an admin and may restore an older revision.
+// @Tags posts
+// @Produce json
+// @Param id path int true "Post ID"
+// @Success 200 {object} gin.H
+// @Failure 400 {object} gin.H
+// @Failure 404 {object} gin.H
+// @Router /api/v1.0/post/{id}/revisions [get]
+func GetPostRevisions(c *gin.Context) {
+	postID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid post ID"})
+		return
+	}
+	if !checkPostReader(c, postID) {
+		return
+	}
+
+	revisions, err := store.Posts.Revisions(postID)
+	if errors.Is(err, models.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Post not found"})
+		return
+	} else if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Only admins may restore, so the frontend shows the button to them alone
+	canRestore := false
+	if userID := sessionUserID(c); userID != 0 {
+		if canRestore, err = isAdmin(userID); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"revisions": revisions, "can_restore": canRestore})
+}
+
+// GetPostRevisionDiff godoc
+// @Summary Compare two revisions of a post
+// @Description Compare a revision of a post with an earlier one: the titles word by word and the contents line by line. By default the revision is compared with the one before it; the first revision is compared with nothing, so that all of it shows as added.
+// @Tags posts
+// @Produce json
+// @Param id path int true "Post ID"
+// @Param revision path int true "Revision number"
+// @Param against query int false "Revision to compare with (default the previous one)"
+// @Success 200 {object} models.RevisionDiff
+// @Failure 400 {object} gin.H
+// @Failure 404 {object} gin.H
+// @Router /api/v1.0/post/{id}/revisions/{revision}/diff [get]
+func GetPostRevisionDiff(c *gin.Context) {
+	postID, number, ok := parseRevisionParams(c)
+	if !ok || !checkPostReader(c, postID) {
+		return
+	}
+
+	against := number - 1
+	if value := c.Query("against"); value != "" {
+		var err error
+		if against, err = strconv.Atoi(value); err != nil || against < 1 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid revision to compare with"})
+			return
+		}
+	}
+
+	to, ok := findRevision(c, postID, number)
+	if !ok {
+		return
+	}
+	var from models.PostRevision
+	if against > 0 {
+		if from, ok = findRevision(c, postID, against); !ok {
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, models.DiffRevisions(from, to))
+}
+
+// RestorePostRevision godoc
+// @Summary Restore a revision of a post
+// @Description Bring back the title, content and category of an older revision of a post. The restored version is recorded as a new revision, so no history is lost. Only admins may restore revisions.
+// @Tags posts
+// @Produce json
+// @Param id path int true "Post ID"
+// @Param revision path int true "Revision number"
+// @Success 200 {object} gin.H
+// @Failure 400 {object} gin.H
+// @Failure 401 {object} gin.H
+// @Failure 403 {object} gin.H
+// @Failure 404 {object} gin.H
+// @Router /api/v1.0/admin/posts/{id}/revisions/{revision}/restore [post]
+// @Security ApiKeyAuth
+func RestorePostRevision(c *gin.Context) {
+	postID, number, ok := parseRevisionParams(c)
+	if !ok {
+		return
+	}
+
+	err := store.Posts.RestoreRevision(postID, number, c.GetInt("userID"))
+	if errors.Is(err, models.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Revision not found"})
+		return
+	} else if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not restore revision"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Revision restored successfully"})
+}
+
+// parseRevisionParams reads the id and revision path parameters. It responds with
+// 400 Bad Request and returns false if either is not a valid number.
+func parseRevisionParams(c *gin.Context) (int, int, bool) {
+	postID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid post ID"})
+		return 0, 0, false
+	}
+	number, err := strconv.Atoi(c.Param("revision"))
+	if err != nil || number < 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid revision"})
+		return 0, 0, false
+	}
+	return postID, number, true
+}
+
+// findRevision looks up a revision of a post. It responds with the matching error
+// and returns false if the revision cannot be read.
+func findRevision(c *gin.Context, postID, number int) (models.PostRevision, bool) {
+	revision, err := store.Posts.Revision(postID, number)
+	if errors.Is(err, models.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Revision not found"})
+		return models.PostRevision{}, false
+	} else if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return models.PostRevision{}, false
+	}
+	return revision, true
+}
@@ -0,0 +1,310 @@
+package handlers
+
+import (
+	"errors"
+	"literary-lions/backend/src/internal/models"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ClubSectionRequest is the payload of the endpoints that plan the reading schedules of clubs.
+type ClubSectionRequest struct {
+	Title     string `json:"title" binding:"required"`      // What the section covers, at most 200 characters
+	UnlocksAt string `json:"unlocks_at" binding:"required"` // Unlock date as YYYY-MM-DD, from midnight UTC
+	BookID    int    `json:"book_id"`                       // Optional book of the catalog being read
+}
+
+// GetClubSchedule godoc
+// @Summary Get the reading schedule of a book club
+// @Description List the sections of the reading schedule of a club in the order they unlock, with the number of posts discussing each. For logged-in members, each section tells whether they marked it read. The schedules of private clubs are only listed to their members and to admins; can_moderate tells whether the member may plan the schedule.
+// @Tags clubs
+// @Produce json
+// @Param slug path string true "Club slug"
+// @Success 200 {object} gin.H
+// @Failure 403 {object} gin.H
+// @Failure 404 {object} gin.H
+// @Router /api/v1.0/clubs/{slug}/schedule [get]
+func GetClubSchedule(c *gin.Context) {
+	userID := sessionUserID(c)
+	club, err := store.Clubs.GetBySlug(c.Param("slug"), userID)
+	if errors.Is(err, models.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Club not found"})
+		return
+	} else if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	canRead, err := mayReadClub(club, userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if !canRead {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only the members of the club can see its schedule"})
+		return
+	}
+	canModerate, err := mayModerateClub(club, userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	sections, err := store.Schedules.List(club.ID, userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"club": club, "sections": sections, "can_moderate": canModerate})
+}
+
+// CreateClubSection godoc
+// @Summary Add a section to the reading schedule of a book club
+// @Description Add a section, such as a few chapters of the book the club reads, to the reading schedule of a club. Members may write posts discussing it from its unlock date on. Only the moderators of the club and admins may plan the schedule.
+// @Tags clubs
+// @Accept json
+// @Produce json
+// @Param slug path string true "Club slug"
+// @Param section body ClubSectionRequest true "Section object"
+// @Success 201 {object} models.ClubSection
+// @Failure 400 {object} gin.H
+// @Failure 401 {object} gin.H
+// @Failure 403 {object} gin.H
+// @Failure 404 {object} gin.H
+// @Router /api/v1.0/clubs/{slug}/schedule [post]
+// @Security ApiKeyAuth
+func CreateClubSection(c *gin.Context) {
+	section, ok := bindClubSection(c)
+	if !ok {
+		return
+	}
+	section.ClubID = currentClub(c).ID
+
+	section, err := store.Schedules.Create(section)
+	if err != nil {
+		scheduleError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, section)
+}
+
+// UpdateClubSection godoc
+// @Summary Edit a section of the reading schedule of a book club
+// @Description Replace the title, unlock date and book of a section. Posts already discussing it stay with it. Only the moderators of the club and admins may plan the schedule.
+// @Tags clubs
+// @Accept json
+// @Produce json
+// @Param slug path string true "Club slug"
+// @Param section_id path int true "Section ID"
+// @Param section body ClubSectionRequest true "Updated section object"
+// @Success 200 {object} gin.H
+// @Failure 400 {object} gin.H
+// @Failure 401 {object} gin.H
+// @Failure 403 {object} gin.H
+// @Failure 404 {object} gin.H
+// @Router /api/v1.0/clubs/{slug}/schedule/{section_id} [put]
+// @Security ApiKeyAuth
+func UpdateClubSection(c *gin.Context) {
+	existing, ok := findSection(c)
+	if !ok {
+		return
+	}
+	section, ok := bindClubSection(c)
+	if !ok {
+		return
+	}
+	section.ID, section.ClubID = existing.ID, existing.ClubID
+
+	if err := store.Schedules.Update(section); err != nil {
+		scheduleError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Section updated successfully"})
+}
+
+// DeleteClubSection godoc
+// @Summary Delete a section of the reading schedule of a book club
+// @Description Take a section off the reading schedule of a club. Sections that posts discuss, in the trash or not, cannot be deleted. Only the moderators of the club and admins may plan the schedule.
+// @Tags clubs
+// @Produce json
+// @Param slug path string true "Club slug"
+// @Param section_id path int true "Section ID"
+// @Success 200 {object} gin.H
+// @Failure 400 {object} gin.H
+// @Failure 401 {object} gin.H
+// @Failure 403 {object} gin.H
+// @Failure 404 {object} gin.H
+// @Failure 409 {object} gin.H
+// @Router /api/v1.0/clubs/{slug}/schedule/{section_id} [delete]
+// @Security ApiKeyAuth
+func DeleteClubSection(c *gin.Context) {
+	section, ok := findSection(c)
+	if !ok {
+		return
+	}
+
+	if err := store.Schedules.Delete(section.ID); err != nil {
+		scheduleError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Section deleted successfully"})
+}
+
+// MarkSectionRead godoc
+// @Summary Mark a section of the reading schedule read
+// @Description Record that the logged-in member has read a section of the reading schedule of their club, so that the posts discussing it are shown to them. Sections may be marked read before they unlock.
+// @Tags clubs
+// @Produce json
+// @Param slug path string true "Club slug"
+// @Param section_id path int true "Section ID"
+// @Success 200 {object} gin.H
+// @Failure 400 {object} gin.H
+// @Failure 401 {object} gin.H
+// @Failure 403 {object} gin.H
+// @Failure 404 {object} gin.H
+// @Router /api/v1.0/clubs/{slug}/schedule/{section_id}/read [put]
+// @Security ApiKeyAuth
+func MarkSectionRead(c *gin.Context) {
+	setSectionRead(c, true)
+}
+
+// MarkSectionUnread godoc
+// @Summary Mark a section of the reading schedule unread
+// @Description Take back that the logged-in member has read a section, so that the posts discussing it are held back from them again.
+// @Tags clubs
+// @Produce json
+// @Param slug path string true "Club slug"
+// @Param section_id path int true "Section ID"
+// @Success 200 {object} gin.H
+// @Failure 400 {object} gin.H
+// @Failure 401 {object} gin.H
+// @Failure 403 {object} gin.H
+// @Failure 404 {object} gin.H
+// @Router /api/v1.0/clubs/{slug}/schedule/{section_id}/read [delete]
+// @Security ApiKeyAuth
+func MarkSectionUnread(c *gin.Context) {
+	setSectionRead(c, false)
+}
+
+// setSectionRead marks the section named by the section_id path parameter read or
+// unread for the logged-in member.
+func setSectionRead(c *gin.Context, read bool) {
+	section, ok := findSection(c)
+	if !ok {
+		return
+	}
+
+	if err := store.Schedules.SetRead(section.ID, c.GetInt("userID"), read); err != nil {
+		scheduleError(c, err)
+		return
+	}
+	if read {
+		c.JSON(http.StatusOK, gin.H{"message": "Section marked read"})
+	} else {
+		c.JSON(http.StatusOK, gin.H{"message": "Section marked unread"})
+	}
+}
+
+// bindClubSection reads a ClubSectionRequest into a section. It responds with 400
+// Bad Request and returns false if the payload or its unlock date is invalid.
+func bindClubSection(c *gin.Context) (models.ClubSection, bool) {
+	var request ClubSectionRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request payload"})
+		return models.ClubSection{}, false
+	}
+	unlocksAt, err := time.Parse("2006-01-02", request.UnlocksAt)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid unlock date, use YYYY-MM-DD"})
+		return models.ClubSection{}, false
+	}
+	return models.ClubSection{Title: request.Title, UnlocksAt: unlocksAt, BookID: request.BookID}, true
+}
+
+// findSection looks up the section named by the section_id path parameter in the
+// schedule of the club stored in the context, with the reading of the logged-in
+// member. It responds with 400 Bad Request, 404 Not Found or 500 Internal Server
+// Error and returns false if the section cannot be found there.
+func findSection(c *gin.Context) (models.ClubSection, bool) {
+	sectionID, err := strconv.Atoi(c.Param("section_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid section ID"})
+		return models.ClubSection{}, false
+	}
+
+	section, err := store.Schedules.Get(sectionID, c.GetInt("userID"))
+	if errors.Is(err, models.ErrNotFound) || (err == nil && section.ClubID != currentClub(c).ID) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Section not found"})
+		return models.ClubSection{}, false
+	} else if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return models.ClubSection{}, false
+	}
+	return section, true
+}
+
+// checkOpenSection checks that a new post of a club may discuss the section with
+// the given ID: it must be in the schedule of the club and unlocked. Otherwise it
+// responds with 400 Bad Request or 500 Internal Server Error and returns false.
+func checkOpenSection(c *gin.Context, clubID, sectionID int) bool {
+	section, err := store.Schedules.Get(sectionID, 0)
+	if errors.Is(err, models.ErrNotFound) || (err == nil && section.ClubID != clubID) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown section"})
+		return false
+	} else if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return false
+	}
+	if !section.Unlocked {
+		c.JSON(http.StatusBadRequest, gin.H{"error": models.ErrSectionLocked.Error()})
+		return false
+	}
+	return true
+}
+
+// postLocked reports whether a post discusses a section of a reading schedule that
+// the user has not marked read. Authors always see their own posts.
+func postLocked(post models.Post, userID int) (bool, error) {
+	if post.SectionID == 0 || (userID != 0 && post.UserID == userID) {
+		return false, nil
+	}
+	if userID == 0 {
+		return true, nil
+	}
+	section, err := store.Schedules.Get(post.SectionID, userID)
+	if errors.Is(err, models.ErrNotFound) {
+		return false, nil
+	}
+	return err == nil && !section.Read, err
+}
+
+// lockUnreadPosts locks the posts discussing a section of the schedule, given by
+// sections, that the user has not marked read, their own posts aside.
+func lockUnreadPosts(posts []models.Post, sections []models.ClubSection, userID int) {
+	read := make(map[int]bool, len(sections))
+	for _, section := range sections {
+		read[section.ID] = section.Read
+	}
+	for i, post := range posts {
+		if post.SectionID != 0 && !read[post.SectionID] && (userID == 0 || post.UserID != userID) {
+			posts[i].Lock()
+		}
+	}
+}
+
+// scheduleError responds with the status matching an error from the schedule repository.
+func scheduleError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, models.ErrInvalidSection), errors.Is(err, models.ErrUnknownBook):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, models.ErrNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Section not found"})
+	case errors.Is(err, models.ErrSectionInUse):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
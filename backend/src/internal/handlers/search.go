@@ -0,0 +1,87 @@
+package handlers
+
+import (
+	"literary-lions/backend/src/internal/models"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Search godoc
+// @Summary Search posts and comments
+// @Description Full-text search over post titles, post bodies and comments, best matches first. Snippets are HTML-escaped with matches wrapped in <mark> tags.
+// @Tags search
+// @Produce json
+// @Param q query string true "Search words; the last word also matches as a prefix"
+// @Param type query string false "Restrict hits to post or comment"
+// @Param category query string false "Only hits on posts in this category"
+// @Param tag query string false "Comma-separated tags; only hits on posts carrying all of them"
+// @Param start_date query string false "Only hits created on or after this date (YYYY-MM-DD)"
+// @Param end_date query string false "Only hits created on or before this date (YYYY-MM-DD)"
+// @Param limit query int false "Maximum number of hits (default 20, max 100)"
+// @Success 200 {array} models.SearchResult
+// @Failure 400 {object} gin.H
+// @Failure 500 {object} gin.H
+// @Router /api/v1.0/search [get]
+// Search handles full-text searches over posts and comments using Gin
+func Search(c *gin.Context) {
+	filter := models.SearchFilter{
+		Query:    strings.TrimSpace(c.Query("q")),
+		Type:     c.Query("type"),
+		Category: c.Query("category"),
+		Tags:     parseTags(c),
+	}
+
+	// A search needs something to look for
+	if filter.Query == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Search query cannot be empty"})
+		return
+	}
+
+	// Only posts and comments are indexed
+	if filter.Type != "" && filter.Type != models.SearchTypePost && filter.Type != models.SearchTypeComment {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid type. Use post or comment."})
+		return
+	}
+
+	// Parse the optional date range
+	var err error
+	if startDate := c.Query("start_date"); startDate != "" {
+		filter.StartDate, err = time.Parse("2006-01-02", startDate)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid start date format. Use YYYY-MM-DD."})
+			return
+		}
+	}
+	if endDate := c.Query("end_date"); endDate != "" {
+		filter.EndDate, err = time.Parse("2006-01-02", endDate)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid end date format. Use YYYY-MM-DD."})
+			return
+		}
+		// Include the whole end day
+		filter.EndDate = filter.EndDate.AddDate(0, 0, 1).Add(-time.Second)
+	}
+
+	// Parse the optional limit; the repository clamps it to the allowed range
+	if limit := c.Query("limit"); limit != "" {
+		filter.Limit, err = strconv.Atoi(limit)
+		if err != nil || filter.Limit < 1 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid limit"})
+			return
+		}
+	}
+
+	// Run the search against the full-text index
+	results, err := store.Search.Search(filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Return the ranked hits as a JSON response
+	c.JSON(http.StatusOK, results)
+}
@@ -0,0 +1,184 @@
+package handlers
+
+import (
+	"errors"
+	"literary-lions/backend/src/internal/models"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ShelfRequest is the payload of the endpoint that puts books on reading shelves.
+type ShelfRequest struct {
+	Shelf      string `json:"shelf" binding:"required"` // want_to_read, reading or finished
+	Progress   int    `json:"progress"`                 // Percentage read, for the reading shelf
+	FinishedAt string `json:"finished_at"`              // Finish date as YYYY-MM-DD, today if empty
+}
+
+// GetShelves godoc
+// @Summary List your reading shelves
+// @Description List the books the logged-in member wants to read, is reading and has finished, the most recently finished or changed first, with the number of books they finished each year. With shelf, only the books on that shelf are listed.
+// @Tags shelves
+// @Produce json
+// @Param shelf query string false "want_to_read, reading or finished"
+// @Success 200 {object} gin.H
+// @Failure 400 {object} gin.H
+// @Failure 401 {object} gin.H
+// @Router /api/v1.0/shelves [get]
+// @Security ApiKeyAuth
+func GetShelves(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	respondWithShelves(c, userID, c.Query("shelf"))
+}
+
+// GetUserShelves godoc
+// @Summary Browse the reading shelves of a member
+// @Description List the books a member wants to read, is reading and has finished, by username, with the number of books they finished each year
+// @Tags users
+// @Produce json
+// @Param username path string true "Username"
+// @Param shelf query string false "want_to_read, reading or finished"
+// @Success 200 {object} gin.H
+// @Failure 400 {object} gin.H
+// @Failure 404 {object} gin.H
+// @Router /api/v1.0/users/{username}/shelves [get]
+func GetUserShelves(c *gin.Context) {
+	user, ok := findProfile(c)
+	if !ok {
+		return
+	}
+	respondWithShelves(c, user.ID, c.Query("shelf"))
+}
+
+// respondWithShelves responds with the books on the shelves of a member, or on one
+// of them, and their yearly reading stats.
+func respondWithShelves(c *gin.Context, userID int, shelf string) {
+	entries, err := store.Shelves.List(userID, shelf)
+	if errors.Is(err, models.ErrInvalidShelfEntry) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid shelf"})
+		return
+	} else if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	stats, err := store.Shelves.YearlyStats(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"shelves": entries, "stats": stats})
+}
+
+// GetReadingStats godoc
+// @Summary Get your yearly reading stats
+// @Description Count the books the logged-in member finished each year, the latest year first
+// @Tags shelves
+// @Produce json
+// @Success 200 {array} models.ReadingYear
+// @Failure 401 {object} gin.H
+// @Router /api/v1.0/shelves/stats [get]
+// @Security ApiKeyAuth
+func GetReadingStats(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	stats, err := store.Shelves.YearlyStats(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, stats)
+}
+
+// SetShelf godoc
+// @Summary Put a book on a reading shelf
+// @Description Put a book on the want_to_read, reading or finished shelf of the logged-in member, moving it from the shelf it was on. Books being read take a progress from 0 to 100 percent and keep the date they were started; finished books are dated today unless finished_at gives an earlier date. Members may shelve the approved books and those they added.
+// @Tags shelves
+// @Accept json
+// @Produce json
+// @Param book_id path int true "Book ID"
+// @Param shelf body ShelfRequest true "Shelf object"
+// @Success 200 {object} models.ShelfEntry
+// @Failure 400 {object} gin.H
+// @Failure 401 {object} gin.H
+// @Router /api/v1.0/shelves/{book_id} [put]
+// @Security ApiKeyAuth
+func SetShelf(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	bookID, err := strconv.Atoi(c.Param("book_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid book ID"})
+		return
+	}
+
+	var request ShelfRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request payload"})
+		return
+	}
+	entry := models.ShelfEntry{BookID: bookID, Shelf: request.Shelf, Progress: request.Progress}
+	if request.FinishedAt != "" {
+		finishedAt, err := time.Parse("2006-01-02", request.FinishedAt)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid finish date, use YYYY-MM-DD"})
+			return
+		}
+		entry.FinishedAt = &finishedAt
+	}
+
+	entry, err = store.Shelves.Set(userID, entry)
+	if errors.Is(err, models.ErrInvalidShelfEntry) || errors.Is(err, models.ErrUnknownBook) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	} else if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, entry)
+}
+
+// RemoveFromShelf godoc
+// @Summary Take a book off your shelves
+// @Description Take a book off the reading shelves of the logged-in member
+// @Tags shelves
+// @Produce json
+// @Param book_id path int true "Book ID"
+// @Success 200 {object} gin.H
+// @Failure 400 {object} gin.H
+// @Failure 401 {object} gin.H
+// @Failure 404 {object} gin.H
+// @Router /api/v1.0/shelves/{book_id} [delete]
+// @Security ApiKeyAuth
+func RemoveFromShelf(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	bookID, err := strconv.Atoi(c.Param("book_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid book ID"})
+		return
+	}
+
+	err = store.Shelves.Remove(userID, bookID)
+	if errors.Is(err, models.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "This book is not on your shelves"})
+		return
+	} else if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Book removed from your shelves"})
+}
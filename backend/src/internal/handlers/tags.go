@@ -0,0 +1,110 @@
+package handlers
+
+import (
+	"errors"
+	"literary-lions/backend/src/internal/models"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// defaultTagSuggestions is the number of tags suggested when the request sets no limit.
+	defaultTagSuggestions = 10
+	// maxTagSuggestions is the largest number of tags suggested at once.
+	maxTagSuggestions = 50
+)
+
+// GetTags godoc
+// @Summary Autocomplete tags
+// @Description Suggest the tags in use that start with the typed text, the most used first. Without q, the most used tags are listed.
+// @Tags tags
+// @Produce json
+// @Param q query string false "Beginning of the tag"
+// @Param limit query int false "Number of suggestions (default 10, max 50)"
+// @Success 200 {array} models.Tag
+// @Failure 400 {object} gin.H
+// @Router /api/v1.0/tags [get]
+// GetTags returns tag suggestions using Gin
+func GetTags(c *gin.Context) {
+	limit := defaultTagSuggestions
+	if value := c.Query("limit"); value != "" {
+		var err error
+		limit, err = strconv.Atoi(value)
+		if err != nil || limit < 1 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid limit"})
+			return
+		}
+		if limit > maxTagSuggestions {
+			limit = maxTagSuggestions
+		}
+	}
+
+	tags, err := store.Tags.Autocomplete(c.Query("q"), limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, tags)
+}
+
+// GetTag godoc
+// @Summary Browse a tag
+// @Description Retrieve a tag by its slug together with one page of the posts carrying it. Tags no published forum post carries are not found.
+// @Tags tags
+// @Produce json
+// @Param slug path string true "Tag slug"
+// @Param sort query string false "newest (default), oldest, most_liked or most_commented"
+// @Param cursor query string false "The next cursor of the previous page"
+// @Param limit query int false "Number of posts per page (default 20, max 100)"
+// @Success 200 {object} gin.H
+// @Failure 400 {object} gin.H
+// @Failure 404 {object} gin.H
+// @Router /api/v1.0/tags/{slug} [get]
+// GetTag returns a tag and a page of its posts using Gin
+func GetTag(c *gin.Context) {
+	tag, err := store.Tags.GetBySlug(c.Param("slug"))
+	if errors.Is(err, models.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Tag not found"})
+		return
+	} else if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Retrieve the sort order, cursor and page size
+	page, ok := parsePageRequest(c)
+	if !ok {
+		return
+	}
+
+	posts, err := store.Posts.GetFiltered(models.PostFilter{Tags: []string{tag.Slug}}, page)
+	if isPageError(err) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	} else if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	renderPosts(posts.Posts)
+	c.JSON(http.StatusOK, gin.H{"tag": tag, "posts": posts})
+}
+
+// parseTags reads the comma-separated tags of the tag query parameter.
+func parseTags(c *gin.Context) []string {
+	var tags []string
+	for _, tag := range strings.Split(c.Query("tag"), ",") {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
+
+// isTagError reports whether a post was rejected because of its tags.
+func isTagError(err error) bool {
+	return errors.Is(err, models.ErrInvalidTag) || errors.Is(err, models.ErrTooManyTags)
+}
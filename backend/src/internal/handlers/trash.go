@@ -0,0 +1,178 @@
+package handlers
+
+import (
+	"errors"
+	"literary-lions/backend/src/internal/models"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetTrash godoc
+// @Summary List the trash
+// @Description List the posts and comments the logged-in user deleted that can still be restored, the most recently deleted first. They are purged retention_days days after being deleted. Comments on deleted posts are not listed, as they come back with their post.
+// @Tags trash
+// @Produce json
+// @Success 200 {object} gin.H
+// @Failure 401 {object} gin.H
+// @Router /api/v1.0/trash [get]
+// @Security ApiKeyAuth
+func GetTrash(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	since := time.Now().Add(-trashRetention)
+
+	posts, err := store.Posts.Trashed(userID, since)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	comments, err := store.Comments.Trashed(userID, since)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"posts":          posts,
+		"comments":       comments,
+		"retention_days": int(trashRetention / (24 * time.Hour)),
+	})
+}
+
+// RestorePost godoc
+// @Summary Restore a deleted post
+// @Description Take a post out of the trash, with its comments and reactions. Authors restore their own posts and admins any post, until it is purged.
+// @Tags trash
+// @Produce json
+// @Param id path int true "Post ID"
+// @Success 200 {object} gin.H
+// @Failure 400 {object} gin.H
+// @Failure 401 {object} gin.H
+// @Failure 404 {object} gin.H
+// @Router /api/v1.0/post/{id}/restore [post]
+// @Security ApiKeyAuth
+func RestorePost(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid post ID"})
+		return
+	}
+
+	// Users only see their own trash, so other posts are reported as not found
+	since := time.Now().Add(-trashRetention)
+	allowed, err := mayRestore(userID, func(userID int) (bool, error) {
+		posts, err := store.Posts.Trashed(userID, since)
+		for _, post := range posts {
+			if post.ID == id {
+				return true, err
+			}
+		}
+		return false, err
+	})
+	if err == nil && allowed {
+		err = store.Posts.Restore(id, since)
+	}
+	if !allowed || errors.Is(err, models.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Post not found in the trash"})
+		return
+	} else if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not restore post"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Post restored successfully", "post_id": id})
+}
+
+// RestoreComment godoc
+// @Summary Restore a deleted comment
+// @Description Take a comment out of the trash, with its reactions. Authors restore their own comments and admins any comment, until it is purged. Comments on a deleted post come back with the post instead.
+// @Tags trash
+// @Produce json
+// @Param id path int true "Comment ID"
+// @Success 200 {object} gin.H
+// @Failure 400 {object} gin.H
+// @Failure 401 {object} gin.H
+// @Failure 404 {object} gin.H
+// @Router /api/v1.0/comment/{id}/restore [post]
+// @Security ApiKeyAuth
+func RestoreComment(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid comment ID"})
+		return
+	}
+
+	// Users only see their own trash, so other comments are reported as not found
+	since := time.Now().Add(-trashRetention)
+	allowed, err := mayRestore(userID, func(userID int) (bool, error) {
+		comments, err := store.Comments.Trashed(userID, since)
+		for _, comment := range comments {
+			if comment.ID == id {
+				return true, err
+			}
+		}
+		return false, err
+	})
+	if err == nil && allowed {
+		err = store.Comments.Restore(id, since)
+	}
+	if !allowed || errors.Is(err, models.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Comment not found in the trash"})
+		return
+	} else if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not restore comment"})
+		return
+	}
+
+	// Tell which post the comment is back on
+	comment, err := store.Comments.GetByID(id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Comment restored successfully", "post_id": comment.PostID})
+}
+
+// mayRestore reports whether the user may restore an item of the trash: admins
+// may restore anything, and other users what inTrash finds in their own trash.
+func mayRestore(userID int, inTrash func(userID int) (bool, error)) (bool, error) {
+	admin, err := isAdmin(userID)
+	if err != nil || admin {
+		return admin, err
+	}
+	return inTrash(userID)
+}
+
+// PurgeTrash permanently deletes the posts and comments that stayed in the trash
+// longer than the retention, logging what was purged. It is run in the background.
+func PurgeTrash() {
+	before := time.Now().Add(-trashRetention)
+
+	posts, err := store.Posts.Purge(before)
+	if err != nil {
+		log.Printf("Purging deleted posts failed: %v", err)
+		return
+	}
+	comments, err := store.Comments.Purge(before)
+	if err != nil {
+		log.Printf("Purging deleted comments failed: %v", err)
+		return
+	}
+	if posts > 0 || comments > 0 {
+		log.Printf("Purged %d deleted posts and %d deleted comments from the trash", posts, comments)
+	}
+}
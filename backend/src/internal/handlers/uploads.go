@@ -0,0 +1,265 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"literary-lions/backend/src/internal/images"
+	"literary-lions/backend/src/internal/models"
+	"literary-lions/backend/src/internal/storage"
+	"log"
+	"mime"
+	"net/http"
+	"path"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// DefaultUploadMaxMB is the size limit of an uploaded image, in megabytes, when
+// no other limit is configured.
+const DefaultUploadMaxMB = 5
+
+// attachmentGracePeriod is how long an uploaded image is kept without a post
+// carrying it, for its uploader to finish writing the post.
+const attachmentGracePeriod = 24 * time.Hour
+
+// files stores the uploaded images and their thumbnails.
+var files storage.Storage
+
+// uploadMaxBytes is the size limit of an uploaded image.
+var uploadMaxBytes int64 = DefaultUploadMaxMB << 20
+
+// attachmentsInput is the body of a request setting the images of a post or draft.
+type attachmentsInput struct {
+	AttachmentIDs []int `json:"attachment_ids"` // The images, in the order they are shown
+	CoverID       int   `json:"cover_id"`       // The image shown in the listings, 0 for the first one
+}
+
+// UploadImage godoc
+// @Summary Upload an image
+// @Description Upload a JPEG, PNG or GIF image in the image field of a multipart form, to attach it to posts. The format is told from the content of the file. JPEG and PNG images are stored without their metadata, and a thumbnail of at most 320 pixels a side is made. Both are served at /api/v1.0/uploads/{key}. Images no post carries are removed after a day.
+// @Tags uploads
+// @Accept multipart/form-data
+// @Produce json
+// @Param image formData file true "The image"
+// @Success 201 {object} models.Attachment
+// @Failure 400 {object} gin.H
+// @Failure 401 {object} gin.H
+// @Failure 413 {object} gin.H
+// @Failure 415 {object} gin.H
+// @Router /api/v1.0/uploads [post]
+// @Security ApiKeyAuth
+func UploadImage(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	img, ok := readImage(c, "image", images.Process)
+	if !ok {
+		return
+	}
+
+	attachment := models.Attachment{
+		UserID:      userID,
+		ContentType: img.ContentType,
+		Size:        len(img.Data),
+		Width:       img.Width,
+		Height:      img.Height,
+	}
+	var err error
+	if attachment.Key, err = files.Put(img.Data, img.Ext); err == nil {
+		attachment.ThumbnailKey, err = files.Put(img.Thumbnail, img.ThumbnailExt)
+	}
+	if err != nil {
+		log.Printf("Storing an upload failed: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not store the image"})
+		return
+	}
+	if attachment.ID, err = store.Attachments.Create(attachment); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not store the image"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, attachment)
+}
+
+// readImage reads the image uploaded in the named field of a multipart form and
+// prepares it with process. It responds with 413 Request Entity Too Large, 415
+// Unsupported Media Type or 400 Bad Request and returns false if the image is refused.
+func readImage(c *gin.Context, field string, process func([]byte) (images.Image, error)) (images.Image, bool) {
+	tooLarge := fmt.Sprintf("Images can be at most %d MB", uploadMaxBytes>>20)
+
+	// Leave room for the rest of the form around the image
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, uploadMaxBytes+64<<10)
+	file, header, err := c.Request.FormFile(field)
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": tooLarge})
+		return images.Image{}, false
+	} else if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Send the image in the %s field of a multipart form", field)})
+		return images.Image{}, false
+	}
+	defer file.Close()
+	if header.Size > uploadMaxBytes {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": tooLarge})
+		return images.Image{}, false
+	}
+	data, err := io.ReadAll(file)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Could not read the image"})
+		return images.Image{}, false
+	}
+
+	img, err := process(data)
+	if errors.Is(err, images.ErrUnsupported) {
+		c.JSON(http.StatusUnsupportedMediaType, gin.H{"error": "Only JPEG, PNG and GIF images are accepted"})
+		return images.Image{}, false
+	} else if errors.Is(err, images.ErrTooLarge) {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": fmt.Sprintf("Images can have at most %d megapixels", images.MaxPixels/1_000_000)})
+		return images.Image{}, false
+	} else if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "The image is damaged"})
+		return images.Image{}, false
+	}
+	return img, true
+}
+
+// GetUpload godoc
+// @Summary Get an uploaded image
+// @Description Serve an uploaded image or thumbnail by its key. The key changes with the content, so responses can be cached for good.
+// @Tags uploads
+// @Produce image/jpeg,image/png,image/gif
+// @Param key path string true "Storage key of the image"
+// @Success 200 {file} binary
+// @Failure 404 {object} gin.H
+// @Router /api/v1.0/uploads/{key} [get]
+func GetUpload(c *gin.Context) {
+	key := c.Param("key")
+	file, err := files.Open(key)
+	if errors.Is(err, storage.ErrNotFound) || errors.Is(err, storage.ErrInvalidKey) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Image not found"})
+		return
+	} else if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not read the image"})
+		return
+	}
+	defer file.Close()
+
+	// Replaces the headers of the NoCache middleware
+	c.Header("Cache-Control", "public, max-age=31536000, immutable")
+	c.Header("Pragma", "")
+	c.Header("Expires", "")
+	c.Header("Content-Type", mime.TypeByExtension(path.Ext(key)))
+	c.Header("X-Content-Type-Options", "nosniff")
+	http.ServeContent(c.Writer, c.Request, key, time.Time{}, file)
+}
+
+// SetPostAttachments godoc
+// @Summary Set the images of a post
+// @Description Replace the images of a post with uploaded ones, in the order given. The cover is shown in the listings and defaults to the first image. Members can add the images they uploaded and keep those already on the post. Only its author and admins may change a post.
+// @Tags uploads
+// @Accept json
+// @Produce json
+// @Param id path int true "Post ID"
+// @Param attachments body object true "attachment_ids and optional cover_id"
+// @Success 200 {object} gin.H
+// @Failure 400 {object} gin.H
+// @Failure 401 {object} gin.H
+// @Failure 403 {object} gin.H
+// @Failure 404 {object} gin.H
+// @Router /api/v1.0/post/{id}/attachments [put]
+// @Security ApiKeyAuth
+func SetPostAttachments(c *gin.Context) {
+	id, ok := findOwnPost(c, "You can only change the images of your own posts")
+	if !ok {
+		return
+	}
+	setAttachments(c, id)
+}
+
+// SetDraftAttachments godoc
+// @Summary Set the images of a draft
+// @Description Replace the images of a draft or scheduled post of the logged-in user, as for a post. They stay with it once it is published.
+// @Tags drafts
+// @Accept json
+// @Produce json
+// @Param id path int true "Draft ID"
+// @Param attachments body object true "attachment_ids and optional cover_id"
+// @Success 200 {object} gin.H
+// @Failure 400 {object} gin.H
+// @Failure 401 {object} gin.H
+// @Failure 404 {object} gin.H
+// @Router /api/v1.0/drafts/{id}/attachments [put]
+// @Security ApiKeyAuth
+func SetDraftAttachments(c *gin.Context) {
+	draft, ok := findOwnDraft(c)
+	if !ok {
+		return
+	}
+	setAttachments(c, draft.ID)
+}
+
+// setAttachments replaces the images of the post with the given ID with those
+// in the request body.
+func setAttachments(c *gin.Context, postID int) {
+	var input attachmentsInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request payload"})
+		return
+	}
+
+	err := store.Attachments.SetForPost(postID, c.GetInt("userID"), input.AttachmentIDs, input.CoverID)
+	if errors.Is(err, models.ErrInvalidAttachment) || errors.Is(err, models.ErrTooManyAttachments) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	} else if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not update the images"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Images updated successfully"})
+}
+
+// CleanupAttachments deletes the uploaded images no post has carried for the
+// grace period, then the stored files no image or avatar uses, logging what was
+// removed.
+// It is run in the background.
+func CleanupAttachments() {
+	before := time.Now().Add(-attachmentGracePeriod)
+
+	purged, err := store.Attachments.PurgeUnused(before)
+	if err != nil {
+		log.Printf("Purging unused images failed: %v", err)
+		return
+	}
+	keys, err := store.Attachments.Keys()
+	if err != nil {
+		log.Printf("Listing stored images failed: %v", err)
+		return
+	}
+	used := make(map[string]bool, len(keys))
+	for _, key := range keys {
+		used[key] = true
+	}
+
+	// Files stored again since the cutoff may belong to an upload in progress
+	removed := 0
+	err = files.Walk(func(key string, storedAt time.Time) error {
+		if used[key] || !storedAt.Before(before) {
+			return nil
+		}
+		if err := files.Delete(key); err != nil {
+			return err
+		}
+		removed++
+		return nil
+	})
+	if err != nil {
+		log.Printf("Removing unused files failed: %v", err)
+	}
+	if purged > 0 || removed > 0 {
+		log.Printf("Purged %d unused images and removed %d files", purged, removed)
+	}
+}
@@ -2,7 +2,6 @@ package handlers
 
 import (
 	"net/http"
-	"literary-lions/backend/src/internal/models"
 	"github.com/gin-gonic/gin"
 )
 
@@ -30,7 +29,7 @@ func Register(c *gin.Context) {
 
     // Call the function to register the user with the provided details
     // If an error occurs during registration, return an internal server error
-    if err := models.RegisterUser(req.Email, req.Username, req.Password); err != nil {
+    if err := store.Users.Register(req.Email, req.Username, req.Password); err != nil {
         c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
         return
     }
@@ -0,0 +1,221 @@
+// Package images checks uploaded images, strips their metadata and makes their
+// thumbnails, with the standard library alone.
+package images
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/draw"
+	_ "image/gif" // Registers the GIF decoder with image.Decode
+	"image/jpeg"
+	"image/png"
+	"net/http"
+)
+
+const (
+	// MaxPixels is the largest image, in pixels, that is decoded. It keeps small
+	// files that decode to huge images from exhausting the memory.
+	MaxPixels = 40_000_000
+	// ThumbnailSize is the longest side of a thumbnail, in pixels.
+	ThumbnailSize = 320
+	// AvatarSize is the side of a square avatar, in pixels.
+	AvatarSize = 256
+	// jpegQuality is the quality JPEG images and thumbnails are encoded at.
+	jpegQuality = 85
+)
+
+var (
+	// ErrUnsupported is returned for files that are not JPEG, PNG or GIF images.
+	ErrUnsupported = errors.New("only JPEG, PNG and GIF images are accepted")
+	// ErrTooLarge is returned for images with more than MaxPixels pixels.
+	ErrTooLarge = errors.New("the image has too many pixels")
+)
+
+// Image is an uploaded image ready to be stored.
+type Image struct {
+	Data         []byte // The image, re-encoded without metadata except for GIFs, which are kept as uploaded
+	ContentType  string // image/jpeg, image/png or image/gif
+	Ext          string // File extension matching ContentType, without the dot
+	Width        int    // Width in pixels
+	Height       int    // Height in pixels
+	Thumbnail    []byte // The image scaled down to fit ThumbnailSize, JPEG for JPEG images and PNG otherwise
+	ThumbnailExt string // File extension of the thumbnail, without the dot
+}
+
+// formats maps the content types accepted to the name image.Decode gives the
+// format and the file extension.
+var formats = map[string]struct{ name, ext string }{
+	"image/jpeg": {"jpeg", "jpg"},
+	"image/png":  {"png", "png"},
+	"image/gif":  {"gif", "gif"},
+}
+
+// Process checks that data is a JPEG, PNG or GIF image by its content, whatever
+// the uploader claimed, and prepares it and its thumbnail for storage.
+//
+// Parameters:
+//   - data: The uploaded file.
+//
+// Returns:
+//   - Image: The image to store.
+//   - error: ErrUnsupported or ErrTooLarge for images that are refused, or a decoding error; otherwise, nil.
+func Process(data []byte) (Image, error) {
+	decoded, contentType, err := decode(data)
+	if err != nil {
+		return Image{}, err
+	}
+
+	bounds := decoded.Bounds()
+	img := Image{
+		ContentType:  contentType,
+		Ext:          formats[contentType].ext,
+		Width:        bounds.Dx(),
+		Height:       bounds.Dy(),
+		ThumbnailExt: "png",
+	}
+
+	// Encoding the pixels again drops EXIF data such as the place a photo was
+	// taken at. Only the first frame of a GIF would survive, so GIFs stay as they are.
+	switch contentType {
+	case "image/jpeg":
+		img.Data, err = encodeJPEG(decoded)
+		img.ThumbnailExt = "jpg"
+	case "image/png":
+		img.Data, err = encodePNG(decoded)
+	default:
+		img.Data = data
+	}
+	if err != nil {
+		return Image{}, err
+	}
+
+	thumbnail := Thumbnail(decoded, ThumbnailSize)
+	if img.ThumbnailExt == "jpg" {
+		img.Thumbnail, err = encodeJPEG(thumbnail)
+	} else {
+		img.Thumbnail, err = encodePNG(thumbnail)
+	}
+	if err != nil {
+		return Image{}, err
+	}
+	return img, nil
+}
+
+// Avatar checks data as Process does and makes a profile picture of it: the
+// largest square in the middle of the image, scaled down to AvatarSize. Avatars
+// are JPEG images for JPEG uploads and PNG images otherwise, from the first frame
+// of GIFs.
+//
+// Parameters:
+//   - data: The uploaded file.
+//
+// Returns:
+//   - Image: The avatar to store, without a thumbnail.
+//   - error: ErrUnsupported or ErrTooLarge for images that are refused, or a decoding error; otherwise, nil.
+func Avatar(data []byte) (Image, error) {
+	decoded, contentType, err := decode(data)
+	if err != nil {
+		return Image{}, err
+	}
+
+	bounds := decoded.Bounds()
+	side := min(bounds.Dx(), bounds.Dy())
+	corner := bounds.Min.Add(image.Pt((bounds.Dx()-side)/2, (bounds.Dy()-side)/2))
+	avatar := image.NewRGBA(image.Rect(0, 0, side, side))
+	draw.Draw(avatar, avatar.Bounds(), decoded, corner, draw.Src)
+	scaled := Thumbnail(avatar, AvatarSize)
+
+	img := Image{ContentType: "image/png", Ext: "png", Width: scaled.Bounds().Dx(), Height: scaled.Bounds().Dy()}
+	if contentType == "image/jpeg" {
+		img.ContentType, img.Ext = contentType, "jpg"
+		img.Data, err = encodeJPEG(scaled)
+	} else {
+		img.Data, err = encodePNG(scaled)
+	}
+	if err != nil {
+		return Image{}, err
+	}
+	return img, nil
+}
+
+// decode checks that data is a JPEG, PNG or GIF image by its content and not
+// too large, and decodes it.
+func decode(data []byte) (image.Image, string, error) {
+	contentType := http.DetectContentType(data)
+	format, ok := formats[contentType]
+	if !ok {
+		return nil, "", ErrUnsupported
+	}
+
+	// Check the size before decoding the pixels
+	config, name, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil || name != format.name {
+		return nil, "", ErrUnsupported
+	}
+	if config.Width <= 0 || config.Height <= 0 || config.Width*config.Height > MaxPixels {
+		return nil, "", ErrTooLarge
+	}
+	decoded, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, "", err
+	}
+	return decoded, contentType, nil
+}
+
+func encodeJPEG(img image.Image) ([]byte, error) {
+	var buf bytes.Buffer
+	err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: jpegQuality})
+	return buf.Bytes(), err
+}
+
+func encodePNG(img image.Image) ([]byte, error) {
+	var buf bytes.Buffer
+	err := png.Encode(&buf, img)
+	return buf.Bytes(), err
+}
+
+// Thumbnail scales img down to fit a square of size pixels, keeping its aspect
+// ratio. Each pixel of the thumbnail is the average of the pixels it covers.
+// Images that already fit are returned as they are.
+func Thumbnail(img image.Image, size int) image.Image {
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	if width <= size && height <= size {
+		return img
+	}
+	thumbWidth, thumbHeight := size, size
+	if width > height {
+		thumbHeight = max(1, height*size/width)
+	} else {
+		thumbWidth = max(1, width*size/height)
+	}
+
+	// Work on premultiplied RGBA pixels, so that transparent pixels do not darken the edges
+	src := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.Draw(src, src.Bounds(), img, bounds.Min, draw.Src)
+	dst := image.NewRGBA(image.Rect(0, 0, thumbWidth, thumbHeight))
+
+	for y := 0; y < thumbHeight; y++ {
+		y0, y1 := y*height/thumbHeight, (y+1)*height/thumbHeight
+		for x := 0; x < thumbWidth; x++ {
+			x0, x1 := x*width/thumbWidth, (x+1)*width/thumbWidth
+			var sum [4]int
+			for sy := y0; sy < y1; sy++ {
+				row := src.Pix[sy*src.Stride+x0*4 : sy*src.Stride+x1*4]
+				for i := 0; i < len(row); i += 4 {
+					sum[0] += int(row[i])
+					sum[1] += int(row[i+1])
+					sum[2] += int(row[i+2])
+					sum[3] += int(row[i+3])
+				}
+			}
+			count := (y1 - y0) * (x1 - x0)
+			pixel := dst.Pix[y*dst.Stride+x*4:]
+			for i := range sum {
+				pixel[i] = uint8((sum[i] + count/2) / count)
+			}
+		}
+	}
+	return dst
+}
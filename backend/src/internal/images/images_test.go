@@ -0,0 +1,174 @@
+package images
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"testing"
+)
+
+// encoded returns a width by height image filled with c, encoded by encode.
+func encoded(t *testing.T, width, height int, c color.Color, encode func(*bytes.Buffer, image.Image) error) []byte {
+	t.Helper()
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			img.Set(x, y, c)
+		}
+	}
+	var buf bytes.Buffer
+	if err := encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func encodeJPEGFile(buf *bytes.Buffer, img image.Image) error { return jpeg.Encode(buf, img, nil) }
+func encodePNGFile(buf *bytes.Buffer, img image.Image) error  { return png.Encode(buf, img) }
+func encodeGIFFile(buf *bytes.Buffer, img image.Image) error  { return gif.Encode(buf, img, nil) }
+
+func TestProcess(t *testing.T) {
+	tests := []struct {
+		name          string
+		data          []byte
+		contentType   string
+		ext, thumbExt string
+		width, height int
+		thumbW        int
+		thumbH        int
+	}{
+		{"jpeg", encoded(t, 800, 600, color.RGBA{200, 30, 30, 255}, encodeJPEGFile), "image/jpeg", "jpg", "jpg", 800, 600, 320, 240},
+		{"png", encoded(t, 100, 1000, color.RGBA{0, 0, 0, 0}, encodePNGFile), "image/png", "png", "png", 100, 1000, 32, 320},
+		{"small gif", encoded(t, 40, 20, color.White, encodeGIFFile), "image/gif", "gif", "png", 40, 20, 40, 20},
+	}
+	for _, test := range tests {
+		img, err := Process(test.data)
+		if err != nil {
+			t.Errorf("%s: %v", test.name, err)
+			continue
+		}
+		if img.ContentType != test.contentType || img.Ext != test.ext || img.ThumbnailExt != test.thumbExt {
+			t.Errorf("%s: got %s, .%s with a .%s thumbnail", test.name, img.ContentType, img.Ext, img.ThumbnailExt)
+		}
+		if img.Width != test.width || img.Height != test.height {
+			t.Errorf("%s: got %dx%d, want %dx%d", test.name, img.Width, img.Height, test.width, test.height)
+		}
+		thumb, _, err := image.DecodeConfig(bytes.NewReader(img.Thumbnail))
+		if err != nil || thumb.Width != test.thumbW || thumb.Height != test.thumbH {
+			t.Errorf("%s: thumbnail %dx%d (%v), want %dx%d", test.name, thumb.Width, thumb.Height, err, test.thumbW, test.thumbH)
+		}
+	}
+}
+
+func TestProcessKeepsGIFs(t *testing.T) {
+	data := encoded(t, 10, 10, color.Black, encodeGIFFile)
+	img, err := Process(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(img.Data, data) {
+		t.Error("the GIF was encoded again")
+	}
+}
+
+func TestProcessRejects(t *testing.T) {
+	// A PNG header claiming far more pixels than MaxPixels, with no pixels behind it
+	huge := encoded(t, 1, 1, color.Black, encodePNGFile)
+	copy(huge[16:24], []byte{0, 0, 0xff, 0xff, 0, 0, 0xff, 0xff})
+	binary.BigEndian.PutUint32(huge[29:33], crc32.ChecksumIEEE(huge[12:29]))
+
+	tests := []struct {
+		name string
+		data []byte
+		want error
+	}{
+		{"text", []byte("just some text"), ErrUnsupported},
+		{"svg", []byte(`<svg xmlns="http://www.w3.org/2000/svg"></svg>`), ErrUnsupported},
+		{"truncated png", encoded(t, 10, 10, color.Black, encodePNGFile)[:20], ErrUnsupported},
+		{"too many pixels", huge, ErrTooLarge},
+	}
+	for _, test := range tests {
+		if _, err := Process(test.data); !errors.Is(err, test.want) {
+			t.Errorf("%s: got %v, want %v", test.name, err, test.want)
+		}
+	}
+}
+
+func TestThumbnailAverages(t *testing.T) {
+	// Black and white columns average to grey
+	img := image.NewGray(image.Rect(0, 0, 640, 2))
+	for y := 0; y < 2; y++ {
+		for x := 0; x < 640; x += 2 {
+			img.SetGray(x, y, color.Gray{255})
+		}
+	}
+	thumb := Thumbnail(img, 320)
+	if got := thumb.Bounds(); got.Dx() != 320 || got.Dy() != 1 {
+		t.Fatalf("thumbnail is %v", got)
+	}
+	if r, _, _, _ := thumb.At(100, 0).RGBA(); r>>8 < 126 || r>>8 > 129 {
+		t.Errorf("pixel is %d, want about 128", r>>8)
+	}
+}
+
+func TestAvatar(t *testing.T) {
+	// A wide image with a red middle and blue sides is cropped to its middle
+	wide := image.NewRGBA(image.Rect(0, 0, 900, 300))
+	for y := 0; y < 300; y++ {
+		for x := 0; x < 900; x++ {
+			c := color.RGBA{0, 0, 255, 255}
+			if x >= 300 && x < 600 {
+				c = color.RGBA{255, 0, 0, 255}
+			}
+			wide.Set(x, y, c)
+		}
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, wide); err != nil {
+		t.Fatal(err)
+	}
+	data := buf.Bytes()
+
+	tests := []struct {
+		name          string
+		data          []byte
+		contentType   string
+		width, height int
+	}{
+		{"wide png", data, "image/png", 256, 256},
+		{"tall jpeg", encoded(t, 400, 1200, color.White, encodeJPEGFile), "image/jpeg", 256, 256},
+		{"small gif", encoded(t, 60, 40, color.White, encodeGIFFile), "image/png", 40, 40},
+	}
+	for _, test := range tests {
+		img, err := Avatar(test.data)
+		if err != nil {
+			t.Errorf("%s: %v", test.name, err)
+			continue
+		}
+		config, _, err := image.DecodeConfig(bytes.NewReader(img.Data))
+		if err != nil || img.ContentType != test.contentType || config.Width != test.width || config.Height != test.height || img.Width != test.width {
+			t.Errorf("%s: got %s %dx%d (%v), want %s %dx%d", test.name, img.ContentType, config.Width, config.Height, err, test.contentType, test.width, test.height)
+		}
+	}
+
+	img, _ := Avatar(data)
+	decoded, err := png.Decode(bytes.NewReader(img.Data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, x := range []int{0, 128, 255} {
+		if r, _, b, _ := decoded.At(x, 128).RGBA(); r>>8 != 255 || b != 0 {
+			t.Errorf("pixel %d is not red, the avatar was not cropped to the middle", x)
+		}
+	}
+
+	if _, err := Avatar([]byte("just some text")); !errors.Is(err, ErrUnsupported) {
+		t.Errorf("text: got %v, want %v", err, ErrUnsupported)
+	}
+}
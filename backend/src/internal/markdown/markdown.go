@@ -0,0 +1,483 @@
+// Package markdown renders the Markdown posts and comments are written in to HTML
+// that is safe to embed in a page.
+//
+// Only a subset of Markdown is supported: paragraphs, where single line breaks are
+// kept, block quotes, bulleted and numbered lists, spoiler blocks, emphasis, strong
+// emphasis, strikethrough, inline code and links. Nothing in the source reaches the
+// output unescaped: raw HTML shows as text, and links only lead to http, https and
+// mailto addresses or to pages of the forum itself.
+package markdown
+
+import (
+	"html"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// maxNesting is how deep quotes, lists and spoilers may be nested inside one
+// another. Deeper markers are shown as text.
+const maxNesting = 16
+
+// Render converts Markdown to sanitized HTML.
+func Render(source string) string {
+	source = strings.ReplaceAll(source, "\r\n", "\n")
+	source = strings.ReplaceAll(source, "\t", "    ")
+
+	var out strings.Builder
+	renderBlocks(&out, strings.Split(source, "\n"), 0)
+	return out.String()
+}
+
+// renderBlocks writes the blocks the lines are made of: quotes, lists, spoilers
+// and paragraphs. depth is how deep they are nested.
+func renderBlocks(out *strings.Builder, lines []string, depth int) {
+	for i := 0; i < len(lines); {
+		trimmed := strings.TrimSpace(lines[i])
+		_, isItem := parseListItem(lines[i])
+		switch {
+		case trimmed == "":
+			i++
+		case depth < maxNesting && isSpoilerStart(trimmed):
+			i = renderSpoiler(out, lines, i, depth)
+		case depth < maxNesting && strings.HasPrefix(trimmed, ">"):
+			i = renderQuote(out, lines, i, depth)
+		case depth < maxNesting && isItem:
+			i = renderList(out, lines, i, depth)
+		default:
+			i = renderParagraph(out, lines, i, depth)
+		}
+	}
+}
+
+// isSpoilerStart reports whether a trimmed line opens a spoiler block: ":::spoiler",
+// optionally followed by the summary shown while the spoiler is hidden.
+func isSpoilerStart(trimmed string) bool {
+	rest, ok := strings.CutPrefix(trimmed, ":::spoiler")
+	return ok && (rest == "" || rest[0] == ' ')
+}
+
+// startsBlock reports whether a line opens a block other than a paragraph, ending
+// the paragraph before it.
+func startsBlock(line string, depth int) bool {
+	if depth >= maxNesting {
+		return false
+	}
+	trimmed := strings.TrimSpace(line)
+	_, isItem := parseListItem(line)
+	return isItem || isSpoilerStart(trimmed) || strings.HasPrefix(trimmed, ">")
+}
+
+// renderSpoiler writes the spoiler block opening at lines[start], up to its closing
+// ":::" line or the end of the text, and returns the index of the line after it.
+func renderSpoiler(out *strings.Builder, lines []string, start, depth int) int {
+	summary := strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(lines[start]), ":::spoiler"))
+	if summary == "" {
+		summary = "Spoiler"
+	}
+
+	// Spoilers may hold spoilers of their own, each closed by its own fence
+	end, open := start+1, 1
+	for ; end < len(lines); end++ {
+		trimmed := strings.TrimSpace(lines[end])
+		if isSpoilerStart(trimmed) {
+			open++
+		} else if trimmed == ":::" {
+			if open--; open == 0 {
+				break
+			}
+		}
+	}
+
+	out.WriteString(`<details class="spoiler"><summary>` + renderInline(summary, true) + "</summary>\n")
+	renderBlocks(out, lines[start+1:end], depth+1)
+	out.WriteString("</details>\n")
+	return end + 1
+}
+
+// renderQuote writes the block quote made of the lines starting with ">" from
+// lines[start] on, and returns the index of the line after it.
+func renderQuote(out *strings.Builder, lines []string, start, depth int) int {
+	var inner []string
+	end := start
+	for ; end < len(lines); end++ {
+		rest, ok := strings.CutPrefix(strings.TrimSpace(lines[end]), ">")
+		if !ok {
+			break
+		}
+		inner = append(inner, strings.TrimPrefix(rest, " "))
+	}
+
+	out.WriteString("<blockquote>\n")
+	renderBlocks(out, inner, depth+1)
+	out.WriteString("</blockquote>\n")
+	return end
+}
+
+// listItem is the first line of an item of a list.
+type listItem struct {
+	ordered bool   // Numbered rather than bulleted
+	number  int    // Number of a numbered item
+	content string // The text after the marker
+	width   int    // Width of the indentation and the marker, which continuation lines are indented by
+}
+
+// parseListItem reads the marker of a list item, "-", "*" or "+" for a bulleted
+// list, or a number followed by "." or ")" for a numbered one, each followed by a
+// space. It returns false if the line does not start an item.
+func parseListItem(line string) (listItem, bool) {
+	indent := indentation(line)
+	if indent > 3 {
+		return listItem{}, false
+	}
+	rest := line[indent:]
+
+	if len(rest) >= 2 && strings.IndexByte("-*+", rest[0]) >= 0 && rest[1] == ' ' {
+		return listItem{content: rest[2:], width: indent + 2}, true
+	}
+
+	digits := 0
+	for digits < len(rest) && digits < 9 && rest[digits] >= '0' && rest[digits] <= '9' {
+		digits++
+	}
+	if digits == 0 || digits+1 >= len(rest) || (rest[digits] != '.' && rest[digits] != ')') || rest[digits+1] != ' ' {
+		return listItem{}, false
+	}
+	number, _ := strconv.Atoi(rest[:digits])
+	return listItem{ordered: true, number: number, content: rest[digits+2:], width: indent + digits + 2}, true
+}
+
+// renderList writes the list whose first item is at lines[start], and returns the
+// index of the line after it. The list ends at the first line that is neither an
+// item of the same kind nor indented below an item.
+func renderList(out *strings.Builder, lines []string, start, depth int) int {
+	first, _ := parseListItem(lines[start])
+	tag := "ul"
+	if first.ordered {
+		tag = "ol"
+	}
+	if first.ordered && first.number != 1 {
+		out.WriteString(`<ol start="` + strconv.Itoa(first.number) + `">` + "\n")
+	} else {
+		out.WriteString("<" + tag + ">\n")
+	}
+
+	i := start
+	for i < len(lines) {
+		item, ok := parseListItem(lines[i])
+		if !ok || item.ordered != first.ordered {
+			break
+		}
+
+		// Gather the lines of the item: indented ones, including those after blank
+		// lines, and unindented ones carrying on its text
+		body := []string{item.content}
+		for i++; i < len(lines); i++ {
+			line := lines[i]
+			if strings.TrimSpace(line) == "" {
+				next := skipBlank(lines, i)
+				if next == len(lines) || indentation(lines[next]) < 2 {
+					break
+				}
+				body = append(body, lines[i:next]...)
+				i = next - 1
+			} else if indentation(line) >= 2 {
+				body = append(body, dedent(line, item.width))
+			} else if !startsBlock(line, depth) {
+				body = append(body, line)
+			} else {
+				break
+			}
+		}
+
+		var content strings.Builder
+		renderBlocks(&content, body, depth+1)
+		out.WriteString("<li>" + strings.TrimSuffix(unwrapParagraph(content.String()), "\n") + "</li>\n")
+
+		// Blank lines between the items of a list do not end it
+		if next := skipBlank(lines, i); next < len(lines) {
+			if item, ok := parseListItem(lines[next]); ok && item.ordered == first.ordered {
+				i = next
+			}
+		}
+	}
+
+	out.WriteString("</" + tag + ">\n")
+	return i
+}
+
+// unwrapParagraph removes the paragraph tags around the first block of a list
+// item, so that items read as lines of text rather than separate paragraphs.
+func unwrapParagraph(content string) string {
+	rest, ok := strings.CutPrefix(content, "<p>")
+	if !ok {
+		return content
+	}
+	text, after, _ := strings.Cut(rest, "</p>\n")
+	if after == "" {
+		return text
+	}
+	return text + "\n" + after
+}
+
+// renderParagraph writes the paragraph starting at lines[start], up to a blank
+// line or the start of another block, and returns the index of the line after it.
+// The line breaks within it are kept.
+func renderParagraph(out *strings.Builder, lines []string, start, depth int) int {
+	var parts []string
+	end := start
+	for ; end < len(lines); end++ {
+		line := strings.TrimSpace(lines[end])
+		if line == "" || (end > start && startsBlock(lines[end], depth)) {
+			break
+		}
+		parts = append(parts, renderInline(line, true))
+	}
+
+	out.WriteString("<p>" + strings.Join(parts, "<br>\n") + "</p>\n")
+	return end
+}
+
+// renderInline converts the formatting within a line: emphasis, strong emphasis,
+// strikethrough, inline code and, if links is true, links. Everything else is escaped.
+func renderInline(text string, links bool) string {
+	closers := emphasisClosers(text)
+	var out strings.Builder
+	for i := 0; i < len(text); {
+		c := text[i]
+		switch {
+		case c == '\\' && i+1 < len(text) && strings.IndexByte(escapable, text[i+1]) >= 0:
+			out.WriteString(html.EscapeString(text[i+1 : i+2]))
+			i += 2
+			continue
+		case c == '`':
+			if end := strings.IndexByte(text[i+1:], '`'); end > 0 {
+				out.WriteString("<code>" + html.EscapeString(text[i+1:i+1+end]) + "</code>")
+				i += end + 2
+				continue
+			}
+		case c == '[' && links:
+			if label, url, length, ok := parseLink(text[i:]); ok {
+				out.WriteString(link(url, renderInline(label, false)))
+				i += length
+				continue
+			}
+		case c == 'h' && links && (i == 0 || text[i-1] == ' ' || text[i-1] == '('):
+			if url := parseAutolink(text[i:]); url != "" {
+				out.WriteString(link(url, html.EscapeString(url)))
+				i += len(url)
+				continue
+			}
+		case c == '*' || c == '_' || c == '~':
+			// Longer runs of delimiters, like a row of stars, are plain text
+			if run := delimiterRun(text, i); run > maxDelimiterRun {
+				out.WriteString(text[i : i+run])
+				i += run
+				continue
+			}
+			if tag, inner, length, ok := parseEmphasis(text, i, closers); ok {
+				out.WriteString("<" + tag + ">" + renderInline(inner, links) + "</" + tag + ">")
+				i += length
+				continue
+			}
+		}
+		out.WriteString(html.EscapeString(text[i : i+1]))
+		i++
+	}
+	return out.String()
+}
+
+// escapable lists the characters a backslash shows as they are.
+const escapable = "\\`*_~[]()>#+-.!|:"
+
+// emphasisTags maps the emphasis delimiters to their HTML tags, the longer first.
+var emphasisTags = []struct{ delimiter, tag string }{
+	{"**", "strong"},
+	{"__", "strong"},
+	{"~~", "del"},
+	{"*", "em"},
+	{"_", "em"},
+}
+
+// maxDelimiterRun is the longest run of emphasis delimiters that opens or closes
+// emphasis, as in ***both***.
+const maxDelimiterRun = 3
+
+// delimiterRun returns the number of times the character at text[start] repeats from there.
+func delimiterRun(text string, start int) int {
+	end := start + 1
+	for end < len(text) && text[end] == text[start] {
+		end++
+	}
+	return end - start
+}
+
+// closingEnd reports whether the emphasis delimiter d at text[at] may close
+// emphasis, and returns where the closing delimiter starts: at the end of a run of
+// delimiters, so that ***both*** nests.
+func closingEnd(text string, at int, d string) (int, bool) {
+	if at == 0 || !strings.HasPrefix(text[at:], d) || text[at-1] == ' ' {
+		return 0, false
+	}
+	// A single delimiter is not closed by part of a double one, as in *a **b** c*
+	if len(d) == 1 && (text[at-1] == d[0] || (at+1 < len(text) && text[at+1] == d[0])) {
+		return 0, false
+	}
+	// Longer runs of delimiters are plain text
+	runStart := at
+	for runStart > 0 && text[runStart-1] == d[0] {
+		if at-runStart == maxDelimiterRun {
+			return 0, false
+		}
+		runStart--
+	}
+	if delimiterRun(text[:min(len(text), runStart+maxDelimiterRun+1)], runStart) > maxDelimiterRun {
+		return 0, false
+	}
+	end := at
+	for end+len(d) < len(text) && text[end+len(d)] == d[0] {
+		end++
+	}
+	if d[0] == '_' && end+len(d) < len(text) && isWordByte(text[end+len(d)]) {
+		return 0, false
+	}
+	return end, true
+}
+
+// emphasisClosers lists, for each of the emphasisTags, the positions in text
+// where it may close, in order. Looking closers up rather than searching for them
+// from every opening delimiter keeps rendering fast on text full of delimiters.
+func emphasisClosers(text string) [][]int {
+	closers := make([][]int, len(emphasisTags))
+	for at := 1; at < len(text); at++ {
+		if c := text[at]; c != '*' && c != '_' && c != '~' {
+			continue
+		}
+		for k, emphasis := range emphasisTags {
+			if _, ok := closingEnd(text, at, emphasis.delimiter); ok {
+				closers[k] = append(closers[k], at)
+			}
+		}
+	}
+	return closers
+}
+
+// parseEmphasis reads the emphasis opening at text[start], given the closers of
+// text. It returns the tag, the text inside the delimiters and the length of the
+// whole, or false if the delimiter at start is not closed. Underscores within
+// words, as in snake_case, are left alone.
+func parseEmphasis(text string, start int, closers [][]int) (string, string, int, bool) {
+	for k, emphasis := range emphasisTags {
+		d := emphasis.delimiter
+		if !strings.HasPrefix(text[start:], d) {
+			continue
+		}
+		if d[0] == '_' && start > 0 && isWordByte(text[start-1]) {
+			return "", "", 0, false
+		}
+		from := start + len(d)
+		if from >= len(text) || text[from] == ' ' {
+			continue
+		}
+
+		// The first closer leaving some text inside
+		next := sort.SearchInts(closers[k], from+1)
+		if next == len(closers[k]) {
+			continue
+		}
+		end, _ := closingEnd(text, closers[k][next], d)
+		return emphasis.tag, text[from:end], end + len(d) - start, true
+	}
+	return "", "", 0, false
+}
+
+// maxLinkLength is the longest a link may be, label and URL included.
+const maxLinkLength = 2048
+
+// parseLink reads a link written [label](url) at the start of text. It returns the
+// label, the URL and the length of the whole, or false if text does not start with one.
+func parseLink(text string) (string, string, int, bool) {
+	if len(text) > maxLinkLength {
+		text = text[:maxLinkLength]
+	}
+	labelEnd := strings.Index(text, "](")
+	if labelEnd < 2 || strings.ContainsAny(text[1:labelEnd], "[]") {
+		return "", "", 0, false
+	}
+	urlEnd := strings.IndexByte(text[labelEnd+2:], ')')
+	if urlEnd < 0 {
+		return "", "", 0, false
+	}
+	url := strings.TrimSpace(text[labelEnd+2 : labelEnd+2+urlEnd])
+	if url == "" || strings.ContainsAny(url, " \"'<>") {
+		return "", "", 0, false
+	}
+	return text[1:labelEnd], url, labelEnd + 3 + urlEnd, true
+}
+
+// parseAutolink returns the http or https address at the start of text, without
+// the punctuation ending the sentence it is in, or "" if there is none.
+func parseAutolink(text string) string {
+	if !strings.HasPrefix(text, "http://") && !strings.HasPrefix(text, "https://") {
+		return ""
+	}
+	end := strings.IndexAny(text, " <>\"'")
+	if end < 0 {
+		end = len(text)
+	}
+	url := strings.TrimRight(text[:end], ".,;:!?)")
+	if strings.HasSuffix(url, "://") {
+		return ""
+	}
+	return url
+}
+
+// link returns an HTML link to url with the given label, already converted to HTML.
+// Links to anything but http, https and mailto addresses or relative ones are
+// dropped, leaving the label alone.
+func link(url, label string) string {
+	if !isSafeURL(url) {
+		return label
+	}
+	return `<a href="` + html.EscapeString(url) + `" rel="nofollow ugc">` + label + "</a>"
+}
+
+// isSafeURL reports whether a link may lead to url: an http, https or mailto
+// address, or a relative one.
+func isSafeURL(url string) bool {
+	colon := strings.IndexByte(url, ':')
+	if colon < 0 || strings.ContainsAny(url[:colon], "/?#") {
+		return true
+	}
+	switch strings.ToLower(url[:colon]) {
+	case "http", "https", "mailto":
+		return true
+	}
+	return false
+}
+
+// indentation returns the number of spaces a line starts with.
+func indentation(line string) int {
+	return len(line) - len(strings.TrimLeft(line, " "))
+}
+
+// dedent removes up to width spaces from the start of a line.
+func dedent(line string, width int) string {
+	if indent := indentation(line); indent < width {
+		width = indent
+	}
+	return line[width:]
+}
+
+// skipBlank returns the index of the first line from lines[start] on that is not blank.
+func skipBlank(lines []string, start int) int {
+	for start < len(lines) && strings.TrimSpace(lines[start]) == "" {
+		start++
+	}
+	return start
+}
+
+// isWordByte reports whether c is a letter, a digit or part of a multibyte character.
+func isWordByte(c byte) bool {
+	return c >= 0x80 || c == '_' || (c >= '0' && c <= '9') || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
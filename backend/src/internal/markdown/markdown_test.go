@@ -0,0 +1,63 @@
+package markdown
+
+import (
+	"regexp"
+	"testing"
+)
+
+func TestRender(t *testing.T) {
+	tests := []struct {
+		name, source, want string
+	}{
+		{"paragraphs", "First line\nsecond line\n\nNext paragraph", "<p>First line<br>\nsecond line</p>\n<p>Next paragraph</p>\n"},
+		{"emphasis", "*em* _em_ **strong** __strong__ ~~gone~~", "<p><em>em</em> <em>em</em> <strong>strong</strong> <strong>strong</strong> <del>gone</del></p>\n"},
+		{"nested emphasis", "***both*** and *a **b** c*", "<p><strong><em>both</em></strong> and <em>a <strong>b</strong> c</em></p>\n"},
+		{"unclosed and spaced delimiters", "2 * 3 * 4 and **open", "<p>2 * 3 * 4 and **open</p>\n"},
+		{"rows of delimiters", "***** *a* ~~~~ **b**", "<p>***** <em>a</em> ~~~~ <strong>b</strong></p>\n"},
+		{"underscores within words", "snake_case_name", "<p>snake_case_name</p>\n"},
+		{"inline code", "`<b>*not bold*</b>`", "<p><code>&lt;b&gt;*not bold*&lt;/b&gt;</code></p>\n"},
+		{"backslash escapes", `\*literal\*`, "<p>*literal*</p>\n"},
+		{"raw html", `<script>alert("x")</script>`, "<p>&lt;script&gt;alert(&#34;x&#34;)&lt;/script&gt;</p>\n"},
+		{"link", "[Dune](https://example.com/dune?a=1&b=2)", `<p><a href="https://example.com/dune?a=1&amp;b=2" rel="nofollow ugc">Dune</a></p>` + "\n"},
+		{"relative link", "[a post](/post?id=3)", `<p><a href="/post?id=3" rel="nofollow ugc">a post</a></p>` + "\n"},
+		{"unsafe links", "[x](javascript:alert(1)) [y](JavaScript:void) [z](data:text/html,hi)", "<p>x) y z</p>\n"},
+		{"emphasis in a link", "[**bold**](http://a.example)", `<p><a href="http://a.example" rel="nofollow ugc"><strong>bold</strong></a></p>` + "\n"},
+		{"autolink", "See https://example.com/a_b.", `<p>See <a href="https://example.com/a_b" rel="nofollow ugc">https://example.com/a_b</a>.</p>` + "\n"},
+		{"quote", "> Call me Ishmael.\n> Some years ago\n\nAfter", "<blockquote>\n<p>Call me Ishmael.<br>\nSome years ago</p>\n</blockquote>\n<p>After</p>\n"},
+		{"nested quote", ">> deep", "<blockquote>\n<blockquote>\n<p>deep</p>\n</blockquote>\n</blockquote>\n"},
+		{"bulleted list", "- one\n* two\n+ **three**", "<ul>\n<li>one</li>\n<li>two</li>\n<li><strong>three</strong></li>\n</ul>\n"},
+		{"numbered list", "3. three\n4) four", "<ol start=\"3\">\n<li>three</li>\n<li>four</li>\n</ol>\n"},
+		{"nested list", "- a\n  1. b\n  2. c\n- d", "<ul>\n<li>a\n<ol>\n<li>b</li>\n<li>c</li>\n</ol></li>\n<li>d</li>\n</ul>\n"},
+		{"list after a paragraph", "Reading list:\n- Emma\n\n- Persuasion\n\nDone", "<p>Reading list:</p>\n<ul>\n<li>Emma</li>\n<li>Persuasion</li>\n</ul>\n<p>Done</p>\n"},
+		{"spoiler", ":::spoiler Ending\nRosebud was the *sled*.\n:::\nAfter", "<details class=\"spoiler\"><summary>Ending</summary>\n<p>Rosebud was the <em>sled</em>.</p>\n</details>\n<p>After</p>\n"},
+		{"unclosed spoiler", ":::spoiler\nhidden", "<details class=\"spoiler\"><summary>Spoiler</summary>\n<p>hidden</p>\n</details>\n"},
+		{"not a spoiler", ":::spoilers", "<p>:::spoilers</p>\n"},
+		{"windows line endings", "a\r\nb", "<p>a<br>\nb</p>\n"},
+		{"empty", "", ""},
+	}
+	for _, test := range tests {
+		if got := Render(test.source); got != test.want {
+			t.Errorf("%s: Render(%q) =\n%q, want\n%q", test.name, test.source, got, test.want)
+		}
+	}
+}
+
+// tag matches the tags in the output of Render, and allowedTag the ones it may write.
+var (
+	tag        = regexp.MustCompile(`<[^>]*>?`)
+	allowedTag = regexp.MustCompile(`^</?(p|br|em|strong|del|code|blockquote|ul|ol|li|a|summary|details|details class="spoiler"|ol start="\d+"|a href="[^"<>]*" rel="nofollow ugc")>$`)
+)
+
+func FuzzRender(f *testing.F) {
+	for _, seed := range []string{"*a* **b** `c`", "[x](javascript:alert(1))", "> - <b>\n>> :::spoiler <i>", "<img src=x onerror=alert(1)>"} {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, source string) {
+		out := Render(source)
+		for _, written := range tag.FindAllString(out, -1) {
+			if !allowedTag.MatchString(written) {
+				t.Fatalf("Render(%q) writes %q, which is not an allowed tag", source, written)
+			}
+		}
+	})
+}
@@ -0,0 +1,205 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"literary-lions/backend/src/internal/db"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Attachment is an image uploaded to be shown on posts, such as a book cover
+// or a photo. The files are kept in storage under their keys.
+type Attachment struct {
+	ID           int    `json:"id"`
+	UserID       int    `json:"user_id,omitempty"` // ID of the uploader, filled in on upload
+	Key          string `json:"key"`               // Storage key of the image
+	ThumbnailKey string `json:"thumbnail_key"`     // Storage key of the thumbnail
+	ContentType  string `json:"content_type,omitempty"`
+	Size         int    `json:"size,omitempty"` // Size of the image in bytes, filled in on upload
+	Width        int    `json:"width"`
+	Height       int    `json:"height"`
+	Cover        bool   `json:"cover,omitempty"` // Whether it is the cover of the post carrying it
+}
+
+// MaxPostAttachments is the number of images a post can carry.
+const MaxPostAttachments = 10
+
+var (
+	// ErrInvalidAttachment is returned when a post is given an image that does not
+	// exist, or that was uploaded by someone else and is not on the post yet.
+	ErrInvalidAttachment = errors.New("unknown image, or an image uploaded by another member")
+	// ErrTooManyAttachments is returned when a post is given more than MaxPostAttachments images.
+	ErrTooManyAttachments = fmt.Errorf("a post can have at most %d images", MaxPostAttachments)
+)
+
+// attachmentRepository implements AttachmentRepository on top of a SQL database.
+type attachmentRepository struct {
+	db *db.DB
+}
+
+// attachmentsColumn aggregates the images of the post aliased p into one column
+// of postSelect, each as "position id key thumbnail_key width height cover".
+const attachmentsColumn = `(SELECT string_agg(pa.position || ' ' || a.id || ' ' || a.file_key || ' ' || a.thumbnail_key || ' ' || a.width || ' ' || a.height || ' ' || CASE WHEN pa.cover THEN 1 ELSE 0 END, ',')
+                FROM post_attachments pa INNER JOIN attachments a ON a.id = pa.attachment_id WHERE pa.post_id = p.id) AS attachments`
+
+// splitAttachments reads the images aggregated by attachmentsColumn, in the order
+// of the post, and picks out its cover.
+func splitAttachments(aggregated sql.NullString) ([]Attachment, *Attachment) {
+	attachments := []Attachment{}
+	if !aggregated.Valid || aggregated.String == "" {
+		return attachments, nil
+	}
+	positions := map[int]int{}
+	for _, item := range strings.Split(aggregated.String, ",") {
+		fields := strings.Fields(item)
+		if len(fields) != 7 {
+			continue
+		}
+		var attachment Attachment
+		position, _ := strconv.Atoi(fields[0])
+		attachment.ID, _ = strconv.Atoi(fields[1])
+		attachment.Key, attachment.ThumbnailKey = fields[2], fields[3]
+		attachment.Width, _ = strconv.Atoi(fields[4])
+		attachment.Height, _ = strconv.Atoi(fields[5])
+		attachment.Cover = fields[6] == "1"
+		positions[attachment.ID] = position
+		attachments = append(attachments, attachment)
+	}
+	sort.Slice(attachments, func(i, j int) bool {
+		return positions[attachments[i].ID] < positions[attachments[j].ID]
+	})
+
+	for i := range attachments {
+		if attachments[i].Cover {
+			return attachments, &attachments[i]
+		}
+	}
+	return attachments, nil
+}
+
+// Create records an uploaded image whose files are in storage.
+// Parameters:
+//   - attachment: The uploader, storage keys, content type, size and dimensions of the image.
+//
+// Returns:
+//   - int: The ID of the image.
+//   - error: An error if the insertion fails; otherwise, nil.
+func (r *attachmentRepository) Create(attachment Attachment) (int, error) {
+	var id int
+	err := r.db.QueryRow(`
+        INSERT INTO attachments (user_id, file_key, thumbnail_key, content_type, size, width, height, created_at)
+        VALUES (?, ?, ?, ?, ?, ?, ?, ?) RETURNING id`,
+		attachment.UserID, attachment.Key, attachment.ThumbnailKey, attachment.ContentType, attachment.Size, attachment.Width, attachment.Height, time.Now().UTC()).Scan(&id)
+	return id, err
+}
+
+// SetForPost replaces the images of a post or draft.
+// Parameters:
+//   - postID: The ID of the post.
+//   - userID: The ID of the user making the change, who may add the images they uploaded.
+//   - attachmentIDs: The images, in the order they are shown; an empty list removes them all.
+//   - coverID: The image shown in the listings, one of attachmentIDs, or 0 for the first one.
+//
+// Returns:
+//   - error: ErrTooManyAttachments, or ErrInvalidAttachment for an image the user may not
+//     add or a cover that is not among the images, or any other error; otherwise, nil.
+func (r *attachmentRepository) SetForPost(postID, userID int, attachmentIDs []int, coverID int) error {
+	ids := []int{}
+	seen := map[int]bool{}
+	for _, id := range attachmentIDs {
+		if !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+	if len(ids) > MaxPostAttachments {
+		return ErrTooManyAttachments
+	}
+	if coverID == 0 && len(ids) > 0 {
+		coverID = ids[0]
+	} else if coverID != 0 && !seen[coverID] {
+		return ErrInvalidAttachment
+	}
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback() // No-op once the transaction is committed.
+
+	// Members can add their own uploads, and keep those already on the post, e.g.
+	// when an admin edits the post of someone else
+	for _, id := range ids {
+		var allowed bool
+		err := tx.QueryRow(`
+            SELECT EXISTS (SELECT 1 FROM attachments a WHERE a.id = ? AND (a.user_id = ?
+                OR EXISTS (SELECT 1 FROM post_attachments pa WHERE pa.attachment_id = a.id AND pa.post_id = ?)))`,
+			id, userID, postID).Scan(&allowed)
+		if err != nil {
+			return err
+		}
+		if !allowed {
+			return ErrInvalidAttachment
+		}
+	}
+
+	if _, err := tx.Exec("DELETE FROM post_attachments WHERE post_id = ?", postID); err != nil {
+		return err
+	}
+	for position, id := range ids {
+		if _, err := tx.Exec("INSERT INTO post_attachments (post_id, attachment_id, position, cover) VALUES (?, ?, ?, ?)", postID, id, position, id == coverID); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// PurgeUnused deletes the images that no post, draft or post in the trash
+// carries, that no book has as its cover, and that were uploaded before the
+// given time. Their files stay in storage, for the caller to remove once no
+// image uses them.
+// Parameters:
+//   - before: The time uploads are kept until, to give the uploader time to attach them.
+//
+// Returns:
+//   - int: The number of images deleted.
+//   - error: An error if the deletion fails; otherwise, nil.
+func (r *attachmentRepository) PurgeUnused(before time.Time) (int, error) {
+	result, err := r.db.Exec(`
+        DELETE FROM attachments WHERE created_at < ? AND id NOT IN (SELECT attachment_id FROM post_attachments)
+            AND id NOT IN (SELECT cover_id FROM books WHERE cover_id IS NOT NULL)`, before.UTC())
+	if err != nil {
+		return 0, err
+	}
+	purged, err := result.RowsAffected()
+	return int(purged), err
+}
+
+// Keys lists the storage keys of the images and thumbnails still recorded, and
+// of the avatars of members.
+// Returns:
+//   - []string: The keys in use.
+//   - error: An error if the query fails; otherwise, nil.
+func (r *attachmentRepository) Keys() ([]string, error) {
+	rows, err := r.db.Query(`
+        SELECT file_key FROM attachments UNION SELECT thumbnail_key FROM attachments
+        UNION SELECT avatar_key FROM users WHERE avatar_key <> ''`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var keys []string
+	for rows.Next() {
+		var key string
+		if err := rows.Scan(&key); err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+	return keys, rows.Err()
+}
@@ -0,0 +1,452 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"literary-lions/backend/src/internal/db"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Book is a title of the catalog that posts can discuss. Books added by members
+// wait for an admin or curator to approve them before they are listed.
+type Book struct {
+	ID          int           `json:"id"`
+	Title       string        `json:"title"`
+	Authors     []string      `json:"authors"`
+	ISBN        string        `json:"isbn"`               // ISBN-10 or ISBN-13, digits only, empty if unknown
+	Year        int           `json:"year"`               // Year of first publication, 0 if unknown
+	CoverID     int           `json:"cover_id,omitempty"` // The uploaded image shown as the cover, 0 for none
+	Cover       *Attachment   `json:"cover,omitempty"`    // Storage keys and size of the cover, filled in when reading
+	Approved    bool          `json:"approved"`           // Whether the book is listed in the catalog
+	SubmittedBy int           `json:"submitted_by,omitempty"`
+	PostCount   int           `json:"post_count"` // Number of posts discussing the book
+	Rating      RatingSummary `json:"rating"`     // Average and spread of the ratings of its reviews
+	CreatedAt   time.Time     `json:"created_at"`
+}
+
+// BookRef is a book as linked from a post: enough to name it and lead to its page.
+type BookRef struct {
+	ID    int    `json:"id"`
+	Title string `json:"title"`
+}
+
+// BookFilter narrows a book listing.
+type BookFilter struct {
+	Query   string // Part of the title or of an author, ignoring case
+	Pending bool   // List the books waiting for approval instead of the catalog
+	Sort    string // BookSortTitle, the default, or BookSortRating
+	Limit   int    // Number of books, 0 for all of them
+}
+
+// Sort orders of book listings.
+const (
+	// BookSortTitle lists books by title.
+	BookSortTitle = "title"
+	// BookSortRating lists the best rated books first, and the most reviewed among equals.
+	BookSortRating = "rating"
+)
+
+const (
+	// MaxPostBooks is the number of books a post can link.
+	MaxPostBooks = 5
+	// maxBookTitleLength is the length of the longest book title.
+	maxBookTitleLength = 200
+)
+
+var (
+	// ErrInvalidBook is returned when a book has no title, one that is too long,
+	// too many or too long authors, or a year in the future.
+	ErrInvalidBook = fmt.Errorf("a book needs a title of at most %d characters, at most %d authors of at most %d characters and a year that is not in the future",
+		maxBookTitleLength, MaxFavorites, maxFavoriteLength)
+	// ErrInvalidISBN is returned when an ISBN has the wrong length or check digit.
+	ErrInvalidISBN = errors.New("an ISBN has 10 or 13 digits and a valid check digit")
+	// ErrBookExists is returned when another book already has the ISBN.
+	ErrBookExists = errors.New("a book with this ISBN is already in the catalog")
+	// ErrUnknownBook is returned when a post links a book that does not exist, or that
+	// another member added and is still waiting for approval.
+	ErrUnknownBook = errors.New("unknown book, or a book waiting for approval that another member added")
+	// ErrTooManyBooks is returned when a post links more than MaxPostBooks books.
+	ErrTooManyBooks = fmt.Errorf("a post can link at most %d books", MaxPostBooks)
+)
+
+// normalizeISBN strips the dashes and spaces of an ISBN and checks its check digit.
+func normalizeISBN(isbn string) (string, error) {
+	isbn = strings.ToUpper(strings.NewReplacer("-", "", " ", "").Replace(isbn))
+	switch len(isbn) {
+	case 0:
+		return "", nil
+	case 10:
+		// Weights 10 down to 1, the check digit may be X for 10
+		sum := 0
+		for i, c := range isbn {
+			digit := int(c - '0')
+			if c == 'X' && i == 9 {
+				digit = 10
+			} else if c < '0' || c > '9' {
+				return "", ErrInvalidISBN
+			}
+			sum += (10 - i) * digit
+		}
+		if sum%11 == 0 {
+			return isbn, nil
+		}
+	case 13:
+		// Weights alternate between 1 and 3
+		sum := 0
+		for i, c := range isbn {
+			if c < '0' || c > '9' {
+				return "", ErrInvalidISBN
+			}
+			sum += int(c-'0') * (1 + 2*(i%2))
+		}
+		if sum%10 == 0 {
+			return isbn, nil
+		}
+	}
+	return "", ErrInvalidISBN
+}
+
+// normalize collapses the spaces of the book, cleans its authors and ISBN and
+// checks the result.
+func (b *Book) normalize() error {
+	b.Title = strings.Join(strings.Fields(b.Title), " ")
+	if b.Title == "" || utf8.RuneCountInString(b.Title) > maxBookTitleLength || b.Year > time.Now().Year()+1 {
+		return ErrInvalidBook
+	}
+	authors, err := cleanFavorites(b.Authors)
+	if err != nil {
+		return ErrInvalidBook
+	}
+	b.Authors = authors
+	b.ISBN, err = normalizeISBN(b.ISBN)
+	return err
+}
+
+// bookRefsColumn aggregates the books linked from the post aliased p into one
+// column of postSelect, one per line, each as "position id title". Titles have
+// their spaces collapsed, so they hold no line breaks.
+const bookRefsColumn = `(SELECT string_agg(pb.position || ' ' || b.id || ' ' || b.title, '` + "\n" + `')
+                FROM post_books pb INNER JOIN books b ON b.id = pb.book_id WHERE pb.post_id = p.id) AS books`
+
+// splitBookRefs reads the books aggregated by bookRefsColumn, in the order of the post.
+func splitBookRefs(aggregated sql.NullString) []BookRef {
+	books := []BookRef{}
+	if !aggregated.Valid || aggregated.String == "" {
+		return books
+	}
+	positions := map[int]int{}
+	for _, line := range strings.Split(aggregated.String, "\n") {
+		fields := strings.SplitN(line, " ", 3)
+		if len(fields) != 3 {
+			continue
+		}
+		var book BookRef
+		position, _ := strconv.Atoi(fields[0])
+		book.ID, _ = strconv.Atoi(fields[1])
+		book.Title = fields[2]
+		positions[book.ID] = position
+		books = append(books, book)
+	}
+	sort.Slice(books, func(i, j int) bool {
+		return positions[books[i].ID] < positions[books[j].ID]
+	})
+	return books
+}
+
+// bookRepository implements BookRepository on top of a SQL database.
+type bookRepository struct {
+	db *db.DB
+}
+
+// bookSelect reads books together with their cover, their ratings and the number
+// of forum posts discussing them, leaving out drafts and the posts in the trash.
+const bookSelect = `
+        SELECT b.id, b.title, b.authors, b.isbn, b.year, b.approved, COALESCE(b.submitted_by, 0), b.created_at,
+               COALESCE(a.id, 0), COALESCE(a.file_key, ''), COALESCE(a.thumbnail_key, ''), COALESCE(a.width, 0), COALESCE(a.height, 0),
+               (SELECT COUNT(*) FROM post_books pb INNER JOIN posts p ON p.id = pb.post_id AND p.deleted_at IS NULL AND p.status = 'published' AND ` + forumPosts + ` WHERE pb.book_id = b.id) AS post_count,
+               ` + ratingsColumns + `
+        FROM books b
+        LEFT JOIN attachments a ON a.id = b.cover_id` + ratingsJoin
+
+// scanBook reads a row selected with bookSelect.
+func scanBook(row interface{ Scan(...interface{}) error }) (Book, error) {
+	var book Book
+	var authors string
+	var cover Attachment
+	dest := []interface{}{&book.ID, &book.Title, &authors, &book.ISBN, &book.Year, &book.Approved, &book.SubmittedBy, &book.CreatedAt,
+		&cover.ID, &cover.Key, &cover.ThumbnailKey, &cover.Width, &cover.Height, &book.PostCount}
+	err := row.Scan(append(dest, book.Rating.ratingsDest()...)...)
+	book.Authors = splitFavorites(authors)
+	book.Rating.round()
+	if cover.ID != 0 {
+		book.CoverID, book.Cover = cover.ID, &cover
+	}
+	return book, err
+}
+
+// List returns the books of the catalog, or those waiting for approval, by title
+// or by rating.
+//
+// Parameters:
+//   - filter: The search, whether to list pending books, the sort order, and how many.
+//
+// Returns:
+//   - []Book: The books, in the requested order.
+//   - error: ErrInvalidSort for an unknown sort order, or any query error; otherwise, nil.
+func (r *bookRepository) List(filter BookFilter) ([]Book, error) {
+	where := " WHERE b.approved = ?"
+	args := []interface{}{!filter.Pending}
+	if query := strings.TrimSpace(filter.Query); query != "" {
+		where += " AND (LOWER(b.title) LIKE LOWER(?) OR LOWER(b.authors) LIKE LOWER(?))"
+		args = append(args, "%"+query+"%", "%"+query+"%")
+	}
+	switch filter.Sort {
+	case "", BookSortTitle:
+		where += " ORDER BY LOWER(b.title), b.id"
+	case BookSortRating:
+		where += " ORDER BY COALESCE(ratings.average_rating, 0) DESC, COALESCE(ratings.review_count, 0) DESC, LOWER(b.title), b.id"
+	default:
+		return nil, fmt.Errorf("%w %q", ErrInvalidSort, filter.Sort)
+	}
+	if filter.Limit > 0 {
+		where += " LIMIT ?"
+		args = append(args, filter.Limit)
+	}
+
+	rows, err := r.db.Query(bookSelect+where, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	books := []Book{}
+	for rows.Next() {
+		book, err := scanBook(rows)
+		if err != nil {
+			return nil, err
+		}
+		books = append(books, book)
+	}
+	return books, rows.Err()
+}
+
+// GetByID returns the book with the given ID, approved or not.
+//
+// Parameters:
+//   - bookID: The ID of the book.
+//
+// Returns:
+//   - Book: The book.
+//   - error: ErrNotFound if no book has the ID, or any other query error; otherwise, nil.
+func (r *bookRepository) GetByID(bookID int) (Book, error) {
+	book, err := scanBook(r.db.QueryRow(bookSelect+" WHERE b.id = ?", bookID))
+	if errors.Is(err, sql.ErrNoRows) {
+		return Book{}, ErrNotFound
+	}
+	return book, err
+}
+
+// Create adds a book to the catalog.
+//
+// Parameters:
+//   - book: The title, authors, ISBN, year and cover of the book, who added it, and
+//     whether it is approved right away.
+//
+// Returns:
+//   - Book: The stored book, with its ID.
+//   - error: ErrInvalidBook, ErrInvalidISBN, ErrBookExists, ErrInvalidAttachment for a cover
+//     the submitter did not upload, or any other error from the insert; otherwise, nil.
+func (r *bookRepository) Create(book Book) (Book, error) {
+	if err := book.normalize(); err != nil {
+		return Book{}, err
+	}
+	if err := r.checkCover(book.CoverID, book.SubmittedBy); err != nil {
+		return Book{}, err
+	}
+
+	var id int
+	err := r.db.QueryRow(`
+        INSERT INTO books (title, authors, isbn, year, cover_id, approved, submitted_by, created_at)
+        VALUES (?, ?, ?, ?, ?, ?, ?, ?) RETURNING id`,
+		book.Title, strings.Join(book.Authors, "\n"), book.ISBN, book.Year, nullableID(book.CoverID), book.Approved, nullableID(book.SubmittedBy), time.Now().UTC()).Scan(&id)
+	if err != nil {
+		return Book{}, r.uniqueError(err)
+	}
+	return r.GetByID(id)
+}
+
+// Update overwrites the title, authors, ISBN, year and cover of a book.
+//
+// Parameters:
+//   - book: The book to update, identified by its ID.
+//
+// Returns:
+//   - Book: The stored book.
+//   - error: ErrNotFound, ErrInvalidBook, ErrInvalidISBN, ErrBookExists, ErrInvalidAttachment
+//     for a cover that does not exist, or any other error from the update; otherwise, nil.
+func (r *bookRepository) Update(book Book) (Book, error) {
+	if err := book.normalize(); err != nil {
+		return Book{}, err
+	}
+	if err := r.checkCover(book.CoverID, 0); err != nil {
+		return Book{}, err
+	}
+
+	result, err := r.db.Exec("UPDATE books SET title = ?, authors = ?, isbn = ?, year = ?, cover_id = ? WHERE id = ?",
+		book.Title, strings.Join(book.Authors, "\n"), book.ISBN, book.Year, nullableID(book.CoverID), book.ID)
+	if err != nil {
+		return Book{}, r.uniqueError(err)
+	}
+	if err := requireAffected(result); err != nil {
+		return Book{}, err
+	}
+	return r.GetByID(book.ID)
+}
+
+// Approve lists a book waiting for approval in the catalog.
+//
+// Parameters:
+//   - bookID: The ID of the book.
+//
+// Returns:
+//   - error: ErrNotFound if no book has the ID, or any other error from the update; otherwise, nil.
+func (r *bookRepository) Approve(bookID int) error {
+	result, err := r.db.Exec("UPDATE books SET approved = ? WHERE id = ?", true, bookID)
+	if err != nil {
+		return err
+	}
+	return requireAffected(result)
+}
+
+// Delete removes a book from the catalog with its reviews, and from the posts,
+// reading shelves and club reading schedules holding it.
+//
+// Parameters:
+//   - bookID: The ID of the book to delete.
+//
+// Returns:
+//   - error: ErrNotFound if no book has the ID, or any other error from the deletion; otherwise, nil.
+func (r *bookRepository) Delete(bookID int) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback() // No-op once the transaction is committed.
+
+	if _, err := tx.Exec("DELETE FROM post_books WHERE book_id = ?", bookID); err != nil {
+		return err
+	}
+	if _, err := tx.Exec("DELETE FROM book_reviews WHERE book_id = ?", bookID); err != nil {
+		return err
+	}
+	if _, err := tx.Exec("DELETE FROM reading_shelves WHERE book_id = ?", bookID); err != nil {
+		return err
+	}
+	if _, err := tx.Exec("UPDATE club_sections SET book_id = NULL WHERE book_id = ?", bookID); err != nil {
+		return err
+	}
+	result, err := tx.Exec("DELETE FROM books WHERE id = ?", bookID)
+	if err != nil {
+		return err
+	}
+	if err := requireAffected(result); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// SetForPost replaces the books a post or draft links.
+//
+// Parameters:
+//   - postID: The ID of the post.
+//   - userID: The ID of the user making the change, who may link the books waiting
+//     for approval that they added.
+//   - bookIDs: The books, in the order they are shown; an empty list removes them all.
+//
+// Returns:
+//   - error: ErrTooManyBooks, ErrUnknownBook for a book the user may not link, or any
+//     other error; otherwise, nil.
+func (r *bookRepository) SetForPost(postID, userID int, bookIDs []int) error {
+	ids := []int{}
+	seen := map[int]bool{}
+	for _, id := range bookIDs {
+		if !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+	if len(ids) > MaxPostBooks {
+		return ErrTooManyBooks
+	}
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback() // No-op once the transaction is committed.
+
+	// Books waiting for approval are kept to their submitter, and to the posts
+	// already linking them, e.g. when an admin edits the post of someone else
+	for _, id := range ids {
+		var allowed bool
+		err := tx.QueryRow(`
+            SELECT EXISTS (SELECT 1 FROM books b WHERE b.id = ? AND (b.approved OR b.submitted_by = ?
+                OR EXISTS (SELECT 1 FROM post_books pb WHERE pb.book_id = b.id AND pb.post_id = ?)))`,
+			id, userID, postID).Scan(&allowed)
+		if err != nil {
+			return err
+		}
+		if !allowed {
+			return ErrUnknownBook
+		}
+	}
+
+	if _, err := tx.Exec("DELETE FROM post_books WHERE post_id = ?", postID); err != nil {
+		return err
+	}
+	for position, id := range ids {
+		if _, err := tx.Exec("INSERT INTO post_books (post_id, book_id, position) VALUES (?, ?, ?)", postID, id, position); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// checkCover checks that the cover of a book is an uploaded image, and one the
+// given user uploaded unless userID is 0.
+func (r *bookRepository) checkCover(coverID, userID int) error {
+	if coverID == 0 {
+		return nil
+	}
+	var exists bool
+	err := r.db.QueryRow("SELECT EXISTS (SELECT 1 FROM attachments WHERE id = ? AND (? = 0 OR user_id = ?))", coverID, userID, userID).Scan(&exists)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return ErrInvalidAttachment
+	}
+	return nil
+}
+
+// uniqueError turns unique constraint failures on the ISBN into ErrBookExists
+// and returns any other error unchanged.
+func (r *bookRepository) uniqueError(err error) error {
+	if r.db.Dialect.IsUniqueViolation(err, "isbn") {
+		return ErrBookExists
+	}
+	return err
+}
+
+// nullableID stores the ID 0 as NULL, for optional foreign keys.
+func nullableID(id int) interface{} {
+	if id == 0 {
+		return nil
+	}
+	return id
+}
@@ -0,0 +1,213 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"literary-lions/backend/src/internal/db"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Category is a section of the forum that posts are filed under.
+type Category struct {
+	ID          int       `json:"id"`
+	Slug        string    `json:"slug"` // URL-friendly identifier, derived from the name if left empty
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	Position    int       `json:"position"`   // Listing order, lowest first
+	PostCount   int       `json:"post_count"` // Number of posts in the category
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+var (
+	// ErrCategoryExists is returned when another category already uses the slug or name.
+	ErrCategoryExists = errors.New("a category with this slug or name already exists")
+	// ErrCategoryInUse is returned when deleting a category that still has posts.
+	ErrCategoryInUse = errors.New("the category still has posts")
+	// ErrInvalidCategory is returned when a category has no name or an unusable slug.
+	ErrInvalidCategory = errors.New("a category needs a name and a slug of lowercase letters, digits and dashes")
+)
+
+// categorySlug matches the slugs a category may use.
+var categorySlug = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+
+// slugSeparators matches the runs of characters replaced by a dash when deriving a slug.
+var slugSeparators = regexp.MustCompile(`[^a-z0-9]+`)
+
+// Slugify derives a slug from a name, e.g. "Science Fiction" becomes "science-fiction".
+func Slugify(name string) string {
+	return strings.Trim(slugSeparators.ReplaceAllString(strings.ToLower(name), "-"), "-")
+}
+
+// normalize trims the category, derives a missing slug and checks the result.
+func (c *Category) normalize() error {
+	c.Name = strings.TrimSpace(c.Name)
+	c.Description = strings.TrimSpace(c.Description)
+	c.Slug = strings.TrimSpace(c.Slug)
+	if c.Slug == "" {
+		c.Slug = Slugify(c.Name)
+	}
+	if c.Name == "" || !categorySlug.MatchString(c.Slug) {
+		return ErrInvalidCategory
+	}
+	return nil
+}
+
+// categoryRepository implements CategoryRepository on top of a SQL database.
+type categoryRepository struct {
+	db *db.DB
+}
+
+// categorySelect reads categories together with the number of forum posts filed under
+// them, leaving out the posts in the trash.
+const categorySelect = `
+        SELECT c.id, c.slug, c.name, c.description, c.position, c.created_at,
+               (SELECT COUNT(*) FROM posts p WHERE p.category_id = c.id AND p.deleted_at IS NULL AND p.status = 'published' AND ` + forumPosts + `) AS post_count
+        FROM categories c`
+
+// scanCategory reads a row selected with categorySelect.
+func scanCategory(row interface{ Scan(...interface{}) error }) (Category, error) {
+	var category Category
+	err := row.Scan(&category.ID, &category.Slug, &category.Name, &category.Description, &category.Position, &category.CreatedAt, &category.PostCount)
+	return category, err
+}
+
+// List returns every category in listing order.
+//
+// Returns:
+//   - []Category: The categories, ordered by position and then name.
+//   - error: An error if the query fails; otherwise, nil.
+func (r *categoryRepository) List() ([]Category, error) {
+	rows, err := r.db.Query(categorySelect + " ORDER BY c.position, c.name")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	categories := []Category{}
+	for rows.Next() {
+		category, err := scanCategory(rows)
+		if err != nil {
+			return nil, err
+		}
+		categories = append(categories, category)
+	}
+	return categories, rows.Err()
+}
+
+// GetByID returns the category with the given ID.
+//
+// Parameters:
+//   - categoryID: The ID of the category.
+//
+// Returns:
+//   - Category: The category.
+//   - error: ErrNotFound if no category has the ID, or any other query error; otherwise, nil.
+func (r *categoryRepository) GetByID(categoryID int) (Category, error) {
+	return r.get(" WHERE c.id = ?", categoryID)
+}
+
+// Find returns the category identified by a slug or, ignoring case, a name, so that
+// clients filing posts by the category names used so far keep working.
+//
+// Parameters:
+//   - ref: The slug or name of the category.
+//
+// Returns:
+//   - Category: The category.
+//   - error: ErrNotFound if no category matches, or any other query error; otherwise, nil.
+func (r *categoryRepository) Find(ref string) (Category, error) {
+	ref = strings.TrimSpace(ref)
+	return r.get(" WHERE c.slug = ? OR LOWER(c.name) = LOWER(?) ORDER BY c.slug = ? DESC LIMIT 1", ref, ref, ref)
+}
+
+// get returns the single category selected by the where clause.
+func (r *categoryRepository) get(where string, args ...interface{}) (Category, error) {
+	category, err := scanCategory(r.db.QueryRow(categorySelect+where, args...))
+	if errors.Is(err, sql.ErrNoRows) {
+		return Category{}, ErrNotFound
+	}
+	return category, err
+}
+
+// Create adds a category. A missing slug is derived from the name.
+//
+// Parameters:
+//   - category: The slug, name, description and position of the new category.
+//
+// Returns:
+//   - Category: The stored category, with its ID.
+//   - error: ErrInvalidCategory, ErrCategoryExists, or any other error from the insert; otherwise, nil.
+func (r *categoryRepository) Create(category Category) (Category, error) {
+	if err := category.normalize(); err != nil {
+		return Category{}, err
+	}
+
+	var id int
+	err := r.db.QueryRow("INSERT INTO categories (slug, name, description, position) VALUES (?, ?, ?, ?) RETURNING id",
+		category.Slug, category.Name, category.Description, category.Position).Scan(&id)
+	if err != nil {
+		return Category{}, r.uniqueError(err)
+	}
+	return r.GetByID(id)
+}
+
+// Update overwrites the slug, name, description and position of a category.
+// A missing slug is derived from the name.
+//
+// Parameters:
+//   - category: The category to update, identified by its ID.
+//
+// Returns:
+//   - Category: The stored category.
+//   - error: ErrNotFound, ErrInvalidCategory, ErrCategoryExists, or any other error from the update; otherwise, nil.
+func (r *categoryRepository) Update(category Category) (Category, error) {
+	if err := category.normalize(); err != nil {
+		return Category{}, err
+	}
+
+	result, err := r.db.Exec("UPDATE categories SET slug = ?, name = ?, description = ?, position = ? WHERE id = ?",
+		category.Slug, category.Name, category.Description, category.Position, category.ID)
+	if err != nil {
+		return Category{}, r.uniqueError(err)
+	}
+	if err := requireAffected(result); err != nil {
+		return Category{}, err
+	}
+	return r.GetByID(category.ID)
+}
+
+// Delete removes a category that no post is filed under.
+//
+// Parameters:
+//   - categoryID: The ID of the category to delete.
+//
+// Returns:
+//   - error: ErrCategoryInUse if posts still use the category, ErrNotFound if no category has the ID,
+//     or any other error from the deletion; otherwise, nil.
+func (r *categoryRepository) Delete(categoryID int) error {
+	// The check and the deletion run in one statement, so a post filed in between cannot be orphaned.
+	result, err := r.db.Exec("DELETE FROM categories WHERE id = ? AND NOT EXISTS (SELECT 1 FROM posts WHERE category_id = ?)", categoryID, categoryID)
+	if err != nil {
+		return err
+	}
+	if err := requireAffected(result); !errors.Is(err, ErrNotFound) {
+		return err
+	}
+
+	// Nothing was deleted: tell a missing category from one that is in use.
+	if _, err := r.GetByID(categoryID); err != nil {
+		return err
+	}
+	return ErrCategoryInUse
+}
+
+// uniqueError turns unique constraint failures on the slug or name into
+// ErrCategoryExists and returns any other error unchanged.
+func (r *categoryRepository) uniqueError(err error) error {
+	if r.db.Dialect.IsUniqueViolation(err, "slug") || r.db.Dialect.IsUniqueViolation(err, "name") {
+		return ErrCategoryExists
+	}
+	return err
+}
@@ -0,0 +1,478 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"literary-lions/backend/src/internal/db"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Visibilities of a club.
+const (
+	// ClubPublic clubs show their posts to everyone and let anyone join.
+	ClubPublic = "public"
+	// ClubPrivate clubs show their posts to their members alone, and moderators
+	// approve who joins.
+	ClubPrivate = "private"
+)
+
+// Roles of the members of a club.
+const (
+	// ClubMemberRole members read and write the discussions of the club.
+	ClubMemberRole = "member"
+	// ClubModeratorRole members also approve who joins, remove members and delete
+	// posts and comments in the club.
+	ClubModeratorRole = "moderator"
+	// ClubOwnerRole is held by the member who started the club, who also edits and
+	// deletes it and names the moderators.
+	ClubOwnerRole = "owner"
+)
+
+// Limits of the text of a club.
+const (
+	MaxClubNameLength        = 100
+	MaxClubDescriptionLength = 1000
+)
+
+var (
+	// ErrClubExists is returned when another club already uses the name, or a name with the same slug.
+	ErrClubExists = errors.New("a club with this name already exists")
+	// ErrInvalidClub is returned when a club has no name, an overlong name or description, or an unknown visibility.
+	ErrInvalidClub = errors.New("a club needs a name of at most 100 characters, a description of at most 1000 characters and a public or private visibility")
+	// ErrInvalidClubRole is returned when a member is given a role other than member or moderator.
+	ErrInvalidClubRole = errors.New("members are given the member or moderator role")
+	// ErrClubOwner is returned when the owner of a club would leave it, be removed or change role.
+	ErrClubOwner = errors.New("the owner stays in the club until it is deleted")
+)
+
+// Club is a group of members discussing books in posts of their own.
+type Club struct {
+	ID          int         `json:"id"`
+	Slug        string      `json:"slug"` // URL-friendly identifier, derived from the name when the club is started
+	Name        string      `json:"name"`
+	Description string      `json:"description"`
+	Visibility  string      `json:"visibility"` // ClubPublic or ClubPrivate
+	OwnerID     int         `json:"owner_id"`
+	Owner       string      `json:"owner"`        // Username of the owner
+	MemberCount int         `json:"member_count"` // Number of members, pending requests aside
+	PostCount   int         `json:"post_count"`   // Number of posts in the club, the trash aside
+	CreatedAt   time.Time   `json:"created_at"`
+	Membership  *ClubMember `json:"membership,omitempty"` // The membership of the requesting member, nil if none
+}
+
+// ClubMember is the membership of a member in a club, or their request to join it.
+type ClubMember struct {
+	UserID     int       `json:"user_id"`
+	Username   string    `json:"username"`
+	ProfilePic string    `json:"profile_pic"` // Key of the avatar, empty for the default one
+	Role       string    `json:"role"`        // ClubMemberRole, ClubModeratorRole or ClubOwnerRole
+	Approved   bool      `json:"approved"`    // False while a request to join a private club waits
+	JoinedAt   time.Time `json:"joined_at"`
+}
+
+// Moderates reports whether the member may moderate the club.
+func (m ClubMember) Moderates() bool {
+	return m.Approved && (m.Role == ClubModeratorRole || m.Role == ClubOwnerRole)
+}
+
+// normalize trims the club and checks the result.
+func (c *Club) normalize() error {
+	c.Name = strings.TrimSpace(c.Name)
+	c.Description = strings.TrimSpace(c.Description)
+	if c.Visibility == "" {
+		c.Visibility = ClubPublic
+	}
+	if c.Name == "" || Slugify(c.Name) == "" || utf8.RuneCountInString(c.Name) > MaxClubNameLength ||
+		utf8.RuneCountInString(c.Description) > MaxClubDescriptionLength ||
+		(c.Visibility != ClubPublic && c.Visibility != ClubPrivate) {
+		return ErrInvalidClub
+	}
+	return nil
+}
+
+// clubRepository implements ClubRepository on top of a SQL database.
+type clubRepository struct {
+	db *db.DB
+}
+
+// clubSelect reads clubs together with their owner, their numbers of members and
+// posts, and the membership of the member bound to its placeholder.
+const clubSelect = `
+        SELECT c.id, c.slug, c.name, c.description, c.visibility, c.owner_id, u.username, c.created_at,
+               (SELECT COUNT(*) FROM club_members cm WHERE cm.club_id = c.id AND cm.approved) AS member_count,
+               (SELECT COUNT(*) FROM posts p WHERE p.club_id = c.id AND p.deleted_at IS NULL AND p.status = 'published') AS post_count,
+               COALESCE(m.role, ''), COALESCE(m.approved, FALSE), m.joined_at, COALESCE(mu.username, ''), COALESCE(mu.avatar_key, '')
+        FROM clubs c
+        INNER JOIN users u ON u.id = c.owner_id
+        LEFT JOIN club_members m ON m.club_id = c.id AND m.user_id = ?
+        LEFT JOIN users mu ON mu.id = m.user_id`
+
+// scanClub reads a row selected with clubSelect for the member with the given ID.
+func scanClub(row interface{ Scan(...interface{}) error }, userID int) (Club, error) {
+	var club Club
+	var membership ClubMember
+	var joinedAt sql.NullTime
+	err := row.Scan(&club.ID, &club.Slug, &club.Name, &club.Description, &club.Visibility, &club.OwnerID, &club.Owner, &club.CreatedAt,
+		&club.MemberCount, &club.PostCount, &membership.Role, &membership.Approved, &joinedAt, &membership.Username, &membership.ProfilePic)
+	if membership.Role != "" {
+		membership.UserID, membership.JoinedAt = userID, joinedAt.Time
+		club.Membership = &membership
+	}
+	return club, err
+}
+
+// List returns every club by name. Private clubs are listed too, so that members
+// can ask to join them.
+//
+// Parameters:
+//   - userID: The ID of the member whose memberships are filled in, or 0 for guests.
+//
+// Returns:
+//   - []Club: The clubs, with the membership of the member in each.
+//   - error: Any query error; otherwise, nil.
+func (r *clubRepository) List(userID int) ([]Club, error) {
+	rows, err := r.db.Query(clubSelect+" ORDER BY LOWER(c.name), c.id", userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	clubs := []Club{}
+	for rows.Next() {
+		club, err := scanClub(rows, userID)
+		if err != nil {
+			return nil, err
+		}
+		clubs = append(clubs, club)
+	}
+	return clubs, rows.Err()
+}
+
+// GetBySlug returns the club with the given slug.
+//
+// Parameters:
+//   - slug: The slug of the club.
+//   - userID: The ID of the member whose membership is filled in, or 0 for guests.
+//
+// Returns:
+//   - Club: The club, with the membership of the member.
+//   - error: ErrNotFound if no club has the slug, or any other query error; otherwise, nil.
+func (r *clubRepository) GetBySlug(slug string, userID int) (Club, error) {
+	club, err := scanClub(r.db.QueryRow(clubSelect+" WHERE c.slug = ?", userID, slug), userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Club{}, ErrNotFound
+	}
+	return club, err
+}
+
+// GetByID returns the club with the given ID.
+//
+// Parameters:
+//   - clubID: The ID of the club.
+//   - userID: The ID of the member whose membership is filled in, or 0 for guests.
+//
+// Returns:
+//   - Club: The club, with the membership of the member.
+//   - error: ErrNotFound if no club has the ID, or any other query error; otherwise, nil.
+func (r *clubRepository) GetByID(clubID, userID int) (Club, error) {
+	club, err := scanClub(r.db.QueryRow(clubSelect+" WHERE c.id = ?", userID, clubID), userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Club{}, ErrNotFound
+	}
+	return club, err
+}
+
+// Create starts a club, with its owner as its first member. The slug is derived
+// from the name.
+//
+// Parameters:
+//   - club: The name, description, visibility and owner of the new club.
+//
+// Returns:
+//   - Club: The stored club, with the membership of its owner.
+//   - error: ErrInvalidClub, ErrClubExists, or any other error from the insert; otherwise, nil.
+func (r *clubRepository) Create(club Club) (Club, error) {
+	if err := club.normalize(); err != nil {
+		return Club{}, err
+	}
+	club.Slug = Slugify(club.Name)
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		return Club{}, err
+	}
+	defer tx.Rollback() // No-op once the transaction is committed.
+
+	var id int
+	err = tx.QueryRow("INSERT INTO clubs (slug, name, description, visibility, owner_id) VALUES (?, ?, ?, ?, ?) RETURNING id",
+		club.Slug, club.Name, club.Description, club.Visibility, club.OwnerID).Scan(&id)
+	if err != nil {
+		return Club{}, r.uniqueError(err)
+	}
+	if _, err := tx.Exec("INSERT INTO club_members (club_id, user_id, role) VALUES (?, ?, ?)", id, club.OwnerID, ClubOwnerRole); err != nil {
+		return Club{}, err
+	}
+	if err := tx.Commit(); err != nil {
+		return Club{}, err
+	}
+	return r.GetBySlug(club.Slug, club.OwnerID)
+}
+
+// Update overwrites the name, description and visibility of a club. Its slug stays
+// the same, so that links to the club keep working. A club made public lets in
+// the members waiting to join it.
+//
+// Parameters:
+//   - club: The club to update, identified by its ID.
+//
+// Returns:
+//   - error: ErrNotFound, ErrInvalidClub, ErrClubExists, or any other error from the update; otherwise, nil.
+func (r *clubRepository) Update(club Club) error {
+	if err := club.normalize(); err != nil {
+		return err
+	}
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback() // No-op once the transaction is committed.
+
+	result, err := tx.Exec("UPDATE clubs SET name = ?, description = ?, visibility = ? WHERE id = ?",
+		club.Name, club.Description, club.Visibility, club.ID)
+	if err != nil {
+		return r.uniqueError(err)
+	}
+	if err := requireAffected(result); err != nil {
+		return err
+	}
+	if club.Visibility == ClubPublic {
+		if _, err := tx.Exec("UPDATE club_members SET approved = TRUE WHERE club_id = ? AND NOT approved", club.ID); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// Delete removes a club, its memberships and its reading schedule, and moves its
+// posts to the trash. They cannot be restored without their club, and are purged
+// with the rest.
+//
+// Parameters:
+//   - clubID: The ID of the club to delete.
+//
+// Returns:
+//   - error: ErrNotFound if no club has the ID, or any other error; otherwise, nil.
+func (r *clubRepository) Delete(clubID int) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback() // No-op once the transaction is committed.
+
+	if _, err := tx.Exec("UPDATE posts SET deleted_at = CURRENT_TIMESTAMP WHERE club_id = ? AND deleted_at IS NULL", clubID); err != nil {
+		return err
+	}
+	if _, err := tx.Exec("DELETE FROM club_section_reads WHERE section_id IN (SELECT id FROM club_sections WHERE club_id = ?)", clubID); err != nil {
+		return err
+	}
+	if _, err := tx.Exec("DELETE FROM club_sections WHERE club_id = ?", clubID); err != nil {
+		return err
+	}
+	if _, err := tx.Exec("DELETE FROM club_members WHERE club_id = ?", clubID); err != nil {
+		return err
+	}
+	result, err := tx.Exec("DELETE FROM clubs WHERE id = ?", clubID)
+	if err != nil {
+		return err
+	}
+	if err := requireAffected(result); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// memberSelect reads the members of clubs together with their username and avatar.
+const memberSelect = `
+        SELECT m.user_id, u.username, u.avatar_key, m.role, m.approved, m.joined_at
+        FROM club_members m
+        INNER JOIN users u ON u.id = m.user_id`
+
+// scanMember reads a row selected with memberSelect.
+func scanMember(row interface{ Scan(...interface{}) error }) (ClubMember, error) {
+	var member ClubMember
+	err := row.Scan(&member.UserID, &member.Username, &member.ProfilePic, &member.Role, &member.Approved, &member.JoinedAt)
+	return member, err
+}
+
+// Members lists the members of a club, the owner and moderators first and then by
+// the time they joined, or the requests to join it waiting for a moderator.
+//
+// Parameters:
+//   - clubID: The ID of the club.
+//   - approved: True for the members, false for the pending requests.
+//
+// Returns:
+//   - []ClubMember: The members or requests.
+//   - error: Any query error; otherwise, nil.
+func (r *clubRepository) Members(clubID int, approved bool) ([]ClubMember, error) {
+	rows, err := r.db.Query(memberSelect+`
+        WHERE m.club_id = ? AND m.approved = ?
+        ORDER BY CASE m.role WHEN 'owner' THEN 0 WHEN 'moderator' THEN 1 ELSE 2 END, m.joined_at, m.user_id`, clubID, approved)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	members := []ClubMember{}
+	for rows.Next() {
+		member, err := scanMember(rows)
+		if err != nil {
+			return nil, err
+		}
+		members = append(members, member)
+	}
+	return members, rows.Err()
+}
+
+// Membership returns the membership of a member in a club, or their request to join it.
+//
+// Parameters:
+//   - clubID: The ID of the club.
+//   - userID: The ID of the member.
+//
+// Returns:
+//   - ClubMember: The membership.
+//   - error: ErrNotFound if the member neither belongs to the club nor asked to, or any other error; otherwise, nil.
+func (r *clubRepository) Membership(clubID, userID int) (ClubMember, error) {
+	member, err := scanMember(r.db.QueryRow(memberSelect+" WHERE m.club_id = ? AND m.user_id = ?", clubID, userID))
+	if errors.Is(err, sql.ErrNoRows) {
+		return ClubMember{}, ErrNotFound
+	}
+	return member, err
+}
+
+// Join adds a member to a public club, or records their request to join a private
+// one. Joining a club again keeps the membership as it is.
+//
+// Parameters:
+//   - clubID: The ID of the club.
+//   - userID: The ID of the member.
+//
+// Returns:
+//   - ClubMember: The membership, approved unless the club is private.
+//   - error: ErrNotFound if no club has the ID, or any other error; otherwise, nil.
+func (r *clubRepository) Join(clubID, userID int) (ClubMember, error) {
+	_, err := r.db.Exec(`
+        INSERT INTO club_members (club_id, user_id, role, approved)
+        SELECT id, ?, ?, visibility = ? FROM clubs WHERE id = ?
+        ON CONFLICT (club_id, user_id) DO NOTHING`, userID, ClubMemberRole, ClubPublic, clubID)
+	if err != nil {
+		return ClubMember{}, err
+	}
+	return r.Membership(clubID, userID)
+}
+
+// Approve lets in a member who asked to join a private club.
+//
+// Parameters:
+//   - clubID: The ID of the club.
+//   - userID: The ID of the member.
+//
+// Returns:
+//   - error: ErrNotFound if the member has no request waiting, or any other error; otherwise, nil.
+func (r *clubRepository) Approve(clubID, userID int) error {
+	result, err := r.db.Exec("UPDATE club_members SET approved = TRUE, joined_at = CURRENT_TIMESTAMP WHERE club_id = ? AND user_id = ? AND NOT approved", clubID, userID)
+	if err != nil {
+		return err
+	}
+	return requireAffected(result)
+}
+
+// SetRole makes a member of a club a moderator, or a plain member again.
+//
+// Parameters:
+//   - clubID: The ID of the club.
+//   - userID: The ID of the member.
+//   - role: ClubMemberRole or ClubModeratorRole.
+//
+// Returns:
+//   - error: ErrInvalidClubRole, ErrClubOwner for the owner, ErrNotFound if the user is
+//     not a member of the club, or any other error; otherwise, nil.
+func (r *clubRepository) SetRole(clubID, userID int, role string) error {
+	if role != ClubMemberRole && role != ClubModeratorRole {
+		return ErrInvalidClubRole
+	}
+	member, err := r.Membership(clubID, userID)
+	if err != nil {
+		return err
+	}
+	if !member.Approved {
+		return ErrNotFound
+	} else if member.Role == ClubOwnerRole {
+		return ErrClubOwner
+	}
+
+	_, err = r.db.Exec("UPDATE club_members SET role = ? WHERE club_id = ? AND user_id = ? AND role <> ?", role, clubID, userID, ClubOwnerRole)
+	return err
+}
+
+// RemoveMember takes a member out of a club, or turns down their request to join
+// it. Members leaving a club are removed the same way. Their posts stay in the club.
+//
+// Parameters:
+//   - clubID: The ID of the club.
+//   - userID: The ID of the member.
+//
+// Returns:
+//   - error: ErrClubOwner for the owner, ErrNotFound if the user neither belongs to the
+//     club nor asked to, or any other error; otherwise, nil.
+func (r *clubRepository) RemoveMember(clubID, userID int) error {
+	result, err := r.db.Exec("DELETE FROM club_members WHERE club_id = ? AND user_id = ? AND role <> ?", clubID, userID, ClubOwnerRole)
+	if err != nil {
+		return err
+	}
+	if err := requireAffected(result); !errors.Is(err, ErrNotFound) {
+		return err
+	}
+
+	// Nothing was removed: tell the owner from someone who is not a member.
+	if _, err := r.Membership(clubID, userID); err != nil {
+		return err
+	}
+	return ErrClubOwner
+}
+
+// ClubOf returns the club a post, or the post of a comment, is in.
+//
+// Parameters:
+//   - targetType: ReactionTargetPost or ReactionTargetComment.
+//   - targetID: The ID of the post or comment.
+//
+// Returns:
+//   - int: The ID of the club, or 0 for the forum.
+//   - error: ErrNotFound if the post or comment does not exist, or any other error; otherwise, nil.
+func (r *clubRepository) ClubOf(targetType string, targetID int) (int, error) {
+	query := "SELECT COALESCE(club_id, 0) FROM posts WHERE id = ?"
+	if targetType == ReactionTargetComment {
+		query = "SELECT COALESCE(p.club_id, 0) FROM comments c INNER JOIN posts p ON p.id = c.post_id WHERE c.id = ?"
+	}
+
+	var clubID int
+	err := r.db.QueryRow(query, targetID).Scan(&clubID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, ErrNotFound
+	}
+	return clubID, err
+}
+
+// uniqueError turns unique constraint failures on the slug or name into
+// ErrClubExists and returns any other error unchanged.
+func (r *clubRepository) uniqueError(err error) error {
+	if r.db.Dialect.IsUniqueViolation(err, "slug") || r.db.Dialect.IsUniqueViolation(err, "name") {
+		return ErrClubExists
+	}
+	return err
+}
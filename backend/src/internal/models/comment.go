@@ -1,83 +1,424 @@
 package models
 
 import (
+	"database/sql"
+	"errors"
+	"literary-lions/backend/src/internal/db"
 	"time"
 )
 
+// DefaultCommentMaxDepth is the deepest level replies are nested at when no other is configured.
+// Comments on the post itself are at depth 0, replies to them at depth 1, and so on.
+const DefaultCommentMaxDepth = 5
+
 type Comment struct {
-	ID        int
-	PostID    int
-	UserID    int
-	Username  string
-	Likes	  int
-	Dislikes  int
-	Content   string
+	ID        int `json:"id"`
+	PostID    int `json:"post_id"`
+	ParentID  int `json:"parent_id"` // The comment replied to, 0 for a comment on the post itself
+	Depth     int `json:"depth"`     // Level in the thread, 0 for a comment on the post itself
+	UserID    int `json:"user_id"`
+	Username  string `json:"username"`
+	Likes	  int `json:"likes"`
+	Dislikes  int `json:"dislikes"`
+	Content   string `json:"content"`
+	ContentHTML string `json:"content_html,omitempty"` // Content rendered from Markdown, filled in on the post page
 	CreatedAt time.Time `json:"created_at" db:"createdAt"`
+	EditedAt  *time.Time `json:"edited_at,omitempty"` // Time of the last edit, nil if never edited
+	Deleted   bool `json:"deleted"` // Removed but kept for its replies, with its content and author blanked
+	DeletedAt *time.Time `json:"deleted_at,omitempty"` // Time it was moved to the trash, filled in on the trash listing
+	PostTitle string `json:"post_title,omitempty"` // Title of the post, filled in on the trash listing and the comments of a user
+	Editable  bool `json:"editable"` // Whether the requesting user may edit and delete it, filled in on the post page
+	Reactions []ReactionCount `json:"reactions,omitempty"` // Counts of the configured reactions, filled in on the post page
+}
+
+// commentRepository implements CommentRepository on top of a SQL database.
+type commentRepository struct {
+	db *db.DB
 }
 
-// CreateComment inserts a new comment into the database.
+// Create inserts a new comment into the database, on a post outside the trash.
 // Parameters:
 //   - postID: The ID of the post that the comment is associated with.
 //   - userID: The ID of the user who is creating the comment.
 //   - content: The text content of the comment.
 //
 // Returns:
-//   - error: An error if the operation fails; otherwise, nil.
-func CreateComment(postID, userID int, content string) error {
-	// Execute the SQL command to insert a new comment into the 'comments' table.
-	_, err := db.Exec("INSERT INTO comments (post_id, user_id, content) VALUES (?, ?, ?)", postID, userID, content)
-	return err
+//   - error: ErrNotFound if the post does not exist, or any other error if the operation fails; otherwise, nil.
+func (r *commentRepository) Create(postID, userID int, content string) error {
+	// Execute the SQL command to insert a new comment into the 'comments' table, if the post exists.
+	result, err := r.db.Exec("INSERT INTO comments (post_id, user_id, content) SELECT ?, ?, ? WHERE EXISTS (SELECT 1 FROM posts WHERE id = ? AND deleted_at IS NULL AND status = 'published')", postID, userID, content, postID)
+	if err != nil {
+		return err
+	}
+	return requireAffected(result)
+}
+
+// Reply inserts a reply to a comment, on the same post. A reply to a comment at the
+// deepest allowed level is attached next to it instead, so that threads keep going
+// without being nested any further.
+// Parameters:
+//   - parentID: The ID of the comment being replied to.
+//   - userID: The ID of the user who is replying.
+//   - content: The text content of the reply.
+//   - maxDepth: The deepest level replies are nested at.
+//
+// Returns:
+//   - int: The ID of the post the reply was added to.
+//   - error: ErrNotFound if the comment does not exist or was removed, or any other error if the operation fails; otherwise, nil.
+func (r *commentRepository) Reply(parentID, userID int, content string, maxDepth int) (int, error) {
+	var postID, depth int
+	var grandparentID sql.NullInt64
+	err := r.db.QueryRow(`
+        SELECT c.post_id, c.parent_id, c.depth
+        FROM comments c
+        INNER JOIN posts p ON p.id = c.post_id AND p.deleted_at IS NULL AND p.status = 'published'
+        WHERE c.id = ? AND c.deleted_at IS NULL`, parentID).Scan(&postID, &grandparentID, &depth)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, ErrNotFound
+	} else if err != nil {
+		return 0, err
+	}
+
+	if depth >= maxDepth && grandparentID.Valid {
+		// Answer alongside the comment, at its level
+		parentID = int(grandparentID.Int64)
+	} else {
+		depth++
+	}
+
+	_, err = r.db.Exec("INSERT INTO comments (post_id, user_id, content, parent_id, depth) VALUES (?, ?, ?, ?, ?)", postID, userID, content, parentID, depth)
+	return postID, err
 }
 
-// GetCommentsByPostID retrieves all comments associated with a specific post from the database.
+// GetByPostID retrieves one page of the threads of comments on a specific post, along
+// with their authors and reaction counts, in a single query. The page is made of
+// comments on the post itself, each followed by its replies, depth first and oldest
+// first; the sort order and cursor only apply to the comments on the post. Comments
+// in the trash are only kept, blanked, to hold the place of replies that are not.
 // Parameters:
 //   - postID: The ID of the post for which comments are being fetched.
+//   - page: The sort order, cursor and size of the page; comments default to oldest first.
+//   - maxDepth: The deepest level reported; replies stored deeper, e.g. before the limit was lowered, are reported at it.
 //
 // Returns:
-//   - []Comment: A slice of Comment structs containing all comments for the specified post.
-//   - error: An error if the operation fails; otherwise, nil.
-func GetCommentsByPostID(postID int) ([]Comment, error) {
-	// Query the database for all comments associated with the given post ID.
-	rows, err := db.Query("SELECT id, post_id, user_id, content, created_at FROM comments WHERE post_id = ?", postID)
+//   - CommentPage: The comments of the page and the cursor of the next one.
+//   - error: ErrInvalidSort or ErrInvalidCursor for a bad page request, or any query error; otherwise, nil.
+func (r *commentRepository) GetByPostID(postID int, page PageRequest, maxDepth int) (CommentPage, error) {
+	pageQuery, err := resolvePage(page, commentSorts, SortOldest)
 	if err != nil {
-		return nil, err
+		return CommentPage{}, err
+	}
+
+	// The page of comments on the post, and every reply below them. The comments
+	// shown are those outside the trash and the ones above them in their thread.
+	roots, args := pageQuery.paginate("SELECT * FROM post_comments WHERE parent_id IS NULL", []interface{}{postID, postID})
+	query := `
+        WITH RECURSIVE shown (id, parent_id) AS (
+            SELECT id, parent_id FROM comments WHERE post_id = ? AND deleted_at IS NULL
+            UNION
+            SELECT c.id, c.parent_id FROM comments c INNER JOIN shown ON c.id = shown.parent_id
+        ),
+        post_comments AS (
+            SELECT c.id, c.post_id, c.parent_id, c.depth, c.user_id, u.username, c.content, c.created_at, c.edited_at, c.deleted_at,
+                   (SELECT COUNT(*) FROM reactions r WHERE r.target_type = 'comment' AND r.target_id = c.id AND r.reaction = 'like' AND c.deleted_at IS NULL) AS likes,
+                   (SELECT COUNT(*) FROM reactions r WHERE r.target_type = 'comment' AND r.target_id = c.id AND r.reaction = 'dislike' AND c.deleted_at IS NULL) AS dislikes
+            FROM comments c
+            INNER JOIN users u ON u.id = c.user_id
+            WHERE c.post_id = ? AND c.id IN (SELECT id FROM shown)
+        ),
+        roots AS (` + roots + `),
+        thread (id, root_id) AS (
+            SELECT id, id FROM roots
+            UNION ALL
+            SELECT pc.id, thread.root_id FROM post_comments pc INNER JOIN thread ON pc.parent_id = thread.id
+        )
+        SELECT pc.id, pc.post_id, pc.parent_id, pc.depth, pc.user_id, pc.username, pc.content, pc.created_at, pc.edited_at, pc.deleted_at, pc.likes, pc.dislikes
+        FROM thread
+        INNER JOIN roots ON roots.id = thread.root_id
+        INNER JOIN post_comments pc ON pc.id = thread.id
+        ORDER BY ` + pageQuery.sortColumns("roots.") + `, pc.id`
+
+	// Query the database for the page of comments associated with the given post ID.
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return CommentPage{}, err
 	}
 	defer rows.Close() // Ensure rows are closed when done to avoid resource leaks.
 
-	var comments []Comment
+	// Rows come thread by thread, in page order, with the replies of each thread oldest first
+	var threads []Comment
+	replies := map[int][]Comment{}
 	for rows.Next() {
 		var comment Comment
+		var parentID sql.NullInt64
+		var editedAt, deletedAt sql.NullTime
 		// Scan the row into the Comment struct fields.
-		err := rows.Scan(&comment.ID, &comment.PostID, &comment.UserID, &comment.Content, &comment.CreatedAt)
+		err := rows.Scan(&comment.ID, &comment.PostID, &parentID, &comment.Depth, &comment.UserID, &comment.Username, &comment.Content, &comment.CreatedAt, &editedAt, &deletedAt, &comment.Likes, &comment.Dislikes)
 		if err != nil {
-			return nil, err
+			return CommentPage{}, err
+		}
+		if editedAt.Valid {
+			comment.EditedAt = &editedAt.Time
 		}
+		if deletedAt.Valid {
+			// Only the place of a removed comment in its thread is kept
+			comment.Deleted = true
+			comment.UserID, comment.Username, comment.Content, comment.EditedAt = 0, "", "", nil
+		}
+		if comment.Depth > maxDepth {
+			comment.Depth = maxDepth
+		}
+		if parentID.Valid {
+			comment.ParentID = int(parentID.Int64)
+			replies[comment.ParentID] = append(replies[comment.ParentID], comment)
+		} else {
+			threads = append(threads, comment)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return CommentPage{}, err
+	}
 
-		// Fetch the Username associated with the comment's user ID.
-		user, err := GetUser(comment.UserID)
-		if err != nil {
-			return nil, err
+	// The extra thread only tells that another page follows
+	result := CommentPage{Comments: []Comment{}}
+	if len(threads) > pageQuery.limit {
+		threads = threads[:pageQuery.limit]
+		last := threads[pageQuery.limit-1]
+		result.Next = pageQuery.nextCursor(last.ID, last.Likes)
+	}
+
+	// Lay every thread out depth first, each comment followed by its replies
+	var appendThread func(comment Comment)
+	appendThread = func(comment Comment) {
+		result.Comments = append(result.Comments, comment)
+		for _, reply := range replies[comment.ID] {
+			appendThread(reply)
 		}
-		comment.Username = user.Username
+	}
+	for _, comment := range threads {
+		appendThread(comment)
+	}
+
+	return result, nil
+}
+
+// GetByUser retrieves one page of the comments a user wrote on published forum
+// posts, with the title of their post and their like and dislike counts. Comments
+// in the trash, or on posts in the trash or in clubs, are left out.
+// Parameters:
+//   - userID: The ID of the author.
+//   - page: The sort order, cursor and size of the page; comments default to newest first.
+//
+// Returns:
+//   - CommentPage: The comments of the page and the cursor of the next one.
+//   - error: ErrInvalidSort or ErrInvalidCursor for a bad page request, or any query error; otherwise, nil.
+func (r *commentRepository) GetByUser(userID int, page PageRequest) (CommentPage, error) {
+	pageQuery, err := resolvePage(page, commentSorts, SortNewest)
+	if err != nil {
+		return CommentPage{}, err
+	}
+
+	query, args := pageQuery.paginate(`
+        SELECT c.id, c.post_id, p.title, c.parent_id, c.depth, c.user_id, u.username, c.content, c.created_at, c.edited_at,
+               (SELECT COUNT(*) FROM reactions r WHERE r.target_type = 'comment' AND r.target_id = c.id AND r.reaction = 'like') AS likes,
+               (SELECT COUNT(*) FROM reactions r WHERE r.target_type = 'comment' AND r.target_id = c.id AND r.reaction = 'dislike') AS dislikes
+        FROM comments c
+        INNER JOIN posts p ON p.id = c.post_id AND p.deleted_at IS NULL AND p.status = 'published' AND `+forumPosts+`
+        INNER JOIN users u ON u.id = c.user_id
+        WHERE c.user_id = ? AND c.deleted_at IS NULL`, []interface{}{userID})
 
-		// Fetch the total number of likes for the comment.
-		likes, err := CountCommentLikes(comment.ID)
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return CommentPage{}, err
+	}
+	defer rows.Close()
+
+	comments := []Comment{}
+	for rows.Next() {
+		var comment Comment
+		var parentID sql.NullInt64
+		var editedAt sql.NullTime
+		err := rows.Scan(&comment.ID, &comment.PostID, &comment.PostTitle, &parentID, &comment.Depth, &comment.UserID, &comment.Username,
+			&comment.Content, &comment.CreatedAt, &editedAt, &comment.Likes, &comment.Dislikes)
 		if err != nil {
-			return nil, err
+			return CommentPage{}, err
+		}
+		comment.ParentID = int(parentID.Int64)
+		if editedAt.Valid {
+			comment.EditedAt = &editedAt.Time
 		}
-		comment.Likes = likes
+		comments = append(comments, comment)
+	}
+	if err := rows.Err(); err != nil {
+		return CommentPage{}, err
+	}
 
-		// Fetch the total number of dislikes for the comment.
-		dislikes, err := CountCommentDislikes(comment.ID)
-		if err != nil {
+	// The extra row only tells that another page follows
+	result := CommentPage{Comments: comments}
+	if len(comments) > pageQuery.limit {
+		result.Comments = comments[:pageQuery.limit]
+		last := result.Comments[pageQuery.limit-1]
+		result.Next = pageQuery.nextCursor(last.ID, last.Likes)
+	}
+	return result, nil
+}
+
+// GetByID retrieves a comment, without its reaction counts.
+// Parameters:
+//   - commentID: The ID of the comment.
+//
+// Returns:
+//   - Comment: The comment and its author.
+//   - error: ErrNotFound if the comment does not exist, or it or its post is in the trash, or any other query error; otherwise, nil.
+func (r *commentRepository) GetByID(commentID int) (Comment, error) {
+	var comment Comment
+	var parentID sql.NullInt64
+	var editedAt sql.NullTime
+	err := r.db.QueryRow(`
+        SELECT c.id, c.post_id, c.parent_id, c.depth, c.user_id, u.username, c.content, c.created_at, c.edited_at
+        FROM comments c
+        INNER JOIN posts p ON p.id = c.post_id AND p.deleted_at IS NULL AND p.status = 'published'
+        INNER JOIN users u ON u.id = c.user_id
+        WHERE c.id = ? AND c.deleted_at IS NULL`, commentID).
+		Scan(&comment.ID, &comment.PostID, &parentID, &comment.Depth, &comment.UserID, &comment.Username, &comment.Content, &comment.CreatedAt, &editedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Comment{}, ErrNotFound
+	} else if err != nil {
+		return Comment{}, err
+	}
+	comment.ParentID = int(parentID.Int64)
+	if editedAt.Valid {
+		comment.EditedAt = &editedAt.Time
+	}
+	return comment, nil
+}
+
+// Update replaces the content of a comment and records when it was edited.
+// Parameters:
+//   - commentID: The ID of the comment.
+//   - content: The new text content of the comment.
+//
+// Returns:
+//   - error: ErrNotFound if the comment does not exist or was removed, or any other error if the operation fails; otherwise, nil.
+func (r *commentRepository) Update(commentID int, content string) error {
+	result, err := r.db.Exec("UPDATE comments SET content = ?, edited_at = CURRENT_TIMESTAMP WHERE id = ? AND deleted_at IS NULL", content, commentID)
+	if err != nil {
+		return err
+	}
+	return requireAffected(result)
+}
+
+// Delete moves a comment to the trash. Its replies stay, below a placeholder
+// holding its place in the thread; the comment can be restored until it is purged.
+// Parameters:
+//   - commentID: The ID of the comment.
+//
+// Returns:
+//   - error: ErrNotFound if the comment does not exist or is already in the trash, or any other error if the operation fails; otherwise, nil.
+func (r *commentRepository) Delete(commentID int) error {
+	result, err := r.db.Exec("UPDATE comments SET deleted_at = CURRENT_TIMESTAMP WHERE id = ? AND deleted_at IS NULL", commentID)
+	if err != nil {
+		return err
+	}
+	return requireAffected(result)
+}
+
+// Restore takes a comment out of the trash, with its reactions.
+// Parameters:
+//   - commentID: The ID of the comment.
+//   - since: The start of the retention window; comments deleted before it can no longer be restored.
+//
+// Returns:
+//   - error: ErrNotFound if the comment is not in the trash, was deleted before since, or its post
+//     is in the trash, or any other error if the operation fails; otherwise, nil.
+func (r *commentRepository) Restore(commentID int, since time.Time) error {
+	// Comments removed before the trash existed lost their content and stay removed
+	result, err := r.db.Exec(`
+        UPDATE comments SET deleted_at = NULL
+        WHERE id = ? AND deleted_at >= ? AND content <> ''
+          AND EXISTS (SELECT 1 FROM posts p WHERE p.id = comments.post_id AND p.deleted_at IS NULL AND p.status = 'published')`, commentID, since.UTC())
+	if err != nil {
+		return err
+	}
+	return requireAffected(result)
+}
+
+// Trashed lists the comments of a user that are in the trash and can still be
+// restored, the most recently deleted first. Comments on posts in the trash are
+// left out, as they come back with their post.
+// Parameters:
+//   - userID: The ID of the author.
+//   - since: The start of the retention window.
+//
+// Returns:
+//   - []Comment: The deleted comments, with the title of their post and the time they were deleted.
+//   - error: An error if the query fails; otherwise, nil.
+func (r *commentRepository) Trashed(userID int, since time.Time) ([]Comment, error) {
+	rows, err := r.db.Query(`
+        SELECT c.id, c.post_id, p.title, c.parent_id, c.depth, c.user_id, u.username, c.content, c.created_at, c.deleted_at
+        FROM comments c
+        INNER JOIN posts p ON p.id = c.post_id AND p.deleted_at IS NULL AND p.status = 'published'
+        INNER JOIN users u ON u.id = c.user_id
+        WHERE c.user_id = ? AND c.deleted_at >= ? AND c.content <> ''
+        ORDER BY c.deleted_at DESC, c.id DESC`, userID, since.UTC())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	comments := []Comment{}
+	for rows.Next() {
+		var comment Comment
+		var parentID sql.NullInt64
+		var deletedAt time.Time
+		if err := rows.Scan(&comment.ID, &comment.PostID, &comment.PostTitle, &parentID, &comment.Depth, &comment.UserID, &comment.Username, &comment.Content, &comment.CreatedAt, &deletedAt); err != nil {
 			return nil, err
 		}
-		comment.Dislikes = dislikes
-
-		// Append the fully populated Comment struct to the slice.
+		comment.ParentID = int(parentID.Int64)
+		comment.Deleted, comment.DeletedAt = true, &deletedAt
 		comments = append(comments, comment)
 	}
+	return comments, rows.Err()
+}
+
+// Purge permanently deletes the comments that were moved to the trash before the
+// given time, along with the reactions to them. Comments that still have replies
+// are kept as placeholders; they go once their last reply does.
+// Parameters:
+//   - before: The end of the retention of the comments to purge.
+//
+// Returns:
+//   - int: The number of comments purged.
+//   - error: An error if the deletion fails; otherwise, nil.
+func (r *commentRepository) Purge(before time.Time) (int, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback() // No-op once the transaction is committed.
+
+	// Purge from the bottom of the threads up, until no expired comment is left without replies
+	expired := `
+        SELECT c.id FROM comments c
+        WHERE c.deleted_at < ? AND NOT EXISTS (SELECT 1 FROM comments r WHERE r.parent_id = c.id)`
+	purged := 0
+	for {
+		if _, err := tx.Exec("DELETE FROM reactions WHERE target_type = ? AND target_id IN ("+expired+")", ReactionTargetComment, before.UTC()); err != nil {
+			return 0, err
+		}
+		result, err := tx.Exec("DELETE FROM comments WHERE id IN ("+expired+")", before.UTC())
+		if err != nil {
+			return 0, err
+		}
+		deleted, err := result.RowsAffected()
+		if err != nil {
+			return 0, err
+		}
+		if deleted == 0 {
+			break
+		}
+		purged += int(deleted)
+	}
 
-	// Return the slice of comments and a nil error indicating success.
-	return comments, nil
+	return purged, tx.Commit()
 }
@@ -0,0 +1,223 @@
+package models
+
+import (
+	"database/sql"
+	"literary-lions/backend/src/internal/db"
+	"time"
+)
+
+// Statuses of a post. Only published posts show on the forum; drafts and
+// scheduled posts are seen by their authors alone.
+const (
+	PostDraft     = "draft"
+	PostScheduled = "scheduled"
+	PostPublished = "published"
+)
+
+// draftStatus returns the status of a draft that goes live at publishAt, or
+// that waits for its author when publishAt is nil.
+func draftStatus(publishAt *time.Time) (string, interface{}) {
+	if publishAt == nil {
+		return PostDraft, nil
+	}
+	return PostScheduled, publishAt.UTC()
+}
+
+// SaveDraft stores a new post without publishing it. It is published by its
+// author, or at publishAt if set, and has no revisions until then.
+// Parameters:
+//   - userID: The ID of the author.
+//   - title: The title of the post.
+//   - content: The content of the post.
+//   - categoryID: The ID of the category the post is filed under.
+//   - tags: The tags of the post, as typed.
+//   - publishAt: The time to publish the post at, nil to keep it as a draft.
+//
+// Returns:
+//   - int: The ID of the draft.
+//   - error: ErrInvalidTag or ErrTooManyTags for bad tags, or an error if the operation fails; otherwise, nil.
+func (r *postRepository) SaveDraft(userID int, title, content string, categoryID int, tags []string, publishAt *time.Time) (int, error) {
+	slugs, err := NormalizeTags(tags)
+	if err != nil {
+		return 0, err
+	}
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback() // No-op once the transaction is committed.
+
+	status, at := draftStatus(publishAt)
+	var postID int
+	err = tx.QueryRow("INSERT INTO posts (user_id, title, content, category_id, status, publish_at) VALUES (?, ?, ?, ?, ?, ?) RETURNING id", userID, title, content, categoryID, status, at).Scan(&postID)
+	if err != nil {
+		return 0, err
+	}
+	if err := setPostTags(tx, postID, slugs); err != nil {
+		return 0, err
+	}
+	return postID, tx.Commit()
+}
+
+// Drafts lists the drafts and scheduled posts of a user that are not in the
+// trash, the most recently started first.
+// Parameters:
+//   - userID: The ID of the author.
+//
+// Returns:
+//   - []Post: The unpublished posts.
+//   - error: An error if the query fails; otherwise, nil.
+func (r *postRepository) Drafts(userID int) ([]Post, error) {
+	rows, err := r.db.Query(postSelect+" WHERE p.user_id = ? AND p.status <> 'published' AND p.deleted_at IS NULL ORDER BY p.id DESC", userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	posts := []Post{}
+	for rows.Next() {
+		post, err := scanPost(rows)
+		if err != nil {
+			return nil, err
+		}
+		posts = append(posts, post)
+	}
+	return posts, rows.Err()
+}
+
+// GetDraft retrieves a draft or scheduled post by its ID.
+// Parameters:
+//   - postID: The ID of the draft.
+//
+// Returns:
+//   - Post: The draft.
+//   - error: ErrNotFound if no unpublished post outside the trash has the ID, or any other error; otherwise, nil.
+func (r *postRepository) GetDraft(postID int) (Post, error) {
+	post, err := scanPost(r.db.QueryRow(postSelect+" WHERE p.id = ? AND p.status <> 'published' AND p.deleted_at IS NULL", postID))
+	if err == sql.ErrNoRows {
+		return Post{}, ErrNotFound
+	}
+	return post, err
+}
+
+// UpdateDraft overwrites a draft or scheduled post. Setting publishAt schedules
+// it, and clearing it takes it back to a draft.
+// Parameters:
+//   - postID: The ID of the draft.
+//   - title: The new title of the post.
+//   - content: The new content of the post.
+//   - categoryID: The ID of the new category of the post.
+//   - tags: The new tags of the post, as typed; they replace the current ones.
+//   - publishAt: The time to publish the post at, nil to keep it as a draft.
+//
+// Returns:
+//   - error: ErrInvalidTag or ErrTooManyTags for bad tags, ErrNotFound if no unpublished
+//     post outside the trash has the ID, or any other error; otherwise, nil.
+func (r *postRepository) UpdateDraft(postID int, title, content string, categoryID int, tags []string, publishAt *time.Time) error {
+	slugs, err := NormalizeTags(tags)
+	if err != nil {
+		return err
+	}
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback() // No-op once the transaction is committed.
+
+	status, at := draftStatus(publishAt)
+	result, err := tx.Exec("UPDATE posts SET title = ?, content = ?, category_id = ?, status = ?, publish_at = ? WHERE id = ? AND status <> 'published' AND deleted_at IS NULL", title, content, categoryID, status, at, postID)
+	if err != nil {
+		return err
+	}
+	if err := requireAffected(result); err != nil {
+		return err
+	}
+	if err := setPostTags(tx, postID, slugs); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// Publish puts a draft or scheduled post live right away.
+// Parameters:
+//   - postID: The ID of the draft.
+//
+// Returns:
+//   - int: The ID of the published post, the same as the draft's.
+//   - error: ErrNotFound if no unpublished post outside the trash has the ID, or any other error; otherwise, nil.
+func (r *postRepository) Publish(postID int) (int, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback() // No-op once the transaction is committed.
+
+	if err := publishDraft(tx, postID, time.Now()); err != nil {
+		return 0, err
+	}
+	return postID, tx.Commit()
+}
+
+// PublishDue puts live the scheduled posts whose time has come, each dated
+// from the time it was scheduled for.
+// Parameters:
+//   - now: The current time.
+//
+// Returns:
+//   - int: The number of posts published.
+//   - error: An error if publishing fails; otherwise, nil.
+func (r *postRepository) PublishDue(now time.Time) (int, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback() // No-op once the transaction is committed.
+
+	type due struct {
+		id        int
+		publishAt time.Time
+	}
+	rows, err := tx.Query("SELECT id, publish_at FROM posts WHERE status = 'scheduled' AND publish_at <= ? AND deleted_at IS NULL ORDER BY publish_at, id", now.UTC())
+	if err != nil {
+		return 0, err
+	}
+	var posts []due
+	for rows.Next() {
+		var post due
+		if err := rows.Scan(&post.id, &post.publishAt); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		posts = append(posts, post)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	for _, post := range posts {
+		if err := publishDraft(tx, post.id, post.publishAt); err != nil {
+			return 0, err
+		}
+	}
+	return len(posts), tx.Commit()
+}
+
+// publishDraft puts an unpublished post live under its own ID, dated publishedAt,
+// and records it as its first revision. Everything attached to the draft, such as
+// its tags, images and books, stays with it.
+func publishDraft(tx *db.Tx, postID int, publishedAt time.Time) error {
+	var userID int
+	err := tx.QueryRow(`
+        UPDATE posts SET status = 'published', publish_at = NULL, created_at = ?
+        WHERE id = ? AND status <> 'published' AND deleted_at IS NULL
+        RETURNING user_id`, publishedAt.UTC(), postID).Scan(&userID)
+	if err == sql.ErrNoRows {
+		return ErrNotFound
+	} else if err != nil {
+		return err
+	}
+	return addRevision(tx, postID, userID, 0)
+}
@@ -1,167 +1,239 @@
 package models
 
-import "database/sql"
-
-type PostLike struct {
-	ID        int  `json:"id"`
-	PostID    int  `json:"post_id"`
-	CommentID int  `json:"comment_id"`
-	UserID    int  `json:"user_id"`
-	IsLike    bool `json:"is_like"`
+import (
+	"database/sql"
+	"fmt"
+	"literary-lions/backend/src/internal/db"
+	"regexp"
+	"strings"
+)
+
+// Kinds of records a reaction can be attached to.
+const (
+	ReactionTargetPost    = "post"
+	ReactionTargetComment = "comment"
+)
+
+// Reactions a user can give to a post or comment.
+const (
+	ReactionLike    = "like"
+	ReactionDislike = "dislike"
+)
+
+// DefaultReactions is the reaction set offered when none is configured.
+var DefaultReactions = []string{"insightful", "funny", "spoiler", "agree"}
+
+// reactionName matches the names allowed in a reaction set.
+var reactionName = regexp.MustCompile(`^[a-z][a-z0-9_]{0,31}$`)
+
+// reactionTargets maps each target type to the query counting its records with
+// an ID that can be reacted to. Comments only exist on published posts, but stay
+// outside the trash when their post is moved to it.
+var reactionTargets = map[string]string{
+	ReactionTargetPost:    "SELECT COUNT(*) FROM posts WHERE id = ? AND deleted_at IS NULL AND status = 'published'",
+	ReactionTargetComment: "SELECT COUNT(*) FROM comments c JOIN posts p ON p.id = c.post_id WHERE c.id = ? AND c.deleted_at IS NULL AND p.deleted_at IS NULL",
 }
 
-type PostDislike struct {
-	ID        	int   `json:"id"`
-	PostID    	int   `json:"post_id"`
-	CommentID 	int   `json:"comment_id"`
-	UserID    	int   `json:"user_id"`
-	IsDislike   bool  `json:"is_dislike"`
-}
+// checkReactionTarget makes sure a post or comment can be reacted to, through
+// queryRow of the database or of a transaction. Reactions have no foreign key to
+// check their target.
+// Parameters:
+//   - queryRow: The QueryRow method to run the check with.
+//   - targetType: ReactionTargetPost or ReactionTargetComment.
+//   - targetID: The ID of the post or comment.
+//
+// Returns:
+//   - error: ErrNotFound if the post or comment does not exist, is a draft or is in the trash, or any query error; otherwise, nil.
+func checkReactionTarget(queryRow func(string, ...interface{}) *sql.Row, targetType string, targetID int) error {
+	query, ok := reactionTargets[targetType]
+	if !ok {
+		return fmt.Errorf("unknown reaction target %q", targetType)
+	}
 
-type CommentLike struct {
-	ID        int  `json:"id"`
-	PostID    int  `json:"post_id"`
-	CommentID int  `json:"comment_id"`
-	UserID    int  `json:"user_id"`
-	IsLike    bool `json:"is_like"`
+	// Only react to published records outside the trash
+	var exists int
+	if err := queryRow(query, targetID).Scan(&exists); err != nil {
+		return err
+	}
+	if exists == 0 {
+		return ErrNotFound
+	}
+	return nil
 }
 
-type CommentDislike struct {
-	ID        int  `json:"id"`
-	PostID    int  `json:"post_id"`
-	CommentID int  `json:"comment_id"`
-	UserID    int  `json:"user_id"`
-	IsDislike bool `json:"is_dislike"`
-}
+// ReactionSet lists the reactions users can add to posts and comments on top of
+// likes and dislikes, in display order. A user may give any number of them to the
+// same post or comment, but each one only once.
+type ReactionSet []string
 
-// PostLikeAndUnlike adds or updates a like for a post. 
-// If the user has already liked the post, it will remove the like.
-// If the user has disliked the post, it will remove the dislike and add a like instead.
+// NewReactionSet validates the configured reaction names.
 // Parameters:
-//   - userID: The ID of the user performing the action.
-//   - postID: The ID of the post to like or unlike.
+//   - names: The reaction names, DefaultReactions if empty.
 //
 // Returns:
-//   - error: An error if the operation fails; otherwise, nil.
-func PostLikeAndUnlike(userID, postID int) error {
-	// Check if the user has already liked the post.
-	var like PostLike
-	row := db.QueryRow("SELECT id, user_id, post_id, is_like FROM post_likes WHERE post_id = ? AND user_id = ?", postID, userID)
+//   - ReactionSet: The validated reaction set.
+//   - error: An error naming the first invalid, reserved or repeated name; otherwise, nil.
+func NewReactionSet(names []string) (ReactionSet, error) {
+	if len(names) == 0 {
+		names = DefaultReactions
+	}
 
-	err := row.Scan(&like.ID, &like.UserID, &like.PostID, &like.IsLike)
-	if err != nil {
-		if err == sql.ErrNoRows {
-			// Insert a new like if no existing like is found.
-			_, err = db.Exec("INSERT INTO post_likes (user_id, post_id, is_like) VALUES (?, ?, ?)", userID, postID, true)
-			RemoveDislikeFromPost(userID, postID) // Remove any existing dislike
-			return err
+	seen := make(map[string]bool, len(names))
+	for _, name := range names {
+		switch {
+		case name == ReactionLike || name == ReactionDislike:
+			return nil, fmt.Errorf("reaction %q is reserved", name)
+		case !reactionName.MatchString(name):
+			return nil, fmt.Errorf("invalid reaction name %q: use up to 32 lowercase letters, digits and underscores", name)
+		case seen[name]:
+			return nil, fmt.Errorf("reaction %q is listed twice", name)
 		}
-		return err
+		seen[name] = true
 	}
+	return ReactionSet(names), nil
+}
 
-	// If the post is already liked, remove the like; otherwise, add a like.
-	if like.IsLike {
-		_, err := db.Exec("UPDATE post_likes SET is_like = ? WHERE post_id = ? AND user_id = ?", false, postID, userID)
-		return err
-	} else {
-		_, err := db.Exec("UPDATE post_likes SET is_like = ? WHERE post_id = ? AND user_id = ?", true, postID, userID)
-		RemoveDislikeFromPost(userID, postID) // Remove any existing dislike
-		return err
+// Contains reports whether the set offers the named reaction.
+func (s ReactionSet) Contains(name string) bool {
+	for _, reaction := range s {
+		if reaction == name {
+			return true
+		}
 	}
+	return false
 }
 
-// PostDisLikeAndUndislike adds or updates a dislike for a post. 
-// If the user has already disliked the post, it will remove the dislike.
-// If the user has liked the post, it will remove the like and add a dislike instead.
+// ReactionCount is the number of times a post or comment received one reaction.
+type ReactionCount struct {
+	Reaction string `json:"reaction"`
+	Count    int    `json:"count"`
+	Reacted  bool   `json:"reacted"` // Whether the requesting user gave this reaction
+}
+
+// reactionRepository implements ReactionRepository on top of a SQL database.
+type reactionRepository struct {
+	db *db.DB
+}
+
+// toggle gives or takes back a user's like or dislike on a post or comment.
+// A user holds at most one of the two per target: giving the one they already
+// hold removes it, and giving the other one replaces it. Both steps run in one
+// transaction, and the unique index on the likes and dislikes of a user and target
+// keeps concurrent toggles from leaving both behind.
 // Parameters:
 //   - userID: The ID of the user performing the action.
-//   - postID: The ID of the post to dislike or undislike.
+//   - targetType: ReactionTargetPost or ReactionTargetComment.
+//   - targetID: The ID of the post or comment reacted to.
+//   - reaction: ReactionLike or ReactionDislike.
 //
 // Returns:
-//   - error: An error if the operation fails; otherwise, nil.
-func PostDisLikeAndUndislike(userID, postID int) error {
-	// Check if the user has already disliked the post.
-	var dislike PostDislike
-	row := db.QueryRow("SELECT id, user_id, post_id, is_dislike FROM post_dislikes WHERE post_id = ? AND user_id = ?", postID, userID)
+//   - error: ErrNotFound if the post or comment does not exist, is a draft or is in the trash, or any query error; otherwise, nil.
+func (r *reactionRepository) toggle(userID int, targetType string, targetID int, reaction string) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback() // No-op once the transaction is committed.
 
-	err := row.Scan(&dislike.ID, &dislike.UserID, &dislike.PostID, &dislike.IsDislike)
+	// Take the reaction back if the user already gave it.
+	result, err := tx.Exec("DELETE FROM reactions WHERE user_id = ? AND target_type = ? AND target_id = ? AND reaction = ?",
+		userID, targetType, targetID, reaction)
 	if err != nil {
-		if err == sql.ErrNoRows {
-			// Insert a new dislike if no existing dislike is found.
-			_, err = db.Exec("INSERT INTO post_dislikes (user_id, post_id, is_dislike) VALUES (?, ?, ?)", userID, postID, true)
-			RemoveLikeFromPost(userID, postID) // Remove any existing like
-			return err
-		}
 		return err
 	}
 
-	// If the post is already disliked, remove the dislike; otherwise, add a dislike.
-	if dislike.IsDislike {
-		_, err := db.Exec("UPDATE post_dislikes SET is_dislike = ? WHERE post_id = ? AND user_id = ?", false, postID, userID)
+	// Check the target only now: on SQLite, the write above takes the lock that keeps
+	// concurrent toggles from failing with "database is locked". A missing target
+	// rolls the transaction back, so nothing is changed.
+	if err := checkReactionTarget(tx.QueryRow, targetType, targetID); err != nil {
 		return err
-	} else {
-		_, err := db.Exec("UPDATE post_dislikes SET is_dislike = ? WHERE post_id = ? AND user_id = ?", true, postID, userID)
-		RemoveLikeFromPost(userID, postID) // Remove any existing like
+	}
+	removed, err := result.RowsAffected()
+	if err != nil {
 		return err
 	}
+
+	// Otherwise add it, replacing the like or dislike the user gave to the target.
+	if removed == 0 {
+		_, err = tx.Exec(`
+        INSERT INTO reactions (user_id, target_type, target_id, reaction) VALUES (?, ?, ?, ?)
+        ON CONFLICT (user_id, target_type, target_id) WHERE reaction IN ('like', 'dislike') DO UPDATE
+        SET reaction = excluded.reaction, created_at = CURRENT_TIMESTAMP`,
+			userID, targetType, targetID, reaction)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
 }
 
-// RemoveDislikeFromPost removes a dislike for a post.
+// count returns the number of times a post or comment received a reaction.
 // Parameters:
-//   - userID: The ID of the user performing the action.
-//   - postID: The ID of the post to remove dislike from.
+//   - targetType: ReactionTargetPost or ReactionTargetComment.
+//   - targetID: The ID of the post or comment.
+//   - reaction: The reaction to count.
 //
 // Returns:
+//   - int: The number of matching reactions.
 //   - error: An error if the operation fails; otherwise, nil.
-func RemoveDislikeFromPost(userID, postID int) error {
-	// Check if the user has disliked the post.
-	var dislike PostDislike
-	row := db.QueryRow("SELECT id, user_id, post_id, is_dislike FROM post_dislikes WHERE post_id = ? AND user_id = ?", postID, userID)
-
-	err := row.Scan(&dislike.ID, &dislike.UserID, &dislike.PostID, &dislike.IsDislike)
-	if err != nil {
-		if err == sql.ErrNoRows {
-			// No need to remove dislike if it doesn't exist.
-			return err
-		}
-		return err
-	}
+func (r *reactionRepository) count(targetType string, targetID int, reaction string) (int, error) {
+	var count int
+	query := "SELECT COUNT(*) FROM reactions WHERE target_type = ? AND target_id = ? AND reaction = ?"
+	err := r.db.QueryRow(query, targetType, targetID, reaction).Scan(&count)
+	return count, err
+}
 
-	// If the post is already disliked, update to remove the dislike.
-	if dislike.IsDislike {
-		_, err := db.Exec("UPDATE post_dislikes SET is_dislike = ? WHERE post_id = ? AND user_id = ?", false, postID, userID)
-		return err
-	}
-	return err
+// TogglePostLike adds or removes a like for a post.
+// If the user has already liked the post, it will remove the like.
+// If the user has disliked the post, it will replace the dislike with a like.
+// Parameters:
+//   - userID: The ID of the user performing the action.
+//   - postID: The ID of the post to like or unlike.
+//
+// Returns:
+//   - error: ErrNotFound if the post does not exist, is a draft or is in the trash, or any other error; otherwise, nil.
+func (r *reactionRepository) TogglePostLike(userID, postID int) error {
+	return r.toggle(userID, ReactionTargetPost, postID, ReactionLike)
 }
 
-// RemoveLikeFromPost removes a like for a post.
+// TogglePostDislike adds or removes a dislike for a post.
+// If the user has already disliked the post, it will remove the dislike.
+// If the user has liked the post, it will replace the like with a dislike.
 // Parameters:
 //   - userID: The ID of the user performing the action.
-//   - postID: The ID of the post to remove like from.
+//   - postID: The ID of the post to dislike or undislike.
 //
 // Returns:
-//   - error: An error if the operation fails; otherwise, nil.
-func RemoveLikeFromPost(userID, postID int) error {
-	// Check if the user has liked the post.
-	var like PostLike
-	row := db.QueryRow("SELECT id, user_id, post_id, is_like FROM post_likes WHERE post_id = ? AND user_id = ?", postID, userID)
+//   - error: ErrNotFound if the post does not exist, is a draft or is in the trash, or any other error; otherwise, nil.
+func (r *reactionRepository) TogglePostDislike(userID, postID int) error {
+	return r.toggle(userID, ReactionTargetPost, postID, ReactionDislike)
+}
 
-	err := row.Scan(&like.ID, &like.UserID, &like.PostID, &like.IsLike)
-	if err != nil {
-		if err == sql.ErrNoRows {
-			// No need to remove like if it doesn't exist.
-			return err
-		}
-		return err
-	}
+// ToggleCommentLike adds or removes a like for a comment.
+// If the user has already liked the comment, it will remove the like.
+// If the user has disliked the comment, it will replace the dislike with a like.
+// Parameters:
+//   - userID: The ID of the user performing the action.
+//   - commentID: The ID of the comment to like or unlike.
+//
+// Returns:
+//   - error: ErrNotFound if the comment or its post does not exist or is in the trash, or any other error; otherwise, nil.
+func (r *reactionRepository) ToggleCommentLike(userID, commentID int) error {
+	return r.toggle(userID, ReactionTargetComment, commentID, ReactionLike)
+}
 
-	// If the post is already liked, update to remove the like.
-	if like.IsLike {
-		_, err := db.Exec("UPDATE post_likes SET is_like = ? WHERE post_id = ? AND user_id = ?", false, postID, userID)
-		return err
-	}
-	return err
+// ToggleCommentDislike adds or removes a dislike for a comment.
+// If the user has already disliked the comment, it will remove the dislike.
+// If the user has liked the comment, it will replace the like with a dislike.
+// Parameters:
+//   - userID: The ID of the user performing the action.
+//   - commentID: The ID of the comment to dislike or undislike.
+//
+// Returns:
+//   - error: ErrNotFound if the comment or its post does not exist or is in the trash, or any other error; otherwise, nil.
+func (r *reactionRepository) ToggleCommentDislike(userID, commentID int) error {
+	return r.toggle(userID, ReactionTargetComment, commentID, ReactionDislike)
 }
 
 // CountPostLikes returns the total number of likes for a specific post.
@@ -171,11 +243,8 @@ func RemoveLikeFromPost(userID, postID int) error {
 // Returns:
 //   - int: The number of likes for the specified post.
 //   - error: An error if the operation fails; otherwise, nil.
-func CountPostLikes(postID int) (int, error) {
-	var count int
-	query := "SELECT COUNT(*) FROM post_likes WHERE post_id = ? AND is_like = 1"
-	err := db.QueryRow(query, postID).Scan(&count)
-	return count, err
+func (r *reactionRepository) CountPostLikes(postID int) (int, error) {
+	return r.count(ReactionTargetPost, postID, ReactionLike)
 }
 
 // CountPostDislikes returns the total number of dislikes for a specific post.
@@ -185,11 +254,8 @@ func CountPostLikes(postID int) (int, error) {
 // Returns:
 //   - int: The number of dislikes for the specified post.
 //   - error: An error if the operation fails; otherwise, nil.
-func CountPostDislikes(postID int) (int, error) {
-	var count int
-	query := "SELECT COUNT(*) FROM post_dislikes WHERE post_id = ? AND is_dislike = 1"
-	err := db.QueryRow(query, postID).Scan(&count)
-	return count, err
+func (r *reactionRepository) CountPostDislikes(postID int) (int, error) {
+	return r.count(ReactionTargetPost, postID, ReactionDislike)
 }
 
 // CountCommentLikes returns the total number of likes for a specific comment.
@@ -199,11 +265,8 @@ func CountPostDislikes(postID int) (int, error) {
 // Returns:
 //   - int: The number of likes for the specified comment.
 //   - error: An error if the operation fails; otherwise, nil.
-func CountCommentLikes(commentID int) (int, error) {
-	var count int
-	query := "SELECT COUNT(*) FROM comment_likes WHERE comment_id = ? AND is_like = 1"
-	err := db.QueryRow(query, commentID).Scan(&count)
-	return count, err
+func (r *reactionRepository) CountCommentLikes(commentID int) (int, error) {
+	return r.count(ReactionTargetComment, commentID, ReactionLike)
 }
 
 // CountCommentDislikes returns the total number of dislikes for a specific comment.
@@ -213,139 +276,120 @@ func CountCommentLikes(commentID int) (int, error) {
 // Returns:
 //   - int: The number of dislikes for the specified comment.
 //   - error: An error if the operation fails; otherwise, nil.
-func CountCommentDislikes(commentID int) (int, error) {
-	var count int
-	query := "SELECT COUNT(*) FROM comment_dislikes WHERE comment_id = ? AND is_dislike = 1"
-	err := db.QueryRow(query, commentID).Scan(&count)
-	return count, err
+func (r *reactionRepository) CountCommentDislikes(commentID int) (int, error) {
+	return r.count(ReactionTargetComment, commentID, ReactionDislike)
 }
 
-// CommentLikeAndUnlike adds or updates a like for a comment. 
-// If the user has already liked the comment, it will remove the like.
-// If the user has disliked the comment, it will remove the dislike and add a like instead.
+// AddReaction gives a reaction from the set to a post or comment. Adding a
+// reaction the user already gave has no effect.
 // Parameters:
 //   - userID: The ID of the user performing the action.
-//   - commentID: The ID of the comment to like or unlike.
+//   - targetType: ReactionTargetPost or ReactionTargetComment.
+//   - targetID: The ID of the post or comment reacted to.
+//   - reaction: The reaction to add.
 //
 // Returns:
-//   - error: An error if the operation fails; otherwise, nil.
-func CommentLikeAndUnlike(userID, commentID int) error {
-	// Check if the user has already liked the comment.
-	var like CommentLike
-	row := db.QueryRow("SELECT id, user_id, comment_id, is_like FROM comment_likes WHERE comment_id = ? AND user_id = ?", commentID, userID)
-
-	err := row.Scan(&like.ID, &like.UserID, &like.CommentID, &like.IsLike)
-	if err != nil {
-		if err == sql.ErrNoRows {
-			// Insert a new like if no existing like is found.
-			_, err = db.Exec("INSERT INTO comment_likes (user_id, comment_id, is_like) VALUES (?, ?, ?)", userID, commentID, true)
-			RemoveDislikeFromComment(userID, commentID) // Remove any existing dislike
-			return err
-		}
+//   - error: ErrNotFound if the post or comment does not exist, is a draft or is in the trash, or any query error; otherwise, nil.
+func (r *reactionRepository) AddReaction(userID int, targetType string, targetID int, reaction string) error {
+	if err := checkReactionTarget(r.db.QueryRow, targetType, targetID); err != nil {
 		return err
 	}
 
-	// If the comment is already liked, remove the like; otherwise, add a like.
-	if like.IsLike {
-		_, err := db.Exec("UPDATE comment_likes SET is_like = ? WHERE comment_id = ? AND user_id = ?", false, commentID, userID)
-		return err
-	} else {
-		_, err := db.Exec("UPDATE comment_likes SET is_like = ? WHERE comment_id = ? AND user_id = ?", true, commentID, userID)
-		RemoveDislikeFromComment(userID, commentID) // Remove any existing dislike
-		return err
-	}
+	_, err := r.db.Exec(`
+        INSERT INTO reactions (user_id, target_type, target_id, reaction) VALUES (?, ?, ?, ?)
+        ON CONFLICT (user_id, target_type, target_id, reaction) DO NOTHING`,
+		userID, targetType, targetID, reaction)
+	return err
 }
 
-// CommentDisLikeAndUndislike adds or updates a dislike for a comment. 
-// If the user has already disliked the comment, it will remove the dislike.
-// If the user has liked the comment, it will remove the like and add a dislike instead.
+// RemoveReaction takes back a reaction a user gave to a post or comment.
+// Removing a reaction the user did not give has no effect.
 // Parameters:
 //   - userID: The ID of the user performing the action.
-//   - commentID: The ID of the comment to dislike or undislike.
+//   - targetType: ReactionTargetPost or ReactionTargetComment.
+//   - targetID: The ID of the post or comment.
+//   - reaction: The reaction to remove.
 //
 // Returns:
 //   - error: An error if the operation fails; otherwise, nil.
-func CommentDisLikeAndUndislike(userID, commentID int) error {
-	// Check if the user has already disliked the comment.
-	var dislike CommentDislike
-	row := db.QueryRow("SELECT id, user_id, comment_id, is_dislike FROM comment_dislikes WHERE comment_id = ? AND user_id = ?", commentID, userID)
-
-	err := row.Scan(&dislike.ID, &dislike.UserID, &dislike.CommentID, &dislike.IsDislike)
-	if err != nil {
-		if err == sql.ErrNoRows {
-			// Insert a new dislike if no existing dislike is found.
-			_, err = db.Exec("INSERT INTO comment_dislikes (user_id, comment_id, is_dislike) VALUES (?, ?, ?)", userID, commentID, true)
-			RemoveLikeFromComment(userID, commentID) // Remove any existing like
-			return err
-		}
-		return err
-	}
-
-	// If the comment is already disliked, remove the dislike; otherwise, add a dislike.
-	if dislike.IsDislike {
-		_, err := db.Exec("UPDATE comment_dislikes SET is_dislike = ? WHERE comment_id = ? AND user_id = ?", false, commentID, userID)
-		return err
-	} else {
-		_, err := db.Exec("UPDATE comment_dislikes SET is_dislike = ? WHERE comment_id = ? AND user_id = ?", true, commentID, userID)
-		RemoveLikeFromComment(userID, commentID) // Remove any existing like
-		return err
-	}
+func (r *reactionRepository) RemoveReaction(userID int, targetType string, targetID int, reaction string) error {
+	_, err := r.db.Exec("DELETE FROM reactions WHERE user_id = ? AND target_type = ? AND target_id = ? AND reaction = ?",
+		userID, targetType, targetID, reaction)
+	return err
 }
 
-// RemoveDislikeFromComment removes a dislike for a comment.
+// CountReactions counts the reactions of a set on several posts or comments in one query.
 // Parameters:
-//   - userID: The ID of the user performing the action.
-//   - commentID: The ID of the comment to remove dislike from.
+//   - userID: The ID of the requesting user, whose own reactions are flagged; 0 for guests.
+//   - targetType: ReactionTargetPost or ReactionTargetComment.
+//   - targetIDs: The IDs of the posts or comments.
+//   - reactions: The reactions to count.
 //
 // Returns:
+//   - map[int][]ReactionCount: For every target ID, the count of each reaction in set order, zeros included.
 //   - error: An error if the operation fails; otherwise, nil.
-func RemoveDislikeFromComment(userID, commentID int) error {
-	// Check if the user has disliked the comment.
-	var dislike CommentDislike
-	row := db.QueryRow("SELECT id, user_id, comment_id, is_dislike FROM comment_dislikes WHERE comment_id = ? AND user_id = ?", commentID, userID)
-
-	err := row.Scan(&dislike.ID, &dislike.UserID, &dislike.CommentID, &dislike.IsDislike)
-	if err != nil {
-		if err == sql.ErrNoRows {
-			// No need to remove dislike if it doesn't exist.
-			return err
+func (r *reactionRepository) CountReactions(userID int, targetType string, targetIDs []int, reactions ReactionSet) (map[int][]ReactionCount, error) {
+	counts := make(map[int][]ReactionCount, len(targetIDs))
+	if len(targetIDs) == 0 || len(reactions) == 0 {
+		for _, id := range targetIDs {
+			counts[id] = []ReactionCount{}
 		}
-		return err
+		return counts, nil
 	}
 
-	// If the comment is already disliked, update to remove the dislike.
-	if dislike.IsDislike {
-		_, err := db.Exec("UPDATE comment_dislikes SET is_dislike = ? WHERE comment_id = ? AND user_id = ?", false, commentID, userID)
-		return err
+	args := []interface{}{userID, targetType}
+	for _, id := range targetIDs {
+		args = append(args, id)
 	}
-	return err
-}
-
-// RemoveLikeFromComment removes a like for a comment.
-// Parameters:
-//   - userID: The ID of the user performing the action.
-//   - commentID: The ID of the comment to remove like from.
-//
-// Returns:
-//   - error: An error if the operation fails; otherwise, nil.
-func RemoveLikeFromComment(userID, commentID int) error {
-	// Check if the user has liked the comment.
-	var like CommentLike
-	row := db.QueryRow("SELECT id, user_id, comment_id, is_like FROM comment_likes WHERE comment_id = ? AND user_id = ?", commentID, userID)
+	for _, reaction := range reactions {
+		args = append(args, reaction)
+	}
+	query := `
+        SELECT target_id, reaction, COUNT(*), SUM(CASE WHEN user_id = ? THEN 1 ELSE 0 END)
+        FROM reactions
+        WHERE target_type = ? AND target_id IN (` + placeholders(len(targetIDs)) + `) AND reaction IN (` + placeholders(len(reactions)) + `)
+        GROUP BY target_id, reaction`
 
-	err := row.Scan(&like.ID, &like.UserID, &like.CommentID, &like.IsLike)
+	rows, err := r.db.Query(query, args...)
 	if err != nil {
-		if err == sql.ErrNoRows {
-			// No need to remove like if it doesn't exist.
-			return err
+		return nil, err
+	}
+	defer rows.Close()
+
+	type key struct {
+		id       int
+		reaction string
+	}
+	found := make(map[key]ReactionCount)
+	for rows.Next() {
+		var id, mine int
+		var count ReactionCount
+		if err := rows.Scan(&id, &count.Reaction, &count.Count, &mine); err != nil {
+			return nil, err
 		}
-		return err
+		count.Reacted = mine > 0
+		found[key{id, count.Reaction}] = count
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
 	}
 
-	// If the comment is already liked, update to remove the like.
-	if like.IsLike {
-		_, err := db.Exec("UPDATE comment_likes SET is_like = ? WHERE comment_id = ? AND user_id = ?", false, commentID, userID)
-		return err
+	// List every reaction of the set for every target, in set order.
+	for _, id := range targetIDs {
+		list := make([]ReactionCount, 0, len(reactions))
+		for _, reaction := range reactions {
+			count, ok := found[key{id, reaction}]
+			if !ok {
+				count = ReactionCount{Reaction: reaction}
+			}
+			list = append(list, count)
+		}
+		counts[id] = list
 	}
-	return err
+	return counts, nil
+}
+
+// placeholders returns n comma-separated query placeholders.
+func placeholders(n int) string {
+	return strings.TrimSuffix(strings.Repeat("?, ", n), ", ")
 }
@@ -0,0 +1,217 @@
+package models
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// Sort orders accepted by the paginated listings.
+const (
+	SortNewest        = "newest"
+	SortOldest        = "oldest"
+	SortMostLiked     = "most_liked"
+	SortMostCommented = "most_commented"
+	SortHighestRated  = "highest_rated"
+	SortLowestRated   = "lowest_rated"
+)
+
+// Default and maximum number of rows in a page.
+const (
+	DefaultPageSize = 20
+	MaxPageSize     = 100
+)
+
+var (
+	// ErrInvalidSort is returned when a listing does not support the requested sort order.
+	ErrInvalidSort = errors.New("invalid sort order")
+	// ErrInvalidCursor is returned when a cursor is malformed or was issued for another sort order.
+	ErrInvalidCursor = errors.New("invalid cursor")
+)
+
+// PageRequest selects one page of a listing.
+type PageRequest struct {
+	Sort   string // One of the Sort* constants, the listing's default if empty
+	Cursor string // The Next value of the previous page, empty for the first page
+	Limit  int    // Maximum number of rows, DefaultPageSize if zero
+}
+
+// PostPage is one page of posts.
+type PostPage struct {
+	Posts []Post `json:"posts"`
+	Next  string `json:"next,omitempty"` // Cursor of the following page, empty on the last page
+}
+
+// CommentPage is one page of comments.
+type CommentPage struct {
+	Comments []Comment `json:"comments"`
+	Next     string    `json:"next,omitempty"` // Cursor of the following page, empty on the last page
+}
+
+// ReviewPage is one page of book reviews.
+type ReviewPage struct {
+	Reviews []Review `json:"reviews"`
+	Next    string   `json:"next,omitempty"` // Cursor of the following page, empty on the last page
+}
+
+// sortOrder describes how a sort order maps onto SQL. Rows are always ordered
+// by id last, which makes the order total and lets the cursor resume after ties.
+// Newest and oldest order comments and reviews by id alone, since ids are handed
+// out in creation order, but posts by creation time, since a published draft
+// keeps the id it was saved under.
+type sortOrder struct {
+	key       string // Column holding the sort key, empty to order by id alone
+	ascending bool   // Lowest first instead of highest first
+	keyTable  string // Table the cursor reads the key of its last row back from, for keys it does not carry such as times
+}
+
+// postSorts lists the sort orders supported by post listings.
+var postSorts = map[string]sortOrder{
+	SortNewest:        {key: "created_at", keyTable: "posts"},
+	SortOldest:        {key: "created_at", ascending: true, keyTable: "posts"},
+	SortMostLiked:     {key: "likes"},
+	SortMostCommented: {key: "comment_count"},
+}
+
+// commentSorts lists the sort orders supported by comment listings.
+var commentSorts = map[string]sortOrder{
+	SortNewest:    {},
+	SortOldest:    {ascending: true},
+	SortMostLiked: {key: "likes"},
+}
+
+// reviewSorts lists the sort orders supported by review listings.
+var reviewSorts = map[string]sortOrder{
+	SortNewest:       {},
+	SortOldest:       {ascending: true},
+	SortHighestRated: {key: "rating"},
+	SortLowestRated:  {key: "rating", ascending: true},
+}
+
+// cursor is the position a page ends at. It is handed to clients as opaque base64.
+type cursor struct {
+	Sort string `json:"s"`
+	Key  int    `json:"k,omitempty"` // Sort key of the last row, unused when ordering by id alone or reading the key back
+	ID   int    `json:"id"`          // ID of the last row
+}
+
+// encode returns the opaque form of the cursor.
+func (c cursor) encode() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// pageQuery is a resolved PageRequest ready to be turned into SQL.
+type pageQuery struct {
+	sort  string
+	order sortOrder
+	after *cursor // Nil on the first page
+	limit int
+}
+
+// resolvePage validates a page request against the sort orders a listing supports.
+//
+// Parameters:
+//   - page: The requested page.
+//   - sorts: The sort orders the listing supports.
+//   - defaultSort: The sort order used when the request does not name one.
+//
+// Returns:
+//   - pageQuery: The validated request.
+//   - error: ErrInvalidSort or ErrInvalidCursor if the request cannot be served; otherwise, nil.
+func resolvePage(page PageRequest, sorts map[string]sortOrder, defaultSort string) (pageQuery, error) {
+	query := pageQuery{sort: page.Sort, limit: page.Limit}
+	if query.sort == "" {
+		query.sort = defaultSort
+	}
+
+	order, ok := sorts[query.sort]
+	if !ok {
+		return pageQuery{}, fmt.Errorf("%w %q", ErrInvalidSort, page.Sort)
+	}
+	query.order = order
+
+	if query.limit <= 0 {
+		query.limit = DefaultPageSize
+	} else if query.limit > MaxPageSize {
+		query.limit = MaxPageSize
+	}
+
+	if page.Cursor != "" {
+		data, err := base64.RawURLEncoding.DecodeString(page.Cursor)
+		if err != nil {
+			return pageQuery{}, ErrInvalidCursor
+		}
+		var after cursor
+		if err := json.Unmarshal(data, &after); err != nil || after.Sort != query.sort {
+			return pageQuery{}, ErrInvalidCursor
+		}
+		query.after = &after
+	}
+
+	return query, nil
+}
+
+// where returns the condition selecting the rows after the cursor, or an empty
+// string on the first page. It must be applied to a query exposing id and the sort key.
+func (q pageQuery) where() (string, []interface{}) {
+	if q.after == nil {
+		return "", nil
+	}
+
+	op := "<"
+	if q.order.ascending {
+		op = ">"
+	}
+	if q.order.key == "" {
+		return " WHERE id " + op + " ?", []interface{}{q.after.ID}
+	}
+	if q.order.keyTable != "" {
+		last := "(SELECT " + q.order.key + " FROM " + q.order.keyTable + " WHERE id = ?)"
+		return " WHERE (" + q.order.key + " " + op + " " + last + " OR (" + q.order.key + " = " + last + " AND id " + op + " ?))",
+			[]interface{}{q.after.ID, q.after.ID, q.after.ID}
+	}
+	return " WHERE (" + q.order.key + " " + op + " ? OR (" + q.order.key + " = ? AND id " + op + " ?))",
+		[]interface{}{q.after.Key, q.after.Key, q.after.ID}
+}
+
+// orderBy returns the ORDER BY and LIMIT clauses of the page. One row more than
+// the limit is fetched to find out whether another page follows.
+func (q pageQuery) orderBy() (string, []interface{}) {
+	return " ORDER BY " + q.sortColumns("") + " LIMIT ?", []interface{}{q.limit + 1}
+}
+
+// sortColumns returns the columns the page is ordered by, with their direction,
+// each prefixed with table, e.g. "roots.", to order a query joining the page.
+func (q pageQuery) sortColumns(table string) string {
+	direction := "DESC"
+	if q.order.ascending {
+		direction = "ASC"
+	}
+
+	columns := ""
+	if q.order.key != "" {
+		columns += table + q.order.key + " " + direction + ", "
+	}
+	return columns + table + "id " + direction
+}
+
+// nextCursor returns the cursor of the page that follows the row identified by id and key.
+func (q pageQuery) nextCursor(id, key int) string {
+	if q.order.key == "" || q.order.keyTable != "" {
+		key = 0
+	}
+	return cursor{Sort: q.sort, Key: key, ID: id}.encode()
+}
+
+// paginate wraps a listing query so that it returns the requested page. The
+// listing must expose an id column and the column of the sort key.
+func (q pageQuery) paginate(query string, args []interface{}) (string, []interface{}) {
+	where, whereArgs := q.where()
+	order, orderArgs := q.orderBy()
+
+	args = append(args, whereArgs...)
+	args = append(args, orderArgs...)
+	return "SELECT * FROM (" + query + ") listing" + where + order, args
+}
@@ -0,0 +1,281 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"literary-lions/backend/src/internal/db"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Limits of a poll.
+const (
+	MaxPollQuestionLength = 300
+	MaxPollOptionLength   = 100
+	MinPollOptions        = 2
+	MaxPollOptions        = 10
+)
+
+var (
+	// ErrInvalidPoll is returned when a poll has no question, too few or too many options, or a close date in the past.
+	ErrInvalidPoll = errors.New("a poll needs a question of at most 300 characters, 2 to 10 distinct options of at most 100 characters and, if any, a close date in the future")
+	// ErrInvalidVote is returned when a vote picks no option, an option of another poll, or several options of a single choice poll.
+	ErrInvalidVote = errors.New("pick one option of the poll, or several if it allows it")
+	// ErrAlreadyVoted is returned when a member votes twice in a poll.
+	ErrAlreadyVoted = errors.New("you have already voted in this poll")
+	// ErrPollClosed is returned when a member votes in a poll after its close date.
+	ErrPollClosed = errors.New("this poll is closed")
+)
+
+// Poll is a question asked in a post, such as which book to read next, that
+// members answer by picking one of its options, or several.
+type Poll struct {
+	ID         int          `json:"id"`
+	PostID     int          `json:"post_id"`
+	Question   string       `json:"question"`
+	Multiple   bool         `json:"multiple"`            // Whether voters may pick several options
+	Anonymous  bool         `json:"anonymous"`           // Whether the voters of each option are kept hidden
+	ClosesAt   *time.Time   `json:"closes_at,omitempty"` // When voting ends, nil to keep the poll open
+	Closed     bool         `json:"closed"`              // Whether the close date has passed
+	Options    []PollOption `json:"options"`             // In the order they were given
+	VoterCount int          `json:"voter_count"`         // Number of members who voted
+	Voted      bool         `json:"voted"`               // Whether the requesting member voted
+	CreatedAt  time.Time    `json:"created_at"`
+}
+
+// PollOption is an answer offered by a poll, with the votes it received.
+type PollOption struct {
+	ID     int      `json:"id"`
+	Label  string   `json:"label"`
+	Votes  int      `json:"votes"`
+	Chosen bool     `json:"chosen"`           // Whether the requesting member picked it
+	Voters []string `json:"voters,omitempty"` // Usernames of its voters, left out of anonymous polls
+}
+
+// Normalize trims the question and options of a poll and checks the result. Create
+// normalizes the poll too; handlers call it to check a poll before the post
+// carrying it is created.
+func (p *Poll) Normalize() error {
+	p.Question = strings.TrimSpace(p.Question)
+	if p.Question == "" || utf8.RuneCountInString(p.Question) > MaxPollQuestionLength {
+		return ErrInvalidPoll
+	}
+	if len(p.Options) < MinPollOptions || len(p.Options) > MaxPollOptions {
+		return ErrInvalidPoll
+	}
+	seen := map[string]bool{}
+	for i := range p.Options {
+		label := strings.TrimSpace(p.Options[i].Label)
+		if label == "" || utf8.RuneCountInString(label) > MaxPollOptionLength || seen[strings.ToLower(label)] {
+			return ErrInvalidPoll
+		}
+		seen[strings.ToLower(label)] = true
+		p.Options[i].Label = label
+	}
+	if p.ClosesAt != nil {
+		if !p.ClosesAt.After(time.Now()) {
+			return ErrInvalidPoll
+		}
+		closesAt := p.ClosesAt.UTC()
+		p.ClosesAt = &closesAt
+	}
+	return nil
+}
+
+// pollRepository implements PollRepository on top of a SQL database.
+type pollRepository struct {
+	db *db.DB
+}
+
+// Create attaches a poll to a post.
+//
+// Parameters:
+//   - poll: The post, question, options and settings of the new poll.
+//
+// Returns:
+//   - int: The ID of the new poll.
+//   - error: ErrInvalidPoll, or any other error from the insert, such as a unique
+//     constraint failure if the post already carries a poll; otherwise, nil.
+func (r *pollRepository) Create(poll Poll) (int, error) {
+	if err := poll.Normalize(); err != nil {
+		return 0, err
+	}
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback() // No-op once the transaction is committed.
+
+	var closesAt interface{}
+	if poll.ClosesAt != nil {
+		closesAt = *poll.ClosesAt
+	}
+	var id int
+	err = tx.QueryRow("INSERT INTO polls (post_id, question, multiple, anonymous, closes_at) VALUES (?, ?, ?, ?, ?) RETURNING id",
+		poll.PostID, poll.Question, poll.Multiple, poll.Anonymous, closesAt).Scan(&id)
+	if err != nil {
+		return 0, err
+	}
+	for position, option := range poll.Options {
+		if _, err := tx.Exec("INSERT INTO poll_options (poll_id, label, position) VALUES (?, ?, ?)", id, option.Label, position); err != nil {
+			return 0, err
+		}
+	}
+	return id, tx.Commit()
+}
+
+// GetForPost returns the poll of a published post, outside the trash, with its
+// results: the votes for each option and, unless the poll is anonymous, who cast them.
+//
+// Parameters:
+//   - postID: The ID of the post.
+//   - userID: The ID of the member whose votes are flagged, or 0 for guests.
+//
+// Returns:
+//   - Poll: The poll and its results.
+//   - error: ErrNotFound if the post carries no poll, or any other query error; otherwise, nil.
+func (r *pollRepository) GetForPost(postID, userID int) (Poll, error) {
+	var poll Poll
+	var closesAt sql.NullTime
+	err := r.db.QueryRow(`
+        SELECT p.id, p.post_id, p.question, p.multiple, p.anonymous, p.closes_at, p.created_at,
+               (SELECT COUNT(*) FROM poll_voters v WHERE v.poll_id = p.id),
+               EXISTS (SELECT 1 FROM poll_voters v WHERE v.poll_id = p.id AND v.user_id = ?)
+        FROM polls p
+        JOIN posts po ON po.id = p.post_id AND po.deleted_at IS NULL AND po.status = 'published'
+        WHERE p.post_id = ?`, userID, postID).Scan(&poll.ID, &poll.PostID, &poll.Question, &poll.Multiple, &poll.Anonymous, &closesAt, &poll.CreatedAt, &poll.VoterCount, &poll.Voted)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Poll{}, ErrNotFound
+	} else if err != nil {
+		return Poll{}, err
+	}
+	if closesAt.Valid {
+		poll.ClosesAt = &closesAt.Time
+		poll.Closed = !closesAt.Time.After(time.Now())
+	}
+
+	rows, err := r.db.Query(`
+        SELECT o.id, o.label,
+               (SELECT COUNT(*) FROM poll_votes v WHERE v.option_id = o.id),
+               EXISTS (SELECT 1 FROM poll_votes v WHERE v.option_id = o.id AND v.user_id = ?)
+        FROM poll_options o
+        WHERE o.poll_id = ?
+        ORDER BY o.position`, userID, poll.ID)
+	if err != nil {
+		return Poll{}, err
+	}
+	defer rows.Close()
+
+	poll.Options = []PollOption{}
+	positions := map[int]int{}
+	for rows.Next() {
+		var option PollOption
+		if err := rows.Scan(&option.ID, &option.Label, &option.Votes, &option.Chosen); err != nil {
+			return Poll{}, err
+		}
+		positions[option.ID] = len(poll.Options)
+		poll.Options = append(poll.Options, option)
+	}
+	if err := rows.Err(); err != nil {
+		return Poll{}, err
+	}
+	if poll.Anonymous {
+		return poll, nil
+	}
+
+	voters, err := r.db.Query(`
+        SELECT v.option_id, u.username
+        FROM poll_votes v
+        JOIN poll_options o ON o.id = v.option_id
+        JOIN users u ON u.id = v.user_id
+        WHERE o.poll_id = ?
+        ORDER BY u.username`, poll.ID)
+	if err != nil {
+		return Poll{}, err
+	}
+	defer voters.Close()
+
+	for voters.Next() {
+		var optionID int
+		var username string
+		if err := voters.Scan(&optionID, &username); err != nil {
+			return Poll{}, err
+		}
+		option := &poll.Options[positions[optionID]]
+		option.Voters = append(option.Voters, username)
+	}
+	return poll, voters.Err()
+}
+
+// Vote records the options a member picks in a poll. Each member votes once, and
+// their vote cannot be changed.
+//
+// Parameters:
+//   - pollID: The ID of the poll.
+//   - userID: The ID of the voter.
+//   - optionIDs: The options picked, one unless the poll allows several.
+//
+// Returns:
+//   - error: ErrNotFound if no poll of a published post has the ID, ErrPollClosed, ErrInvalidVote,
+//     ErrAlreadyVoted, or any other error from the insert; otherwise, nil.
+func (r *pollRepository) Vote(pollID, userID int, optionIDs []int) error {
+	ids := []int{}
+	seen := map[int]bool{}
+	for _, id := range optionIDs {
+		if !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback() // No-op once the transaction is committed.
+
+	var multiple bool
+	var closesAt sql.NullTime
+	err = tx.QueryRow(`
+        SELECT p.multiple, p.closes_at
+        FROM polls p
+        JOIN posts po ON po.id = p.post_id AND po.deleted_at IS NULL AND po.status = 'published'
+        WHERE p.id = ?`, pollID).Scan(&multiple, &closesAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrNotFound
+	} else if err != nil {
+		return err
+	}
+	if closesAt.Valid && !closesAt.Time.After(time.Now()) {
+		return ErrPollClosed
+	}
+	if len(ids) == 0 || (!multiple && len(ids) > 1) {
+		return ErrInvalidVote
+	}
+	for _, id := range ids {
+		var inPoll bool
+		if err := tx.QueryRow("SELECT EXISTS (SELECT 1 FROM poll_options WHERE id = ? AND poll_id = ?)", id, pollID).Scan(&inPoll); err != nil {
+			return err
+		}
+		if !inPoll {
+			return ErrInvalidVote
+		}
+	}
+
+	// The unique key on the voters keeps each member to one vote, even when two
+	// requests race
+	_, err = tx.Exec("INSERT INTO poll_voters (poll_id, user_id) VALUES (?, ?)", pollID, userID)
+	if r.db.Dialect.IsUniqueViolation(err, "user_id") {
+		return ErrAlreadyVoted
+	} else if err != nil {
+		return err
+	}
+	for _, id := range ids {
+		if _, err := tx.Exec("INSERT INTO poll_votes (option_id, user_id) VALUES (?, ?)", id, userID); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
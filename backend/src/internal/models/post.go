@@ -2,261 +2,482 @@ package models
 
 import (
 	"database/sql"
-	"fmt"
+	"literary-lions/backend/src/internal/db"
 	"strings"
 	"time"
 )
 
 type Post struct {
-	ID        int       `json:"id"`
-	UserID    int       `json:"user_id"`
-	Title     string    `json:"title"`
-	Content   string    `json:"content"`
-	Username  string    `json:"username"`
-	Category  string    `json:"category"`
-	CreatedAt time.Time `json:"created_at" db:"createdAt"`
+	ID           int             `json:"id"`
+	UserID       int             `json:"user_id"`
+	Title        string          `json:"title"`
+	Content      string          `json:"content"`
+	ContentHTML  string          `json:"content_html,omitempty"` // Content rendered from Markdown, filled in on the post page and listings
+	Username     string          `json:"username"`
+	CategoryID   int             `json:"category_id"`
+	Category     string          `json:"category"`             // Name of the category
+	CategorySlug string          `json:"category_slug"`        // Slug of the category
+	ClubID       int             `json:"club_id,omitempty"`    // The club the post is in, 0 for the forum
+	Club         string          `json:"club,omitempty"`       // Name of the club
+	ClubSlug     string          `json:"club_slug,omitempty"`  // Slug of the club
+	SectionID    int             `json:"section_id,omitempty"` // The section of the reading schedule of the club it discusses, 0 for none
+	Section      string          `json:"section,omitempty"`    // Title of the section
+	Locked       bool            `json:"locked,omitempty"`     // Whether the content is held back until the requesting member reads the section
+	CreatedAt    time.Time       `json:"created_at" db:"createdAt"`
+	Likes        int             `json:"likes"`                // Number of likes
+	Dislikes     int             `json:"dislikes"`             // Number of dislikes
+	CommentCount int             `json:"comment_count"`        // Number of comments, removed ones aside
+	Reactions    []ReactionCount `json:"reactions,omitempty"`  // Counts of the configured reactions, filled in on the post page
+	Tags         []string        `json:"tags"`                 // Slugs of the tags, in alphabetical order
+	Editable     bool            `json:"editable"`             // Whether the requesting user may edit and delete it, filled in on the post page
+	EditedAt     *time.Time      `json:"edited_at,omitempty"`  // Time of the last edit, nil if never edited
+	DeletedAt    *time.Time      `json:"deleted_at,omitempty"` // Time it was moved to the trash, nil while it is live
+	Status       string          `json:"status"`               // PostDraft, PostScheduled or PostPublished
+	PublishAt    *time.Time      `json:"publish_at,omitempty"` // Time a scheduled post goes live, nil for the others
+	Attachments  []Attachment    `json:"attachments"`          // Images, in the order they are shown
+	Cover        *Attachment     `json:"cover,omitempty"`      // The image shown in the listings, nil without images
+	Books        []BookRef       `json:"books"`                // Books the post discusses, in the order they are shown
 }
 
-// CreatePost inserts a new post into the database with the provided details.
+// PostFilter narrows a post listing. Zero fields do not filter.
+type PostFilter struct {
+	Category  string    // Slug or, ignoring case, name of the category
+	Tags      []string  // Only posts carrying every one of these tags
+	Book      int       // Only posts discussing the book with this ID
+	Title     string    // Part of the title, ignoring case
+	StartDate time.Time // Only posts created on or after this time
+	EndDate   time.Time // Only posts created on or before this time
+}
+
+// forumPosts is the condition keeping the posts, aliased p, that are in the forum
+// rather than in a club. Club posts are only listed in their club.
+const forumPosts = "p.club_id IS NULL"
+
+// DefaultTrashRetention is how long deleted posts and comments stay in the trash,
+// where their authors can restore them, when no other retention is configured.
+const DefaultTrashRetention = 30 * 24 * time.Hour
+
+// postRepository implements PostRepository on top of a SQL database.
+type postRepository struct {
+	db *db.DB
+}
+
+// postSelect reads posts together with their author, club and section, reaction
+// counts, comment count, tags, images and books, so that loading any number of
+// posts takes a single query.
+const postSelect = `
+        SELECT p.id, p.user_id, p.title, p.content, COALESCE(p.category_id, 0), COALESCE(cat.name, ''), COALESCE(cat.slug, ''), p.created_at, p.edited_at, p.deleted_at, p.status, p.publish_at, u.username,
+               COALESCE(p.club_id, 0), COALESCE(cl.name, ''), COALESCE(cl.slug, ''), COALESCE(p.section_id, 0), COALESCE(cs.title, ''),
+               (SELECT COUNT(*) FROM reactions r WHERE r.target_type = 'post' AND r.target_id = p.id AND r.reaction = 'like') AS likes,
+               (SELECT COUNT(*) FROM reactions r WHERE r.target_type = 'post' AND r.target_id = p.id AND r.reaction = 'dislike') AS dislikes,
+               (SELECT COUNT(*) FROM comments c WHERE c.post_id = p.id AND c.deleted_at IS NULL) AS comment_count,
+               (SELECT string_agg(t.slug, ',') FROM post_tags pt INNER JOIN tags t ON t.id = pt.tag_id WHERE pt.post_id = p.id) AS tags,
+               ` + attachmentsColumn + `,
+               ` + bookRefsColumn + `
+        FROM posts p
+        INNER JOIN users u ON u.id = p.user_id
+        LEFT JOIN categories cat ON cat.id = p.category_id
+        LEFT JOIN clubs cl ON cl.id = p.club_id
+        LEFT JOIN club_sections cs ON cs.id = p.section_id`
+
+// scanPost reads a row selected with postSelect.
+func scanPost(row interface{ Scan(...interface{}) error }) (Post, error) {
+	var post Post
+	var tags, attachments, books sql.NullString
+	var editedAt, deletedAt, publishAt sql.NullTime
+	err := row.Scan(&post.ID, &post.UserID, &post.Title, &post.Content, &post.CategoryID, &post.Category, &post.CategorySlug, &post.CreatedAt, &editedAt, &deletedAt, &post.Status, &publishAt, &post.Username, &post.ClubID, &post.Club, &post.ClubSlug, &post.SectionID, &post.Section, &post.Likes, &post.Dislikes, &post.CommentCount, &tags, &attachments, &books)
+	post.Tags = splitTags(tags)
+	post.Books = splitBookRefs(books)
+	post.Attachments, post.Cover = splitAttachments(attachments)
+	if editedAt.Valid {
+		post.EditedAt = &editedAt.Time
+	}
+	if deletedAt.Valid {
+		post.DeletedAt = &deletedAt.Time
+	}
+	if publishAt.Valid {
+		post.PublishAt = &publishAt.Time
+	}
+	return post, err
+}
+
+// Create inserts a new post into the database with the provided details, and
+// records it as the first revision of the post.
 // Parameters:
 //   - userID: The ID of the user creating the post.
 //   - title: The title of the post.
 //   - content: The content of the post.
-//   - category: The category of the post.
+//   - categoryID: The ID of the category the post is filed under.
+//   - tags: The tags of the post, as typed.
 //
 // Returns:
-//   - error: An error if the operation fails; otherwise, nil.
-func CreatePost(userID int, title, content, category string) error {
-	_, err := db.Exec("INSERT INTO posts (user_id, title, content, category) VALUES (?, ?, ?, ?)", userID, title, content, category)
-	return err
+//   - int: The ID of the new post.
+//   - error: ErrInvalidTag or ErrTooManyTags for bad tags, or an error if the operation fails; otherwise, nil.
+func (r *postRepository) Create(userID int, title, content string, categoryID int, tags []string) (int, error) {
+	return r.create(0, 0, userID, title, content, categoryID, tags)
 }
 
-// GetAllPosts retrieves all posts from the database, sorted by the creation time in descending order.
+// CreateInClub inserts a new post into a club, where it is discussed rather than
+// in the forum, and records it as the first revision of the post.
 // Parameters:
-//   - db: The database connection to use for the query.
+//   - clubID: The ID of the club.
+//   - sectionID: The ID of the section of the reading schedule of the club the post discusses, or 0 for none.
+//   - userID: The ID of the member creating the post.
+//   - title: The title of the post.
+//   - content: The content of the post.
+//   - categoryID: The ID of the category the post is filed under.
+//   - tags: The tags of the post, as typed.
 //
 // Returns:
-//   - []Post: A slice of Post structs containing the details of each post.
-//   - error: An error if the operation fails; otherwise, nil.
-func GetAllPosts(db *sql.DB, keyword string) ([]Post, error) {
-	// Split the keyword into individual words
-	words := strings.Fields(keyword)
+//   - int: The ID of the new post.
+//   - error: ErrInvalidTag or ErrTooManyTags for bad tags, or an error if the operation fails; otherwise, nil.
+func (r *postRepository) CreateInClub(clubID, sectionID, userID int, title, content string, categoryID int, tags []string) (int, error) {
+	return r.create(clubID, sectionID, userID, title, content, categoryID, tags)
+}
 
-	// Check if there are any words to search for
-	if len(words) == 0 {
-		return nil, fmt.Errorf("no keywords provided for search")
+// create inserts a new post in the club with the given ID, or in the forum for 0,
+// discussing the section with the given ID, if any, and records its first revision.
+func (r *postRepository) create(clubID, sectionID, userID int, title, content string, categoryID int, tags []string) (int, error) {
+	slugs, err := NormalizeTags(tags)
+	if err != nil {
+		return 0, err
 	}
 
-	// Start building the query
-	query := `
-        SELECT p.id, p.title, p.content, p.category, p.user_id, p.created_at, u.username
-        FROM posts p
-        INNER JOIN users u ON p.user_id = u.id
-        WHERE
-    `
-
-	// Build the WHERE clause with LIKE for each word, joined by AND
-	conditions := []string{}
-	params := []interface{}{}
-
-	for _, word := range words {
-		likeWord := "%" + word + "%"
-		conditions = append(conditions, "p.title LIKE ?")
-		params = append(params, likeWord)
+	tx, err := r.db.Begin()
+	if err != nil {
+		return 0, err
 	}
+	defer tx.Rollback() // No-op once the transaction is committed.
 
-	// Join conditions with AND to ensure all words must appear in the title
-	query += strings.Join(conditions, " AND ")
-
-	// Add ORDER BY clause to sort by creation date
-	query += " ORDER BY p.created_at DESC"
-
-	// Execute the query with the keyword parameters
-	rows, err := db.Query(query, params...)
+	var postID int
+	err = tx.QueryRow("INSERT INTO posts (user_id, title, content, category_id, club_id, section_id) VALUES (?, ?, ?, ?, ?, ?) RETURNING id",
+		userID, title, content, categoryID, nullableID(clubID), nullableID(sectionID)).Scan(&postID)
 	if err != nil {
-		if err == sql.ErrNoRows {
-			return []Post{}, nil // Return an empty slice if no posts are found
-		}
-		return nil, err
+		return 0, err
 	}
-	defer rows.Close()
-
-	var posts []Post
-	for rows.Next() {
-		var post Post
-		// Scan each field into the Post struct
-		if err := rows.Scan(&post.ID, &post.Title, &post.Content, &post.Category, &post.UserID, &post.CreatedAt, &post.Username); err != nil {
-			return nil, err
-		}
-		posts = append(posts, post)
+	if err := setPostTags(tx, postID, slugs); err != nil {
+		return 0, err
 	}
-
-	// Check for errors encountered during the iteration
-	if err := rows.Err(); err != nil {
-		return nil, err
+	if err := addRevision(tx, postID, userID, 0); err != nil {
+		return 0, err
 	}
-
-	return posts, nil
+	return postID, tx.Commit()
 }
 
-// GetPostByID retrieves a specific post by its ID from the database.
+// GetByID retrieves a specific post by its ID from the database, along with its
+// author, reaction counts and comment count. Drafts and posts in the trash are
+// not found.
 // Parameters:
 //   - postID: The ID of the post to retrieve.
 //
 // Returns:
 //   - Post: A Post struct containing the details of the requested post.
-//   - error: An error if the operation fails; otherwise, nil.
-func GetPostByID(postID int) (Post, error) {
-	var post Post
-	row := db.QueryRow("SELECT id, user_id, title, content, category, created_at FROM posts WHERE id = ?", postID)
-
-	err := row.Scan(&post.ID, &post.UserID, &post.Title, &post.Content, &post.Category, &post.CreatedAt)
+//   - error: ErrNotFound if no post has the ID, or any other error if the operation fails; otherwise, nil.
+func (r *postRepository) GetByID(postID int) (Post, error) {
+	post, err := scanPost(r.db.QueryRow(postSelect+" WHERE p.id = ? AND p.deleted_at IS NULL AND p.status = 'published'", postID))
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return Post{}, nil // Return an empty Post if not found
+			return Post{}, ErrNotFound
 		}
 		return Post{}, err
 	}
-	// Fetch the username for the post's author
-	user, err := GetUser(post.UserID)
-	if err != nil {
-		return Post{}, err
-	}
-	post.Username = user.Username
 
 	return post, nil
 }
 
-// GetFilteredPosts retrieves posts from the database based on the provided filters.
-func GetFilteredPosts(category, title string, startDate, endDate time.Time) ([]Post, error) {
-	var posts []Post
-	var filters []string
+// GetFiltered retrieves one page of the forum posts matching the provided filters.
+// Parameters:
+//   - filter: The category, tags, title and date range to match; zero fields match every post.
+//   - page: The sort order, cursor and size of the page.
+//
+// Returns:
+//   - PostPage: The posts of the page and the cursor of the next one.
+//   - error: ErrInvalidSort or ErrInvalidCursor for a bad page request, or any query error; otherwise, nil.
+func (r *postRepository) GetFiltered(filter PostFilter, page PageRequest) (PostPage, error) {
+	filters := []string{forumPosts}
 	var args []interface{}
 
 	// Apply title filter
-	if title != "" {
-		filters = append(filters, "title LIKE ?")
-		args = append(args, "%"+title+"%")
+	if filter.Title != "" {
+		filters = append(filters, "LOWER(p.title) LIKE LOWER(?)")
+		args = append(args, "%"+filter.Title+"%")
 	}
 
 	// Apply category filter
-	if category != "" {
-		filters = append(filters, "category LIKE ?")
-		args = append(args, "%"+category+"%")
+	if filter.Category != "" {
+		filters = append(filters, "(cat.slug = ? OR LOWER(cat.name) = LOWER(?))")
+		args = append(args, filter.Category, filter.Category)
+	}
+
+	// Apply tag filter
+	tagFilters, tagArgs := tagConditions(filter.Tags)
+	filters = append(filters, tagFilters...)
+	args = append(args, tagArgs...)
+
+	// Apply book filter
+	if filter.Book != 0 {
+		filters = append(filters, "p.id IN (SELECT pb.post_id FROM post_books pb WHERE pb.book_id = ?)")
+		args = append(args, filter.Book)
 	}
 
 	// Apply date range filter
-	if !startDate.IsZero() {
-		filters = append(filters, "created_at >= ?")
-		args = append(args, startDate)
+	if !filter.StartDate.IsZero() {
+		filters = append(filters, "p.created_at >= ?")
+		args = append(args, filter.StartDate)
 	}
-	if !endDate.IsZero() {
-		filters = append(filters, "created_at <= ?")
-		args = append(args, endDate)
+	if !filter.EndDate.IsZero() {
+		filters = append(filters, "p.created_at <= ?")
+		args = append(args, filter.EndDate)
 	}
 
-	// Build the query
-	query := "SELECT id, user_id, title, content, category, created_at FROM posts"
-	if len(filters) > 0 {
-		query += " WHERE " + strings.Join(filters, " AND ")
+	return r.list(filters, args, page)
+}
+
+// GetByUser fetches one page of the forum posts created by the given user.
+// Parameters:
+//   - userID: The ID of the author.
+//   - page: The sort order, cursor and size of the page.
+//
+// Returns:
+//   - PostPage: The posts of the page and the cursor of the next one.
+//   - error: ErrInvalidSort or ErrInvalidCursor for a bad page request, or any query error; otherwise, nil.
+func (r *postRepository) GetByUser(userID int, page PageRequest) (PostPage, error) {
+	return r.list([]string{forumPosts, "p.user_id = ?"}, []interface{}{userID}, page)
+}
+
+// GetByClub fetches one page of the posts of a club, or of those discussing one
+// section of its reading schedule.
+// Parameters:
+//   - clubID: The ID of the club.
+//   - sectionID: The ID of the section, or 0 for every post of the club.
+//   - page: The sort order, cursor and size of the page.
+//
+// Returns:
+//   - PostPage: The posts of the page and the cursor of the next one.
+//   - error: ErrInvalidSort or ErrInvalidCursor for a bad page request, or any query error; otherwise, nil.
+func (r *postRepository) GetByClub(clubID, sectionID int, page PageRequest) (PostPage, error) {
+	if sectionID != 0 {
+		return r.list([]string{"p.club_id = ?", "p.section_id = ?"}, []interface{}{clubID, sectionID}, page)
+	}
+	return r.list([]string{"p.club_id = ?"}, []interface{}{clubID}, page)
+}
+
+// GetLikedByUser fetches one page of the forum posts the given user currently likes.
+// Parameters:
+//   - userID: The ID of the user whose likes are listed.
+//   - page: The sort order, cursor and size of the page.
+//
+// Returns:
+//   - PostPage: The posts of the page and the cursor of the next one.
+//   - error: ErrInvalidSort or ErrInvalidCursor for a bad page request, or any query error; otherwise, nil.
+func (r *postRepository) GetLikedByUser(userID int, page PageRequest) (PostPage, error) {
+	filter := "p.id IN (SELECT r.target_id FROM reactions r WHERE r.user_id = ? AND r.target_type = 'post' AND r.reaction = 'like')"
+	return r.list([]string{forumPosts, filter}, []interface{}{userID}, page)
+}
+
+// list runs a post listing restricted by the filters, leaving out drafts and
+// the posts in the trash, and returns the requested page of it.
+func (r *postRepository) list(filters []string, args []interface{}, page PageRequest) (PostPage, error) {
+	pageQuery, err := resolvePage(page, postSorts, SortNewest)
+	if err != nil {
+		return PostPage{}, err
 	}
-	query += " ORDER BY created_at DESC" // Order by most recent posts
+
+	where := " WHERE " + strings.Join(append(filters, "p.deleted_at IS NULL", "p.status = 'published'"), " AND ")
+	query, args := pageQuery.paginate(postSelect+where, args)
 
 	// Execute the query
-	rows, err := db.Query(query, args...)
+	rows, err := r.db.Query(query, args...)
 	if err != nil {
-		return nil, err
+		return PostPage{}, err
 	}
 	defer rows.Close()
 
 	// Iterate over the rows and scan the data into the posts slice
+	posts := []Post{}
 	for rows.Next() {
-		var post Post
-		if err := rows.Scan(&post.ID, &post.UserID, &post.Title, &post.Content, &post.Category, &post.CreatedAt); err != nil {
-			return nil, err
-		}
-
-		// Fetch the username for each post based on the user ID
-		user, err := GetUser(post.UserID)
+		post, err := scanPost(rows)
 		if err != nil {
-			return nil, err
+			return PostPage{}, err
 		}
-		post.Username = user.Username
-
 		posts = append(posts, post)
 	}
+	if err := rows.Err(); err != nil {
+		return PostPage{}, err
+	}
+
+	// The extra row only tells that another page follows
+	result := PostPage{Posts: posts}
+	if len(posts) > pageQuery.limit {
+		result.Posts = posts[:pageQuery.limit]
+		last := result.Posts[pageQuery.limit-1]
+		key := last.Likes
+		if pageQuery.order.key == "comment_count" {
+			key = last.CommentCount
+		}
+		result.Next = pageQuery.nextCursor(last.ID, key)
+	}
 
-	return posts, nil
+	return result, nil
 }
 
-// GetUserPosts fetches all posts created by the given user.
-func GetUserPosts(userID int) ([]Post, error) {
-	// Query to select posts by user ID
-	rows, err := db.Query("SELECT id, title, content, category, user_id, created_at FROM posts WHERE user_id = ? ORDER BY created_at DESC", userID)
+// Update overwrites the title, content, category and tags of an existing post.
+// When the title, content or category change, the new version is recorded as
+// the next revision of the post and the post is marked as edited.
+// Parameters:
+//   - postID: The ID of the post to update.
+//   - editorID: The ID of the user making the edit.
+//   - title: The new title of the post.
+//   - content: The new content of the post.
+//   - categoryID: The ID of the new category of the post.
+//   - tags: The new tags of the post, as typed; they replace the current ones.
+//
+// Returns:
+//   - error: ErrInvalidTag or ErrTooManyTags for bad tags, ErrNotFound if no post has the ID
+//     or it is a draft or in the trash, or any other error from the update; otherwise, nil.
+func (r *postRepository) Update(postID, editorID int, title, content string, categoryID int, tags []string) error {
+	slugs, err := NormalizeTags(tags)
 	if err != nil {
-		return nil, err
+		return err
 	}
-	defer rows.Close()
 
-	var posts []Post
-	for rows.Next() {
-		var post Post
-		if err := rows.Scan(&post.ID, &post.Title, &post.Content, &post.Category, &post.UserID, &post.CreatedAt); err != nil {
-			return nil, err
-		}
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback() // No-op once the transaction is committed.
+
+	// Tags are not part of the history, so changing only them is not an edit
+	var changed bool
+	err = tx.QueryRow("SELECT title <> ? OR content <> ? OR COALESCE(category_id, 0) <> ? FROM posts WHERE id = ? AND deleted_at IS NULL AND status = 'published'", title, content, categoryID, postID).Scan(&changed)
+	if err == sql.ErrNoRows {
+		return ErrNotFound
+	} else if err != nil {
+		return err
+	}
 
-		// Set the Username field directly, as all posts belong to the same user
-		user, err := GetUser(post.UserID) // Assuming `GetUser` is a function that fetches user details based on `userID`
-		if err != nil {
-			return nil, err
+	if changed {
+		if _, err := tx.Exec("UPDATE posts SET category_id = ?, title = ?, content = ?, edited_at = CURRENT_TIMESTAMP WHERE id = ?", categoryID, title, content, postID); err != nil {
+			return err
+		}
+		if err := addRevision(tx, postID, editorID, 0); err != nil {
+			return err
 		}
-		post.Username = user.Username
-
-		posts = append(posts, post)
 	}
-
-	// Check for any errors encountered during the iteration over the rows
-	if err := rows.Err(); err != nil {
-		return nil, err
+	if err := setPostTags(tx, postID, slugs); err != nil {
+		return err
 	}
+	return tx.Commit()
+}
 
-	return posts, nil
+// Delete moves a post to the trash. It disappears from the forum along with its
+// comments, and can be restored until it is purged.
+// Parameters:
+//   - postID: The ID of the post to delete.
+//
+// Returns:
+//   - error: ErrNotFound if no post has the ID or it is already in the trash, or any other error; otherwise, nil.
+func (r *postRepository) Delete(postID int) error {
+	result, err := r.db.Exec("UPDATE posts SET deleted_at = CURRENT_TIMESTAMP WHERE id = ? AND deleted_at IS NULL", postID)
+	if err != nil {
+		return err
+	}
+	return requireAffected(result)
 }
 
-func GetLikedPostsByUserID(userID int) ([]Post, error) {
-	// SQL query to select liked posts along with the username
-	query := `
-        SELECT p.id, p.title, p.content, p.category, p.user_id, p.created_at, u.username 
-        FROM posts p
-        INNER JOIN post_likes pl ON p.id = pl.post_id
-        INNER JOIN users u ON p.user_id = u.id
-        WHERE pl.user_id = ? AND pl.is_like = 1
-    `
+// Restore takes a post out of the trash, with its comments and reactions. Posts of
+// a deleted club stay in the trash.
+// Parameters:
+//   - postID: The ID of the post to restore.
+//   - since: The start of the retention window; posts deleted before it can no longer be restored.
+//
+// Returns:
+//   - error: ErrNotFound if the post is not in the trash or was deleted before since, or any other error; otherwise, nil.
+func (r *postRepository) Restore(postID int, since time.Time) error {
+	result, err := r.db.Exec("UPDATE posts SET deleted_at = NULL WHERE id = ? AND deleted_at >= ? AND (club_id IS NULL OR club_id IN (SELECT id FROM clubs))", postID, since.UTC())
+	if err != nil {
+		return err
+	}
+	return requireAffected(result)
+}
 
-	// Execute the query
-	rows, err := db.Query(query, userID)
+// Trashed lists the posts of a user that are in the trash and can still be
+// restored, the most recently deleted first. Posts of a deleted club are left out.
+// Parameters:
+//   - userID: The ID of the author.
+//   - since: The start of the retention window.
+//
+// Returns:
+//   - []Post: The deleted posts, with the time they were deleted.
+//   - error: An error if the query fails; otherwise, nil.
+func (r *postRepository) Trashed(userID int, since time.Time) ([]Post, error) {
+	rows, err := r.db.Query(postSelect+" WHERE p.user_id = ? AND p.deleted_at >= ? AND (p.club_id IS NULL OR cl.id IS NOT NULL) ORDER BY p.deleted_at DESC, p.id DESC", userID, since.UTC())
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
-	// Slice to hold the result
-	var likedPosts []Post
-
-	// Iterate over the rows
+	posts := []Post{}
 	for rows.Next() {
-		var post Post
-		// Assuming Post struct has a Username field
-		if err := rows.Scan(&post.ID, &post.Title, &post.Content, &post.Category, &post.UserID, &post.CreatedAt, &post.Username); err != nil {
+		post, err := scanPost(rows)
+		if err != nil {
 			return nil, err
 		}
-		likedPosts = append(likedPosts, post)
+		posts = append(posts, post)
 	}
+	return posts, rows.Err()
+}
 
-	// Check for any errors encountered during iteration
-	if err := rows.Err(); err != nil {
-		return nil, err
+// Purge permanently deletes the posts that were moved to the trash before the
+// given time, along with their comments, the reactions to the posts and their
+// comments, their tags, revisions, images, links to books and polls with their
+// votes. The tags themselves stay for other posts, and the images for the cleanup
+// of unused uploads.
+// Parameters:
+//   - before: The end of the retention of the posts to purge.
+//
+// Returns:
+//   - int: The number of posts purged.
+//   - error: An error if the deletion fails; otherwise, nil.
+func (r *postRepository) Purge(before time.Time) (int, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback() // No-op once the transaction is committed.
+
+	// Reactions point at posts and comments alike, so no foreign key removes them
+	expired := "SELECT id FROM posts WHERE deleted_at < ?"
+	cutoff := before.UTC()
+	cleanup := []struct {
+		query string
+		args  []interface{}
+	}{
+		{"DELETE FROM reactions WHERE target_type = ? AND target_id IN (SELECT id FROM comments WHERE post_id IN (" + expired + "))", []interface{}{ReactionTargetComment, cutoff}},
+		{"DELETE FROM reactions WHERE target_type = ? AND target_id IN (" + expired + ")", []interface{}{ReactionTargetPost, cutoff}},
+		{"DELETE FROM comments WHERE post_id IN (" + expired + ")", []interface{}{cutoff}},
+		{"DELETE FROM post_tags WHERE post_id IN (" + expired + ")", []interface{}{cutoff}},
+		{"DELETE FROM post_revisions WHERE post_id IN (" + expired + ")", []interface{}{cutoff}},
+		{"DELETE FROM post_attachments WHERE post_id IN (" + expired + ")", []interface{}{cutoff}},
+		{"DELETE FROM post_books WHERE post_id IN (" + expired + ")", []interface{}{cutoff}},
+		{"DELETE FROM poll_votes WHERE option_id IN (SELECT o.id FROM poll_options o JOIN polls p ON p.id = o.poll_id WHERE p.post_id IN (" + expired + "))", []interface{}{cutoff}},
+		{"DELETE FROM poll_voters WHERE poll_id IN (SELECT id FROM polls WHERE post_id IN (" + expired + "))", []interface{}{cutoff}},
+		{"DELETE FROM poll_options WHERE poll_id IN (SELECT id FROM polls WHERE post_id IN (" + expired + "))", []interface{}{cutoff}},
+		{"DELETE FROM polls WHERE post_id IN (" + expired + ")", []interface{}{cutoff}},
+	}
+	for _, step := range cleanup {
+		if _, err := tx.Exec(step.query, step.args...); err != nil {
+			return 0, err
+		}
 	}
 
-	return likedPosts, nil
+	result, err := tx.Exec("DELETE FROM posts WHERE deleted_at < ?", cutoff)
+	if err != nil {
+		return 0, err
+	}
+	purged, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+	return int(purged), tx.Commit()
 }
@@ -0,0 +1,239 @@
+package models
+
+import (
+	"errors"
+	"literary-lions/backend/src/internal/db"
+	"time"
+)
+
+// ErrNotFound is returned by repositories when the requested record does not exist.
+var ErrNotFound = errors.New("record not found")
+
+// UserRepository stores user accounts and their profiles.
+type UserRepository interface {
+	Register(email, username, password string) error
+	Authenticate(email, password string) (*User, error)
+	GetByID(userID int) (*User, error)
+	FindByEmail(email string) (*User, error)
+	GetByUsername(username string) (*User, error)
+	List() ([]User, error)
+	UpdateProfile(user User) error
+	SetAvatar(userID int, key string) error
+	Activity(userID int, reactions ReactionSet) (Activity, error)
+	Update(user User) error
+	UpdateRole(userID int, role string) error
+	Delete(userID int) error
+}
+
+// PostRepository stores forum posts and the history of their edits. Posts may be
+// kept as drafts, or scheduled, before they are published, and may be written in a
+// club rather than in the forum. Deleted posts go to a trash they can be restored
+// from until they are purged.
+type PostRepository interface {
+	Create(userID int, title, content string, categoryID int, tags []string) (int, error)
+	CreateInClub(clubID, sectionID, userID int, title, content string, categoryID int, tags []string) (int, error)
+	GetByID(postID int) (Post, error)
+	GetFiltered(filter PostFilter, page PageRequest) (PostPage, error)
+	GetByUser(userID int, page PageRequest) (PostPage, error)
+	GetLikedByUser(userID int, page PageRequest) (PostPage, error)
+	GetByClub(clubID, sectionID int, page PageRequest) (PostPage, error)
+	Update(postID, editorID int, title, content string, categoryID int, tags []string) error
+	Delete(postID int) error
+	Restore(postID int, since time.Time) error
+	Trashed(userID int, since time.Time) ([]Post, error)
+	Purge(before time.Time) (int, error)
+	SaveDraft(userID int, title, content string, categoryID int, tags []string, publishAt *time.Time) (int, error)
+	Drafts(userID int) ([]Post, error)
+	GetDraft(postID int) (Post, error)
+	UpdateDraft(postID int, title, content string, categoryID int, tags []string, publishAt *time.Time) error
+	Publish(postID int) (int, error)
+	PublishDue(now time.Time) (int, error)
+	Revisions(postID int) ([]PostRevision, error)
+	Revision(postID, revision int) (PostRevision, error)
+	RestoreRevision(postID, revision, editorID int) error
+}
+
+// CategoryRepository stores the categories posts are filed under.
+type CategoryRepository interface {
+	List() ([]Category, error)
+	GetByID(categoryID int) (Category, error)
+	Find(ref string) (Category, error)
+	Create(category Category) (Category, error)
+	Update(category Category) (Category, error)
+	Delete(categoryID int) error
+}
+
+// TagRepository reads the tags carried by posts. Tags are written along with their posts.
+type TagRepository interface {
+	Autocomplete(prefix string, limit int) ([]Tag, error)
+	GetBySlug(slug string) (Tag, error)
+}
+
+// CommentRepository stores comments on posts and the replies threaded below them.
+// Deleted comments go to a trash they can be restored from until they are purged.
+type CommentRepository interface {
+	Create(postID, userID int, content string) error
+	Reply(parentID, userID int, content string, maxDepth int) (int, error)
+	GetByID(commentID int) (Comment, error)
+	GetByPostID(postID int, page PageRequest, maxDepth int) (CommentPage, error)
+	GetByUser(userID int, page PageRequest) (CommentPage, error)
+	Update(commentID int, content string) error
+	Delete(commentID int) error
+	Restore(commentID int, since time.Time) error
+	Trashed(userID int, since time.Time) ([]Comment, error)
+	Purge(before time.Time) (int, error)
+}
+
+// SessionRepository stores login sessions.
+type SessionRepository interface {
+	Create(userID int) (string, error)
+	Validate(sessionUUID string) (int, error)
+	Invalidate(sessionUUID string) error
+}
+
+// ReactionRepository stores likes, dislikes and the reactions of a ReactionSet on posts and comments.
+type ReactionRepository interface {
+	TogglePostLike(userID, postID int) error
+	TogglePostDislike(userID, postID int) error
+	ToggleCommentLike(userID, commentID int) error
+	ToggleCommentDislike(userID, commentID int) error
+	CountPostLikes(postID int) (int, error)
+	CountPostDislikes(postID int) (int, error)
+	CountCommentLikes(commentID int) (int, error)
+	CountCommentDislikes(commentID int) (int, error)
+	AddReaction(userID int, targetType string, targetID int, reaction string) error
+	RemoveReaction(userID int, targetType string, targetID int, reaction string) error
+	CountReactions(userID int, targetType string, targetIDs []int, reactions ReactionSet) (map[int][]ReactionCount, error)
+}
+
+// AttachmentRepository stores uploaded images and the posts carrying them.
+type AttachmentRepository interface {
+	Create(attachment Attachment) (int, error)
+	SetForPost(postID, userID int, attachmentIDs []int, coverID int) error
+	PurgeUnused(before time.Time) (int, error)
+	Keys() ([]string, error)
+}
+
+// BookRepository stores the catalog of books and the posts discussing them. Books
+// added by members wait for an admin or curator to approve them.
+type BookRepository interface {
+	List(filter BookFilter) ([]Book, error)
+	GetByID(bookID int) (Book, error)
+	Create(book Book) (Book, error)
+	Update(book Book) (Book, error)
+	Approve(bookID int) error
+	Delete(bookID int) error
+	SetForPost(postID, userID int, bookIDs []int) error
+}
+
+// ReviewRepository stores the reviews members write of the books of the catalog,
+// at most one per member and book.
+type ReviewRepository interface {
+	ListForBook(bookID int, page PageRequest) (ReviewPage, error)
+	GetByID(reviewID int) (Review, error)
+	FindByAuthor(bookID, userID int) (Review, error)
+	Create(review Review) (int, error)
+	Update(review Review) error
+	Delete(reviewID int) error
+}
+
+// ShelfRepository stores the reading shelves of members: the books they want to
+// read, are reading and have finished, one shelf per member and book.
+type ShelfRepository interface {
+	List(userID int, shelf string) ([]ShelfEntry, error)
+	Get(userID, bookID int) (ShelfEntry, error)
+	Set(userID int, entry ShelfEntry) (ShelfEntry, error)
+	Remove(userID, bookID int) error
+	YearlyStats(userID int) ([]ReadingYear, error)
+}
+
+// ClubRepository stores the book clubs, their members and the requests to join
+// private clubs. The posts of a club are stored with the other posts.
+type ClubRepository interface {
+	List(userID int) ([]Club, error)
+	GetBySlug(slug string, userID int) (Club, error)
+	GetByID(clubID, userID int) (Club, error)
+	Create(club Club) (Club, error)
+	Update(club Club) error
+	Delete(clubID int) error
+	Members(clubID int, approved bool) ([]ClubMember, error)
+	Membership(clubID, userID int) (ClubMember, error)
+	Join(clubID, userID int) (ClubMember, error)
+	Approve(clubID, userID int) error
+	SetRole(clubID, userID int, role string) error
+	RemoveMember(clubID, userID int) error
+	ClubOf(targetType string, targetID int) (int, error)
+}
+
+// ScheduleRepository stores the reading schedules of clubs, made of sections that
+// unlock for discussion one after the other, and the sections each member has read.
+type ScheduleRepository interface {
+	List(clubID, userID int) ([]ClubSection, error)
+	Get(sectionID, userID int) (ClubSection, error)
+	Create(section ClubSection) (ClubSection, error)
+	Update(section ClubSection) error
+	Delete(sectionID int) error
+	SetRead(sectionID, userID int, read bool) error
+}
+
+// PollRepository stores the polls posts carry and the votes cast in them, one
+// vote per member and poll.
+type PollRepository interface {
+	Create(poll Poll) (int, error)
+	GetForPost(postID, userID int) (Poll, error)
+	Vote(pollID, userID int, optionIDs []int) error
+}
+
+// SearchRepository runs full-text searches over posts and comments.
+type SearchRepository interface {
+	Search(filter SearchFilter) ([]SearchResult, error)
+}
+
+// Store groups the repositories the handlers work with.
+type Store struct {
+	Users       UserRepository
+	Posts       PostRepository
+	Categories  CategoryRepository
+	Tags        TagRepository
+	Comments    CommentRepository
+	Sessions    SessionRepository
+	Reactions   ReactionRepository
+	Search      SearchRepository
+	Attachments AttachmentRepository
+	Books       BookRepository
+	Reviews     ReviewRepository
+	Shelves     ShelfRepository
+	Clubs       ClubRepository
+	Schedules   ScheduleRepository
+	Polls       PollRepository
+}
+
+// NewStore returns a Store whose repositories run SQL against the given database.
+// The same implementation serves SQLite and PostgreSQL; the connection's dialect
+// takes care of placeholders and error matching. Only full-text search has a
+// separate implementation per dialect.
+//
+// Parameters:
+//   - database: An open, migrated database connection.
+//
+// Returns:
+//   - *Store: The repositories backed by the database.
+func NewStore(database *db.DB) *Store {
+	return &Store{
+		Users:       &userRepository{db: database},
+		Posts:       &postRepository{db: database},
+		Categories:  &categoryRepository{db: database},
+		Tags:        &tagRepository{db: database},
+		Comments:    &commentRepository{db: database},
+		Sessions:    &sessionRepository{db: database},
+		Reactions:   &reactionRepository{db: database},
+		Search:      newSearchRepository(database),
+		Attachments: &attachmentRepository{db: database},
+		Books:       &bookRepository{db: database},
+		Reviews:     &reviewRepository{db: database},
+		Shelves:     &shelfRepository{db: database},
+		Clubs:       &clubRepository{db: database},
+		Schedules:   &scheduleRepository{db: database},
+		Polls:       &pollRepository{db: database},
+	}
+}
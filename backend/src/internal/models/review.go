@@ -0,0 +1,244 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"literary-lions/backend/src/internal/db"
+	"math"
+	"time"
+	"unicode/utf8"
+)
+
+// Review is the opinion of a member on a book of the catalog: a rating from 1
+// to 5 stars and an optional text, which may be flagged as a spoiler.
+type Review struct {
+	ID          int        `json:"id"`
+	BookID      int        `json:"book_id"`
+	UserID      int        `json:"user_id"`
+	Username    string     `json:"username"`
+	Rating      int        `json:"rating"`                 // Number of stars, from 1 to 5
+	Spoiler     bool       `json:"spoiler"`                // Whether the text reveals the plot
+	Content     string     `json:"content"`                // Markdown text, empty for a rating alone
+	ContentHTML string     `json:"content_html,omitempty"` // Content rendered from Markdown, filled in on the listings
+	CreatedAt   time.Time  `json:"created_at"`
+	EditedAt    *time.Time `json:"edited_at,omitempty"` // Time of the last edit, nil if never edited
+	Editable    bool       `json:"editable"`            // Whether the requesting user may edit and delete it, filled in on the listings
+}
+
+// RatingSummary aggregates the ratings a book received.
+type RatingSummary struct {
+	Average   float64 `json:"average"`   // Mean number of stars, rounded to two decimals, 0 without reviews
+	Count     int     `json:"count"`     // Number of reviews
+	Histogram [5]int  `json:"histogram"` // Number of reviews giving 1 to 5 stars, in that order
+}
+
+// maxReviewLength is the length of the longest review text.
+const maxReviewLength = 10000
+
+var (
+	// ErrInvalidReview is returned when a review has a rating out of range or a text that is too long.
+	ErrInvalidReview = fmt.Errorf("a review needs a rating from 1 to 5 stars and a text of at most %d characters", maxReviewLength)
+	// ErrReviewExists is returned when a member reviews a book they already reviewed.
+	ErrReviewExists = errors.New("you already reviewed this book, edit your review instead")
+)
+
+// validate checks the rating and the length of the text of the review.
+func (rv Review) validate() error {
+	if rv.Rating < 1 || rv.Rating > 5 || utf8.RuneCountInString(rv.Content) > maxReviewLength {
+		return ErrInvalidReview
+	}
+	return nil
+}
+
+// ratingsJoin joins the rating summary of each book aliased b to a query, as the
+// columns of the ratings table. Reviews of deleted members do not count.
+const ratingsJoin = `
+        LEFT JOIN (SELECT rv.book_id, COUNT(*) AS review_count, AVG(rv.rating) AS average_rating,
+                          SUM(CASE WHEN rv.rating = 1 THEN 1 ELSE 0 END) AS one_star,
+                          SUM(CASE WHEN rv.rating = 2 THEN 1 ELSE 0 END) AS two_stars,
+                          SUM(CASE WHEN rv.rating = 3 THEN 1 ELSE 0 END) AS three_stars,
+                          SUM(CASE WHEN rv.rating = 4 THEN 1 ELSE 0 END) AS four_stars,
+                          SUM(CASE WHEN rv.rating = 5 THEN 1 ELSE 0 END) AS five_stars
+                   FROM book_reviews rv INNER JOIN users u ON u.id = rv.user_id
+                   GROUP BY rv.book_id) ratings ON ratings.book_id = b.id`
+
+// ratingsColumns selects the rating summary joined by ratingsJoin, in the order of ratingsDest.
+const ratingsColumns = `COALESCE(ratings.review_count, 0), COALESCE(ratings.average_rating, 0),
+               COALESCE(ratings.one_star, 0), COALESCE(ratings.two_stars, 0), COALESCE(ratings.three_stars, 0),
+               COALESCE(ratings.four_stars, 0), COALESCE(ratings.five_stars, 0)`
+
+// ratingsDest returns the scan destinations of ratingsColumns.
+func (s *RatingSummary) ratingsDest() []interface{} {
+	return []interface{}{&s.Count, &s.Average, &s.Histogram[0], &s.Histogram[1], &s.Histogram[2], &s.Histogram[3], &s.Histogram[4]}
+}
+
+// round keeps two decimals of the average, which is all a listing shows.
+func (s *RatingSummary) round() {
+	s.Average = math.Round(s.Average*100) / 100
+}
+
+// reviewRepository implements ReviewRepository on top of a SQL database.
+type reviewRepository struct {
+	db *db.DB
+}
+
+// reviewSelect reads reviews together with the username of their author.
+const reviewSelect = `
+        SELECT rv.id, rv.book_id, rv.user_id, u.username, rv.rating, rv.spoiler, rv.content, rv.created_at, rv.edited_at
+        FROM book_reviews rv
+        INNER JOIN users u ON u.id = rv.user_id`
+
+// scanReview reads a row selected with reviewSelect.
+func scanReview(row interface{ Scan(...interface{}) error }) (Review, error) {
+	var review Review
+	var editedAt sql.NullTime
+	err := row.Scan(&review.ID, &review.BookID, &review.UserID, &review.Username, &review.Rating, &review.Spoiler,
+		&review.Content, &review.CreatedAt, &editedAt)
+	if editedAt.Valid {
+		review.EditedAt = &editedAt.Time
+	}
+	return review, err
+}
+
+// ListForBook retrieves one page of the reviews of a book.
+//
+// Parameters:
+//   - bookID: The ID of the book.
+//   - page: The sort order (newest by default, oldest, highest_rated or lowest_rated), cursor and size of the page.
+//
+// Returns:
+//   - ReviewPage: The reviews of the page and the cursor of the next one.
+//   - error: ErrInvalidSort or ErrInvalidCursor for a bad page request, or any query error; otherwise, nil.
+func (r *reviewRepository) ListForBook(bookID int, page PageRequest) (ReviewPage, error) {
+	pageQuery, err := resolvePage(page, reviewSorts, SortNewest)
+	if err != nil {
+		return ReviewPage{}, err
+	}
+
+	query, args := pageQuery.paginate(reviewSelect+" WHERE rv.book_id = ?", []interface{}{bookID})
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return ReviewPage{}, err
+	}
+	defer rows.Close()
+
+	reviews := []Review{}
+	for rows.Next() {
+		review, err := scanReview(rows)
+		if err != nil {
+			return ReviewPage{}, err
+		}
+		reviews = append(reviews, review)
+	}
+	if err := rows.Err(); err != nil {
+		return ReviewPage{}, err
+	}
+
+	// The extra row only tells that another page follows
+	result := ReviewPage{Reviews: reviews}
+	if len(reviews) > pageQuery.limit {
+		result.Reviews = reviews[:pageQuery.limit]
+		last := result.Reviews[pageQuery.limit-1]
+		result.Next = pageQuery.nextCursor(last.ID, last.Rating)
+	}
+	return result, nil
+}
+
+// GetByID retrieves a review.
+//
+// Parameters:
+//   - reviewID: The ID of the review.
+//
+// Returns:
+//   - Review: The review and its author.
+//   - error: ErrNotFound if no review has the ID, or any other query error; otherwise, nil.
+func (r *reviewRepository) GetByID(reviewID int) (Review, error) {
+	review, err := scanReview(r.db.QueryRow(reviewSelect+" WHERE rv.id = ?", reviewID))
+	if errors.Is(err, sql.ErrNoRows) {
+		return Review{}, ErrNotFound
+	}
+	return review, err
+}
+
+// FindByAuthor retrieves the review a member wrote of a book.
+//
+// Parameters:
+//   - bookID: The ID of the book.
+//   - userID: The ID of the member.
+//
+// Returns:
+//   - Review: The review.
+//   - error: ErrNotFound if the member has not reviewed the book, or any other query error; otherwise, nil.
+func (r *reviewRepository) FindByAuthor(bookID, userID int) (Review, error) {
+	review, err := scanReview(r.db.QueryRow(reviewSelect+" WHERE rv.book_id = ? AND rv.user_id = ?", bookID, userID))
+	if errors.Is(err, sql.ErrNoRows) {
+		return Review{}, ErrNotFound
+	}
+	return review, err
+}
+
+// Create adds the review of a member to an approved book.
+//
+// Parameters:
+//   - review: The book, the reviewer, the rating, the spoiler flag and the text.
+//
+// Returns:
+//   - int: The ID of the new review.
+//   - error: ErrInvalidReview, ErrNotFound if the book does not exist or waits for approval,
+//     ErrReviewExists if the member already reviewed it, or any other error; otherwise, nil.
+func (r *reviewRepository) Create(review Review) (int, error) {
+	if err := review.validate(); err != nil {
+		return 0, err
+	}
+
+	var approved bool
+	err := r.db.QueryRow("SELECT approved FROM books WHERE id = ?", review.BookID).Scan(&approved)
+	if errors.Is(err, sql.ErrNoRows) || (err == nil && !approved) {
+		return 0, ErrNotFound
+	} else if err != nil {
+		return 0, err
+	}
+
+	var id int
+	err = r.db.QueryRow("INSERT INTO book_reviews (book_id, user_id, rating, spoiler, content, created_at) VALUES (?, ?, ?, ?, ?, ?) RETURNING id",
+		review.BookID, review.UserID, review.Rating, review.Spoiler, review.Content, time.Now().UTC()).Scan(&id)
+	if r.db.Dialect.IsUniqueViolation(err, "user_id") {
+		return 0, ErrReviewExists
+	}
+	return id, err
+}
+
+// Update replaces the rating, spoiler flag and text of a review and records when it was edited.
+//
+// Parameters:
+//   - review: The review to update, identified by its ID.
+//
+// Returns:
+//   - error: ErrInvalidReview, ErrNotFound if no review has the ID, or any other error from the update; otherwise, nil.
+func (r *reviewRepository) Update(review Review) error {
+	if err := review.validate(); err != nil {
+		return err
+	}
+	result, err := r.db.Exec("UPDATE book_reviews SET rating = ?, spoiler = ?, content = ?, edited_at = ? WHERE id = ?",
+		review.Rating, review.Spoiler, review.Content, time.Now().UTC(), review.ID)
+	if err != nil {
+		return err
+	}
+	return requireAffected(result)
+}
+
+// Delete removes a review.
+//
+// Parameters:
+//   - reviewID: The ID of the review to delete.
+//
+// Returns:
+//   - error: ErrNotFound if no review has the ID, or any other error from the deletion; otherwise, nil.
+func (r *reviewRepository) Delete(reviewID int) error {
+	result, err := r.db.Exec("DELETE FROM book_reviews WHERE id = ?", reviewID)
+	if err != nil {
+		return err
+	}
+	return requireAffected(result)
+}
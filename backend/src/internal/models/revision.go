@@ -0,0 +1,259 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"literary-lions/backend/src/internal/db"
+	"strings"
+	"time"
+)
+
+// PostRevision is one version of the title, content and category of a post.
+type PostRevision struct {
+	PostID       int       `json:"post_id"`
+	Revision     int       `json:"revision"` // 1 for the post as first written, counting up with each edit
+	Title        string    `json:"title"`
+	Content      string    `json:"content"`
+	CategoryID   int       `json:"category_id"`             // 0 if the category has since been deleted
+	Category     string    `json:"category"`                // Name of the category
+	EditorID     int       `json:"editor_id"`               // ID of the user who wrote this version
+	Editor       string    `json:"editor"`                  // Username of the user who wrote this version
+	RestoredFrom int       `json:"restored_from,omitempty"` // Revision this version brought back, 0 for an ordinary edit
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// The operations of a DiffLine.
+const (
+	DiffEqual  = "equal"
+	DiffInsert = "insert"
+	DiffDelete = "delete"
+)
+
+// DiffLine is one line of a diff: kept as is, added or removed.
+type DiffLine struct {
+	Op   string `json:"op"`
+	Text string `json:"text"`
+}
+
+// RevisionDiff tells what changed between two revisions of a post.
+type RevisionDiff struct {
+	From            PostRevision `json:"from"` // Zero revision when comparing against nothing
+	To              PostRevision `json:"to"`
+	Title           []DiffLine   `json:"title"`   // The titles compared word by word
+	Content         []DiffLine   `json:"content"` // The contents compared line by line
+	CategoryChanged bool         `json:"category_changed"`
+}
+
+// revisionSelect reads the revisions of the posts outside the trash, together with
+// the name of their category and editor.
+const revisionSelect = `
+        SELECT pr.post_id, pr.revision, pr.title, pr.content, COALESCE(cat.id, 0), COALESCE(cat.name, ''), pr.editor_id, u.username, COALESCE(pr.restored_from, 0), pr.created_at
+        FROM post_revisions pr
+        INNER JOIN posts p ON p.id = pr.post_id AND p.deleted_at IS NULL AND p.status = 'published'
+        INNER JOIN users u ON u.id = pr.editor_id
+        LEFT JOIN categories cat ON cat.id = pr.category_id`
+
+// scanRevision reads a row selected with revisionSelect.
+func scanRevision(row interface{ Scan(...interface{}) error }) (PostRevision, error) {
+	var revision PostRevision
+	err := row.Scan(&revision.PostID, &revision.Revision, &revision.Title, &revision.Content, &revision.CategoryID, &revision.Category, &revision.EditorID, &revision.Editor, &revision.RestoredFrom, &revision.CreatedAt)
+	return revision, err
+}
+
+// addRevision records the current title, content and category of a post as its next revision.
+func addRevision(tx *db.Tx, postID, editorID, restoredFrom int) error {
+	var restored interface{}
+	if restoredFrom > 0 {
+		restored = restoredFrom
+	}
+	_, err := tx.Exec(`
+        INSERT INTO post_revisions (post_id, revision, title, content, category_id, editor_id, restored_from)
+        SELECT p.id, COALESCE((SELECT MAX(pr.revision) FROM post_revisions pr WHERE pr.post_id = p.id), 0) + 1, p.title, p.content, p.category_id, ?, ?
+        FROM posts p
+        WHERE p.id = ?`, editorID, restored, postID)
+	return err
+}
+
+// Revisions lists every version of a post, the latest first.
+// Parameters:
+//   - postID: The ID of the post.
+//
+// Returns:
+//   - []PostRevision: The revisions of the post.
+//   - error: ErrNotFound if no post has the ID or it is in the trash, or any other query error; otherwise, nil.
+func (r *postRepository) Revisions(postID int) ([]PostRevision, error) {
+	rows, err := r.db.Query(revisionSelect+" WHERE pr.post_id = ? ORDER BY pr.revision DESC", postID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	revisions := []PostRevision{}
+	for rows.Next() {
+		revision, err := scanRevision(rows)
+		if err != nil {
+			return nil, err
+		}
+		revisions = append(revisions, revision)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// Every post has at least its first revision
+	if len(revisions) == 0 {
+		return nil, ErrNotFound
+	}
+	return revisions, nil
+}
+
+// Revision retrieves one version of a post.
+// Parameters:
+//   - postID: The ID of the post.
+//   - revision: The number of the revision, starting at 1.
+//
+// Returns:
+//   - PostRevision: The revision.
+//   - error: ErrNotFound if the post or the revision does not exist or the post is in the trash, or any other query error; otherwise, nil.
+func (r *postRepository) Revision(postID, revision int) (PostRevision, error) {
+	result, err := scanRevision(r.db.QueryRow(revisionSelect+" WHERE pr.post_id = ? AND pr.revision = ?", postID, revision))
+	if errors.Is(err, sql.ErrNoRows) {
+		return PostRevision{}, ErrNotFound
+	}
+	return result, err
+}
+
+// RestoreRevision brings back the title, content and category of an older
+// version of a post. The history is kept: the restored version is recorded as
+// the next revision. A category deleted since is left as it currently is.
+// Parameters:
+//   - postID: The ID of the post.
+//   - revision: The number of the revision to restore.
+//   - editorID: The ID of the user restoring it.
+//
+// Returns:
+//   - error: ErrNotFound if the post or the revision does not exist or the post is in the trash, or any other error; otherwise, nil.
+func (r *postRepository) RestoreRevision(postID, revision, editorID int) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback() // No-op once the transaction is committed.
+
+	result, err := tx.Exec(`
+        UPDATE posts SET
+            title = (SELECT pr.title FROM post_revisions pr WHERE pr.post_id = posts.id AND pr.revision = ?),
+            content = (SELECT pr.content FROM post_revisions pr WHERE pr.post_id = posts.id AND pr.revision = ?),
+            category_id = COALESCE((SELECT cat.id FROM post_revisions pr INNER JOIN categories cat ON cat.id = pr.category_id WHERE pr.post_id = posts.id AND pr.revision = ?), category_id),
+            edited_at = CURRENT_TIMESTAMP
+        WHERE id = ? AND deleted_at IS NULL AND status = 'published' AND EXISTS (SELECT 1 FROM post_revisions pr WHERE pr.post_id = posts.id AND pr.revision = ?)`,
+		revision, revision, revision, postID, revision)
+	if err != nil {
+		return err
+	}
+	if err := requireAffected(result); err != nil {
+		return err
+	}
+	if err := addRevision(tx, postID, editorID, revision); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// DiffRevisions compares two revisions of a post: the titles word by word and
+// the contents line by line. A zero from revision compares against nothing, so
+// that everything in the to revision shows as added.
+func DiffRevisions(from, to PostRevision) RevisionDiff {
+	return RevisionDiff{
+		From:            from,
+		To:              to,
+		Title:           diffTokens(strings.Fields(from.Title), strings.Fields(to.Title)),
+		Content:         diffTokens(splitLines(from.Content), splitLines(to.Content)),
+		CategoryChanged: from.Revision > 0 && from.CategoryID != to.CategoryID,
+	}
+}
+
+// splitLines splits a text into its lines, with no line at all for an empty text.
+func splitLines(text string) []string {
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	if text == "" {
+		return nil
+	}
+	return strings.Split(text, "\n")
+}
+
+// maxDiffCells bounds the table diffTokens fills, the product of the numbers of
+// tokens left once the common start and end are set aside. Longer rewrites are
+// shown as the old tokens removed and the new ones added.
+const maxDiffCells = 1 << 20
+
+// diffTokens returns the shortest edit turning a into b, found through their
+// longest common subsequence. Removals come before the additions replacing them.
+// The common start and end are kept as is, and the rest compared only when it
+// fits in maxDiffCells, so that large revisions cannot hold up the server.
+func diffTokens(a, b []string) []DiffLine {
+	diff := []DiffLine{}
+	start := 0
+	for start < len(a) && start < len(b) && a[start] == b[start] {
+		diff = append(diff, DiffLine{Op: DiffEqual, Text: a[start]})
+		start++
+	}
+	end := 0
+	for end < len(a)-start && end < len(b)-start && a[len(a)-1-end] == b[len(b)-1-end] {
+		end++
+	}
+
+	diff = append(diff, diffMiddle(a[start:len(a)-end], b[start:len(b)-end])...)
+	for _, token := range a[len(a)-end:] {
+		diff = append(diff, DiffLine{Op: DiffEqual, Text: token})
+	}
+	return diff
+}
+
+// diffMiddle is diffTokens for tokens that differ at both ends.
+func diffMiddle(a, b []string) []DiffLine {
+	diff := []DiffLine{}
+	if len(a) > 0 && len(b) > 0 && len(a) > maxDiffCells/len(b) {
+		for _, token := range a {
+			diff = append(diff, DiffLine{Op: DiffDelete, Text: token})
+		}
+		for _, token := range b {
+			diff = append(diff, DiffLine{Op: DiffInsert, Text: token})
+		}
+		return diff
+	}
+
+	// common[i][j] is the length of the longest common subsequence of a[i:] and b[j:]
+	common := make([][]int, len(a)+1)
+	for i := range common {
+		common[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				common[i][j] = common[i+1][j+1] + 1
+			} else if common[i+1][j] >= common[i][j+1] {
+				common[i][j] = common[i+1][j]
+			} else {
+				common[i][j] = common[i][j+1]
+			}
+		}
+	}
+
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			diff = append(diff, DiffLine{Op: DiffEqual, Text: a[i]})
+			i++
+			j++
+		case i < len(a) && (j == len(b) || common[i+1][j] >= common[i][j+1]):
+			diff = append(diff, DiffLine{Op: DiffDelete, Text: a[i]})
+			i++
+		default:
+			diff = append(diff, DiffLine{Op: DiffInsert, Text: b[j]})
+			j++
+		}
+	}
+	return diff
+}
@@ -0,0 +1,252 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"literary-lions/backend/src/internal/db"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// MaxSectionTitleLength is the longest title a section of a reading schedule may have.
+const MaxSectionTitleLength = 200
+
+var (
+	// ErrInvalidSection is returned when a section has no title, an overlong one, or no unlock date.
+	ErrInvalidSection = errors.New("a section needs a title of at most 200 characters and an unlock date")
+	// ErrSectionLocked is returned when a post would discuss a section before it unlocks.
+	ErrSectionLocked = errors.New("this section is not open for discussion yet")
+	// ErrSectionInUse is returned when a section that posts discuss would be deleted.
+	ErrSectionInUse = errors.New("posts discuss this section")
+)
+
+// ClubSection is a part of the reading schedule of a club, such as a few chapters
+// of the book the club reads together. Posts discussing it are held back from the
+// members who have not read it.
+type ClubSection struct {
+	ID        int       `json:"id"`
+	ClubID    int       `json:"club_id"`
+	Title     string    `json:"title"`                // What the section covers, such as "Chapters 1-5"
+	BookID    int       `json:"book_id,omitempty"`    // The book of the catalog being read, 0 if none
+	Book      string    `json:"book,omitempty"`       // Title of the book
+	UnlocksAt time.Time `json:"unlocks_at"`           // When the section opens for discussion
+	Unlocked  bool      `json:"unlocked"`             // Whether the unlock date has passed
+	Read      bool      `json:"read"`                 // Whether the requesting member marked it read
+	PostCount int       `json:"post_count"`           // Number of posts discussing it, the trash aside
+	CreatedAt time.Time `json:"created_at,omitempty"` // Time the section was added to the schedule
+}
+
+// normalize trims the section and checks the result.
+func (s *ClubSection) normalize() error {
+	s.Title = strings.TrimSpace(s.Title)
+	if s.Title == "" || utf8.RuneCountInString(s.Title) > MaxSectionTitleLength || s.UnlocksAt.IsZero() {
+		return ErrInvalidSection
+	}
+	s.UnlocksAt = s.UnlocksAt.UTC()
+	return nil
+}
+
+// Lock holds back the content and images of a post discussing a section the
+// requesting member has not read. The title, author and counts stay visible.
+func (p *Post) Lock() {
+	p.Locked = true
+	p.Content, p.ContentHTML = "", ""
+	p.Attachments, p.Cover = []Attachment{}, nil
+}
+
+// scheduleRepository implements ScheduleRepository on top of a SQL database.
+type scheduleRepository struct {
+	db *db.DB
+}
+
+// sectionSelect reads sections together with the title of their book, whether the
+// member bound to its first placeholder read them, and their number of posts.
+const sectionSelect = `
+        SELECT s.id, s.club_id, s.title, COALESCE(s.book_id, 0), COALESCE(b.title, ''), s.unlocks_at, s.created_at,
+               EXISTS (SELECT 1 FROM club_section_reads r WHERE r.section_id = s.id AND r.user_id = ?) AS has_read,
+               (SELECT COUNT(*) FROM posts p WHERE p.section_id = s.id AND p.deleted_at IS NULL AND p.status = 'published') AS post_count
+        FROM club_sections s
+        LEFT JOIN books b ON b.id = s.book_id`
+
+// scanSection reads a row selected with sectionSelect.
+func scanSection(row interface{ Scan(...interface{}) error }) (ClubSection, error) {
+	var section ClubSection
+	err := row.Scan(&section.ID, &section.ClubID, &section.Title, &section.BookID, &section.Book, &section.UnlocksAt, &section.CreatedAt, &section.Read, &section.PostCount)
+	section.Unlocked = !section.UnlocksAt.After(time.Now())
+	return section, err
+}
+
+// List returns the reading schedule of a club, in the order the sections unlock.
+//
+// Parameters:
+//   - clubID: The ID of the club.
+//   - userID: The ID of the member whose reading is filled in, or 0 for guests.
+//
+// Returns:
+//   - []ClubSection: The sections of the schedule.
+//   - error: Any query error; otherwise, nil.
+func (r *scheduleRepository) List(clubID, userID int) ([]ClubSection, error) {
+	rows, err := r.db.Query(sectionSelect+" WHERE s.club_id = ? ORDER BY s.unlocks_at, s.id", userID, clubID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	sections := []ClubSection{}
+	for rows.Next() {
+		section, err := scanSection(rows)
+		if err != nil {
+			return nil, err
+		}
+		sections = append(sections, section)
+	}
+	return sections, rows.Err()
+}
+
+// Get returns a section of a reading schedule.
+//
+// Parameters:
+//   - sectionID: The ID of the section.
+//   - userID: The ID of the member whose reading is filled in, or 0 for guests.
+//
+// Returns:
+//   - ClubSection: The section.
+//   - error: ErrNotFound if no section has the ID, or any other query error; otherwise, nil.
+func (r *scheduleRepository) Get(sectionID, userID int) (ClubSection, error) {
+	section, err := scanSection(r.db.QueryRow(sectionSelect+" WHERE s.id = ?", userID, sectionID))
+	if errors.Is(err, sql.ErrNoRows) {
+		return ClubSection{}, ErrNotFound
+	}
+	return section, err
+}
+
+// Create adds a section to the reading schedule of a club.
+//
+// Parameters:
+//   - section: The club, title, unlock date and optional book of the new section.
+//
+// Returns:
+//   - ClubSection: The stored section.
+//   - error: ErrInvalidSection, ErrUnknownBook if the book is not in the catalog, or
+//     any other error from the insert; otherwise, nil.
+func (r *scheduleRepository) Create(section ClubSection) (ClubSection, error) {
+	if err := section.normalize(); err != nil {
+		return ClubSection{}, err
+	}
+	if err := r.checkBook(section.BookID); err != nil {
+		return ClubSection{}, err
+	}
+
+	var id int
+	err := r.db.QueryRow("INSERT INTO club_sections (club_id, title, book_id, unlocks_at) VALUES (?, ?, ?, ?) RETURNING id",
+		section.ClubID, section.Title, nullableID(section.BookID), section.UnlocksAt).Scan(&id)
+	if err != nil {
+		return ClubSection{}, err
+	}
+	return r.Get(id, 0)
+}
+
+// Update overwrites the title, unlock date and book of a section. Posts already
+// discussing it stay with it.
+//
+// Parameters:
+//   - section: The section to update, identified by its ID.
+//
+// Returns:
+//   - error: ErrNotFound, ErrInvalidSection, ErrUnknownBook, or any other error from the update; otherwise, nil.
+func (r *scheduleRepository) Update(section ClubSection) error {
+	if err := section.normalize(); err != nil {
+		return err
+	}
+	if err := r.checkBook(section.BookID); err != nil {
+		return err
+	}
+
+	result, err := r.db.Exec("UPDATE club_sections SET title = ?, book_id = ?, unlocks_at = ? WHERE id = ?",
+		section.Title, nullableID(section.BookID), section.UnlocksAt, section.ID)
+	if err != nil {
+		return err
+	}
+	return requireAffected(result)
+}
+
+// Delete removes a section from a reading schedule, along with the record of who
+// read it. Sections that posts discuss, in the trash or not, are kept, so that no
+// post loses the section hiding it.
+//
+// Parameters:
+//   - sectionID: The ID of the section to delete.
+//
+// Returns:
+//   - error: ErrSectionInUse, ErrNotFound if no section has the ID, or any other error; otherwise, nil.
+func (r *scheduleRepository) Delete(sectionID int) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback() // No-op once the transaction is committed.
+
+	var posts int
+	if err := tx.QueryRow("SELECT COUNT(*) FROM posts WHERE section_id = ?", sectionID).Scan(&posts); err != nil {
+		return err
+	}
+	if posts > 0 {
+		return ErrSectionInUse
+	}
+	if _, err := tx.Exec("DELETE FROM club_section_reads WHERE section_id = ?", sectionID); err != nil {
+		return err
+	}
+	result, err := tx.Exec("DELETE FROM club_sections WHERE id = ?", sectionID)
+	if err != nil {
+		return err
+	}
+	if err := requireAffected(result); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// SetRead records that a member has read a section, so that its posts are shown
+// to them, or takes it back. Marking a section read again keeps the first time.
+//
+// Parameters:
+//   - sectionID: The ID of the section.
+//   - userID: The ID of the member.
+//   - read: True to mark the section read, false to mark it unread.
+//
+// Returns:
+//   - error: ErrNotFound if no section has the ID when marking it read, or any other error; otherwise, nil.
+func (r *scheduleRepository) SetRead(sectionID, userID int, read bool) error {
+	if !read {
+		_, err := r.db.Exec("DELETE FROM club_section_reads WHERE section_id = ? AND user_id = ?", sectionID, userID)
+		return err
+	}
+
+	result, err := r.db.Exec(`
+        INSERT INTO club_section_reads (section_id, user_id)
+        SELECT id, ? FROM club_sections WHERE id = ?
+        ON CONFLICT (section_id, user_id) DO NOTHING`, userID, sectionID)
+	if err != nil {
+		return err
+	}
+	if affected, err := result.RowsAffected(); err != nil || affected > 0 {
+		return err
+	}
+	// Nothing was inserted: tell a section read before from a missing one.
+	_, err = r.Get(sectionID, userID)
+	return err
+}
+
+// checkBook checks that the book a section is about, if any, is in the catalog.
+func (r *scheduleRepository) checkBook(bookID int) error {
+	if bookID == 0 {
+		return nil
+	}
+	var approved bool
+	err := r.db.QueryRow("SELECT approved FROM books WHERE id = ?", bookID).Scan(&approved)
+	if errors.Is(err, sql.ErrNoRows) || (err == nil && !approved) {
+		return ErrUnknownBook
+	}
+	return err
+}
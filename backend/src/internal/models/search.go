@@ -0,0 +1,264 @@
+package models
+
+import (
+	"html"
+	"literary-lions/backend/src/internal/db"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Search result types.
+const (
+	SearchTypePost    = "post"
+	SearchTypeComment = "comment"
+)
+
+// Default and maximum number of hits returned by a search.
+const (
+	DefaultSearchLimit = 20
+	MaxSearchLimit     = 100
+)
+
+// Markers the database puts around matched terms in snippets. They are
+// control characters so they cannot clash with user content, and are turned
+// into <mark> tags once the rest of the snippet has been HTML-escaped.
+const (
+	highlightStart = "\x02"
+	highlightEnd   = "\x03"
+)
+
+// SearchFilter narrows a full-text search.
+type SearchFilter struct {
+	Query     string    // Words to look for; every word must match, the last one as a prefix
+	Type      string    // SearchTypePost, SearchTypeComment, or empty for both
+	Category  string    // Only hits on posts (or comments on posts) in this category, by slug or name
+	Tags      []string  // Only hits on posts (or comments on posts) carrying every one of these tags
+	StartDate time.Time // Only hits created on or after this time
+	EndDate   time.Time // Only hits created on or before this time
+	Limit     int       // Maximum number of hits, DefaultSearchLimit if zero
+}
+
+// SearchResult is a single ranked search hit on a post or a comment.
+type SearchResult struct {
+	Type      string    `json:"type"`       // SearchTypePost or SearchTypeComment
+	ID        int       `json:"id"`         // ID of the matching post or comment
+	PostID    int       `json:"post_id"`    // ID of the post, or of the post the comment belongs to
+	Title     string    `json:"title"`      // Title of that post
+	Snippet   string    `json:"snippet"`    // HTML-escaped excerpt with matches wrapped in <mark> tags
+	Username  string    `json:"username"`   // Author of the post or comment
+	CreatedAt time.Time `json:"created_at"` // When the post or comment was created
+	Rank      float64   `json:"rank"`       // Relevance score, higher is better
+}
+
+// newSearchRepository returns the search implementation for the database's dialect.
+func newSearchRepository(database *db.DB) SearchRepository {
+	if database.Dialect == db.Postgres {
+		return &postgresSearchRepository{db: database}
+	}
+	return &sqliteSearchRepository{db: database}
+}
+
+// sqliteSearchRepository implements SearchRepository with the FTS5 tables
+// created by the search_index migration.
+type sqliteSearchRepository struct {
+	db *db.DB
+}
+
+// Search returns the posts and comments matching the filter, best matches first.
+//
+// Parameters:
+//   - filter: The query and the optional restrictions on the hits.
+//
+// Returns:
+//   - []SearchResult: The matching hits, at most filter.Limit of them.
+//   - error: An error if the query fails; otherwise, nil.
+func (r *sqliteSearchRepository) Search(filter SearchFilter) ([]SearchResult, error) {
+	terms := searchTerms(filter.Query)
+	if len(terms) == 0 {
+		return []SearchResult{}, nil
+	}
+
+	// Quote every term so FTS5 operators typed by the user are matched literally,
+	// and let the last one match as a prefix for search-as-you-type
+	quoted := make([]string, len(terms))
+	for i, term := range terms {
+		quoted[i] = `"` + term + `"`
+	}
+	match := strings.Join(quoted, " ") + "*"
+
+	// bm25 scores are negative with the best match lowest, so they are negated to rank
+	var parts []string
+	var args []interface{}
+	if filter.Type != SearchTypeComment {
+		where, whereArgs := searchConditions(filter, "p")
+		parts = append(parts, `
+            SELECT 'post', p.id, p.id, p.title,
+                   snippet(posts_fts, -1, ?, ?, '…', 16), u.username, p.created_at,
+                   -bm25(posts_fts, 10.0, 1.0) AS score
+            FROM posts_fts
+            INNER JOIN posts p ON p.id = posts_fts.rowid
+            INNER JOIN users u ON u.id = p.user_id
+            WHERE posts_fts MATCH ?`+where)
+		args = append(args, highlightStart, highlightEnd, match)
+		args = append(args, whereArgs...)
+	}
+	if filter.Type != SearchTypePost {
+		where, whereArgs := searchConditions(filter, "c")
+		parts = append(parts, `
+            SELECT 'comment', c.id, c.post_id, p.title,
+                   snippet(comments_fts, 0, ?, ?, '…', 16), u.username, c.created_at,
+                   -bm25(comments_fts) AS score
+            FROM comments_fts
+            INNER JOIN comments c ON c.id = comments_fts.rowid
+            INNER JOIN posts p ON p.id = c.post_id
+            INNER JOIN users u ON u.id = c.user_id
+            WHERE comments_fts MATCH ?`+where)
+		args = append(args, highlightStart, highlightEnd, match)
+		args = append(args, whereArgs...)
+	}
+
+	query := strings.Join(parts, " UNION ALL ") + " ORDER BY score DESC LIMIT ?"
+	args = append(args, searchLimit(filter.Limit))
+
+	return querySearchResults(r.db, query, args...)
+}
+
+// postgresSearchRepository implements SearchRepository with the generated
+// tsvector columns created by the search_index migration.
+type postgresSearchRepository struct {
+	db *db.DB
+}
+
+// Search returns the posts and comments matching the filter, best matches first.
+//
+// Parameters:
+//   - filter: The query and the optional restrictions on the hits.
+//
+// Returns:
+//   - []SearchResult: The matching hits, at most filter.Limit of them.
+//   - error: An error if the query fails; otherwise, nil.
+func (r *postgresSearchRepository) Search(filter SearchFilter) ([]SearchResult, error) {
+	terms := searchTerms(filter.Query)
+	if len(terms) == 0 {
+		return []SearchResult{}, nil
+	}
+
+	// Terms only hold letters and digits, so they can be joined into a tsquery
+	// directly; the last one matches as a prefix like on SQLite
+	tsquery := strings.Join(terms, " & ") + ":*"
+	headline := "StartSel=" + highlightStart + ", StopSel=" + highlightEnd + ", MaxWords=24, MinWords=8"
+
+	var parts []string
+	var args []interface{}
+	if filter.Type != SearchTypeComment {
+		where, whereArgs := searchConditions(filter, "p")
+		parts = append(parts, `
+            SELECT 'post', p.id, p.id, p.title,
+                   ts_headline('english', p.title || ' — ' || p.content, q, ?), u.username, p.created_at,
+                   ts_rank(p.search_vector, q) AS score
+            FROM posts p
+            CROSS JOIN to_tsquery('english', ?) q
+            INNER JOIN users u ON u.id = p.user_id
+            WHERE p.search_vector @@ q`+where)
+		args = append(args, headline, tsquery)
+		args = append(args, whereArgs...)
+	}
+	if filter.Type != SearchTypePost {
+		where, whereArgs := searchConditions(filter, "c")
+		parts = append(parts, `
+            SELECT 'comment', c.id, c.post_id, p.title,
+                   ts_headline('english', c.content, q, ?), u.username, c.created_at,
+                   ts_rank(c.search_vector, q) AS score
+            FROM comments c
+            CROSS JOIN to_tsquery('english', ?) q
+            INNER JOIN posts p ON p.id = c.post_id
+            INNER JOIN users u ON u.id = c.user_id
+            WHERE c.search_vector @@ q`+where)
+		args = append(args, headline, tsquery)
+		args = append(args, whereArgs...)
+	}
+
+	query := strings.Join(parts, " UNION ALL ") + " ORDER BY score DESC LIMIT ?"
+	args = append(args, searchLimit(filter.Limit))
+
+	return querySearchResults(r.db, query, args...)
+}
+
+// searchTerms lowercases a user query and splits it into words made of letters
+// and digits only, which makes them safe to embed in FTS5 and tsquery syntax.
+func searchTerms(query string) []string {
+	return strings.FieldsFunc(strings.ToLower(query), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// searchConditions returns the extra WHERE clauses for the category, tag and date
+// restrictions of a filter, leaving out drafts, club posts and the hits in the trash. alias names the table
+// holding the hit's created_at; the category and tags are always read from the post, aliased p.
+func searchConditions(filter SearchFilter, alias string) (string, []interface{}) {
+	where := " AND p.deleted_at IS NULL AND p.status = 'published' AND " + forumPosts
+	if alias != "p" {
+		where += " AND " + alias + ".deleted_at IS NULL"
+	}
+	var args []interface{}
+
+	if filter.Category != "" {
+		where += " AND p.category_id IN (SELECT id FROM categories WHERE slug = ? OR LOWER(name) = LOWER(?))"
+		args = append(args, filter.Category, filter.Category)
+	}
+	tagFilters, tagArgs := tagConditions(filter.Tags)
+	for _, tagFilter := range tagFilters {
+		where += " AND " + tagFilter
+	}
+	args = append(args, tagArgs...)
+	if !filter.StartDate.IsZero() {
+		where += " AND " + alias + ".created_at >= ?"
+		args = append(args, filter.StartDate)
+	}
+	if !filter.EndDate.IsZero() {
+		where += " AND " + alias + ".created_at <= ?"
+		args = append(args, filter.EndDate)
+	}
+
+	return where, args
+}
+
+// searchLimit clamps a requested number of hits to the allowed range.
+func searchLimit(limit int) int {
+	if limit <= 0 {
+		return DefaultSearchLimit
+	}
+	if limit > MaxSearchLimit {
+		return MaxSearchLimit
+	}
+	return limit
+}
+
+// querySearchResults runs a search query and scans its rows, turning the raw
+// snippets into safe HTML.
+func querySearchResults(database *db.DB, query string, args ...interface{}) ([]SearchResult, error) {
+	rows, err := database.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	results := []SearchResult{}
+	for rows.Next() {
+		var result SearchResult
+		if err := rows.Scan(&result.Type, &result.ID, &result.PostID, &result.Title, &result.Snippet, &result.Username, &result.CreatedAt, &result.Rank); err != nil {
+			return nil, err
+		}
+		result.Snippet = highlightSnippet(result.Snippet)
+		results = append(results, result)
+	}
+
+	return results, rows.Err()
+}
+
+// highlightSnippet HTML-escapes a snippet and replaces the highlight markers with <mark> tags.
+func highlightSnippet(snippet string) string {
+	escaped := html.EscapeString(snippet)
+	return strings.NewReplacer(highlightStart, "<mark>", highlightEnd, "</mark>").Replace(escaped)
+}
@@ -2,25 +2,31 @@ package models
 
 import (
 	"errors"
+	"literary-lions/backend/src/internal/db"
 	"log"
 	"time"
 
 	"github.com/google/uuid"
 )
 
-// ValidateSession retrieves the user ID associated with a session UUID and checks if the session is still valid.
+// sessionRepository implements SessionRepository on top of a SQL database.
+type sessionRepository struct {
+	db *db.DB
+}
+
+// Validate retrieves the user ID associated with a session UUID and checks if the session is still valid.
 // Parameters:
 //   - sessionUUID: The session UUID to validate.
 //
 // Returns:
 //   - int: The user ID associated with the session if the session is valid.
 //   - error: An error if the session is invalid or if any other issue occurs; otherwise, nil.
-func ValidateSession(sessionUUID string) (int, error) {
+func (r *sessionRepository) Validate(sessionUUID string) (int, error) {
 	var userID int
 	var expiresAt time.Time
 
 	// Query to get the user ID and expiration time for the provided session UUID
-	err := db.QueryRow("SELECT user_id, expires_at FROM sessions WHERE uuid = ?", sessionUUID).Scan(&userID, &expiresAt)
+	err := r.db.QueryRow("SELECT user_id, expires_at FROM sessions WHERE uuid = ?", sessionUUID).Scan(&userID, &expiresAt)
 	if err != nil {
 		return 0, err // Return 0 and the error if the UUID is not found or there is a query error
 	}
@@ -33,36 +39,36 @@ func ValidateSession(sessionUUID string) (int, error) {
 	return userID, nil // Return the user ID and no error if the session is valid
 }
 
-// InvalidateSession removes a session from the database using the provided UUID.
+// Invalidate removes a session from the database using the provided UUID.
 // Parameters:
 //   - sessionUUID: The session UUID to invalidate.
 //
 // Returns:
 //   - error: An error if the deletion fails; otherwise, nil.
-func InvalidateSession(sessionUUID string) error {
+func (r *sessionRepository) Invalidate(sessionUUID string) error {
 	// Execute a DELETE statement to remove the session with the specified UUID
-	_, err := db.Exec("DELETE FROM sessions WHERE uuid = ?", sessionUUID)
+	_, err := r.db.Exec("DELETE FROM sessions WHERE uuid = ?", sessionUUID)
 	return err // Return any error encountered during the deletion
 }
 
-// CreateSession generates a new session UUID and inserts it into the database for the specified user.
+// Create generates a new session UUID and inserts it into the database for the specified user.
 // Parameters:
 //   - userID: The ID of the user for whom the session is created.
 //
 // Returns:
 //   - string: The generated session UUID if successful.
 //   - error: An error if UUID generation or insertion fails; otherwise, nil.
-
-func CreateSession(userID int) (string, error) {
+func (r *sessionRepository) Create(userID int) (string, error) {
 	sessionUUID, err := generateSessionUUID()
 	if err != nil {
 		log.Printf("Error generating session UUID: %v", err)
 		return "", err
 	}
 
-	expiresAt := time.Now().Add(24 * time.Hour)
+	// Store the expiry in UTC so it compares correctly on databases without time zones
+	expiresAt := time.Now().UTC().Add(24 * time.Hour)
 
-	_, err = db.Exec("INSERT INTO sessions (user_id, uuid, expires_at) VALUES (?, ?, ?)", userID, sessionUUID, expiresAt)
+	_, err = r.db.Exec("INSERT INTO sessions (user_id, uuid, expires_at) VALUES (?, ?, ?)", userID, sessionUUID, expiresAt)
 	if err != nil {
 		log.Printf("Error inserting session into database: %v", err)
 		return "", err
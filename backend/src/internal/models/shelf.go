@@ -0,0 +1,240 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"literary-lions/backend/src/internal/db"
+	"slices"
+	"time"
+)
+
+// Reading shelves, in the order they are shown.
+const (
+	// ShelfWantToRead holds the books a member means to read.
+	ShelfWantToRead = "want_to_read"
+	// ShelfReading holds the books a member is reading, with their progress.
+	ShelfReading = "reading"
+	// ShelfFinished holds the books a member has read.
+	ShelfFinished = "finished"
+)
+
+// Shelves lists the reading shelves in the order they are shown.
+var Shelves = []string{ShelfWantToRead, ShelfReading, ShelfFinished}
+
+// ErrInvalidShelfEntry is returned when a book is put on an unknown shelf, with a
+// progress out of 0 to 100 percent, or with a finish date in the future.
+var ErrInvalidShelfEntry = errors.New("a book goes on the want_to_read, reading or finished shelf, with a progress from 0 to 100 and a finish date that is not in the future")
+
+// ShelfEntry is a book on the reading shelves of a member.
+type ShelfEntry struct {
+	BookID     int         `json:"book_id"`
+	Title      string      `json:"title"`
+	Authors    []string    `json:"authors"`
+	Cover      *Attachment `json:"cover,omitempty"` // The cover of the book, nil for none
+	Shelf      string      `json:"shelf"`           // ShelfWantToRead, ShelfReading or ShelfFinished
+	Progress   int         `json:"progress"`        // Percentage of the book read, 100 once finished
+	StartedAt  *time.Time  `json:"started_at,omitempty"`
+	FinishedAt *time.Time  `json:"finished_at,omitempty"`
+	UpdatedAt  time.Time   `json:"updated_at"`
+}
+
+// ReadingYear counts the books a member finished in a year.
+type ReadingYear struct {
+	Year     int `json:"year"`
+	Finished int `json:"finished"`
+}
+
+// shelfRepository implements ShelfRepository on top of a SQL database.
+type shelfRepository struct {
+	db *db.DB
+}
+
+// shelfSelect reads shelf entries together with the title, authors and cover of their book.
+const shelfSelect = `
+        SELECT s.book_id, b.title, b.authors, COALESCE(a.id, 0), COALESCE(a.file_key, ''), COALESCE(a.thumbnail_key, ''),
+               COALESCE(a.width, 0), COALESCE(a.height, 0), s.shelf, s.progress, s.started_at, s.finished_at, s.updated_at
+        FROM reading_shelves s
+        INNER JOIN books b ON b.id = s.book_id
+        LEFT JOIN attachments a ON a.id = b.cover_id`
+
+// scanShelfEntry reads a row selected with shelfSelect.
+func scanShelfEntry(row interface{ Scan(...interface{}) error }) (ShelfEntry, error) {
+	var entry ShelfEntry
+	var authors string
+	var cover Attachment
+	var startedAt, finishedAt sql.NullTime
+	err := row.Scan(&entry.BookID, &entry.Title, &authors, &cover.ID, &cover.Key, &cover.ThumbnailKey, &cover.Width, &cover.Height,
+		&entry.Shelf, &entry.Progress, &startedAt, &finishedAt, &entry.UpdatedAt)
+	entry.Authors = splitFavorites(authors)
+	if cover.ID != 0 {
+		entry.Cover = &cover
+	}
+	if startedAt.Valid {
+		entry.StartedAt = &startedAt.Time
+	}
+	if finishedAt.Valid {
+		entry.FinishedAt = &finishedAt.Time
+	}
+	return entry, err
+}
+
+// List returns the books on the shelves of a member, the most recently finished or
+// changed first.
+//
+// Parameters:
+//   - userID: The ID of the member.
+//   - shelf: The shelf to list, or "" for all of them.
+//
+// Returns:
+//   - []ShelfEntry: The books on the shelves.
+//   - error: ErrInvalidShelfEntry for an unknown shelf, or any query error; otherwise, nil.
+func (r *shelfRepository) List(userID int, shelf string) ([]ShelfEntry, error) {
+	query := shelfSelect + " WHERE s.user_id = ?"
+	args := []interface{}{userID}
+	if shelf != "" {
+		if !slices.Contains(Shelves, shelf) {
+			return nil, ErrInvalidShelfEntry
+		}
+		query += " AND s.shelf = ?"
+		args = append(args, shelf)
+	}
+	rows, err := r.db.Query(query+" ORDER BY COALESCE(s.finished_at, s.updated_at) DESC, s.id DESC", args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := []ShelfEntry{}
+	for rows.Next() {
+		entry, err := scanShelfEntry(rows)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+	return entries, rows.Err()
+}
+
+// Get returns the shelf a member keeps a book on.
+//
+// Parameters:
+//   - userID: The ID of the member.
+//   - bookID: The ID of the book.
+//
+// Returns:
+//   - ShelfEntry: The book and its shelf.
+//   - error: ErrNotFound if the book is not on the shelves of the member, or any other query error; otherwise, nil.
+func (r *shelfRepository) Get(userID, bookID int) (ShelfEntry, error) {
+	entry, err := scanShelfEntry(r.db.QueryRow(shelfSelect+" WHERE s.user_id = ? AND s.book_id = ?", userID, bookID))
+	if errors.Is(err, sql.ErrNoRows) {
+		return ShelfEntry{}, ErrNotFound
+	}
+	return entry, err
+}
+
+// Set puts a book on a shelf of a member, moving it from the shelf it was on. Books
+// wanted to read have no progress; books being read keep when they were started;
+// finished books are read to 100% and dated, today unless the entry gives a date.
+//
+// Parameters:
+//   - userID: The ID of the member.
+//   - entry: The book, the shelf, the progress and, for finished books, the finish date.
+//
+// Returns:
+//   - ShelfEntry: The book as it is now shelved.
+//   - error: ErrInvalidShelfEntry, ErrUnknownBook for a book waiting for approval that
+//     another member added or one that does not exist, or any other error; otherwise, nil.
+func (r *shelfRepository) Set(userID int, entry ShelfEntry) (ShelfEntry, error) {
+	now := time.Now().UTC()
+	if !slices.Contains(Shelves, entry.Shelf) || entry.Progress < 0 || entry.Progress > 100 ||
+		(entry.FinishedAt != nil && entry.FinishedAt.After(now)) {
+		return ShelfEntry{}, ErrInvalidShelfEntry
+	}
+
+	// Books waiting for approval are kept to the member who added them
+	var allowed bool
+	err := r.db.QueryRow("SELECT EXISTS (SELECT 1 FROM books WHERE id = ? AND (approved OR submitted_by = ?))",
+		entry.BookID, userID).Scan(&allowed)
+	if err != nil {
+		return ShelfEntry{}, err
+	}
+	if !allowed {
+		return ShelfEntry{}, ErrUnknownBook
+	}
+
+	var startedAt, finishedAt interface{}
+	switch entry.Shelf {
+	case ShelfWantToRead:
+		entry.Progress = 0
+	case ShelfReading:
+		startedAt = now
+	case ShelfFinished:
+		entry.Progress = 100
+		finishedAt = now
+		if entry.FinishedAt != nil {
+			finishedAt = entry.FinishedAt.UTC()
+		}
+	}
+
+	// A book started earlier keeps its start date, unless it goes back to the wanted books
+	_, err = r.db.Exec(`
+        INSERT INTO reading_shelves (user_id, book_id, shelf, progress, started_at, finished_at, updated_at) VALUES (?, ?, ?, ?, ?, ?, ?)
+        ON CONFLICT (user_id, book_id) DO UPDATE
+        SET shelf = excluded.shelf, progress = excluded.progress, finished_at = excluded.finished_at, updated_at = excluded.updated_at,
+            started_at = CASE WHEN excluded.shelf = 'want_to_read' THEN NULL ELSE COALESCE(reading_shelves.started_at, excluded.started_at) END`,
+		userID, entry.BookID, entry.Shelf, entry.Progress, startedAt, finishedAt, now)
+	if err != nil {
+		return ShelfEntry{}, err
+	}
+	return r.Get(userID, entry.BookID)
+}
+
+// Remove takes a book off the shelves of a member.
+//
+// Parameters:
+//   - userID: The ID of the member.
+//   - bookID: The ID of the book.
+//
+// Returns:
+//   - error: ErrNotFound if the book is not on the shelves of the member, or any other error; otherwise, nil.
+func (r *shelfRepository) Remove(userID, bookID int) error {
+	result, err := r.db.Exec("DELETE FROM reading_shelves WHERE user_id = ? AND book_id = ?", userID, bookID)
+	if err != nil {
+		return err
+	}
+	return requireAffected(result)
+}
+
+// YearlyStats counts the books a member finished each year.
+//
+// Parameters:
+//   - userID: The ID of the member.
+//
+// Returns:
+//   - []ReadingYear: The years the member finished books in, the latest first.
+//   - error: Any query error; otherwise, nil.
+func (r *shelfRepository) YearlyStats(userID int) ([]ReadingYear, error) {
+	rows, err := r.db.Query(`
+        SELECT s.finished_at FROM reading_shelves s INNER JOIN books b ON b.id = s.book_id
+        WHERE s.user_id = ? AND s.shelf = ? AND s.finished_at IS NOT NULL
+        ORDER BY s.finished_at DESC`, userID, ShelfFinished)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	// Years are counted here, as SQLite and PostgreSQL extract them differently
+	years := []ReadingYear{}
+	for rows.Next() {
+		var finishedAt time.Time
+		if err := rows.Scan(&finishedAt); err != nil {
+			return nil, err
+		}
+		if n := len(years); n > 0 && years[n-1].Year == finishedAt.Year() {
+			years[n-1].Finished++
+		} else {
+			years = append(years, ReadingYear{Year: finishedAt.Year(), Finished: 1})
+		}
+	}
+	return years, rows.Err()
+}
@@ -0,0 +1,208 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"literary-lions/backend/src/internal/db"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+// countingDriverName is the driver registered by newCountingTestStore.
+const countingDriverName = "sqlite3_counting"
+
+var (
+	registerCountingDriver sync.Once
+	// queryCount counts the statements run through the counting driver.
+	queryCount atomic.Int64
+)
+
+// countingDriver wraps the SQLite driver and counts the statements it runs.
+type countingDriver struct {
+	sqlite3.SQLiteDriver
+}
+
+// Open opens a SQLite connection that counts its statements.
+func (d *countingDriver) Open(name string) (driver.Conn, error) {
+	conn, err := d.SQLiteDriver.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	return &countingConn{SQLiteConn: conn.(*sqlite3.SQLiteConn)}, nil
+}
+
+// countingConn counts every statement run on a SQLite connection, whether it
+// is executed directly or prepared first.
+type countingConn struct {
+	*sqlite3.SQLiteConn
+}
+
+func (c *countingConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	queryCount.Add(1)
+	return c.SQLiteConn.PrepareContext(ctx, query)
+}
+
+func (c *countingConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	queryCount.Add(1)
+	return c.SQLiteConn.ExecContext(ctx, query, args)
+}
+
+func (c *countingConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	queryCount.Add(1)
+	return c.SQLiteConn.QueryContext(ctx, query, args)
+}
+
+// newCountingTestStore returns a store backed by a freshly migrated SQLite file
+// whose statements are counted in queryCount.
+func newCountingTestStore(tb testing.TB) (*Store, *db.DB) {
+	tb.Helper()
+
+	registerCountingDriver.Do(func() {
+		sql.Register(countingDriverName, &countingDriver{})
+	})
+
+	conn, err := sql.Open(countingDriverName, filepath.Join(tb.TempDir(), "bench.db"))
+	if err != nil {
+		tb.Fatalf("open sqlite: %v", err)
+	}
+	database := &db.DB{DB: conn, Dialect: db.SQLite}
+	tb.Cleanup(func() { database.Close() })
+
+	migrateTestDatabase(tb, database)
+	return NewStore(database), database
+}
+
+// benchAuthors is the number of users seedAuthors creates, with IDs 1 to benchAuthors.
+const benchAuthors = 5
+
+// seedAuthors creates the users the seeded posts and comments are written by.
+func seedAuthors(tb testing.TB, database *db.DB) {
+	tb.Helper()
+
+	for i := 1; i <= benchAuthors; i++ {
+		_, err := database.Exec("INSERT INTO users (email, username, password, role) VALUES (?, ?, 'x', 'user')", fmt.Sprintf("reader%d@example.com", i), fmt.Sprintf("reader%d", i))
+		if err != nil {
+			tb.Fatalf("seed user: %v", err)
+		}
+	}
+}
+
+// seedDiscussion creates a post with the given number of comments spread over
+// the seeded authors, every other comment liked and every third one disliked.
+// It writes in one transaction to keep the setup fast, and returns the post ID.
+func seedDiscussion(tb testing.TB, database *db.DB, comments int) int {
+	tb.Helper()
+
+	tx, err := database.Begin()
+	if err != nil {
+		tb.Fatalf("begin: %v", err)
+	}
+	defer tx.Rollback()
+
+	var postID int
+	err = tx.QueryRow("INSERT INTO posts (user_id, title, content, category_id) VALUES (1, 'Benchmark', 'Discussion', 1) RETURNING id").Scan(&postID)
+	if err != nil {
+		tb.Fatalf("seed post: %v", err)
+	}
+
+	for i := 0; i < comments; i++ {
+		var commentID int
+		err := tx.QueryRow("INSERT INTO comments (post_id, user_id, content) VALUES (?, ?, ?) RETURNING id", postID, i%benchAuthors+1, fmt.Sprintf("Comment %d", i)).Scan(&commentID)
+		if err != nil {
+			tb.Fatalf("seed comment: %v", err)
+		}
+		if i%2 == 0 {
+			if _, err := tx.Exec("INSERT INTO reactions (user_id, target_type, target_id, reaction) VALUES (1, 'comment', ?, 'like')", commentID); err != nil {
+				tb.Fatalf("seed like: %v", err)
+			}
+		}
+		if i%3 == 0 {
+			if _, err := tx.Exec("INSERT INTO reactions (user_id, target_type, target_id, reaction) VALUES (2, 'comment', ?, 'dislike')", commentID); err != nil {
+				tb.Fatalf("seed dislike: %v", err)
+			}
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		tb.Fatalf("commit seed: %v", err)
+	}
+	return postID
+}
+
+// reportQueries reports the statements run per iteration since the timer was
+// reset and fails the benchmark if it differs from want.
+func reportQueries(b *testing.B, want int) {
+	b.Helper()
+
+	perOp := float64(queryCount.Load()) / float64(b.N)
+	b.ReportMetric(perOp, "queries/op")
+	if perOp != float64(want) {
+		b.Fatalf("ran %.2f queries per iteration, want %d", perOp, want)
+	}
+}
+
+// BenchmarkPostDetail loads a post and a full page of its comments, the reads
+// behind the post page, and checks that they take two queries however many
+// comments the post has.
+func BenchmarkPostDetail(b *testing.B) {
+	for _, comments := range []int{10, 50, MaxPageSize} {
+		b.Run(fmt.Sprintf("comments=%d", comments), func(b *testing.B) {
+			store, database := newCountingTestStore(b)
+			seedAuthors(b, database)
+			postID := seedDiscussion(b, database, comments)
+
+			queryCount.Store(0)
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if _, err := store.Posts.GetByID(postID); err != nil {
+					b.Fatalf("get post: %v", err)
+				}
+				page, err := store.Comments.GetByPostID(postID, PageRequest{Limit: MaxPageSize}, DefaultCommentMaxDepth)
+				if err != nil {
+					b.Fatalf("get comments: %v", err)
+				}
+				if len(page.Comments) != comments {
+					b.Fatalf("loaded %d comments, want %d", len(page.Comments), comments)
+				}
+			}
+			b.StopTimer()
+
+			reportQueries(b, 2)
+		})
+	}
+}
+
+// BenchmarkPostListing loads a full page of posts with their authors and counts,
+// and checks that it takes a single query however many posts the page holds.
+func BenchmarkPostListing(b *testing.B) {
+	for _, posts := range []int{10, 50, MaxPageSize} {
+		b.Run(fmt.Sprintf("posts=%d", posts), func(b *testing.B) {
+			store, database := newCountingTestStore(b)
+			seedAuthors(b, database)
+			for i := 0; i < posts; i++ {
+				seedDiscussion(b, database, 3)
+			}
+
+			queryCount.Store(0)
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				page, err := store.Posts.GetFiltered(PostFilter{}, PageRequest{Sort: SortMostCommented, Limit: MaxPageSize})
+				if err != nil {
+					b.Fatalf("list posts: %v", err)
+				}
+				if len(page.Posts) != posts {
+					b.Fatalf("loaded %d posts, want %d", len(page.Posts), posts)
+				}
+			}
+			b.StopTimer()
+
+			reportQueries(b, 1)
+		})
+	}
+}
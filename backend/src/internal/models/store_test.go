@@ -0,0 +1,3239 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"literary-lions/backend/src/internal/db"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// postgresDSNEnv names the variable pointing the suite at a disposable PostgreSQL database,
+// for example the one started with `docker compose --profile postgres up -d postgres`.
+const postgresDSNEnv = "TEST_POSTGRES_DSN"
+
+// newSQLiteTestStore returns a store backed by a freshly migrated SQLite file.
+func newSQLiteTestStore(tb testing.TB) *Store {
+	tb.Helper()
+
+	database, err := db.Open(filepath.Join(tb.TempDir(), "test.db"))
+	if err != nil {
+		tb.Fatalf("open sqlite: %v", err)
+	}
+	tb.Cleanup(func() { database.Close() })
+
+	migrateTestDatabase(tb, database)
+	return NewStore(database)
+}
+
+// newPostgresTestStore returns a store backed by the database in TEST_POSTGRES_DSN.
+// Every migration is rolled back and re-applied first, so the database must be disposable.
+func newPostgresTestStore(tb testing.TB) *Store {
+	tb.Helper()
+
+	dsn := os.Getenv(postgresDSNEnv)
+	if dsn == "" {
+		tb.Skipf("%s is not set; skipping PostgreSQL tests", postgresDSNEnv)
+	}
+
+	database, err := db.Open(dsn)
+	if err != nil {
+		tb.Fatalf("open postgres: %v", err)
+	}
+	tb.Cleanup(func() { database.Close() })
+
+	migrations, err := db.LoadMigrations(database.Dialect)
+	if err != nil {
+		tb.Fatalf("load migrations: %v", err)
+	}
+	if err := db.Rollback(database, len(migrations)); err != nil {
+		tb.Fatalf("reset postgres schema: %v", err)
+	}
+
+	migrateTestDatabase(tb, database)
+	return NewStore(database)
+}
+
+// migrateTestDatabase applies every migration to a test database. A driver built
+// without FTS5 fails the tests, with db.ErrNoFTS5 telling to rebuild with the
+// sqlite_fts5 tag, rather than skipping them, so that such a run cannot pass unnoticed.
+func migrateTestDatabase(tb testing.TB, database *db.DB) {
+	tb.Helper()
+
+	if err := db.Migrate(database); err != nil {
+		tb.Fatalf("migrate: %v", err)
+	}
+}
+
+func TestSQLiteStore(t *testing.T) {
+	runStoreSuite(t, newSQLiteTestStore)
+}
+
+func TestPostgresStore(t *testing.T) {
+	runStoreSuite(t, newPostgresTestStore)
+}
+
+// runStoreSuite runs the repository tests shared by every backend.
+// Each subtest gets its own store so the tests do not depend on each other.
+func runStoreSuite(t *testing.T, newStore func(testing.TB) *Store) {
+	tests := []struct {
+		name string
+		run  func(t *testing.T, store *Store)
+	}{
+		{"Users", testUsers},
+		{"UserActivity", testUserActivity},
+		{"Sessions", testSessions},
+		{"Posts", testPosts},
+		{"PostRevisions", testPostRevisions},
+		{"Drafts", testDrafts},
+		{"Attachments", testAttachments},
+		{"Books", testBooks},
+		{"Reviews", testReviews},
+		{"Shelves", testShelves},
+		{"Clubs", testClubs},
+		{"Schedules", testSchedules},
+		{"Polls", testPolls},
+		{"Categories", testCategories},
+		{"Tags", testTags},
+		{"Comments", testComments},
+		{"CommentReplies", testCommentReplies},
+		{"CommentEdits", testCommentEdits},
+		{"CommentTrash", testCommentTrash},
+		{"Reactions", testReactions},
+		{"ReactionSet", testReactionSet},
+		{"Search", testSearch},
+		{"Pagination", testPagination},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			test.run(t, newStore(t))
+		})
+	}
+}
+
+// mustRegister registers a user and returns its stored record.
+func mustRegister(t *testing.T, store *Store, username string) *User {
+	t.Helper()
+
+	email := username + "@example.com"
+	if err := store.Users.Register(email, username, "secret123"); err != nil {
+		t.Fatalf("register %s: %v", username, err)
+	}
+	user, err := store.Users.FindByEmail(email)
+	if err != nil {
+		t.Fatalf("find %s: %v", username, err)
+	}
+	return user
+}
+
+// mustFindCategory returns the category with the given slug or name.
+func mustFindCategory(t *testing.T, store *Store, ref string) Category {
+	t.Helper()
+
+	category, err := store.Categories.Find(ref)
+	if err != nil {
+		t.Fatalf("find category %q: %v", ref, err)
+	}
+	return category
+}
+
+// mustCreatePost creates a post in the named category with the given tags and
+// returns it as read back from the store.
+func mustCreatePost(t *testing.T, store *Store, userID int, title, category string, tags ...string) Post {
+	t.Helper()
+
+	postID, err := store.Posts.Create(userID, title, "Content of "+title, mustFindCategory(t, store, category).ID, tags)
+	if err != nil {
+		t.Fatalf("create post %q: %v", title, err)
+	}
+	post, err := store.Posts.GetByID(postID)
+	if err != nil || post.ID != postID {
+		t.Fatalf("post %q not found after creation: %v", title, err)
+	}
+	return post
+}
+
+func testUsers(t *testing.T, store *Store) {
+	alice := mustRegister(t, store, "alice")
+	if alice.Role != "user" {
+		t.Errorf("role = %q, want user", alice.Role)
+	}
+
+	err := store.Users.Register("other@example.com", "alice", "secret123")
+	if err == nil || !strings.Contains(err.Error(), "username already exists") {
+		t.Errorf("duplicate username error = %v", err)
+	}
+	err = store.Users.Register("ALICE@example.com", "alice2", "secret123")
+	if err != nil {
+		t.Fatalf("register with different email case: %v", err)
+	}
+	err = store.Users.Register("alice@example.com", "alice3", "secret123")
+	if err == nil || !strings.Contains(err.Error(), "email already exists") {
+		t.Errorf("duplicate email error = %v", err)
+	}
+
+	if _, err := store.Users.Authenticate("alice@example.com", "wrong"); err == nil {
+		t.Error("authenticate with wrong password succeeded")
+	}
+	if user, err := store.Users.Authenticate("alice@example.com", "secret123"); err != nil || user.ID != alice.ID {
+		t.Errorf("authenticate = %+v, %v", user, err)
+	}
+
+	if time.Since(alice.CreatedAt) > time.Minute {
+		t.Errorf("joined at %v, want now", alice.CreatedAt)
+	}
+	if len(alice.FavoriteBooks) != 0 || alice.Bio != "" || alice.ProfilePic != "" {
+		t.Errorf("new profile = %+v, want an empty one", alice)
+	}
+
+	profile := User{
+		ID:             alice.ID,
+		Email:          "alice@lions.test",
+		Username:       "lioness",
+		Bio:            "  Reading since the savannah.  ",
+		Location:       " Nairobi,\n Kenya ",
+		FavoriteBooks:  []string{"Born Free", " ", "The  Jungle Book", "born free"},
+		FavoriteGenres: []string{"Nature writing"},
+	}
+	if err := store.Users.UpdateProfile(profile); err != nil {
+		t.Fatalf("update profile: %v", err)
+	}
+	user, err := store.Users.GetByID(alice.ID)
+	if err != nil || user.Username != "lioness" || user.Email != "alice@lions.test" {
+		t.Errorf("after profile update = %+v, %v", user, err)
+	}
+	if user.Bio != "Reading since the savannah." || user.Location != "Nairobi, Kenya" ||
+		fmt.Sprint(user.FavoriteBooks) != "[Born Free The Jungle Book]" || fmt.Sprint(user.FavoriteGenres) != "[Nature writing]" {
+		t.Errorf("profile = %q, %q, %q, %q", user.Bio, user.Location, user.FavoriteBooks, user.FavoriteGenres)
+	}
+
+	tooMany := make([]string, MaxFavorites+1)
+	for i := range tooMany {
+		tooMany[i] = fmt.Sprint("Book ", i)
+	}
+	for name, invalid := range map[string]User{
+		"long bio":           {Bio: strings.Repeat("a", MaxBioLength+1)},
+		"long location":      {Location: strings.Repeat("a", 101)},
+		"long favorite":      {FavoriteGenres: []string{strings.Repeat("a", 101)}},
+		"too many favorites": {FavoriteBooks: tooMany},
+	} {
+		invalid.ID, invalid.Email, invalid.Username = alice.ID, user.Email, user.Username
+		if err := store.Users.UpdateProfile(invalid); !errors.Is(err, ErrInvalidProfile) {
+			t.Errorf("%s: update profile = %v, want ErrInvalidProfile", name, err)
+		}
+	}
+
+	if err := store.Users.SetAvatar(alice.ID, "avatar.png"); err != nil {
+		t.Fatalf("set avatar: %v", err)
+	}
+	if err := store.Users.SetAvatar(alice.ID+1000, "avatar.png"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("set avatar of missing user = %v, want ErrNotFound", err)
+	}
+	if keys, err := store.Attachments.Keys(); err != nil || fmt.Sprint(keys) != "[avatar.png]" {
+		t.Errorf("Keys = %v, %v; want the avatar", keys, err)
+	}
+
+	user, err = store.Users.GetByUsername("lioness")
+	if err != nil || user.ID != alice.ID || user.ProfilePic != "avatar.png" || user.Bio != "Reading since the savannah." {
+		t.Errorf("get by username = %+v, %v", user, err)
+	}
+	if _, err := store.Users.GetByUsername("alice"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("get by old username = %v, want ErrNotFound", err)
+	}
+
+	if err := store.Users.UpdateRole(alice.ID, "admin"); err != nil {
+		t.Fatalf("update role: %v", err)
+	}
+	if err := store.Users.UpdateRole(alice.ID+1000, "admin"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("update role of missing user = %v, want ErrNotFound", err)
+	}
+
+	users, err := store.Users.List()
+	if err != nil || len(users) != 2 {
+		t.Fatalf("list users = %d, %v", len(users), err)
+	}
+
+	if err := store.Users.Delete(alice.ID); err != nil {
+		t.Fatalf("delete user: %v", err)
+	}
+	if _, err := store.Users.GetByID(alice.ID); err == nil {
+		t.Error("deleted user still found")
+	}
+}
+
+func testUserActivity(t *testing.T, store *Store) {
+	alice := mustRegister(t, store, "alice")
+	bob := mustRegister(t, store, "bob")
+	dune := mustCreatePost(t, store, alice.ID, "Dune", "Science")
+	emma := mustCreatePost(t, store, bob.ID, "Emma", "Random")
+	gone := mustCreatePost(t, store, alice.ID, "Gone", "Random")
+	if _, err := store.Posts.SaveDraft(alice.ID, "Draft", "Content", dune.CategoryID, nil, nil); err != nil {
+		t.Fatalf("save draft: %v", err)
+	}
+	for _, content := range []string{"First", "Second", "Third"} {
+		if err := store.Comments.Create(emma.ID, alice.ID, content); err != nil {
+			t.Fatalf("comment: %v", err)
+		}
+	}
+	if err := store.Comments.Create(gone.ID, alice.ID, "On a deleted post"); err != nil {
+		t.Fatalf("comment: %v", err)
+	}
+	if err := store.Comments.Create(dune.ID, bob.ID, "Not by alice"); err != nil {
+		t.Fatalf("comment: %v", err)
+	}
+
+	// The comments of alice, newest first, a page at a time
+	page, err := store.Comments.GetByUser(alice.ID, PageRequest{Limit: 2})
+	if err != nil || len(page.Comments) != 2 || page.Next == "" {
+		t.Fatalf("first page of comments = %+v, %v", page, err)
+	}
+	first, second := page.Comments[0], page.Comments[1]
+	if first.Content != "On a deleted post" || first.PostTitle != "Gone" || second.Content != "Third" || second.PostTitle != "Emma" {
+		t.Errorf("first page = %q on %q, %q on %q", first.Content, first.PostTitle, second.Content, second.PostTitle)
+	}
+	page, err = store.Comments.GetByUser(alice.ID, PageRequest{Limit: 2, Cursor: page.Next})
+	if err != nil || len(page.Comments) != 2 || page.Next != "" || page.Comments[1].Content != "First" {
+		t.Errorf("second page of comments = %+v, %v", page, err)
+	}
+
+	// Reactions of others to what alice wrote; her own are left out
+	set := ReactionSet{"insightful", "funny"}
+	if err := store.Reactions.TogglePostLike(bob.ID, dune.ID); err != nil {
+		t.Fatalf("like: %v", err)
+	}
+	if err := store.Reactions.TogglePostDislike(alice.ID, gone.ID); err != nil {
+		t.Fatalf("dislike: %v", err)
+	}
+	if err := store.Reactions.ToggleCommentLike(bob.ID, first.ID); err != nil {
+		t.Fatalf("like comment: %v", err)
+	}
+	if err := store.Reactions.AddReaction(bob.ID, ReactionTargetComment, second.ID, "funny"); err != nil {
+		t.Fatalf("react: %v", err)
+	}
+	if err := store.Reactions.AddReaction(bob.ID, ReactionTargetPost, dune.ID, "funny"); err != nil {
+		t.Fatalf("react: %v", err)
+	}
+
+	activity, err := store.Users.Activity(alice.ID, set)
+	if err != nil {
+		t.Fatalf("activity: %v", err)
+	}
+	want := Activity{Posts: 2, Comments: 4, Likes: 2, Dislikes: 0, Reactions: []ReactionCount{{"insightful", 0, false}, {"funny", 2, false}}}
+	if !reflect.DeepEqual(activity, want) {
+		t.Errorf("activity = %+v, want %+v", activity, want)
+	}
+
+	// The trash takes a post out of the counts, along with the comments on it and their reactions
+	if err := store.Posts.Delete(gone.ID); err != nil {
+		t.Fatalf("delete post: %v", err)
+	}
+	activity, err = store.Users.Activity(alice.ID, set)
+	want = Activity{Posts: 1, Comments: 3, Likes: 1, Dislikes: 0, Reactions: []ReactionCount{{"insightful", 0, false}, {"funny", 2, false}}}
+	if err != nil || !reflect.DeepEqual(activity, want) {
+		t.Errorf("activity after deletion = %+v, %v; want %+v", activity, err, want)
+	}
+	if page, err := store.Comments.GetByUser(alice.ID, PageRequest{}); err != nil || len(page.Comments) != 3 {
+		t.Errorf("comments after deletion = %d, %v; want 3", len(page.Comments), err)
+	}
+
+	// Liked posts can be kept private
+	alice.HideLikedPosts = true
+	if err := store.Users.UpdateProfile(*alice); err != nil {
+		t.Fatalf("update profile: %v", err)
+	}
+	if user, err := store.Users.GetByUsername("alice"); err != nil || !user.HideLikedPosts {
+		t.Errorf("after hiding liked posts = %+v, %v", user, err)
+	}
+}
+
+func testSessions(t *testing.T, store *Store) {
+	alice := mustRegister(t, store, "alice")
+
+	token, err := store.Sessions.Create(alice.ID)
+	if err != nil {
+		t.Fatalf("create session: %v", err)
+	}
+	if userID, err := store.Sessions.Validate(token); err != nil || userID != alice.ID {
+		t.Errorf("validate = %d, %v; want %d", userID, err, alice.ID)
+	}
+
+	if err := store.Sessions.Invalidate(token); err != nil {
+		t.Fatalf("invalidate: %v", err)
+	}
+	if _, err := store.Sessions.Validate(token); err == nil {
+		t.Error("invalidated session is still valid")
+	}
+}
+
+func testPosts(t *testing.T, store *Store) {
+	alice := mustRegister(t, store, "alice")
+	bob := mustRegister(t, store, "bob")
+
+	dune := mustCreatePost(t, store, alice.ID, "Dune Reread", "Science")
+	mustCreatePost(t, store, bob.ID, "Sports Biographies", "Sport")
+
+	post, err := store.Posts.GetByID(dune.ID)
+	if err != nil || post.Title != "Dune Reread" || post.Username != "alice" {
+		t.Errorf("get post = %+v, %v", post, err)
+	}
+
+	filtered, err := store.Posts.GetFiltered(PostFilter{Category: "science"}, PageRequest{})
+	if err != nil || len(filtered.Posts) != 1 || filtered.Posts[0].ID != dune.ID {
+		t.Errorf("filter by category = %+v, %v", filtered, err)
+	}
+	filtered, err = store.Posts.GetFiltered(PostFilter{StartDate: time.Now().UTC().Add(-time.Hour)}, PageRequest{})
+	if err != nil || len(filtered.Posts) != 2 || filtered.Next != "" {
+		t.Errorf("filter by date = %d posts, %v", len(filtered.Posts), err)
+	}
+
+	filtered, err = store.Posts.GetFiltered(PostFilter{Category: "sci"}, PageRequest{})
+	if err != nil || len(filtered.Posts) != 0 {
+		t.Errorf("filter by part of a category = %+v, %v, want no posts", filtered, err)
+	}
+
+	news := mustFindCategory(t, store, "news")
+	if err := store.Posts.Update(dune.ID, alice.ID, "Dune Messiah", "Sequel talk", news.ID, nil); err != nil {
+		t.Fatalf("update post: %v", err)
+	}
+	post, _ = store.Posts.GetByID(dune.ID)
+	if post.Title != "Dune Messiah" || post.Category != "News" || post.CategorySlug != "news" || post.CategoryID != news.ID {
+		t.Errorf("after update = %+v", post)
+	}
+	if err := store.Posts.Update(dune.ID+1000, alice.ID, "x", "y", news.ID, nil); !errors.Is(err, ErrNotFound) {
+		t.Errorf("update missing post = %v, want ErrNotFound", err)
+	}
+
+	// Deleting a post moves it to the trash, and purging it takes its comments and the reactions to both along
+	if err := store.Comments.Create(dune.ID, bob.ID, "The spice must flow"); err != nil {
+		t.Fatalf("create comment: %v", err)
+	}
+	comments, err := store.Comments.GetByPostID(dune.ID, PageRequest{}, DefaultCommentMaxDepth)
+	if err != nil || len(comments.Comments) != 1 {
+		t.Fatalf("comments = %+v, %v", comments, err)
+	}
+	commentID := comments.Comments[0].ID
+	if _, err := store.Comments.Reply(commentID, alice.ID, "Fear is the mind-killer", DefaultCommentMaxDepth); err != nil {
+		t.Fatalf("reply: %v", err)
+	}
+	if err := store.Reactions.ToggleCommentLike(alice.ID, commentID); err != nil {
+		t.Fatalf("like comment: %v", err)
+	}
+	if err := store.Reactions.TogglePostLike(bob.ID, dune.ID); err != nil {
+		t.Fatalf("like post: %v", err)
+	}
+
+	if err := store.Posts.Delete(dune.ID); err != nil {
+		t.Fatalf("delete post: %v", err)
+	}
+	if _, err := store.Posts.GetByID(dune.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("get deleted post = %v, want ErrNotFound", err)
+	}
+	if err := store.Posts.Delete(dune.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("second delete = %v, want ErrNotFound", err)
+	}
+	if _, err := store.Comments.GetByID(commentID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("comment of deleted post = %v, want ErrNotFound", err)
+	}
+	if err := store.Comments.Create(dune.ID, bob.ID, "Too late"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("comment on deleted post = %v, want ErrNotFound", err)
+	}
+	if page, err := store.Posts.GetByUser(alice.ID, PageRequest{}); err != nil || len(page.Posts) != 0 {
+		t.Errorf("posts of alice = %+v, %v, want none outside the trash", page.Posts, err)
+	}
+	if trashed, err := store.Posts.Trashed(alice.ID, time.Now().Add(-time.Hour)); err != nil || len(trashed) != 1 || trashed[0].DeletedAt == nil {
+		t.Errorf("trash = %+v, %v", trashed, err)
+	}
+
+	// Restoring brings the post back with its comments and reactions
+	if err := store.Posts.Restore(dune.ID, time.Now().Add(time.Hour)); !errors.Is(err, ErrNotFound) {
+		t.Errorf("restore past the retention = %v, want ErrNotFound", err)
+	}
+	if err := store.Posts.Restore(dune.ID, time.Now().Add(-time.Hour)); err != nil {
+		t.Fatalf("restore post: %v", err)
+	}
+	if post, err := store.Posts.GetByID(dune.ID); err != nil || post.Likes != 1 || post.CommentCount != 2 {
+		t.Errorf("restored post = %+v, %v", post, err)
+	}
+	if err := store.Posts.Restore(dune.ID, time.Now().Add(-time.Hour)); !errors.Is(err, ErrNotFound) {
+		t.Errorf("restore live post = %v, want ErrNotFound", err)
+	}
+
+	if err := store.Posts.Delete(dune.ID); err != nil {
+		t.Fatalf("delete post again: %v", err)
+	}
+	if purged, err := store.Posts.Purge(time.Now().Add(-time.Hour)); err != nil || purged != 0 {
+		t.Errorf("purge within the retention = %d, %v, want 0", purged, err)
+	}
+	if purged, err := store.Posts.Purge(time.Now().Add(time.Hour)); err != nil || purged != 1 {
+		t.Errorf("purge past the retention = %d, %v, want 1", purged, err)
+	}
+	if err := store.Posts.Restore(dune.ID, time.Time{}); !errors.Is(err, ErrNotFound) {
+		t.Errorf("restore purged post = %v, want ErrNotFound", err)
+	}
+	if likes, err := store.Reactions.CountCommentLikes(commentID); err != nil || likes != 0 {
+		t.Errorf("likes on comment of deleted post = %d, %v, want 0", likes, err)
+	}
+	if likes, err := store.Reactions.CountPostLikes(dune.ID); err != nil || likes != 0 {
+		t.Errorf("likes on deleted post = %d, %v, want 0", likes, err)
+	}
+}
+
+func testPostRevisions(t *testing.T, store *Store) {
+	alice := mustRegister(t, store, "alice")
+	admin := mustRegister(t, store, "admin")
+	post := mustCreatePost(t, store, alice.ID, "Dune", "Science", "frank-herbert")
+	news := mustFindCategory(t, store, "news")
+
+	// numbers lists the revision numbers of the post, the latest first
+	numbers := func() string {
+		t.Helper()
+		revisions, err := store.Posts.Revisions(post.ID)
+		if err != nil {
+			t.Fatalf("revisions: %v", err)
+		}
+		var numbers []string
+		for _, revision := range revisions {
+			numbers = append(numbers, fmt.Sprint(revision.Revision))
+		}
+		return strings.Join(numbers, ",")
+	}
+
+	if post.EditedAt != nil || numbers() != "1" {
+		t.Errorf("new post = %+v with revisions %s, want unedited with revision 1", post, numbers())
+	}
+	first, err := store.Posts.Revision(post.ID, 1)
+	if err != nil || first.Title != "Dune" || first.Content != "Content of Dune" || first.Category != "Science" || first.Editor != "alice" {
+		t.Errorf("first revision = %+v, %v", first, err)
+	}
+
+	// Changing only the tags is not an edit
+	if err := store.Posts.Update(post.ID, alice.ID, "Dune", "Content of Dune", post.CategoryID, []string{"sci-fi"}); err != nil {
+		t.Fatalf("update tags: %v", err)
+	}
+	if got, _ := store.Posts.GetByID(post.ID); got.EditedAt != nil || numbers() != "1" {
+		t.Errorf("after tag change = %+v with revisions %s", got, numbers())
+	}
+
+	if err := store.Posts.Update(post.ID, admin.ID, "Dune Messiah", "Content of Dune\nThe sequel", news.ID, nil); err != nil {
+		t.Fatalf("update post: %v", err)
+	}
+	if got, _ := store.Posts.GetByID(post.ID); got.EditedAt == nil || numbers() != "2,1" {
+		t.Errorf("after edit = %+v with revisions %s", got, numbers())
+	}
+	second, err := store.Posts.Revision(post.ID, 2)
+	if err != nil || second.Title != "Dune Messiah" || second.Category != "News" || second.Editor != "admin" {
+		t.Errorf("second revision = %+v, %v", second, err)
+	}
+	if _, err := store.Posts.Revision(post.ID, 3); !errors.Is(err, ErrNotFound) {
+		t.Errorf("missing revision = %v, want ErrNotFound", err)
+	}
+	if _, err := store.Posts.Revisions(post.ID + 1000); !errors.Is(err, ErrNotFound) {
+		t.Errorf("revisions of missing post = %v, want ErrNotFound", err)
+	}
+
+	diff := DiffRevisions(first, second)
+	wantTitle := []DiffLine{{DiffEqual, "Dune"}, {DiffInsert, "Messiah"}}
+	wantContent := []DiffLine{{DiffEqual, "Content of Dune"}, {DiffInsert, "The sequel"}}
+	if !reflect.DeepEqual(diff.Title, wantTitle) || !reflect.DeepEqual(diff.Content, wantContent) || !diff.CategoryChanged {
+		t.Errorf("diff = %+v", diff)
+	}
+	if added := DiffRevisions(PostRevision{}, first); len(added.Content) != 1 || added.Content[0].Op != DiffInsert || added.CategoryChanged {
+		t.Errorf("diff of first revision = %+v", added)
+	}
+
+	// Restoring records the old version as a new revision
+	if err := store.Posts.RestoreRevision(post.ID, 1, admin.ID); err != nil {
+		t.Fatalf("restore: %v", err)
+	}
+	got, _ := store.Posts.GetByID(post.ID)
+	if got.Title != "Dune" || got.Content != "Content of Dune" || got.Category != "Science" || numbers() != "3,2,1" {
+		t.Errorf("after restore = %+v with revisions %s", got, numbers())
+	}
+	if restored, _ := store.Posts.Revision(post.ID, 3); restored.RestoredFrom != 1 || restored.EditorID != admin.ID {
+		t.Errorf("restored revision = %+v", restored)
+	}
+	if err := store.Posts.RestoreRevision(post.ID, 9, admin.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("restore missing revision = %v, want ErrNotFound", err)
+	}
+
+	// The history goes along with the post
+	if err := store.Posts.Delete(post.ID); err != nil {
+		t.Fatalf("delete post: %v", err)
+	}
+	if _, err := store.Posts.Revisions(post.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("revisions of deleted post = %v, want ErrNotFound", err)
+	}
+}
+
+func testDrafts(t *testing.T, store *Store) {
+	alice := mustRegister(t, store, "alice")
+	bob := mustRegister(t, store, "bob")
+	random := mustFindCategory(t, store, "random")
+
+	draftID, err := store.Posts.SaveDraft(alice.ID, "Half-written", "Content", random.ID, []string{"Drafting"}, nil)
+	if err != nil {
+		t.Fatalf("save draft: %v", err)
+	}
+	publishAt := time.Now().Add(time.Hour)
+	scheduledID, err := store.Posts.SaveDraft(alice.ID, "Scheduled", "Content", random.ID, nil, &publishAt)
+	if err != nil {
+		t.Fatalf("save scheduled post: %v", err)
+	}
+
+	// Drafts stay off the forum
+	for _, id := range []int{draftID, scheduledID} {
+		if _, err := store.Posts.GetByID(id); err != ErrNotFound {
+			t.Errorf("GetByID(draft %d) error = %v, want ErrNotFound", id, err)
+		}
+		if err := store.Posts.Update(id, alice.ID, "Edited", "Content", random.ID, nil); err != ErrNotFound {
+			t.Errorf("Update(draft %d) error = %v, want ErrNotFound", id, err)
+		}
+		if err := store.Comments.Create(id, bob.ID, "First!"); err != ErrNotFound {
+			t.Errorf("comment on draft %d error = %v, want ErrNotFound", id, err)
+		}
+		if err := store.Reactions.AddReaction(bob.ID, ReactionTargetPost, id, "love"); err != ErrNotFound {
+			t.Errorf("react to draft %d error = %v, want ErrNotFound", id, err)
+		}
+		if err := store.Reactions.TogglePostLike(bob.ID, id); err != ErrNotFound {
+			t.Errorf("like draft %d error = %v, want ErrNotFound", id, err)
+		}
+	}
+	if page, err := store.Posts.GetByUser(alice.ID, PageRequest{}); err != nil || len(page.Posts) != 0 {
+		t.Errorf("GetByUser = %+v, %v; want no posts", page, err)
+	}
+	if category := mustFindCategory(t, store, "random"); category.PostCount != 0 {
+		t.Errorf("category post count = %d, want 0", category.PostCount)
+	}
+	if tags, err := store.Tags.Autocomplete("draft", 10); err != nil || len(tags) != 0 {
+		t.Errorf("Autocomplete(draft) = %+v, %v; want none", tags, err)
+	}
+	if _, err := store.Tags.GetBySlug("drafting"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("GetBySlug(drafting) = %v, want ErrNotFound for a tag found only on a draft", err)
+	}
+
+	// Only their author lists them, the most recently started first
+	drafts, err := store.Posts.Drafts(alice.ID)
+	if err != nil || len(drafts) != 2 || drafts[0].ID != scheduledID || drafts[1].ID != draftID {
+		t.Fatalf("Drafts(alice) = %+v, %v", drafts, err)
+	}
+	if drafts[0].Status != PostScheduled || drafts[0].PublishAt == nil || drafts[1].Status != PostDraft || drafts[1].PublishAt != nil {
+		t.Errorf("draft statuses = %+v", drafts)
+	}
+	if drafts, err := store.Posts.Drafts(bob.ID); err != nil || len(drafts) != 0 {
+		t.Errorf("Drafts(bob) = %+v, %v; want none", drafts, err)
+	}
+
+	// Scheduling a draft and taking it back
+	if err := store.Posts.UpdateDraft(draftID, "Nearly done", "More content", random.ID, []string{"Drafting", "Essays"}, &publishAt); err != nil {
+		t.Fatalf("update draft: %v", err)
+	}
+	if draft, err := store.Posts.GetDraft(draftID); err != nil || draft.Status != PostScheduled || draft.Title != "Nearly done" || strings.Join(draft.Tags, ",") != "drafting,essays" {
+		t.Errorf("scheduled draft = %+v, %v", draft, err)
+	}
+	if err := store.Posts.UpdateDraft(draftID, "Nearly done", "More content", random.ID, []string{"Drafting"}, nil); err != nil {
+		t.Fatalf("unschedule draft: %v", err)
+	}
+	if draft, err := store.Posts.GetDraft(draftID); err != nil || draft.Status != PostDraft || draft.PublishAt != nil {
+		t.Errorf("unscheduled draft = %+v, %v", draft, err)
+	}
+
+	// A published draft keeps its ID, so that links to it keep working, but lists
+	// after the posts published before it
+	later := mustCreatePost(t, store, bob.ID, "Published meanwhile", "Random")
+	postID, err := store.Posts.Publish(draftID)
+	if err != nil || postID != draftID || postID >= later.ID {
+		t.Fatalf("Publish = %d, %v; want %d, before %d", postID, err, draftID, later.ID)
+	}
+	if _, err := store.Posts.GetDraft(draftID); err != ErrNotFound {
+		t.Errorf("GetDraft(published) error = %v, want ErrNotFound", err)
+	}
+	post, err := store.Posts.GetByID(postID)
+	if err != nil || post.Status != PostPublished || post.Title != "Nearly done" || strings.Join(post.Tags, ",") != "drafting" || post.EditedAt != nil {
+		t.Errorf("published post = %+v, %v", post, err)
+	}
+	if revisions, err := store.Posts.Revisions(postID); err != nil || len(revisions) != 1 || revisions[0].Title != "Nearly done" {
+		t.Errorf("revisions of published post = %+v, %v; want the first one", revisions, err)
+	}
+	if at := post.CreatedAt; time.Since(at) > time.Minute {
+		t.Errorf("published draft created at %v, want the time it went live", at)
+	}
+	if page, _ := store.Posts.GetFiltered(PostFilter{}, PageRequest{}); len(page.Posts) != 2 || page.Posts[0].ID != postID {
+		t.Errorf("newest posts = %+v, want the published draft first", page.Posts)
+	}
+	if page, _ := store.Posts.GetFiltered(PostFilter{}, PageRequest{Sort: SortOldest}); len(page.Posts) != 2 || page.Posts[1].ID != postID {
+		t.Errorf("oldest posts = %+v, want the published draft last", page.Posts)
+	}
+	first, err := store.Posts.GetFiltered(PostFilter{}, PageRequest{Limit: 1})
+	if err != nil || len(first.Posts) != 1 || first.Posts[0].ID != postID || first.Next == "" {
+		t.Fatalf("first page = %+v, %v; want the published draft", first, err)
+	}
+	if next, err := store.Posts.GetFiltered(PostFilter{}, PageRequest{Cursor: first.Next, Limit: 1}); err != nil || len(next.Posts) != 1 || next.Posts[0].ID != later.ID || next.Next != "" {
+		t.Errorf("second page = %+v, %v; want the post published meanwhile", next, err)
+	}
+	if _, err := store.Posts.Publish(postID); err != ErrNotFound {
+		t.Errorf("Publish(published) error = %v, want ErrNotFound", err)
+	}
+
+	// The scheduler publishes scheduled posts once they are due, dated as scheduled
+	if published, err := store.Posts.PublishDue(time.Now()); err != nil || published != 0 {
+		t.Errorf("PublishDue(now) = %d, %v; want 0", published, err)
+	}
+	if published, err := store.Posts.PublishDue(publishAt.Add(time.Minute)); err != nil || published != 1 {
+		t.Fatalf("PublishDue(due) = %d, %v; want 1", published, err)
+	}
+	page, err := store.Posts.GetByUser(alice.ID, PageRequest{})
+	if err != nil || len(page.Posts) != 2 || page.Posts[0].Title != "Scheduled" {
+		t.Fatalf("GetByUser after publishing = %+v, %v", page, err)
+	}
+	if at := page.Posts[0].CreatedAt; at.Sub(publishAt) > time.Second || publishAt.Sub(at) > time.Second {
+		t.Errorf("scheduled post created at %v, want %v", at, publishAt)
+	}
+	if drafts, err := store.Posts.Drafts(alice.ID); err != nil || len(drafts) != 0 {
+		t.Errorf("Drafts after publishing = %+v, %v; want none", drafts, err)
+	}
+
+	// Deleted drafts go to the trash and come back as drafts
+	trashedID, err := store.Posts.SaveDraft(alice.ID, "Abandoned", "Content", random.ID, nil, nil)
+	if err != nil {
+		t.Fatalf("save draft: %v", err)
+	}
+	if err := store.Posts.Delete(trashedID); err != nil {
+		t.Fatalf("delete draft: %v", err)
+	}
+	if _, err := store.Posts.GetDraft(trashedID); err != ErrNotFound {
+		t.Errorf("GetDraft(trashed) error = %v, want ErrNotFound", err)
+	}
+	if _, err := store.Posts.Publish(trashedID); err != ErrNotFound {
+		t.Errorf("Publish(trashed) error = %v, want ErrNotFound", err)
+	}
+	if err := store.Posts.Restore(trashedID, time.Now().Add(-time.Hour)); err != nil {
+		t.Fatalf("restore draft: %v", err)
+	}
+	if drafts, err := store.Posts.Drafts(alice.ID); err != nil || len(drafts) != 1 || drafts[0].ID != trashedID {
+		t.Errorf("Drafts after restoring = %+v, %v", drafts, err)
+	}
+}
+
+func testAttachments(t *testing.T, store *Store) {
+	alice := mustRegister(t, store, "alice")
+	bob := mustRegister(t, store, "bob")
+	upload := func(userID int, name string) int {
+		t.Helper()
+		id, err := store.Attachments.Create(Attachment{UserID: userID, Key: strings.Repeat(name, 64) + ".jpg", ThumbnailKey: strings.Repeat(name, 64) + ".png", ContentType: "image/jpeg", Size: 100, Width: 640, Height: 480})
+		if err != nil {
+			t.Fatalf("create attachment: %v", err)
+		}
+		return id
+	}
+	cover, photo, other := upload(alice.ID, "a"), upload(alice.ID, "b"), upload(bob.ID, "c")
+	post := mustCreatePost(t, store, alice.ID, "Dune", "Random")
+
+	// Images are shown in the given order, and the first is the cover unless another is picked
+	if err := store.Attachments.SetForPost(post.ID, alice.ID, []int{photo, cover}, 0); err != nil {
+		t.Fatalf("set attachments: %v", err)
+	}
+	got, err := store.Posts.GetByID(post.ID)
+	if err != nil || len(got.Attachments) != 2 || got.Attachments[0].ID != photo || got.Cover == nil || got.Cover.ID != photo {
+		t.Fatalf("post with images = %+v, %v", got, err)
+	}
+	if err := store.Attachments.SetForPost(post.ID, alice.ID, []int{photo, cover, photo}, cover); err != nil {
+		t.Fatalf("set cover: %v", err)
+	}
+	page, err := store.Posts.GetFiltered(PostFilter{}, PageRequest{})
+	if err != nil || len(page.Posts) != 1 || len(page.Posts[0].Attachments) != 2 || page.Posts[0].Cover == nil || page.Posts[0].Cover.ID != cover {
+		t.Fatalf("listed post = %+v, %v", page.Posts, err)
+	}
+	if a := page.Posts[0].Cover; a.Key != strings.Repeat("a", 64)+".jpg" || a.ThumbnailKey != strings.Repeat("a", 64)+".png" || a.Width != 640 || a.Height != 480 {
+		t.Errorf("cover = %+v", a)
+	}
+
+	// Only the uploader can put an image on a post, but others keep those already there
+	tests := []struct {
+		name   string
+		userID int
+		ids    []int
+		cover  int
+		want   error
+	}{
+		{"image of someone else", alice.ID, []int{cover, other}, 0, ErrInvalidAttachment},
+		{"unknown image", alice.ID, []int{9999}, 0, ErrInvalidAttachment},
+		{"cover not among the images", alice.ID, []int{cover}, photo, ErrInvalidAttachment},
+		{"too many images", alice.ID, []int{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11}, 0, ErrTooManyAttachments},
+		{"images of the author kept by someone else", bob.ID, []int{cover, other}, other, nil},
+	}
+	for _, test := range tests {
+		if err := store.Attachments.SetForPost(post.ID, test.userID, test.ids, test.cover); err != test.want {
+			t.Errorf("%s: error = %v, want %v", test.name, err, test.want)
+		}
+	}
+
+	// Drafts carry their images once published
+	draftID, err := store.Posts.SaveDraft(alice.ID, "Draft", "Content", post.CategoryID, nil, nil)
+	if err != nil {
+		t.Fatalf("save draft: %v", err)
+	}
+	if err := store.Attachments.SetForPost(draftID, alice.ID, []int{photo}, 0); err != nil {
+		t.Fatalf("set draft attachments: %v", err)
+	}
+	publishedID, err := store.Posts.Publish(draftID)
+	if err != nil {
+		t.Fatalf("publish draft: %v", err)
+	}
+	if published, err := store.Posts.GetByID(publishedID); err != nil || len(published.Attachments) != 1 || published.Cover == nil || published.Cover.ID != photo {
+		t.Errorf("published draft = %+v, %v", published, err)
+	}
+
+	// Unused uploads are purged after the grace period, once their posts are gone
+	if purged, err := store.Attachments.PurgeUnused(time.Now().Add(-time.Hour)); err != nil || purged != 0 {
+		t.Errorf("PurgeUnused(an hour ago) = %d, %v; want 0", purged, err)
+	}
+	if err := store.Attachments.SetForPost(publishedID, alice.ID, nil, 0); err != nil {
+		t.Fatalf("remove attachments: %v", err)
+	}
+	if err := store.Posts.Delete(post.ID); err != nil {
+		t.Fatalf("delete post: %v", err)
+	}
+	if purged, err := store.Attachments.PurgeUnused(time.Now().Add(time.Hour)); err != nil || purged != 1 {
+		t.Errorf("PurgeUnused with the post in the trash = %d, %v; want only the removed image", purged, err)
+	}
+	if _, err := store.Posts.Purge(time.Now().Add(time.Hour)); err != nil {
+		t.Fatalf("purge posts: %v", err)
+	}
+	if purged, err := store.Attachments.PurgeUnused(time.Now().Add(time.Hour)); err != nil || purged != 2 {
+		t.Errorf("PurgeUnused after the purge = %d, %v; want 2", purged, err)
+	}
+	if keys, err := store.Attachments.Keys(); err != nil || len(keys) != 0 {
+		t.Errorf("Keys = %v, %v; want none", keys, err)
+	}
+}
+
+func testBooks(t *testing.T, store *Store) {
+	alice := mustRegister(t, store, "alice")
+	bob := mustRegister(t, store, "bob")
+	cover, err := store.Attachments.Create(Attachment{UserID: alice.ID, Key: strings.Repeat("a", 64) + ".jpg", ThumbnailKey: strings.Repeat("a", 64) + ".png", ContentType: "image/jpeg", Size: 100, Width: 400, Height: 600})
+	if err != nil {
+		t.Fatalf("create attachment: %v", err)
+	}
+
+	// Titles, authors and ISBNs are cleaned up before they are stored
+	dune, err := store.Books.Create(Book{Title: "  Dune ", Authors: []string{" Frank  Herbert"}, ISBN: "978-0-441-01359-3", Year: 1965, CoverID: cover, Approved: true, SubmittedBy: alice.ID})
+	if err != nil {
+		t.Fatalf("create book: %v", err)
+	}
+	if dune.Title != "Dune" || fmt.Sprint(dune.Authors) != "[Frank Herbert]" || dune.ISBN != "9780441013593" || dune.Cover == nil || dune.Cover.ID != cover || !dune.Approved {
+		t.Errorf("created book = %+v", dune)
+	}
+	emma, err := store.Books.Create(Book{Title: "Emma", Authors: []string{"Jane Austen"}, ISBN: "0-8044-2957-X", SubmittedBy: bob.ID})
+	if err != nil || emma.ISBN != "080442957X" || emma.Approved {
+		t.Fatalf("create pending book = %+v, %v", emma, err)
+	}
+
+	tests := []struct {
+		name string
+		book Book
+		want error
+	}{
+		{"no title", Book{Title: " "}, ErrInvalidBook},
+		{"future year", Book{Title: "Tomorrow", Year: time.Now().Year() + 2}, ErrInvalidBook},
+		{"wrong check digit", Book{Title: "Dune", ISBN: "9780441013594"}, ErrInvalidISBN},
+		{"wrong length", Book{Title: "Dune", ISBN: "12345"}, ErrInvalidISBN},
+		{"same ISBN", Book{Title: "Dune Messiah", ISBN: "9780441013593"}, ErrBookExists},
+		{"cover of someone else", Book{Title: "Persuasion", CoverID: cover, SubmittedBy: bob.ID}, ErrInvalidAttachment},
+	}
+	for _, test := range tests {
+		if _, err := store.Books.Create(test.book); err != test.want {
+			t.Errorf("%s: error = %v, want %v", test.name, err, test.want)
+		}
+	}
+
+	// Pending books stay out of the catalog until approved
+	titles := func(filter BookFilter) string {
+		t.Helper()
+		books, err := store.Books.List(filter)
+		if err != nil {
+			t.Fatalf("list books: %v", err)
+		}
+		var titles []string
+		for _, book := range books {
+			titles = append(titles, book.Title)
+		}
+		return strings.Join(titles, ",")
+	}
+	if got := titles(BookFilter{}); got != "Dune" {
+		t.Errorf("catalog = %q, want Dune", got)
+	}
+	if got := titles(BookFilter{Pending: true}); got != "Emma" {
+		t.Errorf("pending books = %q, want Emma", got)
+	}
+	if err := store.Books.Approve(emma.ID); err != nil {
+		t.Fatalf("approve book: %v", err)
+	}
+	if err := store.Books.Approve(9999); err != ErrNotFound {
+		t.Errorf("approve unknown book: error = %v, want ErrNotFound", err)
+	}
+	if got := titles(BookFilter{Query: "austen"}); got != "Emma" {
+		t.Errorf("books by austen = %q, want Emma", got)
+	}
+	if got := titles(BookFilter{Limit: 1}); got != "Dune" {
+		t.Errorf("first book = %q, want Dune", got)
+	}
+	if _, err := store.Books.Update(Book{ID: emma.ID, Title: "Emma", ISBN: dune.ISBN}); err != ErrBookExists {
+		t.Errorf("update to a taken ISBN: error = %v, want ErrBookExists", err)
+	}
+	if updated, err := store.Books.Update(Book{ID: emma.ID, Title: "Emma", Authors: []string{"Jane Austen"}, Year: 1815, CoverID: cover}); err != nil || updated.Year != 1815 || updated.ISBN != "" || updated.CoverID != cover {
+		t.Errorf("updated book = %+v, %v", updated, err)
+	}
+
+	// Posts show their books in the given order, listings included
+	post := mustCreatePost(t, store, alice.ID, "Desert and drawing rooms", "Random")
+	if err := store.Books.SetForPost(post.ID, alice.ID, []int{emma.ID, dune.ID, emma.ID}); err != nil {
+		t.Fatalf("set books: %v", err)
+	}
+	got, err := store.Posts.GetByID(post.ID)
+	if err != nil || fmt.Sprint(got.Books) != fmt.Sprint([]BookRef{{emma.ID, "Emma"}, {dune.ID, "Dune"}}) {
+		t.Fatalf("post books = %+v, %v", got.Books, err)
+	}
+	page, err := store.Posts.GetFiltered(PostFilter{Book: dune.ID}, PageRequest{})
+	if err != nil || len(page.Posts) != 1 || len(page.Posts[0].Books) != 2 || page.Posts[0].Books[1].Title != "Dune" {
+		t.Fatalf("posts about Dune = %+v, %v", page.Posts, err)
+	}
+	if book, err := store.Books.GetByID(dune.ID); err != nil || book.PostCount != 1 {
+		t.Errorf("Dune = %+v, %v; want one post", book, err)
+	}
+
+	// Pending books can only be linked by their submitter, or kept on posts already linking them
+	pending, err := store.Books.Create(Book{Title: "Persuasion", SubmittedBy: bob.ID})
+	if err != nil {
+		t.Fatalf("create pending book: %v", err)
+	}
+	linkTests := []struct {
+		name   string
+		postID int
+		userID int
+		ids    []int
+		want   error
+	}{
+		{"pending book of someone else", post.ID, alice.ID, []int{pending.ID}, ErrUnknownBook},
+		{"unknown book", post.ID, alice.ID, []int{9999}, ErrUnknownBook},
+		{"too many books", post.ID, alice.ID, []int{1, 2, 3, 4, 5, 6}, ErrTooManyBooks},
+		{"own pending book", post.ID, bob.ID, []int{dune.ID, pending.ID}, nil},
+		{"pending book already linked", post.ID, alice.ID, []int{pending.ID, dune.ID}, nil},
+	}
+	for _, test := range linkTests {
+		if err := store.Books.SetForPost(test.postID, test.userID, test.ids); err != test.want {
+			t.Errorf("%s: error = %v, want %v", test.name, err, test.want)
+		}
+	}
+
+	// Drafts carry their books once published
+	draftID, err := store.Posts.SaveDraft(alice.ID, "Draft", "Content", post.CategoryID, nil, nil)
+	if err != nil {
+		t.Fatalf("save draft: %v", err)
+	}
+	if err := store.Books.SetForPost(draftID, alice.ID, []int{emma.ID}); err != nil {
+		t.Fatalf("set draft books: %v", err)
+	}
+	if page, err := store.Posts.GetFiltered(PostFilter{Book: emma.ID}, PageRequest{}); err != nil || len(page.Posts) != 0 {
+		t.Errorf("posts about Emma before publishing = %+v, %v; want none", page.Posts, err)
+	}
+	publishedID, err := store.Posts.Publish(draftID)
+	if err != nil {
+		t.Fatalf("publish draft: %v", err)
+	}
+	if published, err := store.Posts.GetByID(publishedID); err != nil || len(published.Books) != 1 || published.Books[0].ID != emma.ID {
+		t.Errorf("published draft = %+v, %v", published, err)
+	}
+
+	// Deleting a book unlinks it, and covers are not purged while books show them
+	if err := store.Books.Delete(dune.ID); err != nil {
+		t.Fatalf("delete book: %v", err)
+	}
+	if err := store.Books.Delete(dune.ID); err != ErrNotFound {
+		t.Errorf("delete book twice: error = %v, want ErrNotFound", err)
+	}
+	if got, err := store.Posts.GetByID(post.ID); err != nil || len(got.Books) != 1 || got.Books[0].ID != pending.ID {
+		t.Errorf("post books after deleting Dune = %+v, %v", got.Books, err)
+	}
+	if purged, err := store.Attachments.PurgeUnused(time.Now().Add(time.Hour)); err != nil || purged != 0 {
+		t.Errorf("PurgeUnused with a book cover = %d, %v; want 0", purged, err)
+	}
+}
+
+func testReviews(t *testing.T, store *Store) {
+	alice := mustRegister(t, store, "alice")
+	bob := mustRegister(t, store, "bob")
+	carol := mustRegister(t, store, "carol")
+
+	dune, err := store.Books.Create(Book{Title: "Dune", Approved: true, SubmittedBy: alice.ID})
+	if err != nil {
+		t.Fatalf("create book: %v", err)
+	}
+	emma, err := store.Books.Create(Book{Title: "Emma", Approved: true, SubmittedBy: alice.ID})
+	if err != nil {
+		t.Fatalf("create book: %v", err)
+	}
+	pending, err := store.Books.Create(Book{Title: "Persuasion", SubmittedBy: bob.ID})
+	if err != nil {
+		t.Fatalf("create pending book: %v", err)
+	}
+
+	review := func(book Book, user *User, rating int, content string) int {
+		t.Helper()
+		id, err := store.Reviews.Create(Review{BookID: book.ID, UserID: user.ID, Rating: rating, Content: content})
+		if err != nil {
+			t.Fatalf("review %s: %v", book.Title, err)
+		}
+		return id
+	}
+	aliceDune := review(dune, alice, 5, "A classic")
+	review(dune, bob, 4, "")
+	review(dune, carol, 2, "Too long")
+	review(emma, alice, 5, "")
+
+	tests := []struct {
+		name   string
+		review Review
+		want   error
+	}{
+		{"no stars", Review{BookID: emma.ID, UserID: bob.ID}, ErrInvalidReview},
+		{"six stars", Review{BookID: emma.ID, UserID: bob.ID, Rating: 6}, ErrInvalidReview},
+		{"text too long", Review{BookID: emma.ID, UserID: bob.ID, Rating: 3, Content: strings.Repeat("a", maxReviewLength+1)}, ErrInvalidReview},
+		{"second review", Review{BookID: dune.ID, UserID: alice.ID, Rating: 1}, ErrReviewExists},
+		{"pending book", Review{BookID: pending.ID, UserID: bob.ID, Rating: 3}, ErrNotFound},
+		{"unknown book", Review{BookID: 9999, UserID: bob.ID, Rating: 3}, ErrNotFound},
+	}
+	for _, test := range tests {
+		if _, err := store.Reviews.Create(test.review); err != test.want {
+			t.Errorf("%s: error = %v, want %v", test.name, err, test.want)
+		}
+	}
+
+	// Books carry the average and histogram of their ratings
+	got, err := store.Books.GetByID(dune.ID)
+	want := RatingSummary{Average: 3.67, Count: 3, Histogram: [5]int{0, 1, 0, 1, 1}}
+	if err != nil || got.Rating != want {
+		t.Errorf("rating of Dune = %+v, %v; want %+v", got.Rating, err, want)
+	}
+	if got, err := store.Books.GetByID(pending.ID); err != nil || got.Rating != (RatingSummary{}) {
+		t.Errorf("rating of a book without reviews = %+v, %v", got.Rating, err)
+	}
+
+	// The catalog lists the best rated books first when asked to
+	titles := func(filter BookFilter) string {
+		t.Helper()
+		books, err := store.Books.List(filter)
+		if err != nil {
+			t.Fatalf("list books: %v", err)
+		}
+		var titles []string
+		for _, book := range books {
+			titles = append(titles, fmt.Sprintf("%s %.2f", book.Title, book.Rating.Average))
+		}
+		return strings.Join(titles, ",")
+	}
+	if got := titles(BookFilter{Sort: BookSortRating}); got != "Emma 5.00,Dune 3.67" {
+		t.Errorf("books by rating = %q", got)
+	}
+	if _, err := store.Books.List(BookFilter{Sort: "popular"}); !errors.Is(err, ErrInvalidSort) {
+		t.Errorf("list books by an unknown sort: error = %v, want ErrInvalidSort", err)
+	}
+
+	// Reviews page through in each sort order
+	ratings := func(page PageRequest) (string, string) {
+		t.Helper()
+		reviews, err := store.Reviews.ListForBook(dune.ID, page)
+		if err != nil {
+			t.Fatalf("list reviews: %v", err)
+		}
+		var got []string
+		for _, review := range reviews.Reviews {
+			got = append(got, fmt.Sprintf("%s %d", review.Username, review.Rating))
+		}
+		return strings.Join(got, ","), reviews.Next
+	}
+	if got, next := ratings(PageRequest{Sort: SortHighestRated, Limit: 2}); got != "alice 5,bob 4" || next == "" {
+		t.Errorf("highest rated reviews = %q, next %q", got, next)
+	} else if got, next := ratings(PageRequest{Sort: SortHighestRated, Cursor: next, Limit: 2}); got != "carol 2" || next != "" {
+		t.Errorf("second page of highest rated reviews = %q, next %q", got, next)
+	}
+	if got, _ := ratings(PageRequest{Sort: SortLowestRated}); got != "carol 2,bob 4,alice 5" {
+		t.Errorf("lowest rated reviews = %q", got)
+	}
+	if got, _ := ratings(PageRequest{Sort: SortOldest}); got != "alice 5,bob 4,carol 2" {
+		t.Errorf("oldest reviews = %q", got)
+	}
+	if _, err := store.Reviews.ListForBook(dune.ID, PageRequest{Sort: SortMostLiked}); !errors.Is(err, ErrInvalidSort) {
+		t.Errorf("list reviews by likes: error = %v, want ErrInvalidSort", err)
+	}
+
+	// Members find and edit their own review
+	mine, err := store.Reviews.FindByAuthor(dune.ID, alice.ID)
+	if err != nil || mine.ID != aliceDune || mine.Content != "A classic" || mine.EditedAt != nil {
+		t.Fatalf("review of alice = %+v, %v", mine, err)
+	}
+	if _, err := store.Reviews.FindByAuthor(emma.ID, bob.ID); err != ErrNotFound {
+		t.Errorf("missing review: error = %v, want ErrNotFound", err)
+	}
+	mine.Rating, mine.Spoiler, mine.Content = 3, true, "The emperor dies"
+	if err := store.Reviews.Update(mine); err != nil {
+		t.Fatalf("update review: %v", err)
+	}
+	if got, err := store.Reviews.GetByID(aliceDune); err != nil || got.Rating != 3 || !got.Spoiler || got.EditedAt == nil {
+		t.Errorf("updated review = %+v, %v", got, err)
+	}
+	if err := store.Reviews.Update(Review{ID: aliceDune, Rating: 0}); err != ErrInvalidReview {
+		t.Errorf("update to no stars: error = %v, want ErrInvalidReview", err)
+	}
+	if err := store.Reviews.Update(Review{ID: 9999, Rating: 3}); err != ErrNotFound {
+		t.Errorf("update unknown review: error = %v, want ErrNotFound", err)
+	}
+
+	// Deleted reviews and the reviews of deleted members no longer count
+	if err := store.Reviews.Delete(aliceDune); err != nil {
+		t.Fatalf("delete review: %v", err)
+	}
+	if err := store.Reviews.Delete(aliceDune); err != ErrNotFound {
+		t.Errorf("delete review twice: error = %v, want ErrNotFound", err)
+	}
+	if err := store.Users.Delete(carol.ID); err != nil {
+		t.Fatalf("delete carol: %v", err)
+	}
+	if got, err := store.Books.GetByID(dune.ID); err != nil || got.Rating != (RatingSummary{Average: 4, Count: 1, Histogram: [5]int{0, 0, 0, 1, 0}}) {
+		t.Errorf("rating of Dune after deletions = %+v, %v", got.Rating, err)
+	}
+	if got, _ := ratings(PageRequest{}); got != "bob 4" {
+		t.Errorf("reviews after deletions = %q, want bob 4", got)
+	}
+
+	// Deleting a book deletes its reviews
+	if err := store.Books.Delete(dune.ID); err != nil {
+		t.Fatalf("delete book: %v", err)
+	}
+	if got, _ := ratings(PageRequest{}); got != "" {
+		t.Errorf("reviews of a deleted book = %q, want none", got)
+	}
+}
+
+func testShelves(t *testing.T, store *Store) {
+	alice := mustRegister(t, store, "alice")
+	bob := mustRegister(t, store, "bob")
+
+	dune, err := store.Books.Create(Book{Title: "Dune", Authors: []string{"Frank Herbert"}, Approved: true, SubmittedBy: alice.ID})
+	if err != nil {
+		t.Fatalf("create book: %v", err)
+	}
+	emma, err := store.Books.Create(Book{Title: "Emma", Approved: true, SubmittedBy: alice.ID})
+	if err != nil {
+		t.Fatalf("create book: %v", err)
+	}
+	persuasion, err := store.Books.Create(Book{Title: "Persuasion", Approved: true, SubmittedBy: alice.ID})
+	if err != nil {
+		t.Fatalf("create book: %v", err)
+	}
+	pending, err := store.Books.Create(Book{Title: "Middlemarch", SubmittedBy: bob.ID})
+	if err != nil {
+		t.Fatalf("create pending book: %v", err)
+	}
+
+	tomorrow := time.Now().Add(48 * time.Hour)
+	tests := []struct {
+		name  string
+		entry ShelfEntry
+		want  error
+	}{
+		{"unknown shelf", ShelfEntry{BookID: dune.ID, Shelf: "abandoned"}, ErrInvalidShelfEntry},
+		{"progress over 100", ShelfEntry{BookID: dune.ID, Shelf: ShelfReading, Progress: 120}, ErrInvalidShelfEntry},
+		{"finished tomorrow", ShelfEntry{BookID: dune.ID, Shelf: ShelfFinished, FinishedAt: &tomorrow}, ErrInvalidShelfEntry},
+		{"pending book of someone else", ShelfEntry{BookID: pending.ID, Shelf: ShelfWantToRead}, ErrUnknownBook},
+		{"unknown book", ShelfEntry{BookID: 9999, Shelf: ShelfWantToRead}, ErrUnknownBook},
+	}
+	for _, test := range tests {
+		if _, err := store.Shelves.Set(alice.ID, test.entry); err != test.want {
+			t.Errorf("%s: error = %v, want %v", test.name, err, test.want)
+		}
+	}
+
+	// Books move from shelf to shelf, keeping when they were started
+	if entry, err := store.Shelves.Set(alice.ID, ShelfEntry{BookID: dune.ID, Shelf: ShelfWantToRead, Progress: 40}); err != nil || entry.Progress != 0 || entry.StartedAt != nil {
+		t.Fatalf("want to read Dune = %+v, %v", entry, err)
+	}
+	started, err := store.Shelves.Set(alice.ID, ShelfEntry{BookID: dune.ID, Shelf: ShelfReading, Progress: 10})
+	if err != nil || started.StartedAt == nil || started.Title != "Dune" || fmt.Sprint(started.Authors) != "[Frank Herbert]" {
+		t.Fatalf("start Dune = %+v, %v", started, err)
+	}
+	entry, err := store.Shelves.Set(alice.ID, ShelfEntry{BookID: dune.ID, Shelf: ShelfReading, Progress: 60})
+	if err != nil || entry.Progress != 60 || entry.StartedAt == nil || !entry.StartedAt.Equal(*started.StartedAt) {
+		t.Errorf("progress on Dune = %+v, %v", entry, err)
+	}
+	if entry, err := store.Shelves.Set(alice.ID, ShelfEntry{BookID: dune.ID, Shelf: ShelfFinished, Progress: 60}); err != nil || entry.Progress != 100 || entry.FinishedAt == nil || entry.StartedAt == nil {
+		t.Errorf("finish Dune = %+v, %v", entry, err)
+	}
+	lastYear := time.Date(time.Now().Year()-1, time.March, 4, 0, 0, 0, 0, time.UTC)
+	if entry, err := store.Shelves.Set(alice.ID, ShelfEntry{BookID: emma.ID, Shelf: ShelfFinished, FinishedAt: &lastYear}); err != nil || entry.FinishedAt == nil || !entry.FinishedAt.Equal(lastYear) {
+		t.Errorf("finish Emma last year = %+v, %v", entry, err)
+	}
+	if _, err := store.Shelves.Set(alice.ID, ShelfEntry{BookID: persuasion.ID, Shelf: ShelfWantToRead}); err != nil {
+		t.Fatalf("want to read Persuasion: %v", err)
+	}
+	if _, err := store.Shelves.Set(bob.ID, ShelfEntry{BookID: pending.ID, Shelf: ShelfReading, Progress: 5}); err != nil {
+		t.Errorf("own pending book: %v", err)
+	}
+
+	// Shelves list the most recently finished or changed books first
+	shelved := func(userID int, shelf string) string {
+		t.Helper()
+		entries, err := store.Shelves.List(userID, shelf)
+		if err != nil {
+			t.Fatalf("list shelves: %v", err)
+		}
+		var got []string
+		for _, entry := range entries {
+			got = append(got, entry.Title+" "+entry.Shelf)
+		}
+		return strings.Join(got, ",")
+	}
+	if got := shelved(alice.ID, ""); got != "Persuasion want_to_read,Dune finished,Emma finished" {
+		t.Errorf("shelves of alice = %q", got)
+	}
+	if got := shelved(alice.ID, ShelfFinished); got != "Dune finished,Emma finished" {
+		t.Errorf("finished books of alice = %q", got)
+	}
+	if _, err := store.Shelves.List(alice.ID, "abandoned"); err != ErrInvalidShelfEntry {
+		t.Errorf("list an unknown shelf: error = %v, want ErrInvalidShelfEntry", err)
+	}
+
+	// Finished books are counted by year, the latest first
+	stats, err := store.Shelves.YearlyStats(alice.ID)
+	want := []ReadingYear{{time.Now().Year(), 1}, {time.Now().Year() - 1, 1}}
+	if err != nil || fmt.Sprint(stats) != fmt.Sprint(want) {
+		t.Errorf("yearly stats = %v, %v; want %v", stats, err, want)
+	}
+	if stats, err := store.Shelves.YearlyStats(bob.ID); err != nil || len(stats) != 0 {
+		t.Errorf("yearly stats of bob = %v, %v; want none", stats, err)
+	}
+
+	// Going back to the wanted books clears the progress and dates
+	if entry, err := store.Shelves.Set(alice.ID, ShelfEntry{BookID: dune.ID, Shelf: ShelfWantToRead}); err != nil || entry.Progress != 0 || entry.StartedAt != nil || entry.FinishedAt != nil {
+		t.Errorf("reread Dune = %+v, %v", entry, err)
+	}
+
+	// Books leave the shelves when removed or deleted from the catalog
+	if err := store.Shelves.Remove(alice.ID, persuasion.ID); err != nil {
+		t.Fatalf("remove Persuasion: %v", err)
+	}
+	if err := store.Shelves.Remove(alice.ID, persuasion.ID); err != ErrNotFound {
+		t.Errorf("remove Persuasion twice: error = %v, want ErrNotFound", err)
+	}
+	if err := store.Books.Delete(emma.ID); err != nil {
+		t.Fatalf("delete Emma: %v", err)
+	}
+	if _, err := store.Shelves.Get(alice.ID, emma.ID); err != ErrNotFound {
+		t.Errorf("shelf of a deleted book: error = %v, want ErrNotFound", err)
+	}
+	if got := shelved(alice.ID, ""); got != "Dune want_to_read" {
+		t.Errorf("shelves of alice after removals = %q", got)
+	}
+}
+
+func testClubs(t *testing.T, store *Store) {
+	alice := mustRegister(t, store, "alice")
+	bob := mustRegister(t, store, "bob")
+	carol := mustRegister(t, store, "carol")
+
+	readers, err := store.Clubs.Create(Club{Name: " Dune Readers ", Description: "Spice talk", OwnerID: alice.ID})
+	if err != nil || readers.Slug != "dune-readers" || readers.Name != "Dune Readers" || readers.Visibility != ClubPublic ||
+		readers.Owner != "alice" || readers.MemberCount != 1 || readers.Membership == nil || readers.Membership.Role != ClubOwnerRole || readers.Membership.Username != "alice" {
+		t.Fatalf("create club = %+v, %v", readers, err)
+	}
+	secret, err := store.Clubs.Create(Club{Name: "Secret Shelf", Visibility: ClubPrivate, OwnerID: bob.ID})
+	if err != nil {
+		t.Fatalf("create private club: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		club Club
+		want error
+	}{
+		{"no name", Club{Name: " ", OwnerID: alice.ID}, ErrInvalidClub},
+		{"name too long", Club{Name: strings.Repeat("a", MaxClubNameLength+1), OwnerID: alice.ID}, ErrInvalidClub},
+		{"unknown visibility", Club{Name: "Hidden", Visibility: "secret", OwnerID: alice.ID}, ErrInvalidClub},
+		{"same slug", Club{Name: "dune readers!", OwnerID: bob.ID}, ErrClubExists},
+	}
+	for _, test := range tests {
+		if _, err := store.Clubs.Create(test.club); err != test.want {
+			t.Errorf("%s: error = %v, want %v", test.name, err, test.want)
+		}
+	}
+
+	// Public clubs let members in right away, private ones wait for a moderator
+	if member, err := store.Clubs.Join(readers.ID, bob.ID); err != nil || !member.Approved || member.Role != ClubMemberRole || member.Username != "bob" {
+		t.Errorf("join public club = %+v, %v", member, err)
+	}
+	if member, err := store.Clubs.Join(secret.ID, carol.ID); err != nil || member.Approved {
+		t.Errorf("join private club = %+v, %v", member, err)
+	}
+	if _, err := store.Clubs.Join(9999, carol.ID); err != ErrNotFound {
+		t.Errorf("join unknown club: error = %v, want ErrNotFound", err)
+	}
+	if requests, err := store.Clubs.Members(secret.ID, false); err != nil || len(requests) != 1 || requests[0].UserID != carol.ID {
+		t.Errorf("requests to join = %+v, %v", requests, err)
+	}
+	if err := store.Clubs.SetRole(secret.ID, carol.ID, ClubModeratorRole); err != ErrNotFound {
+		t.Errorf("promote a pending member: error = %v, want ErrNotFound", err)
+	}
+	if err := store.Clubs.Approve(secret.ID, carol.ID); err != nil {
+		t.Fatalf("approve carol: %v", err)
+	}
+	if err := store.Clubs.Approve(secret.ID, carol.ID); err != ErrNotFound {
+		t.Errorf("approve carol twice: error = %v, want ErrNotFound", err)
+	}
+	if club, err := store.Clubs.GetBySlug("secret-shelf", carol.ID); err != nil || club.MemberCount != 2 || club.Membership == nil || !club.Membership.Approved {
+		t.Errorf("private club for carol = %+v, %v", club, err)
+	}
+
+	// The owner names the moderators and stays in the club
+	if err := store.Clubs.SetRole(readers.ID, bob.ID, ClubModeratorRole); err != nil {
+		t.Fatalf("promote bob: %v", err)
+	}
+	if err := store.Clubs.SetRole(readers.ID, bob.ID, ClubOwnerRole); err != ErrInvalidClubRole {
+		t.Errorf("make bob owner: error = %v, want ErrInvalidClubRole", err)
+	}
+	if err := store.Clubs.SetRole(readers.ID, alice.ID, ClubMemberRole); err != ErrClubOwner {
+		t.Errorf("demote the owner: error = %v, want ErrClubOwner", err)
+	}
+	if err := store.Clubs.RemoveMember(readers.ID, alice.ID); err != ErrClubOwner {
+		t.Errorf("remove the owner: error = %v, want ErrClubOwner", err)
+	}
+	if err := store.Clubs.RemoveMember(readers.ID, carol.ID); err != ErrNotFound {
+		t.Errorf("remove someone who is not a member: error = %v, want ErrNotFound", err)
+	}
+	if _, err := store.Clubs.Join(readers.ID, carol.ID); err != nil {
+		t.Fatalf("carol joins: %v", err)
+	}
+	members, err := store.Clubs.Members(readers.ID, true)
+	var roles []string
+	for _, member := range members {
+		roles = append(roles, member.Username+" "+member.Role)
+	}
+	if err != nil || strings.Join(roles, ",") != "alice owner,bob moderator,carol member" {
+		t.Errorf("members = %v, %v", roles, err)
+	}
+
+	// Club posts stay out of the forum
+	forum := mustCreatePost(t, store, alice.ID, "Forum talk", "Science")
+	postID, err := store.Posts.CreateInClub(readers.ID, 0, carol.ID, "Club talk", "Content of the club", mustFindCategory(t, store, "science").ID, []string{"Spice"})
+	if err != nil {
+		t.Fatalf("create club post: %v", err)
+	}
+	if post, err := store.Posts.GetByID(postID); err != nil || post.ClubID != readers.ID || post.Club != "Dune Readers" || post.ClubSlug != "dune-readers" {
+		t.Errorf("club post = %+v, %v", post, err)
+	}
+	if page, err := store.Posts.GetFiltered(PostFilter{}, PageRequest{}); err != nil || len(page.Posts) != 1 || page.Posts[0].ID != forum.ID {
+		t.Errorf("forum posts = %+v, %v", page, err)
+	}
+	if page, err := store.Posts.GetByUser(carol.ID, PageRequest{}); err != nil || len(page.Posts) != 0 {
+		t.Errorf("forum posts of carol = %+v, %v", page, err)
+	}
+	if page, err := store.Posts.GetByClub(readers.ID, 0, PageRequest{}); err != nil || len(page.Posts) != 1 || page.Posts[0].ID != postID {
+		t.Errorf("club posts = %+v, %v", page, err)
+	}
+	if science := mustFindCategory(t, store, "science"); science.PostCount != 1 {
+		t.Errorf("forum posts in science = %d, want 1", science.PostCount)
+	}
+	if tags, err := store.Tags.Autocomplete("spi", 10); err != nil || len(tags) != 0 {
+		t.Errorf("Autocomplete(spi) = %+v, %v; want none for a tag found only in a club", tags, err)
+	}
+	if _, err := store.Tags.GetBySlug("spice"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("GetBySlug(spice) = %v, want ErrNotFound for a tag found only in a club", err)
+	}
+	if club, err := store.Clubs.GetByID(readers.ID, 0); err != nil || club.PostCount != 1 || club.MemberCount != 3 || club.Membership != nil {
+		t.Errorf("club for guests = %+v, %v", club, err)
+	}
+
+	// Comments and reactions are traced back to their club
+	if err := store.Comments.Create(postID, bob.ID, "Agreed"); err != nil {
+		t.Fatalf("comment on club post: %v", err)
+	}
+	comments, err := store.Comments.GetByPostID(postID, PageRequest{}, 3)
+	if err != nil || len(comments.Comments) != 1 {
+		t.Fatalf("comments of club post = %+v, %v", comments, err)
+	}
+	if clubID, err := store.Clubs.ClubOf(ReactionTargetComment, comments.Comments[0].ID); err != nil || clubID != readers.ID {
+		t.Errorf("club of comment = %d, %v", clubID, err)
+	}
+	if clubID, err := store.Clubs.ClubOf(ReactionTargetPost, forum.ID); err != nil || clubID != 0 {
+		t.Errorf("club of forum post = %d, %v", clubID, err)
+	}
+	if _, err := store.Clubs.ClubOf(ReactionTargetPost, 9999); err != ErrNotFound {
+		t.Errorf("club of unknown post: error = %v, want ErrNotFound", err)
+	}
+
+	// Leaving keeps the posts; making a club public lets the waiting members in
+	if err := store.Clubs.RemoveMember(readers.ID, carol.ID); err != nil {
+		t.Fatalf("carol leaves: %v", err)
+	}
+	if _, err := store.Clubs.Membership(readers.ID, carol.ID); err != ErrNotFound {
+		t.Errorf("membership after leaving: error = %v, want ErrNotFound", err)
+	}
+	if _, err := store.Clubs.Join(secret.ID, alice.ID); err != nil {
+		t.Fatalf("alice asks to join: %v", err)
+	}
+	secret.Name, secret.Visibility = "Open Shelf", ClubPublic
+	if err := store.Clubs.Update(secret); err != nil {
+		t.Fatalf("update club: %v", err)
+	}
+	if club, err := store.Clubs.GetBySlug("secret-shelf", alice.ID); err != nil || club.Name != "Open Shelf" || club.Membership == nil || !club.Membership.Approved {
+		t.Errorf("club made public = %+v, %v", club, err)
+	}
+	secret.Name = "Dune Readers"
+	if err := store.Clubs.Update(secret); err != ErrClubExists {
+		t.Errorf("rename to a taken name: error = %v, want ErrClubExists", err)
+	}
+	if clubs, err := store.Clubs.List(0); err != nil || len(clubs) != 2 || clubs[0].Name != "Dune Readers" {
+		t.Errorf("clubs = %+v, %v", clubs, err)
+	}
+
+	// Deleting a club trashes its posts for good
+	since := time.Now().Add(-time.Hour)
+	if err := store.Clubs.Delete(readers.ID); err != nil {
+		t.Fatalf("delete club: %v", err)
+	}
+	if _, err := store.Clubs.GetBySlug("dune-readers", 0); err != ErrNotFound {
+		t.Errorf("deleted club: error = %v, want ErrNotFound", err)
+	}
+	if _, err := store.Posts.GetByID(postID); err != ErrNotFound {
+		t.Errorf("post of deleted club: error = %v, want ErrNotFound", err)
+	}
+	if err := store.Posts.Restore(postID, since); err != ErrNotFound {
+		t.Errorf("restore post of deleted club: error = %v, want ErrNotFound", err)
+	}
+	if trashed, err := store.Posts.Trashed(carol.ID, since); err != nil || len(trashed) != 0 {
+		t.Errorf("trash of carol = %+v, %v", trashed, err)
+	}
+	if err := store.Clubs.Delete(readers.ID); err != ErrNotFound {
+		t.Errorf("delete club twice: error = %v, want ErrNotFound", err)
+	}
+}
+
+func testSchedules(t *testing.T, store *Store) {
+	alice := mustRegister(t, store, "alice")
+	bob := mustRegister(t, store, "bob")
+	club, err := store.Clubs.Create(Club{Name: "Dune Readers", OwnerID: alice.ID})
+	if err != nil {
+		t.Fatalf("create club: %v", err)
+	}
+	dune, err := store.Books.Create(Book{Title: "Dune", Approved: true, SubmittedBy: alice.ID})
+	if err != nil {
+		t.Fatalf("create book: %v", err)
+	}
+	pending, err := store.Books.Create(Book{Title: "Emma", SubmittedBy: bob.ID})
+	if err != nil {
+		t.Fatalf("create pending book: %v", err)
+	}
+
+	now := time.Now().UTC()
+	later, err := store.Schedules.Create(ClubSection{ClubID: club.ID, Title: "Chapters 6-10", UnlocksAt: now.Add(7 * 24 * time.Hour)})
+	if err != nil || later.Unlocked || later.Read || later.BookID != 0 {
+		t.Fatalf("create locked section = %+v, %v", later, err)
+	}
+	first, err := store.Schedules.Create(ClubSection{ClubID: club.ID, Title: " Chapters 1-5 ", BookID: dune.ID, UnlocksAt: now.Add(-time.Hour)})
+	if err != nil || first.Title != "Chapters 1-5" || !first.Unlocked || first.Book != "Dune" {
+		t.Fatalf("create open section = %+v, %v", first, err)
+	}
+
+	tests := []struct {
+		name    string
+		section ClubSection
+		want    error
+	}{
+		{"no title", ClubSection{ClubID: club.ID, Title: " ", UnlocksAt: now}, ErrInvalidSection},
+		{"title too long", ClubSection{ClubID: club.ID, Title: strings.Repeat("a", MaxSectionTitleLength+1), UnlocksAt: now}, ErrInvalidSection},
+		{"no unlock date", ClubSection{ClubID: club.ID, Title: "Epilogue"}, ErrInvalidSection},
+		{"pending book", ClubSection{ClubID: club.ID, Title: "Epilogue", BookID: pending.ID, UnlocksAt: now}, ErrUnknownBook},
+		{"unknown book", ClubSection{ClubID: club.ID, Title: "Epilogue", BookID: 9999, UnlocksAt: now}, ErrUnknownBook},
+	}
+	for _, test := range tests {
+		if _, err := store.Schedules.Create(test.section); err != test.want {
+			t.Errorf("%s: error = %v, want %v", test.name, err, test.want)
+		}
+	}
+
+	// Sections are listed in the order they unlock, with the reading of the member
+	if err := store.Schedules.SetRead(first.ID, bob.ID, true); err != nil {
+		t.Fatalf("bob reads the first section: %v", err)
+	}
+	if err := store.Schedules.SetRead(first.ID, bob.ID, true); err != nil {
+		t.Errorf("read the first section twice: %v", err)
+	}
+	if err := store.Schedules.SetRead(9999, bob.ID, true); err != ErrNotFound {
+		t.Errorf("read an unknown section: error = %v, want ErrNotFound", err)
+	}
+	sections, err := store.Schedules.List(club.ID, bob.ID)
+	if err != nil || len(sections) != 2 || sections[0].ID != first.ID || !sections[0].Read || sections[1].ID != later.ID || sections[1].Read {
+		t.Errorf("schedule for bob = %+v, %v", sections, err)
+	}
+	if sections, err := store.Schedules.List(club.ID, alice.ID); err != nil || len(sections) != 2 || sections[0].Read {
+		t.Errorf("schedule for alice = %+v, %v", sections, err)
+	}
+
+	// Posts discussing a section are listed with it and counted
+	postID, err := store.Posts.CreateInClub(club.ID, first.ID, alice.ID, "Arrakis", "The spice must flow", mustFindCategory(t, store, "science").ID, nil)
+	if err != nil {
+		t.Fatalf("create post on the first section: %v", err)
+	}
+	if _, err := store.Posts.CreateInClub(club.ID, 0, alice.ID, "Welcome", "Hello", mustFindCategory(t, store, "science").ID, nil); err != nil {
+		t.Fatalf("create post on no section: %v", err)
+	}
+	if post, err := store.Posts.GetByID(postID); err != nil || post.SectionID != first.ID || post.Section != "Chapters 1-5" {
+		t.Errorf("post on a section = %+v, %v", post, err)
+	}
+	if page, err := store.Posts.GetByClub(club.ID, first.ID, PageRequest{}); err != nil || len(page.Posts) != 1 || page.Posts[0].ID != postID {
+		t.Errorf("posts on the first section = %+v, %v", page, err)
+	}
+	if page, err := store.Posts.GetByClub(club.ID, 0, PageRequest{}); err != nil || len(page.Posts) != 2 {
+		t.Errorf("posts of the club = %+v, %v", page, err)
+	}
+	if section, err := store.Schedules.Get(first.ID, 0); err != nil || section.PostCount != 1 || section.Read {
+		t.Errorf("first section for guests = %+v, %v", section, err)
+	}
+
+	// Locking a post holds back its content
+	post, _ := store.Posts.GetByID(postID)
+	post.Lock()
+	if !post.Locked || post.Content != "" || post.Title != "Arrakis" {
+		t.Errorf("locked post = %+v", post)
+	}
+
+	// Sections are edited in place; those discussed by posts are kept
+	later.Title, later.UnlocksAt = "Chapters 6-12", now.Add(-time.Minute)
+	if err := store.Schedules.Update(later); err != nil {
+		t.Fatalf("update section: %v", err)
+	}
+	if section, err := store.Schedules.Get(later.ID, bob.ID); err != nil || section.Title != "Chapters 6-12" || !section.Unlocked {
+		t.Errorf("updated section = %+v, %v", section, err)
+	}
+	if err := store.Schedules.Update(ClubSection{ID: 9999, Title: "Gone", UnlocksAt: now}); err != ErrNotFound {
+		t.Errorf("update unknown section: error = %v, want ErrNotFound", err)
+	}
+	if err := store.Schedules.Delete(first.ID); err != ErrSectionInUse {
+		t.Errorf("delete discussed section: error = %v, want ErrSectionInUse", err)
+	}
+	if err := store.Schedules.SetRead(later.ID, bob.ID, true); err != nil {
+		t.Fatalf("bob reads the later section: %v", err)
+	}
+	if err := store.Schedules.SetRead(later.ID, bob.ID, false); err != nil {
+		t.Fatalf("bob unreads the later section: %v", err)
+	}
+	if section, err := store.Schedules.Get(later.ID, bob.ID); err != nil || section.Read {
+		t.Errorf("unread section = %+v, %v", section, err)
+	}
+	if err := store.Schedules.Delete(later.ID); err != nil {
+		t.Fatalf("delete section: %v", err)
+	}
+	if _, err := store.Schedules.Get(later.ID, 0); err != ErrNotFound {
+		t.Errorf("deleted section: error = %v, want ErrNotFound", err)
+	}
+
+	// Removing the book from the catalog keeps the section
+	if err := store.Books.Delete(dune.ID); err != nil {
+		t.Fatalf("delete book: %v", err)
+	}
+	if section, err := store.Schedules.Get(first.ID, 0); err != nil || section.BookID != 0 || section.Book != "" {
+		t.Errorf("section of deleted book = %+v, %v", section, err)
+	}
+
+	// Deleting the club takes its schedule with it
+	if err := store.Clubs.Delete(club.ID); err != nil {
+		t.Fatalf("delete club: %v", err)
+	}
+	if _, err := store.Schedules.Get(first.ID, 0); err != ErrNotFound {
+		t.Errorf("section of deleted club: error = %v, want ErrNotFound", err)
+	}
+}
+
+func testPolls(t *testing.T, store *Store) {
+	alice := mustRegister(t, store, "alice")
+	bob := mustRegister(t, store, "bob")
+	carol := mustRegister(t, store, "carol")
+	post := mustCreatePost(t, store, alice.ID, "Next read", "Random")
+
+	tomorrow := time.Now().Add(24 * time.Hour)
+	yesterday := time.Now().Add(-24 * time.Hour)
+	options := func(labels ...string) []PollOption {
+		var options []PollOption
+		for _, label := range labels {
+			options = append(options, PollOption{Label: label})
+		}
+		return options
+	}
+	tests := []struct {
+		name string
+		poll Poll
+	}{
+		{"no question", Poll{PostID: post.ID, Question: " ", Options: options("Dune", "Emma")}},
+		{"question too long", Poll{PostID: post.ID, Question: strings.Repeat("a", MaxPollQuestionLength+1), Options: options("Dune", "Emma")}},
+		{"one option", Poll{PostID: post.ID, Question: "Next?", Options: options("Dune")}},
+		{"too many options", Poll{PostID: post.ID, Question: "Next?", Options: options("1", "2", "3", "4", "5", "6", "7", "8", "9", "10", "11")}},
+		{"blank option", Poll{PostID: post.ID, Question: "Next?", Options: options("Dune", " ")}},
+		{"same option twice", Poll{PostID: post.ID, Question: "Next?", Options: options("Dune", " dune")}},
+		{"closed already", Poll{PostID: post.ID, Question: "Next?", Options: options("Dune", "Emma"), ClosesAt: &yesterday}},
+	}
+	for _, test := range tests {
+		if _, err := store.Polls.Create(test.poll); err != ErrInvalidPoll {
+			t.Errorf("%s: error = %v, want ErrInvalidPoll", test.name, err)
+		}
+	}
+	if _, err := store.Polls.GetForPost(post.ID, alice.ID); err != ErrNotFound {
+		t.Errorf("poll of a post without one: error = %v, want ErrNotFound", err)
+	}
+
+	pollID, err := store.Polls.Create(Poll{PostID: post.ID, Question: " Which book next? ", Options: options("Dune ", "Emma", "Beloved"), ClosesAt: &tomorrow})
+	if err != nil {
+		t.Fatalf("create poll: %v", err)
+	}
+	if _, err := store.Polls.Create(Poll{PostID: post.ID, Question: "Another?", Options: options("Yes", "No")}); err == nil {
+		t.Errorf("second poll on a post: want an error")
+	}
+	poll, err := store.Polls.GetForPost(post.ID, bob.ID)
+	if err != nil || poll.ID != pollID || poll.Question != "Which book next?" || poll.Closed || poll.Voted || len(poll.Options) != 3 || poll.Options[0].Label != "Dune" || poll.Options[2].Label != "Beloved" {
+		t.Fatalf("new poll = %+v, %v", poll, err)
+	}
+	dune, emma, beloved := poll.Options[0].ID, poll.Options[1].ID, poll.Options[2].ID
+
+	// Single choice polls take one option of their own, once per member
+	votes := []struct {
+		name    string
+		options []int
+		want    error
+	}{
+		{"no option", nil, ErrInvalidVote},
+		{"two options", []int{dune, emma}, ErrInvalidVote},
+		{"unknown option", []int{9999}, ErrInvalidVote},
+		{"same option twice", []int{dune, dune}, nil},
+		{"second vote", []int{emma}, ErrAlreadyVoted},
+	}
+	for _, vote := range votes {
+		if err := store.Polls.Vote(pollID, bob.ID, vote.options); err != vote.want {
+			t.Errorf("%s: error = %v, want %v", vote.name, err, vote.want)
+		}
+	}
+	if err := store.Polls.Vote(pollID, carol.ID, []int{dune}); err != nil {
+		t.Fatalf("carol votes: %v", err)
+	}
+	if err := store.Polls.Vote(9999, carol.ID, []int{dune}); err != ErrNotFound {
+		t.Errorf("vote in an unknown poll: error = %v, want ErrNotFound", err)
+	}
+
+	// The results count the votes and name the voters
+	poll, err = store.Polls.GetForPost(post.ID, bob.ID)
+	if err != nil || !poll.Voted || poll.VoterCount != 2 || poll.Options[0].Votes != 2 || !poll.Options[0].Chosen || poll.Options[1].Votes != 0 || poll.Options[1].Chosen {
+		t.Errorf("results for bob = %+v, %v", poll, err)
+	}
+	if got := strings.Join(poll.Options[0].Voters, ","); got != "bob,carol" {
+		t.Errorf("voters = %s, want bob,carol", got)
+	}
+	if poll, err := store.Polls.GetForPost(post.ID, 0); err != nil || poll.Voted || poll.Options[0].Chosen {
+		t.Errorf("results for guests = %+v, %v", poll, err)
+	}
+
+	// Multiple choice polls take several options, and anonymous ones hide who picked them
+	other := mustCreatePost(t, store, bob.ID, "Club snacks", "Random")
+	anonymousID, err := store.Polls.Create(Poll{PostID: other.ID, Question: "Snacks?", Options: options("Tea", "Cake", "Crisps"), Multiple: true, Anonymous: true})
+	if err != nil {
+		t.Fatalf("create anonymous poll: %v", err)
+	}
+	snacks, err := store.Polls.GetForPost(other.ID, alice.ID)
+	if err != nil {
+		t.Fatalf("get anonymous poll: %v", err)
+	}
+	if err := store.Polls.Vote(anonymousID, alice.ID, []int{snacks.Options[0].ID, snacks.Options[1].ID}); err != nil {
+		t.Fatalf("vote for two options: %v", err)
+	}
+	if err := store.Polls.Vote(anonymousID, alice.ID, []int{snacks.Options[2].ID}); err != ErrAlreadyVoted {
+		t.Errorf("second vote in the anonymous poll: error = %v, want ErrAlreadyVoted", err)
+	}
+	if err := store.Polls.Vote(anonymousID, bob.ID, []int{beloved}); err != ErrInvalidVote {
+		t.Errorf("vote for an option of another poll: error = %v, want ErrInvalidVote", err)
+	}
+	snacks, err = store.Polls.GetForPost(other.ID, alice.ID)
+	if err != nil || snacks.VoterCount != 1 || snacks.Options[0].Votes != 1 || snacks.Options[1].Votes != 1 || !snacks.Options[1].Chosen {
+		t.Errorf("anonymous results = %+v, %v", snacks, err)
+	}
+	for _, option := range snacks.Options {
+		if len(option.Voters) != 0 {
+			t.Errorf("anonymous poll names the voters of %s: %v", option.Label, option.Voters)
+		}
+	}
+
+	// Polls take no vote once closed. Polls cannot be created closed, so close this one by hand
+	database := store.Polls.(*pollRepository).db
+	if _, err := database.Exec("UPDATE polls SET closes_at = ? WHERE id = ?", yesterday.UTC(), pollID); err != nil {
+		t.Fatalf("close poll: %v", err)
+	}
+	if poll, err := store.Polls.GetForPost(post.ID, alice.ID); err != nil || !poll.Closed {
+		t.Errorf("closed poll = %+v, %v", poll, err)
+	}
+	if err := store.Polls.Vote(pollID, alice.ID, []int{emma}); err != ErrPollClosed {
+		t.Errorf("vote in a closed poll: error = %v, want ErrPollClosed", err)
+	}
+
+	// The polls of posts in the trash are hidden, and purged with them
+	if err := store.Posts.Delete(post.ID); err != nil {
+		t.Fatalf("delete post: %v", err)
+	}
+	if _, err := store.Polls.GetForPost(post.ID, alice.ID); err != ErrNotFound {
+		t.Errorf("poll of a post in the trash: error = %v, want ErrNotFound", err)
+	}
+	if _, err := store.Posts.Purge(time.Now().Add(time.Hour)); err != nil {
+		t.Fatalf("purge: %v", err)
+	}
+	var left int
+	if err := database.QueryRow("SELECT (SELECT COUNT(*) FROM polls) + (SELECT COUNT(*) FROM poll_options) + (SELECT COUNT(*) FROM poll_voters) + (SELECT COUNT(*) FROM poll_votes)").Scan(&left); err != nil || left != 1+3+1+2 {
+		t.Errorf("poll rows after the purge = %d, %v; want those of the other poll alone", left, err)
+	}
+	if poll, err := store.Polls.GetForPost(other.ID, alice.ID); err != nil || poll.VoterCount != 1 {
+		t.Errorf("poll of another post after the purge = %+v, %v", poll, err)
+	}
+}
+
+func testCategories(t *testing.T, store *Store) {
+	alice := mustRegister(t, store, "alice")
+
+	// The migration seeds the categories offered so far, in order
+	categories, err := store.Categories.List()
+	if err != nil {
+		t.Fatalf("list categories: %v", err)
+	}
+	var slugs []string
+	for _, category := range categories {
+		slugs = append(slugs, category.Slug)
+	}
+	if got := strings.Join(slugs, ","); got != "random,news,sport,technology,science,health" {
+		t.Errorf("seeded categories = %s", got)
+	}
+
+	poetry, err := store.Categories.Create(Category{Name: " Poetry & Verse ", Description: "Rhymes", Position: 3})
+	if err != nil {
+		t.Fatalf("create category: %v", err)
+	}
+	if poetry.ID == 0 || poetry.Slug != "poetry-verse" || poetry.Name != "Poetry & Verse" || poetry.CreatedAt.IsZero() {
+		t.Errorf("created category = %+v", poetry)
+	}
+	if _, err := store.Categories.Create(Category{Name: "poetry & verse", Slug: "verse"}); !errors.Is(err, ErrCategoryExists) {
+		t.Errorf("duplicate name = %v, want ErrCategoryExists", err)
+	}
+	if _, err := store.Categories.Create(Category{Name: "Verse", Slug: "poetry-verse"}); !errors.Is(err, ErrCategoryExists) {
+		t.Errorf("duplicate slug = %v, want ErrCategoryExists", err)
+	}
+	for _, invalid := range []Category{{Name: "  "}, {Name: "Verse", Slug: "Not A Slug"}, {Name: "!!!"}} {
+		if _, err := store.Categories.Create(invalid); !errors.Is(err, ErrInvalidCategory) {
+			t.Errorf("create %+v = %v, want ErrInvalidCategory", invalid, err)
+		}
+	}
+
+	// Categories are found by slug or by name, ignoring case
+	for _, ref := range []string{"poetry-verse", "POETRY & VERSE"} {
+		if found, err := store.Categories.Find(ref); err != nil || found.ID != poetry.ID {
+			t.Errorf("find %q = %+v, %v", ref, found, err)
+		}
+	}
+	if _, err := store.Categories.Find("drama"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("find missing category = %v, want ErrNotFound", err)
+	}
+
+	poetry.Name = "Poetry"
+	poetry.Slug = ""
+	poetry.Position = 0
+	updated, err := store.Categories.Update(poetry)
+	if err != nil || updated.Slug != "poetry" || updated.Name != "Poetry" || updated.Description != "Rhymes" {
+		t.Errorf("update category = %+v, %v", updated, err)
+	}
+	if _, err := store.Categories.Update(Category{ID: poetry.ID, Name: "News"}); !errors.Is(err, ErrCategoryExists) {
+		t.Errorf("rename to an existing name = %v, want ErrCategoryExists", err)
+	}
+	if _, err := store.Categories.Update(Category{ID: poetry.ID + 1000, Name: "Drama"}); !errors.Is(err, ErrNotFound) {
+		t.Errorf("update missing category = %v, want ErrNotFound", err)
+	}
+	categories, _ = store.Categories.List()
+	if len(categories) != 7 || categories[0].ID != poetry.ID {
+		t.Errorf("listing after update = %+v, want poetry first", categories)
+	}
+
+	// A category with posts cannot be deleted
+	post := mustCreatePost(t, store, alice.ID, "Sonnets", "poetry")
+	if post.CategoryID != poetry.ID || post.Category != "Poetry" || post.CategorySlug != "poetry" {
+		t.Errorf("post category = %+v", post)
+	}
+	if found, _ := store.Categories.GetByID(poetry.ID); found.PostCount != 1 {
+		t.Errorf("post count = %d, want 1", found.PostCount)
+	}
+	if err := store.Categories.Delete(poetry.ID); !errors.Is(err, ErrCategoryInUse) {
+		t.Errorf("delete used category = %v, want ErrCategoryInUse", err)
+	}
+	// Posts in the trash still hold their category until they are purged
+	if err := store.Posts.Delete(post.ID); err != nil {
+		t.Fatalf("delete post: %v", err)
+	}
+	if found, _ := store.Categories.GetByID(poetry.ID); found.PostCount != 0 {
+		t.Errorf("post count = %d, want 0 with the post in the trash", found.PostCount)
+	}
+	if err := store.Categories.Delete(poetry.ID); !errors.Is(err, ErrCategoryInUse) {
+		t.Errorf("delete category of a post in the trash = %v, want ErrCategoryInUse", err)
+	}
+	if _, err := store.Posts.Purge(time.Now().Add(time.Hour)); err != nil {
+		t.Fatalf("purge: %v", err)
+	}
+	if err := store.Categories.Delete(poetry.ID); err != nil {
+		t.Errorf("delete category: %v", err)
+	}
+	if err := store.Categories.Delete(poetry.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("second delete = %v, want ErrNotFound", err)
+	}
+}
+
+func testTags(t *testing.T, store *Store) {
+	alice := mustRegister(t, store, "alice")
+
+	emma := mustCreatePost(t, store, alice.ID, "Emma", "Random", "Jane Austen", "regency-era", "jane austen ")
+	if got := strings.Join(emma.Tags, ","); got != "jane-austen,regency-era" {
+		t.Errorf("tags = %s, want jane-austen,regency-era", got)
+	}
+	persuasion := mustCreatePost(t, store, alice.ID, "Persuasion", "Random", "jane-austen")
+	mustCreatePost(t, store, alice.ID, "Dracula", "Science", "gothic")
+	if plain := mustCreatePost(t, store, alice.ID, "Untagged", "Random"); len(plain.Tags) != 0 {
+		t.Errorf("untagged post tags = %v", plain.Tags)
+	}
+
+	// Tags filter the listing alone, together, and with the other filters
+	filter := func(filter PostFilter) []string {
+		t.Helper()
+		page, err := store.Posts.GetFiltered(filter, PageRequest{Sort: SortOldest})
+		if err != nil {
+			t.Fatalf("filter %+v: %v", filter, err)
+		}
+		var titles []string
+		for _, post := range page.Posts {
+			titles = append(titles, post.Title)
+		}
+		return titles
+	}
+	tests := []struct {
+		filter PostFilter
+		want   string
+	}{
+		{PostFilter{Tags: []string{"Jane Austen"}}, "Emma,Persuasion"},
+		{PostFilter{Tags: []string{"jane-austen", "regency-era"}}, "Emma"},
+		{PostFilter{Tags: []string{"jane-austen"}, Title: "pers"}, "Persuasion"},
+		{PostFilter{Tags: []string{"gothic"}, Category: "random"}, ""},
+		{PostFilter{Tags: []string{"unknown"}}, ""},
+	}
+	for _, test := range tests {
+		if got := strings.Join(filter(test.filter), ","); got != test.want {
+			t.Errorf("filter %+v = %s, want %s", test.filter, got, test.want)
+		}
+	}
+
+	// Autocomplete suggests the tags in use, the most used first
+	suggest := func(prefix string, limit int) string {
+		t.Helper()
+		tags, err := store.Tags.Autocomplete(prefix, limit)
+		if err != nil {
+			t.Fatalf("autocomplete %q: %v", prefix, err)
+		}
+		var slugs []string
+		for _, tag := range tags {
+			slugs = append(slugs, fmt.Sprintf("%s:%d", tag.Slug, tag.PostCount))
+		}
+		return strings.Join(slugs, ",")
+	}
+	if got := suggest("", 10); got != "jane-austen:2,gothic:1,regency-era:1" {
+		t.Errorf("popular tags = %s", got)
+	}
+	if got := suggest("Re", 10); got != "regency-era:1" {
+		t.Errorf("suggestions for Re = %s", got)
+	}
+	if got := suggest("", 1); got != "jane-austen:2" {
+		t.Errorf("limited suggestions = %s", got)
+	}
+
+	tag, err := store.Tags.GetBySlug("Jane Austen")
+	if err != nil || tag.Slug != "jane-austen" || tag.PostCount != 2 {
+		t.Errorf("get tag = %+v, %v", tag, err)
+	}
+	if _, err := store.Tags.GetBySlug("unknown"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("get missing tag = %v, want ErrNotFound", err)
+	}
+
+	// Updating replaces the tags; unused tags are no longer suggested
+	if err := store.Posts.Update(emma.ID, emma.UserID, "Emma", "Matchmaking", emma.CategoryID, []string{"matchmaking"}); err != nil {
+		t.Fatalf("update post: %v", err)
+	}
+	if post, _ := store.Posts.GetByID(emma.ID); strings.Join(post.Tags, ",") != "matchmaking" {
+		t.Errorf("tags after update = %v", post.Tags)
+	}
+	if got := suggest("re", 10); got != "" {
+		t.Errorf("suggestions for an unused tag = %s", got)
+	}
+
+	// Deleting a post takes its tags off it, and a tag no post carries is not found
+	if err := store.Posts.Delete(persuasion.ID); err != nil {
+		t.Fatalf("delete post: %v", err)
+	}
+	if _, err := store.Tags.GetBySlug("jane-austen"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("get tag after delete = %v, want ErrNotFound", err)
+	}
+
+	// Bad tags are rejected before anything is written
+	tooMany := make([]string, MaxPostTags+1)
+	for i := range tooMany {
+		tooMany[i] = fmt.Sprintf("tag-%d", i)
+	}
+	bad := []struct {
+		tags []string
+		want error
+	}{
+		{[]string{"!!"}, ErrInvalidTag},
+		{[]string{strings.Repeat("a", 41)}, ErrInvalidTag},
+		{tooMany, ErrTooManyTags},
+	}
+	for _, test := range bad {
+		if _, err := store.Posts.Create(alice.ID, "Bad tags", "Content", emma.CategoryID, test.tags); !errors.Is(err, test.want) {
+			t.Errorf("create with tags %v = %v, want %v", test.tags, err, test.want)
+		}
+	}
+	if titles := filter(PostFilter{Title: "Bad tags"}); len(titles) != 0 {
+		t.Errorf("posts with bad tags were stored: %v", titles)
+	}
+}
+
+func testComments(t *testing.T, store *Store) {
+	alice := mustRegister(t, store, "alice")
+	bob := mustRegister(t, store, "bob")
+	post := mustCreatePost(t, store, alice.ID, "Favourite openings", "Random")
+
+	if err := store.Comments.Create(post.ID, bob.ID, "Call me Ishmael."); err != nil {
+		t.Fatalf("create comment: %v", err)
+	}
+
+	page, err := store.Comments.GetByPostID(post.ID, PageRequest{}, DefaultCommentMaxDepth)
+	if err != nil || len(page.Comments) != 1 {
+		t.Fatalf("comments = %+v, %v", page, err)
+	}
+	comment := page.Comments[0]
+	if comment.Username != "bob" || comment.Content != "Call me Ishmael." {
+		t.Errorf("comment = %+v", comment)
+	}
+
+	if err := store.Reactions.ToggleCommentLike(alice.ID, comment.ID); err != nil {
+		t.Fatalf("like comment: %v", err)
+	}
+	page, _ = store.Comments.GetByPostID(post.ID, PageRequest{}, DefaultCommentMaxDepth)
+	if page.Comments[0].Likes != 1 || page.Comments[0].Dislikes != 0 {
+		t.Errorf("comment reactions = %d/%d, want 1/0", page.Comments[0].Likes, page.Comments[0].Dislikes)
+	}
+
+	if err := store.Reactions.TogglePostDislike(bob.ID, post.ID); err != nil {
+		t.Fatalf("dislike post: %v", err)
+	}
+	if got, err := store.Posts.GetByID(post.ID); err != nil || got.Username != "alice" || got.CommentCount != 1 || got.Dislikes != 1 {
+		t.Errorf("post with counts = %+v, %v", got, err)
+	}
+
+	if _, err := store.Comments.GetByPostID(post.ID, PageRequest{Sort: SortMostCommented}, DefaultCommentMaxDepth); !errors.Is(err, ErrInvalidSort) {
+		t.Errorf("comments sorted by comment count = %v, want ErrInvalidSort", err)
+	}
+}
+
+func testCommentReplies(t *testing.T, store *Store) {
+	alice := mustRegister(t, store, "alice")
+	bob := mustRegister(t, store, "bob")
+	post := mustCreatePost(t, store, alice.ID, "Unreliable narrators", "Random")
+
+	// find returns the comment or reply with the given content
+	find := func(content string) Comment {
+		t.Helper()
+		page, err := store.Comments.GetByPostID(post.ID, PageRequest{Limit: MaxPageSize}, DefaultCommentMaxDepth)
+		if err != nil {
+			t.Fatalf("comments: %v", err)
+		}
+		for _, comment := range page.Comments {
+			if comment.Content == content {
+				return comment
+			}
+		}
+		t.Fatalf("comment %q not found in %+v", content, page.Comments)
+		return Comment{}
+	}
+	// comment adds a comment on the post and returns its ID
+	comment := func(content string) int {
+		t.Helper()
+		if err := store.Comments.Create(post.ID, bob.ID, content); err != nil {
+			t.Fatalf("create comment %q: %v", content, err)
+		}
+		return find(content).ID
+	}
+	// reply answers a comment with the given maximum depth and returns the ID of the reply
+	reply := func(parentID int, content string, maxDepth int) int {
+		t.Helper()
+		postID, err := store.Comments.Reply(parentID, alice.ID, content, maxDepth)
+		if err != nil || postID != post.ID {
+			t.Fatalf("reply %q = %d, %v, want post %d", content, postID, err, post.ID)
+		}
+		return find(content).ID
+	}
+	// thread lists the contents and depths of a page of comments
+	thread := func(page PageRequest, maxDepth int) ([]string, string) {
+		t.Helper()
+		result, err := store.Comments.GetByPostID(post.ID, page, maxDepth)
+		if err != nil {
+			t.Fatalf("comments: %v", err)
+		}
+		var lines []string
+		for _, comment := range result.Comments {
+			lines = append(lines, fmt.Sprintf("%d %s", comment.Depth, comment.Content))
+		}
+		return lines, result.Next
+	}
+
+	first := comment("Stevens")
+	second := comment("Humbert")
+	answer := reply(first, "The butler", DefaultCommentMaxDepth)
+	reply(second, "Nabokov", DefaultCommentMaxDepth)
+	reply(answer, "Remains of the Day", DefaultCommentMaxDepth)
+	reply(first, "Ishiguro", DefaultCommentMaxDepth)
+
+	want := []string{"0 Stevens", "1 The butler", "2 Remains of the Day", "1 Ishiguro", "0 Humbert", "1 Nabokov"}
+	if got, next := thread(PageRequest{}, DefaultCommentMaxDepth); fmt.Sprint(got) != fmt.Sprint(want) || next != "" {
+		t.Errorf("threads = %q, next %q, want %q", got, next, want)
+	}
+
+	// Pages are made of whole threads, the sort order applying to the comments on the post
+	got, next := thread(PageRequest{Sort: SortNewest, Limit: 1}, DefaultCommentMaxDepth)
+	if fmt.Sprint(got) != fmt.Sprint([]string{"0 Humbert", "1 Nabokov"}) || next == "" {
+		t.Fatalf("first page = %q, next %q", got, next)
+	}
+	got, next = thread(PageRequest{Sort: SortNewest, Limit: 1, Cursor: next}, DefaultCommentMaxDepth)
+	if len(got) != 4 || got[0] != "0 Stevens" || next != "" {
+		t.Errorf("second page = %q, next %q", got, next)
+	}
+
+	// A reply to a comment at the deepest level is added next to it
+	reply(answer, "Atonement", 1)
+	if deepest := find("Atonement"); deepest.ParentID != first || deepest.Depth != 1 {
+		t.Errorf("reply at the deepest level has parent %d and depth %d, want %d and 1", deepest.ParentID, deepest.Depth, first)
+	}
+
+	// Replies stored deeper than the limit are reported at it
+	got, _ = thread(PageRequest{}, 1)
+	for _, line := range got {
+		if strings.HasPrefix(line, "2 ") {
+			t.Errorf("reply reported below the deepest level: %q", got)
+		}
+	}
+
+	if _, err := store.Comments.Reply(1<<30, alice.ID, "Lost", DefaultCommentMaxDepth); !errors.Is(err, ErrNotFound) {
+		t.Errorf("reply to a missing comment = %v, want ErrNotFound", err)
+	}
+	if got, err := store.Posts.GetByID(post.ID); err != nil || got.CommentCount != 7 {
+		t.Errorf("comment count = %d, %v, want 7 including replies", got.CommentCount, err)
+	}
+}
+
+func testCommentEdits(t *testing.T, store *Store) {
+	alice := mustRegister(t, store, "alice")
+	bob := mustRegister(t, store, "bob")
+	post := mustCreatePost(t, store, alice.ID, "Second-hand bookshops", "Random")
+
+	// comments lists the page of comments on the post, keyed by content
+	comments := func() map[string]Comment {
+		t.Helper()
+		page, err := store.Comments.GetByPostID(post.ID, PageRequest{Limit: MaxPageSize}, DefaultCommentMaxDepth)
+		if err != nil {
+			t.Fatalf("comments: %v", err)
+		}
+		byContent := map[string]Comment{}
+		for _, comment := range page.Comments {
+			byContent[comment.Content] = comment
+		}
+		return byContent
+	}
+
+	if err := store.Comments.Create(post.ID, bob.ID, "Try Hay-on-Wye"); err != nil {
+		t.Fatalf("create comment: %v", err)
+	}
+	first := comments()["Try Hay-on-Wye"]
+	if first.EditedAt != nil || first.Deleted {
+		t.Errorf("new comment = %+v, want neither edited nor deleted", first)
+	}
+
+	if err := store.Comments.Update(first.ID, "Try Hay-on-Wye in May"); err != nil {
+		t.Fatalf("update comment: %v", err)
+	}
+	edited, err := store.Comments.GetByID(first.ID)
+	if err != nil || edited.Content != "Try Hay-on-Wye in May" || edited.EditedAt == nil || edited.UserID != bob.ID {
+		t.Errorf("edited comment = %+v, %v", edited, err)
+	}
+	if err := store.Comments.Update(1<<30, "Nothing"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("update missing comment = %v, want ErrNotFound", err)
+	}
+
+	// A comment with replies leaves a tombstone behind
+	if _, err := store.Comments.Reply(first.ID, alice.ID, "During the festival?", DefaultCommentMaxDepth); err != nil {
+		t.Fatalf("reply: %v", err)
+	}
+	if err := store.Reactions.ToggleCommentLike(alice.ID, first.ID); err != nil {
+		t.Fatalf("like comment: %v", err)
+	}
+	if err := store.Comments.Delete(first.ID); err != nil {
+		t.Fatalf("delete comment with replies: %v", err)
+	}
+	tombstone := comments()[""]
+	if tombstone.ID != first.ID || !tombstone.Deleted || tombstone.Username != "" || tombstone.Likes != 0 {
+		t.Errorf("tombstone = %+v", tombstone)
+	}
+	if _, err := store.Comments.GetByID(first.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("get removed comment = %v, want ErrNotFound", err)
+	}
+	for name, err := range map[string]error{
+		"update": store.Comments.Update(first.ID, "Back"),
+		"delete": store.Comments.Delete(first.ID),
+	} {
+		if !errors.Is(err, ErrNotFound) {
+			t.Errorf("%s removed comment = %v, want ErrNotFound", name, err)
+		}
+	}
+	if _, err := store.Comments.Reply(first.ID, alice.ID, "Hello?", DefaultCommentMaxDepth); !errors.Is(err, ErrNotFound) {
+		t.Errorf("reply to removed comment = %v, want ErrNotFound", err)
+	}
+	if got, _ := store.Posts.GetByID(post.ID); got.CommentCount != 1 {
+		t.Errorf("comment count = %d, want 1 without the removed comment", got.CommentCount)
+	}
+
+	// Deleting the last reply clears the tombstone too
+	if err := store.Comments.Delete(comments()["During the festival?"].ID); err != nil {
+		t.Fatalf("delete reply: %v", err)
+	}
+	if left := comments(); len(left) != 0 {
+		t.Errorf("comments left = %+v, want none", left)
+	}
+}
+
+func testCommentTrash(t *testing.T, store *Store) {
+	alice := mustRegister(t, store, "alice")
+	bob := mustRegister(t, store, "bob")
+	post := mustCreatePost(t, store, alice.ID, "Rereading", "Random")
+	hourAgo, inAnHour := time.Now().Add(-time.Hour), time.Now().Add(time.Hour)
+
+	// thread lists the contents of the comments on the post, tombstones showing as "-"
+	thread := func() string {
+		t.Helper()
+		page, err := store.Comments.GetByPostID(post.ID, PageRequest{}, DefaultCommentMaxDepth)
+		if err != nil {
+			t.Fatalf("comments: %v", err)
+		}
+		var contents []string
+		for _, comment := range page.Comments {
+			if comment.Deleted {
+				contents = append(contents, "-")
+			} else {
+				contents = append(contents, comment.Content)
+			}
+		}
+		return strings.Join(contents, ",")
+	}
+
+	if err := store.Comments.Create(post.ID, bob.ID, "Middlemarch every decade"); err != nil {
+		t.Fatalf("create comment: %v", err)
+	}
+	page, _ := store.Comments.GetByPostID(post.ID, PageRequest{}, DefaultCommentMaxDepth)
+	commentID := page.Comments[0].ID
+	if _, err := store.Comments.Reply(commentID, alice.ID, "Same here", DefaultCommentMaxDepth); err != nil {
+		t.Fatalf("reply: %v", err)
+	}
+	if err := store.Reactions.ToggleCommentLike(alice.ID, commentID); err != nil {
+		t.Fatalf("like comment: %v", err)
+	}
+
+	if err := store.Comments.Delete(commentID); err != nil {
+		t.Fatalf("delete comment: %v", err)
+	}
+	if got := thread(); got != "-,Same here" {
+		t.Errorf("thread = %q, want the deleted comment as a tombstone", got)
+	}
+	trashed, err := store.Comments.Trashed(bob.ID, hourAgo)
+	if err != nil || len(trashed) != 1 || trashed[0].Content != "Middlemarch every decade" || trashed[0].PostTitle != "Rereading" || trashed[0].DeletedAt == nil {
+		t.Errorf("trash = %+v, %v", trashed, err)
+	}
+	if trashed, _ := store.Comments.Trashed(bob.ID, inAnHour); len(trashed) != 0 {
+		t.Errorf("trash past the retention = %+v, want empty", trashed)
+	}
+
+	// Restoring brings back the content and the reactions
+	if err := store.Comments.Restore(commentID, inAnHour); !errors.Is(err, ErrNotFound) {
+		t.Errorf("restore past the retention = %v, want ErrNotFound", err)
+	}
+	if err := store.Comments.Restore(commentID, hourAgo); err != nil {
+		t.Fatalf("restore comment: %v", err)
+	}
+	if comment, err := store.Comments.GetByID(commentID); err != nil || comment.Content != "Middlemarch every decade" || comment.DeletedAt != nil {
+		t.Errorf("restored comment = %+v, %v", comment, err)
+	}
+	if likes, _ := store.Reactions.CountCommentLikes(commentID); likes != 1 {
+		t.Errorf("likes of restored comment = %d, want 1", likes)
+	}
+
+	// Comments on a post in the trash come back with the post, not on their own
+	if err := store.Comments.Delete(commentID); err != nil {
+		t.Fatalf("delete comment again: %v", err)
+	}
+	if err := store.Posts.Delete(post.ID); err != nil {
+		t.Fatalf("delete post: %v", err)
+	}
+	if trashed, _ := store.Comments.Trashed(bob.ID, hourAgo); len(trashed) != 0 {
+		t.Errorf("trash with the post deleted = %+v, want empty", trashed)
+	}
+	if err := store.Comments.Restore(commentID, hourAgo); !errors.Is(err, ErrNotFound) {
+		t.Errorf("restore comment of deleted post = %v, want ErrNotFound", err)
+	}
+	if err := store.Posts.Restore(post.ID, hourAgo); err != nil {
+		t.Fatalf("restore post: %v", err)
+	}
+
+	// Purging keeps the comment as a placeholder while it has replies
+	if purged, err := store.Comments.Purge(inAnHour); err != nil || purged != 0 {
+		t.Errorf("purge with replies = %d, %v, want 0", purged, err)
+	}
+	if err := store.Comments.Restore(commentID, hourAgo); err != nil {
+		t.Errorf("restore kept comment: %v", err)
+	}
+	reply := page.Comments[0].ID
+	if page, _ := store.Comments.GetByPostID(post.ID, PageRequest{}, DefaultCommentMaxDepth); len(page.Comments) == 2 {
+		reply = page.Comments[1].ID
+	}
+	for _, id := range []int{reply, commentID} {
+		if err := store.Comments.Delete(id); err != nil {
+			t.Fatalf("delete comment %d: %v", id, err)
+		}
+	}
+	if purged, err := store.Comments.Purge(inAnHour); err != nil || purged != 2 {
+		t.Errorf("purge = %d, %v, want 2", purged, err)
+	}
+	if got := thread(); got != "" {
+		t.Errorf("thread after the purge = %q, want empty", got)
+	}
+	if likes, _ := store.Reactions.CountCommentLikes(commentID); likes != 0 {
+		t.Errorf("likes of purged comment = %d, want 0", likes)
+	}
+}
+
+func testReactions(t *testing.T, store *Store) {
+	alice := mustRegister(t, store, "alice")
+	post := mustCreatePost(t, store, alice.ID, "Poetry corner", "Random")
+
+	counts := func() (int, int) {
+		t.Helper()
+		likes, err := store.Reactions.CountPostLikes(post.ID)
+		if err != nil {
+			t.Fatalf("count likes: %v", err)
+		}
+		dislikes, err := store.Reactions.CountPostDislikes(post.ID)
+		if err != nil {
+			t.Fatalf("count dislikes: %v", err)
+		}
+		return likes, dislikes
+	}
+
+	steps := []struct {
+		name         string
+		toggle       func(userID, postID int) error
+		wantLikes    int
+		wantDislikes int
+	}{
+		{"like", store.Reactions.TogglePostLike, 1, 0},
+		{"dislike replaces like", store.Reactions.TogglePostDislike, 0, 1},
+		{"like replaces dislike", store.Reactions.TogglePostLike, 1, 0},
+		{"like again removes like", store.Reactions.TogglePostLike, 0, 0},
+	}
+	for _, step := range steps {
+		if err := step.toggle(alice.ID, post.ID); err != nil {
+			t.Fatalf("%s: %v", step.name, err)
+		}
+		if likes, dislikes := counts(); likes != step.wantLikes || dislikes != step.wantDislikes {
+			t.Errorf("%s: counts = %d/%d, want %d/%d", step.name, likes, dislikes, step.wantLikes, step.wantDislikes)
+		}
+	}
+
+	liked, err := store.Posts.GetLikedByUser(alice.ID, PageRequest{})
+	if err != nil || len(liked.Posts) != 0 {
+		t.Errorf("liked posts after unlike = %+v, %v", liked, err)
+	}
+
+	// Missing posts and comments, and those in the trash, take no like or dislike
+	trashed := mustCreatePost(t, store, alice.ID, "Trashed", "Random")
+	if err := store.Comments.Create(trashed.ID, alice.ID, "Comment on a trashed post"); err != nil {
+		t.Fatalf("create comment: %v", err)
+	}
+	comments, err := store.Comments.GetByPostID(trashed.ID, PageRequest{}, DefaultCommentMaxDepth)
+	if err != nil || len(comments.Comments) != 1 {
+		t.Fatalf("comments = %+v, %v", comments, err)
+	}
+	if err := store.Posts.Delete(trashed.ID); err != nil {
+		t.Fatalf("delete post: %v", err)
+	}
+	missing := []struct {
+		name   string
+		toggle func(userID, targetID int) error
+		id     int
+	}{
+		{"like missing post", store.Reactions.TogglePostLike, 9999},
+		{"dislike post in the trash", store.Reactions.TogglePostDislike, trashed.ID},
+		{"like missing comment", store.Reactions.ToggleCommentLike, 9999},
+		{"dislike comment of a post in the trash", store.Reactions.ToggleCommentDislike, comments.Comments[0].ID},
+	}
+	for _, test := range missing {
+		if err := test.toggle(alice.ID, test.id); err != ErrNotFound {
+			t.Errorf("%s: error = %v, want ErrNotFound", test.name, err)
+		}
+	}
+	if likes, err := store.Reactions.CountPostDislikes(trashed.ID); err != nil || likes != 0 {
+		t.Errorf("dislikes of the post in the trash = %d, %v; want 0", likes, err)
+	}
+
+	// Concurrent clicks must never leave a user both liking and disliking.
+	bob := mustRegister(t, store, "bob")
+	var wg sync.WaitGroup
+	errs := make(chan error, 20)
+	for i := 0; i < 20; i++ {
+		toggle := store.Reactions.TogglePostLike
+		if i%2 == 1 {
+			toggle = store.Reactions.TogglePostDislike
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs <- toggle(bob.ID, post.ID)
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatalf("concurrent toggle: %v", err)
+		}
+	}
+	if likes, dislikes := counts(); likes+dislikes > 1 {
+		t.Errorf("after concurrent toggles: counts = %d/%d, want at most one reaction", likes, dislikes)
+	}
+}
+
+func testReactionSet(t *testing.T, store *Store) {
+	alice := mustRegister(t, store, "alice")
+	bob := mustRegister(t, store, "bob")
+	post := mustCreatePost(t, store, alice.ID, "Hamlet", "Random")
+	set := ReactionSet{"insightful", "funny", "spoiler"}
+
+	summary := func(userID int) []ReactionCount {
+		t.Helper()
+		counts, err := store.Reactions.CountReactions(userID, ReactionTargetPost, []int{post.ID}, set)
+		if err != nil {
+			t.Fatalf("count reactions: %v", err)
+		}
+		return counts[post.ID]
+	}
+
+	// Several reactions stack with each other and with a like; repeating one has no effect.
+	for _, add := range []struct {
+		userID   int
+		reaction string
+	}{{alice.ID, "insightful"}, {alice.ID, "funny"}, {alice.ID, "funny"}, {bob.ID, "funny"}} {
+		if err := store.Reactions.AddReaction(add.userID, ReactionTargetPost, post.ID, add.reaction); err != nil {
+			t.Fatalf("add %s: %v", add.reaction, err)
+		}
+	}
+	if err := store.Reactions.TogglePostLike(alice.ID, post.ID); err != nil {
+		t.Fatalf("like: %v", err)
+	}
+
+	want := []ReactionCount{{"insightful", 1, false}, {"funny", 2, true}, {"spoiler", 0, false}}
+	if got := summary(bob.ID); !reflect.DeepEqual(got, want) {
+		t.Errorf("summary for bob = %+v, want %+v", got, want)
+	}
+	if got, _ := store.Posts.GetByID(post.ID); got.Likes != 1 {
+		t.Errorf("likes = %d, want 1", got.Likes)
+	}
+
+	// Likes and dislikes still replace each other next to the other reactions.
+	if err := store.Reactions.TogglePostDislike(alice.ID, post.ID); err != nil {
+		t.Fatalf("dislike: %v", err)
+	}
+	if got, _ := store.Posts.GetByID(post.ID); got.Likes != 0 || got.Dislikes != 1 {
+		t.Errorf("votes after dislike = %d/%d, want 0/1", got.Likes, got.Dislikes)
+	}
+
+	if err := store.Reactions.RemoveReaction(alice.ID, ReactionTargetPost, post.ID, "funny"); err != nil {
+		t.Fatalf("remove funny: %v", err)
+	}
+	want = []ReactionCount{{"insightful", 1, true}, {"funny", 1, false}, {"spoiler", 0, false}}
+	if got := summary(alice.ID); !reflect.DeepEqual(got, want) {
+		t.Errorf("summary for alice = %+v, want %+v", got, want)
+	}
+
+	if err := store.Reactions.AddReaction(alice.ID, ReactionTargetComment, 9999, "funny"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("reaction on missing comment = %v, want ErrNotFound", err)
+	}
+	if counts, err := store.Reactions.CountReactions(alice.ID, ReactionTargetComment, nil, set); err != nil || len(counts) != 0 {
+		t.Errorf("counts without targets = %v, %v", counts, err)
+	}
+}
+
+func TestNewReactionSet(t *testing.T) {
+	if set, err := NewReactionSet(nil); err != nil || !reflect.DeepEqual([]string(set), DefaultReactions) {
+		t.Errorf("empty configuration = %v, %v, want the default set", set, err)
+	}
+	if set, err := NewReactionSet([]string{"agree", "mind_blown"}); err != nil || !set.Contains("mind_blown") || set.Contains("funny") {
+		t.Errorf("custom set = %v, %v", set, err)
+	}
+	for _, names := range [][]string{{"like"}, {"Funny"}, {"so funny"}, {"agree", "agree"}} {
+		if _, err := NewReactionSet(names); err == nil {
+			t.Errorf("NewReactionSet(%q) succeeded, want an error", names)
+		}
+	}
+}
+
+// TestDiffRevisionsLargeInput checks that long texts are compared without filling
+// a table of every pair of their lines.
+func TestDiffRevisionsLargeInput(t *testing.T) {
+	lines := func(prefix string, n int) []string {
+		text := make([]string, n)
+		for i := range text {
+			text[i] = fmt.Sprintf("%s %d", prefix, i)
+		}
+		return text
+	}
+
+	// A single line changed in a long text is found past the common start and end
+	before := lines("line", 100000)
+	after := append([]string{}, before...)
+	after[50000] = "changed"
+	diff := DiffRevisions(PostRevision{Revision: 1, Content: strings.Join(before, "\n")}, PostRevision{Revision: 2, Content: strings.Join(after, "\n")})
+	changes := []DiffLine{}
+	for _, line := range diff.Content {
+		if line.Op != DiffEqual {
+			changes = append(changes, line)
+		}
+	}
+	if want := []DiffLine{{DiffDelete, "line 50000"}, {DiffInsert, "changed"}}; len(diff.Content) != 100001 || !reflect.DeepEqual(changes, want) {
+		t.Errorf("diff of one changed line has %d lines, changes %v", len(diff.Content), changes)
+	}
+
+	// Rewrites too long to compare line by line are shown as replaced whole
+	old, rewritten := lines("old", 50000), lines("new", 50000)
+	diff = DiffRevisions(PostRevision{Revision: 1, Content: "kept\n" + strings.Join(old, "\n")}, PostRevision{Revision: 2, Content: "kept\n" + strings.Join(rewritten, "\n")})
+	if len(diff.Content) != 100001 || diff.Content[0] != (DiffLine{DiffEqual, "kept"}) ||
+		diff.Content[1] != (DiffLine{DiffDelete, "old 0"}) || diff.Content[50001] != (DiffLine{DiffInsert, "new 0"}) {
+		t.Errorf("diff of a rewrite has %d lines, starting %v", len(diff.Content), diff.Content[:2])
+	}
+}
+
+// rollBackTo rolls a test database back to the given schema version.
+func rollBackTo(tb testing.TB, database *db.DB, version int) {
+	tb.Helper()
+
+	statuses, err := db.MigrationStatuses(database)
+	if err != nil {
+		tb.Fatalf("migration statuses: %v", err)
+	}
+	steps := 0
+	for _, status := range statuses {
+		if status.Version > version && status.Applied {
+			steps++
+		}
+	}
+	if err := db.Rollback(database, steps); err != nil {
+		tb.Fatalf("roll back to version %d: %v", version, err)
+	}
+}
+
+// TestReactionsMigration checks that migrating to the reactions table carries
+// over the likes and dislikes stored in the tables it replaces.
+func TestReactionsMigration(t *testing.T) {
+	database, err := db.Open(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("open sqlite: %v", err)
+	}
+	defer database.Close()
+
+	// Register the users with the current schema, then roll back to the one
+	// with the four like/dislike tables.
+	migrateTestDatabase(t, database)
+	store := NewStore(database)
+	alice := mustRegister(t, store, "alice")
+	bob := mustRegister(t, store, "bob")
+	rollBackTo(t, database, 2)
+
+	// The store reads reactions from the new table, so write the post and comment directly.
+	var postID, commentID int
+	if err := database.QueryRow("INSERT INTO posts (user_id, title, content, category) VALUES (?, 'Old likes', 'Content', 'Random') RETURNING id", alice.ID).Scan(&postID); err != nil {
+		t.Fatalf("create post: %v", err)
+	}
+	if err := database.QueryRow("INSERT INTO comments (post_id, user_id, content) VALUES (?, ?, 'Old comment') RETURNING id", postID, bob.ID).Scan(&commentID); err != nil {
+		t.Fatalf("create comment: %v", err)
+	}
+
+	seed := []struct {
+		query string
+		args  []interface{}
+	}{
+		// Alice likes the post; bob both liked and later disliked it.
+		{"INSERT INTO post_likes (user_id, post_id, is_like) VALUES (?, ?, TRUE)", []interface{}{alice.ID, postID}},
+		{"INSERT INTO post_likes (user_id, post_id, is_like, created_at) VALUES (?, ?, TRUE, '2024-01-01 10:00:00')", []interface{}{bob.ID, postID}},
+		{"INSERT INTO post_dislikes (user_id, post_id, is_dislike, created_at) VALUES (?, ?, TRUE, '2024-01-02 10:00:00')", []interface{}{bob.ID, postID}},
+		// A withdrawn like and an active dislike on the comment.
+		{"INSERT INTO comment_likes (user_id, comment_id, is_like) VALUES (?, ?, FALSE)", []interface{}{alice.ID, commentID}},
+		{"INSERT INTO comment_dislikes (user_id, comment_id, is_dislike) VALUES (?, ?, TRUE)", []interface{}{alice.ID, commentID}},
+	}
+	for _, row := range seed {
+		if _, err := database.Exec(row.query, row.args...); err != nil {
+			t.Fatalf("seed %q: %v", row.query, err)
+		}
+	}
+
+	if err := db.Migrate(database); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+
+	got, err := store.Posts.GetByID(postID)
+	if err != nil || got.Likes != 1 || got.Dislikes != 1 {
+		t.Errorf("post reactions = %d/%d, %v, want 1/1", got.Likes, got.Dislikes, err)
+	}
+	page, err := store.Comments.GetByPostID(postID, PageRequest{}, DefaultCommentMaxDepth)
+	if err != nil || page.Comments[0].Likes != 0 || page.Comments[0].Dislikes != 1 {
+		t.Errorf("comment reactions = %+v, %v, want 0/1", page.Comments, err)
+	}
+
+	// Rolling back restores the old tables with the same reactions.
+	rollBackTo(t, database, 2)
+	var likes, dislikes int
+	if err := database.QueryRow("SELECT COUNT(*) FROM post_likes WHERE post_id = ? AND is_like = TRUE", postID).Scan(&likes); err != nil {
+		t.Fatalf("count restored likes: %v", err)
+	}
+	if err := database.QueryRow("SELECT COUNT(*) FROM post_dislikes WHERE post_id = ? AND is_dislike = TRUE", postID).Scan(&dislikes); err != nil {
+		t.Fatalf("count restored dislikes: %v", err)
+	}
+	if likes != 1 || dislikes != 1 {
+		t.Errorf("restored post reactions = %d/%d, want 1/1", likes, dislikes)
+	}
+}
+
+// TestCategoriesMigration checks that migrating to the categories table files
+// existing posts under the category they named, and that rolling back restores the names.
+func TestCategoriesMigration(t *testing.T) {
+	database, err := db.Open(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("open sqlite: %v", err)
+	}
+	defer database.Close()
+
+	// Register the user with the current schema, then roll back to the one with
+	// free-text categories.
+	migrateTestDatabase(t, database)
+	store := NewStore(database)
+	alice := mustRegister(t, store, "alice")
+	rollBackTo(t, database, 4)
+
+	postIDs := map[string]int{}
+	for _, category := range []string{"Science", "science ", "Poetry Slam", ""} {
+		var postID int
+		if err := database.QueryRow("INSERT INTO posts (user_id, title, content, category) VALUES (?, 'Title', 'Content', ?) RETURNING id", alice.ID, category).Scan(&postID); err != nil {
+			t.Fatalf("create post: %v", err)
+		}
+		postIDs[category] = postID
+	}
+
+	if err := db.Migrate(database); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+
+	want := map[string]string{"Science": "science", "science ": "science", "Poetry Slam": "poetry-slam", "": ""}
+	for category, slug := range want {
+		post, err := store.Posts.GetByID(postIDs[category])
+		if err != nil || post.CategorySlug != slug {
+			t.Errorf("post in %q = %+v, %v, want category %q", category, post, err, slug)
+		}
+	}
+	if poetry, err := store.Categories.Find("poetry-slam"); err != nil || poetry.Name != "Poetry Slam" || poetry.PostCount != 1 {
+		t.Errorf("legacy category = %+v, %v", poetry, err)
+	}
+
+	// Rolling back puts the category names back on the posts.
+	rollBackTo(t, database, 4)
+	var category string
+	if err := database.QueryRow("SELECT category FROM posts WHERE id = ?", postIDs["Poetry Slam"]).Scan(&category); err != nil || category != "Poetry Slam" {
+		t.Errorf("restored category = %q, %v", category, err)
+	}
+}
+
+func TestOrphanCleanupMigration(t *testing.T) {
+	database, err := db.Open(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("open sqlite: %v", err)
+	}
+	defer database.Close()
+
+	// Roll back to the schema whose post deletion left comments and reactions behind.
+	migrateTestDatabase(t, database)
+	store := NewStore(database)
+	alice := mustRegister(t, store, "alice")
+	kept := mustCreatePost(t, store, alice.ID, "Kept", "Random")
+	gone := mustCreatePost(t, store, alice.ID, "Gone", "Random", "ephemera")
+	for _, postID := range []int{kept.ID, gone.ID} {
+		if err := store.Comments.Create(postID, alice.ID, "A comment"); err != nil {
+			t.Fatalf("create comment: %v", err)
+		}
+		if err := store.Reactions.TogglePostLike(alice.ID, postID); err != nil {
+			t.Fatalf("like post: %v", err)
+		}
+	}
+	if _, err := database.Exec("INSERT INTO reactions (user_id, target_type, target_id, reaction) SELECT ?, 'comment', id, 'like' FROM comments", alice.ID); err != nil {
+		t.Fatalf("like comments: %v", err)
+	}
+	rollBackTo(t, database, 8)
+	if _, err := database.Exec("DELETE FROM posts WHERE id = ?", gone.ID); err != nil {
+		t.Fatalf("delete post: %v", err)
+	}
+
+	if err := db.Migrate(database); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+
+	counts := map[string]int{
+		"SELECT COUNT(*) FROM comments":                                1,
+		"SELECT COUNT(*) FROM post_tags":                               0,
+		"SELECT COUNT(*) FROM reactions WHERE target_type = 'post'":    1,
+		"SELECT COUNT(*) FROM reactions WHERE target_type = 'comment'": 1,
+	}
+	for query, want := range counts {
+		var got int
+		if err := database.QueryRow(query).Scan(&got); err != nil || got != want {
+			t.Errorf("%s = %d, %v, want %d", query, got, err, want)
+		}
+	}
+	if got, err := store.Posts.GetByID(kept.ID); err != nil || got.Likes != 1 || got.CommentCount != 1 {
+		t.Errorf("kept post = %+v, %v", got, err)
+	}
+}
+
+// TestPostRevisionsMigration checks that migrating to the post history starts
+// the history of every existing post with its current version.
+func TestPostRevisionsMigration(t *testing.T) {
+	database, err := db.Open(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("open sqlite: %v", err)
+	}
+	defer database.Close()
+
+	migrateTestDatabase(t, database)
+	store := NewStore(database)
+	alice := mustRegister(t, store, "alice")
+	post := mustCreatePost(t, store, alice.ID, "Middlemarch", "Random")
+
+	// Roll back to the schema without history, and edit the post there
+	rollBackTo(t, database, 9)
+	if _, err := database.Exec("UPDATE posts SET title = 'Middlemarch, again' WHERE id = ?", post.ID); err != nil {
+		t.Fatalf("edit post: %v", err)
+	}
+
+	if err := db.Migrate(database); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+
+	revisions, err := store.Posts.Revisions(post.ID)
+	if err != nil || len(revisions) != 1 {
+		t.Fatalf("revisions = %+v, %v, want one", revisions, err)
+	}
+	if got := revisions[0]; got.Revision != 1 || got.Title != "Middlemarch, again" || got.EditorID != alice.ID || !got.CreatedAt.Equal(post.CreatedAt) {
+		t.Errorf("seeded revision = %+v", got)
+	}
+	if got, _ := store.Posts.GetByID(post.ID); got.EditedAt != nil {
+		t.Errorf("migrated post edited at %v, want never", got.EditedAt)
+	}
+}
+
+func TestSoftDeleteMigration(t *testing.T) {
+	database, err := db.Open(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("open sqlite: %v", err)
+	}
+	defer database.Close()
+
+	migrateTestDatabase(t, database)
+	store := NewStore(database)
+	alice := mustRegister(t, store, "alice")
+	kept := mustCreatePost(t, store, alice.ID, "Kept", "Random")
+	trashed := mustCreatePost(t, store, alice.ID, "Trashed", "Random")
+	for _, postID := range []int{kept.ID, trashed.ID} {
+		if err := store.Comments.Create(postID, alice.ID, "Comment"); err != nil {
+			t.Fatalf("create comment: %v", err)
+		}
+	}
+	page, _ := store.Comments.GetByPostID(kept.ID, PageRequest{}, DefaultCommentMaxDepth)
+	parentID := page.Comments[0].ID
+	if _, err := store.Comments.Reply(parentID, alice.ID, "Reply", DefaultCommentMaxDepth); err != nil {
+		t.Fatalf("reply: %v", err)
+	}
+	if err := store.Comments.Delete(parentID); err != nil {
+		t.Fatalf("delete comment: %v", err)
+	}
+	if err := store.Posts.Delete(trashed.ID); err != nil {
+		t.Fatalf("delete post: %v", err)
+	}
+
+	// Without the trash, deleted posts are gone and deleted comments with replies blanked
+	rollBackTo(t, database, 10)
+	var posts, comments, blanked int
+	if err := database.QueryRow("SELECT COUNT(*) FROM posts").Scan(&posts); err != nil {
+		t.Fatalf("count posts: %v", err)
+	}
+	if err := database.QueryRow("SELECT COUNT(*), COUNT(CASE WHEN content = '' THEN 1 END) FROM comments").Scan(&comments, &blanked); err != nil {
+		t.Fatalf("count comments: %v", err)
+	}
+	if posts != 1 || comments != 2 || blanked != 1 {
+		t.Errorf("after rollback: %d posts, %d comments, %d blanked; want 1, 2, 1", posts, comments, blanked)
+	}
+
+	if err := db.Migrate(database); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	if got, err := store.Posts.GetByID(kept.ID); err != nil || got.DeletedAt != nil || got.CommentCount != 1 {
+		t.Errorf("migrated post = %+v, %v", got, err)
+	}
+}
+
+func TestPostStatusMigration(t *testing.T) {
+	database, err := db.Open(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("open sqlite: %v", err)
+	}
+	defer database.Close()
+
+	migrateTestDatabase(t, database)
+	store := NewStore(database)
+	alice := mustRegister(t, store, "alice")
+	kept := mustCreatePost(t, store, alice.ID, "Kept", "Random")
+	if _, err := store.Posts.SaveDraft(alice.ID, "Draft", "Content", kept.CategoryID, []string{"unpublished"}, nil); err != nil {
+		t.Fatalf("save draft: %v", err)
+	}
+
+	// Without drafts, unpublished posts are gone with their tags
+	rollBackTo(t, database, 11)
+	var posts, tagged int
+	if err := database.QueryRow("SELECT COUNT(*), (SELECT COUNT(*) FROM post_tags) FROM posts").Scan(&posts, &tagged); err != nil {
+		t.Fatalf("count posts: %v", err)
+	}
+	if posts != 1 || tagged != 0 {
+		t.Errorf("after rollback: %d posts, %d tagged; want 1, 0", posts, tagged)
+	}
+
+	if err := db.Migrate(database); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	if got, err := store.Posts.GetByID(kept.ID); err != nil || got.Status != PostPublished {
+		t.Errorf("migrated post = %+v, %v", got, err)
+	}
+}
+
+func TestAttachmentsMigration(t *testing.T) {
+	database, err := db.Open(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("open sqlite: %v", err)
+	}
+	defer database.Close()
+
+	migrateTestDatabase(t, database)
+	store := NewStore(database)
+	alice := mustRegister(t, store, "alice")
+	post := mustCreatePost(t, store, alice.ID, "Kept", "Random")
+	id, err := store.Attachments.Create(Attachment{UserID: alice.ID, Key: "cover.jpg", ThumbnailKey: "thumb.jpg", ContentType: "image/jpeg"})
+	if err != nil {
+		t.Fatalf("create attachment: %v", err)
+	}
+	if err := store.Attachments.SetForPost(post.ID, alice.ID, []int{id}, 0); err != nil {
+		t.Fatalf("set attachments: %v", err)
+	}
+
+	// Posts lose their images along with the tables
+	rollBackTo(t, database, 12)
+	var tables int
+	if err := database.QueryRow("SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name IN ('attachments', 'post_attachments')").Scan(&tables); err != nil {
+		t.Fatalf("count tables: %v", err)
+	}
+	if tables != 0 {
+		t.Errorf("after rollback: %d attachment tables left", tables)
+	}
+
+	if err := db.Migrate(database); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	if got, err := store.Posts.GetByID(post.ID); err != nil || len(got.Attachments) != 0 || got.Cover != nil {
+		t.Errorf("migrated post = %+v, %v", got, err)
+	}
+}
+
+func TestUserProfilesMigration(t *testing.T) {
+	database, err := db.Open(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("open sqlite: %v", err)
+	}
+	defer database.Close()
+
+	migrateTestDatabase(t, database)
+	store := NewStore(database)
+	alice := mustRegister(t, store, "alice")
+	bob := mustRegister(t, store, "bob")
+	post := mustCreatePost(t, store, alice.ID, "Kept", "Random")
+	if _, err := database.Exec("UPDATE posts SET created_at = '2024-03-01 10:00:00' WHERE id = ?", post.ID); err != nil {
+		t.Fatalf("date post: %v", err)
+	}
+
+	rollBackTo(t, database, 13)
+	var columns int
+	if err := database.QueryRow("SELECT COUNT(*) FROM pragma_table_info('users') WHERE name IN ('bio', 'avatar_key', 'created_at')").Scan(&columns); err != nil {
+		t.Fatalf("count columns: %v", err)
+	}
+	if columns != 0 {
+		t.Errorf("after rollback: %d profile columns left", columns)
+	}
+
+	// Members who joined before take the date of their first post, or now
+	if err := db.Migrate(database); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	if got, err := store.Users.GetByID(alice.ID); err != nil || !got.CreatedAt.Equal(time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)) {
+		t.Errorf("migrated alice = %+v, %v", got, err)
+	}
+	if got, err := store.Users.GetByID(bob.ID); err != nil || time.Since(got.CreatedAt) > time.Minute || len(got.FavoriteGenres) != 0 {
+		t.Errorf("migrated bob = %+v, %v", got, err)
+	}
+}
+
+func TestProfilePrivacyMigration(t *testing.T) {
+	database, err := db.Open(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("open sqlite: %v", err)
+	}
+	defer database.Close()
+
+	migrateTestDatabase(t, database)
+	store := NewStore(database)
+	alice := mustRegister(t, store, "alice")
+
+	rollBackTo(t, database, 14)
+	if err := db.Migrate(database); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+
+	// Liked posts stay public until their member hides them
+	if got, err := store.Users.GetByID(alice.ID); err != nil || got.HideLikedPosts {
+		t.Errorf("migrated alice = %+v, %v", got, err)
+	}
+}
+
+func TestBooksMigration(t *testing.T) {
+	database, err := db.Open(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("open sqlite: %v", err)
+	}
+	defer database.Close()
+
+	migrateTestDatabase(t, database)
+	store := NewStore(database)
+	alice := mustRegister(t, store, "alice")
+	post := mustCreatePost(t, store, alice.ID, "Dune", "Random")
+
+	rollBackTo(t, database, 15)
+	if err := db.Migrate(database); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+
+	// Existing posts link no books, and the catalog starts empty
+	if got, err := store.Posts.GetByID(post.ID); err != nil || len(got.Books) != 0 {
+		t.Errorf("migrated post = %+v, %v", got, err)
+	}
+	if books, err := store.Books.List(BookFilter{}); err != nil || len(books) != 0 {
+		t.Errorf("books = %+v, %v; want none", books, err)
+	}
+
+	// Members keep their profiles, and can become curators
+	if err := store.Users.UpdateRole(alice.ID, "curator"); err != nil {
+		t.Fatalf("make alice a curator: %v", err)
+	}
+	if got, err := store.Users.GetByID(alice.ID); err != nil || got.Role != "curator" || got.Email != alice.Email || !got.CreatedAt.Equal(alice.CreatedAt) {
+		t.Errorf("migrated alice = %+v, %v", got, err)
+	}
+	if err := store.Users.UpdateRole(alice.ID, "librarian"); err == nil {
+		t.Error("UpdateRole(librarian) succeeded, want the role constraint to refuse it")
+	}
+
+	// Curators go back to being plain users when rolling back
+	rollBackTo(t, database, 15)
+	if got, err := store.Users.GetByID(alice.ID); err != nil || got.Role != "user" {
+		t.Errorf("rolled back alice = %+v, %v", got, err)
+	}
+}
+
+// TestBookReviewsMigration checks that books start without reviews and that
+// rolling back drops the reviews along with their table.
+func TestBookReviewsMigration(t *testing.T) {
+	database, err := db.Open(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("open sqlite: %v", err)
+	}
+	defer database.Close()
+
+	migrateTestDatabase(t, database)
+	store := NewStore(database)
+	alice := mustRegister(t, store, "alice")
+	dune, err := store.Books.Create(Book{Title: "Dune", Approved: true, SubmittedBy: alice.ID})
+	if err != nil {
+		t.Fatalf("create book: %v", err)
+	}
+	if _, err := store.Reviews.Create(Review{BookID: dune.ID, UserID: alice.ID, Rating: 5}); err != nil {
+		t.Fatalf("create review: %v", err)
+	}
+
+	rollBackTo(t, database, 16)
+	if err := db.Migrate(database); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+
+	// The books are kept, without their reviews
+	if got, err := store.Books.GetByID(dune.ID); err != nil || got.Title != "Dune" || got.Rating != (RatingSummary{}) {
+		t.Errorf("migrated book = %+v, %v", got, err)
+	}
+	if _, err := store.Reviews.Create(Review{BookID: dune.ID, UserID: alice.ID, Rating: 4}); err != nil {
+		t.Errorf("review after migrating: %v", err)
+	}
+}
+
+// TestReadingShelvesMigration checks that members start with empty shelves and
+// that rolling back drops the shelves.
+func TestReadingShelvesMigration(t *testing.T) {
+	database, err := db.Open(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("open sqlite: %v", err)
+	}
+	defer database.Close()
+
+	migrateTestDatabase(t, database)
+	store := NewStore(database)
+	alice := mustRegister(t, store, "alice")
+	dune, err := store.Books.Create(Book{Title: "Dune", Approved: true, SubmittedBy: alice.ID})
+	if err != nil {
+		t.Fatalf("create book: %v", err)
+	}
+	if _, err := store.Shelves.Set(alice.ID, ShelfEntry{BookID: dune.ID, Shelf: ShelfFinished}); err != nil {
+		t.Fatalf("finish book: %v", err)
+	}
+
+	rollBackTo(t, database, 17)
+	if err := db.Migrate(database); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+
+	// The books are kept, off the shelves
+	if entries, err := store.Shelves.List(alice.ID, ""); err != nil || len(entries) != 0 {
+		t.Errorf("shelves after migrating = %+v, %v; want none", entries, err)
+	}
+	if _, err := store.Shelves.Set(alice.ID, ShelfEntry{BookID: dune.ID, Shelf: ShelfReading, Progress: 30}); err != nil {
+		t.Errorf("shelve after migrating: %v", err)
+	}
+}
+
+// TestBookClubsMigration checks that rolling back the clubs migration takes the
+// posts of clubs with it and leaves the forum as it was.
+func TestBookClubsMigration(t *testing.T) {
+	database, err := db.Open(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("open sqlite: %v", err)
+	}
+	defer database.Close()
+
+	migrateTestDatabase(t, database)
+	store := NewStore(database)
+	alice := mustRegister(t, store, "alice")
+	forum := mustCreatePost(t, store, alice.ID, "Forum talk", "Science")
+	club, err := store.Clubs.Create(Club{Name: "Dune Readers", OwnerID: alice.ID})
+	if err != nil {
+		t.Fatalf("create club: %v", err)
+	}
+	postID, err := store.Posts.CreateInClub(club.ID, 0, alice.ID, "Club talk", "Content", forum.CategoryID, nil)
+	if err != nil {
+		t.Fatalf("create club post: %v", err)
+	}
+	if err := store.Comments.Create(postID, alice.ID, "A comment"); err != nil {
+		t.Fatalf("comment on club post: %v", err)
+	}
+
+	rollBackTo(t, database, 18)
+	if err := db.Migrate(database); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+
+	// The forum post is kept, the club and its post are gone
+	if _, err := store.Posts.GetByID(forum.ID); err != nil {
+		t.Errorf("forum post after migrating: %v", err)
+	}
+	if _, err := store.Posts.GetByID(postID); err != ErrNotFound {
+		t.Errorf("club post after migrating: error = %v, want ErrNotFound", err)
+	}
+	if clubs, err := store.Clubs.List(0); err != nil || len(clubs) != 0 {
+		t.Errorf("clubs after migrating = %+v, %v; want none", clubs, err)
+	}
+	if _, err := store.Clubs.Create(Club{Name: "Dune Readers", OwnerID: alice.ID}); err != nil {
+		t.Errorf("create club after migrating: %v", err)
+	}
+}
+
+func TestClubSchedulesMigration(t *testing.T) {
+	database, err := db.Open(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("open sqlite: %v", err)
+	}
+	defer database.Close()
+
+	migrateTestDatabase(t, database)
+	store := NewStore(database)
+	alice := mustRegister(t, store, "alice")
+	forum := mustCreatePost(t, store, alice.ID, "Forum talk", "Science")
+	club, err := store.Clubs.Create(Club{Name: "Dune Readers", OwnerID: alice.ID})
+	if err != nil {
+		t.Fatalf("create club: %v", err)
+	}
+	section, err := store.Schedules.Create(ClubSection{ClubID: club.ID, Title: "Chapters 1-5", UnlocksAt: time.Now().Add(-time.Hour)})
+	if err != nil {
+		t.Fatalf("create section: %v", err)
+	}
+	if err := store.Schedules.SetRead(section.ID, alice.ID, true); err != nil {
+		t.Fatalf("read section: %v", err)
+	}
+	postID, err := store.Posts.CreateInClub(club.ID, section.ID, alice.ID, "Arrakis", "Content", forum.CategoryID, nil)
+	if err != nil {
+		t.Fatalf("create post on a section: %v", err)
+	}
+
+	rollBackTo(t, database, 19)
+	if err := db.Migrate(database); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+
+	// The post stays in its club, the schedule is gone
+	if post, err := store.Posts.GetByID(postID); err != nil || post.ClubID != club.ID || post.SectionID != 0 {
+		t.Errorf("post after migrating = %+v, %v", post, err)
+	}
+	if sections, err := store.Schedules.List(club.ID, alice.ID); err != nil || len(sections) != 0 {
+		t.Errorf("schedule after migrating = %+v, %v; want none", sections, err)
+	}
+}
+
+func testSearch(t *testing.T, store *Store) {
+	alice := mustRegister(t, store, "alice")
+	bob := mustRegister(t, store, "bob")
+
+	science := mustFindCategory(t, store, "science")
+	if _, err := store.Posts.Create(alice.ID, "Arrakis", "The <b>spice</b> of the desert planet.", science.ID, []string{"Frank Herbert"}); err != nil {
+		t.Fatalf("create post: %v", err)
+	}
+	dune := mustCreatePost(t, store, alice.ID, "Dune Reread", "Science")
+	other := mustCreatePost(t, store, bob.ID, "Poetry corner", "Random")
+	if err := store.Comments.Create(other.ID, bob.ID, "This reminds me of the spice trade."); err != nil {
+		t.Fatalf("create comment: %v", err)
+	}
+
+	search := func(filter SearchFilter) []SearchResult {
+		t.Helper()
+		results, err := store.Search.Search(filter)
+		if err != nil {
+			t.Fatalf("search %+v: %v", filter, err)
+		}
+		return results
+	}
+
+	results := search(SearchFilter{Query: "spice"})
+	if len(results) != 2 {
+		t.Fatalf("search spice = %+v, want a post and a comment", results)
+	}
+	types := map[string]SearchResult{}
+	for _, result := range results {
+		types[result.Type] = result
+	}
+	post, comment := types[SearchTypePost], types[SearchTypeComment]
+	if post.Username != "alice" || !strings.Contains(post.Snippet, "<mark>spice</mark>") {
+		t.Errorf("post hit = %+v", post)
+	}
+	if strings.Contains(post.Snippet, "<b>") {
+		t.Errorf("post snippet is not escaped: %q", post.Snippet)
+	}
+	if comment.PostID != other.ID || comment.Title != "Poetry corner" || comment.Username != "bob" {
+		t.Errorf("comment hit = %+v", comment)
+	}
+
+	if results := search(SearchFilter{Query: "spice", Type: SearchTypeComment}); len(results) != 1 || results[0].Type != SearchTypeComment {
+		t.Errorf("comment-only search = %+v", results)
+	}
+	if results := search(SearchFilter{Query: "spice", Category: "random"}); len(results) != 1 || results[0].PostID != other.ID {
+		t.Errorf("category search = %+v", results)
+	}
+	if results := search(SearchFilter{Query: "spice", Tags: []string{"frank-herbert"}}); len(results) != 1 || results[0].Title != "Arrakis" {
+		t.Errorf("tag search = %+v", results)
+	}
+	if results := search(SearchFilter{Query: "rerea"}); len(results) != 1 || results[0].ID != dune.ID {
+		t.Errorf("prefix search = %+v", results)
+	}
+	if results := search(SearchFilter{Query: `"dune* (`}); len(results) != 1 || results[0].ID != dune.ID {
+		t.Errorf("search with operators = %+v", results)
+	}
+	if results := search(SearchFilter{Query: "  !!  "}); len(results) != 0 {
+		t.Errorf("empty search = %+v", results)
+	}
+
+	// The index follows updates and deletions
+	if err := store.Posts.Update(dune.ID, dune.UserID, "Foundation", "Psychohistory", science.ID, nil); err != nil {
+		t.Fatalf("update post: %v", err)
+	}
+	if results := search(SearchFilter{Query: "psychohistory"}); len(results) != 1 || results[0].ID != dune.ID {
+		t.Errorf("search after update = %+v", results)
+	}
+	if err := store.Posts.Delete(dune.ID); err != nil {
+		t.Fatalf("delete post: %v", err)
+	}
+	if results := search(SearchFilter{Query: "psychohistory"}); len(results) != 0 {
+		t.Errorf("search after delete = %+v", results)
+	}
+}
+
+func testPagination(t *testing.T, store *Store) {
+	alice := mustRegister(t, store, "alice")
+	bob := mustRegister(t, store, "bob")
+
+	// Five posts; the third is the most liked and the fourth the most commented
+	var ids []int
+	for _, title := range []string{"One", "Two", "Three", "Four", "Five"} {
+		ids = append(ids, mustCreatePost(t, store, alice.ID, title, "Random").ID)
+	}
+	for _, user := range []*User{alice, bob} {
+		if err := store.Reactions.TogglePostLike(user.ID, ids[2]); err != nil {
+			t.Fatalf("like post: %v", err)
+		}
+	}
+	if err := store.Reactions.TogglePostLike(bob.ID, ids[0]); err != nil {
+		t.Fatalf("like post: %v", err)
+	}
+	for i := 0; i < 3; i++ {
+		if err := store.Comments.Create(ids[3], bob.ID, "Comment"); err != nil {
+			t.Fatalf("create comment: %v", err)
+		}
+	}
+
+	// walk collects the post IDs of every page, two at a time
+	walk := func(sort string) []int {
+		t.Helper()
+		var got []int
+		page := PageRequest{Sort: sort, Limit: 2}
+		for pages := 0; pages < 10; pages++ {
+			result, err := store.Posts.GetFiltered(PostFilter{}, page)
+			if err != nil {
+				t.Fatalf("sort %s: %v", sort, err)
+			}
+			for _, post := range result.Posts {
+				got = append(got, post.ID)
+			}
+			if result.Next == "" {
+				return got
+			}
+			page.Cursor = result.Next
+		}
+		t.Fatalf("sort %s: too many pages", sort)
+		return nil
+	}
+
+	tests := []struct {
+		sort string
+		want []int
+	}{
+		{"", []int{ids[4], ids[3], ids[2], ids[1], ids[0]}},
+		{SortOldest, ids},
+		{SortMostLiked, []int{ids[2], ids[0], ids[4], ids[3], ids[1]}},
+		{SortMostCommented, []int{ids[3], ids[4], ids[2], ids[1], ids[0]}},
+	}
+	for _, test := range tests {
+		got := walk(test.sort)
+		if len(got) != len(test.want) {
+			t.Errorf("sort %q = %v, want %v", test.sort, got, test.want)
+			continue
+		}
+		for i := range got {
+			if got[i] != test.want[i] {
+				t.Errorf("sort %q = %v, want %v", test.sort, got, test.want)
+				break
+			}
+		}
+	}
+
+	first, err := store.Posts.GetFiltered(PostFilter{}, PageRequest{Sort: SortOldest, Limit: 2})
+	if err != nil || first.Posts[0].Likes != 1 || first.Next == "" {
+		t.Fatalf("first page = %+v, %v", first, err)
+	}
+	if _, err := store.Posts.GetFiltered(PostFilter{}, PageRequest{Sort: SortNewest, Cursor: first.Next}); !errors.Is(err, ErrInvalidCursor) {
+		t.Errorf("cursor of another sort order = %v, want ErrInvalidCursor", err)
+	}
+	if _, err := store.Posts.GetFiltered(PostFilter{}, PageRequest{Cursor: "not a cursor"}); !errors.Is(err, ErrInvalidCursor) {
+		t.Errorf("malformed cursor = %v, want ErrInvalidCursor", err)
+	}
+	if _, err := store.Posts.GetFiltered(PostFilter{}, PageRequest{Sort: "random"}); !errors.Is(err, ErrInvalidSort) {
+		t.Errorf("unknown sort = %v, want ErrInvalidSort", err)
+	}
+}
+
+func TestPollsMigration(t *testing.T) {
+	database, err := db.Open(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("open sqlite: %v", err)
+	}
+	defer database.Close()
+
+	migrateTestDatabase(t, database)
+	store := NewStore(database)
+	alice := mustRegister(t, store, "alice")
+	post := mustCreatePost(t, store, alice.ID, "Next read", "Random")
+	pollID, err := store.Polls.Create(Poll{PostID: post.ID, Question: "Which book next?", Options: []PollOption{{Label: "Dune"}, {Label: "Emma"}}})
+	if err != nil {
+		t.Fatalf("create poll: %v", err)
+	}
+	poll, err := store.Polls.GetForPost(post.ID, alice.ID)
+	if err != nil {
+		t.Fatalf("get poll: %v", err)
+	}
+	if err := store.Polls.Vote(pollID, alice.ID, []int{poll.Options[0].ID}); err != nil {
+		t.Fatalf("vote: %v", err)
+	}
+
+	rollBackTo(t, database, 20)
+	if err := db.Migrate(database); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+
+	// The post stays, its poll is gone
+	if _, err := store.Posts.GetByID(post.ID); err != nil {
+		t.Errorf("post after migrating: %v", err)
+	}
+	if _, err := store.Polls.GetForPost(post.ID, alice.ID); err != ErrNotFound {
+		t.Errorf("poll after migrating: error = %v, want ErrNotFound", err)
+	}
+}
@@ -0,0 +1,164 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"literary-lions/backend/src/internal/db"
+	"sort"
+	"strings"
+)
+
+// Tag is a free-form label on posts, such as a genre, an author, an era or a book title.
+// Tags are stored as slugs, so "Jane Austen" and "jane-austen" are the same tag.
+type Tag struct {
+	ID        int    `json:"id"`
+	Slug      string `json:"slug"`
+	PostCount int    `json:"post_count"` // Number of posts carrying the tag
+}
+
+const (
+	// MaxPostTags is the number of tags a post can carry.
+	MaxPostTags = 10
+	// maxTagLength is the length of the longest tag slug.
+	maxTagLength = 40
+)
+
+var (
+	// ErrInvalidTag is returned when a tag has no letters or digits or is too long.
+	ErrInvalidTag = fmt.Errorf("a tag needs letters or digits and at most %d characters", maxTagLength)
+	// ErrTooManyTags is returned when a post is given more than MaxPostTags tags.
+	ErrTooManyTags = fmt.Errorf("a post can have at most %d tags", MaxPostTags)
+)
+
+// NormalizeTags turns the tags typed for a post into distinct slugs, keeping their order.
+//
+// Parameters:
+//   - names: The tags as typed, e.g. "Jane Austen" or "regency-era".
+//
+// Returns:
+//   - []string: The slugs of the tags.
+//   - error: ErrInvalidTag or ErrTooManyTags; otherwise, nil.
+func NormalizeTags(names []string) ([]string, error) {
+	slugs := []string{}
+	seen := map[string]bool{}
+	for _, name := range names {
+		slug := Slugify(name)
+		if slug == "" || len(slug) > maxTagLength {
+			return nil, ErrInvalidTag
+		}
+		if !seen[slug] {
+			seen[slug] = true
+			slugs = append(slugs, slug)
+		}
+	}
+	if len(slugs) > MaxPostTags {
+		return nil, ErrTooManyTags
+	}
+	return slugs, nil
+}
+
+// splitTags reads the comma-separated tag slugs aggregated by postSelect, in alphabetical order.
+func splitTags(aggregated sql.NullString) []string {
+	if !aggregated.Valid || aggregated.String == "" {
+		return []string{}
+	}
+	tags := strings.Split(aggregated.String, ",")
+	sort.Strings(tags)
+	return tags
+}
+
+// tagConditions returns a WHERE clause per tag, each keeping the posts, aliased p,
+// that carry the tag, so that a listing narrowed by several tags keeps the posts carrying all of them.
+func tagConditions(tags []string) ([]string, []interface{}) {
+	var conditions []string
+	var args []interface{}
+	for _, tag := range tags {
+		if slug := Slugify(tag); slug != "" {
+			conditions = append(conditions, "p.id IN (SELECT pt.post_id FROM post_tags pt INNER JOIN tags t ON t.id = pt.tag_id WHERE t.slug = ?)")
+			args = append(args, slug)
+		}
+	}
+	return conditions, args
+}
+
+// setPostTags replaces the tags of a post, creating the tags that are new.
+func setPostTags(tx *db.Tx, postID int, slugs []string) error {
+	if _, err := tx.Exec("DELETE FROM post_tags WHERE post_id = ?", postID); err != nil {
+		return err
+	}
+	for _, slug := range slugs {
+		if _, err := tx.Exec("INSERT INTO tags (slug) VALUES (?) ON CONFLICT (slug) DO NOTHING", slug); err != nil {
+			return err
+		}
+		if _, err := tx.Exec("INSERT INTO post_tags (post_id, tag_id) SELECT ?, id FROM tags WHERE slug = ?", postID, slug); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// tagRepository implements TagRepository on top of a SQL database.
+type tagRepository struct {
+	db *db.DB
+}
+
+// tagSelect reads tags together with the number of forum posts carrying them,
+// leaving out the posts in the trash.
+const tagSelect = `
+        SELECT t.id, t.slug, (SELECT COUNT(*) FROM post_tags pt INNER JOIN posts p ON p.id = pt.post_id AND p.deleted_at IS NULL AND p.status = 'published' AND ` + forumPosts + ` WHERE pt.tag_id = t.id) AS post_count
+        FROM tags t`
+
+// tagInUse keeps the tags, aliased t, that a published forum post outside the
+// trash carries.
+const tagInUse = "EXISTS (SELECT 1 FROM post_tags pt INNER JOIN posts p ON p.id = pt.post_id AND p.deleted_at IS NULL AND p.status = 'published' AND " + forumPosts + " WHERE pt.tag_id = t.id)"
+
+// Autocomplete suggests the tags in use on forum posts that start with what the
+// user typed, the most used first. Tags found only in clubs are left out.
+//
+// Parameters:
+//   - prefix: The beginning of the tag; an empty prefix suggests the most used tags.
+//   - limit: The number of suggestions.
+//
+// Returns:
+//   - []Tag: The suggested tags.
+//   - error: An error if the query fails; otherwise, nil.
+func (r *tagRepository) Autocomplete(prefix string, limit int) ([]Tag, error) {
+	// Slugs only hold letters, digits and dashes, so the prefix needs no LIKE escaping
+	rows, err := r.db.Query(tagSelect+`
+        WHERE t.slug LIKE ? AND `+tagInUse+`
+        ORDER BY post_count DESC, t.slug
+        LIMIT ?`, Slugify(prefix)+"%", limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tags := []Tag{}
+	for rows.Next() {
+		var tag Tag
+		if err := rows.Scan(&tag.ID, &tag.Slug, &tag.PostCount); err != nil {
+			return nil, err
+		}
+		tags = append(tags, tag)
+	}
+	return tags, rows.Err()
+}
+
+// GetBySlug returns the tag with the given slug, if a published forum post
+// outside the trash carries it.
+//
+// Parameters:
+//   - slug: The slug of the tag; other spellings such as "Jane Austen" are turned into one.
+//
+// Returns:
+//   - Tag: The tag.
+//   - error: ErrNotFound if no such post carries the tag, or any other query error; otherwise, nil.
+func (r *tagRepository) GetBySlug(slug string) (Tag, error) {
+	var tag Tag
+	err := r.db.QueryRow(tagSelect+" WHERE t.slug = ? AND "+tagInUse, Slugify(slug)).Scan(&tag.ID, &tag.Slug, &tag.PostCount)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Tag{}, ErrNotFound
+	}
+	return tag, err
+}
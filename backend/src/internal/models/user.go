@@ -4,28 +4,114 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"literary-lions/backend/src/internal/db"
 	"strings"
+	"time"
+	"unicode/utf8"
+
 	"golang.org/x/crypto/bcrypt"
 )
 
-var db *sql.DB
-
+// User is a member of the forum along with the profile they show to others.
 type User struct {
-	ID       	int
-	Email    	string
-	Username 	string
-	Password 	string
-	Role     	string
+	ID             int       `json:"id"`
+	Email          string    `json:"email,omitempty"` // Left out of public profiles
+	Username       string    `json:"username"`
+	Password       string    `json:"password,omitempty"` // Password hash, never sent back
+	Role           string    `json:"role"`
+	ProfilePic     string    `json:"profile_pic"` // Storage key of the avatar, empty for none
+	Bio            string    `json:"bio"`
+	Location       string    `json:"location"`
+	FavoriteBooks  []string  `json:"favorite_books"`
+	FavoriteGenres []string  `json:"favorite_genres"`
+	HideLikedPosts bool      `json:"hide_liked_posts"`   // Whether others are kept from listing the posts they like
+	CreatedAt      time.Time `json:"created_at"`         // When the member joined
+	Activity       *Activity `json:"activity,omitempty"` // What they wrote and the reactions it received, filled in on the public profile
 }
 
-// SetDatabase initializes the global database instance with the provided database connection.
-// Parameters:
-//   - databaseInstance: The database connection instance to be set as the global db variable.
-func SetDatabase(databaseInstance *sql.DB) {
-	db = databaseInstance
+// Activity sums up what a member wrote, leaving out drafts and the trash, and
+// the reactions others gave to it.
+type Activity struct {
+	Posts     int             `json:"posts"`
+	Comments  int             `json:"comments"`
+	Likes     int             `json:"likes"`     // Likes received on their posts and comments
+	Dislikes  int             `json:"dislikes"`  // Dislikes received on their posts and comments
+	Reactions []ReactionCount `json:"reactions"` // Counts of the configured reactions received, in the order of the set
+}
+
+const (
+	// MaxBioLength is the number of characters a bio can have.
+	MaxBioLength = 1000
+	// MaxFavorites is the number of favorite books, or genres, a profile can list.
+	MaxFavorites = 10
+	// maxLocationLength is the number of characters a location can have.
+	maxLocationLength = 100
+	// maxFavoriteLength is the number of characters a favorite book or genre can have.
+	maxFavoriteLength = 100
+)
+
+// ErrInvalidProfile is returned for profiles with fields that are too long or
+// too many favorites.
+var ErrInvalidProfile = fmt.Errorf("bios can have at most %d characters, locations %d, and favorite books and genres %d entries of %d characters",
+	MaxBioLength, maxLocationLength, MaxFavorites, maxFavoriteLength)
+
+// userSelect reads users along with their profile.
+const userSelect = `
+        SELECT id, email, username, password, role, avatar_key, bio, location, favorite_books, favorite_genres, hide_liked_posts, created_at
+        FROM users`
+
+// scanUser reads a row selected with userSelect.
+func scanUser(row interface{ Scan(...interface{}) error }) (*User, error) {
+	var user User
+	var favoriteBooks, favoriteGenres string
+	var createdAt sql.NullTime
+	err := row.Scan(&user.ID, &user.Email, &user.Username, &user.Password, &user.Role,
+		&user.ProfilePic, &user.Bio, &user.Location, &favoriteBooks, &favoriteGenres, &user.HideLikedPosts, &createdAt)
+	if err != nil {
+		return nil, err
+	}
+	user.FavoriteBooks = splitFavorites(favoriteBooks)
+	user.FavoriteGenres = splitFavorites(favoriteGenres)
+	user.CreatedAt = createdAt.Time
+	return &user, nil
+}
+
+// splitFavorites reads favorites stored one per line.
+func splitFavorites(stored string) []string {
+	if stored == "" {
+		return []string{}
+	}
+	return strings.Split(stored, "\n")
 }
 
-// RegisterUser creates a new user record in the database with the provided email, username, and password.
+// cleanFavorites collapses the spaces in favorites and drops the empty and
+// repeated ones, ignoring case.
+func cleanFavorites(favorites []string) ([]string, error) {
+	cleaned := []string{}
+	seen := map[string]bool{}
+	for _, favorite := range favorites {
+		favorite = strings.Join(strings.Fields(favorite), " ")
+		if favorite == "" || seen[strings.ToLower(favorite)] {
+			continue
+		}
+		if utf8.RuneCountInString(favorite) > maxFavoriteLength {
+			return nil, ErrInvalidProfile
+		}
+		seen[strings.ToLower(favorite)] = true
+		cleaned = append(cleaned, favorite)
+	}
+	if len(cleaned) > MaxFavorites {
+		return nil, ErrInvalidProfile
+	}
+	return cleaned, nil
+}
+
+// userRepository implements UserRepository on top of a SQL database.
+type userRepository struct {
+	db *db.DB
+}
+
+// Register creates a new user record in the database with the provided email, username, and password.
 // The password is hashed before being stored.
 //
 // Parameters:
@@ -35,20 +121,7 @@ func SetDatabase(databaseInstance *sql.DB) {
 //
 // Returns:
 //   - error: An error if the user registration fails; otherwise, nil.
-
-// func RegisterUser(email, username, password string) error {
-// 	// Hash the password using bcrypt
-// 	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
-// 	if err != nil {
-// 		return err // Return the error if password hashing fails
-// 	}
-
-// 	// Insert the new user record into the database
-// 	_, err = db.Exec("INSERT INTO users (email, username, password, role) VALUES (?, ?, ?, 'user')", email, username, string(hashedPassword))
-// 	return err // Return any error encountered during insertion
-// }
-
-func RegisterUser(email, username, password string) error {
+func (r *userRepository) Register(email, username, password string) error {
 	// Hash the password using bcrypt
 	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
 	if err != nil {
@@ -56,22 +129,17 @@ func RegisterUser(email, username, password string) error {
 	}
 
 	// Insert the new user record into the database
-	_, err = db.Exec("INSERT INTO users (email, username, password, role) VALUES (?, ?, ?, 'user')", email, username, string(hashedPassword))
+	_, err = r.db.Exec("INSERT INTO users (email, username, password, role, created_at) VALUES (?, ?, ?, 'user', ?)", email, username, string(hashedPassword), time.Now().UTC())
 
 	// Check for uniqueness constraint errors
 	if err != nil {
-		if strings.Contains(err.Error(), "UNIQUE constraint failed: users.username") {
-			return errors.New("username already exists, please use another username")
-		} else if strings.Contains(err.Error(), "UNIQUE constraint failed: users.email") {
-			return errors.New("email already exists, please use another email")
-		}
-		return err // Return the error if it's not a uniqueness constraint error
+		return r.uniqueError(err)
 	}
 
 	return nil
 }
 
-// AuthenticateUser checks if the provided email and password match a user record in the database.
+// Authenticate checks if the provided email and password match a user record in the database.
 // It returns the user details if authentication is successful.
 //
 // Parameters:
@@ -81,12 +149,9 @@ func RegisterUser(email, username, password string) error {
 // Returns:
 //   - *User: A pointer to the User object if authentication is successful; otherwise, nil.
 //   - error: An error if the user is not found or if the password is incorrect; otherwise, nil.
-func AuthenticateUser(email, password string) (*User, error) {
-	user := &User{}
-
+func (r *userRepository) Authenticate(email, password string) (*User, error) {
 	// Query to retrieve user details based on the provided email
-	row := db.QueryRow("SELECT id, email, username, password FROM users WHERE email = ?", email)
-	err := row.Scan(&user.ID, &user.Email, &user.Username, &user.Password)
+	user, err := scanUser(r.db.QueryRow(userSelect+" WHERE email = ?", email))
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, errors.New("user not found") // Return an error if the user does not exist
@@ -103,7 +168,7 @@ func AuthenticateUser(email, password string) (*User, error) {
 	return user, nil // Return the user details if authentication is successful
 }
 
-// GetUser retrieves a user record from the database based on the provided user ID.
+// GetByID retrieves a user record from the database based on the provided user ID.
 //
 // Parameters:
 //   - userID: The ID of the user to retrieve.
@@ -111,13 +176,9 @@ func AuthenticateUser(email, password string) (*User, error) {
 // Returns:
 //   - *User: A pointer to the User object if the user is found; otherwise, nil.
 //   - error: An error if the user is not found or if any other issue occurs; otherwise, nil.
-func GetUser(userID int) (*User, error) {
-	user := &User{}
-
+func (r *userRepository) GetByID(userID int) (*User, error) {
 	// Query to retrieve user details based on the provided user ID
-	row := db.QueryRow("SELECT id, email, username, password FROM users WHERE id = ?", userID)
-
-	err := row.Scan(&user.ID, &user.Email, &user.Username, &user.Password)
+	user, err := scanUser(r.db.QueryRow(userSelect+" WHERE id = ?", userID))
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, errors.New("user not found") // Return an error if the user does not exist
@@ -128,31 +189,99 @@ func GetUser(userID int) (*User, error) {
 	return user, nil // Return the user details if found
 }
 
-// ProfileUpdate updates the email and username for a user with the specified user ID.
+// FindByEmail looks a user up by email address, ignoring case.
 //
 // Parameters:
-//   - userID: The ID of the user whose profile is being updated.
-//   - email: The new email address for the user.
-//   - username: The new username for the user.
+//   - email: The email address to look for.
 //
 // Returns:
-//   - error: An error if the profile update fails; otherwise, nil.
+//   - *User: A pointer to the User object if the user is found; otherwise, nil.
+//   - error: An error if the user is not found or if any other issue occurs; otherwise, nil.
+func (r *userRepository) FindByEmail(email string) (*User, error) {
+	user, err := scanUser(r.db.QueryRow(userSelect+" WHERE LOWER(email) = LOWER(?)", email))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errors.New("user not found")
+		}
+		// Log the error for debugging
+		fmt.Printf("Error querying user by email: %v\n", err)
+		return nil, err
+	}
 
-func ProfileUpdate(userID int, email, username string) error {
-	// Prepare the SQL statement for updating the user profile
-	stmt, err := db.Prepare("UPDATE users SET email = ?, username = ? WHERE id = ?")
+	return user, nil
+}
+
+// GetByUsername retrieves the user with the given username, for their public profile.
+//
+// Parameters:
+//   - username: The username of the user to retrieve.
+//
+// Returns:
+//   - *User: A pointer to the User object if the user is found; otherwise, nil.
+//   - error: ErrNotFound if no user has the username, or any other query error; otherwise, nil.
+func (r *userRepository) GetByUsername(username string) (*User, error) {
+	user, err := scanUser(r.db.QueryRow(userSelect+" WHERE username = ?", username))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	return user, err
+}
+
+// List returns every user without their password hashes.
+//
+// Returns:
+//   - []User: All users in the database.
+//   - error: An error if the query fails; otherwise, nil.
+func (r *userRepository) List() ([]User, error) {
+	rows, err := r.db.Query(userSelect)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close() // Ensure the rows are closed after use
+
+	var users []User
+	for rows.Next() {
+		user, err := scanUser(rows)
+		if err != nil {
+			return nil, err
+		}
+		user.Password = ""
+		users = append(users, *user)
+	}
+
+	return users, rows.Err()
+}
+
+// UpdateProfile updates the email, username, profile and privacy settings of a
+// user. Favorites are cleaned up as they are stored.
+//
+// Parameters:
+//   - user: The user whose profile is being updated, identified by its ID, with the new email,
+//     username, bio, location, favorite books and genres, and whether their likes are hidden.
+//
+// Returns:
+//   - error: ErrInvalidProfile for fields that are too long, an error for a username or
+//     email taken by someone else, or any other error from the update; otherwise, nil.
+func (r *userRepository) UpdateProfile(user User) error {
+	favoriteBooks, err := cleanFavorites(user.FavoriteBooks)
 	if err != nil {
-		return fmt.Errorf("failed to prepare SQL statement: %w", err)
+		return err
+	}
+	favoriteGenres, err := cleanFavorites(user.FavoriteGenres)
+	if err != nil {
+		return err
+	}
+	bio, location := strings.TrimSpace(user.Bio), strings.Join(strings.Fields(user.Location), " ")
+	if utf8.RuneCountInString(bio) > MaxBioLength || utf8.RuneCountInString(location) > maxLocationLength {
+		return ErrInvalidProfile
 	}
-	defer stmt.Close()
 
 	// Execute the SQL statement to update the user profile
-	_, err = stmt.Exec(email, username, userID)
+	_, err = r.db.Exec("UPDATE users SET email = ?, username = ?, bio = ?, location = ?, favorite_books = ?, favorite_genres = ?, hide_liked_posts = ? WHERE id = ?",
+		user.Email, user.Username, bio, location, strings.Join(favoriteBooks, "\n"), strings.Join(favoriteGenres, "\n"), user.HideLikedPosts, user.ID)
 	if err != nil {
-		if strings.Contains(err.Error(), "UNIQUE constraint failed: users.username") {
-			return errors.New("username already exists, please use another username")
-		} else if strings.Contains(err.Error(), "UNIQUE constraint failed: users.email") {
-			return errors.New("email already exists, please use another email")
+		if uniqueErr := r.uniqueError(err); uniqueErr != err {
+			return uniqueErr
 		}
 		return fmt.Errorf("failed to execute SQL statement: %w", err)
 	}
@@ -160,20 +289,147 @@ func ProfileUpdate(userID int, email, username string) error {
 	return nil // Return nil if the profile update is successful
 }
 
-func FindUserByEmail(tx *sql.Tx, email string) (*User, error) {
-	var user User
-	err := tx.QueryRow("SELECT id, email, username, password FROM users WHERE LOWER(email) = LOWER(?)", email).Scan(&user.ID, &user.Email, &user.Username, &user.Password)
+// SetAvatar sets or removes the profile picture of a user. The file of the
+// previous one stays in storage until the cleanup of unused uploads.
+//
+// Parameters:
+//   - userID: The ID of the user.
+//   - key: The storage key of the avatar, or an empty string to remove it.
+//
+// Returns:
+//   - error: ErrNotFound if no user has the ID, or any other error from the update; otherwise, nil.
+func (r *userRepository) SetAvatar(userID int, key string) error {
+	result, err := r.db.Exec("UPDATE users SET avatar_key = ? WHERE id = ?", key, userID)
+	if err != nil {
+		return err
+	}
+	return requireAffected(result)
+}
+
+// Activity counts the posts and comments of a user that others can see, and the
+// reactions others gave to them.
+//
+// Parameters:
+//   - userID: The ID of the user.
+//   - reactions: The reactions counted besides likes and dislikes.
+//
+// Returns:
+//   - Activity: The counts, with every reaction of the set.
+//   - error: An error if a query fails; otherwise, nil.
+func (r *userRepository) Activity(userID int, reactions ReactionSet) (Activity, error) {
+	// What others can see of the user: published forum posts and the comments on them, outside the trash
+	const written = `
+        WITH written_posts AS (
+            SELECT id FROM posts WHERE user_id = ? AND deleted_at IS NULL AND status = 'published' AND club_id IS NULL
+        ),
+        written_comments AS (
+            SELECT c.id FROM comments c
+            INNER JOIN posts p ON p.id = c.post_id AND p.deleted_at IS NULL AND p.status = 'published' AND ` + forumPosts + `
+            WHERE c.user_id = ? AND c.deleted_at IS NULL
+        )`
 
+	activity := Activity{Reactions: make([]ReactionCount, 0, len(reactions))}
+	err := r.db.QueryRow(written+" SELECT (SELECT COUNT(*) FROM written_posts), (SELECT COUNT(*) FROM written_comments)", userID, userID).
+		Scan(&activity.Posts, &activity.Comments)
 	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			return nil, errors.New("user not found")
+		return Activity{}, err
+	}
+
+	rows, err := r.db.Query(written+`
+        SELECT r.reaction, COUNT(*) FROM reactions r
+        WHERE r.user_id <> ? AND ((r.target_type = 'post' AND r.target_id IN (SELECT id FROM written_posts))
+           OR (r.target_type = 'comment' AND r.target_id IN (SELECT id FROM written_comments)))
+        GROUP BY r.reaction`, userID, userID, userID)
+	if err != nil {
+		return Activity{}, err
+	}
+	defer rows.Close()
+
+	received := map[string]int{}
+	for rows.Next() {
+		var reaction string
+		var count int
+		if err := rows.Scan(&reaction, &count); err != nil {
+			return Activity{}, err
 		}
-		// Log the error for debugging
-		fmt.Printf("Error querying user by email: %v\n", err)
-		return nil, err
+		received[reaction] = count
+	}
+	if err := rows.Err(); err != nil {
+		return Activity{}, err
 	}
 
-	// Log user details for debugging
-	return &user, nil
+	activity.Likes, activity.Dislikes = received[ReactionLike], received[ReactionDislike]
+	for _, reaction := range reactions {
+		activity.Reactions = append(activity.Reactions, ReactionCount{Reaction: reaction, Count: received[reaction]})
+	}
+	return activity, nil
 }
 
+// Update overwrites the username, email and role of an existing user.
+//
+// Parameters:
+//   - user: The user to update, identified by its ID.
+//
+// Returns:
+//   - error: ErrNotFound if no user has the ID, or any other error from the update; otherwise, nil.
+func (r *userRepository) Update(user User) error {
+	result, err := r.db.Exec("UPDATE users SET username = ?, email = ?, role = ? WHERE id = ?", user.Username, user.Email, user.Role, user.ID)
+	if err != nil {
+		return r.uniqueError(err)
+	}
+	return requireAffected(result)
+}
+
+// UpdateRole changes the role of an existing user.
+//
+// Parameters:
+//   - userID: The ID of the user to update.
+//   - role: The new role, "user", "curator" or "admin".
+//
+// Returns:
+//   - error: ErrNotFound if no user has the ID, or any other error from the update; otherwise, nil.
+func (r *userRepository) UpdateRole(userID int, role string) error {
+	result, err := r.db.Exec("UPDATE users SET role = ? WHERE id = ?", role, userID)
+	if err != nil {
+		return err
+	}
+	return requireAffected(result)
+}
+
+// Delete removes a user.
+//
+// Parameters:
+//   - userID: The ID of the user to delete.
+//
+// Returns:
+//   - error: ErrNotFound if no user has the ID, or any other error from the deletion; otherwise, nil.
+func (r *userRepository) Delete(userID int) error {
+	result, err := r.db.Exec("DELETE FROM users WHERE id = ?", userID)
+	if err != nil {
+		return err
+	}
+	return requireAffected(result)
+}
+
+// uniqueError turns unique constraint failures on the username or email into
+// user-facing errors and returns any other error unchanged.
+func (r *userRepository) uniqueError(err error) error {
+	if r.db.Dialect.IsUniqueViolation(err, "username") {
+		return errors.New("username already exists, please use another username")
+	} else if r.db.Dialect.IsUniqueViolation(err, "email") {
+		return errors.New("email already exists, please use another email")
+	}
+	return err // Return the error if it's not a uniqueness constraint error
+}
+
+// requireAffected returns ErrNotFound when a statement did not change any row.
+func requireAffected(result sql.Result) error {
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
@@ -0,0 +1,155 @@
+// Package storage keeps uploaded files under keys derived from their content,
+// so that a file uploaded many times is stored once.
+package storage
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"time"
+)
+
+var (
+	// ErrNotFound is returned when no file is stored under a key.
+	ErrNotFound = errors.New("file not found")
+	// ErrInvalidKey is returned for keys that no stored file could have.
+	ErrInvalidKey = errors.New("invalid file key")
+)
+
+// Storage stores files under content-derived keys. The handlers only go
+// through it, so the local disk can be swapped for another backend.
+type Storage interface {
+	// Put stores data and returns its key, the SHA-256 of data followed by "." and ext.
+	// Storing a file that is already there marks it as stored again.
+	Put(data []byte, ext string) (string, error)
+	// Open returns the file stored under key, or ErrNotFound.
+	Open(key string) (io.ReadSeekCloser, error)
+	// Delete removes the file stored under key. Removing a missing file is not an error.
+	Delete(key string) error
+	// Walk calls fn with the key of every stored file and the time it was last stored.
+	Walk(fn func(key string, storedAt time.Time) error) error
+}
+
+// keyPattern matches the keys handed out by Put.
+var keyPattern = regexp.MustCompile(`^[0-9a-f]{64}\.[a-z0-9]{1,5}$`)
+
+// Key returns the key data is stored under with the given extension.
+func Key(data []byte, ext string) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]) + "." + ext
+}
+
+// Local stores files in a directory on the local disk, spread over
+// subdirectories named after the first two characters of their keys.
+type Local struct {
+	dir string
+}
+
+// NewLocal returns a Local storing its files under dir, creating it if needed.
+//
+// Parameters:
+//   - dir: The directory holding the files.
+//
+// Returns:
+//   - *Local: The storage.
+//   - error: An error if the directory cannot be created; otherwise, nil.
+func NewLocal(dir string) (*Local, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &Local{dir: dir}, nil
+}
+
+// path returns where the file with the given key is kept, or ErrInvalidKey,
+// which also keeps keys from reaching outside the directory.
+func (l *Local) path(key string) (string, error) {
+	if !keyPattern.MatchString(key) {
+		return "", ErrInvalidKey
+	}
+	return filepath.Join(l.dir, key[:2], key), nil
+}
+
+// Put stores data on disk. The file is written under a temporary name and
+// renamed once complete, so that a file is never seen half written.
+func (l *Local) Put(data []byte, ext string) (string, error) {
+	key := Key(data, ext)
+	path, err := l.path(key)
+	if err != nil {
+		return "", err
+	}
+
+	// Same content, same key: touch the file so the cleanup keeps it
+	if _, err := os.Stat(path); err == nil {
+		now := time.Now()
+		return key, os.Chtimes(path, now, now)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return "", err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".upload-*")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(tmp.Name()) // No-op once the file is renamed.
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return "", err
+	}
+	if err := tmp.Close(); err != nil {
+		return "", err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return "", err
+	}
+	return key, nil
+}
+
+// Open opens the file stored under key.
+func (l *Local) Open(key string) (io.ReadSeekCloser, error) {
+	path, err := l.path(key)
+	if err != nil {
+		return nil, err
+	}
+	file, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	return file, err
+}
+
+// Delete removes the file stored under key.
+func (l *Local) Delete(key string) error {
+	path, err := l.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// Walk goes through the files on disk, skipping those being written.
+func (l *Local) Walk(fn func(key string, storedAt time.Time) error) error {
+	return filepath.WalkDir(l.dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() || !keyPattern.MatchString(entry.Name()) {
+			return nil
+		}
+		info, err := entry.Info()
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil // Deleted in the meantime
+		} else if err != nil {
+			return err
+		}
+		return fn(entry.Name(), info.ModTime())
+	})
+}
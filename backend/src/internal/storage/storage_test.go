@@ -0,0 +1,83 @@
+package storage
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestLocal(t *testing.T) {
+	dir := t.TempDir()
+	files, err := NewLocal(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	key, err := files.Put([]byte("cover"), "png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if key != Key([]byte("cover"), "png") || !keyPattern.MatchString(key) {
+		t.Fatalf("Put returned key %q", key)
+	}
+
+	// Storing the same content again touches the existing file
+	path := filepath.Join(dir, key[:2], key)
+	old := time.Now().Add(-48 * time.Hour)
+	if err := os.Chtimes(path, old, old); err != nil {
+		t.Fatal(err)
+	}
+	if again, err := files.Put([]byte("cover"), "png"); err != nil || again != key {
+		t.Fatalf("second Put = %q, %v, want %q", again, err, key)
+	}
+
+	var walked []string
+	err = files.Walk(func(key string, storedAt time.Time) error {
+		if !storedAt.After(old) {
+			t.Errorf("%s stored at %v, want it touched by the second Put", key, storedAt)
+		}
+		walked = append(walked, key)
+		return nil
+	})
+	if err != nil || len(walked) != 1 || walked[0] != key {
+		t.Fatalf("Walk saw %v, %v, want only %s", walked, err, key)
+	}
+
+	file, err := files.Open(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := io.ReadAll(file)
+	file.Close()
+	if err != nil || string(data) != "cover" {
+		t.Fatalf("Open read %q, %v", data, err)
+	}
+
+	if err := files.Delete(key); err != nil {
+		t.Fatal(err)
+	}
+	if err := files.Delete(key); err != nil {
+		t.Errorf("deleting a missing file: %v", err)
+	}
+	if _, err := files.Open(key); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Open after Delete: %v, want ErrNotFound", err)
+	}
+}
+
+func TestLocalRejectsInvalidKeys(t *testing.T) {
+	files, err := NewLocal(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range []string{"", "../../etc/passwd", "ab/../cd.png", Key(nil, "png") + "/x", Key(nil, "PNG")} {
+		if _, err := files.Open(key); !errors.Is(err, ErrInvalidKey) {
+			t.Errorf("Open(%q): %v, want ErrInvalidKey", key, err)
+		}
+		if err := files.Delete(key); !errors.Is(err, ErrInvalidKey) {
+			t.Errorf("Delete(%q): %v, want ErrInvalidKey", key, err)
+		}
+	}
+}
@@ -0,0 +1,522 @@
+package handlers
+
+import (
+	"encoding/json"
+	"literary-lions/frontend/src/config"
+	"literary-lions/frontend/src/models"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// catalogSize is the number of books offered in the book pickers and on the catalog page.
+const catalogSize = 100
+
+// loadBooks fetches the books of the catalog, offered when linking books to a post.
+// A failure is logged and offers no books, so that the page still renders.
+func loadBooks() []models.Book {
+	respChan := make(chan []models.Book, 1)
+	statusChan := make(chan int, 1)
+	var wg sync.WaitGroup
+
+	wg.Add(1)
+	go SendGetBooksRequest(config.BaseApi+"/books?limit="+strconv.Itoa(catalogSize), nil, &wg, respChan, statusChan)
+	go func() {
+		wg.Wait()
+		close(respChan)
+		close(statusChan)
+	}()
+
+	books := <-respChan
+	if status := <-statusChan; status != http.StatusOK {
+		log.Printf("Failed to fetch books: status %d", status)
+	}
+	return books
+}
+
+// formBookIDs reads the books picked in the book_ids field of a form.
+func formBookIDs(r *http.Request) []int {
+	ids := []int{}
+	for _, value := range r.Form["book_ids"] {
+		if id, err := strconv.Atoi(value); err == nil {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// bookRefs names the picked books, to show a form again with them selected.
+func bookRefs(ids []int) []models.BookRef {
+	books := []models.BookRef{}
+	for _, id := range ids {
+		books = append(books, models.BookRef{ID: id})
+	}
+	return books
+}
+
+// bookOptions returns the books offered in a book picker, the catalog followed by
+// the linked books it leaves out, such as those waiting for approval, together with
+// the IDs of the linked books to select.
+func bookOptions(linked []models.BookRef) ([]models.Book, map[int]bool) {
+	books := loadBooks()
+	listed := map[int]bool{}
+	for _, book := range books {
+		listed[book.ID] = true
+	}
+	picked := map[int]bool{}
+	for _, book := range linked {
+		picked[book.ID] = true
+		if listed[book.ID] {
+			continue
+		}
+		if book.Title == "" {
+			// Picked in a form, fetch the title of the book
+			page, status := getBook(strconv.Itoa(book.ID) + "?limit=1")
+			if status != http.StatusOK {
+				continue
+			}
+			book.Title = page.Book.Title
+		}
+		books = append(books, models.Book{ID: book.ID, Title: book.Title})
+	}
+	return books, picked
+}
+
+// splitAuthors reads the authors typed one per line in a form.
+func splitAuthors(typed string) []string {
+	authors := []string{}
+	for _, author := range strings.Split(typed, "\n") {
+		if author = strings.TrimSpace(author); author != "" {
+			authors = append(authors, author)
+		}
+	}
+	return authors
+}
+
+// ShowBooks renders the catalog, searched with the q query parameter and ordered by
+// the sort one, with the form to add a book. Admins and curators also see the books
+// waiting for approval. A POST adds the book of the form, with the image chosen as its cover.
+func ShowBooks(w http.ResponseWriter, r *http.Request) {
+	currentUser, authenticated := isAuthenticated(r)
+	query := r.URL.Query().Get("q")
+	sort := r.URL.Query().Get("sort")
+	cookieToken, cookieErr := r.Cookie("session_token")
+
+	// renderPage shows the catalog, keeping what was typed in the form after an error
+	renderPage := func(book models.Book, authors, message string) {
+		params := url.Values{}
+		params.Set("limit", strconv.Itoa(catalogSize))
+		if query != "" {
+			params.Set("q", query)
+		}
+		if sort != "" {
+			params.Set("sort", sort)
+		}
+
+		catalogChan := make(chan []models.Book, 1)
+		catalogStatusChan := make(chan int, 1)
+		pendingChan := make(chan []models.Book, 1)
+		pendingStatusChan := make(chan int, 1)
+		var wg sync.WaitGroup
+
+		wg.Add(1)
+		go SendGetBooksRequest(config.BaseApi+"/books?"+params.Encode(), nil, &wg, catalogChan, catalogStatusChan)
+		if cookieErr == nil {
+			// Only admins and curators are let in, others get 403 Forbidden
+			wg.Add(1)
+			go SendGetBooksRequest(config.BaseApi+"/curation/books", []*http.Cookie{cookieToken}, &wg, pendingChan, pendingStatusChan)
+		} else {
+			pendingChan <- nil
+			pendingStatusChan <- http.StatusUnauthorized
+		}
+		go func() {
+			wg.Wait()
+			close(catalogChan)
+			close(catalogStatusChan)
+			close(pendingChan)
+			close(pendingStatusChan)
+		}()
+
+		catalog := <-catalogChan
+		if status := <-catalogStatusChan; status != http.StatusOK {
+			StatusInternalServerError(w, "Failed to fetch the books")
+			return
+		}
+		pending := <-pendingChan
+		curator := <-pendingStatusChan == http.StatusOK
+
+		data := struct {
+			Books         []models.Book
+			Pending       []models.Book
+			Curator       bool
+			Query         string
+			Sort          string
+			Book          models.Book
+			Authors       string
+			Error         string
+			Authenticated bool
+			Username      string
+		}{
+			Books:         catalog,
+			Pending:       pending,
+			Curator:       curator,
+			Query:         query,
+			Sort:          sort,
+			Book:          book,
+			Authors:       authors,
+			Error:         message,
+			Authenticated: authenticated,
+			Username:      currentUser,
+		}
+		RenderTemplate(w, "books.html", data)
+	}
+
+	if r.Method != http.MethodPost {
+		renderPage(models.Book{}, "", "")
+		return
+	}
+
+	if cookieErr != nil {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+
+	r.ParseMultipartForm(maxFormMemory) // Also reads forms without a cover
+	book := models.Book{
+		Title:   r.FormValue("title"),
+		Authors: splitAuthors(r.FormValue("authors")),
+		ISBN:    r.FormValue("isbn"),
+	}
+	var responseDetails models.ResponseDetails
+	if year := strings.TrimSpace(r.FormValue("year")); year != "" {
+		var err error
+		if book.Year, err = strconv.Atoi(year); err != nil {
+			responseDetails = models.ResponseDetails{Status: http.StatusBadRequest, Message: "Invalid year"}
+		}
+	}
+
+	// Upload the cover first, so that a refused image adds no book
+	if responseDetails.Status == 0 {
+		var images []int
+		images, responseDetails = uploadImages(cookieToken, r, 0)
+		if len(images) > 0 {
+			book.CoverID = images[0]
+		}
+	}
+	if responseDetails.Status == http.StatusCreated {
+		respChan := make(chan models.ResponseDetails, 1)
+		var wg sync.WaitGroup
+
+		wg.Add(1)
+		go SendCreateBookRequest(cookieToken, book, &wg, respChan)
+		go func() {
+			wg.Wait()
+			close(respChan)
+		}()
+		responseDetails = <-respChan
+	}
+
+	switch responseDetails.Status {
+	case http.StatusCreated:
+		http.Redirect(w, r, "/book?id="+strconv.Itoa(responseDetails.ID), http.StatusSeeOther)
+	case http.StatusUnauthorized:
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+	case http.StatusBadRequest, http.StatusConflict, http.StatusRequestEntityTooLarge, http.StatusUnsupportedMediaType:
+		// Show what the backend rejected and keep what was typed
+		renderPage(book, r.FormValue("authors"), strings.TrimSpace(responseDetails.Message))
+	default:
+		StatusInternalServerError(w, "Oops! Something went wrong. Failed to add the book.")
+	}
+}
+
+// ShowBook renders the book named by the id query parameter with its rating, one
+// page of its reviews and one page of the posts discussing it.
+func ShowBook(w http.ResponseWriter, r *http.Request) {
+	showBook(w, r, r.URL.Query(), nil, "")
+}
+
+// ratingBar is a row of the histogram of the ratings of a book.
+type ratingBar struct {
+	Stars   int
+	Count   int
+	Percent int // Share of the reviews, for the width of the bar
+}
+
+// showBook renders the book page for the given query. The form to write a review
+// shows review, or the review of the logged-in member if nil, with message as its error.
+func showBook(w http.ResponseWriter, r *http.Request, query url.Values, review *models.Review, message string) {
+	id := query.Get("id")
+	sort := query.Get("sort")
+	reviewSort := query.Get("review_sort")
+
+	// Forward the sort order and cursor of the posts, and those of the reviews
+	params := url.Values{}
+	if sort != "" {
+		params.Set("sort", sort)
+	}
+	if cursor := query.Get("cursor"); cursor != "" {
+		params.Set("cursor", cursor)
+	}
+	reviewParams := url.Values{}
+	if reviewSort != "" {
+		reviewParams.Set("sort", reviewSort)
+	}
+	if cursor := query.Get("review_cursor"); cursor != "" {
+		reviewParams.Set("cursor", cursor)
+	}
+
+	// The session tells the backend whose review to return and which ones may be edited
+	var cookies []*http.Cookie
+	if cookieToken, err := r.Cookie("session_token"); err == nil {
+		cookies = append(cookies, cookieToken)
+	}
+
+	reviewsChan := make(chan models.ReviewPage, 1)
+	reviewsStatusChan := make(chan int, 1)
+	var wg sync.WaitGroup
+
+	wg.Add(1)
+	go SendGetReviewsRequest(config.BaseApi+"/books/"+url.PathEscape(id)+"/reviews?"+reviewParams.Encode(), cookies, &wg, reviewsChan, reviewsStatusChan)
+	go func() {
+		wg.Wait()
+		close(reviewsChan)
+		close(reviewsStatusChan)
+	}()
+
+	page, status := getBook(url.PathEscape(id)+"?"+params.Encode(), cookies...)
+	reviews, reviewsStatus := <-reviewsChan, <-reviewsStatusChan
+	if status == http.StatusNotFound || status == http.StatusBadRequest {
+		StatusInternalServerError(w, "This book is not in the catalog")
+		return
+	} else if status != http.StatusOK || reviewsStatus != http.StatusOK {
+		StatusInternalServerError(w, "Failed to fetch the book")
+		return
+	}
+
+	// Truncate content if necessary
+	for i := range page.Posts.Posts {
+		page.Posts.Posts[i].Content = truncateContent(page.Posts.Posts[i].Content, 150)
+	}
+
+	// List the histogram from 5 stars down to 1
+	var bars []ratingBar
+	for stars := 5; stars >= 1; stars-- {
+		bar := ratingBar{Stars: stars, Count: page.Book.Rating.Histogram[stars-1]}
+		if page.Book.Rating.Count > 0 {
+			bar.Percent = bar.Count * 100 / page.Book.Rating.Count
+		}
+		bars = append(bars, bar)
+	}
+
+	if review == nil {
+		review = page.MyReview
+	}
+
+	currentUser, authenticated := isAuthenticated(r)
+
+	data := struct {
+		Book            models.Book
+		Posts           []models.Post
+		RatingBars      []ratingBar
+		Reviews         []models.Review
+		MyReview        *models.Review
+		MyShelf         *models.ShelfEntry
+		Review          *models.Review
+		ReviewError     string
+		ReviewSort      string
+		NextReviewsURL  string
+		FirstReviewsURL string
+		Authenticated   bool
+		Username        string
+		Sort            string
+		NextURL         string
+		FirstURL        string
+	}{
+		Book:          page.Book,
+		Posts:         page.Posts.Posts,
+		RatingBars:    bars,
+		Reviews:       reviews.Reviews,
+		MyReview:      page.MyReview,
+		MyShelf:       page.MyShelf,
+		Review:        review,
+		ReviewError:   message,
+		ReviewSort:    reviewSort,
+		Authenticated: authenticated,
+		Username:      currentUser,
+		Sort:          sort,
+	}
+	if page.Posts.Next != "" {
+		data.NextURL = bookURL(query, "cursor", page.Posts.Next)
+	}
+	if query.Get("cursor") != "" {
+		data.FirstURL = bookURL(query, "cursor", "")
+	}
+	if reviews.Next != "" {
+		data.NextReviewsURL = bookURL(query, "review_cursor", reviews.Next) + "#reviews"
+	}
+	if query.Get("review_cursor") != "" {
+		data.FirstReviewsURL = bookURL(query, "review_cursor", "") + "#reviews"
+	}
+
+	RenderTemplate(w, "book.html", data)
+}
+
+// bookURL returns the URL of the book page for the query with key set to value,
+// or removed when value is empty, such as the next page of posts or reviews.
+func bookURL(query url.Values, key, value string) string {
+	params := url.Values{}
+	for name, values := range query {
+		params[name] = values
+	}
+	if value == "" {
+		params.Del(key)
+	} else {
+		params.Set(key, value)
+	}
+	return "/book?" + params.Encode()
+}
+
+// getBook fetches the book at the given path under /books/, with the query of its
+// page of posts, and returns it with the status code of the response. The session
+// cookie, if given, also fetches the review of the logged-in member.
+func getBook(path string, cookies ...*http.Cookie) (models.BookPage, int) {
+	respChan := make(chan models.BookPage, 1)
+	statusChan := make(chan int, 1)
+	var wg sync.WaitGroup
+
+	wg.Add(1)
+	go SendGetBookRequest(config.BaseApi+"/books/"+path, cookies, &wg, respChan, statusChan)
+	go func() {
+		wg.Wait()
+		close(respChan)
+		close(statusChan)
+	}()
+	return <-respChan, <-statusChan
+}
+
+// ApproveBook lists the book named by the id query parameter in the catalog, for
+// admins and curators, then shows the catalog again.
+func ApproveBook(w http.ResponseWriter, r *http.Request) {
+	curateBook(w, r, http.MethodPost, "/approve")
+}
+
+// DeleteBook removes the book named by the id query parameter, or turns it down
+// while it waits for approval, for admins and curators.
+func DeleteBook(w http.ResponseWriter, r *http.Request) {
+	curateBook(w, r, http.MethodDelete, "")
+}
+
+// curateBook sends a curation request about the book named by the id query
+// parameter to the backend, then shows the catalog again.
+func curateBook(w http.ResponseWriter, r *http.Request, method, action string) {
+	if r.Method != http.MethodPost {
+		http.Redirect(w, r, "/books", http.StatusSeeOther)
+		return
+	}
+	cookieToken, err := r.Cookie("session_token")
+	if err != nil {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+
+	respChan := make(chan models.ResponseDetails, 1)
+	var wg sync.WaitGroup
+
+	wg.Add(1)
+	apiURL := config.BaseApi + "/curation/books/" + url.PathEscape(r.URL.Query().Get("id")) + action
+	go func() {
+		defer wg.Done()
+		respChan <- sendToBackend(cookieToken, method, apiURL, nil)
+	}()
+	go func() {
+		wg.Wait()
+		close(respChan)
+	}()
+
+	switch responseDetails := <-respChan; responseDetails.Status {
+	case http.StatusOK:
+		http.Redirect(w, r, "/books", http.StatusSeeOther)
+	case http.StatusUnauthorized:
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+	case http.StatusForbidden:
+		StatusInternalServerError(w, "Only admins and curators can look after the catalog")
+	case http.StatusNotFound, http.StatusBadRequest:
+		StatusInternalServerError(w, "This book is not in the catalog")
+	default:
+		StatusInternalServerError(w, "Oops! Something went wrong. Failed to update the catalog.")
+	}
+}
+
+// SendGetBooksRequest fetches a list of books from the backend, with the given
+// cookies, and sends it on respChan and the status code of the response on statusChan.
+func SendGetBooksRequest(apiURL string, cookies []*http.Cookie, waitGroup *sync.WaitGroup, respChan chan []models.Book, statusChan chan int) {
+	defer waitGroup.Done()
+
+	var books []models.Book
+	body, status, err := getFromBackend(apiURL, cookies...)
+	if err != nil {
+		log.Print(err)
+		status = http.StatusInternalServerError
+	} else if status == http.StatusOK {
+		if err := json.Unmarshal(body, &books); err != nil {
+			log.Printf("Failed to parse books: %v", err)
+			status = http.StatusInternalServerError
+		}
+	}
+
+	respChan <- books
+	statusChan <- status
+}
+
+// SendGetBookRequest fetches a book and one page of the posts discussing it from
+// the backend, with the given cookies, and sends them on respChan and the status
+// code of the response on statusChan.
+func SendGetBookRequest(apiURL string, cookies []*http.Cookie, waitGroup *sync.WaitGroup, respChan chan models.BookPage, statusChan chan int) {
+	defer waitGroup.Done()
+
+	var page models.BookPage
+	body, status, err := getFromBackend(apiURL, cookies...)
+	if err != nil {
+		log.Print(err)
+		status = http.StatusInternalServerError
+	} else if status == http.StatusOK {
+		if err := json.Unmarshal(body, &page); err != nil {
+			log.Printf("Failed to parse book: %v", err)
+			status = http.StatusInternalServerError
+		}
+	}
+
+	respChan <- page
+	statusChan <- status
+}
+
+// SendCreateBookRequest adds a book to the catalog and sends the outcome on respChan.
+func SendCreateBookRequest(cookie *http.Cookie, book models.Book, waitGroup *sync.WaitGroup, respChan chan models.ResponseDetails) {
+	defer waitGroup.Done()
+	respChan <- sendToBackend(cookie, http.MethodPost, config.BaseApi+"/books", book)
+}
+
+// SendSetBooksRequest sets the books of a post or draft, named by its path on the
+// backend such as "/post/3" or "/drafts/4", and sends the outcome on respChan.
+func SendSetBooksRequest(cookie *http.Cookie, target string, ids []int, waitGroup *sync.WaitGroup, respChan chan models.ResponseDetails) {
+	defer waitGroup.Done()
+	payload := map[string]interface{}{"book_ids": ids}
+	respChan <- sendToBackend(cookie, http.MethodPut, config.BaseApi+target+"/books", payload)
+}
+
+// setBooks sets the books of the post or draft at target and returns the outcome.
+func setBooks(cookie *http.Cookie, target string, ids []int) models.ResponseDetails {
+	respChan := make(chan models.ResponseDetails, 1)
+	var wg sync.WaitGroup
+
+	wg.Add(1)
+	go SendSetBooksRequest(cookie, target, ids, &wg, respChan)
+	go func() {
+		wg.Wait()
+		close(respChan)
+	}()
+	return <-respChan
+}
@@ -0,0 +1,177 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"literary-lions/frontend/src/config"
+	"literary-lions/frontend/src/models"
+	"log"
+	"net/http"
+	"net/url"
+	"sync"
+)
+
+// loadCategories fetches the categories offered in the filters and the post form.
+// A failure is logged and gives no categories, so that the page still renders.
+func loadCategories() []models.Category {
+	respChan := make(chan []models.Category, 1)
+	var wg sync.WaitGroup
+
+	wg.Add(1)
+	go SendGetCategoriesRequest(&wg, respChan)
+	go func() {
+		wg.Wait()
+		close(respChan)
+	}()
+
+	return <-respChan
+}
+
+// ShowCategories renders the list of categories with their descriptions and post counts.
+func ShowCategories(w http.ResponseWriter, r *http.Request) {
+	currentUser, authenticated := isAuthenticated(r)
+
+	data := struct {
+		Categories    []models.Category
+		Authenticated bool
+		Username      string
+	}{
+		Categories:    loadCategories(),
+		Authenticated: authenticated,
+		Username:      currentUser,
+	}
+
+	RenderTemplate(w, "categories.html", data)
+}
+
+// ShowCategory renders one page of the posts filed under the category named by the slug query parameter.
+func ShowCategory(w http.ResponseWriter, r *http.Request) {
+	slug := r.URL.Query().Get("slug")
+	sort := r.URL.Query().Get("sort")
+
+	// Forward the sort order and cursor of the posts
+	params := url.Values{}
+	if sort != "" {
+		params.Set("sort", sort)
+	}
+	if cursor := r.URL.Query().Get("cursor"); cursor != "" {
+		params.Set("cursor", cursor)
+	}
+
+	respChan := make(chan models.CategoryPage, 1)
+	statusChan := make(chan int, 1)
+	var wg sync.WaitGroup
+
+	wg.Add(1)
+	apiURL := config.BaseApi + "/categories/" + url.PathEscape(slug) + "?" + params.Encode()
+	go SendGetCategoryRequest(apiURL, &wg, respChan, statusChan)
+	go func() {
+		wg.Wait()
+		close(respChan)
+		close(statusChan)
+	}()
+
+	page := <-respChan
+	status := <-statusChan
+
+	if status == http.StatusNotFound {
+		StatusInternalServerError(w, "This category does not exist")
+		return
+	} else if status != http.StatusOK {
+		StatusInternalServerError(w, "Failed to fetch the category")
+		return
+	}
+
+	// Truncate content if necessary
+	for i := range page.Posts.Posts {
+		page.Posts.Posts[i].Content = truncateContent(page.Posts.Posts[i].Content, 150)
+	}
+
+	currentUser, authenticated := isAuthenticated(r)
+
+	data := struct {
+		Category      models.Category
+		Posts         []models.Post
+		Authenticated bool
+		Username      string
+		Sort          string
+		NextURL       string
+		FirstURL      string
+	}{
+		Category:      page.Category,
+		Posts:         page.Posts.Posts,
+		Authenticated: authenticated,
+		Username:      currentUser,
+		Sort:          sort,
+		NextURL:       nextPageURL(r, page.Posts.Next),
+		FirstURL:      firstPageURL(r),
+	}
+
+	RenderTemplate(w, "category.html", data)
+}
+
+// SendGetCategoriesRequest fetches every category from the backend and sends them on respChan.
+func SendGetCategoriesRequest(waitGroup *sync.WaitGroup, respChan chan []models.Category) {
+	defer waitGroup.Done()
+
+	body, status, err := getFromBackend(config.BaseApi + "/categories")
+	if err != nil || status != http.StatusOK {
+		log.Printf("Failed to fetch categories: status %d, %v", status, err)
+		respChan <- nil
+		return
+	}
+
+	// Unmarshal the response directly into a slice of categories
+	var categories []models.Category
+	if err := json.Unmarshal(body, &categories); err != nil {
+		log.Printf("Failed to parse categories: %v", err)
+	}
+	respChan <- categories
+}
+
+// SendGetCategoryRequest fetches a category and one page of its posts from the backend,
+// and sends them on respChan and the status code of the response on statusChan.
+func SendGetCategoryRequest(apiURL string, waitGroup *sync.WaitGroup, respChan chan models.CategoryPage, statusChan chan int) {
+	defer waitGroup.Done()
+
+	var page models.CategoryPage
+	body, status, err := getFromBackend(apiURL)
+	if err != nil {
+		log.Print(err)
+		status = http.StatusInternalServerError
+	} else if status == http.StatusOK {
+		if err := json.Unmarshal(body, &page); err != nil {
+			log.Printf("Failed to parse category: %v", err)
+			status = http.StatusInternalServerError
+		}
+	}
+
+	respChan <- page
+	statusChan <- status
+}
+
+// getFromBackend sends a GET request to the backend and returns the body and status code of the response.
+// The given cookies, such as the session cookie, are sent along.
+func getFromBackend(apiURL string, cookies ...*http.Cookie) ([]byte, int, error) {
+	req, err := http.NewRequest("GET", apiURL, nil)
+	if err != nil {
+		return nil, 0, fmt.Errorf("error creating request: %v", err)
+	}
+	for _, cookie := range cookies {
+		req.AddCookie(cookie)
+	}
+
+	client := &http.Client{}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, 0, fmt.Errorf("request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, resp.StatusCode, fmt.Errorf("failed to read response: %v", err)
+	}
+	return body, resp.StatusCode, nil
+}
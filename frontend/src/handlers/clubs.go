@@ -0,0 +1,425 @@
+package handlers
+
+import (
+	"encoding/json"
+	"literary-lions/frontend/src/config"
+	"literary-lions/frontend/src/models"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// ShowClubs renders the book clubs with the form to start one. A POST starts the
+// club of the form, owned by the logged-in member, and shows its page.
+func ShowClubs(w http.ResponseWriter, r *http.Request) {
+	currentUser, authenticated := isAuthenticated(r)
+	cookieToken, cookieErr := r.Cookie("session_token")
+
+	// renderPage lists the clubs, keeping what was typed in the form after an error
+	renderPage := func(club models.ClubRequest, message string) {
+		// The session tells the backend which clubs the member belongs to
+		var cookies []*http.Cookie
+		if cookieErr == nil {
+			cookies = append(cookies, cookieToken)
+		}
+
+		respChan := make(chan []models.Club, 1)
+		statusChan := make(chan int, 1)
+		var wg sync.WaitGroup
+
+		wg.Add(1)
+		go SendGetClubsRequest(config.BaseApi+"/clubs", cookies, &wg, respChan, statusChan)
+		go func() {
+			wg.Wait()
+			close(respChan)
+			close(statusChan)
+		}()
+
+		clubs := <-respChan
+		if status := <-statusChan; status != http.StatusOK {
+			StatusInternalServerError(w, "Failed to fetch the clubs")
+			return
+		}
+
+		data := struct {
+			Clubs         []models.Club
+			Club          models.ClubRequest
+			Error         string
+			Authenticated bool
+			Username      string
+		}{
+			Clubs:         clubs,
+			Club:          club,
+			Error:         message,
+			Authenticated: authenticated,
+			Username:      currentUser,
+		}
+		RenderTemplate(w, "clubs.html", data)
+	}
+
+	if r.Method != http.MethodPost {
+		renderPage(models.ClubRequest{Visibility: "public"}, "")
+		return
+	}
+
+	if cookieErr != nil {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+
+	r.ParseForm()
+	club := models.ClubRequest{
+		Name:        r.FormValue("name"),
+		Description: r.FormValue("description"),
+		Visibility:  r.FormValue("visibility"),
+	}
+
+	respChan := make(chan models.ResponseDetails, 1)
+	var wg sync.WaitGroup
+
+	wg.Add(1)
+	go SendClubRequest(cookieToken, http.MethodPost, config.BaseApi+"/clubs", club, &wg, respChan)
+	go func() {
+		wg.Wait()
+		close(respChan)
+	}()
+
+	switch responseDetails := <-respChan; responseDetails.Status {
+	case http.StatusCreated:
+		http.Redirect(w, r, "/club?slug="+url.QueryEscape(responseDetails.Slug), http.StatusSeeOther)
+	case http.StatusUnauthorized:
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+	case http.StatusBadRequest, http.StatusConflict:
+		// Show what the backend rejected and keep what was typed
+		renderPage(club, strings.TrimSpace(responseDetails.Message))
+	default:
+		StatusInternalServerError(w, "Oops! Something went wrong. Failed to start the club.")
+	}
+}
+
+// ShowClub renders the club named by the slug query parameter with its members, its
+// reading schedule and one page of its posts, or of those discussing the section
+// named by the section query parameter, the form to write in it for its members,
+// the requests to join it for its moderators and the form to edit it for its owner.
+func ShowClub(w http.ResponseWriter, r *http.Request) {
+	showClub(w, r, nil, "")
+}
+
+// showClub renders the club page. The form to write a post shows post, if not nil,
+// with message as its error.
+func showClub(w http.ResponseWriter, r *http.Request, post *models.Post, message string) {
+	slug := r.URL.Query().Get("slug")
+	sort := r.URL.Query().Get("sort")
+	section, _ := strconv.Atoi(r.URL.Query().Get("section"))
+
+	// Forward the sort order, section and cursor of the posts
+	params := url.Values{}
+	if sort != "" {
+		params.Set("sort", sort)
+	}
+	if section != 0 {
+		params.Set("section", strconv.Itoa(section))
+	}
+	if cursor := r.URL.Query().Get("cursor"); cursor != "" {
+		params.Set("cursor", cursor)
+	}
+
+	// The session tells the backend whether the member may read a private club
+	var cookies []*http.Cookie
+	if cookieToken, err := r.Cookie("session_token"); err == nil {
+		cookies = append(cookies, cookieToken)
+	}
+
+	respChan := make(chan models.ClubPage, 1)
+	statusChan := make(chan int, 1)
+	var wg sync.WaitGroup
+
+	wg.Add(1)
+	apiURL := config.BaseApi + "/clubs/" + url.PathEscape(slug) + "?" + params.Encode()
+	go SendGetClubRequest(apiURL, cookies, &wg, respChan, statusChan)
+	go func() {
+		wg.Wait()
+		close(respChan)
+		close(statusChan)
+	}()
+
+	page := <-respChan
+	status := <-statusChan
+	if status == http.StatusNotFound {
+		StatusInternalServerError(w, "This club does not exist")
+		return
+	} else if status != http.StatusOK {
+		StatusInternalServerError(w, "Failed to fetch the club")
+		return
+	}
+
+	// Truncate content if necessary
+	for i := range page.Posts.Posts {
+		page.Posts.Posts[i].Content = truncateContent(page.Posts.Posts[i].Content, 150)
+	}
+
+	membership := page.Club.Membership
+	member := membership != nil && membership.Approved
+
+	// Only the members write in the club, and need the categories to file posts under
+	var categories []models.Category
+	if member {
+		categories = loadCategories()
+	}
+	if post == nil {
+		post = &models.Post{}
+	}
+
+	currentUser, authenticated := isAuthenticated(r)
+
+	data := struct {
+		Club          models.Club
+		Members       []models.ClubMember
+		Requests      []models.ClubMember
+		Sections      []models.ClubSection
+		Section       int
+		Posts         []models.Post
+		CanRead       bool
+		CanModerate   bool
+		Member        bool
+		Pending       bool
+		Owner         bool
+		Categories    []models.Category
+		Post          *models.Post
+		Tags          string
+		Error         string
+		Authenticated bool
+		Username      string
+		Sort          string
+		NextURL       string
+		FirstURL      string
+	}{
+		Club:          page.Club,
+		Members:       page.Members,
+		Requests:      page.Requests,
+		Sections:      page.Sections,
+		Section:       section,
+		Posts:         page.Posts.Posts,
+		CanRead:       page.CanRead,
+		CanModerate:   page.CanModerate,
+		Member:        member,
+		Pending:       membership != nil && !membership.Approved,
+		Owner:         member && membership.Role == "owner",
+		Categories:    categories,
+		Post:          post,
+		Tags:          strings.Join(post.Tags, ", "),
+		Error:         message,
+		Authenticated: authenticated,
+		Username:      currentUser,
+		Sort:          sort,
+		NextURL:       nextPageURL(r, page.Posts.Next),
+		FirstURL:      firstPageURL(r),
+	}
+
+	RenderTemplate(w, "club.html", data)
+}
+
+// clubURL returns the page of the club with the given slug.
+func clubURL(slug string) string {
+	return "/club?slug=" + url.QueryEscape(slug)
+}
+
+// CreateClubPost writes the post of the form in the club named by the slug query
+// parameter and shows the new post. The club page shows the form again with the
+// error if the backend rejects the post.
+func CreateClubPost(w http.ResponseWriter, r *http.Request) {
+	slug := r.URL.Query().Get("slug")
+	if r.Method != http.MethodPost {
+		http.Redirect(w, r, clubURL(slug), http.StatusSeeOther)
+		return
+	}
+	cookieToken, err := r.Cookie("session_token")
+	if err != nil {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+
+	r.ParseForm()
+	sectionID, _ := strconv.Atoi(r.FormValue("section_id"))
+	post := models.Post{
+		Category:  r.FormValue("category"),
+		Title:     r.FormValue("title"),
+		Content:   r.FormValue("content"),
+		Tags:      splitTags(r.FormValue("tags")),
+		SectionID: sectionID,
+	}
+
+	respChan := make(chan models.ResponseDetails, 1)
+	var wg sync.WaitGroup
+
+	wg.Add(1)
+	go SendClubRequest(cookieToken, http.MethodPost, config.BaseApi+"/clubs/"+url.PathEscape(slug)+"/posts", post, &wg, respChan)
+	go func() {
+		wg.Wait()
+		close(respChan)
+	}()
+
+	switch responseDetails := <-respChan; responseDetails.Status {
+	case http.StatusCreated:
+		http.Redirect(w, r, "/post?id="+strconv.Itoa(responseDetails.ID), http.StatusSeeOther)
+	case http.StatusUnauthorized:
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+	case http.StatusBadRequest:
+		// Show what the backend rejected and keep what was typed
+		showClub(w, r, &post, strings.TrimSpace(responseDetails.Message))
+	case http.StatusForbidden, http.StatusNotFound:
+		StatusInternalServerError(w, strings.TrimSpace(responseDetails.Message))
+	default:
+		StatusInternalServerError(w, "Oops! Something went wrong. Failed to create the post.")
+	}
+}
+
+// JoinClub joins the club named by the slug query parameter, or asks to join it if
+// it is private, then shows the club again.
+func JoinClub(w http.ResponseWriter, r *http.Request) {
+	slug := r.URL.Query().Get("slug")
+	changeClub(w, r, http.MethodPost, "/join", nil, clubURL(slug))
+}
+
+// LeaveClub leaves the club named by the slug query parameter, or withdraws the
+// request to join it, then shows the club again.
+func LeaveClub(w http.ResponseWriter, r *http.Request) {
+	slug := r.URL.Query().Get("slug")
+	changeClub(w, r, http.MethodDelete, "/membership", nil, clubURL(slug))
+}
+
+// EditClub saves the name, description and visibility of the form for the club
+// named by the slug query parameter, for its owner, then shows the club again.
+func EditClub(w http.ResponseWriter, r *http.Request) {
+	slug := r.URL.Query().Get("slug")
+	r.ParseForm()
+	club := models.ClubRequest{
+		Name:        r.FormValue("name"),
+		Description: r.FormValue("description"),
+		Visibility:  r.FormValue("visibility"),
+	}
+	changeClub(w, r, http.MethodPut, "", club, clubURL(slug))
+}
+
+// DeleteClub deletes the club named by the slug query parameter, for its owner,
+// then shows the clubs.
+func DeleteClub(w http.ResponseWriter, r *http.Request) {
+	changeClub(w, r, http.MethodDelete, "", nil, "/clubs")
+}
+
+// ModerateClubMember approves, removes, promotes or demotes the member named by
+// the username query parameter in the club named by the slug one, as the action
+// form field tells, for the moderators of the club.
+func ModerateClubMember(w http.ResponseWriter, r *http.Request) {
+	slug := r.URL.Query().Get("slug")
+	path := "/members/" + url.PathEscape(r.URL.Query().Get("username"))
+
+	r.ParseForm()
+	switch action := r.FormValue("action"); action {
+	case "approve":
+		changeClub(w, r, http.MethodPost, path+"/approve", nil, clubURL(slug))
+	case "remove":
+		changeClub(w, r, http.MethodDelete, path, nil, clubURL(slug))
+	case "moderator", "member":
+		changeClub(w, r, http.MethodPut, path+"/role", map[string]string{"role": action}, clubURL(slug))
+	default:
+		http.Redirect(w, r, clubURL(slug), http.StatusSeeOther)
+	}
+}
+
+// DeleteClubPost moves the post named by the id query parameter of the club named
+// by the slug one to the trash, for the moderators of the club.
+func DeleteClubPost(w http.ResponseWriter, r *http.Request) {
+	slug := r.URL.Query().Get("slug")
+	changeClub(w, r, http.MethodDelete, "/posts/"+url.PathEscape(r.URL.Query().Get("id")), nil, clubURL(slug))
+}
+
+// changeClub sends a request about the club named by the slug query parameter, at
+// path under the club on the backend, on behalf of the logged-in member, then
+// redirects to next.
+func changeClub(w http.ResponseWriter, r *http.Request, method, path string, payload interface{}, next string) {
+	slug := r.URL.Query().Get("slug")
+	if r.Method != http.MethodPost {
+		http.Redirect(w, r, clubURL(slug), http.StatusSeeOther)
+		return
+	}
+	cookieToken, err := r.Cookie("session_token")
+	if err != nil {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+
+	respChan := make(chan models.ResponseDetails, 1)
+	var wg sync.WaitGroup
+
+	wg.Add(1)
+	go SendClubRequest(cookieToken, method, config.BaseApi+"/clubs/"+url.PathEscape(slug)+path, payload, &wg, respChan)
+	go func() {
+		wg.Wait()
+		close(respChan)
+	}()
+
+	switch responseDetails := <-respChan; responseDetails.Status {
+	case http.StatusOK, http.StatusCreated:
+		http.Redirect(w, r, next, http.StatusSeeOther)
+	case http.StatusUnauthorized:
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+	case http.StatusBadRequest, http.StatusForbidden, http.StatusNotFound, http.StatusConflict:
+		StatusInternalServerError(w, strings.TrimSpace(responseDetails.Message))
+	default:
+		StatusInternalServerError(w, "Oops! Something went wrong. Failed to update the club.")
+	}
+}
+
+// SendGetClubsRequest fetches the book clubs from the backend, with the given
+// cookies, and sends them on respChan and the status code of the response on statusChan.
+func SendGetClubsRequest(apiURL string, cookies []*http.Cookie, waitGroup *sync.WaitGroup, respChan chan []models.Club, statusChan chan int) {
+	defer waitGroup.Done()
+
+	var clubs []models.Club
+	body, status, err := getFromBackend(apiURL, cookies...)
+	if err != nil {
+		log.Print(err)
+		status = http.StatusInternalServerError
+	} else if status == http.StatusOK {
+		if err := json.Unmarshal(body, &clubs); err != nil {
+			log.Printf("Failed to parse clubs: %v", err)
+			status = http.StatusInternalServerError
+		}
+	}
+
+	respChan <- clubs
+	statusChan <- status
+}
+
+// SendGetClubRequest fetches a club with its members and one page of its posts
+// from the backend, with the given cookies, and sends them on respChan and the
+// status code of the response on statusChan.
+func SendGetClubRequest(apiURL string, cookies []*http.Cookie, waitGroup *sync.WaitGroup, respChan chan models.ClubPage, statusChan chan int) {
+	defer waitGroup.Done()
+
+	var page models.ClubPage
+	body, status, err := getFromBackend(apiURL, cookies...)
+	if err != nil {
+		log.Print(err)
+		status = http.StatusInternalServerError
+	} else if status == http.StatusOK {
+		if err := json.Unmarshal(body, &page); err != nil {
+			log.Printf("Failed to parse club: %v", err)
+			status = http.StatusInternalServerError
+		}
+	}
+
+	respChan <- page
+	statusChan <- status
+}
+
+// SendClubRequest sends a change to a club, its members or its posts to the
+// backend and sends the outcome on respChan.
+func SendClubRequest(cookie *http.Cookie, method, apiURL string, payload interface{}, waitGroup *sync.WaitGroup, respChan chan models.ResponseDetails) {
+	defer waitGroup.Done()
+	respChan <- sendToBackend(cookie, method, apiURL, payload)
+}
@@ -23,10 +23,7 @@ func AddComment(w http.ResponseWriter, r *http.Request) {
 		respChan := make(chan models.ResponseDetails, 1)
 		var wg sync.WaitGroup
 
-		payload := models.Comment{
-			PostID:  postID,
-			Content: content,
-		}
+		payload := models.Comment{Content: content}
 
 		// Extract the session cookie from the header
 		cookieToken, err := r.Cookie("session_token")
@@ -40,7 +37,7 @@ func AddComment(w http.ResponseWriter, r *http.Request) {
 		// Calls the function that sends request to the server
 		wg.Add(1)
 		go func() {
-			SendAddCommentRequest(cookieToken, payload, &wg, respChan)
+			SendAddCommentRequest(cookieToken, postID, payload, &wg, respChan)
 		}()
 
 		go func() {
@@ -65,21 +62,156 @@ func AddComment(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
-func SendAddCommentRequest(cookie *http.Cookie, payload models.Comment, waitGroup *sync.WaitGroup, respChan chan models.ResponseDetails) {
-	defer waitGroup.Done() // Ensure the channel is closed once this function completes
-	// Convert payload to JSON
-	commentData, err := json.Marshal(payload)
+// ReplyToComment adds a reply to the comment named by the commentID query parameter,
+// then shows the post named by postID again.
+func ReplyToComment(w http.ResponseWriter, r *http.Request) {
+	commentID := r.URL.Query().Get("commentID")
+	postID := r.URL.Query().Get("postID")
+
+	if r.Method != http.MethodPost {
+		http.Redirect(w, r, "post?id="+postID, http.StatusSeeOther)
+		return
+	}
+
+	// Extract the session cookie from the header
+	cookieToken, err := r.Cookie("session_token")
 	if err != nil {
-		respChan <- models.ResponseDetails{Status: http.StatusInternalServerError, Message: "Failed to marshal payload"}
+		// User must be logged-in to continue
+		message := `You are not authorized! Please <a href="/login">login</a> before replying.`
+		UnauthorizedErrorNotification(w, r, postID, message)
+		return
+	}
+
+	r.ParseForm()
+	payload := models.Comment{Content: r.FormValue("content")}
+
+	respChan := make(chan models.ResponseDetails, 1)
+	var wg sync.WaitGroup
+
+	// Calls the function that sends request to the server
+	wg.Add(1)
+	go SendReplyRequest(cookieToken, commentID, payload, &wg, respChan)
+	go func() {
+		wg.Wait()
+		close(respChan)
+	}()
+
+	responseDetails := <-respChan
+
+	switch responseDetails.Status {
+	case http.StatusCreated:
+		http.Redirect(w, r, "post?id="+postID+"#comment-"+commentID, http.StatusSeeOther)
+	case http.StatusUnauthorized:
+		message := `You are not authorized! Please <a href="/login">login</a> before replying.`
+		UnauthorizedErrorNotification(w, r, postID, message)
+	case http.StatusBadRequest, http.StatusNotFound:
+		UnauthorizedErrorNotification(w, r, postID, responseDetails.Message)
+	default:
+		message := "Oops! Something went wrong. Failed to add reply."
+		UnauthorizedErrorNotification(w, r, postID, message)
+	}
+}
+
+// EditComment replaces the content of the comment named by the commentID query
+// parameter, then shows the post named by postID again.
+func EditComment(w http.ResponseWriter, r *http.Request) {
+	changeComment(w, r, http.MethodPut, "Failed to edit comment.")
+}
+
+// DeleteComment removes the comment named by the commentID query parameter,
+// then shows the post named by postID again.
+func DeleteComment(w http.ResponseWriter, r *http.Request) {
+	changeComment(w, r, http.MethodDelete, "Failed to delete comment.")
+}
+
+// changeComment sends an edit (PUT) or a removal (DELETE) of a comment to the backend
+// on behalf of the logged-in user and redirects back to the post.
+func changeComment(w http.ResponseWriter, r *http.Request, method, failure string) {
+	commentID := r.URL.Query().Get("commentID")
+	postID := r.URL.Query().Get("postID")
+
+	if r.Method != http.MethodPost {
+		http.Redirect(w, r, "post?id="+postID, http.StatusSeeOther)
 		return
 	}
 
-	// Create a POST request
-	req, err := http.NewRequest("POST", config.BaseApi+"/post/"+payload.PostID+"/comment", bytes.NewBuffer(commentData))
+	// Extract the session cookie from the header
+	cookieToken, err := r.Cookie("session_token")
 	if err != nil {
-		respChan <- models.ResponseDetails{Status: http.StatusInternalServerError, Message: "Failed to create request"}
+		message := `You are not authorized! Please <a href="/login">login</a> first.`
+		UnauthorizedErrorNotification(w, r, postID, message)
 		return
 	}
+
+	r.ParseForm()
+	payload := models.Comment{Content: r.FormValue("content")}
+
+	respChan := make(chan models.ResponseDetails, 1)
+	var wg sync.WaitGroup
+
+	wg.Add(1)
+	go SendChangeCommentRequest(cookieToken, method, commentID, payload, &wg, respChan)
+	go func() {
+		wg.Wait()
+		close(respChan)
+	}()
+
+	responseDetails := <-respChan
+
+	switch responseDetails.Status {
+	case http.StatusOK:
+		anchor := ""
+		if method == http.MethodPut {
+			anchor = "#comment-" + commentID
+		}
+		http.Redirect(w, r, "post?id="+postID+anchor, http.StatusSeeOther)
+	case http.StatusUnauthorized:
+		message := `You are not authorized! Please <a href="/login">login</a> first.`
+		UnauthorizedErrorNotification(w, r, postID, message)
+	case http.StatusBadRequest, http.StatusForbidden, http.StatusNotFound:
+		UnauthorizedErrorNotification(w, r, postID, responseDetails.Message)
+	default:
+		UnauthorizedErrorNotification(w, r, postID, "Oops! Something went wrong. "+failure)
+	}
+}
+
+// SendChangeCommentRequest sends an edit (PUT) or a removal (DELETE) of the comment
+// with the given ID to the backend and sends the outcome on respChan.
+func SendChangeCommentRequest(cookie *http.Cookie, method, commentID string, payload models.Comment, waitGroup *sync.WaitGroup, respChan chan models.ResponseDetails) {
+	defer waitGroup.Done()
+	respChan <- sendToBackend(cookie, method, config.BaseApi+"/comment/"+commentID, payload)
+}
+
+func SendAddCommentRequest(cookie *http.Cookie, postID string, payload models.Comment, waitGroup *sync.WaitGroup, respChan chan models.ResponseDetails) {
+	defer waitGroup.Done() // Ensure the channel is closed once this function completes
+	respChan <- sendToBackend(cookie, http.MethodPost, config.BaseApi+"/post/"+postID+"/comment", payload)
+}
+
+// SendReplyRequest sends a reply to the comment with the given ID to the backend
+// and sends the outcome on respChan.
+func SendReplyRequest(cookie *http.Cookie, commentID string, payload models.Comment, waitGroup *sync.WaitGroup, respChan chan models.ResponseDetails) {
+	defer waitGroup.Done()
+	respChan <- sendToBackend(cookie, http.MethodPost, config.BaseApi+"/comment/"+commentID+"/reply", payload)
+}
+
+// sendToBackend sends a change, such as a new comment or an edited post, to the
+// backend endpoint at apiURL with the given method and the user's session.
+// A nil payload sends no body.
+func sendToBackend(cookie *http.Cookie, method, apiURL string, payload interface{}) models.ResponseDetails {
+	// Convert payload to JSON
+	var data []byte
+	if payload != nil {
+		var err error
+		if data, err = json.Marshal(payload); err != nil {
+			return models.ResponseDetails{Status: http.StatusInternalServerError, Message: "Failed to marshal payload"}
+		}
+	}
+
+	// Create the request
+	req, err := http.NewRequest(method, apiURL, bytes.NewBuffer(data))
+	if err != nil {
+		return models.ResponseDetails{Status: http.StatusInternalServerError, Message: "Failed to create request"}
+	}
 	req.Header.Set("Content-Type", "application/json")
 
 	// Set the session cookie in the request
@@ -89,23 +221,21 @@ func SendAddCommentRequest(cookie *http.Cookie, payload models.Comment, waitGrou
 	client := &http.Client{}
 	resp, err := client.Do(req)
 	if err != nil {
-		respChan <- models.ResponseDetails{Status: http.StatusInternalServerError, Message: "Request failed"}
-		return
+		return models.ResponseDetails{Status: http.StatusInternalServerError, Message: "Request failed"}
 	}
 	defer resp.Body.Close()
 
 	// Read the response body
 	body, err := ioutil.ReadAll(resp.Body)
 	if err != nil {
-		respChan <- models.ResponseDetails{
+		return models.ResponseDetails{
 			Success: false,
 			Message: fmt.Sprintf("error reading response: %v", err),
 		}
-		return
 	}
 
-	// Check response status code
-	if resp.StatusCode != http.StatusCreated {
+	// Check response status code: 201 Created for new records, 200 OK for changes
+	if resp.StatusCode != http.StatusCreated && resp.StatusCode != http.StatusOK {
 		// Attempt to parse the error message from the response
 		var errorResponse map[string]interface{}
 		var errorMessage string
@@ -119,23 +249,21 @@ func SendAddCommentRequest(cookie *http.Cookie, payload models.Comment, waitGrou
 			}
 		}
 
-		respChan <- models.ResponseDetails{
+		return models.ResponseDetails{
 			Success: false,
 			Message: fmt.Sprintln(errorMessage),
 			Status:  resp.StatusCode,
 		}
-		return
 	}
 
 	// Optionally, you can further process the response body if needed
 	var responseMessage map[string]interface{}
 	if err := json.Unmarshal(body, &responseMessage); err != nil {
-		respChan <- models.ResponseDetails{
+		return models.ResponseDetails{
 			Success: false,
 			Message: fmt.Sprintf("error unmarshaling response: %v", err),
 			Status:  resp.StatusCode,
 		}
-		return
 	}
 
 	// Extracting the message from the response map
@@ -144,9 +272,15 @@ func SendAddCommentRequest(cookie *http.Cookie, payload models.Comment, waitGrou
 		message = "Unexpected response format"
 	}
 
-	respChan <- models.ResponseDetails{
+	// New records come with their ID, and those addressed by name with their slug
+	id, _ := responseMessage["id"].(float64)
+	slug, _ := responseMessage["slug"].(string)
+
+	return models.ResponseDetails{
 		Success: true,
 		Message: fmt.Sprintln(message), // displays server response to the user
 		Status:  resp.StatusCode,
+		ID:      int(id),
+		Slug:    slug,
 	}
 }
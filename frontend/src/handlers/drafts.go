@@ -0,0 +1,325 @@
+package handlers
+
+import (
+	"encoding/json"
+	"literary-lions/frontend/src/config"
+	"literary-lions/frontend/src/models"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// publishAtLayout is the format of the datetime-local inputs scheduling posts.
+const publishAtLayout = "2006-01-02T15:04"
+
+// parsePublishAt reads the time a post is scheduled for from a datetime-local
+// input, in the server's time zone. An empty value schedules nothing.
+func parsePublishAt(value string) (*time.Time, bool) {
+	if value == "" {
+		return nil, true
+	}
+	publishAt, err := time.ParseInLocation(publishAtLayout, value, time.Local)
+	if err != nil {
+		return nil, false
+	}
+	return &publishAt, true
+}
+
+// EditDraft shows the form to edit the draft named by the id query parameter,
+// prefilled with its category, title, content, tags, images and publishing time, and
+// sends the changes to the backend. The publish button also puts it live.
+func EditDraft(w http.ResponseWriter, r *http.Request) {
+	id := r.URL.Query().Get("id")
+	currentUser, authenticated := isAuthenticated(r)
+	cookieToken, err := r.Cookie("session_token")
+	if !authenticated || err != nil {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+
+	// renderForm shows the form with the given draft and error message
+	renderForm := func(draft models.Post, tags, publishAt, message string) {
+		books, picked := bookOptions(draft.Books)
+		data := struct {
+			Post          models.Post
+			Tags          string
+			PublishAt     string
+			Categories    []models.Category
+			PopularTags   []models.Tag
+			Books         []models.Book
+			PickedBooks   map[int]bool
+			Error         string
+			Authenticated bool
+			Username      string
+		}{
+			Post:          draft,
+			Tags:          tags,
+			PublishAt:     publishAt,
+			Categories:    loadCategories(),
+			PopularTags:   loadPopularTags(),
+			Books:         books,
+			PickedBooks:   picked,
+			Error:         message,
+			Authenticated: authenticated,
+			Username:      currentUser,
+		}
+		RenderTemplate(w, "edit-draft.html", data)
+	}
+
+	if r.Method == http.MethodGet {
+		respChan := make(chan models.Post, 1)
+		statusChan := make(chan int, 1)
+		var wg sync.WaitGroup
+
+		wg.Add(1)
+		go SendGetDraftRequest(cookieToken, id, &wg, respChan, statusChan)
+		go func() {
+			wg.Wait()
+			close(respChan)
+			close(statusChan)
+		}()
+
+		draft := <-respChan
+		status := <-statusChan
+
+		switch status {
+		case http.StatusOK:
+		case http.StatusUnauthorized:
+			http.Redirect(w, r, "/login", http.StatusSeeOther)
+			return
+		case http.StatusNotFound, http.StatusBadRequest:
+			StatusInternalServerError(w, "This draft does not exist")
+			return
+		default:
+			StatusInternalServerError(w, "Failed to fetch draft")
+			return
+		}
+
+		publishAt := ""
+		if draft.PublishAt != nil {
+			publishAt = draft.PublishAt.In(time.Local).Format(publishAtLayout)
+		}
+		renderForm(draft, strings.Join(draft.Tags, ", "), publishAt, "")
+		return
+	}
+
+	if r.Method != http.MethodPost {
+		http.Redirect(w, r, "/profile?tab=drafts", http.StatusSeeOther)
+		return
+	}
+
+	r.ParseMultipartForm(maxFormMemory) // Also reads forms without images
+	payload := models.Post{
+		Category: r.FormValue("category"),
+		Title:    r.FormValue("title"),
+		Content:  r.FormValue("content"),
+		Tags:     splitTags(r.FormValue("tags")),
+	}
+	payload.ID, _ = strconv.Atoi(id) // Only used to show the form again
+	publish := r.FormValue("publish") != ""
+	attachments, kept, coverID := formAttachments(r)
+	bookIDs := formBookIDs(r)
+
+	// Publishing right away leaves the publishing time aside, and a refused
+	// image leaves the draft as it was
+	var responseDetails models.ResponseDetails
+	var images []int
+	publishAt, ok := parsePublishAt(r.FormValue("publish_at"))
+	if !ok {
+		responseDetails = models.ResponseDetails{Status: http.StatusBadRequest, Message: "Invalid publishing time"}
+	} else {
+		images, responseDetails = uploadImages(cookieToken, r, len(kept))
+	}
+	if responseDetails.Status == http.StatusCreated {
+		if !publish {
+			payload.PublishAt = publishAt
+		}
+		respChan := make(chan models.ResponseDetails, 1)
+		var wg sync.WaitGroup
+
+		wg.Add(1)
+		go SendUpdateDraftRequest(cookieToken, id, payload, &wg, respChan)
+		go func() {
+			wg.Wait()
+			close(respChan)
+		}()
+		responseDetails = <-respChan
+	}
+
+	// The images kept come first, then the new ones
+	if responseDetails.Status == http.StatusOK {
+		responseDetails = setAttachments(cookieToken, "/drafts/"+url.PathEscape(id), append(kept, images...), coverID)
+	}
+	if responseDetails.Status == http.StatusOK {
+		responseDetails = setBooks(cookieToken, "/drafts/"+url.PathEscape(id), bookIDs)
+	}
+
+	switch responseDetails.Status {
+	case http.StatusOK:
+		if publish {
+			publishDraft(w, r, cookieToken, id)
+			return
+		}
+		http.Redirect(w, r, "/profile?tab=drafts", http.StatusSeeOther)
+	case http.StatusUnauthorized:
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+	case http.StatusBadRequest, http.StatusRequestEntityTooLarge, http.StatusUnsupportedMediaType:
+		// Show what the backend rejected and keep what was typed
+		payload.CategorySlug = payload.Category
+		payload.Attachments = attachments
+		payload.Books = bookRefs(bookIDs)
+		renderForm(payload, r.FormValue("tags"), r.FormValue("publish_at"), strings.TrimSpace(responseDetails.Message))
+	case http.StatusNotFound:
+		StatusInternalServerError(w, "This draft does not exist")
+	default:
+		StatusInternalServerError(w, "Oops! Something went wrong. Failed to update draft.")
+	}
+}
+
+// PublishDraft puts the draft named by the id query parameter live, then shows
+// the posts of the user.
+func PublishDraft(w http.ResponseWriter, r *http.Request) {
+	cookieToken, err := r.Cookie("session_token")
+	if err != nil {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+	if r.Method != http.MethodPost {
+		http.Redirect(w, r, "/profile?tab=drafts", http.StatusSeeOther)
+		return
+	}
+	publishDraft(w, r, cookieToken, r.URL.Query().Get("id"))
+}
+
+// publishDraft asks the backend to publish the draft with the given ID and shows
+// the posts of the user once it is live, or renders what went wrong.
+func publishDraft(w http.ResponseWriter, r *http.Request, cookie *http.Cookie, id string) {
+	respChan := make(chan models.ResponseDetails, 1)
+	var wg sync.WaitGroup
+
+	wg.Add(1)
+	go SendDraftRequest(cookie, http.MethodPost, id, "/publish", &wg, respChan)
+	go func() {
+		wg.Wait()
+		close(respChan)
+	}()
+
+	responseDetails := <-respChan
+
+	switch responseDetails.Status {
+	case http.StatusOK:
+		http.Redirect(w, r, "/?filter=my-posts", http.StatusSeeOther)
+	case http.StatusUnauthorized:
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+	case http.StatusNotFound, http.StatusBadRequest:
+		StatusInternalServerError(w, "This draft does not exist")
+	default:
+		StatusInternalServerError(w, "Oops! Something went wrong. Failed to publish draft.")
+	}
+}
+
+// DeleteDraft moves the draft named by the id query parameter to the trash,
+// then shows the remaining drafts.
+func DeleteDraft(w http.ResponseWriter, r *http.Request) {
+	id := r.URL.Query().Get("id")
+	cookieToken, err := r.Cookie("session_token")
+	if err != nil {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+	if r.Method != http.MethodPost {
+		http.Redirect(w, r, "/profile?tab=drafts", http.StatusSeeOther)
+		return
+	}
+
+	respChan := make(chan models.ResponseDetails, 1)
+	var wg sync.WaitGroup
+
+	wg.Add(1)
+	go SendDraftRequest(cookieToken, http.MethodDelete, id, "", &wg, respChan)
+	go func() {
+		wg.Wait()
+		close(respChan)
+	}()
+
+	responseDetails := <-respChan
+
+	switch responseDetails.Status {
+	case http.StatusOK:
+		http.Redirect(w, r, "/profile?tab=drafts", http.StatusSeeOther)
+	case http.StatusUnauthorized:
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+	case http.StatusNotFound, http.StatusBadRequest:
+		StatusInternalServerError(w, "This draft does not exist")
+	default:
+		StatusInternalServerError(w, "Oops! Something went wrong. Failed to delete draft.")
+	}
+}
+
+// SendGetDraftsRequest fetches the drafts and scheduled posts of the logged-in user
+// from the backend, and sends them on respChan and the status code of the response on statusChan.
+func SendGetDraftsRequest(cookie *http.Cookie, waitGroup *sync.WaitGroup, respChan chan []models.Post, statusChan chan int) {
+	defer waitGroup.Done()
+
+	var drafts []models.Post
+	body, status, err := getFromBackend(config.BaseApi+"/drafts", cookie)
+	if err != nil {
+		log.Print(err)
+		status = http.StatusInternalServerError
+	} else if status == http.StatusOK {
+		if err := json.Unmarshal(body, &drafts); err != nil {
+			log.Printf("Failed to parse drafts: %v", err)
+			status = http.StatusInternalServerError
+		}
+	}
+
+	respChan <- drafts
+	statusChan <- status
+}
+
+// SendGetDraftRequest fetches the draft with the given ID from the backend, and sends
+// it on respChan and the status code of the response on statusChan.
+func SendGetDraftRequest(cookie *http.Cookie, id string, waitGroup *sync.WaitGroup, respChan chan models.Post, statusChan chan int) {
+	defer waitGroup.Done()
+
+	var draft models.Post
+	body, status, err := getFromBackend(config.BaseApi+"/drafts/"+url.PathEscape(id), cookie)
+	if err != nil {
+		log.Print(err)
+		status = http.StatusInternalServerError
+	} else if status == http.StatusOK {
+		if err := json.Unmarshal(body, &draft); err != nil {
+			log.Printf("Failed to parse draft: %v", err)
+			status = http.StatusInternalServerError
+		}
+	}
+
+	respChan <- draft
+	statusChan <- status
+}
+
+// SendSaveDraftRequest sends a new draft, or a post to schedule, to the backend
+// and sends the outcome on respChan.
+func SendSaveDraftRequest(cookie *http.Cookie, payload models.Post, waitGroup *sync.WaitGroup, respChan chan models.ResponseDetails) {
+	defer waitGroup.Done()
+	respChan <- sendToBackend(cookie, http.MethodPost, config.BaseApi+"/drafts", payload)
+}
+
+// SendUpdateDraftRequest sends the changes to the draft with the given ID to the
+// backend and sends the outcome on respChan.
+func SendUpdateDraftRequest(cookie *http.Cookie, id string, payload models.Post, waitGroup *sync.WaitGroup, respChan chan models.ResponseDetails) {
+	defer waitGroup.Done()
+	respChan <- sendToBackend(cookie, http.MethodPut, config.BaseApi+"/drafts/"+url.PathEscape(id), payload)
+}
+
+// SendDraftRequest sends a request without a body about the draft with the given
+// ID to the backend, e.g. DELETE to delete it or POST with action "/publish" to
+// publish it, and sends the outcome on respChan.
+func SendDraftRequest(cookie *http.Cookie, method, id, action string, waitGroup *sync.WaitGroup, respChan chan models.ResponseDetails) {
+	defer waitGroup.Done()
+	respChan <- sendToBackend(cookie, method, config.BaseApi+"/drafts/"+url.PathEscape(id)+action, nil)
+}
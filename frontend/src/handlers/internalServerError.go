@@ -6,7 +6,6 @@ import (
 	"literary-lions/frontend/src/models"
 	"log"
 	"net/http"
-	"strings"
 	"sync"
 )
 
@@ -49,12 +48,18 @@ func UnauthorizedErrorNotification(w http.ResponseWriter, r *http.Request, postI
 	currentUser, authenticated := isAuthenticated(r)
 	response := <-respChan
 	formattedDate := response.Post.CreatedAt.Format("January 2, 2006 at 3:04pm")
-	response.Post.FormattedContent = strings.Split(response.Post.Content, "\n")
 
 	if response.Status == http.StatusOK {
 
+		// Show the poll too, e.g. with the error of a vote
+		var poll *models.Poll
+		if !response.Post.Locked {
+			poll = loadPoll(postID, r)
+		}
+
 		data := struct {
 			Post          models.Post
+			Poll          *models.Poll
 			FormattedDate string
 			Authenticated bool
 			Comments      []models.Comment
@@ -62,8 +67,12 @@ func UnauthorizedErrorNotification(w http.ResponseWriter, r *http.Request, postI
 			Username      string
 			Likes         int
 			Dislikes      int
+			CommentSort   string
+			NextURL       string
+			FirstURL      string
 		}{
 			Post:          response.Post,
+			Poll:          poll,
 			FormattedDate: formattedDate,
 			Authenticated: authenticated,
 			Comments:      response.Comments,
@@ -111,19 +120,30 @@ func DateErrorNotification(w http.ResponseWriter, r *http.Request, message strin
 		data := struct {
 			Posts         []models.Post
 			Authenticated bool
-			Categories    []string
+			Categories    []models.Category
+			Category      string
+			Tag           string
+			PopularTags   []models.Tag
 			Username      string
 			NoPostsFound  bool
+			Query         string
+			Sort          string
+			Filter        string
+			NextURL       string
+			FirstURL      string
 			SearchMessage string
 			DateError     bool
+			Error         string
 		}{
 			Posts:         posts,
 			Authenticated: authenticated,
-			Categories:    []string{"Random", "News", "Sport", "Technology", "Science", "Health"},
+			Categories:    loadCategories(),
+			PopularTags:   loadPopularTags(),
 			Username:      currentUser,
 			NoPostsFound:  true,
 			SearchMessage: "No posts found for the selected criteria.",
 			DateError:	   false,
+			Error:         message,
 		}
 
 		RenderTemplate(w, "index.html", data)
@@ -135,19 +155,28 @@ func DateErrorNotification(w http.ResponseWriter, r *http.Request, message strin
 		posts[i].Content = truncateContent(posts[i].Content, 150)
 	}
 
-	categories := []string{"Random", "News", "Sport", "Technology", "Science", "Health"}
+	categories := loadCategories()
 
 	data := struct {
 		Posts         []models.Post
 		Authenticated bool
-		Categories    []string
+		Categories    []models.Category
+		Category      string
+		Tag           string
+		PopularTags   []models.Tag
 		Username      string
 		NoPostsFound  bool
+		Query         string
+		Sort          string
+		Filter        string
+		NextURL       string
+		FirstURL      string
 		Error         string
 	}{
 		Posts:         posts,
 		Authenticated: authenticated,
 		Categories:    categories,
+		PopularTags:   loadPopularTags(),
 		Username:      currentUser,
 		NoPostsFound:  false,
 		Error:         message,
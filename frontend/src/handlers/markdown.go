@@ -0,0 +1,97 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"literary-lions/frontend/src/config"
+	"literary-lions/frontend/src/models"
+	"log"
+	"net/http"
+	"sync"
+)
+
+// PreviewMarkdown renders the Markdown in the content form value as posts and
+// comments show it. It writes the HTML alone, for the preview button of the post
+// and comment forms to show under the form.
+func PreviewMarkdown(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// Extract the session cookie from the header
+	cookieToken, err := r.Cookie("session_token")
+	if err != nil {
+		http.Error(w, "Please log in to preview your text.", http.StatusUnauthorized)
+		return
+	}
+
+	respChan := make(chan models.MarkdownPreview, 1)
+	statusChan := make(chan int, 1)
+	var wg sync.WaitGroup
+
+	wg.Add(1)
+	go SendPreviewMarkdownRequest(cookieToken, r.FormValue("content"), &wg, respChan, statusChan)
+	go func() {
+		wg.Wait()
+		close(respChan)
+		close(statusChan)
+	}()
+
+	preview := <-respChan
+	status := <-statusChan
+
+	switch status {
+	case http.StatusOK:
+		// The backend sanitizes the HTML it renders
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		io.WriteString(w, preview.HTML)
+	case http.StatusUnauthorized:
+		http.Error(w, "Please log in to preview your text.", status)
+	case http.StatusBadRequest:
+		http.Error(w, preview.Error, status)
+	default:
+		http.Error(w, "The preview is not available right now.", http.StatusInternalServerError)
+	}
+}
+
+// SendPreviewMarkdownRequest asks the backend to render Markdown, and sends the
+// result on respChan and the status code of the response on statusChan.
+func SendPreviewMarkdownRequest(cookie *http.Cookie, content string, waitGroup *sync.WaitGroup, respChan chan models.MarkdownPreview, statusChan chan int) {
+	defer waitGroup.Done()
+
+	var preview models.MarkdownPreview
+	status, err := previewMarkdown(cookie, content, &preview)
+	if err != nil {
+		log.Printf("Failed to preview Markdown: %v", err)
+		status = http.StatusInternalServerError
+	}
+
+	respChan <- preview
+	statusChan <- status
+}
+
+// previewMarkdown posts content to the preview endpoint of the backend and reads
+// the response into preview, returning its status code.
+func previewMarkdown(cookie *http.Cookie, content string, preview *models.MarkdownPreview) (int, error) {
+	payload, err := json.Marshal(map[string]string{"content": content})
+	if err != nil {
+		return 0, err
+	}
+	req, err := http.NewRequest(http.MethodPost, config.BaseApi+"/markdown/preview", bytes.NewReader(payload))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.AddCookie(cookie)
+
+	client := &http.Client{}
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	return resp.StatusCode, json.NewDecoder(resp.Body).Decode(preview)
+}
@@ -0,0 +1,149 @@
+package handlers
+
+import (
+	"encoding/json"
+	"html"
+	"literary-lions/frontend/src/config"
+	"literary-lions/frontend/src/models"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// formPoll reads the poll typed in the form of a new post: a question, one option
+// per line, and whether voters pick several options, stay anonymous and vote until
+// a close date. It returns nil when no poll was typed, and false when the close
+// date cannot be read.
+func formPoll(r *http.Request) (*models.PollRequest, bool) {
+	question := strings.TrimSpace(r.FormValue("poll_question"))
+	var options []string
+	for _, line := range strings.Split(r.FormValue("poll_options"), "\n") {
+		if option := strings.TrimSpace(line); option != "" {
+			options = append(options, option)
+		}
+	}
+	if question == "" && len(options) == 0 {
+		return nil, true
+	}
+
+	closesAt, ok := parsePublishAt(r.FormValue("poll_closes_at"))
+	return &models.PollRequest{
+		Question:  question,
+		Options:   options,
+		Multiple:  r.FormValue("poll_multiple") != "",
+		Anonymous: r.FormValue("poll_anonymous") != "",
+		ClosesAt:  closesAt,
+	}, ok
+}
+
+// loadPoll fetches the poll of the post with the given ID, with the votes of the
+// logged-in member if any, and works out the share of each option. It returns nil
+// when the post carries no poll or the poll cannot be fetched.
+func loadPoll(postID string, r *http.Request) *models.Poll {
+	var cookies []*http.Cookie
+	if cookieToken, err := r.Cookie("session_token"); err == nil {
+		cookies = append(cookies, cookieToken)
+	}
+
+	respChan := make(chan *models.Poll, 1)
+	var wg sync.WaitGroup
+
+	wg.Add(1)
+	go SendGetPollRequest(config.BaseApi+"/post/"+url.PathEscape(postID)+"/poll", cookies, &wg, respChan)
+	go func() {
+		wg.Wait()
+		close(respChan)
+	}()
+
+	poll := <-respChan
+	if poll == nil {
+		return nil
+	}
+	for i := range poll.Options {
+		if poll.VoterCount > 0 {
+			poll.Options[i].Percent = poll.Options[i].Votes * 100 / poll.VoterCount
+		}
+	}
+	return poll
+}
+
+// VotePoll sends the options picked in the poll of the post named by the post_id
+// form field to the backend, then returns to the post with the results.
+func VotePoll(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Redirect(w, r, "/", http.StatusSeeOther)
+		return
+	}
+	r.ParseForm()
+	postID := r.FormValue("post_id")
+
+	// Extract the session cookie from the header
+	cookieToken, err := r.Cookie("session_token")
+	if err != nil {
+		message := `You are not authorized! Please <a href="/login">login</a> before voting.`
+		UnauthorizedErrorNotification(w, r, postID, message)
+		return
+	}
+
+	payload := models.VoteRequest{OptionIDs: []int{}}
+	for _, value := range r.Form["option"] {
+		if id, err := strconv.Atoi(value); err == nil {
+			payload.OptionIDs = append(payload.OptionIDs, id)
+		}
+	}
+
+	respChan := make(chan models.ResponseDetails, 1)
+	var wg sync.WaitGroup
+
+	wg.Add(1)
+	go SendVoteRequest(cookieToken, postID, payload, &wg, respChan)
+	go func() {
+		wg.Wait()
+		close(respChan)
+	}()
+
+	switch responseDetails := <-respChan; responseDetails.Status {
+	case http.StatusOK:
+		http.Redirect(w, r, "/post?id="+url.QueryEscape(postID)+"#poll", http.StatusSeeOther)
+	case http.StatusUnauthorized:
+		message := `You are not authorized! Please <a href="/login">login</a> before voting.`
+		UnauthorizedErrorNotification(w, r, postID, message)
+	case http.StatusBadRequest, http.StatusForbidden, http.StatusNotFound, http.StatusConflict:
+		UnauthorizedErrorNotification(w, r, postID, html.EscapeString(strings.TrimSpace(responseDetails.Message)))
+	default:
+		UnauthorizedErrorNotification(w, r, postID, "Oops! Something went wrong. Failed to vote.")
+	}
+}
+
+// SendGetPollRequest fetches a poll from the backend, with the given cookies, and
+// sends it on respChan, or nil if the post carries none.
+func SendGetPollRequest(apiURL string, cookies []*http.Cookie, waitGroup *sync.WaitGroup, respChan chan *models.Poll) {
+	defer waitGroup.Done()
+
+	body, status, err := getFromBackend(apiURL, cookies...)
+	if err != nil {
+		log.Print(err)
+		respChan <- nil
+		return
+	} else if status != http.StatusOK {
+		respChan <- nil
+		return
+	}
+
+	var poll models.Poll
+	if err := json.Unmarshal(body, &poll); err != nil {
+		log.Printf("Failed to parse poll: %v", err)
+		respChan <- nil
+		return
+	}
+	respChan <- &poll
+}
+
+// SendVoteRequest sends the options picked in the poll of a post to the backend.
+func SendVoteRequest(cookie *http.Cookie, postID string, payload models.VoteRequest, waitGroup *sync.WaitGroup, respChan chan models.ResponseDetails) {
+	defer waitGroup.Done()
+	respChan <- sendToBackend(cookie, http.MethodPost, config.BaseApi+"/post/"+url.PathEscape(postID)+"/poll/vote", payload)
+}
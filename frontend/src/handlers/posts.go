@@ -11,6 +11,7 @@ import (
 	"log"
 	"net/http"
 	"net/url"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -30,9 +31,12 @@ func ShowPosts(w http.ResponseWriter, r *http.Request) {
 	// Get the search query parameters
 	keyword := r.URL.Query().Get("keyword")
 	category := r.URL.Query().Get("category")
+	tag := r.URL.Query().Get("tag")
 	startDate := r.URL.Query().Get("start_date")
 	endDate := r.URL.Query().Get("end_date")
 	filter := r.URL.Query().Get("filter")
+	sort := r.URL.Query().Get("sort")
+	cursor := r.URL.Query().Get("cursor")
 
 	var filterIsSet bool
 	var cookie *http.Cookie
@@ -62,6 +66,12 @@ func ShowPosts(w http.ResponseWriter, r *http.Request) {
         }
     }
 
+	// Keywords typed in the search box go through the full-text search
+	if keyword != "" && filter == "" {
+		ShowSearchResults(w, r, keyword, category, tag, startDate, endDate)
+		return
+	}
+
 	// Construct the API request URL with query parameters
 	apiURL := config.BaseApi + "/posts?"
 	filteredURL := config.BaseApi + "/filtered-posts?"
@@ -72,12 +82,23 @@ func ShowPosts(w http.ResponseWriter, r *http.Request) {
 	if category != "" {
 		apiURL += "category=" + url.QueryEscape(category) + "&"
 	}
+	if tag != "" {
+		apiURL += "tag=" + url.QueryEscape(tag) + "&"
+	}
 	if startDate != "" {
 		apiURL += "start_date=" + url.QueryEscape(startDate) + "&"
 	}
 	if endDate != "" {
 		apiURL += "end_date=" + url.QueryEscape(endDate) + "&"
 	}
+	if sort != "" {
+		apiURL += "sort=" + url.QueryEscape(sort) + "&"
+		filteredURL += "sort=" + url.QueryEscape(sort) + "&"
+	}
+	if cursor != "" {
+		apiURL += "cursor=" + url.QueryEscape(cursor) + "&"
+		filteredURL += "cursor=" + url.QueryEscape(cursor) + "&"
+	}
 	if filter != "" {
 		filterIsSet = true
 		filteredURL += "filter=" + url.QueryEscape(filter) + "&"
@@ -125,17 +146,32 @@ func ShowPosts(w http.ResponseWriter, r *http.Request) {
 		data := struct {
 			Posts         []models.Post
 			Authenticated bool
-			Categories    []string
+			Categories    []models.Category
+			Category      string
+			Tag           string
+			PopularTags   []models.Tag
 			Username      string
 			NoPostsFound  bool
+			Query         string
+			Sort          string
+			Filter        string
+			NextURL       string
+			FirstURL      string
 			SearchMessage string
 			DateError     bool
+			Error         bool
 		}{
 			Posts:         posts,
 			Authenticated: authenticated,
-			Categories:    []string{"Random", "News", "Sport", "Technology", "Science", "Health"},
+			Categories:    loadCategories(),
+			PopularTags:   loadPopularTags(),
+			Category:      category,
+			Tag:           tag,
 			Username:      currentUser,
 			NoPostsFound:  true,
+			Sort:          sort,
+			Filter:        filter,
+			FirstURL:      firstPageURL(r),
 			SearchMessage: "No posts found for the selected criteria.",
 			DateError:		false,
 		}
@@ -151,27 +187,64 @@ func ShowPosts(w http.ResponseWriter, r *http.Request) {
 
 	currentUser, authenticated := isAuthenticated(r)
 
-	categories := []string{"Random", "News", "Sport", "Technology", "Science", "Health"}
+	categories := loadCategories()
 
 	data := struct {
 		Posts         []models.Post
 		Authenticated bool
-		Categories    []string
+		Categories    []models.Category
+		Category      string
+		Tag           string
+		PopularTags   []models.Tag
 		Username      string
 		NoPostsFound  bool
+		Query         string
+		Sort          string
+		Filter        string
+		NextURL       string
+		FirstURL      string
 		Error         bool
 	}{
 		Posts:         posts,
 		Authenticated: authenticated,
 		Categories:    categories,
+		PopularTags:   loadPopularTags(),
+		Category:      category,
+		Tag:           tag,
 		Username:      currentUser,
 		NoPostsFound:  false,
+		Sort:          sort,
+		Filter:        filter,
+		NextURL:       nextPageURL(r, response.Next),
+		FirstURL:      firstPageURL(r),
 		Error:		   false,
 	}
 
 	RenderTemplate(w, "index.html", data)
 }
 
+// nextPageURL returns the current page's URL with its cursor replaced by next,
+// or an empty string when there is no next page.
+func nextPageURL(r *http.Request, next string) string {
+	if next == "" {
+		return ""
+	}
+	query := r.URL.Query()
+	query.Set("cursor", next)
+	return r.URL.Path + "?" + query.Encode()
+}
+
+// firstPageURL returns the current page's URL without its cursor, or an empty
+// string when the current page is already the first one.
+func firstPageURL(r *http.Request) string {
+	query := r.URL.Query()
+	if query.Get("cursor") == "" {
+		return ""
+	}
+	query.Del("cursor")
+	return r.URL.Path + "?" + query.Encode()
+}
+
 // Helper function to handle errors and render templates
 func handleErrorResponse(w http.ResponseWriter, response models.Data) {
 	var tmpl *template.Template
@@ -203,14 +276,28 @@ func ShowPostByID(w http.ResponseWriter, r *http.Request) {
 	// Extract the id query parameter from the URL
 	id := r.URL.Query().Get("id")
 
+	// Forward the sort order and cursor of the comments
+	params := url.Values{}
+	if sort := r.URL.Query().Get("sort"); sort != "" {
+		params.Set("sort", sort)
+	}
+	if cursor := r.URL.Query().Get("cursor"); cursor != "" {
+		params.Set("cursor", cursor)
+	}
+
 	// Create a new GET request
-	req, err := http.NewRequest("GET", config.BaseApi+"/post/"+id, nil)
+	req, err := http.NewRequest("GET", config.BaseApi+"/post/"+url.PathEscape(id)+"?"+params.Encode(), nil)
 	if err != nil {
 		message := "Failed to create request"
 		StatusInternalServerError(w, message)
 		return
 	}
 
+	// Forward the session so the backend can flag the user's own reactions
+	if cookie, err := r.Cookie("session_token"); err == nil {
+		req.AddCookie(cookie)
+	}
+
 	// Use an http.Client to make the request
 	client := &http.Client{}
 	resp, err := client.Do(req)
@@ -229,6 +316,12 @@ func ShowPostByID(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// The post may have been deleted
+	if resp.StatusCode == http.StatusNotFound {
+		StatusInternalServerError(w, "This post does not exist")
+		return
+	}
+
 	// Parse the JSON response into a PostDetails model
 	var response models.PostDetails
 	err = json.Unmarshal(body, &response)
@@ -237,16 +330,23 @@ func ShowPostByID(w http.ResponseWriter, r *http.Request) {
 		StatusInternalServerError(w, message)
 		return
 	}
+	labelPostReactions(&response)
 
 	// Format the created_at date
 	formattedDate := response.Post.CreatedAt.Format("January 2, 2006 at 3:04pm")
 
 	// Get the authentication status and the currentUser if any
 	currentUser, authenticated := isAuthenticated(r)
-	response.Post.FormattedContent = strings.Split(response.Post.Content, "\n")
+
+	// Locked posts hold back their poll too
+	var poll *models.Poll
+	if !response.Post.Locked {
+		poll = loadPoll(id, r)
+	}
 
 	data := struct {
 		Post          models.Post
+		Poll          *models.Poll
 		FormattedDate string
 		Authenticated bool
 		Comments      []models.Comment
@@ -254,8 +354,12 @@ func ShowPostByID(w http.ResponseWriter, r *http.Request) {
 		Username      string
 		Likes         int
 		Dislikes      int
+		CommentSort   string
+		NextURL       string
+		FirstURL      string
 	}{
 		Post:          response.Post,
+		Poll:          poll,
 		FormattedDate: formattedDate,
 		Authenticated: authenticated,
 		Comments:      response.Comments,
@@ -263,6 +367,9 @@ func ShowPostByID(w http.ResponseWriter, r *http.Request) {
 		Username:      currentUser,
 		Likes:         response.Likes,
 		Dislikes:      response.Dislikes,
+		CommentSort:   r.URL.Query().Get("sort"),
+		NextURL:       nextPageURL(r, response.Next),
+		FirstURL:      firstPageURL(r),
 	}
 	// Render the template with posts and authentication status
 	RenderTemplate(w, "post.html", data)
@@ -274,15 +381,30 @@ func CreatePost(w http.ResponseWriter, r *http.Request) {
 
 	// Render the page to the user to select from category
 	if r.Method == http.MethodGet {
-		// Hardcoded categories for now. Might be nice to add feature for creating it and make it dynamic later
-		categories := []string{"Random", "News", "Sport", "Technology", "Science", "Health"}
+		// The categories are managed on the backend; a category page can preselect its own
+		categories := loadCategories()
+		// A book page can preselect its own book
+		var linked []models.BookRef
+		if id, err := strconv.Atoi(r.URL.Query().Get("book")); err == nil {
+			linked = bookRefs([]int{id})
+		}
+		books, picked := bookOptions(linked)
 		data := struct {
-			Categories    []string
+			Categories    []models.Category
+			Category      string
+			Tag           string
+			PopularTags   []models.Tag
+			Books         []models.Book
+			PickedBooks   map[int]bool
 			Error         interface{}
 			Authenticated bool
 			Username      string
 		}{
 			Categories:    categories,
+			PopularTags:   loadPopularTags(),
+			Books:         books,
+			PickedBooks:   picked,
+			Category:      r.URL.Query().Get("category"),
 			Error:         nil,
 			Authenticated: authenticated,
 			Username:      currentUser,
@@ -290,10 +412,11 @@ func CreatePost(w http.ResponseWriter, r *http.Request) {
 		tmpl := template.Must(template.ParseFiles("templates/create-post.html"))
 		tmpl.Execute(w, data)
 	} else if r.Method == http.MethodPost {
-		r.ParseForm()
+		r.ParseMultipartForm(maxFormMemory) // Also reads forms without images
 		category := r.FormValue("category")
 		title := r.FormValue("title")
 		content := r.FormValue("content")
+		tags := r.FormValue("tags")
 
 		respChan := make(chan models.ResponseDetails, 1)
 		var wg sync.WaitGroup
@@ -303,6 +426,8 @@ func CreatePost(w http.ResponseWriter, r *http.Request) {
 			Category: category,
 			Title:    title,
 			Content:  content,
+			Tags:     splitTags(tags),
+			BookIDs:  formBookIDs(r),
 		}
 
 		// Extract the session cookie from the header
@@ -317,10 +442,42 @@ func CreatePost(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
+		// Posts saved as drafts, or scheduled, wait in the drafts of the user
+		draft := r.FormValue("draft") != "" || r.FormValue("publish_at") != ""
+		publishAt, ok := parsePublishAt(r.FormValue("publish_at"))
+		payload.PublishAt = publishAt
+
+		// Polls are attached to posts published right away
+		poll, pollOK := formPoll(r)
+		payload.Poll = poll
+
+		// Upload the images first, so that a refused image leaves no post behind
+		var images []int
+		uploaded := models.ResponseDetails{Status: http.StatusCreated}
+		if ok && pollOK && (poll == nil || !draft) {
+			images, uploaded = uploadImages(cookieToken, r, 0)
+		}
+
 		// Calls the function that sends request to the server
 		wg.Add(1)
 		go func() {
-			SendCreatePostRequest(cookieToken, payload, &wg, respChan)
+			if !ok {
+				respChan <- models.ResponseDetails{Status: http.StatusBadRequest, Message: "Invalid publishing time"}
+				wg.Done()
+			} else if !pollOK {
+				respChan <- models.ResponseDetails{Status: http.StatusBadRequest, Message: "Invalid poll close date"}
+				wg.Done()
+			} else if draft && poll != nil {
+				respChan <- models.ResponseDetails{Status: http.StatusBadRequest, Message: "Polls can only be added to posts published right away"}
+				wg.Done()
+			} else if uploaded.Status != http.StatusCreated {
+				respChan <- uploaded
+				wg.Done()
+			} else if draft {
+				SendSaveDraftRequest(cookieToken, payload, &wg, respChan)
+			} else {
+				SendCreatePostRequest(cookieToken, payload, &wg, respChan)
+			}
 		}()
 
 		go func() {
@@ -330,7 +487,29 @@ func CreatePost(w http.ResponseWriter, r *http.Request) {
 
 		responseDetails := <-respChan
 
-		if responseDetails.Status == http.StatusCreated {
+		// The first image is the cover of the new post
+		if responseDetails.Status == http.StatusCreated && len(images) > 0 {
+			target := "/post/" + strconv.Itoa(responseDetails.ID)
+			if draft {
+				target = "/drafts/" + strconv.Itoa(responseDetails.ID)
+			}
+			if attached := setAttachments(cookieToken, target, images, 0); attached.Status != http.StatusOK {
+				StatusInternalServerError(w, "The post was saved, but its images could not be attached to it")
+				return
+			}
+		}
+
+		// Posts link their books when created, drafts right after
+		if responseDetails.Status == http.StatusCreated && draft && len(payload.BookIDs) > 0 {
+			if linked := setBooks(cookieToken, "/drafts/"+strconv.Itoa(responseDetails.ID), payload.BookIDs); linked.Status != http.StatusOK {
+				StatusInternalServerError(w, "The draft was saved, but its books could not be linked to it")
+				return
+			}
+		}
+
+		if responseDetails.Status == http.StatusCreated && draft {
+			http.Redirect(w, r, "/profile?tab=drafts", http.StatusSeeOther)
+		} else if responseDetails.Status == http.StatusCreated {
 			http.Redirect(w, r, "/", http.StatusSeeOther)
 		} else if responseDetails.Status == http.StatusUnauthorized {
 			responseDetails.Message = `You are not authorized! Please <a href="/login">login</a> before creating a post.`
@@ -338,6 +517,21 @@ func CreatePost(w http.ResponseWriter, r *http.Request) {
 			tmpl.Execute(w, map[string]interface{}{
 				"Error": template.HTML(responseDetails.Message),
 			})
+		} else if responseDetails.Status == http.StatusBadRequest || responseDetails.Status == http.StatusRequestEntityTooLarge || responseDetails.Status == http.StatusUnsupportedMediaType {
+			// Show what the backend rejected, e.g. a category that was deleted meanwhile or an image that is too large
+			books, picked := bookOptions(bookRefs(payload.BookIDs))
+			tmpl := template.Must(template.ParseFiles("templates/create-post.html"))
+			tmpl.Execute(w, map[string]interface{}{
+				"Error":         strings.TrimSpace(responseDetails.Message),
+				"Categories":    loadCategories(),
+				"Category":      category,
+				"Tag":           tags,
+				"PopularTags":   loadPopularTags(),
+				"Books":         books,
+				"PickedBooks":   picked,
+				"Authenticated": authenticated,
+				"Username":      currentUser,
+			})
 		} else {
 			responseDetails.Message = "Oops! Something went wrong. Failed to create post."
 			tmpl := template.Must(template.ParseFiles("templates/create-post.html"))
@@ -348,6 +542,194 @@ func CreatePost(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// EditPost shows the form to edit the post named by the id query parameter, prefilled
+// with its current category, title, content, tags and images, and sends the changes to the backend.
+func EditPost(w http.ResponseWriter, r *http.Request) {
+	id := r.URL.Query().Get("id")
+	currentUser, authenticated := isAuthenticated(r)
+
+	// Extract the session cookie from the header
+	cookieToken, err := r.Cookie("session_token")
+	if err != nil {
+		message := `You are not authorized! Please <a href="/login">login</a> before editing a post.`
+		UnauthorizedErrorNotification(w, r, id, message)
+		return
+	}
+
+	// renderForm shows the form with the given post and error message
+	renderForm := func(post models.Post, tags string, message string) {
+		books, picked := bookOptions(post.Books)
+		data := struct {
+			Post          models.Post
+			Tags          string
+			Categories    []models.Category
+			PopularTags   []models.Tag
+			Books         []models.Book
+			PickedBooks   map[int]bool
+			Error         string
+			Authenticated bool
+			Username      string
+		}{
+			Post:          post,
+			Tags:          tags,
+			Categories:    loadCategories(),
+			PopularTags:   loadPopularTags(),
+			Books:         books,
+			PickedBooks:   picked,
+			Error:         message,
+			Authenticated: authenticated,
+			Username:      currentUser,
+		}
+		RenderTemplate(w, "edit-post.html", data)
+	}
+
+	if r.Method == http.MethodGet {
+		respChan := make(chan models.PostDetails, 1)
+		var wg sync.WaitGroup
+
+		wg.Add(1)
+		go SendGetPostByIdRequest(id, w, r, &wg, respChan)
+		go func() {
+			wg.Wait()
+			close(respChan)
+		}()
+
+		response, ok := <-respChan
+		if !ok {
+			return // The error page was already shown
+		}
+		if response.Status == http.StatusNotFound {
+			StatusInternalServerError(w, "This post does not exist")
+			return
+		} else if response.Status != http.StatusOK {
+			StatusInternalServerError(w, "Failed to fetch post")
+			return
+		}
+		if !response.Post.Editable {
+			UnauthorizedErrorNotification(w, r, id, "You can only edit your own posts.")
+			return
+		}
+
+		renderForm(response.Post, strings.Join(response.Post.Tags, ", "), "")
+		return
+	}
+
+	if r.Method != http.MethodPost {
+		http.Redirect(w, r, "post?id="+id, http.StatusSeeOther)
+		return
+	}
+
+	r.ParseMultipartForm(maxFormMemory) // Also reads forms without images
+	payload := models.Post{
+		Category: r.FormValue("category"),
+		Title:    r.FormValue("title"),
+		Content:  r.FormValue("content"),
+		Tags:     splitTags(r.FormValue("tags")),
+	}
+	attachments, kept, coverID := formAttachments(r)
+	bookIDs := formBookIDs(r)
+
+	// Upload the new images first, so that a refused image leaves the post as it was
+	images, responseDetails := uploadImages(cookieToken, r, len(kept))
+	if responseDetails.Status == http.StatusCreated {
+		respChan := make(chan models.ResponseDetails, 1)
+		var wg sync.WaitGroup
+
+		wg.Add(1)
+		go SendUpdatePostRequest(cookieToken, id, payload, &wg, respChan)
+		go func() {
+			wg.Wait()
+			close(respChan)
+		}()
+
+		responseDetails = <-respChan
+	}
+
+	// The images kept come first, then the new ones
+	if responseDetails.Status == http.StatusOK {
+		responseDetails = setAttachments(cookieToken, "/post/"+url.PathEscape(id), append(kept, images...), coverID)
+	}
+	if responseDetails.Status == http.StatusOK {
+		responseDetails = setBooks(cookieToken, "/post/"+url.PathEscape(id), bookIDs)
+	}
+
+	switch responseDetails.Status {
+	case http.StatusOK:
+		http.Redirect(w, r, "post?id="+id, http.StatusSeeOther)
+	case http.StatusUnauthorized:
+		message := `You are not authorized! Please <a href="/login">login</a> before editing a post.`
+		UnauthorizedErrorNotification(w, r, id, message)
+	case http.StatusBadRequest, http.StatusForbidden, http.StatusRequestEntityTooLarge, http.StatusUnsupportedMediaType:
+		// Show what the backend rejected and keep what was typed
+		payload.CategorySlug = payload.Category
+		payload.Attachments = attachments
+		payload.Books = bookRefs(bookIDs)
+		renderForm(payload, r.FormValue("tags"), strings.TrimSpace(responseDetails.Message))
+	case http.StatusNotFound:
+		StatusInternalServerError(w, "This post does not exist")
+	default:
+		UnauthorizedErrorNotification(w, r, id, "Oops! Something went wrong. Failed to update post.")
+	}
+}
+
+// DeletePost deletes the post named by the id query parameter, then shows the home page.
+func DeletePost(w http.ResponseWriter, r *http.Request) {
+	id := r.URL.Query().Get("id")
+
+	if r.Method != http.MethodPost {
+		http.Redirect(w, r, "post?id="+id, http.StatusSeeOther)
+		return
+	}
+
+	// Extract the session cookie from the header
+	cookieToken, err := r.Cookie("session_token")
+	if err != nil {
+		message := `You are not authorized! Please <a href="/login">login</a> before deleting a post.`
+		UnauthorizedErrorNotification(w, r, id, message)
+		return
+	}
+
+	respChan := make(chan models.ResponseDetails, 1)
+	var wg sync.WaitGroup
+
+	wg.Add(1)
+	go SendDeletePostRequest(cookieToken, id, &wg, respChan)
+	go func() {
+		wg.Wait()
+		close(respChan)
+	}()
+
+	responseDetails := <-respChan
+
+	switch responseDetails.Status {
+	case http.StatusOK:
+		http.Redirect(w, r, "/", http.StatusSeeOther)
+	case http.StatusUnauthorized:
+		message := `You are not authorized! Please <a href="/login">login</a> before deleting a post.`
+		UnauthorizedErrorNotification(w, r, id, message)
+	case http.StatusForbidden:
+		UnauthorizedErrorNotification(w, r, id, responseDetails.Message)
+	case http.StatusNotFound:
+		StatusInternalServerError(w, "This post does not exist")
+	default:
+		UnauthorizedErrorNotification(w, r, id, "Oops! Something went wrong. Failed to delete post.")
+	}
+}
+
+// SendUpdatePostRequest sends the changes to the post with the given ID to the backend
+// and sends the outcome on respChan.
+func SendUpdatePostRequest(cookie *http.Cookie, id string, payload models.Post, waitGroup *sync.WaitGroup, respChan chan models.ResponseDetails) {
+	defer waitGroup.Done()
+	respChan <- sendToBackend(cookie, http.MethodPut, config.BaseApi+"/post/"+url.PathEscape(id), payload)
+}
+
+// SendDeletePostRequest asks the backend to delete the post with the given ID
+// and sends the outcome on respChan.
+func SendDeletePostRequest(cookie *http.Cookie, id string, waitGroup *sync.WaitGroup, respChan chan models.ResponseDetails) {
+	defer waitGroup.Done()
+	respChan <- sendToBackend(cookie, http.MethodDelete, config.BaseApi+"/post/"+url.PathEscape(id), nil)
+}
+
 func SendCreatePostRequest(cookie *http.Cookie, payload models.Post, waitGroup *sync.WaitGroup, respChan chan models.ResponseDetails) {
 	defer waitGroup.Done() // Ensure the channel is closed once this function completes
 
@@ -428,10 +810,14 @@ func SendCreatePostRequest(cookie *http.Cookie, payload models.Post, waitGroup *
 		message = "Unexpected response format"
 	}
 
+	// The new post comes with its ID
+	id, _ := responseMessage["id"].(float64)
+
 	respChan <- models.ResponseDetails{
 		Success: true,
 		Message: fmt.Sprintln(message), // displays server response to the user
 		Status:  resp.StatusCode,
+		ID:      int(id),
 	}
 }
 
@@ -445,6 +831,9 @@ func SendGetPostByIdRequest(id string, w http.ResponseWriter, r *http.Request, w
 		StatusInternalServerError(w, message)
 		return
 	}
+	if cookie, err := r.Cookie("session_token"); err == nil {
+		req.AddCookie(cookie)
+	}
 
 	// Use an http.Client to make the request
 	client := &http.Client{}
@@ -473,9 +862,12 @@ func SendGetPostByIdRequest(id string, w http.ResponseWriter, r *http.Request, w
 		return
 	}
 
+	labelPostReactions(&response)
+
 	respChan <- models.PostDetails{
 		Post:     response.Post,
 		Comments: response.Comments,
+		Next:     response.Next,
 		Likes:    response.Likes,
 		Dislikes: response.Dislikes,
 		Status:   resp.StatusCode,
@@ -522,9 +914,9 @@ func SendShowPostsRequest(apiURL string, respChan chan models.Data) {
 		return
 	}
 
-	// Unmarshal the response directly into a slice of posts
-	var posts []models.Post
-	if err := json.Unmarshal(body, &posts); err != nil {
+	// Unmarshal the response into a page of posts
+	var page models.PostPage
+	if err := json.Unmarshal(body, &page); err != nil {
 		respChan <- models.Data{
 			Success: false,
 			Message: fmt.Sprintf("error unmarshaling response: %v", err),
@@ -535,7 +927,8 @@ func SendShowPostsRequest(apiURL string, respChan chan models.Data) {
 	// Successfully return the posts
 	respChan <- models.Data{
 		Success: true,
-		Posts:   posts,
+		Posts:   page.Posts,
+		Next:    page.Next,
 		Message: "Posts fetched successfully",
 	}
 }
@@ -585,9 +978,9 @@ func SendShowPostsRequestFilter(cookie *http.Cookie, apiURL string, respChan cha
 		return
 	}
 
-	// Unmarshal the response directly into a slice of posts
-	var posts []models.Post
-	if err := json.Unmarshal(body, &posts); err != nil {
+	// Unmarshal the response into a page of posts
+	var page models.PostPage
+	if err := json.Unmarshal(body, &page); err != nil {
 		respChan <- models.Data{
 			Success: false,
 			Message: fmt.Sprintf("Error unmarshaling response: %v", err),
@@ -598,7 +991,8 @@ func SendShowPostsRequestFilter(cookie *http.Cookie, apiURL string, respChan cha
 	// Successfully return the posts
 	respChan <- models.Data{
 		Success: true,
-		Posts:   posts,
+		Posts:   page.Posts,
+		Next:    page.Next,
 		Message: "Posts fetched successfully",
 	}
 }
@@ -9,7 +9,11 @@ import (
 	"literary-lions/frontend/src/config"
 	"literary-lions/frontend/src/models"
 	"log"
+	"mime/multipart"
 	"net/http"
+	"net/url"
+	"strings"
+	"sync"
 	"time"
 )
 
@@ -47,14 +51,80 @@ func ShowUserProfile(w http.ResponseWriter, r *http.Request) {
 
 	// Handle GET requests to render the profile page
 	if r.Method == http.MethodGet {
+		// The profile is fetched along with the drafts or the reading shelves, which
+		// the drafts and shelves tabs list
+		tab := r.URL.Query().Get("tab")
+		profileChan := make(chan models.User, 1)
+		profileStatusChan := make(chan int, 1)
+		draftsChan := make(chan []models.Post, 1)
+		draftsStatusChan := make(chan int, 1)
+		shelvesChan := make(chan models.Shelves, 1)
+		shelvesStatusChan := make(chan int, 1)
+		var wg sync.WaitGroup
+
+		wg.Add(1)
+		go SendGetUserProfileRequest(currentUser, &wg, profileChan, profileStatusChan)
+		switch tab {
+		case "drafts":
+			wg.Add(1)
+			go SendGetDraftsRequest(cookie, &wg, draftsChan, draftsStatusChan)
+		case "shelves":
+			wg.Add(1)
+			go SendGetShelvesRequest(config.BaseApi+"/users/"+url.PathEscape(currentUser)+"/shelves", &wg, shelvesChan, shelvesStatusChan)
+		}
+		go func() {
+			wg.Wait()
+			close(profileChan)
+			close(profileStatusChan)
+			close(draftsChan)
+			close(draftsStatusChan)
+			close(shelvesChan)
+			close(shelvesStatusChan)
+		}()
+
+		profile := <-profileChan
+		if status := <-profileStatusChan; status != http.StatusOK {
+			StatusInternalServerError(w, "Failed to fetch your profile")
+			return
+		}
+		var drafts []models.Post
+		if tab == "drafts" {
+			drafts = <-draftsChan
+			if status := <-draftsStatusChan; status == http.StatusUnauthorized {
+				http.Redirect(w, r, "/login", http.StatusSeeOther)
+				return
+			} else if status != http.StatusOK {
+				StatusInternalServerError(w, "Failed to fetch your drafts")
+				return
+			}
+		}
+		var shelves models.Shelves
+		if tab == "shelves" {
+			shelves = <-shelvesChan
+			if status := <-shelvesStatusChan; status != http.StatusOK {
+				StatusInternalServerError(w, "Failed to fetch your shelves")
+				return
+			}
+		}
+
 		data := struct {
 			Error	 bool
 			Username string
 			Email    string
+			Profile  models.User
+			Tab      string
+			Drafts   []models.Post
+			Shelves  []shelfGroup
+			Stats    []models.ReadingYear
 		}{
 			Error:    false,
 			Username: currentUser,
 			Email:    userData.Email,
+			Profile:  profile,
+			Tab:      tab,
+			Drafts:   drafts,
+			Shelves:  groupShelves(shelves.Shelves),
+			Stats:    shelves.Stats,
 		}	
 
 		// Render the profile template with the user's data
@@ -101,36 +171,80 @@ func UpdateUserProfile(w http.ResponseWriter, r *http.Request) {
 
 	// Renders page to the user to update profile
 	if r.Method == http.MethodGet {
-		data := struct {
-			Username string
-			Email    string
-		}{
-			Username: currentUser,
-			Email:    userData.Email,
-		} 
-		
+		profileChan := make(chan models.User, 1)
+		statusChan := make(chan int, 1)
+		var wg sync.WaitGroup
+
+		wg.Add(1)
+		go SendGetUserProfileRequest(currentUser, &wg, profileChan, statusChan)
+		go func() {
+			wg.Wait()
+			close(profileChan)
+			close(statusChan)
+		}()
+
+		profile := <-profileChan
+		if status := <-statusChan; status != http.StatusOK {
+			StatusInternalServerError(w, "Failed to fetch your profile")
+			return
+		}
+		profile.Email = userData.Email
+
 		// Render the profile template with the user's data
-		RenderTemplate(w, "profile-update.html", data)
+		renderProfileForm(w, profile, "")
 		return
-	} 
+	}
 
 	// Handle POST request to process the update form submission
 	if r.Method == http.MethodPost {
-		// Extract credentials from form values
-		email := r.FormValue("email")
-		username := r.FormValue("username")
+		r.ParseMultipartForm(maxFormMemory) // Also reads forms without an avatar
 
-		respChan := make(chan models.ResponseDetails, 1)
+		// Extract the profile from form values; favorites are entered one per line
+		profile := models.User{
+			Email:          r.FormValue("email"),
+			Username:       r.FormValue("username"),
+			ProfilePic:     r.FormValue("profile_pic"),
+			Bio:            r.FormValue("bio"),
+			Location:       r.FormValue("location"),
+			FavoriteBooks:  splitLines(r.FormValue("favorite_books")),
+			FavoriteGenres: splitLines(r.FormValue("favorite_genres")),
+			HideLikedPosts: r.FormValue("hide_liked_posts") != "",
+		}
 
-		// Sample credentials
-		credentials := models.User{
-			Email:    email,
-			Username: username,
+		// Change the avatar first, so that a refused image leaves the profile as it was
+		var avatar *multipart.FileHeader
+		if r.MultipartForm != nil && len(r.MultipartForm.File["avatar"]) > 0 && r.MultipartForm.File["avatar"][0].Filename != "" {
+			avatar = r.MultipartForm.File["avatar"][0]
 		}
+		if avatar != nil || r.FormValue("remove_avatar") != "" {
+			avatarChan := make(chan models.ResponseDetails, 1)
+			var wg sync.WaitGroup
+
+			wg.Add(1)
+			if avatar != nil {
+				go SendUploadAvatarRequest(cookie, avatar, &wg, avatarChan)
+			} else {
+				go SendDeleteAvatarRequest(cookie, &wg, avatarChan)
+			}
+			go func() {
+				wg.Wait()
+				close(avatarChan)
+			}()
+
+			if response := <-avatarChan; response.Status == http.StatusUnauthorized {
+				http.Redirect(w, r, "/login", http.StatusSeeOther)
+				return
+			} else if response.Status != http.StatusOK {
+				renderProfileForm(w, profile, "Avatar: "+response.Message)
+				return
+			}
+		}
+
+		respChan := make(chan models.ResponseDetails, 1)
 
 		// Calls the function that sends request to the server
 		go func() {
-			SendUpdateUserProfile(cookie, credentials, respChan)
+			SendUpdateUserProfile(cookie, profile, respChan)
 		}()
 
 		select {
@@ -140,25 +254,9 @@ func UpdateUserProfile(w http.ResponseWriter, r *http.Request) {
 				sessionStore.Set(token, response.Username, response.Email)
 				http.Redirect(w, r, "/profile", http.StatusSeeOther)
 				return
-			} else {
-				// Pass error message to template
-				tmpl := template.Must(template.ParseFiles("templates/profile.html"))
-				data := struct {
-					Error   template.HTML
-					Email   string
-					Username string
-				}{
-					Error:    template.HTML(response.Message),
-					Username: currentUser,
-					Email:    userData.Email,
-				}
-				if err := tmpl.Execute(w, data); err != nil {
-					message := "Error rendering template"
-					StatusInternalServerError(w, message)
-					// http.Error(w, "Error rendering template", http.StatusInternalServerError)
-					log.Fatalf("Error rendering template: %v", err)
-				}
 			}
+			// Show the form again with the error message
+			renderProfileForm(w, profile, response.Message)
 		case <-time.After(10 * time.Second):
 			// Handle the case where the operation times out
 			message := "Profile update timed out"
@@ -272,4 +370,88 @@ func SendUpdateUserProfile(cookie *http.Cookie, user models.User, respChan chan
 		Username: username,
 		Email:	  email,
 	}
-}
\ No newline at end of file
+}
+
+// renderProfileForm renders the profile update form filled in with profile,
+// and the message of the error that kept it from being saved, if any.
+func renderProfileForm(w http.ResponseWriter, profile models.User, message string) {
+	data := struct {
+		Error          string
+		Profile        models.User
+		FavoriteBooks  string
+		FavoriteGenres string
+	}{
+		Error:          message,
+		Profile:        profile,
+		FavoriteBooks:  strings.Join(profile.FavoriteBooks, "\n"),
+		FavoriteGenres: strings.Join(profile.FavoriteGenres, "\n"),
+	}
+	RenderTemplate(w, "profile-update.html", data)
+}
+
+// splitLines returns the non-empty lines of a text area, trimmed.
+func splitLines(text string) []string {
+	lines := []string{}
+	for _, line := range strings.Split(text, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+// SendGetUserProfileRequest fetches the public profile of the member with the given
+// username from the backend, and sends it on respChan and the status code of the
+// response on statusChan.
+func SendGetUserProfileRequest(username string, waitGroup *sync.WaitGroup, respChan chan models.User, statusChan chan int) {
+	defer waitGroup.Done()
+
+	var profile models.User
+	body, status, err := getFromBackend(config.BaseApi + "/users/" + url.PathEscape(username))
+	if err != nil {
+		log.Print(err)
+		status = http.StatusInternalServerError
+	} else if status == http.StatusOK {
+		if err := json.Unmarshal(body, &profile); err != nil {
+			log.Printf("Failed to parse profile: %v", err)
+			status = http.StatusInternalServerError
+		}
+	}
+
+	respChan <- profile
+	statusChan <- status
+}
+
+// SendUploadAvatarRequest sets the avatar of the logged-in user from the image
+// chosen in the form, and sends the outcome on respChan.
+func SendUploadAvatarRequest(cookie *http.Cookie, file *multipart.FileHeader, waitGroup *sync.WaitGroup, respChan chan models.ResponseDetails) {
+	defer waitGroup.Done()
+	response := models.ResponseDetails{Status: http.StatusInternalServerError, Message: "Failed to upload image"}
+
+	req, err := newFileRequest(cookie, http.MethodPut, config.BaseApi+"/userprofile-avatar", "avatar", file)
+	if err != nil {
+		respChan <- response
+		return
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		log.Printf("Failed to upload avatar: %v", err)
+		respChan <- response
+		return
+	}
+	defer resp.Body.Close()
+
+	response.Status = resp.StatusCode
+	response.Success = resp.StatusCode == http.StatusOK
+	if message := backendError(resp.Body); message != "" {
+		response.Message = message
+	}
+	respChan <- response
+}
+
+// SendDeleteAvatarRequest removes the avatar of the logged-in user and sends the
+// outcome on respChan.
+func SendDeleteAvatarRequest(cookie *http.Cookie, waitGroup *sync.WaitGroup, respChan chan models.ResponseDetails) {
+	defer waitGroup.Done()
+	respChan <- sendToBackend(cookie, http.MethodDelete, config.BaseApi+"/userprofile-avatar", nil)
+}
@@ -0,0 +1,171 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"html"
+	"io/ioutil"
+	"literary-lions/frontend/src/config"
+	"literary-lions/frontend/src/models"
+	"net/http"
+	"net/url"
+	"sync"
+)
+
+// reactionEmojis maps the reactions of the default set to the emoji shown next to their name.
+// Reactions configured on the backend without an emoji here are shown by name alone.
+var reactionEmojis = map[string]string{
+	"insightful": "💡",
+	"funny":      "😂",
+	"spoiler":    "⚠️",
+	"agree":      "👍",
+}
+
+// reactionLabel returns the text of the picker button for a reaction.
+func reactionLabel(reaction string) string {
+	if emoji, ok := reactionEmojis[reaction]; ok {
+		return emoji + " " + reaction
+	}
+	return reaction
+}
+
+// labelPostReactions fills in the picker labels of the reactions on a post and its comments.
+func labelPostReactions(details *models.PostDetails) {
+	for i := range details.Post.Reactions {
+		details.Post.Reactions[i].Label = reactionLabel(details.Post.Reactions[i].Reaction)
+	}
+	for i := range details.Comments {
+		for j := range details.Comments[i].Reactions {
+			details.Comments[i].Reactions[j].Label = reactionLabel(details.Comments[i].Reactions[j].Reaction)
+		}
+	}
+}
+
+// React adds or removes a reaction picked on the post page, then returns to the post.
+// The form names the target ("post" or "comment") and its ID, the post it is shown on,
+// the reaction, and whether to "add" or "remove" it.
+func React(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Redirect(w, r, "/", http.StatusSeeOther)
+		return
+	}
+	r.ParseForm()
+	target := r.FormValue("target")
+	targetID := r.FormValue("id")
+	postID := r.FormValue("post_id")
+	reaction := r.FormValue("reaction")
+
+	// Only posts and comments can be reacted to
+	if target != "post" && target != "comment" {
+		UnauthorizedErrorNotification(w, r, postID, "Invalid reaction target")
+		return
+	}
+
+	// Adding is the default; removing is a DELETE on the same resource
+	method := http.MethodPost
+	if r.FormValue("action") == "remove" {
+		method = http.MethodDelete
+	}
+
+	// Extract the session cookie from the header
+	cookieToken, err := r.Cookie("session_token")
+	if err != nil {
+		// User must be logged-in to continue
+		message := `You are not authorized! Please <a href="/login">login</a> before reacting.`
+		UnauthorizedErrorNotification(w, r, postID, message)
+		return
+	}
+
+	respChan := make(chan models.ResponseDetails, 1)
+	var wg sync.WaitGroup
+
+	wg.Add(1)
+
+	// Calls the function that sends request to the server
+	apiURL := config.BaseApi + "/" + target + "/" + url.PathEscape(targetID) + "/reactions/" + url.PathEscape(reaction)
+	go SendReactionRequest(method, apiURL, cookieToken, &wg, respChan)
+	go func() {
+		wg.Wait()
+		close(respChan)
+	}()
+
+	responseDetails := <-respChan
+
+	if responseDetails.Status == http.StatusOK {
+		redirect := "/post?id=" + url.QueryEscape(postID)
+		if target == "comment" {
+			redirect += "#comment-" + url.PathEscape(targetID)
+		}
+		http.Redirect(w, r, redirect, http.StatusSeeOther)
+	} else if responseDetails.Status == http.StatusUnauthorized {
+		// The backend asks to log in again with a link
+		UnauthorizedErrorNotification(w, r, postID, responseDetails.Message)
+	} else {
+		UnauthorizedErrorNotification(w, r, postID, html.EscapeString(responseDetails.Message))
+	}
+}
+
+// SendReactionRequest sends an add (POST) or remove (DELETE) reaction request to the backend.
+func SendReactionRequest(method, apiURL string, cookie *http.Cookie, waitGroup *sync.WaitGroup, respChan chan models.ResponseDetails) {
+	defer waitGroup.Done()
+	// Creates request to send the the backend
+	req, err := http.NewRequest(method, apiURL, nil)
+	if err != nil {
+		respChan <- models.ResponseDetails{Status: http.StatusInternalServerError, Message: "Failed to create request"}
+		return
+	}
+	req.AddCookie(cookie) // adding cookies to the request
+
+	// Sends request to the server
+	client := &http.Client{}
+	resp, err := client.Do(req)
+	if err != nil {
+		respChan <- models.ResponseDetails{Status: http.StatusInternalServerError, Message: "Request failed"}
+		return
+	}
+	defer resp.Body.Close()
+
+	// Reads the response body
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		respChan <- models.ResponseDetails{
+			Success: false,
+			Message: fmt.Sprintf("error reading response: %v", err),
+		}
+		return
+	}
+
+	// Decode the message or the error returned by the backend
+	var responseMessage map[string]interface{}
+	if err := json.Unmarshal(body, &responseMessage); err != nil {
+		respChan <- models.ResponseDetails{
+			Success: false,
+			Message: string(body),
+			Status:  resp.StatusCode,
+		}
+		return
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		errorMessage := "unknown error"
+		if errMsg, exists := responseMessage["error"]; exists {
+			errorMessage = fmt.Sprintf("%v", errMsg)
+		}
+		respChan <- models.ResponseDetails{
+			Success: false,
+			Message: errorMessage,
+			Status:  resp.StatusCode,
+		}
+		return
+	}
+
+	message, ok := responseMessage["message"].(string)
+	if !ok {
+		message = "Unexpected response format"
+	}
+	respChan <- models.ResponseDetails{
+		Success: true,
+		Message: message,
+		Status:  resp.StatusCode,
+	}
+}
@@ -0,0 +1,138 @@
+package handlers
+
+import (
+	"encoding/json"
+	"literary-lions/frontend/src/config"
+	"literary-lions/frontend/src/models"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// SaveReview writes the review of the form for the book named by the book query
+// parameter, or edits the review named by id, then shows the book again.
+func SaveReview(w http.ResponseWriter, r *http.Request) {
+	bookID := r.URL.Query().Get("book")
+	reviewID := r.URL.Query().Get("id")
+	bookPage := "/book?id=" + url.QueryEscape(bookID)
+
+	if r.Method != http.MethodPost {
+		http.Redirect(w, r, bookPage, http.StatusSeeOther)
+		return
+	}
+	cookieToken, err := r.Cookie("session_token")
+	if err != nil {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+
+	r.ParseForm()
+	review := models.Review{
+		Spoiler: r.FormValue("spoiler") != "",
+		Content: r.FormValue("content"),
+	}
+	review.Rating, _ = strconv.Atoi(r.FormValue("rating"))
+	review.ID, _ = strconv.Atoi(reviewID)
+
+	respChan := make(chan models.ResponseDetails, 1)
+	var wg sync.WaitGroup
+
+	wg.Add(1)
+	go SendSaveReviewRequest(cookieToken, bookID, review, &wg, respChan)
+	go func() {
+		wg.Wait()
+		close(respChan)
+	}()
+
+	switch responseDetails := <-respChan; responseDetails.Status {
+	case http.StatusCreated, http.StatusOK:
+		http.Redirect(w, r, bookPage+"#reviews", http.StatusSeeOther)
+	case http.StatusUnauthorized:
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+	case http.StatusBadRequest, http.StatusConflict:
+		// Show what the backend rejected and keep what was typed
+		showBook(w, r, url.Values{"id": {bookID}}, &review, strings.TrimSpace(responseDetails.Message))
+	case http.StatusForbidden, http.StatusNotFound:
+		StatusInternalServerError(w, responseDetails.Message)
+	default:
+		StatusInternalServerError(w, "Oops! Something went wrong. Failed to save the review.")
+	}
+}
+
+// DeleteReview removes the review named by the id query parameter, then shows the
+// book named by book again.
+func DeleteReview(w http.ResponseWriter, r *http.Request) {
+	bookPage := "/book?id=" + url.QueryEscape(r.URL.Query().Get("book"))
+
+	if r.Method != http.MethodPost {
+		http.Redirect(w, r, bookPage, http.StatusSeeOther)
+		return
+	}
+	cookieToken, err := r.Cookie("session_token")
+	if err != nil {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+
+	respChan := make(chan models.ResponseDetails, 1)
+	var wg sync.WaitGroup
+
+	wg.Add(1)
+	apiURL := config.BaseApi + "/reviews/" + url.PathEscape(r.URL.Query().Get("id"))
+	go func() {
+		defer wg.Done()
+		respChan <- sendToBackend(cookieToken, http.MethodDelete, apiURL, nil)
+	}()
+	go func() {
+		wg.Wait()
+		close(respChan)
+	}()
+
+	switch responseDetails := <-respChan; responseDetails.Status {
+	case http.StatusOK:
+		http.Redirect(w, r, bookPage+"#reviews", http.StatusSeeOther)
+	case http.StatusUnauthorized:
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+	case http.StatusBadRequest, http.StatusForbidden, http.StatusNotFound:
+		StatusInternalServerError(w, responseDetails.Message)
+	default:
+		StatusInternalServerError(w, "Oops! Something went wrong. Failed to delete the review.")
+	}
+}
+
+// SendGetReviewsRequest fetches one page of the reviews of a book from the backend,
+// with the given cookies, and sends it on respChan and the status code of the
+// response on statusChan.
+func SendGetReviewsRequest(apiURL string, cookies []*http.Cookie, waitGroup *sync.WaitGroup, respChan chan models.ReviewPage, statusChan chan int) {
+	defer waitGroup.Done()
+
+	var page models.ReviewPage
+	body, status, err := getFromBackend(apiURL, cookies...)
+	if err != nil {
+		log.Print(err)
+		status = http.StatusInternalServerError
+	} else if status == http.StatusOK {
+		if err := json.Unmarshal(body, &page); err != nil {
+			log.Printf("Failed to parse reviews: %v", err)
+			status = http.StatusInternalServerError
+		}
+	}
+
+	respChan <- page
+	statusChan <- status
+}
+
+// SendSaveReviewRequest sends a new review of the book with the given ID to the
+// backend, or the edit of an existing one when the review has an ID, and sends the
+// outcome on respChan.
+func SendSaveReviewRequest(cookie *http.Cookie, bookID string, review models.Review, waitGroup *sync.WaitGroup, respChan chan models.ResponseDetails) {
+	defer waitGroup.Done()
+	if review.ID != 0 {
+		respChan <- sendToBackend(cookie, http.MethodPut, config.BaseApi+"/reviews/"+strconv.Itoa(review.ID), review)
+		return
+	}
+	respChan <- sendToBackend(cookie, http.MethodPost, config.BaseApi+"/books/"+url.PathEscape(bookID)+"/reviews", review)
+}
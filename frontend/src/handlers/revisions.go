@@ -0,0 +1,217 @@
+package handlers
+
+import (
+	"encoding/json"
+	"literary-lions/frontend/src/config"
+	"literary-lions/frontend/src/models"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+// ShowPostHistory renders the revisions of the post named by the id query parameter,
+// the latest first, with a restore button for admins.
+func ShowPostHistory(w http.ResponseWriter, r *http.Request) {
+	id := r.URL.Query().Get("id")
+
+	// The session cookie tells the backend whether the user may restore revisions
+	var cookies []*http.Cookie
+	if cookie, err := r.Cookie("session_token"); err == nil {
+		cookies = append(cookies, cookie)
+	}
+
+	respChan := make(chan models.PostHistory, 1)
+	statusChan := make(chan int, 1)
+	var wg sync.WaitGroup
+
+	wg.Add(1)
+	apiURL := config.BaseApi + "/post/" + url.PathEscape(id) + "/revisions"
+	go SendGetPostHistoryRequest(apiURL, cookies, &wg, respChan, statusChan)
+	go func() {
+		wg.Wait()
+		close(respChan)
+		close(statusChan)
+	}()
+
+	history := <-respChan
+	status := <-statusChan
+
+	if status == http.StatusNotFound || status == http.StatusBadRequest {
+		StatusInternalServerError(w, "This post does not exist")
+		return
+	} else if status != http.StatusOK {
+		StatusInternalServerError(w, "Failed to fetch the history of the post")
+		return
+	}
+
+	currentUser, authenticated := isAuthenticated(r)
+
+	data := struct {
+		PostID        string
+		Title         string
+		Revisions     []models.PostRevision
+		CanRestore    bool
+		Authenticated bool
+		Username      string
+	}{
+		PostID:        id,
+		Title:         history.Revisions[0].Title,
+		Revisions:     history.Revisions,
+		CanRestore:    history.CanRestore,
+		Authenticated: authenticated,
+		Username:      currentUser,
+	}
+
+	RenderTemplate(w, "history.html", data)
+}
+
+// ShowRevisionDiff renders what the revision named by the revision query parameter
+// changed in the post named by id, compared with the revision in against or, by
+// default, the one before it.
+func ShowRevisionDiff(w http.ResponseWriter, r *http.Request) {
+	id := r.URL.Query().Get("id")
+	revision := r.URL.Query().Get("revision")
+
+	params := url.Values{}
+	if against := r.URL.Query().Get("against"); against != "" {
+		params.Set("against", against)
+	}
+
+	respChan := make(chan models.RevisionDiff, 1)
+	statusChan := make(chan int, 1)
+	var wg sync.WaitGroup
+
+	wg.Add(1)
+	apiURL := config.BaseApi + "/post/" + url.PathEscape(id) + "/revisions/" + url.PathEscape(revision) + "/diff?" + params.Encode()
+	go SendGetRevisionDiffRequest(apiURL, &wg, respChan, statusChan)
+	go func() {
+		wg.Wait()
+		close(respChan)
+		close(statusChan)
+	}()
+
+	diff := <-respChan
+	status := <-statusChan
+
+	if status == http.StatusNotFound || status == http.StatusBadRequest {
+		StatusInternalServerError(w, "This revision does not exist")
+		return
+	} else if status != http.StatusOK {
+		StatusInternalServerError(w, "Failed to compare the revisions")
+		return
+	}
+
+	currentUser, authenticated := isAuthenticated(r)
+
+	data := struct {
+		PostID        string
+		Diff          models.RevisionDiff
+		Authenticated bool
+		Username      string
+	}{
+		PostID:        id,
+		Diff:          diff,
+		Authenticated: authenticated,
+		Username:      currentUser,
+	}
+
+	RenderTemplate(w, "revision-diff.html", data)
+}
+
+// RestoreRevision asks the backend to restore the revision named by the revision
+// query parameter of the post named by id, then shows the post. Only admins may.
+func RestoreRevision(w http.ResponseWriter, r *http.Request) {
+	id := r.URL.Query().Get("id")
+	revision := r.URL.Query().Get("revision")
+
+	if r.Method != http.MethodPost {
+		http.Redirect(w, r, "post-history?id="+id, http.StatusSeeOther)
+		return
+	}
+
+	// Extract the session cookie from the header
+	cookieToken, err := r.Cookie("session_token")
+	if err != nil {
+		message := `You are not authorized! Please <a href="/login">login</a> before restoring a revision.`
+		UnauthorizedErrorNotification(w, r, id, message)
+		return
+	}
+
+	respChan := make(chan models.ResponseDetails, 1)
+	var wg sync.WaitGroup
+
+	wg.Add(1)
+	go SendRestoreRevisionRequest(cookieToken, id, revision, &wg, respChan)
+	go func() {
+		wg.Wait()
+		close(respChan)
+	}()
+
+	responseDetails := <-respChan
+
+	switch responseDetails.Status {
+	case http.StatusOK:
+		http.Redirect(w, r, "post?id="+id, http.StatusSeeOther)
+	case http.StatusUnauthorized:
+		message := `You are not authorized! Please <a href="/login">login</a> before restoring a revision.`
+		UnauthorizedErrorNotification(w, r, id, message)
+	case http.StatusForbidden:
+		UnauthorizedErrorNotification(w, r, id, "Only admins can restore revisions.")
+	case http.StatusNotFound, http.StatusBadRequest:
+		StatusInternalServerError(w, strings.TrimSpace(responseDetails.Message))
+	default:
+		UnauthorizedErrorNotification(w, r, id, "Oops! Something went wrong. Failed to restore the revision.")
+	}
+}
+
+// SendGetPostHistoryRequest fetches the revisions of a post from the backend, and
+// sends them on respChan and the status code of the response on statusChan.
+func SendGetPostHistoryRequest(apiURL string, cookies []*http.Cookie, waitGroup *sync.WaitGroup, respChan chan models.PostHistory, statusChan chan int) {
+	defer waitGroup.Done()
+
+	var history models.PostHistory
+	body, status, err := getFromBackend(apiURL, cookies...)
+	if err != nil {
+		log.Print(err)
+		status = http.StatusInternalServerError
+	} else if status == http.StatusOK {
+		if err := json.Unmarshal(body, &history); err != nil || len(history.Revisions) == 0 {
+			log.Printf("Failed to parse post history: %v", err)
+			status = http.StatusInternalServerError
+		}
+	}
+
+	respChan <- history
+	statusChan <- status
+}
+
+// SendGetRevisionDiffRequest fetches the comparison of two revisions of a post from
+// the backend, and sends it on respChan and the status code of the response on statusChan.
+func SendGetRevisionDiffRequest(apiURL string, waitGroup *sync.WaitGroup, respChan chan models.RevisionDiff, statusChan chan int) {
+	defer waitGroup.Done()
+
+	var diff models.RevisionDiff
+	body, status, err := getFromBackend(apiURL)
+	if err != nil {
+		log.Print(err)
+		status = http.StatusInternalServerError
+	} else if status == http.StatusOK {
+		if err := json.Unmarshal(body, &diff); err != nil {
+			log.Printf("Failed to parse revision diff: %v", err)
+			status = http.StatusInternalServerError
+		}
+	}
+
+	respChan <- diff
+	statusChan <- status
+}
+
+// SendRestoreRevisionRequest asks the backend to restore a revision of the post with
+// the given ID and sends the outcome on respChan.
+func SendRestoreRevisionRequest(cookie *http.Cookie, id, revision string, waitGroup *sync.WaitGroup, respChan chan models.ResponseDetails) {
+	defer waitGroup.Done()
+	apiURL := config.BaseApi + "/admin/posts/" + url.PathEscape(id) + "/revisions/" + url.PathEscape(revision) + "/restore"
+	respChan <- sendToBackend(cookie, http.MethodPost, apiURL, nil)
+}
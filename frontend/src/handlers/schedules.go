@@ -0,0 +1,172 @@
+package handlers
+
+import (
+	"encoding/json"
+	"literary-lions/frontend/src/config"
+	"literary-lions/frontend/src/models"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// ShowClubSchedule renders the reading schedule of the club named by the slug query
+// parameter, with the buttons to mark its sections read for the members and the
+// forms to plan it for the moderators.
+func ShowClubSchedule(w http.ResponseWriter, r *http.Request) {
+	slug := r.URL.Query().Get("slug")
+
+	// The session tells the backend what the member has read
+	var cookies []*http.Cookie
+	if cookieToken, err := r.Cookie("session_token"); err == nil {
+		cookies = append(cookies, cookieToken)
+	}
+
+	respChan := make(chan models.ClubSchedule, 1)
+	statusChan := make(chan int, 1)
+	var wg sync.WaitGroup
+
+	wg.Add(1)
+	go SendGetClubScheduleRequest(config.BaseApi+"/clubs/"+url.PathEscape(slug)+"/schedule", cookies, &wg, respChan, statusChan)
+	go func() {
+		wg.Wait()
+		close(respChan)
+		close(statusChan)
+	}()
+
+	schedule := <-respChan
+	switch status := <-statusChan; status {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		StatusInternalServerError(w, "This club does not exist")
+		return
+	case http.StatusForbidden:
+		StatusInternalServerError(w, "The schedule of this private club is shown to its members only")
+		return
+	default:
+		StatusInternalServerError(w, "Failed to fetch the reading schedule")
+		return
+	}
+
+	// Only the moderators pick the books sections are about
+	var books []models.Book
+	if schedule.CanModerate {
+		books = loadBooks()
+	}
+
+	currentUser, authenticated := isAuthenticated(r)
+	membership := schedule.Club.Membership
+
+	data := struct {
+		Club          models.Club
+		Sections      []models.ClubSection
+		CanModerate   bool
+		Member        bool
+		Books         []models.Book
+		Authenticated bool
+		Username      string
+	}{
+		Club:          schedule.Club,
+		Sections:      schedule.Sections,
+		CanModerate:   schedule.CanModerate,
+		Member:        membership != nil && membership.Approved,
+		Books:         books,
+		Authenticated: authenticated,
+		Username:      currentUser,
+	}
+
+	RenderTemplate(w, "club-schedule.html", data)
+}
+
+// scheduleURL returns the reading schedule of the club with the given slug.
+func scheduleURL(slug string) string {
+	return "/club-schedule?slug=" + url.QueryEscape(slug)
+}
+
+// AddClubSection adds the section of the form to the reading schedule of the club
+// named by the slug query parameter, for its moderators, then shows the schedule.
+func AddClubSection(w http.ResponseWriter, r *http.Request) {
+	slug := r.URL.Query().Get("slug")
+	r.ParseForm()
+	changeClub(w, r, http.MethodPost, "/schedule", formSection(r), scheduleURL(slug))
+}
+
+// EditClubSection saves the title, unlock date and book of the form for the
+// section named by the id query parameter, for the moderators of the club named by
+// the slug one, then shows the schedule.
+func EditClubSection(w http.ResponseWriter, r *http.Request) {
+	slug := r.URL.Query().Get("slug")
+	r.ParseForm()
+	changeClub(w, r, http.MethodPut, sectionPath(r), formSection(r), scheduleURL(slug))
+}
+
+// DeleteClubSection takes the section named by the id query parameter off the
+// reading schedule of the club named by the slug one, for its moderators, then
+// shows the schedule.
+func DeleteClubSection(w http.ResponseWriter, r *http.Request) {
+	slug := r.URL.Query().Get("slug")
+	changeClub(w, r, http.MethodDelete, sectionPath(r), nil, scheduleURL(slug))
+}
+
+// MarkClubSection marks the section named by the id query parameter read or
+// unread, as the action form field tells, for the logged-in member, then goes back
+// to the page the form was on.
+func MarkClubSection(w http.ResponseWriter, r *http.Request) {
+	slug := r.URL.Query().Get("slug")
+	r.ParseForm()
+
+	// Only go back to pages of this site
+	next := r.FormValue("next")
+	if !strings.HasPrefix(next, "/") || strings.HasPrefix(next, "//") {
+		next = scheduleURL(slug)
+	}
+
+	switch r.FormValue("action") {
+	case "read":
+		changeClub(w, r, http.MethodPut, sectionPath(r)+"/read", nil, next)
+	case "unread":
+		changeClub(w, r, http.MethodDelete, sectionPath(r)+"/read", nil, next)
+	default:
+		http.Redirect(w, r, next, http.StatusSeeOther)
+	}
+}
+
+// sectionPath returns the path, under the club on the backend, of the section
+// named by the id query parameter.
+func sectionPath(r *http.Request) string {
+	return "/schedule/" + url.PathEscape(r.URL.Query().Get("id"))
+}
+
+// formSection reads the section typed in a form.
+func formSection(r *http.Request) models.ClubSectionRequest {
+	bookID, _ := strconv.Atoi(r.FormValue("book_id"))
+	return models.ClubSectionRequest{
+		Title:     r.FormValue("title"),
+		UnlocksAt: r.FormValue("unlocks_at"),
+		BookID:    bookID,
+	}
+}
+
+// SendGetClubScheduleRequest fetches the reading schedule of a club from the
+// backend, with the given cookies, and sends it on respChan and the status code of
+// the response on statusChan.
+func SendGetClubScheduleRequest(apiURL string, cookies []*http.Cookie, waitGroup *sync.WaitGroup, respChan chan models.ClubSchedule, statusChan chan int) {
+	defer waitGroup.Done()
+
+	var schedule models.ClubSchedule
+	body, status, err := getFromBackend(apiURL, cookies...)
+	if err != nil {
+		log.Print(err)
+		status = http.StatusInternalServerError
+	} else if status == http.StatusOK {
+		if err := json.Unmarshal(body, &schedule); err != nil {
+			log.Printf("Failed to parse schedule: %v", err)
+			status = http.StatusInternalServerError
+		}
+	}
+
+	respChan <- schedule
+	statusChan <- status
+}
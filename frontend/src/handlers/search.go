@@ -0,0 +1,139 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"literary-lions/frontend/src/config"
+	"literary-lions/frontend/src/models"
+	"net/http"
+	"net/url"
+	"sync"
+)
+
+// ShowSearchResults renders the index page with the full-text search hits for the keyword
+// typed in the search box, narrowed by the category, tags and dates picked next to it.
+func ShowSearchResults(w http.ResponseWriter, r *http.Request, keyword, category, tag, startDate, endDate string) {
+	// Construct the API request URL with query parameters
+	params := url.Values{}
+	params.Set("q", keyword)
+	if category != "" {
+		params.Set("category", category)
+	}
+	if tag != "" {
+		params.Set("tag", tag)
+	}
+	if startDate != "" {
+		params.Set("start_date", startDate)
+	}
+	if endDate != "" {
+		params.Set("end_date", endDate)
+	}
+	apiURL := config.BaseApi + "/search?" + params.Encode()
+
+	respChan := make(chan models.Data, 1)
+	var wg sync.WaitGroup
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		SendSearchRequest(apiURL, respChan)
+	}()
+
+	wg.Wait()
+	close(respChan)
+
+	response := <-respChan
+
+	if !response.Success {
+		handleErrorResponse(w, response)
+		return
+	}
+
+	currentUser, authenticated := isAuthenticated(r)
+
+	data := struct {
+		Query         string
+		Sort          string
+		Filter        string
+		Categories    []models.Category
+		Category      string
+		Tag           string
+		PopularTags   []models.Tag
+		SearchResults []models.SearchResult
+		Authenticated bool
+		Username      string
+		Error         bool
+	}{
+		Query:         keyword,
+		Categories:    loadCategories(),
+		PopularTags:   loadPopularTags(),
+		Category:      category,
+		Tag:           tag,
+		SearchResults: response.SearchResults,
+		Authenticated: authenticated,
+		Username:      currentUser,
+		Error:         false,
+	}
+
+	RenderTemplate(w, "index.html", data)
+}
+
+// SendSearchRequest fetches search hits from the backend and sends them on respChan.
+func SendSearchRequest(apiURL string, respChan chan models.Data) {
+	req, err := http.NewRequest("GET", apiURL, nil)
+	if err != nil {
+		respChan <- models.Data{
+			Success: false,
+			Message: fmt.Sprintf("error creating request: %v", err),
+		}
+		return
+	}
+
+	client := &http.Client{}
+	resp, err := client.Do(req)
+	if err != nil {
+		respChan <- models.Data{
+			Success: false,
+			Message: fmt.Sprintf("Failed to search: %v", err),
+		}
+		return
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		respChan <- models.Data{
+			Success: false,
+			Message: fmt.Sprintf("Failed to read response: %v", err),
+		}
+		return
+	}
+
+	// Check response status code
+	if resp.StatusCode != http.StatusOK {
+		respChan <- models.Data{
+			Success: false,
+			Status:  resp.StatusCode,
+			Message: fmt.Sprintf("Unexpected status code: %d", resp.StatusCode),
+		}
+		return
+	}
+
+	// Unmarshal the response directly into a slice of hits
+	var results []models.SearchResult
+	if err := json.Unmarshal(body, &results); err != nil {
+		respChan <- models.Data{
+			Success: false,
+			Message: fmt.Sprintf("error unmarshaling response: %v", err),
+		}
+		return
+	}
+
+	// Successfully return the hits
+	respChan <- models.Data{
+		Success:       true,
+		SearchResults: results,
+		Message:       "Search completed successfully",
+	}
+}
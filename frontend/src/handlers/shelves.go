@@ -0,0 +1,147 @@
+package handlers
+
+import (
+	"encoding/json"
+	"literary-lions/frontend/src/config"
+	"literary-lions/frontend/src/models"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// shelfLabels names the reading shelves, in the order they are shown.
+var shelfLabels = []struct{ Shelf, Label string }{
+	{"reading", "Currently reading"},
+	{"want_to_read", "Want to read"},
+	{"finished", "Finished"},
+}
+
+// shelfGroup is a reading shelf with the books on it.
+type shelfGroup struct {
+	Shelf   string
+	Label   string
+	Entries []models.ShelfEntry
+}
+
+// groupShelves sorts the books of a member onto their shelves, keeping the order
+// of the backend within each shelf.
+func groupShelves(entries []models.ShelfEntry) []shelfGroup {
+	groups := []shelfGroup{}
+	for _, shelf := range shelfLabels {
+		group := shelfGroup{Shelf: shelf.Shelf, Label: shelf.Label}
+		for _, entry := range entries {
+			if entry.Shelf == shelf.Shelf {
+				group.Entries = append(group.Entries, entry)
+			}
+		}
+		groups = append(groups, group)
+	}
+	return groups
+}
+
+// shelfReturnURL returns the page named by the next form field to go back to once
+// a shelf changes, the book named by bookID if the field is missing or not a path
+// of this site.
+func shelfReturnURL(r *http.Request, bookID string) string {
+	next := r.FormValue("next")
+	if !strings.HasPrefix(next, "/") || strings.HasPrefix(next, "//") {
+		return "/book?id=" + url.QueryEscape(bookID)
+	}
+	return next
+}
+
+// ShelveBook puts the book named by the book query parameter on the shelf of the
+// form, with the progress and finish date typed, then goes back to the page the
+// form was on.
+func ShelveBook(w http.ResponseWriter, r *http.Request) {
+	changeShelf(w, r, http.MethodPut)
+}
+
+// UnshelveBook takes the book named by the book query parameter off the shelves of
+// the logged-in member, then goes back to the page the form was on.
+func UnshelveBook(w http.ResponseWriter, r *http.Request) {
+	changeShelf(w, r, http.MethodDelete)
+}
+
+// changeShelf sends a change (PUT) or a removal (DELETE) of the shelf of a book to
+// the backend on behalf of the logged-in user and redirects back.
+func changeShelf(w http.ResponseWriter, r *http.Request, method string) {
+	bookID := r.URL.Query().Get("book")
+
+	r.ParseForm()
+	next := shelfReturnURL(r, bookID)
+	if r.Method != http.MethodPost {
+		http.Redirect(w, r, next, http.StatusSeeOther)
+		return
+	}
+	cookieToken, err := r.Cookie("session_token")
+	if err != nil {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+
+	var payload interface{}
+	if method == http.MethodPut {
+		request := models.ShelfRequest{Shelf: r.FormValue("shelf"), FinishedAt: r.FormValue("finished_at")}
+		if progress := strings.TrimSpace(r.FormValue("progress")); progress != "" {
+			if request.Progress, err = strconv.Atoi(progress); err != nil {
+				StatusInternalServerError(w, "The progress is a percentage from 0 to 100")
+				return
+			}
+		}
+		payload = request
+	}
+
+	respChan := make(chan models.ResponseDetails, 1)
+	var wg sync.WaitGroup
+
+	wg.Add(1)
+	go SendShelfRequest(cookieToken, method, bookID, payload, &wg, respChan)
+	go func() {
+		wg.Wait()
+		close(respChan)
+	}()
+
+	switch responseDetails := <-respChan; responseDetails.Status {
+	case http.StatusOK:
+		http.Redirect(w, r, next, http.StatusSeeOther)
+	case http.StatusUnauthorized:
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+	case http.StatusBadRequest, http.StatusNotFound:
+		StatusInternalServerError(w, responseDetails.Message)
+	default:
+		StatusInternalServerError(w, "Oops! Something went wrong. Failed to update your shelves.")
+	}
+}
+
+// SendGetShelvesRequest fetches the reading shelves and yearly stats of a member
+// from the backend, and sends them on respChan and the status code of the response
+// on statusChan.
+func SendGetShelvesRequest(apiURL string, waitGroup *sync.WaitGroup, respChan chan models.Shelves, statusChan chan int) {
+	defer waitGroup.Done()
+
+	var shelves models.Shelves
+	body, status, err := getFromBackend(apiURL)
+	if err != nil {
+		log.Print(err)
+		status = http.StatusInternalServerError
+	} else if status == http.StatusOK {
+		if err := json.Unmarshal(body, &shelves); err != nil {
+			log.Printf("Failed to parse shelves: %v", err)
+			status = http.StatusInternalServerError
+		}
+	}
+
+	respChan <- shelves
+	statusChan <- status
+}
+
+// SendShelfRequest sends a change (PUT) or a removal (DELETE) of the shelf of the
+// book with the given ID to the backend and sends the outcome on respChan.
+func SendShelfRequest(cookie *http.Cookie, method, bookID string, payload interface{}, waitGroup *sync.WaitGroup, respChan chan models.ResponseDetails) {
+	defer waitGroup.Done()
+	respChan <- sendToBackend(cookie, method, config.BaseApi+"/shelves/"+url.PathEscape(bookID), payload)
+}
@@ -0,0 +1,149 @@
+package handlers
+
+import (
+	"encoding/json"
+	"literary-lions/frontend/src/config"
+	"literary-lions/frontend/src/models"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// popularTagCount is the number of tags offered as suggestions in the forms.
+const popularTagCount = 50
+
+// loadPopularTags fetches the most used tags, suggested while typing tags.
+// A failure is logged and gives no suggestions, so that the page still renders.
+func loadPopularTags() []models.Tag {
+	respChan := make(chan []models.Tag, 1)
+	var wg sync.WaitGroup
+
+	wg.Add(1)
+	go SendGetTagsRequest(&wg, respChan)
+	go func() {
+		wg.Wait()
+		close(respChan)
+	}()
+
+	return <-respChan
+}
+
+// splitTags reads the comma-separated tags typed in a form.
+func splitTags(typed string) []string {
+	tags := []string{}
+	for _, tag := range strings.Split(typed, ",") {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
+
+// ShowTag renders one page of the posts carrying the tag named by the slug query parameter.
+func ShowTag(w http.ResponseWriter, r *http.Request) {
+	slug := r.URL.Query().Get("slug")
+	sort := r.URL.Query().Get("sort")
+
+	// Forward the sort order and cursor of the posts
+	params := url.Values{}
+	if sort != "" {
+		params.Set("sort", sort)
+	}
+	if cursor := r.URL.Query().Get("cursor"); cursor != "" {
+		params.Set("cursor", cursor)
+	}
+
+	respChan := make(chan models.TagPage, 1)
+	statusChan := make(chan int, 1)
+	var wg sync.WaitGroup
+
+	wg.Add(1)
+	apiURL := config.BaseApi + "/tags/" + url.PathEscape(slug) + "?" + params.Encode()
+	go SendGetTagRequest(apiURL, &wg, respChan, statusChan)
+	go func() {
+		wg.Wait()
+		close(respChan)
+		close(statusChan)
+	}()
+
+	page := <-respChan
+	status := <-statusChan
+
+	if status == http.StatusNotFound {
+		StatusInternalServerError(w, "No post carries this tag")
+		return
+	} else if status != http.StatusOK {
+		StatusInternalServerError(w, "Failed to fetch the tag")
+		return
+	}
+
+	// Truncate content if necessary
+	for i := range page.Posts.Posts {
+		page.Posts.Posts[i].Content = truncateContent(page.Posts.Posts[i].Content, 150)
+	}
+
+	currentUser, authenticated := isAuthenticated(r)
+
+	data := struct {
+		Tag           models.Tag
+		Posts         []models.Post
+		Authenticated bool
+		Username      string
+		Sort          string
+		NextURL       string
+		FirstURL      string
+	}{
+		Tag:           page.Tag,
+		Posts:         page.Posts.Posts,
+		Authenticated: authenticated,
+		Username:      currentUser,
+		Sort:          sort,
+		NextURL:       nextPageURL(r, page.Posts.Next),
+		FirstURL:      firstPageURL(r),
+	}
+
+	RenderTemplate(w, "tag.html", data)
+}
+
+// SendGetTagsRequest fetches the most used tags from the backend and sends them on respChan.
+func SendGetTagsRequest(waitGroup *sync.WaitGroup, respChan chan []models.Tag) {
+	defer waitGroup.Done()
+
+	body, status, err := getFromBackend(config.BaseApi + "/tags?limit=" + strconv.Itoa(popularTagCount))
+	if err != nil || status != http.StatusOK {
+		log.Printf("Failed to fetch tags: status %d, %v", status, err)
+		respChan <- nil
+		return
+	}
+
+	// Unmarshal the response directly into a slice of tags
+	var tags []models.Tag
+	if err := json.Unmarshal(body, &tags); err != nil {
+		log.Printf("Failed to parse tags: %v", err)
+	}
+	respChan <- tags
+}
+
+// SendGetTagRequest fetches a tag and one page of its posts from the backend,
+// and sends them on respChan and the status code of the response on statusChan.
+func SendGetTagRequest(apiURL string, waitGroup *sync.WaitGroup, respChan chan models.TagPage, statusChan chan int) {
+	defer waitGroup.Done()
+
+	var page models.TagPage
+	body, status, err := getFromBackend(apiURL)
+	if err != nil {
+		log.Print(err)
+		status = http.StatusInternalServerError
+	} else if status == http.StatusOK {
+		if err := json.Unmarshal(body, &page); err != nil {
+			log.Printf("Failed to parse tag: %v", err)
+			status = http.StatusInternalServerError
+		}
+	}
+
+	respChan <- page
+	statusChan <- status
+}
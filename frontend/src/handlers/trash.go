@@ -0,0 +1,146 @@
+package handlers
+
+import (
+	"encoding/json"
+	"literary-lions/frontend/src/config"
+	"literary-lions/frontend/src/models"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+// ShowTrash renders the posts and comments the logged-in user deleted, with a
+// button to restore each of them until they are purged.
+func ShowTrash(w http.ResponseWriter, r *http.Request) {
+	currentUser, authenticated := isAuthenticated(r)
+	cookieToken, err := r.Cookie("session_token")
+	if !authenticated || err != nil {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+
+	respChan := make(chan models.Trash, 1)
+	statusChan := make(chan int, 1)
+	var wg sync.WaitGroup
+
+	wg.Add(1)
+	go SendGetTrashRequest(cookieToken, &wg, respChan, statusChan)
+	go func() {
+		wg.Wait()
+		close(respChan)
+		close(statusChan)
+	}()
+
+	trash := <-respChan
+	status := <-statusChan
+
+	if status == http.StatusUnauthorized {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	} else if status != http.StatusOK {
+		StatusInternalServerError(w, "Failed to fetch your trash")
+		return
+	}
+
+	data := struct {
+		Trash         models.Trash
+		Authenticated bool
+		Username      string
+	}{
+		Trash:         trash,
+		Authenticated: authenticated,
+		Username:      currentUser,
+	}
+
+	RenderTemplate(w, "trash.html", data)
+}
+
+// RestorePost asks the backend to take the post named by the id query parameter
+// out of the trash, then shows it, or the drafts if the draft parameter is set.
+func RestorePost(w http.ResponseWriter, r *http.Request) {
+	id := r.URL.Query().Get("id")
+	target := "post?id=" + id
+	if r.URL.Query().Get("draft") != "" {
+		target = "/profile?tab=drafts"
+	}
+	restoreFromTrash(w, r, id, "/post/"+url.PathEscape(id)+"/restore", target, "post")
+}
+
+// RestoreComment asks the backend to take the comment named by the commentID query
+// parameter out of the trash, then shows the post named by postID it is on.
+func RestoreComment(w http.ResponseWriter, r *http.Request) {
+	commentID := r.URL.Query().Get("commentID")
+	postID := r.URL.Query().Get("postID")
+	restoreFromTrash(w, r, postID, "/comment/"+url.PathEscape(commentID)+"/restore", "post?id="+postID, "comment")
+}
+
+// restoreFromTrash posts a restore request to the backend path and redirects to
+// target once it succeeds, or renders what went wrong; item names what is restored.
+func restoreFromTrash(w http.ResponseWriter, r *http.Request, postID, path, target, item string) {
+	if r.Method != http.MethodPost {
+		http.Redirect(w, r, "/trash", http.StatusSeeOther)
+		return
+	}
+
+	// Extract the session cookie from the header
+	cookieToken, err := r.Cookie("session_token")
+	if err != nil {
+		message := `You are not authorized! Please <a href="/login">login</a> before restoring a ` + item + `.`
+		UnauthorizedErrorNotification(w, r, postID, message)
+		return
+	}
+
+	respChan := make(chan models.ResponseDetails, 1)
+	var wg sync.WaitGroup
+
+	wg.Add(1)
+	go SendRestoreRequest(cookieToken, config.BaseApi+path, &wg, respChan)
+	go func() {
+		wg.Wait()
+		close(respChan)
+	}()
+
+	responseDetails := <-respChan
+
+	switch responseDetails.Status {
+	case http.StatusOK:
+		http.Redirect(w, r, target, http.StatusSeeOther)
+	case http.StatusUnauthorized:
+		message := `You are not authorized! Please <a href="/login">login</a> before restoring a ` + item + `.`
+		UnauthorizedErrorNotification(w, r, postID, message)
+	case http.StatusNotFound, http.StatusBadRequest:
+		StatusInternalServerError(w, strings.TrimSpace(responseDetails.Message))
+	default:
+		UnauthorizedErrorNotification(w, r, postID, "Oops! Something went wrong. Failed to restore the "+item+".")
+	}
+}
+
+// SendGetTrashRequest fetches the trash of the logged-in user from the backend, and
+// sends it on respChan and the status code of the response on statusChan.
+func SendGetTrashRequest(cookie *http.Cookie, waitGroup *sync.WaitGroup, respChan chan models.Trash, statusChan chan int) {
+	defer waitGroup.Done()
+
+	var trash models.Trash
+	body, status, err := getFromBackend(config.BaseApi+"/trash", cookie)
+	if err != nil {
+		log.Print(err)
+		status = http.StatusInternalServerError
+	} else if status == http.StatusOK {
+		if err := json.Unmarshal(body, &trash); err != nil {
+			log.Printf("Failed to parse trash: %v", err)
+			status = http.StatusInternalServerError
+		}
+	}
+
+	respChan <- trash
+	statusChan <- status
+}
+
+// SendRestoreRequest asks the backend to restore an item of the trash at apiURL
+// and sends the outcome on respChan.
+func SendRestoreRequest(cookie *http.Cookie, apiURL string, waitGroup *sync.WaitGroup, respChan chan models.ResponseDetails) {
+	defer waitGroup.Done()
+	respChan <- sendToBackend(cookie, http.MethodPost, apiURL, nil)
+}
@@ -0,0 +1,219 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"literary-lions/frontend/src/config"
+	"literary-lions/frontend/src/models"
+	"log"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// maxPostImages is the number of images a post can carry.
+const maxPostImages = 10
+
+// maxFormMemory is how much of a form with images is held in memory; the rest
+// of the files wait on disk until they are sent to the backend.
+const maxFormMemory = 32 << 20
+
+// ServeUpload passes the uploaded image or thumbnail named in the path on from
+// the backend, which the browser does not reach, with its caching headers.
+func ServeUpload(w http.ResponseWriter, r *http.Request) {
+	key := strings.TrimPrefix(r.URL.Path, "/uploads/")
+	resp, err := http.Get(config.BaseApi + "/uploads/" + url.PathEscape(key))
+	if err != nil {
+		log.Printf("Failed to fetch upload: %v", err)
+		http.Error(w, "Image not available", http.StatusBadGateway)
+		return
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		http.NotFound(w, r)
+		return
+	}
+	for _, header := range []string{"Content-Type", "Content-Length", "Cache-Control", "X-Content-Type-Options"} {
+		if value := resp.Header.Get(header); value != "" {
+			w.Header().Set(header, value)
+		}
+	}
+	io.Copy(w, resp.Body)
+}
+
+// uploadImages sends the files chosen in the images field of the form to the
+// backend, all at once, and returns the IDs of the uploaded images in the order
+// they were chosen. The returned status is 201 Created unless an image was
+// refused, in which case the message names the file.
+func uploadImages(cookie *http.Cookie, r *http.Request, alreadyAttached int) ([]int, models.ResponseDetails) {
+	var files []*multipart.FileHeader
+	if r.MultipartForm != nil {
+		for _, file := range r.MultipartForm.File["images"] {
+			if file.Filename != "" {
+				files = append(files, file)
+			}
+		}
+	}
+	if alreadyAttached+len(files) > maxPostImages {
+		return nil, models.ResponseDetails{Status: http.StatusBadRequest, Message: fmt.Sprintf("A post can have at most %d images", maxPostImages)}
+	}
+
+	respChan := make(chan models.UploadDetails, len(files))
+	var wg sync.WaitGroup
+	for i, file := range files {
+		wg.Add(1)
+		go SendUploadImageRequest(cookie, i, file, &wg, respChan)
+	}
+	go func() {
+		wg.Wait()
+		close(respChan)
+	}()
+
+	ids := make([]int, len(files))
+	var failure *models.UploadDetails
+	for upload := range respChan {
+		if upload.Status == http.StatusCreated {
+			ids[upload.Index] = upload.Attachment.ID
+		} else if failure == nil || upload.Index < failure.Index {
+			// Report the first file of the form that was refused
+			refused := upload
+			failure = &refused
+		}
+	}
+	if failure != nil {
+		return nil, models.ResponseDetails{Status: failure.Status, Message: files[failure.Index].Filename + ": " + failure.Message}
+	}
+	return ids, models.ResponseDetails{Status: http.StatusCreated}
+}
+
+// formAttachments reads the images a post already carries from the edit form:
+// all of them, to show the form again, and the IDs of those kept, in order,
+// with the cover picked among them, 0 to leave it to the first image.
+func formAttachments(r *http.Request) ([]models.Attachment, []int, int) {
+	removed := map[string]bool{}
+	for _, id := range r.Form["remove"] {
+		removed[id] = true
+	}
+	cover := r.FormValue("cover")
+
+	var attachments []models.Attachment
+	var kept []int
+	coverID := 0
+	for _, value := range r.Form["attachment"] {
+		id, err := strconv.Atoi(value)
+		if err != nil {
+			continue
+		}
+		attachments = append(attachments, models.Attachment{ID: id, ThumbnailKey: r.FormValue("thumbnail_" + value), Cover: value == cover})
+		if !removed[value] {
+			kept = append(kept, id)
+			if value == cover {
+				coverID = id
+			}
+		}
+	}
+	return attachments, kept, coverID
+}
+
+// SendUploadImageRequest uploads one image to the backend as a multipart form
+// and sends the outcome on respChan, along with the index of the file.
+func SendUploadImageRequest(cookie *http.Cookie, index int, file *multipart.FileHeader, waitGroup *sync.WaitGroup, respChan chan models.UploadDetails) {
+	defer waitGroup.Done()
+	upload := models.UploadDetails{Index: index, Status: http.StatusInternalServerError, Message: "Failed to upload image"}
+
+	req, err := newFileRequest(cookie, http.MethodPost, config.BaseApi+"/uploads", "image", file)
+	if err != nil {
+		respChan <- upload
+		return
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		log.Printf("Failed to upload image: %v", err)
+		respChan <- upload
+		return
+	}
+	defer resp.Body.Close()
+
+	upload.Status = resp.StatusCode
+	if resp.StatusCode == http.StatusCreated {
+		if err := json.NewDecoder(resp.Body).Decode(&upload.Attachment); err != nil {
+			upload.Status = http.StatusInternalServerError
+		}
+	} else if message := backendError(resp.Body); message != "" {
+		upload.Message = message
+	}
+	respChan <- upload
+}
+
+// newFileRequest builds a request to the backend sending the file in the named
+// field of a multipart form, with the session cookie.
+func newFileRequest(cookie *http.Cookie, method, apiURL, field string, file *multipart.FileHeader) (*http.Request, error) {
+	src, err := file.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer src.Close()
+
+	// Build the form the backend expects, the file in the named field
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	part, err := writer.CreateFormFile(field, file.Filename)
+	if err == nil {
+		_, err = io.Copy(part, src)
+	}
+	if err == nil {
+		err = writer.Close()
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequest(method, apiURL, &body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	req.AddCookie(cookie)
+	return req, nil
+}
+
+// backendError returns the error message in the body of a response from the
+// backend, or an empty string if it has none.
+func backendError(body io.Reader) string {
+	var errorResponse struct {
+		Error string `json:"error"`
+	}
+	if json.NewDecoder(body).Decode(&errorResponse) != nil {
+		return ""
+	}
+	return errorResponse.Error
+}
+
+// SendSetAttachmentsRequest sets the images of a post or draft, named by its path
+// on the backend such as "/post/3" or "/drafts/4", and sends the outcome on respChan.
+func SendSetAttachmentsRequest(cookie *http.Cookie, target string, ids []int, coverID int, waitGroup *sync.WaitGroup, respChan chan models.ResponseDetails) {
+	defer waitGroup.Done()
+	payload := map[string]interface{}{"attachment_ids": ids, "cover_id": coverID}
+	respChan <- sendToBackend(cookie, http.MethodPut, config.BaseApi+target+"/attachments", payload)
+}
+
+// setAttachments sets the images of the post or draft at target and returns the outcome.
+func setAttachments(cookie *http.Cookie, target string, ids []int, coverID int) models.ResponseDetails {
+	respChan := make(chan models.ResponseDetails, 1)
+	var wg sync.WaitGroup
+
+	wg.Add(1)
+	go SendSetAttachmentsRequest(cookie, target, ids, coverID, &wg, respChan)
+	go func() {
+		wg.Wait()
+		close(respChan)
+	}()
+	return <-respChan
+}
@@ -0,0 +1,194 @@
+package handlers
+
+import (
+	"encoding/json"
+	"literary-lions/frontend/src/config"
+	"literary-lions/frontend/src/models"
+	"log"
+	"net/http"
+	"net/url"
+	"sync"
+)
+
+// ShowUser renders the public profile of the member named by the name query
+// parameter, with one page of their posts, comments or liked posts depending on
+// the tab query parameter.
+func ShowUser(w http.ResponseWriter, r *http.Request) {
+	name := r.URL.Query().Get("name")
+	tab := r.URL.Query().Get("tab")
+	if tab != "comments" && tab != "liked" && tab != "shelves" {
+		tab = "posts"
+	}
+	sort := r.URL.Query().Get("sort")
+
+	// Forward the sort order and cursor of the tab
+	params := url.Values{}
+	if sort != "" {
+		params.Set("sort", sort)
+	}
+	if cursor := r.URL.Query().Get("cursor"); cursor != "" {
+		params.Set("cursor", cursor)
+	}
+
+	// The session cookie lets members see their own liked posts when they keep them private
+	var cookies []*http.Cookie
+	if cookie, err := r.Cookie("session_token"); err == nil {
+		cookies = append(cookies, cookie)
+	}
+
+	profileChan := make(chan models.User, 1)
+	profileStatusChan := make(chan int, 1)
+	postsChan := make(chan models.PostPage, 1)
+	commentsChan := make(chan models.UserCommentPage, 1)
+	shelvesChan := make(chan models.Shelves, 1)
+	listStatusChan := make(chan int, 1)
+	var wg sync.WaitGroup
+
+	wg.Add(2)
+	go SendGetUserProfileRequest(name, &wg, profileChan, profileStatusChan)
+	userURL := config.BaseApi + "/users/" + url.PathEscape(name)
+	switch tab {
+	case "comments":
+		go SendGetUserCommentsRequest(userURL+"/comments?"+params.Encode(), &wg, commentsChan, listStatusChan)
+	case "liked":
+		go SendGetUserPostsRequest(userURL+"/liked-posts?"+params.Encode(), cookies, &wg, postsChan, listStatusChan)
+	case "shelves":
+		go SendGetShelvesRequest(userURL+"/shelves", &wg, shelvesChan, listStatusChan)
+	default:
+		go SendGetUserPostsRequest(userURL+"/posts?"+params.Encode(), nil, &wg, postsChan, listStatusChan)
+	}
+	go func() {
+		wg.Wait()
+		close(profileChan)
+		close(profileStatusChan)
+		close(postsChan)
+		close(commentsChan)
+		close(shelvesChan)
+		close(listStatusChan)
+	}()
+
+	profile := <-profileChan
+	if status := <-profileStatusChan; status == http.StatusNotFound {
+		StatusInternalServerError(w, "No member goes by this name")
+		return
+	} else if status != http.StatusOK {
+		StatusInternalServerError(w, "Failed to fetch the profile")
+		return
+	}
+
+	// Private liked posts leave the tab empty, with a note in place of the list
+	var posts models.PostPage
+	var comments models.UserCommentPage
+	var shelves models.Shelves
+	private := false
+	switch tab {
+	case "comments":
+		comments = <-commentsChan
+	case "shelves":
+		shelves = <-shelvesChan
+	default:
+		posts = <-postsChan
+	}
+	if status := <-listStatusChan; status == http.StatusForbidden && tab == "liked" {
+		private = true
+	} else if status != http.StatusOK {
+		StatusInternalServerError(w, "Failed to fetch the activity of "+profile.Username)
+		return
+	}
+
+	// Truncate content if necessary
+	for i := range posts.Posts {
+		posts.Posts[i].Content = truncateContent(posts.Posts[i].Content, 150)
+	}
+	var activity models.Activity
+	if profile.Activity != nil {
+		activity = *profile.Activity
+	}
+	for i := range activity.Reactions {
+		activity.Reactions[i].Label = reactionLabel(activity.Reactions[i].Reaction)
+	}
+
+	next := posts.Next
+	if tab == "comments" {
+		next = comments.Next
+	}
+
+	currentUser, authenticated := isAuthenticated(r)
+
+	data := struct {
+		Profile       models.User
+		Activity      models.Activity
+		Tab           string
+		Posts         []models.Post
+		Comments      []models.UserComment
+		Shelves       []shelfGroup
+		Stats         []models.ReadingYear
+		Private       bool
+		Own           bool
+		Authenticated bool
+		Username      string
+		Sort          string
+		NextURL       string
+		FirstURL      string
+	}{
+		Profile:       profile,
+		Activity:      activity,
+		Tab:           tab,
+		Posts:         posts.Posts,
+		Comments:      comments.Comments,
+		Shelves:       groupShelves(shelves.Shelves),
+		Stats:         shelves.Stats,
+		Private:       private,
+		Own:           authenticated && currentUser == profile.Username,
+		Authenticated: authenticated,
+		Username:      currentUser,
+		Sort:          sort,
+		NextURL:       nextPageURL(r, next),
+		FirstURL:      firstPageURL(r),
+	}
+
+	RenderTemplate(w, "user.html", data)
+}
+
+// SendGetUserPostsRequest fetches one page of the posts or liked posts of a member
+// from the backend, with the given cookies, and sends it on respChan and the status
+// code of the response on statusChan.
+func SendGetUserPostsRequest(apiURL string, cookies []*http.Cookie, waitGroup *sync.WaitGroup, respChan chan models.PostPage, statusChan chan int) {
+	defer waitGroup.Done()
+
+	var page models.PostPage
+	body, status, err := getFromBackend(apiURL, cookies...)
+	if err != nil {
+		log.Print(err)
+		status = http.StatusInternalServerError
+	} else if status == http.StatusOK {
+		if err := json.Unmarshal(body, &page); err != nil {
+			log.Printf("Failed to parse posts: %v", err)
+			status = http.StatusInternalServerError
+		}
+	}
+
+	respChan <- page
+	statusChan <- status
+}
+
+// SendGetUserCommentsRequest fetches one page of the comments of a member from the
+// backend, and sends it on respChan and the status code of the response on statusChan.
+func SendGetUserCommentsRequest(apiURL string, waitGroup *sync.WaitGroup, respChan chan models.UserCommentPage, statusChan chan int) {
+	defer waitGroup.Done()
+
+	var page models.UserCommentPage
+	body, status, err := getFromBackend(apiURL)
+	if err != nil {
+		log.Print(err)
+		status = http.StatusInternalServerError
+	} else if status == http.StatusOK {
+		if err := json.Unmarshal(body, &page); err != nil {
+			log.Printf("Failed to parse comments: %v", err)
+			status = http.StatusInternalServerError
+		}
+	}
+
+	respChan <- page
+	statusChan <- status
+}
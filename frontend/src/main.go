@@ -14,14 +14,58 @@ func main() {
 	http.HandleFunc("/postlike", handlers.LikePost)
 	http.HandleFunc("/postdislike", handlers.DislikePost)
 	http.HandleFunc("/comment", handlers.AddComment)
+	http.HandleFunc("/reply", handlers.ReplyToComment)
+	http.HandleFunc("/commentedit", handlers.EditComment)
+	http.HandleFunc("/commentdelete", handlers.DeleteComment)
 	http.HandleFunc("/commentlike", handlers.LikeComment)
 	http.HandleFunc("/commentdislike", handlers.DislikeComment)
+	http.HandleFunc("/react", handlers.React)
+	http.HandleFunc("/poll-vote", handlers.VotePoll)
 	http.HandleFunc("/profile", handlers.ShowUserProfile)
 	http.HandleFunc("/update-profile", handlers.UpdateUserProfile)
+	http.HandleFunc("/user", handlers.ShowUser)
 	http.HandleFunc("/register", handlers.Register)
 	http.HandleFunc("/login", handlers.LoginHandler)
 	http.HandleFunc("/logout-handler", handlers.Logout)
 	http.HandleFunc("/create-post", handlers.CreatePost)
+	http.HandleFunc("/edit-post", handlers.EditPost)
+	http.HandleFunc("/delete-post", handlers.DeletePost)
+	http.HandleFunc("/edit-draft", handlers.EditDraft)
+	http.HandleFunc("/publish-draft", handlers.PublishDraft)
+	http.HandleFunc("/delete-draft", handlers.DeleteDraft)
+	http.HandleFunc("/markdown-preview", handlers.PreviewMarkdown)
+	http.HandleFunc("/post-history", handlers.ShowPostHistory)
+	http.HandleFunc("/post-diff", handlers.ShowRevisionDiff)
+	http.HandleFunc("/restore-revision", handlers.RestoreRevision)
+	http.HandleFunc("/trash", handlers.ShowTrash)
+	http.HandleFunc("/restore-post", handlers.RestorePost)
+	http.HandleFunc("/restore-comment", handlers.RestoreComment)
+	http.HandleFunc("/categories", handlers.ShowCategories)
+	http.HandleFunc("/category", handlers.ShowCategory)
+	http.HandleFunc("/tag", handlers.ShowTag)
+	http.HandleFunc("/books", handlers.ShowBooks)
+	http.HandleFunc("/book", handlers.ShowBook)
+	http.HandleFunc("/approve-book", handlers.ApproveBook)
+	http.HandleFunc("/delete-book", handlers.DeleteBook)
+	http.HandleFunc("/review", handlers.SaveReview)
+	http.HandleFunc("/delete-review", handlers.DeleteReview)
+	http.HandleFunc("/shelve", handlers.ShelveBook)
+	http.HandleFunc("/unshelve", handlers.UnshelveBook)
+	http.HandleFunc("/clubs", handlers.ShowClubs)
+	http.HandleFunc("/club", handlers.ShowClub)
+	http.HandleFunc("/club-post", handlers.CreateClubPost)
+	http.HandleFunc("/club-join", handlers.JoinClub)
+	http.HandleFunc("/club-leave", handlers.LeaveClub)
+	http.HandleFunc("/club-edit", handlers.EditClub)
+	http.HandleFunc("/club-delete", handlers.DeleteClub)
+	http.HandleFunc("/club-member", handlers.ModerateClubMember)
+	http.HandleFunc("/club-delete-post", handlers.DeleteClubPost)
+	http.HandleFunc("/club-schedule", handlers.ShowClubSchedule)
+	http.HandleFunc("/club-section-add", handlers.AddClubSection)
+	http.HandleFunc("/club-section-edit", handlers.EditClubSection)
+	http.HandleFunc("/club-section-delete", handlers.DeleteClubSection)
+	http.HandleFunc("/club-section-read", handlers.MarkClubSection)
+	http.HandleFunc("/uploads/", handlers.ServeUpload)
 
 	http.Handle("/static/", http.StripPrefix("/static/", http.FileServer(http.Dir("static"))))
 
@@ -1,6 +1,7 @@
 package models
 
 import (
+	"html/template"
 	"time"
 )
 
@@ -16,6 +17,8 @@ type ResponseDetails struct {
 	Status    int
 	Username  string
 	Email     string
+	ID        int // ID of the record created, if the backend returned one
+	Slug      string // Slug of the record created, if the backend returned one
 }
 
 type AuthResponse struct {
@@ -32,15 +35,53 @@ type User struct {
 	Email    	string 		`json:"email"`
 	Username 	string 		`json:"username"`
 	Password 	string 		`json:"-"`
-	ProfilePic 	string   	`json:"profile_pic"`
+	ProfilePic 	string   	`json:"profile_pic"` // Storage key of the avatar, empty for the default one
 	CreatedAt  	time.Time 	`json:"created_at"`
 	Role     	string 		`json:"role"`
+	Bio            	string   	`json:"bio"`
+	Location       	string   	`json:"location"`
+	FavoriteBooks  	[]string 	`json:"favorite_books"`
+	FavoriteGenres 	[]string 	`json:"favorite_genres"`
+	HideLikedPosts 	bool     	`json:"hide_liked_posts"` // Whether only the user sees the posts they like
+	Activity       	*Activity 	`json:"activity,omitempty"` // Totals of the profile, filled in on the public profile
+}
+
+// Activity struct represents what a member wrote and the reactions others gave to it.
+type Activity struct {
+	Posts     int             `json:"posts"`
+	Comments  int             `json:"comments"`
+	Likes     int             `json:"likes"`
+	Dislikes  int             `json:"dislikes"`
+	Reactions []ReactionCount `json:"reactions"`
 }
 
 // Category struct represents a category for posts.
 type Category struct {
-	ID   int    `json:"id"`
-	Name string `json:"name"`
+	ID          int    `json:"id"`
+	Slug        string `json:"slug"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Position    int    `json:"position"`
+	PostCount   int    `json:"post_count"`
+}
+
+// CategoryPage struct represents a category together with one page of its posts.
+type CategoryPage struct {
+	Category Category `json:"category"`
+	Posts    PostPage `json:"posts"`
+}
+
+// Tag struct represents a tag carried by posts.
+type Tag struct {
+	ID        int    `json:"id"`
+	Slug      string `json:"slug"`
+	PostCount int    `json:"post_count"`
+}
+
+// TagPage struct represents a tag together with one page of the posts carrying it.
+type TagPage struct {
+	Tag   Tag      `json:"tag"`
+	Posts PostPage `json:"posts"`
 }
 
 // Post struct represents a post in the forum.
@@ -49,34 +90,371 @@ type Post struct {
 	UserID     int       `json:"user_id"`
 	Category   string    `json:"category"`
 	CategoryID int       `json:"category_id"`
+	CategorySlug string  `json:"category_slug"`
 	Title      string    `json:"title"`
 	Username   string 	 `json:"username"`
 	Content    string    `json:"content"`
 	CreatedAt  time.Time `json:"created_at"`
-	FormattedContent []string	`json:"formatted_content"`
-	
+	ContentHTML  template.HTML `json:"content_html"` // Content rendered from Markdown and sanitized by the backend
+	Likes        int `json:"likes"`
+	CommentCount int `json:"comment_count"`
+	Reactions    []ReactionCount `json:"reactions"`
+	Tags         []string `json:"tags"`
+	Editable     bool     `json:"editable"` // Whether the logged-in user may edit and delete it
+	EditedAt     *time.Time `json:"edited_at"` // Time of the last edit, nil if never edited
+	DeletedAt    *time.Time `json:"deleted_at"` // Time it was moved to the trash, set on the trash page only
+	Status       string     `json:"status,omitempty"`     // draft, scheduled or published
+	PublishAt    *time.Time `json:"publish_at,omitempty"` // Time a scheduled post goes live, nil for the others
+	Attachments  []Attachment `json:"attachments,omitempty"` // Images, in the order they are shown
+	Cover        *Attachment  `json:"cover,omitempty"`       // The image shown in the listings, nil without images
+	Books        []BookRef    `json:"books,omitempty"`       // Books of the catalog it discusses, in the order they are shown
+	BookIDs      []int        `json:"book_ids,omitempty"`    // Books to link, sent when creating a post
+	ClubID       int          `json:"club_id,omitempty"`     // Club the post is written in, 0 for the forum
+	Club         string       `json:"club,omitempty"`        // Name of the club
+	ClubSlug     string       `json:"club_slug,omitempty"`
+	SectionID    int          `json:"section_id,omitempty"` // Section of the club's reading schedule it discusses, 0 if none
+	Section      string       `json:"section,omitempty"`    // Title of the section
+	Locked       bool         `json:"locked,omitempty"`     // Held back until the logged-in member marks the section read
+	Poll         *PollRequest `json:"poll,omitempty"`       // Poll to attach, sent when creating a post
+}
+
+// Poll struct represents a poll carried by a post, with its results.
+type Poll struct {
+	ID         int          `json:"id"`
+	Question   string       `json:"question"`
+	Multiple   bool         `json:"multiple"`  // Whether voters may pick several options
+	Anonymous  bool         `json:"anonymous"` // Whether the voters of each option are kept hidden
+	ClosesAt   *time.Time   `json:"closes_at"` // When voting ends, nil if it stays open
+	Closed     bool         `json:"closed"`
+	Options    []PollOption `json:"options"`
+	VoterCount int          `json:"voter_count"`
+	Voted      bool         `json:"voted"` // Whether the logged-in member voted
+}
+
+// PollOption struct represents an answer of a poll and the votes it received.
+type PollOption struct {
+	ID      int      `json:"id"`
+	Label   string   `json:"label"`
+	Votes   int      `json:"votes"`
+	Chosen  bool     `json:"chosen"`  // Whether the logged-in member picked it
+	Voters  []string `json:"voters"` // Usernames of its voters, empty for anonymous polls
+	Percent int      `json:"-"`      // Share of the voters who picked it, for the result bar
+}
+
+// PollRequest struct represents the poll typed in the form of a new post.
+type PollRequest struct {
+	Question  string     `json:"question"`
+	Options   []string   `json:"options"`
+	Multiple  bool       `json:"multiple"`
+	Anonymous bool       `json:"anonymous"`
+	ClosesAt  *time.Time `json:"closes_at,omitempty"`
+}
+
+// VoteRequest struct represents the options picked in a poll.
+type VoteRequest struct {
+	OptionIDs []int `json:"option_ids"`
+}
+
+// BookRef struct represents a book as linked from a post.
+type BookRef struct {
+	ID    int    `json:"id"`
+	Title string `json:"title"`
+}
+
+// Book struct represents a title of the catalog that posts can discuss.
+type Book struct {
+	ID        int           `json:"id"`
+	Title     string        `json:"title"`
+	Authors   []string      `json:"authors"`
+	ISBN      string        `json:"isbn"`            // Digits only, empty if unknown
+	Year      int           `json:"year"`            // Year of first publication, 0 if unknown
+	CoverID   int           `json:"cover_id,omitempty"`
+	Cover     *Attachment   `json:"cover,omitempty"` // The uploaded image shown as the cover, nil for none
+	Approved  bool          `json:"approved"`        // Whether the book is listed in the catalog
+	PostCount int           `json:"post_count"`      // Number of posts discussing the book
+	Rating    RatingSummary `json:"rating"`          // The ratings of its reviews
+}
+
+// BookPage struct represents a book together with one page of the posts discussing it.
+type BookPage struct {
+	Book     Book        `json:"book"`
+	Posts    PostPage    `json:"posts"`
+	MyReview *Review     `json:"my_review"` // The review of the logged-in member, nil if none
+	MyShelf  *ShelfEntry `json:"my_shelf"`  // The shelf the logged-in member keeps it on, nil if none
+}
+
+// RatingSummary struct represents the ratings a book received.
+type RatingSummary struct {
+	Average   float64 `json:"average"`   // Mean number of stars, 0 without reviews
+	Count     int     `json:"count"`     // Number of reviews
+	Histogram [5]int  `json:"histogram"` // Number of reviews giving 1 to 5 stars, in that order
+}
+
+// Review struct represents the rating and opinion of a member on a book.
+type Review struct {
+	ID          int           `json:"id"`
+	BookID      int           `json:"book_id"`
+	Username    string        `json:"username"`
+	Rating      int           `json:"rating"`  // Number of stars, from 1 to 5
+	Spoiler     bool          `json:"spoiler"` // Whether the text reveals the plot
+	Content     string        `json:"content"`
+	ContentHTML template.HTML `json:"content_html,omitempty"` // Rendered by the backend from Markdown
+	CreatedAt   time.Time     `json:"created_at"`
+	EditedAt    *time.Time    `json:"edited_at,omitempty"`
+	Editable    bool          `json:"editable"` // Whether the logged-in user may edit and delete it
+}
+
+// ShelfEntry struct represents a book on the reading shelves of a member.
+type ShelfEntry struct {
+	BookID     int         `json:"book_id"`
+	Title      string      `json:"title"`
+	Authors    []string    `json:"authors"`
+	Cover      *Attachment `json:"cover,omitempty"`
+	Shelf      string      `json:"shelf"`    // want_to_read, reading or finished
+	Progress   int         `json:"progress"` // Percentage of the book read
+	StartedAt  *time.Time  `json:"started_at,omitempty"`
+	FinishedAt *time.Time  `json:"finished_at,omitempty"`
+	UpdatedAt  time.Time   `json:"updated_at"`
+}
+
+// ReadingYear struct represents the number of books a member finished in a year.
+type ReadingYear struct {
+	Year     int `json:"year"`
+	Finished int `json:"finished"`
+}
+
+// Shelves struct represents the reading shelves of a member and their yearly stats.
+type Shelves struct {
+	Shelves []ShelfEntry  `json:"shelves"`
+	Stats   []ReadingYear `json:"stats"`
+}
+
+// ShelfRequest struct represents a change to the shelf of a book.
+type ShelfRequest struct {
+	Shelf      string `json:"shelf"`
+	Progress   int    `json:"progress"`
+	FinishedAt string `json:"finished_at,omitempty"` // YYYY-MM-DD, today if empty
+}
+
+// Club struct represents a book club, whose members discuss books in posts of their own.
+type Club struct {
+	ID          int         `json:"id"`
+	Slug        string      `json:"slug"`
+	Name        string      `json:"name"`
+	Description string      `json:"description"`
+	Visibility  string      `json:"visibility"` // public or private
+	Owner       string      `json:"owner"`      // Username of the owner
+	MemberCount int         `json:"member_count"`
+	PostCount   int         `json:"post_count"`
+	CreatedAt   time.Time   `json:"created_at"`
+	Membership  *ClubMember `json:"membership,omitempty"` // The membership of the logged-in member, nil if none
+}
+
+// ClubMember struct represents the membership of a member in a club, or their request to join it.
+type ClubMember struct {
+	Username   string    `json:"username"`
+	ProfilePic string    `json:"profile_pic"`
+	Role       string    `json:"role"`     // member, moderator or owner
+	Approved   bool      `json:"approved"` // False while a request to join a private club waits
+	JoinedAt   time.Time `json:"joined_at"`
+}
+
+// ClubPage struct represents a club with its members, its reading schedule and one page of its posts.
+type ClubPage struct {
+	Club        Club          `json:"club"`
+	Members     []ClubMember  `json:"members"`
+	Requests    []ClubMember  `json:"requests"` // Requests to join, listed to moderators only
+	Sections    []ClubSection `json:"sections"` // The reading schedule
+	Posts       PostPage      `json:"posts"`
+	CanRead     bool          `json:"can_read"`     // Whether the members, schedule and posts are shown
+	CanModerate bool          `json:"can_moderate"` // Whether the logged-in member moderates the club
+}
+
+// ClubSection struct represents a section of the reading schedule of a club, such
+// as a few chapters of the book its members read together.
+type ClubSection struct {
+	ID        int       `json:"id"`
+	Title     string    `json:"title"`
+	BookID    int       `json:"book_id,omitempty"`
+	Book      string    `json:"book,omitempty"` // Title of the book, if any
+	UnlocksAt time.Time `json:"unlocks_at"`     // When members may start discussing it
+	Unlocked  bool      `json:"unlocked"`
+	Read      bool      `json:"read"` // Whether the logged-in member marked it read
+	PostCount int       `json:"post_count"`
+}
+
+// ClubSchedule struct represents the reading schedule of a club.
+type ClubSchedule struct {
+	Club        Club          `json:"club"`
+	Sections    []ClubSection `json:"sections"`
+	CanModerate bool          `json:"can_moderate"` // Whether the logged-in member plans the schedule
+}
+
+// ClubSectionRequest struct represents the title, unlock date and book of a section.
+type ClubSectionRequest struct {
+	Title     string `json:"title"`
+	UnlocksAt string `json:"unlocks_at"` // YYYY-MM-DD
+	BookID    int    `json:"book_id,omitempty"`
+}
+
+// ClubRequest struct represents the name, description and visibility of a club.
+type ClubRequest struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Visibility  string `json:"visibility"`
+}
+
+// ReviewPage struct represents one page of the reviews of a book.
+type ReviewPage struct {
+	Reviews []Review `json:"reviews"`
+	Next    string   `json:"next,omitempty"` // Cursor of the next page, empty on the last one
+}
+
+// Attachment struct represents an image uploaded to be shown on posts. The
+// image and its thumbnail are served by their keys under /uploads/.
+type Attachment struct {
+	ID           int    `json:"id"`
+	Key          string `json:"key"`
+	ThumbnailKey string `json:"thumbnail_key"`
+	Width        int    `json:"width"`
+	Height       int    `json:"height"`
+	Cover        bool   `json:"cover"` // Whether it is the cover of the post
+}
+
+// UploadDetails struct represents the outcome of uploading one image.
+type UploadDetails struct {
+	Index      int        // Position of the file among those uploaded together
+	Attachment Attachment // The uploaded image, when Status is 201 Created
+	Status     int
+	Message    string
+}
+
+// PostRevision struct represents one version of the title, content and category of a post.
+type PostRevision struct {
+	PostID       int       `json:"post_id"`
+	Revision     int       `json:"revision"`      // 1 for the post as first written
+	Title        string    `json:"title"`
+	Content      string    `json:"content"`
+	Category     string    `json:"category"`
+	Editor       string    `json:"editor"`        // Username of the user who wrote this version
+	RestoredFrom int       `json:"restored_from"` // Revision this version brought back, 0 for an ordinary edit
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// PostHistory struct represents the revisions of a post, the latest first.
+type PostHistory struct {
+	Revisions  []PostRevision `json:"revisions"`
+	CanRestore bool           `json:"can_restore"` // Whether the logged-in user is an admin
+}
+
+// DiffLine struct represents one line of a diff; Op is equal, insert or delete.
+type DiffLine struct {
+	Op   string `json:"op"`
+	Text string `json:"text"`
+}
+
+// RevisionDiff struct represents what changed between two revisions of a post.
+type RevisionDiff struct {
+	From            PostRevision `json:"from"` // Revision 0 when compared with nothing
+	To              PostRevision `json:"to"`
+	Title           []DiffLine   `json:"title"`
+	Content         []DiffLine   `json:"content"`
+	CategoryChanged bool         `json:"category_changed"`
 }
 
 // Post struct represents a post in the forum.
 type PostDetails struct {
 	Post     Post
 	Comments []Comment
+	Next     string // Cursor of the next page of comments
 	Likes 	  int
 	Dislikes  int
 	Status	  int
 	Content   string  
 }
 
+// PostPage struct represents one page of a post listing.
+type PostPage struct {
+	Posts []Post `json:"posts"`
+	Next  string `json:"next"` // Cursor of the next page, empty on the last page
+}
+
 // Comment struct represents a comment on a post.
 type Comment struct {
 	ID        int       `json:"id"`
-	PostID    string    `json:"post_id"`
+	PostID    int       `json:"post_id"`
+	ParentID  int       `json:"parent_id"` // The comment replied to, 0 for a comment on the post itself
+	Depth     int       `json:"depth"`     // Level in the thread, 0 for a comment on the post itself
 	UserID    int       `json:"user_id"`
 	Username  string 	`json:"username"`
 	Content   string    `json:"content"`
+	ContentHTML template.HTML `json:"content_html"` // Content rendered from Markdown and sanitized by the backend
 	CreatedAt time.Time `json:"created_at"`
+	EditedAt  *time.Time `json:"edited_at"` // Time of the last edit, nil if never edited
+	Deleted   bool      `json:"deleted"`    // Removed but kept for its replies
+	Editable  bool      `json:"editable"`   // Whether the logged-in user may edit and delete it
 	Likes 	  int		`json:"likes"`
 	Dislikes  int		`json:"dislikes"`
+	Reactions []ReactionCount `json:"reactions"`
+}
+
+// MarkdownPreview struct represents the HTML the backend renders Markdown to, or why it could not.
+type MarkdownPreview struct {
+	HTML  string `json:"html"`
+	Error string `json:"error"`
+}
+
+// TrashedComment struct represents a comment in the trash of its author.
+type TrashedComment struct {
+	ID        int        `json:"id"`
+	PostID    int        `json:"post_id"`
+	PostTitle string     `json:"post_title"`
+	Content   string     `json:"content"`
+	CreatedAt time.Time  `json:"created_at"`
+	DeletedAt *time.Time `json:"deleted_at"`
+}
+
+// UserComment struct represents a comment in the list of those a member wrote.
+type UserComment struct {
+	ID          int           `json:"id"`
+	PostID      int           `json:"post_id"`
+	PostTitle   string        `json:"post_title"`
+	ContentHTML template.HTML `json:"content_html"` // Content rendered from Markdown and sanitized by the backend
+	CreatedAt   time.Time     `json:"created_at"`
+	Likes       int           `json:"likes"`
+	Dislikes    int           `json:"dislikes"`
+}
+
+// UserCommentPage struct represents one page of the comments a member wrote.
+type UserCommentPage struct {
+	Comments []UserComment `json:"comments"`
+	Next     string        `json:"next"` // Cursor of the next page, empty on the last page
+}
+
+// Trash struct represents what the logged-in user deleted and can still restore.
+type Trash struct {
+	Posts         []Post           `json:"posts"`
+	Comments      []TrashedComment `json:"comments"`
+	RetentionDays int              `json:"retention_days"` // Days after which deleted items are purged
+}
+
+// ReactionCount struct represents how often a post or comment received one reaction.
+type ReactionCount struct {
+	Reaction string `json:"reaction"`
+	Count    int    `json:"count"`
+	Reacted  bool   `json:"reacted"` // Whether the logged-in user gave this reaction
+	Label    string `json:"-"`       // Emoji and name shown on the picker button
+}
+
+// SearchResult struct represents a ranked search hit on a post or a comment.
+type SearchResult struct {
+	Type      string        `json:"type"`    // "post" or "comment"
+	ID        int           `json:"id"`      // ID of the matching post or comment
+	PostID    int           `json:"post_id"` // Post to link to
+	Title     string        `json:"title"`
+	Snippet   template.HTML `json:"snippet"` // Escaped by the backend, with matches wrapped in <mark>
+	Username  string        `json:"username"`
+	CreatedAt time.Time     `json:"created_at"`
 }
 
 // Like struct represents a like/dislike on a post or comment.
@@ -98,6 +476,8 @@ type Message struct {
 // Data struct for template.
 type Data struct {
 	Posts         []Post
+	Next          string
+	SearchResults []SearchResult
 	Authenticated bool
 	Status	  int
 	Message   string
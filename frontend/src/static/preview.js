@@ -0,0 +1,21 @@
+// Show how the Markdown written in a form will look. Each button with the
+// preview-button class renders the textarea of its form into the .preview
+// element of the same form.
+document.querySelectorAll('.preview-button').forEach(button => {
+    button.addEventListener('click', async () => {
+        const form = button.closest('form');
+        const preview = form.querySelector('.preview');
+        const body = new URLSearchParams({ content: form.querySelector('textarea').value });
+        try {
+            const response = await fetch('/markdown-preview', { method: 'POST', body });
+            if (response.ok) {
+                preview.innerHTML = await response.text();
+            } else {
+                preview.textContent = await response.text();
+            }
+        } catch (error) {
+            preview.textContent = 'The preview is not available right now.';
+        }
+        preview.hidden = false;
+    });
+});